@@ -6,6 +6,7 @@ require (
 	github.com/aledsdavies/devcmd/core v0.0.0
 	github.com/aledsdavies/devcmd/runtime v0.0.0
 	github.com/aledsdavies/devcmd/testing v0.0.0-00010101000000-000000000000
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/google/go-cmp v0.7.0
 	github.com/spf13/cobra v1.9.1
 )
@@ -13,6 +14,7 @@ require (
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.7 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
 
 replace github.com/aledsdavies/devcmd/core => ../core
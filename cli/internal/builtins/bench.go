@@ -0,0 +1,402 @@
+package decorators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// BenchBaselineFileName is the file devcmd bench keeps baselines in, next to the commands file
+const BenchBaselineFileName = "devcmd.bench.json"
+
+// BenchStats summarizes the durations of benchmark runs
+type BenchStats struct {
+	Runs int           `json:"runs"`
+	Min  time.Duration `json:"min_ns"`
+	Mean time.Duration `json:"mean_ns"`
+	P95  time.Duration `json:"p95_ns"`
+}
+
+// NewBenchStats computes the minimum, mean and nearest-rank 95th percentile of durations
+func NewBenchStats(durations []time.Duration) BenchStats {
+	if len(durations) == 0 {
+		return BenchStats{}
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return BenchStats{
+		Runs: len(sorted),
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P95:  sorted[(len(sorted)*95+99)/100-1],
+	}
+}
+
+// String formats the stats as "5 runs: min 1.2s, mean 1.3s, p95 1.5s"
+func (s BenchStats) String() string {
+	return fmt.Sprintf("%d runs: min %s, mean %s, p95 %s", s.Runs, roundBenchDuration(s.Min), roundBenchDuration(s.Mean), roundBenchDuration(s.P95))
+}
+
+// Compare returns the change of the mean from a baseline's, in percent, and whether it is
+// slower by more than threshold percent
+func (s BenchStats) Compare(baseline BenchStats, threshold int) (float64, bool) {
+	if baseline.Mean <= 0 {
+		return 0, false
+	}
+	change := (float64(s.Mean) - float64(baseline.Mean)) / float64(baseline.Mean) * 100
+	return change, change > float64(threshold)
+}
+
+// RunBench calls run warmup times, then times runs calls of it
+func RunBench(runs, warmup int, run func() error) (BenchStats, error) {
+	for i := 1; i <= warmup; i++ {
+		if err := run(); err != nil {
+			return BenchStats{}, fmt.Errorf("warmup run %d failed: %w", i, err)
+		}
+	}
+	durations := make([]time.Duration, 0, runs)
+	for i := 1; i <= runs; i++ {
+		start := time.Now()
+		if err := run(); err != nil {
+			return BenchStats{}, fmt.Errorf("run %d failed: %w", i, err)
+		}
+		durations = append(durations, time.Since(start))
+	}
+	return NewBenchStats(durations), nil
+}
+
+// ReadBenchBaseline reads the stats stored by name in a baseline file; a missing file has none
+func ReadBenchBaseline(path string) (map[string]BenchStats, error) {
+	baseline := make(map[string]BenchStats)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return baseline, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("%s is not a benchmark baseline: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// WriteBenchBaseline writes the stats by name to a baseline file
+func WriteBenchBaseline(path string, baseline map[string]BenchStats) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// roundBenchDuration rounds a duration to three significant figures or so, for display
+func roundBenchDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+// benchTemplate times the block's commands in generated CLIs. It mirrors RunBench and
+// BenchDecorator.compare.
+const benchTemplate = `// Benchmark: {{.Runs}} runs after {{.Warmup}} warmup
+{
+	type benchStats struct {
+		Runs int           ` + "`json:\"runs\"`" + `
+		Min  time.Duration ` + "`json:\"min_ns\"`" + `
+		Mean time.Duration ` + "`json:\"mean_ns\"`" + `
+		P95  time.Duration ` + "`json:\"p95_ns\"`" + `
+	}
+	round := func(d time.Duration) time.Duration {
+		switch {
+		case d >= time.Second:
+			return d.Round(10 * time.Millisecond)
+		case d >= time.Millisecond:
+			return d.Round(10 * time.Microsecond)
+		default:
+			return d.Round(time.Microsecond)
+		}
+	}
+	run := func() error {
+{{range .Content}}		{{. | buildCommand}}
+{{end}}		return nil
+	}
+	for i := 1; i <= {{.Warmup}}; i++ {
+		if err := run(); err != nil {
+			return fmt.Errorf("@bench: warmup run %d failed: %w", i, err)
+		}
+	}
+	durations := make([]time.Duration, 0, {{.Runs}})
+	for i := 1; i <= {{.Runs}}; i++ {
+		start := time.Now()
+		if err := run(); err != nil {
+			return fmt.Errorf("@bench: run %d failed: %w", i, err)
+		}
+		durations = append(durations, time.Since(start))
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	stats := benchStats{Runs: len(durations), Min: durations[0], Mean: total / time.Duration(len(durations)), P95: durations[(len(durations)*95+99)/100-1]}
+	logf("info", "@bench "+{{printf "%q" .Name}}, "%d runs: min %s, mean %s, p95 %s", stats.Runs, round(stats.Min), round(stats.Mean), round(stats.P95))
+{{if .Baseline}}
+	baselinePath := {{printf "%q" .Baseline}}
+	baseline := make(map[string]benchStats)
+	if data, err := os.ReadFile(baselinePath); err == nil {
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			return fmt.Errorf("@bench: %s is not a benchmark baseline: %w", baselinePath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("@bench: %w", err)
+	}
+	if previous, ok := baseline[{{printf "%q" .Name}}]; ok && previous.Mean > 0 {
+		change := (float64(stats.Mean) - float64(previous.Mean)) / float64(previous.Mean) * 100
+		if change > {{.Threshold}} {
+			return fmt.Errorf("@bench %s: mean %s is %.1f%% slower than the baseline %s (threshold {{.Threshold}}%%)", {{printf "%q" .Name}}, round(stats.Mean), change, round(previous.Mean))
+		}
+		logf("info", "@bench "+{{printf "%q" .Name}}, "mean %+.1f%% against the baseline %s", change, round(previous.Mean))
+	} else {
+		baseline[{{printf "%q" .Name}}] = stats
+		data, err := json.MarshalIndent(baseline, "", "  ")
+		if err == nil {
+			err = os.WriteFile(baselinePath, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			return fmt.Errorf("@bench: failed to record the baseline: %w", err)
+		}
+		logf("info", "@bench "+{{printf "%q" .Name}}, "recorded the baseline in %s", baselinePath)
+	}
+{{end}}}`
+
+// benchParams holds the parameters of @bench
+type benchParams struct {
+	Runs      int
+	Warmup    int
+	Name      string
+	Baseline  string
+	Threshold int
+}
+
+// BenchDecorator implements the @bench decorator for timing repeated runs of a block
+type BenchDecorator struct{}
+
+// Name returns the decorator name
+func (b *BenchDecorator) Name() string {
+	return "bench"
+}
+
+// Description returns a human-readable description
+func (b *BenchDecorator) Description() string {
+	return "Run the block repeatedly and report min, mean and p95 durations, optionally against a baseline"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (b *BenchDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "runs",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Number of timed runs (default: 5)",
+		},
+		{
+			Name:        "warmup",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Untimed runs before the timed ones (default: 1)",
+		},
+		{
+			Name:        "name",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Name the results are reported and stored in the baseline under (default: bench)",
+		},
+		{
+			Name:        "baseline",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Baseline file to compare with; the first run records it (default: none)",
+		},
+		{
+			Name:        "threshold",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Percent the mean may be slower than the baseline's before failing (default: 10)",
+		},
+	}
+}
+
+// ExecuteInterpreter times the block in interpreter mode
+func (b *BenchDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	bench, err := b.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	stats, err := RunBench(bench.Runs, bench.Warmup, func() error {
+		commandExecutor := decorators.NewCommandExecutor()
+		defer commandExecutor.Cleanup()
+
+		return commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content)
+	})
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@bench: %w", err)}
+	}
+
+	logger := ctx.Logger()
+	logger.Infof("@bench "+bench.Name, "%s", stats)
+	return &execution.ExecutionResult{
+		Data:  stats,
+		Error: b.compare(logger, bench, stats),
+	}
+}
+
+// compare checks the stats against the baseline file, recording them when it has none
+func (b *BenchDecorator) compare(logger *logging.Logger, bench benchParams, stats BenchStats) error {
+	if bench.Baseline == "" {
+		return nil
+	}
+	baseline, err := ReadBenchBaseline(bench.Baseline)
+	if err != nil {
+		return fmt.Errorf("@bench: %w", err)
+	}
+	if previous, ok := baseline[bench.Name]; ok && previous.Mean > 0 {
+		change, regressed := stats.Compare(previous, bench.Threshold)
+		if regressed {
+			return fmt.Errorf("@bench %s: mean %s is %.1f%% slower than the baseline %s (threshold %d%%)",
+				bench.Name, roundBenchDuration(stats.Mean), change, roundBenchDuration(previous.Mean), bench.Threshold)
+		}
+		logger.Infof("@bench "+bench.Name, "mean %+.1f%% against the baseline %s", change, roundBenchDuration(previous.Mean))
+		return nil
+	}
+	baseline[bench.Name] = stats
+	if err := WriteBenchBaseline(bench.Baseline, baseline); err != nil {
+		return fmt.Errorf("@bench: failed to record the baseline: %w", err)
+	}
+	logger.Infof("@bench "+bench.Name, "recorded the baseline in %s", bench.Baseline)
+	return nil
+}
+
+// GenerateTemplate generates template for timing the block
+func (b *BenchDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	bench, err := b.extractParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("bench").Funcs(ctx.GetTemplateFunctions()).Parse(benchTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bench template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			benchParams
+			Content []ast.CommandContent
+		}{
+			benchParams: bench,
+			Content:     content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (b *BenchDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	bench, err := b.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	description := fmt.Sprintf("Time %d runs after %d warmup", bench.Runs, bench.Warmup)
+	element := plan.Decorator(b.Name()).
+		WithType("block").
+		WithParameter("runs", fmt.Sprintf("%d", bench.Runs)).
+		WithParameter("warmup", fmt.Sprintf("%d", bench.Warmup))
+	if bench.Baseline != "" {
+		description += fmt.Sprintf(", failing when %d%% slower than %s in %s", bench.Threshold, bench.Name, bench.Baseline)
+		element = element.
+			WithParameter("baseline", bench.Baseline).
+			WithParameter("threshold", fmt.Sprintf("%d", bench.Threshold))
+	}
+	element = element.WithDescription(description)
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractParams extracts and validates the bench parameters
+func (b *BenchDecorator) extractParams(params []ast.NamedParameter) (benchParams, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 5, b.Name()); err != nil {
+		return benchParams{}, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, b.ParameterSchema(), b.Name()); err != nil {
+		return benchParams{}, err
+	}
+	for _, limit := range []struct {
+		name     string
+		min, max int
+	}{{"runs", 1, 1000}, {"warmup", 0, 100}, {"threshold", 0, 1000}} {
+		if ast.FindParameter(params, limit.name) == nil {
+			continue
+		}
+		if err := decorators.ValidateIntegerRange(params, limit.name, limit.min, limit.max, b.Name()); err != nil {
+			return benchParams{}, err
+		}
+	}
+	if ast.FindParameter(params, "baseline") != nil {
+		if err := decorators.ValidatePathSafety(params, "baseline", b.Name()); err != nil {
+			return benchParams{}, err
+		}
+	}
+
+	bench := benchParams{
+		Runs:      ast.GetIntParam(params, "runs", 5),
+		Warmup:    ast.GetIntParam(params, "warmup", 1),
+		Name:      ast.GetStringParam(params, "name", "bench"),
+		Baseline:  ast.GetStringParam(params, "baseline", ""),
+		Threshold: ast.GetIntParam(params, "threshold", 10),
+	}
+	if bench.Name == "" {
+		return benchParams{}, fmt.Errorf("@bench name must not be empty")
+	}
+	return bench, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (b *BenchDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.TimeImports, []string{"encoding/json", "sort"})
+}
+
+// init registers the bench decorator
+func init() {
+	decorators.RegisterBlock(&BenchDecorator{})
+}
@@ -0,0 +1,122 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestBenchDecorator_Basic(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "runs.txt")
+	result := decoratortesting.NewDecoratorTest(t, &BenchDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.IntParam("runs", 3),
+			decoratortesting.IntParam("warmup", 2),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo run >> " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("for i := 1; i <= 2; i++", "make([]time.Duration, 0, 3)", "sort.Slice(durations").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("BenchDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := strings.Count(string(data), "run\n"); runs != 5 {
+		t.Errorf("block ran %d times in interpreter mode, want 5 (2 warmup + 3 timed)", runs)
+	}
+}
+
+func TestBenchDecorator_Baseline(t *testing.T) {
+	baselineFile := filepath.Join(t.TempDir(), "bench.json")
+	params := []ast.NamedParameter{
+		decoratortesting.IntParam("runs", 1),
+		decoratortesting.IntParam("warmup", 0),
+		decoratortesting.StringParam("name", "sleep"),
+		decoratortesting.StringParam("baseline", baselineFile),
+	}
+	content := []ast.CommandContent{decoratortesting.Shell("sleep 0.05")}
+
+	// The first run records the baseline
+	result := decoratortesting.NewDecoratorTest(t, &BenchDecorator{}).TestBlockDecorator(params, content)
+	if errors := decoratortesting.Assert(result).InterpreterSucceeds().GeneratorProducesValidGo().GeneratorCodeContains("json.Unmarshal(data, &baseline)").Validate(); len(errors) > 0 {
+		t.Fatalf("BenchDecorator first run failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	baseline, err := ReadBenchBaseline(baselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats, ok := baseline["sleep"]; !ok || stats.Runs != 1 || stats.Mean < 50*time.Millisecond {
+		t.Fatalf("recorded baseline = %+v, want one run of sleep of at least 50ms", baseline)
+	}
+
+	// A baseline much faster than the run is a regression
+	baseline["sleep"] = BenchStats{Runs: 1, Min: time.Millisecond, Mean: time.Millisecond, P95: time.Millisecond}
+	if err := WriteBenchBaseline(baselineFile, baseline); err != nil {
+		t.Fatal(err)
+	}
+	result = decoratortesting.NewDecoratorTest(t, &BenchDecorator{}).TestBlockDecorator(params, content)
+	if errors := decoratortesting.Assert(result).InterpreterFails("slower than the baseline").Validate(); len(errors) > 0 {
+		t.Errorf("BenchDecorator regression test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestBenchDecorator_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []ast.NamedParameter
+	}{
+		{"zero runs", []ast.NamedParameter{decoratortesting.IntParam("runs", 0)}},
+		{"negative warmup", []ast.NamedParameter{decoratortesting.IntParam("warmup", -1)}},
+		{"empty name", []ast.NamedParameter{decoratortesting.StringParam("name", "")}},
+		{"unknown parameter", []ast.NamedParameter{decoratortesting.IntParam("iterations", 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &BenchDecorator{}).
+				TestBlockDecorator(tt.params, []ast.CommandContent{decoratortesting.Shell("true")})
+			if errors := decoratortesting.Assert(result).InterpreterFails("").Validate(); len(errors) > 0 {
+				t.Errorf("BenchDecorator accepted invalid parameters:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestNewBenchStats(t *testing.T) {
+	var durations []time.Duration
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	stats := NewBenchStats(durations)
+	want := BenchStats{Runs: 20, Min: time.Millisecond, Mean: 10500 * time.Microsecond, P95: 19 * time.Millisecond}
+	if stats != want {
+		t.Errorf("NewBenchStats() = %+v, want %+v", stats, want)
+	}
+	if got := stats.String(); got != "20 runs: min 1ms, mean 10.5ms, p95 19ms" {
+		t.Errorf("String() = %q", got)
+	}
+
+	baseline := BenchStats{Mean: 10 * time.Millisecond}
+	if change, regressed := stats.Compare(baseline, 10); change != 5 || regressed {
+		t.Errorf("Compare() = %v, %v; want 5, false", change, regressed)
+	}
+	if _, regressed := stats.Compare(baseline, 4); !regressed {
+		t.Error("Compare() with a 4% threshold did not report a regression")
+	}
+}
@@ -0,0 +1,396 @@
+package decorators
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// Cache entries live in .devcmd/cache under the working directory, one file per @cache block
+// named by cacheKey, holding cacheFormat and the fingerprint of the block's inputs on their
+// own lines. Interpreted commands and generated CLIs read and write the same entries.
+const (
+	cacheDir    = ".devcmd/cache"
+	cacheFormat = "devcmd cache v1"
+)
+
+// cacheTemplate skips the block when its inputs are unchanged since its last successful run.
+// It mirrors cacheFingerprint, cacheOutputsExist and the matching of globFiles.
+const cacheTemplate = `// Cache: skip when {{.Label}} are unchanged
+{
+	hidden := func(name string) bool { return strings.HasPrefix(name, ".") }
+	var match func(pattern, name []string) bool
+	match = func(pattern, name []string) bool {
+		for len(pattern) > 0 {
+			if pattern[0] == "**" {
+				for i := 0; i <= len(name); i++ {
+					if i > 0 && hidden(name[i-1]) {
+						return false
+					}
+					if match(pattern[1:], name[i:]) {
+						return true
+					}
+				}
+				return false
+			}
+			if len(name) == 0 || (hidden(name[0]) && !hidden(pattern[0])) {
+				return false
+			}
+			if ok, _ := path.Match(pattern[0], name[0]); !ok {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		}
+		return len(name) == 0
+	}
+	glob := func(pattern string) []string {
+		segments := strings.Split(filepath.ToSlash(pattern), "/")
+		literal, recursive, dotted := 0, false, false
+		for i, segment := range segments {
+			if literal == i && segment != "**" && !strings.ContainsAny(segment, "*?[\\") {
+				literal++
+				continue
+			}
+			recursive = recursive || segment == "**"
+			dotted = dotted || hidden(segment)
+		}
+		base, rest := strings.Join(segments[:literal], "/"), segments[literal:]
+		if literal == 1 && base == "" {
+			base = "/"
+		}
+		root := filepath.FromSlash(base)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(ctx.Dir, root)
+		}
+		if root == "" {
+			root = "."
+		}
+		var matches []string
+		if len(rest) == 0 {
+			if _, err := os.Lstat(root); err == nil {
+				matches = append(matches, base)
+			}
+			return matches
+		}
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return nil
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if match(rest, parts) {
+				matches = append(matches, path.Join(base, filepath.ToSlash(rel)))
+			}
+			if d.IsDir() && ((!recursive && len(parts) >= len(rest)) || (hidden(d.Name()) && !dotted)) {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		return matches
+	}
+	resolve := func(name string) string {
+		name = filepath.FromSlash(name)
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(ctx.Dir, name)
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, pattern := range []string{ {{range .Inputs}}{{printf "%q" .}}, {{end}}} {
+		for _, name := range glob(pattern) {
+			if info, err := os.Stat(resolve(name)); err == nil && info.Mode().IsRegular() && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("@cache: no files match inputs %q", {{printf "%q" .InputList}})
+	}
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(resolve(name))
+		if err != nil {
+			return fmt.Errorf("@cache: %w", err)
+		}
+		fmt.Fprintf(hash, "%s\x00%x\n", name, sha256.Sum256(data))
+	}
+	entry := {{printf "%q" .CacheFormat}} + "\n" + fmt.Sprintf("%x", hash.Sum(nil)) + "\n"
+	entryFile := filepath.Join(ctx.Dir, filepath.FromSlash({{printf "%q" .CacheDir}}), {{printf "%q" .Key}})
+
+	upToDate := false
+	if previous, err := os.ReadFile(entryFile); err == nil && string(previous) == entry {
+		upToDate = true
+		for _, pattern := range []string{ {{range .Outputs}}{{printf "%q" .}}, {{end}}} {
+			if len(glob(pattern)) == 0 {
+				upToDate = false
+			}
+		}
+	}
+	if upToDate {
+		logf("info", "@cache", "inputs unchanged, skipping")
+	} else {
+		if err := func() error {
+{{range .Content}}			{{. | buildCommand}}
+{{end}}			return nil
+		}(); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(entryFile), 0o755); err == nil {
+			_ = os.WriteFile(entryFile, []byte(entry), 0o644)
+		}
+	}
+}`
+
+// CacheDecorator implements the @cache decorator for skipping blocks whose inputs are unchanged
+type CacheDecorator struct{}
+
+// Name returns the decorator name
+func (c *CacheDecorator) Name() string {
+	return "cache"
+}
+
+// Description returns a human-readable description
+func (c *CacheDecorator) Description() string {
+	return "Skip the block when its input files are unchanged since it last succeeded and its outputs exist"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (c *CacheDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "inputs",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Patterns of the files the block reads, as @glob matches them, separated by commas, e.g. \"src/**/*.go,go.mod\"",
+		},
+		{
+			Name:        "outputs",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Patterns of the files the block writes, which must exist for it to be skipped, e.g. \"bin/app\"",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block unless its inputs are unchanged in interpreter mode
+func (c *CacheDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	inputs, outputs, err := c.extractPatterns(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	dir := ctx.GetWorkingDir()
+	fingerprint, err := cacheFingerprint(dir, inputs)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@cache: %w", err)}
+	}
+	entry := cacheFormat + "\n" + fingerprint + "\n"
+	entryFile := filepath.Join(dir, filepath.FromSlash(cacheDir), cacheKey(inputs, outputs, content))
+
+	if previous, err := os.ReadFile(entryFile); err == nil && string(previous) == entry && cacheOutputsExist(dir, outputs) {
+		ctx.Logger().Infof("@cache", "inputs unchanged, skipping")
+		return &execution.ExecutionResult{Data: nil, Error: nil}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+	if err := commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	// A cache that can't be written only means the block runs again next time
+	if err := os.MkdirAll(filepath.Dir(entryFile), 0o755); err == nil {
+		_ = os.WriteFile(entryFile, []byte(entry), 0o644)
+	}
+	return &execution.ExecutionResult{Data: nil, Error: nil}
+}
+
+// GenerateTemplate generates template for running the block unless its inputs are unchanged
+func (c *CacheDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	inputs, outputs, err := c.extractPatterns(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("cache").Funcs(ctx.GetTemplateFunctions()).Parse(cacheTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cache template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Label       string
+			Inputs      []string
+			InputList   string
+			Outputs     []string
+			Key         string
+			CacheDir    string
+			CacheFormat string
+			Content     []ast.CommandContent
+		}{
+			Label:       strings.Join(inputs, ", "),
+			Inputs:      inputs,
+			InputList:   strings.Join(inputs, ","),
+			Outputs:     outputs,
+			Key:         cacheKey(inputs, outputs, content),
+			CacheDir:    cacheDir,
+			CacheFormat: cacheFormat,
+			Content:     content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (c *CacheDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	inputs, outputs, err := c.extractPatterns(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(c.Name()).
+		WithType("block").
+		WithParameter("inputs", strings.Join(inputs, ",")).
+		WithDescription("Skip when " + strings.Join(inputs, ", ") + " are unchanged since the last successful run")
+	if len(outputs) > 0 {
+		element = element.WithParameter("outputs", strings.Join(outputs, ","))
+	}
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractPatterns validates parameters and returns the input and output patterns
+func (c *CacheDecorator) extractPatterns(params []ast.NamedParameter) (inputs []string, outputs []string, err error) {
+	if err := decorators.ValidateParameterCount(params, 1, 2, c.Name()); err != nil {
+		return nil, nil, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, c.ParameterSchema(), c.Name()); err != nil {
+		return nil, nil, err
+	}
+	params, err = decorators.ResolvePositionalParameters(params, c.ParameterSchema())
+	if err != nil {
+		return nil, nil, fmt.Errorf("@cache: %w", err)
+	}
+
+	inputs = splitPatterns(ast.GetStringParam(params, "inputs", ""))
+	if len(inputs) == 0 {
+		return nil, nil, fmt.Errorf("@cache requires at least one input pattern")
+	}
+	outputs = splitPatterns(ast.GetStringParam(params, "outputs", ""))
+	for _, pattern := range append(append([]string{}, inputs...), outputs...) {
+		if err := validateGlobPattern(pattern); err != nil {
+			return nil, nil, fmt.Errorf("@cache: %w", err)
+		}
+	}
+	return inputs, outputs, nil
+}
+
+// splitPatterns splits a comma-separated list of patterns, dropping empty ones
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// cacheKey names the cache entry of a block from its patterns and its commands as written, so
+// editing either starts a new entry
+func cacheKey(inputs, outputs []string, content []ast.CommandContent) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00", strings.Join(inputs, ","), strings.Join(outputs, ","))
+	for _, item := range content {
+		fmt.Fprintf(hash, "%s\n", item.String())
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))[:16]
+}
+
+// cacheFingerprint hashes the regular files matching patterns under dir: each file's path as
+// matched, with slashes, and the SHA-256 of its contents, in path order. It fails when no file
+// matches, which is more likely a mistyped pattern than a block without inputs.
+func cacheFingerprint(dir string, patterns []string) (string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := globFiles(dir, pattern, false)
+		if err != nil {
+			return "", err
+		}
+		for _, match := range matches {
+			name := filepath.ToSlash(match)
+			if info, err := os.Stat(resolveCachePath(dir, name)); err == nil && info.Mode().IsRegular() && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files match inputs %q", strings.Join(patterns, ","))
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(resolveCachePath(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%x\n", name, sha256.Sum256(data))
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// cacheOutputsExist reports whether every output pattern matches at least one file
+func cacheOutputsExist(dir string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matches, err := globFiles(dir, pattern, false); err != nil || len(matches) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveCachePath returns the file a matched path names, relative to dir unless it is absolute
+func resolveCachePath(dir, name string) string {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (c *CacheDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"crypto/sha256", "io/fs", "path", "path/filepath", "sort"})
+}
+
+// init registers the cache decorator
+func init() {
+	decorators.RegisterBlock(&CacheDecorator{})
+}
@@ -0,0 +1,146 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestCacheFingerprint(t *testing.T) {
+	root := createGlobTree(t, "src/main.go", "src/api/handler.go", "README.md")
+
+	first, err := cacheFingerprint(root, []string{"src/**/*.go"})
+	if err != nil {
+		t.Fatalf("cacheFingerprint failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cacheFingerprint(root, []string{"src/**/*.go"}); again != first {
+		t.Error("fingerprint changed with a file outside the inputs")
+	}
+	if again, _ := cacheFingerprint(root, []string{"src/**/*.go", "src/main.go"}); again != first {
+		t.Error("fingerprint changed with a file matched twice")
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "api", "handler.go"), []byte("package api"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cacheFingerprint(root, []string{"src/**/*.go"}); again == first {
+		t.Error("fingerprint unchanged after an input changed")
+	}
+
+	if _, err := cacheFingerprint(root, []string{"lib/**/*.go"}); err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Errorf("err = %v, want no matching inputs", err)
+	}
+}
+
+func TestCacheDecorator_SkipsUnchanged(t *testing.T) {
+	root := createGlobTree(t, "src/main.go")
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithWorkingDir(root)
+	params := []ast.NamedParameter{
+		decoratortesting.StringParam("inputs", "src/**/*.go"),
+		decoratortesting.StringParam("outputs", "bin/app"),
+	}
+	content := []ast.CommandContent{decoratortesting.Shell("mkdir -p bin && echo run >> bin/app")}
+
+	run := func() {
+		t.Helper()
+		if result := (&CacheDecorator{}).ExecuteInterpreter(ctx, params, content); result.Error != nil {
+			t.Fatalf("ExecuteInterpreter failed: %v", result.Error)
+		}
+	}
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(root, "bin", "app"))
+		return strings.Count(string(data), "run")
+	}
+
+	run()
+	run()
+	if got := runs(); got != 1 {
+		t.Errorf("block ran %d times with unchanged inputs, want 1", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	if got := runs(); got != 2 {
+		t.Errorf("block ran %d times after an input changed, want 2", got)
+	}
+
+	// A missing output runs the block again
+	if err := os.Remove(filepath.Join(root, "bin", "app")); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	if got := runs(); got != 1 {
+		t.Errorf("block ran %d times after its output was removed, want 1", got)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, ".devcmd", "cache"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache entries = %v (%v), want one", entries, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, ".devcmd", "cache", entries[0].Name()))
+	if !strings.HasPrefix(string(data), cacheFormat+"\n") {
+		t.Errorf("cache entry = %q, want it to start with %q", data, cacheFormat)
+	}
+}
+
+func TestCacheDecorator_FailureIsNotCached(t *testing.T) {
+	root := createGlobTree(t, "src/main.go")
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithWorkingDir(root)
+	params := []ast.NamedParameter{decoratortesting.StringParam("inputs", "src/*.go")}
+
+	result := (&CacheDecorator{}).ExecuteInterpreter(ctx, params, []ast.CommandContent{decoratortesting.Shell("exit 1")})
+	if result.Error == nil {
+		t.Fatal("failing block succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(root, ".devcmd", "cache")); !os.IsNotExist(err) {
+		t.Errorf("failed block left a cache entry (%v)", err)
+	}
+}
+
+func TestCacheDecorator_Generate(t *testing.T) {
+	params := []ast.NamedParameter{
+		decoratortesting.StringParam("inputs", "src/**/*.go, go.mod"),
+		decoratortesting.StringParam("outputs", "bin/app"),
+	}
+	content := []ast.CommandContent{decoratortesting.Shell("go build -o bin/app ./src")}
+
+	result := decoratortesting.NewDecoratorTest(t, &CacheDecorator{}).
+		TestBlockDecorator(params, content)
+
+	errors := decoratortesting.Assert(result).
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(
+			`[]string{ "src/**/*.go", "go.mod", }`,
+			`"devcmd cache v1"`,
+			`"`+cacheKey([]string{"src/**/*.go", "go.mod"}, []string{"bin/app"}, content)+`"`,
+		).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("CacheDecorator generate test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestCacheDecorator_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"no inputs":       {decoratortesting.StringParam("inputs", " , ")},
+		"invalid pattern": {decoratortesting.StringParam("inputs", "src/[.go")},
+	} {
+		if _, _, err := (&CacheDecorator{}).extractPatterns(params); err == nil {
+			t.Errorf("%s: extractPatterns succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package decorators
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+const (
+	// ChaosEnvVar turns @fail-randomly on when set to anything but "", "0" or "false"; without
+	// it, blocks run as written
+	ChaosEnvVar = "DEVCMD_CHAOS"
+	// ChaosSeedEnvVar seeds the random choices of @fail-randomly so a run can be repeated
+	ChaosSeedEnvVar = "DEVCMD_CHAOS_SEED"
+)
+
+var (
+	chaosMu      sync.Mutex
+	chaosSources = make(map[uint64]*rand.Rand)
+)
+
+// chaosTemplate injects the delay and failure in generated CLIs. It mirrors injectChaos.
+const chaosTemplate = `// Chaos: fail {{.Rate}} of the time{{if .Delay}}, delay up to {{.DelayDuration}}{{end}} when {{.EnvVar}} is set
+{
+	if chaos := os.Getenv({{printf "%q" .EnvVar}}); chaos != "" && chaos != "0" && chaos != "false" {
+		random := rand.Float64
+		if seed, err := strconv.ParseUint(os.Getenv({{printf "%q" .SeedEnvVar}}), 10, 64); err == nil {
+			source := rand.New(rand.NewPCG(seed, 0))
+			random = source.Float64
+			// The next block continues the sequence rather than repeating its choices
+			os.Setenv({{printf "%q" .SeedEnvVar}}, strconv.FormatUint(source.Uint64(), 10))
+		}
+{{- if .Delay}}
+		wait := time.Duration(random() * float64({{.Delay | formatDuration}}))
+		logf("info", "@fail-randomly", "delaying %s", wait.Round(time.Millisecond))
+		time.Sleep(wait)
+{{- end}}
+		if random() < {{.Rate}} {
+			logf("warn", "@fail-randomly", "injecting a failure")
+			return fmt.Errorf("@fail-randomly: injected failure ({{.EnvVar}} is set)")
+		}
+	}
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// chaosParams holds the parameters of @fail-randomly
+type chaosParams struct {
+	Rate  float64
+	Delay time.Duration
+}
+
+// FailRandomlyDecorator implements the @fail-randomly decorator for injecting failures and
+// delays into a block while testing how a pipeline copes with them
+type FailRandomlyDecorator struct{}
+
+// Name returns the decorator name
+func (f *FailRandomlyDecorator) Name() string {
+	return "fail-randomly"
+}
+
+// Description returns a human-readable description
+func (f *FailRandomlyDecorator) Description() string {
+	return "Inject random failures and delays into the block when DEVCMD_CHAOS is set, to test failure handling"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (f *FailRandomlyDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "rate",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Probability from 0 to 1 that the block fails instead of running (default: 0.5)",
+		},
+		{
+			Name:        "delay",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "Longest random delay before the block (default: none)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block, possibly after a delay or not at all, in interpreter mode
+func (f *FailRandomlyDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	chaos, err := f.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	if err := injectChaos(ctx, chaos); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content),
+	}
+}
+
+// injectChaos sleeps for a random part of the delay and fails at the rate, when
+// DEVCMD_CHAOS turns chaos on
+func injectChaos(ctx execution.InterpreterContext, chaos chaosParams) error {
+	if value, _ := ctx.GetEnv(ChaosEnvVar); value == "" || value == "0" || value == "false" {
+		return nil
+	}
+	random := rand.Float64
+	if value, _ := ctx.GetEnv(ChaosSeedEnvVar); value != "" {
+		if seed, err := strconv.ParseUint(value, 10, 64); err == nil {
+			random = seededChaos(seed)
+		}
+	}
+
+	if chaos.Delay > 0 {
+		wait := time.Duration(random() * float64(chaos.Delay))
+		ctx.Logger().Infof("@fail-randomly", "delaying %s", wait.Round(time.Millisecond))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if random() < chaos.Rate {
+		ctx.Logger().Warnf("@fail-randomly", "injecting a failure")
+		return fmt.Errorf("@fail-randomly: injected failure (%s is set)", ChaosEnvVar)
+	}
+	return nil
+}
+
+// seededChaos returns the random source for a seed, shared by every block of the run so that
+// blocks and retries continue one repeatable sequence instead of each repeating its start
+func seededChaos(seed uint64) func() float64 {
+	return func() float64 {
+		chaosMu.Lock()
+		defer chaosMu.Unlock()
+		source, ok := chaosSources[seed]
+		if !ok {
+			source = rand.New(rand.NewPCG(seed, 0))
+			chaosSources[seed] = source
+		}
+		return source.Float64()
+	}
+}
+
+// GenerateTemplate generates template for injecting chaos before the block
+func (f *FailRandomlyDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	chaos, err := f.extractParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("fail-randomly").Funcs(ctx.GetTemplateFunctions()).Parse(chaosTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fail-randomly template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Rate          string
+			Delay         time.Duration
+			DelayDuration string
+			EnvVar        string
+			SeedEnvVar    string
+			Content       []ast.CommandContent
+		}{
+			Rate:          strconv.FormatFloat(chaos.Rate, 'f', -1, 64),
+			Delay:         chaos.Delay,
+			DelayDuration: chaos.Delay.String(),
+			EnvVar:        ChaosEnvVar,
+			SeedEnvVar:    ChaosSeedEnvVar,
+			Content:       content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (f *FailRandomlyDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	chaos, err := f.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	description := fmt.Sprintf("When %s is set, fail %s of the time", ChaosEnvVar, strconv.FormatFloat(chaos.Rate, 'f', -1, 64))
+	element := plan.Decorator(f.Name()).
+		WithType("block").
+		WithParameter("rate", strconv.FormatFloat(chaos.Rate, 'f', -1, 64))
+	if chaos.Delay > 0 {
+		description += fmt.Sprintf(" and delay up to %s", chaos.Delay)
+		element = element.WithParameter("delay", chaos.Delay.String())
+	}
+	element = element.WithDescription(description)
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractParams validates parameters and returns the failure rate and delay
+func (f *FailRandomlyDecorator) extractParams(params []ast.NamedParameter) (chaosParams, error) {
+	chaos := chaosParams{Rate: 0.5}
+	if err := decorators.ValidateParameterCount(params, 0, 2, f.Name()); err != nil {
+		return chaos, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, f.ParameterSchema(), f.Name()); err != nil {
+		return chaos, err
+	}
+
+	if param := ast.FindParameter(params, "rate"); param != nil {
+		num, ok := param.Value.(*ast.NumberLiteral)
+		if !ok {
+			return chaos, fmt.Errorf("@%s: rate must be a number", f.Name())
+		}
+		rate, err := strconv.ParseFloat(num.Value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return chaos, fmt.Errorf("@%s: rate must be a probability from 0 to 1", f.Name())
+		}
+		chaos.Rate = rate
+	}
+
+	if ast.FindParameter(params, "delay") != nil {
+		if err := decorators.ValidateDuration(params, "delay", time.Millisecond, time.Hour, f.Name()); err != nil {
+			return chaos, err
+		}
+		chaos.Delay = ast.GetDurationParam(params, "delay", 0)
+	}
+
+	return chaos, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (f *FailRandomlyDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.TimeImports, []string{"math/rand/v2", "strconv"})
+}
+
+// init registers the fail-randomly decorator
+func init() {
+	decorators.RegisterBlock(&FailRandomlyDecorator{})
+}
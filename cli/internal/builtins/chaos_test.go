@@ -0,0 +1,94 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func numberParam(name, value string) ast.NamedParameter {
+	return ast.NamedParameter{Name: name, Value: &ast.NumberLiteral{Value: value}}
+}
+
+func TestFailRandomlyDecorator_Disabled(t *testing.T) {
+	t.Setenv(ChaosEnvVar, "")
+	out := filepath.Join(t.TempDir(), "ran")
+	result := decoratortesting.NewDecoratorTest(t, &FailRandomlyDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			numberParam("rate", "1"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("touch " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`os.Getenv("DEVCMD_CHAOS")`, "if random() < 1 {").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("FailRandomlyDecorator disabled test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("block did not run without %s: %v", ChaosEnvVar, err)
+	}
+}
+
+func TestFailRandomlyDecorator_Enabled(t *testing.T) {
+	t.Setenv(ChaosEnvVar, "1")
+	out := filepath.Join(t.TempDir(), "ran")
+	content := []ast.CommandContent{decoratortesting.Shell("touch " + out)}
+
+	result := decoratortesting.NewDecoratorTest(t, &FailRandomlyDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			numberParam("rate", "1"),
+			{Name: "delay", Value: &ast.DurationLiteral{Value: "10ms"}},
+		}, content)
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("injected failure").
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("time.Sleep(wait)").
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("FailRandomlyDecorator failure test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("block ran despite an injected failure: %v", err)
+	}
+
+	result = decoratortesting.NewDecoratorTest(t, &FailRandomlyDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{numberParam("rate", "0")}, content)
+	if errors := decoratortesting.Assert(result).InterpreterSucceeds().Validate(); len(errors) > 0 {
+		t.Errorf("FailRandomlyDecorator with rate 0 failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("block did not run with rate 0: %v", err)
+	}
+}
+
+func TestFailRandomlyDecorator_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []ast.NamedParameter
+	}{
+		{"rate above one", []ast.NamedParameter{numberParam("rate", "1.5")}},
+		{"negative rate", []ast.NamedParameter{numberParam("rate", "-0.1")}},
+		{"string rate", []ast.NamedParameter{decoratortesting.StringParam("rate", "half")}},
+		{"unknown parameter", []ast.NamedParameter{decoratortesting.IntParam("seed", 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &FailRandomlyDecorator{}).
+				TestBlockDecorator(tt.params, []ast.CommandContent{decoratortesting.Shell("true")})
+			if errors := decoratortesting.Assert(result).InterpreterFails("").Validate(); len(errors) > 0 {
+				t.Errorf("FailRandomlyDecorator accepted invalid parameters:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// cloudEnvVar is an environment variable set for the duration of a cloud scope
+type cloudEnvVar struct {
+	Name  string
+	Value string
+}
+
+// cloudScope describes the environment and credential checks for a cloud block decorator
+type cloudScope struct {
+	Decorator string        // Decorator name, used to prefix errors
+	Label     string        // Human-readable scope, e.g. AWS profile "dev"
+	Env       []cloudEnvVar // Variables set for the block
+	Validate  bool          // Verify credentials before running the block
+	Login     bool          // Run the login command interactively if verification fails
+	Check     []string      // Command that succeeds only with valid credentials
+	LoginCmd  []string      // Interactive login command
+}
+
+// loginHint returns the command users should run to refresh credentials
+func (s *cloudScope) loginHint() string {
+	return strings.Join(s.LoginCmd, " ")
+}
+
+// cloudScopeTemplate sets the scope's environment on a cloned context, verifies
+// credentials, and runs the block. It mirrors executeCloudScope.
+const cloudScopeTemplate = `// Scope {{.Label}}
+{
+	ctx := ctx.Clone()
+{{range .Env}}	ctx.Env[{{printf "%q" .Name}}] = {{printf "%q" .Value}}
+{{end}}{{if .Validate}}
+	cloudCmd := func(args ...string) *execpkg.Cmd {
+		cmd := execpkg.Command(args[0], args[1:]...)
+		cmd.Dir = ctx.Dir
+		cmd.Env = os.Environ()
+		for k, v := range ctx.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		return cmd
+	}
+	checkCredentials := func() error {
+		// Output discards stdout, which may contain tokens, and captures stderr in the error
+		_, err := cloudCmd({{range $i, $arg := .Check}}{{if $i}}, {{end}}{{printf "%q" $arg}}{{end}}).Output()
+		if exitErr, ok := err.(*execpkg.ExitError); ok && len(exitErr.Stderr) > 0 {
+			msg := exitErr.Stderr
+			for len(msg) > 0 && (msg[len(msg)-1] == '\n' || msg[len(msg)-1] == '\r') {
+				msg = msg[:len(msg)-1]
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	if _, err := execpkg.LookPath({{printf "%q" (index .Check 0)}}); err != nil {
+		return fmt.Errorf("@{{.Decorator}}: %s CLI not found in PATH", {{printf "%q" (index .Check 0)}})
+	}
+	if err := checkCredentials(); err != nil {
+{{if .Login}}		logf("warn", "@{{.Decorator}}", "credentials for %s are not valid, running %s", {{printf "%q" .Label}}, {{printf "%q" .LoginHint}})
+		login := cloudCmd({{range $i, $arg := .LoginCmd}}{{if $i}}, {{end}}{{printf "%q" $arg}}{{end}})
+		login.Stdin, login.Stdout, login.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := login.Run(); err != nil {
+			return fmt.Errorf("@{{.Decorator}}: %s failed: %w", {{printf "%q" .LoginHint}}, err)
+		}
+		if err := checkCredentials(); err != nil {
+			return fmt.Errorf("@{{.Decorator}}: credentials for %s are still not valid after login: %w", {{printf "%q" .Label}}, err)
+		}
+{{else}}		return fmt.Errorf("@{{.Decorator}}: credentials for %s are not valid: %w (run '%s' or set login=true)", {{printf "%q" .Label}}, err, {{printf "%q" .LoginHint}})
+{{end}}	}
+{{end}}
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// executeCloudScope verifies credentials and runs the block with the scope's environment
+func executeCloudScope(ctx execution.InterpreterContext, scope *cloudScope, content []ast.CommandContent) *execution.ExecutionResult {
+	scopeCtx := ctx.Child()
+	for _, v := range scope.Env {
+		scopeCtx.ExportEnv(v.Name, v.Value)
+	}
+
+	if scope.Validate {
+		if err := verifyCloudCredentials(scopeCtx, scope); err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: err}
+		}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(scopeCtx, content),
+	}
+}
+
+// verifyCloudCredentials runs the scope's check command, logging in first if allowed
+func verifyCloudCredentials(ctx execution.InterpreterContext, scope *cloudScope) error {
+	if _, err := exec.LookPath(scope.Check[0]); err != nil {
+		return fmt.Errorf("%s CLI not found in PATH", scope.Check[0])
+	}
+
+	err := runCloudCheck(ctx, scope)
+	if err == nil {
+		return nil
+	}
+	if !scope.Login {
+		return fmt.Errorf("credentials for %s are not valid: %w (run '%s' or set login=true)", scope.Label, err, scope.loginHint())
+	}
+
+	ctx.Logger().Warnf("@"+scope.Decorator, "credentials for %s are not valid, running %s", scope.Label, scope.loginHint())
+	login := cloudCommand(ctx, scope, scope.LoginCmd)
+	login.Stdin, login.Stdout, login.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := login.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", scope.loginHint(), err)
+	}
+
+	if err := runCloudCheck(ctx, scope); err != nil {
+		return fmt.Errorf("credentials for %s are still not valid after login: %w", scope.Label, err)
+	}
+	return nil
+}
+
+// runCloudCheck runs the credential check, discarding stdout since it may contain tokens
+func runCloudCheck(ctx execution.InterpreterContext, scope *cloudScope) error {
+	var stderr strings.Builder
+	check := cloudCommand(ctx, scope, scope.Check)
+	check.Stderr = &stderr
+	if err := check.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// cloudCommand builds a CLI command running with the scope's environment
+func cloudCommand(ctx execution.InterpreterContext, scope *cloudScope, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = ctx.GetWorkingDir()
+	cmd.Env = os.Environ()
+	for _, v := range scope.Env {
+		cmd.Env = append(cmd.Env, v.Name+"="+v.Value)
+	}
+	return cmd
+}
+
+// generateCloudScopeTemplate returns the block template for a cloud scope
+func generateCloudScopeTemplate(ctx execution.GeneratorContext, scope *cloudScope, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	tmpl, err := template.New(scope.Decorator).Funcs(ctx.GetTemplateFunctions()).Parse(cloudScopeTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", scope.Decorator, err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			*cloudScope
+			LoginHint string
+			Content   []ast.CommandContent
+		}{
+			cloudScope: scope,
+			LoginHint:  scope.loginHint(),
+			Content:    content,
+		},
+	}, nil
+}
+
+// planCloudScope creates a plan element showing the scope's environment without contacting the provider
+func planCloudScope(ctx execution.PlanContext, scope *cloudScope, content []ast.CommandContent) *execution.ExecutionResult {
+	description := scope.Label
+	if scope.Validate {
+		description += ", verify credentials"
+		if scope.Login {
+			description += " (login if needed)"
+		}
+	}
+
+	element := plan.Decorator(scope.Decorator).
+		WithType("block").
+		WithDescription(description)
+	for _, v := range scope.Env {
+		if v.Value != "" {
+			element = element.WithParameter(v.Name, v.Value)
+		}
+	}
+
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// cloudScopeImports returns the imports needed by cloudScopeTemplate
+func cloudScopeImports() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"os/exec"})
+}
+
+// AWSProfileDecorator implements the @aws-profile decorator for scoping AWS credentials
+type AWSProfileDecorator struct{}
+
+// Name returns the decorator name
+func (a *AWSProfileDecorator) Name() string {
+	return "aws-profile"
+}
+
+// Description returns a human-readable description
+func (a *AWSProfileDecorator) Description() string {
+	return "Run the block with an AWS profile and region, verifying credentials first"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (a *AWSProfileDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "profile",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "AWS profile name from ~/.aws/config",
+		},
+		{
+			Name:        "region",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "AWS region for the block (default: the profile's region)",
+		},
+		{
+			Name:        "validate",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Verify credentials with 'aws sts get-caller-identity' before running (default: true)",
+		},
+		{
+			Name:        "login",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Run 'aws sso login' interactively when credentials are missing or expired (default: false)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block with the AWS profile in interpreter mode
+func (a *AWSProfileDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := a.extractScope(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return executeCloudScope(ctx, scope, content)
+}
+
+// GenerateTemplate generates template for running the block with the AWS profile
+func (a *AWSProfileDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	scope, err := a.extractScope(params)
+	if err != nil {
+		return nil, err
+	}
+	return generateCloudScopeTemplate(ctx, scope, content)
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (a *AWSProfileDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := a.extractScope(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return planCloudScope(ctx, scope, content)
+}
+
+// extractScope validates parameters and returns the AWS scope they describe
+func (a *AWSProfileDecorator) extractScope(params []ast.NamedParameter) (*cloudScope, error) {
+	if err := decorators.ValidateSchemaCompliance(params, a.ParameterSchema(), a.Name()); err != nil {
+		return nil, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, a.ParameterSchema())
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", a.Name(), err)
+	}
+
+	profile := ast.GetStringParam(params, "profile", "")
+	region := ast.GetStringParam(params, "region", "")
+	if profile == "" || strings.ContainsAny(profile, " \t\n") {
+		return nil, fmt.Errorf("@%s: invalid profile %q", a.Name(), profile)
+	}
+
+	label := fmt.Sprintf("AWS profile %q", profile)
+	env := []cloudEnvVar{
+		{Name: "AWS_PROFILE", Value: profile},
+		// Static keys take precedence over AWS_PROFILE, so clear them for the block
+		{Name: "AWS_ACCESS_KEY_ID"},
+		{Name: "AWS_SECRET_ACCESS_KEY"},
+		{Name: "AWS_SESSION_TOKEN"},
+	}
+	if region != "" {
+		label += fmt.Sprintf(" in %s", region)
+		env = append(env, cloudEnvVar{Name: "AWS_REGION", Value: region}, cloudEnvVar{Name: "AWS_DEFAULT_REGION", Value: region})
+	}
+
+	return &cloudScope{
+		Decorator: a.Name(),
+		Label:     label,
+		Env:       env,
+		Validate:  ast.GetBoolParam(params, "validate", true),
+		Login:     ast.GetBoolParam(params, "login", false),
+		Check:     []string{"aws", "sts", "get-caller-identity"},
+		LoginCmd:  []string{"aws", "sso", "login", "--profile", profile},
+	}, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (a *AWSProfileDecorator) ImportRequirements() decorators.ImportRequirement {
+	return cloudScopeImports()
+}
+
+// GCPProjectDecorator implements the @gcp-project decorator for scoping gcloud configuration
+type GCPProjectDecorator struct{}
+
+// Name returns the decorator name
+func (g *GCPProjectDecorator) Name() string {
+	return "gcp-project"
+}
+
+// Description returns a human-readable description
+func (g *GCPProjectDecorator) Description() string {
+	return "Run the block against a GCP project and gcloud configuration, verifying credentials first"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GCPProjectDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "project",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "GCP project ID",
+		},
+		{
+			Name:        "config",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "gcloud named configuration to activate for the block",
+		},
+		{
+			Name:        "validate",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Verify credentials with 'gcloud auth print-access-token' before running (default: true)",
+		},
+		{
+			Name:        "login",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Run 'gcloud auth login' interactively when credentials are missing or expired (default: false)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block with the GCP project in interpreter mode
+func (g *GCPProjectDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := g.extractScope(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return executeCloudScope(ctx, scope, content)
+}
+
+// GenerateTemplate generates template for running the block with the GCP project
+func (g *GCPProjectDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	scope, err := g.extractScope(params)
+	if err != nil {
+		return nil, err
+	}
+	return generateCloudScopeTemplate(ctx, scope, content)
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (g *GCPProjectDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := g.extractScope(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return planCloudScope(ctx, scope, content)
+}
+
+// extractScope validates parameters and returns the GCP scope they describe
+func (g *GCPProjectDecorator) extractScope(params []ast.NamedParameter) (*cloudScope, error) {
+	if err := decorators.ValidateSchemaCompliance(params, g.ParameterSchema(), g.Name()); err != nil {
+		return nil, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, g.ParameterSchema())
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", g.Name(), err)
+	}
+
+	project := ast.GetStringParam(params, "project", "")
+	config := ast.GetStringParam(params, "config", "")
+	if project == "" || strings.ContainsAny(project, " \t\n") {
+		return nil, fmt.Errorf("@%s: invalid project %q", g.Name(), project)
+	}
+
+	label := fmt.Sprintf("GCP project %q", project)
+	env := []cloudEnvVar{
+		{Name: "CLOUDSDK_CORE_PROJECT", Value: project},
+		{Name: "GOOGLE_CLOUD_PROJECT", Value: project},
+	}
+	if config != "" {
+		label += fmt.Sprintf(" (gcloud config %q)", config)
+		env = append(env, cloudEnvVar{Name: "CLOUDSDK_ACTIVE_CONFIG_NAME", Value: config})
+	}
+
+	return &cloudScope{
+		Decorator: g.Name(),
+		Label:     label,
+		Env:       env,
+		Validate:  ast.GetBoolParam(params, "validate", true),
+		Login:     ast.GetBoolParam(params, "login", false),
+		Check:     []string{"gcloud", "auth", "print-access-token", "--quiet"},
+		LoginCmd:  []string{"gcloud", "auth", "login"},
+	}, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GCPProjectDecorator) ImportRequirements() decorators.ImportRequirement {
+	return cloudScopeImports()
+}
+
+// init registers the cloud credential decorators
+func init() {
+	decorators.RegisterBlock(&AWSProfileDecorator{})
+	decorators.RegisterBlock(&GCPProjectDecorator{})
+}
@@ -0,0 +1,205 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeCLI puts an executable shell script named name at the front of PATH
+func installFakeCLI(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", name, err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestAWSProfileDecorator_SetsEnvironment(t *testing.T) {
+	installFakeCLI(t, "aws", `[ "$AWS_PROFILE" = "dev" ] || { echo "The config profile ($AWS_PROFILE) could not be found" >&2; exit 255; }`)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIASTATICKEY")
+
+	out := filepath.Join(t.TempDir(), "env.txt")
+	result := decoratortesting.NewDecoratorTest(t, &AWSProfileDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("profile", "dev"),
+			decoratortesting.StringParam("region", "eu-west-1"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell(`echo "$AWS_PROFILE $AWS_REGION [$AWS_ACCESS_KEY_ID]" > ` + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorCodeContains(`ctx.Env["AWS_PROFILE"] = "dev"`, `ctx.Env["AWS_DEFAULT_REGION"] = "eu-west-1"`, `"sts", "get-caller-identity"`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AWSProfileDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "dev eu-west-1 []" {
+		t.Errorf("block environment = %q, want profile, region, and cleared static keys", strings.TrimSpace(string(got)))
+	}
+}
+
+func TestAWSProfileDecorator_InvalidCredentials(t *testing.T) {
+	installFakeCLI(t, "aws", `echo "Error when retrieving token from sso: Token has expired" >&2; exit 255`)
+
+	result := decoratortesting.NewDecoratorTest(t, &AWSProfileDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("profile", "prod"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo should not run"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("Token has expired (run 'aws sso login --profile prod' or set login=true)").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AWSProfileDecorator invalid credentials test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestAWSProfileDecorator_LoginWhenExpired(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "logged-in")
+	installFakeCLI(t, "aws", `
+case "$1" in
+sso) touch `+marker+` ;;
+sts) [ -f `+marker+` ] || { echo "Token has expired" >&2; exit 255; } ;;
+esac`)
+
+	result := decoratortesting.NewDecoratorTest(t, &AWSProfileDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("profile", "dev"),
+			decoratortesting.BoolParam("login", true),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("true"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorCodeContains(`cloudCmd("aws", "sso", "login", "--profile", "dev")`).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AWSProfileDecorator login test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected aws sso login to run: %v", err)
+	}
+}
+
+func TestAWSProfileDecorator_SkipValidation(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	result := decoratortesting.NewDecoratorTest(t, &AWSProfileDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("profile", "dev"),
+			decoratortesting.BoolParam("validate", false),
+		}, []ast.CommandContent{})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AWSProfileDecorator skip validation test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if code, ok := result.GeneratorResult.Data.(string); ok && strings.Contains(code, "get-caller-identity") {
+		t.Error("generated code should not verify credentials when validate=false")
+	}
+}
+
+func TestGCPProjectDecorator_SetsEnvironment(t *testing.T) {
+	installFakeCLI(t, "gcloud", `echo "ya29.secret-token"`)
+
+	out := filepath.Join(t.TempDir(), "env.txt")
+	result := decoratortesting.NewDecoratorTest(t, &GCPProjectDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("project", "my-project"),
+			decoratortesting.StringParam("config", "staging"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell(`echo "$CLOUDSDK_CORE_PROJECT $CLOUDSDK_ACTIVE_CONFIG_NAME" > ` + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorCodeContains(`ctx.Env["CLOUDSDK_CORE_PROJECT"] = "my-project"`, `"auth", "print-access-token"`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GCPProjectDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "my-project staging" {
+		t.Errorf("block environment = %q, want \"my-project staging\"", strings.TrimSpace(string(got)))
+	}
+}
+
+func TestGCPProjectDecorator_MissingCLI(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	result := decoratortesting.NewDecoratorTest(t, &GCPProjectDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("project", "my-project"),
+		}, []ast.CommandContent{})
+
+	// Generation doesn't depend on the CLI being installed
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("gcloud CLI not found").
+		GeneratorSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GCPProjectDecorator missing CLI test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestCloudDecorators_InvalidParameters(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &AWSProfileDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("profile", "has space"),
+		}, []ast.CommandContent{})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("invalid profile").
+		GeneratorFails("invalid profile").
+		PlanFails("invalid profile").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AWSProfileDecorator invalid parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	result = decoratortesting.NewDecoratorTest(t, &GCPProjectDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{}, []ast.CommandContent{})
+
+	errors = decoratortesting.Assert(result).
+		InterpreterFails("project").
+		GeneratorFails("project").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GCPProjectDecorator invalid parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
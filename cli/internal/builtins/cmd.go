@@ -60,7 +60,7 @@ func (d *CmdDecorator) GenerateTemplate(ctx execution.GeneratorContext, params [
 	return d.generateTemplateImpl(ctx, params)
 }
 
-// ExpandPlan creates a plan element for the command reference
+// ExpandPlan describes the command reference inline for plans
 func (d *CmdDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
 	return d.ExecutePlan(ctx, params)
 }
@@ -103,6 +103,26 @@ func (d *CmdDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, para
 		}
 	}
 
+	// A command with parameters runs with their defaults
+	if len(command.Params) > 0 {
+		defaults, err := paramDefaults(command)
+		if err != nil {
+			return &execution.ExecutionResult{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		ctx = ctx.WithParams(defaults)
+	}
+
+	// The commands it needs run first, unless they already ran in this invocation
+	if err := ctx.RunNeeds(command); err != nil {
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
 	// Execute the command's content directly using the context's ExecuteCommandContent method
 	// This properly handles all command content types: ShellContent, BlockDecorators, etc.
 	for _, content := range command.Body.Content {
@@ -128,8 +148,18 @@ func (d *CmdDecorator) generateTemplateImpl(ctx execution.GeneratorContext, para
 		return nil, err
 	}
 
-	// Create template for function call that returns CommandResult
+	// Create template for function call that returns CommandResult, passing a command with
+	// parameters their defaults
 	tmplStr := `execute{{.FunctionName}}(ctx)`
+	var defaults map[string]string
+	for _, cmd := range ctx.GetProgram().Commands {
+		if cmd.Name == cmdName && len(cmd.Params) > 0 {
+			if defaults, err = paramDefaults(&cmd); err != nil {
+				return nil, err
+			}
+			tmplStr = `execute{{.FunctionName}}(ctx.WithParams(map[string]string{ {{range $name, $value := .Defaults}}{{printf "%q" $name}}: {{printf "%q" $value}}, {{end}}}))`
+		}
+	}
 
 	// Parse template
 	tmpl, err := template.New("cmd").Parse(tmplStr)
@@ -142,14 +172,16 @@ func (d *CmdDecorator) generateTemplateImpl(ctx execution.GeneratorContext, para
 		Data: struct {
 			CmdName      string
 			FunctionName string
+			Defaults     map[string]string
 		}{
 			CmdName:      cmdName,
-			FunctionName: capitalizeFirst(toCamelCase(cmdName)),
+			FunctionName: capitalizeFirst(ctx.GetCommandIdentifier(cmdName)),
+			Defaults:     defaults,
 		},
 	}, nil
 }
 
-// ExecutePlan creates a plan element for the command reference
+// ExecutePlan describes the command reference inline for plans
 func (d *CmdDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
 	cmdName, err := d.extractCommandName(params)
 	if err != nil {
@@ -176,17 +208,9 @@ func (d *CmdDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.Named
 		}
 	}
 
-	// Create a simple plan element that references the command
-	// For now, we'll create a basic plan element - this could be enhanced
-	// to actually generate the nested plan for the referenced command
-	planData := map[string]interface{}{
-		"type":        "command_reference",
-		"command":     cmdName,
-		"description": fmt.Sprintf("Execute command: %s", cmdName),
-	}
-
+	// Action decorators describe themselves inline in the shell command's plan element
 	return &execution.ExecutionResult{
-		Data:  planData,
+		Data:  fmt.Sprintf("@cmd(%s)", cmdName),
 		Error: nil,
 	}
 }
@@ -210,27 +234,6 @@ func (d *CmdDecorator) extractCommandName(params []ast.NamedParameter) (string,
 	}
 }
 
-// toCamelCase converts a command name to camelCase for function naming
-// This matches the engine's toCamelCase function exactly
-func toCamelCase(name string) string {
-	// Handle different separators: hyphens, underscores, and spaces
-	parts := strings.FieldsFunc(name, func(r rune) bool {
-		return r == '-' || r == '_' || r == ' '
-	})
-
-	if len(parts) == 0 {
-		return name
-	}
-
-	// First part stays lowercase, subsequent parts get title case
-	result := parts[0]
-	for i := 1; i < len(parts); i++ {
-		result += capitalizeFirst(parts[i])
-	}
-
-	return result
-}
-
 // capitalizeFirst capitalizes the first letter of a string
 func capitalizeFirst(s string) string {
 	if len(s) == 0 {
@@ -243,3 +246,16 @@ func capitalizeFirst(s string) string {
 func init() {
 	decorators.RegisterAction(&CmdDecorator{})
 }
+
+// paramDefaults returns the default values of a command's parameters, which @cmd runs it
+// with; a parameter without a default can't be given by @cmd
+func paramDefaults(command *ast.CommandDecl) (map[string]string, error) {
+	defaults := make(map[string]string, len(command.Params))
+	for _, param := range command.Params {
+		if param.Required() {
+			return nil, fmt.Errorf("@cmd(%s) can't run %s: its parameter %s has no default", command.Name, command.Name, param.Name)
+		}
+		defaults[param.Name] = param.Default.String()
+	}
+	return defaults, nil
+}
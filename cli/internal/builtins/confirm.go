@@ -2,6 +2,7 @@ package decorators
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/aledsdavies/devcmd/core/plan"
 	"github.com/aledsdavies/devcmd/runtime/decorators"
 	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/messages"
 )
 
 // ConfirmDecorator implements the @confirm decorator for user confirmation prompts
@@ -162,7 +164,7 @@ func (c *ConfirmDecorator) executeInterpreterImpl(ctx execution.InterpreterConte
 	// Check if we should skip confirmation in CI environment
 	if skipInCI && c.isCI(ctx) {
 		// Auto-confirm in CI and execute commands in child context
-		fmt.Printf("CI environment detected - auto-confirming: %s\n", message)
+		fmt.Println(messages.Sprintf(messages.ConfirmAutoConfirmed, message))
 
 		// Use CommandExecutor utility to handle command execution
 		commandExecutor := decorators.NewCommandExecutor()
@@ -175,12 +177,11 @@ func (c *ConfirmDecorator) executeInterpreterImpl(ctx execution.InterpreterConte
 		}
 	}
 
-	// Display the confirmation message
-	fmt.Print(message)
+	// Display the confirmation message, in the locale's words
 	if defaultYes {
-		fmt.Print(" [Y/n]: ")
+		fmt.Print(messages.Sprintf(messages.ConfirmPromptDefaultYes, message))
 	} else {
-		fmt.Print(" [y/N]: ")
+		fmt.Print(messages.Sprintf(messages.ConfirmPrompt, message))
 	}
 
 	// Read user input
@@ -189,30 +190,23 @@ func (c *ConfirmDecorator) executeInterpreterImpl(ctx execution.InterpreterConte
 	if err != nil {
 		return &execution.ExecutionResult{
 			Data:  nil,
-			Error: fmt.Errorf("failed to read user input: %w", err),
+			Error: messages.Errorf(messages.InputReadFailed, err),
 		}
 	}
 
 	response = strings.TrimSpace(response)
 
-	// Determine if user confirmed
-	confirmed := false
-	if response == "" {
-		confirmed = defaultYes
-	} else {
-		if caseSensitive {
-			confirmed = response == "y" || response == "Y" || response == "yes" || response == "Yes"
-		} else {
-			lowerResponse := strings.ToLower(response)
-			confirmed = lowerResponse == "y" || lowerResponse == "yes"
-		}
+	// Determine if user confirmed, with one of the locale's answers
+	confirmed := defaultYes
+	if response != "" {
+		confirmed = messages.IsAnswer(response, caseSensitive)
 	}
 
 	if !confirmed {
 		if abortOnNo {
 			return &execution.ExecutionResult{
 				Data:  nil,
-				Error: fmt.Errorf("user cancelled execution"),
+				Error: errors.New(messages.Get(messages.ConfirmCancelled)),
 			}
 		}
 		// User said no but don't abort - just skip execution
@@ -256,28 +250,28 @@ if envContext["CI"] != "" || envContext["GITHUB_ACTIONS"] != "" || envContext["T
 {{range .Content}}	{{. | buildCommand}}
 {{end}}	return nil
 }
-{{end}}fmt.Print({{printf "%q" .Message}} + " {{if .DefaultYes}}[Y/n]{{else}}[y/N]{{end}}: ")
+{{end}}fmt.Printf(msg({{if .DefaultYes}}"confirm.promptDefaultYes"{{else}}"confirm.prompt"{{end}}), {{printf "%q" .Message}})
 reader := bufio.NewReader(os.Stdin)
 response, err := reader.ReadString('\n')
 if err != nil {
-	return fmt.Errorf("failed to read user input: %w", err)
+	return fmt.Errorf(msg("input.readFailed"), err)
 }
 response = strings.TrimSpace(response)
 
-confirmed := false
-if response == "" {
-	confirmed = {{.DefaultYes}}
-} else {
-{{if .CaseSensitive}}
-	confirmed = response == "y" || response == "Y" || response == "yes" || response == "Yes"
-{{else}}
-	confirmed = strings.ToLower(response) == "y" || strings.ToLower(response) == "yes"
-{{end}}
+// The locale's answers confirm, as messages.IsAnswer accepts them
+confirmed := {{.DefaultYes}}
+if response != "" {
+	confirmed = false
+	for _, answer := range strings.Split(msg("confirm.answers"), ",") {
+		if answer = strings.TrimSpace(answer); answer != "" && {{if .CaseSensitive}}(response == answer || response == strings.ToUpper(answer[:1])+answer[1:]){{else}}strings.EqualFold(response, answer){{end}} {
+			confirmed = true
+		}
+	}
 }
 
 {{if .AbortOnNo}}
 if !confirmed {
-	return fmt.Errorf("user cancelled execution")
+	return fmt.Errorf("%s", msg("confirm.cancelled"))
 }
 {{else}}
 if confirmed {
@@ -359,6 +353,11 @@ func (c *ConfirmDecorator) executePlanImpl(ctx execution.PlanContext, message st
 		element = element.WithParameter("ci", "false")
 	}
 
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
 	return &execution.ExecutionResult{
 		Data:  element,
 		Error: nil,
@@ -74,7 +74,7 @@ func TestConfirmDecorator_DefaultYes(t *testing.T) {
 
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("[Y/n]", "confirmed = true").
+		GeneratorCodeContains(`msg("confirm.promptDefaultYes")`, "confirmed := true").
 		PlanSucceeds().
 		Validate()
 
@@ -99,7 +99,7 @@ func TestConfirmDecorator_DefaultNo(t *testing.T) {
 
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("[y/N]", "confirmed = false").
+		GeneratorCodeContains(`msg("confirm.prompt")`, "confirmed := false").
 		PlanSucceeds().
 		Validate()
 
@@ -124,7 +124,7 @@ func TestConfirmDecorator_AbortOnNo(t *testing.T) {
 
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("user cancelled execution").
+		GeneratorCodeContains(`msg("confirm.cancelled")`).
 		PlanSucceeds().
 		Validate()
 
@@ -174,7 +174,7 @@ func TestConfirmDecorator_CaseSensitive(t *testing.T) {
 
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("response == \"y\" || response == \"Y\""). // Case sensitive matching
+		GeneratorCodeContains("response == strings.ToUpper(answer[:1])+answer[1:]"). // Case sensitive matching
 		PlanSucceeds().
 		Validate()
 
@@ -199,7 +199,7 @@ func TestConfirmDecorator_CaseInsensitive(t *testing.T) {
 
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("strings.ToLower(response)"). // Case insensitive matching
+		GeneratorCodeContains("strings.EqualFold(response, answer)"). // Case insensitive matching
 		PlanSucceeds().
 		Validate()
 
@@ -277,7 +277,7 @@ func TestConfirmDecorator_AllParameters(t *testing.T) {
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
 		GeneratorProducesValidGo().
-		GeneratorCodeContains("Full parameter test?", "[Y/n]").
+		GeneratorCodeContains("Full parameter test?", `msg("confirm.promptDefaultYes")`).
 		PlanSucceeds().
 		Validate()
 
@@ -493,7 +493,7 @@ func TestConfirmDecorator_ScenarioDeploymentConfirmation(t *testing.T) {
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
 		GeneratorProducesValidGo().
-		GeneratorCodeContains("Deploy to production", "kubectl apply", "user cancelled execution").
+		GeneratorCodeContains("Deploy to production", "kubectl apply", `msg("confirm.cancelled")`).
 		PlanSucceeds().
 		SupportsNesting().
 		Validate()
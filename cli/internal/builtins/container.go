@@ -0,0 +1,243 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// containerRuntimes are the container CLIs tried, in order, to run a block in an image
+var containerRuntimes = []string{"docker", "podman"}
+
+// containerShellTemplate points ctx.Shell at a container running image with the working
+// directory mounted at the same path. It expects runtimeName and image in scope and
+// mirrors containerShell.
+const containerShellTemplate = `	dir := ctx.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	shell := []string{runtimeName, "run", "--rm", "-i", "-v", dir + ":" + dir, "-w", dir, "-e", "HOME=/tmp"}
+	if uid := os.Getuid(); uid >= 0 {
+		shell = append(shell, "--user", fmt.Sprintf("%d:%d", uid, os.Getgid()))
+	}
+	envNames := make([]string, 0, len(ctx.Env))
+	for name := range ctx.Env {
+		if name != "PATH" && name != "HOME" {
+			envNames = append(envNames, name)
+		}
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		shell = append(shell, "-e", name)
+	}
+	ctx.Shell = append(shell, "--entrypoint", "sh", image)
+`
+
+// containerRuntimeTemplate sets runtimeName to the first available container CLI.
+// It mirrors findContainerRuntime.
+const containerRuntimeTemplate = `	runtimeName := ""
+	for _, candidate := range []string{ {{range .Runtimes}}{{printf "%q" .}}, {{end}} } {
+		if _, err := execpkg.LookPath(candidate); err == nil {
+			runtimeName = candidate
+			break
+		}
+	}
+`
+
+// containerTemplate runs the block's shell steps inside the image. It mirrors ContainerDecorator.ExecuteInterpreter.
+const containerTemplate = `// Run in container {{.Image}}
+{
+	ctx := ctx.Clone()
+	image := {{printf "%q" .Image}}
+` + containerRuntimeTemplate + `	if runtimeName == "" {
+		return fmt.Errorf("@container: no container runtime found in PATH (install {{.RuntimeList}})")
+	}
+` + containerShellTemplate + `
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// findContainerRuntime returns the first available container CLI
+func findContainerRuntime() (string, error) {
+	for _, candidate := range containerRuntimes {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no container runtime found in PATH (install %s)", strings.Join(containerRuntimes, " or "))
+}
+
+// containerShell returns the command prefix that runs shell steps inside image. The working
+// directory is mounted at the same path, files are written as the current user, and variables
+// exported by enclosing decorators are passed through; PATH and HOME come from the image.
+func containerShell(ctx execution.InterpreterContext, runtimeName, image string) []string {
+	dir := ctx.GetWorkingDir()
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	shell := []string{runtimeName, "run", "--rm", "-i", "-v", dir + ":" + dir, "-w", dir, "-e", "HOME=/tmp"}
+	if uid := os.Getuid(); uid >= 0 {
+		shell = append(shell, "--user", fmt.Sprintf("%d:%d", uid, os.Getgid()))
+	}
+
+	exported := ctx.ExportedEnv()
+	envNames := make([]string, 0, len(exported))
+	for name := range exported {
+		if name != "PATH" && name != "HOME" {
+			envNames = append(envNames, name)
+		}
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		// Values are taken from the runtime CLI's environment, which carries the exported variables
+		shell = append(shell, "-e", name)
+	}
+	return append(shell, "--entrypoint", "sh", image)
+}
+
+// executeInContainer runs the block's commands with their shell steps inside image
+func executeInContainer(ctx execution.InterpreterContext, runtimeName, image string, content []ast.CommandContent) *execution.ExecutionResult {
+	containerCtx := ctx.Child()
+	containerCtx = containerCtx.WithShell(containerShell(containerCtx, runtimeName, image))
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(containerCtx, content),
+	}
+}
+
+// containerImports returns the imports needed by containerShellTemplate and containerRuntimeTemplate
+func containerImports() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"os/exec", "sort"})
+}
+
+// ContainerDecorator implements the @container decorator for running a block inside a container image
+type ContainerDecorator struct{}
+
+// Name returns the decorator name
+func (c *ContainerDecorator) Name() string {
+	return "container"
+}
+
+// Description returns a human-readable description
+func (c *ContainerDecorator) Description() string {
+	return "Run the block's commands inside a container image with the working directory mounted"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (c *ContainerDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "image",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Container image to run the commands in, e.g. \"hashicorp/terraform:1.9\"",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block inside the container in interpreter mode
+func (c *ContainerDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	image, err := c.extractImage(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	runtimeName, err := findContainerRuntime()
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return executeInContainer(ctx, runtimeName, image, content)
+}
+
+// GenerateTemplate generates template for running the block inside the container
+func (c *ContainerDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	image, err := c.extractImage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("container").Funcs(ctx.GetTemplateFunctions()).Parse(containerTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse container template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Image       string
+			Runtimes    []string
+			RuntimeList string
+			Content     []ast.CommandContent
+		}{
+			Image:       image,
+			Runtimes:    containerRuntimes,
+			RuntimeList: strings.Join(containerRuntimes, " or "),
+			Content:     content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (c *ContainerDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	image, err := c.extractImage(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(c.Name()).
+		WithType("block").
+		WithParameter("image", image).
+		WithDescription(fmt.Sprintf("Run in container %s", image))
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractImage validates parameters and returns the image with @var references resolved
+func (c *ContainerDecorator) extractImage(ctx execution.BaseContext, params []ast.NamedParameter) (string, error) {
+	if err := decorators.ValidateSchemaCompliance(params, c.ParameterSchema(), c.Name()); err != nil {
+		return "", err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, c.ParameterSchema())
+	if err != nil {
+		return "", fmt.Errorf("@%s: %w", c.Name(), err)
+	}
+
+	image, err := resolveVariableReferences(ctx, ast.GetStringParam(params, "image", ""))
+	if err != nil {
+		return "", fmt.Errorf("@%s: %w", c.Name(), err)
+	}
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return "", fmt.Errorf("@%s: invalid image %q", c.Name(), image)
+	}
+	return image, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (c *ContainerDecorator) ImportRequirements() decorators.ImportRequirement {
+	return containerImports()
+}
+
+// init registers the container decorator
+func init() {
+	decorators.RegisterBlock(&ContainerDecorator{})
+}
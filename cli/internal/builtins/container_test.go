@@ -0,0 +1,112 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeDocker replaces docker with a script that records its arguments and runs the
+// container's `sh -c` command locally with IN_CONTAINER set
+func installFakeDocker(t *testing.T) string {
+	t.Helper()
+	args := filepath.Join(t.TempDir(), "docker-args")
+	installFakeCLI(t, "docker", `echo "$@" >> `+args+`
+while [ "$1" != "--entrypoint" ]; do shift; done
+shift 3
+IN_CONTAINER=1 exec sh "$@"`)
+	return args
+}
+
+func TestContainerDecorator_Basic(t *testing.T) {
+	args := installFakeDocker(t)
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &ContainerDecorator{}).
+		WithVariable("TF_VERSION", "1.9").
+		TestBlockDecorator([]ast.NamedParameter{
+			{Value: &ast.StringLiteral{Value: "hashicorp/terraform:@var(TF_VERSION)"}},
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo $IN_CONTAINER > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`image := "hashicorp/terraform:1.9"`, `ctx.Shell = append(shell, "--entrypoint", "sh", image)`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ContainerDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "1" {
+		t.Errorf("block ran outside the container: %q", got)
+	}
+	recorded, _ := os.ReadFile(args)
+	if !strings.Contains(string(recorded), "run --rm -i -v ") || !strings.Contains(string(recorded), "--entrypoint sh hashicorp/terraform:1.9 -c") {
+		t.Errorf("unexpected docker arguments: %s", recorded)
+	}
+}
+
+func TestContainerShell_PassesExportedEnvironment(t *testing.T) {
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	ctx.ExportEnv("AWS_PROFILE", "dev")
+	ctx.ExportEnv("PATH", "/opt/go/bin:/usr/bin")
+	dir := t.TempDir()
+	ctx = ctx.WithWorkingDir(dir)
+
+	shell := strings.Join(containerShell(ctx, "podman", "alpine:3"), " ")
+	if !strings.HasPrefix(shell, "podman run --rm -i -v "+dir+":"+dir+" -w "+dir+" -e HOME=/tmp") {
+		t.Errorf("shell = %q, want the working directory mounted at the same path", shell)
+	}
+	if !strings.Contains(shell, "-e AWS_PROFILE") || strings.Contains(shell, "-e PATH") {
+		t.Errorf("shell = %q, want exported variables except PATH passed through", shell)
+	}
+	if !strings.HasSuffix(shell, "--entrypoint sh alpine:3") {
+		t.Errorf("shell = %q, want the image's sh as entrypoint", shell)
+	}
+}
+
+func TestContainerDecorator_InvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		errMsg string
+	}{
+		{"missing image", []ast.NamedParameter{}, "image"},
+		{"image with spaces", []ast.NamedParameter{
+			decoratortesting.StringParam("image", "alpine 3"),
+		}, "invalid image"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &ContainerDecorator{}).
+				TestBlockDecorator(tc.params, []ast.CommandContent{})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.errMsg).
+				GeneratorFails(tc.errMsg).
+				PlanFails(tc.errMsg).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("ContainerDecorator invalid parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
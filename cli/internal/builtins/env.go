@@ -19,7 +19,7 @@ func (e *EnvDecorator) Name() string {
 
 // Description returns a human-readable description
 func (e *EnvDecorator) Description() string {
-	return "Access environment variables with optional defaults"
+	return "Access environment variables with optional defaults or as required"
 }
 
 // ParameterSchema returns the expected parameters for this decorator
@@ -43,12 +43,24 @@ func (e *EnvDecorator) ParameterSchema() []decorators.ParameterSchema {
 			Required:    false,
 			Description: "If true, empty string values are preserved instead of using default",
 		},
+		{
+			Name:        "raw",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, the value is inserted as shell syntax instead of being quoted",
+		},
+		{
+			Name:        "required",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, the command fails before running when the variable is unset (or empty unless allowEmpty); a default is only offered as a suggestion",
+		},
 	}
 }
 
 // ExpandInterpreter returns the captured environment variable value for interpreter mode
 func (e *EnvDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
-	key, defaultValue, allowEmpty, err := e.extractParameters(params)
+	key, defaultValue, allowEmpty, required, err := e.extractParameters(params)
 	if err != nil {
 		return &execution.ExecutionResult{
 			Data:  nil,
@@ -59,8 +71,15 @@ func (e *EnvDecorator) ExpandInterpreter(ctx execution.InterpreterContext, param
 	// Get the environment variable value from captured environment (deterministic)
 	value, exists := ctx.GetEnv(key)
 
-	// Use captured value or default based on allowEmpty flag
+	// Use captured value or default based on allowEmpty flag. Required variables are checked
+	// before the command runs; this covers steps run without that check, such as triggers.
 	if !exists || (!allowEmpty && value == "") {
+		if required {
+			return &execution.ExecutionResult{
+				Data:  nil,
+				Error: fmt.Errorf("environment variable %s is required but is %s", key, envState(exists)),
+			}
+		}
 		value = defaultValue
 	}
 
@@ -72,27 +91,34 @@ func (e *EnvDecorator) ExpandInterpreter(ctx execution.InterpreterContext, param
 
 // GenerateTemplate returns template for Go code that references captured environment for generator mode
 func (e *EnvDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
-	key, defaultValue, allowEmpty, err := e.extractParameters(params)
+	key, defaultValue, allowEmpty, required, err := e.extractParameters(params)
 	if err != nil {
 		return nil, fmt.Errorf("env parameter error: %w", err)
 	}
 
+	// A required variable is checked before the command runs, so its default is never used
+	if required {
+		defaultValue = ""
+	}
+
 	// Track this environment variable for global capture generation
 	ctx.TrackEnvironmentVariableReference(key, defaultValue)
 
-	// Create template for environment variable access
+	// Create template for environment variable access. ctx.Env holds what devcmd sets, such as
+	// settings defaults, over the caller's environment.
+	lookup := `val, exists := ctx.Env[{{printf "%q" .Key}}]; if !exists { val, exists = os.LookupEnv({{printf "%q" .Key}}) }; `
 	var tmplStr string
 	if defaultValue != "" {
 		if allowEmpty {
 			// If allowEmpty=true, only use default if not exists
-			tmplStr = `func() string { if val, exists := ctx.Env[{{printf "%q" .Key}}]; exists { return val }; return {{printf "%q" .DefaultValue}} }()`
+			tmplStr = `func() string { ` + lookup + `if exists { return val }; return {{printf "%q" .DefaultValue}} }()`
 		} else {
 			// Default behavior: use default if not exists or empty
-			tmplStr = `func() string { if val, exists := ctx.Env[{{printf "%q" .Key}}]; exists && val != "" { return val }; return {{printf "%q" .DefaultValue}} }()`
+			tmplStr = `func() string { ` + lookup + `if exists && val != "" { return val }; return {{printf "%q" .DefaultValue}} }()`
 		}
 	} else {
-		// No default, just use captured value
-		tmplStr = `ctx.Env[{{printf "%q" .Key}}]`
+		// No default, just use the value
+		tmplStr = `func() string { ` + lookup + `return val }()`
 	}
 
 	// Parse template
@@ -117,7 +143,7 @@ func (e *EnvDecorator) GenerateTemplate(ctx execution.GeneratorContext, params [
 
 // ExpandPlan returns description showing the captured environment value for plan mode
 func (e *EnvDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
-	key, defaultValue, allowEmpty, err := e.extractParameters(params)
+	key, defaultValue, allowEmpty, required, err := e.extractParameters(params)
 	if err != nil {
 		return &execution.ExecutionResult{
 			Data:  nil,
@@ -129,15 +155,16 @@ func (e *EnvDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedP
 	value, exists := ctx.GetEnv(key)
 
 	var displayValue string
-	// Apply same logic as interpreter mode for consistency
-	if !exists || (!allowEmpty && value == "") {
-		if defaultValue != "" {
-			displayValue = fmt.Sprintf("@env(%s) → %q (default)", key, defaultValue)
-		} else {
-			displayValue = fmt.Sprintf("@env(%s) → <unset>", key)
-		}
-	} else {
-		displayValue = fmt.Sprintf("@env(%s) → %q (captured)", key, value)
+	// Apply same logic as interpreter mode for consistency, showing whether the variable is set
+	switch {
+	case exists && (allowEmpty || value != ""):
+		displayValue = fmt.Sprintf("@env(%s) → %q (set)", key, value)
+	case required:
+		displayValue = fmt.Sprintf("@env(%s) → <%s> (required)", key, envState(exists))
+	case defaultValue != "":
+		displayValue = fmt.Sprintf("@env(%s) → %q (default, %s)", key, defaultValue, envState(exists))
+	default:
+		displayValue = fmt.Sprintf("@env(%s) → <%s>", key, envState(exists))
 	}
 
 	return &execution.ExecutionResult{
@@ -146,21 +173,29 @@ func (e *EnvDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedP
 	}
 }
 
-// extractParameters extracts the environment variable key and default value from decorator parameters
-func (e *EnvDecorator) extractParameters(params []ast.NamedParameter) (key string, defaultValue string, allowEmpty bool, err error) {
+// envState describes an environment variable without a usable value
+func envState(exists bool) string {
+	if exists {
+		return "empty"
+	}
+	return "unset"
+}
+
+// extractParameters extracts the environment variable key, default value and flags from decorator parameters
+func (e *EnvDecorator) extractParameters(params []ast.NamedParameter) (key string, defaultValue string, allowEmpty bool, required bool, err error) {
 	// Use centralized validation
-	if err := decorators.ValidateParameterCount(params, 1, 3, "env"); err != nil {
-		return "", "", false, err
+	if err := decorators.ValidateParameterCount(params, 1, 5, "env"); err != nil {
+		return "", "", false, false, err
 	}
 
 	// Validate parameter schema compliance
 	if err := decorators.ValidateSchemaCompliance(params, e.ParameterSchema(), "env"); err != nil {
-		return "", "", false, err
+		return "", "", false, false, err
 	}
 
 	// Validate environment variable name if present
 	if err := decorators.ValidateEnvironmentVariableName(params, "key", "env"); err != nil {
-		return "", "", false, err
+		return "", "", false, false, err
 	}
 
 	// Parse parameters (validation passed, so these should be safe)
@@ -178,7 +213,7 @@ func (e *EnvDecorator) extractParameters(params []ast.NamedParameter) (key strin
 
 	// Additional check for empty key (shouldn't happen after validation)
 	if key == "" {
-		return "", "", false, fmt.Errorf("@env decorator requires a valid environment variable name")
+		return "", "", false, false, fmt.Errorf("@env decorator requires a valid environment variable name")
 	}
 
 	// Get default value if provided
@@ -187,13 +222,22 @@ func (e *EnvDecorator) extractParameters(params []ast.NamedParameter) (key strin
 	// Get allowEmpty flag (defaults to false for backward compatibility)
 	allowEmpty = ast.GetBoolParam(params, "allowEmpty", false)
 
-	return key, defaultValue, allowEmpty, nil
+	// Get required flag
+	required = ast.GetBoolParam(params, "required", false)
+
+	return key, defaultValue, allowEmpty, required, nil
+}
+
+// QuotesShellValue reports whether the environment variable's value is quoted in shell
+// commands, so spaces and quotes in it don't split or break the command
+func (e *EnvDecorator) QuotesShellValue(params []ast.NamedParameter) bool {
+	return !ast.GetBoolParam(params, "raw", false)
 }
 
 // ImportRequirements returns the dependencies needed for code generation
 func (e *EnvDecorator) ImportRequirements() decorators.ImportRequirement {
 	return decorators.ImportRequirement{
-		StandardLibrary: []string{}, // No imports needed - generates string literals
+		StandardLibrary: []string{"os"}, // Reads the caller's environment
 		ThirdParty:      []string{},
 		GoModules:       map[string]string{},
 	}
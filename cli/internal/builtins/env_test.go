@@ -2,6 +2,7 @@ package decorators
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/aledsdavies/devcmd/core/ast"
@@ -194,3 +195,43 @@ func TestEnvDecorator_GlobalTracking(t *testing.T) {
 		t.Errorf("EnvDecorator global tracking test failed:\n%s", decoratortesting.JoinErrors(errors))
 	}
 }
+
+func TestEnvDecorator_Required(t *testing.T) {
+	decorator := &EnvDecorator{}
+	params := []ast.NamedParameter{
+		decoratortesting.StringParam("key", "DEVCMD_TEST_REQUIRED"),
+		decoratortesting.StringParam("default", "dev-key"),
+		decoratortesting.BoolParam("required", true),
+	}
+
+	// Unset, the default isn't used
+	result := decoratortesting.NewDecoratorTest(t, decorator).TestValueDecorator(params)
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("environment variable DEVCMD_TEST_REQUIRED is required but is unset").
+		GeneratorSucceeds().
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("EnvDecorator required unset test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if plan, _ := result.PlanResult.Data.(string); plan != "@env(DEVCMD_TEST_REQUIRED) → <unset> (required)" {
+		t.Errorf("plan = %q, want the variable shown as unset and required", plan)
+	}
+	if code, _ := result.GeneratorResult.Data.(string); strings.Contains(code, "dev-key") {
+		t.Errorf("generated code falls back to the default of a required variable: %s", code)
+	}
+
+	t.Setenv("DEVCMD_TEST_REQUIRED", "real-key")
+	result = decoratortesting.NewDecoratorTest(t, decorator).TestValueDecorator(params)
+	errors = decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("real-key").
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("EnvDecorator required set test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if plan, _ := result.PlanResult.Data.(string); plan != `@env(DEVCMD_TEST_REQUIRED) → "real-key" (set)` {
+		t.Errorf("plan = %q, want the variable shown as set", plan)
+	}
+}
@@ -0,0 +1,173 @@
+package decorators
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// processEnvVar names the background process a command runs as, if any.
+// Watch commands set it so allocated ports are recorded next to the process's PID file,
+// in the directory named by processDirEnvVar.
+const (
+	processEnvVar    = "DEVCMD_PROCESS"
+	processDirEnvVar = "DEVCMD_PROCESS_DIR"
+)
+
+// freeportTemplate allocates a port in generated code. Value decorators can't
+// return errors there, so failing to find a port exits.
+const freeportTemplate = `func() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logf("error", "@freeport", "failed to find a free port: %v", err)
+		os.Exit(1)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+	ctx.Env[{{printf "%q" .Name}}] = port
+	if process, dir := ctx.Env[{{printf "%q" .ProcessEnvVar}}], ctx.Env[{{printf "%q" .ProcessDirEnvVar}}]; process != "" && dir != "" {
+		_ = appendProcessFile(dir, process+".ports", {{printf "%q" .Name}}+"="+port)
+	}
+	return port
+}()`
+
+// FreeportDecorator implements the @freeport decorator for allocating TCP ports
+type FreeportDecorator struct{}
+
+// Name returns the decorator name
+func (f *FreeportDecorator) Name() string {
+	return "freeport"
+}
+
+// Description returns a human-readable description
+func (f *FreeportDecorator) Description() string {
+	return "Allocate an available TCP port and export it as an environment variable for subsequent steps"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (f *FreeportDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "name",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Environment variable that receives the port, e.g. API_PORT",
+		},
+	}
+}
+
+// ExpandInterpreter allocates a port and exports it for the rest of the command
+func (f *FreeportDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	name, err := f.extractName(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	port, err := allocatePort()
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@freeport: %w", err)}
+	}
+	ctx.ExportEnv(name, port)
+
+	process, _ := ctx.GetEnv(processEnvVar)
+	if dir, _ := ctx.GetEnv(processDirEnvVar); process != "" && dir != "" {
+		if err := recordPort(dir, process, name, port); err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@freeport: failed to record port for %s: %w", process, err)}
+		}
+	}
+
+	return &execution.ExecutionResult{Data: port, Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that allocates a port at runtime
+func (f *FreeportDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	name, err := f.extractName(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("freeport").Parse(freeportTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse freeport template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Name             string
+			ProcessEnvVar    string
+			ProcessDirEnvVar string
+		}{
+			Name:             name,
+			ProcessEnvVar:    processEnvVar,
+			ProcessDirEnvVar: processDirEnvVar,
+		},
+	}, nil
+}
+
+// ExpandPlan describes the allocation without reserving a port
+func (f *FreeportDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	name, err := f.extractName(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  fmt.Sprintf("@freeport(%s) → <free port at runtime>", name),
+		Error: nil,
+	}
+}
+
+// extractName validates parameters and returns the environment variable name
+func (f *FreeportDecorator) extractName(params []ast.NamedParameter) (string, error) {
+	if err := decorators.ValidateParameterCount(params, 1, 1, "freeport"); err != nil {
+		return "", err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, f.ParameterSchema())
+	if err != nil {
+		return "", fmt.Errorf("@freeport: %w", err)
+	}
+
+	// Allow a bare identifier, e.g. @freeport(API_PORT)
+	if identifier, ok := params[0].Value.(*ast.Identifier); ok {
+		return identifier.Name, nil
+	}
+	if err := decorators.ValidateSchemaCompliance(params, f.ParameterSchema(), "freeport"); err != nil {
+		return "", err
+	}
+	if err := decorators.ValidateEnvironmentVariableName(params, "name", "freeport"); err != nil {
+		return "", err
+	}
+	return ast.GetStringParam(params, "name", ""), nil
+}
+
+// allocatePort asks the OS for an unused loopback TCP port
+func allocatePort() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+// recordPort appends an allocated port to the process's ports file beside its PID file in dir
+func recordPort(dir, process, name, port string) error {
+	return processes.DirStore{Dir: dir}.Append(process+".ports", name+"="+port)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (f *FreeportDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"net", "path/filepath", "strconv"})
+}
+
+// init registers the freeport decorator
+func init() {
+	decorators.RegisterValue(&FreeportDecorator{})
+}
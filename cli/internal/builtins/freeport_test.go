@@ -0,0 +1,90 @@
+package decorators
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestFreeportDecorator_Basic(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &FreeportDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("name", "API_PORT"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorCodeContains(`net.Listen("tcp", "127.0.0.1:0")`, `ctx.Env["API_PORT"] = port`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("FreeportDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	port, err := strconv.Atoi(result.InterpreterResult.Data.(string))
+	if err != nil || port <= 0 || port > 65535 {
+		t.Errorf("expected a valid port, got %v", result.InterpreterResult.Data)
+	}
+}
+
+func TestFreeportDecorator_ExportsPortAndRecordsProcess(t *testing.T) {
+	process := "freeport-test"
+	dir := t.TempDir()
+	portsFile := filepath.Join(dir, process+".ports")
+
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	ctx.ExportEnv(processEnvVar, process)
+	ctx.ExportEnv(processDirEnvVar, dir)
+
+	result := (&FreeportDecorator{}).ExpandInterpreter(ctx, []ast.NamedParameter{
+		{Value: &ast.Identifier{Name: "WEB_PORT"}},
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	port := result.Data.(string)
+
+	if value, ok := ctx.GetEnv("WEB_PORT"); !ok || value != port {
+		t.Errorf("WEB_PORT = %q, %v; want %q", value, ok, port)
+	}
+
+	recorded, err := os.ReadFile(portsFile)
+	if err != nil {
+		t.Fatalf("ports file not written: %v", err)
+	}
+	if string(recorded) != "WEB_PORT="+port+"\n" {
+		t.Errorf("ports file = %q, want %q", recorded, "WEB_PORT="+port+"\n")
+	}
+
+	// The port was released so the command can bind it
+	listener, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatalf("allocated port %s is not bindable: %v", port, err)
+	}
+	listener.Close()
+}
+
+func TestFreeportDecorator_InvalidName(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &FreeportDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("name", "API-PORT"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("valid environment variable name").
+		GeneratorFails("valid environment variable name").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("FreeportDecorator invalid name test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
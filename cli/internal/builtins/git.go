@@ -0,0 +1,495 @@
+package decorators
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// maxDirtyFilesShown limits how many changed paths are listed when the worktree is dirty
+const maxDirtyFilesShown = 10
+
+// gitValueTemplate runs git in the command's working directory and yields its trimmed output.
+// Value decorators can't return errors in generated code, so failures exit unless a default is set.
+const gitValueTemplate = `func() string {
+	cmd := execpkg.Command("git"{{range .Args}}, {{printf "%q" .}}{{end}})
+	cmd.Dir = ctx.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		{{if .HasDefault}}return {{printf "%q" .Default}}{{else}}logf("error", "@{{.Name}}", "git {{.Command}} failed: %v", err)
+		os.Exit(1)
+		return ""{{end}}
+	}
+	return strings.TrimSpace(string(out))
+}()`
+
+// runGit runs git with the given arguments in dir and returns its trimmed stdout
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), msg)
+		}
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// generateGitValueTemplate builds the template for a git-backed value decorator
+func generateGitValueTemplate(name string, args []string, defaultValue string, hasDefault bool) (*execution.TemplateResult, error) {
+	tmpl, err := template.New(name).Parse(gitValueTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Name       string
+			Args       []string
+			Command    string
+			Default    string
+			HasDefault bool
+		}{
+			Name:       name,
+			Args:       args,
+			Command:    strings.Join(args, " "),
+			Default:    defaultValue,
+			HasDefault: hasDefault,
+		},
+	}, nil
+}
+
+// planGitValue describes the value a git decorator resolves to for plan mode
+func planGitValue(ctx execution.PlanContext, name string, args []string, defaultValue string, hasDefault bool) *execution.ExecutionResult {
+	value, err := runGit(ctx.GetWorkingDir(), args...)
+
+	var displayValue string
+	switch {
+	case err == nil:
+		displayValue = fmt.Sprintf("@%s → %q", name, value)
+	case hasDefault:
+		displayValue = fmt.Sprintf("@%s → %q (default)", name, defaultValue)
+	default:
+		displayValue = fmt.Sprintf("@%s → <unavailable>", name)
+	}
+
+	return &execution.ExecutionResult{
+		Data:  displayValue,
+		Error: nil,
+	}
+}
+
+// gitValueImports are the imports needed by gitValueTemplate
+func gitValueImports() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"os/exec"})
+}
+
+// GitBranchDecorator implements the @git-branch decorator for the current branch name
+type GitBranchDecorator struct{}
+
+// Name returns the decorator name
+func (g *GitBranchDecorator) Name() string {
+	return "git-branch"
+}
+
+// Description returns a human-readable description
+func (g *GitBranchDecorator) Description() string {
+	return "Current git branch name (\"HEAD\" when detached)"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GitBranchDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{}
+}
+
+// ExpandInterpreter returns the current branch for interpreter mode
+func (g *GitBranchDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	if err := decorators.ValidateParameterCount(params, 0, 0, g.Name()); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	branch, err := runGit(ctx.GetWorkingDir(), g.args()...)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@git-branch: %w", err)}
+	}
+
+	return &execution.ExecutionResult{Data: branch, Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that resolves the branch at runtime
+func (g *GitBranchDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 0, g.Name()); err != nil {
+		return nil, err
+	}
+
+	return generateGitValueTemplate(g.Name(), g.args(), "", false)
+}
+
+// ExpandPlan returns description showing the current branch for plan mode
+func (g *GitBranchDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	if err := decorators.ValidateParameterCount(params, 0, 0, g.Name()); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return planGitValue(ctx, g.Name(), g.args(), "", false)
+}
+
+// args returns the git arguments that print the branch name
+func (g *GitBranchDecorator) args() []string {
+	return []string{"rev-parse", "--abbrev-ref", "HEAD"}
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GitBranchDecorator) ImportRequirements() decorators.ImportRequirement {
+	return gitValueImports()
+}
+
+// GitShaDecorator implements the @git-sha decorator for the current commit hash
+type GitShaDecorator struct{}
+
+// Name returns the decorator name
+func (g *GitShaDecorator) Name() string {
+	return "git-sha"
+}
+
+// Description returns a human-readable description
+func (g *GitShaDecorator) Description() string {
+	return "Commit hash of HEAD, optionally abbreviated"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GitShaDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "short",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Return the abbreviated hash (default: false)",
+		},
+	}
+}
+
+// ExpandInterpreter returns the commit hash for interpreter mode
+func (g *GitShaDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	args, err := g.extractArgs(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	sha, err := runGit(ctx.GetWorkingDir(), args...)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@git-sha: %w", err)}
+	}
+
+	return &execution.ExecutionResult{Data: sha, Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that resolves the commit hash at runtime
+func (g *GitShaDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	args, err := g.extractArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateGitValueTemplate(g.Name(), args, "", false)
+}
+
+// ExpandPlan returns description showing the commit hash for plan mode
+func (g *GitShaDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	args, err := g.extractArgs(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return planGitValue(ctx, g.Name(), args, "", false)
+}
+
+// extractArgs validates parameters and returns the git arguments that print the hash
+func (g *GitShaDecorator) extractArgs(params []ast.NamedParameter) ([]string, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 1, g.Name()); err != nil {
+		return nil, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, g.ParameterSchema(), g.Name()); err != nil {
+		return nil, err
+	}
+
+	if ast.GetBoolParam(params, "short", false) {
+		return []string{"rev-parse", "--short", "HEAD"}, nil
+	}
+	return []string{"rev-parse", "HEAD"}, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GitShaDecorator) ImportRequirements() decorators.ImportRequirement {
+	return gitValueImports()
+}
+
+// GitTagDecorator implements the @git-tag decorator for the most recent reachable tag
+type GitTagDecorator struct{}
+
+// Name returns the decorator name
+func (g *GitTagDecorator) Name() string {
+	return "git-tag"
+}
+
+// Description returns a human-readable description
+func (g *GitTagDecorator) Description() string {
+	return "Most recent tag reachable from HEAD, with an optional default when there are no tags"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GitTagDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "default",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Value to use when no tag is reachable (default: fail)",
+		},
+	}
+}
+
+// ExpandInterpreter returns the latest tag for interpreter mode
+func (g *GitTagDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	defaultValue, hasDefault, err := g.extractDefault(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	tag, err := runGit(ctx.GetWorkingDir(), g.args()...)
+	if err != nil {
+		if hasDefault {
+			return &execution.ExecutionResult{Data: defaultValue, Error: nil}
+		}
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@git-tag: %w", err)}
+	}
+
+	return &execution.ExecutionResult{Data: tag, Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that resolves the latest tag at runtime
+func (g *GitTagDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	defaultValue, hasDefault, err := g.extractDefault(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateGitValueTemplate(g.Name(), g.args(), defaultValue, hasDefault)
+}
+
+// ExpandPlan returns description showing the latest tag for plan mode
+func (g *GitTagDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	defaultValue, hasDefault, err := g.extractDefault(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return planGitValue(ctx, g.Name(), g.args(), defaultValue, hasDefault)
+}
+
+// extractDefault validates parameters and returns the fallback tag, if any
+func (g *GitTagDecorator) extractDefault(params []ast.NamedParameter) (string, bool, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 1, g.Name()); err != nil {
+		return "", false, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, g.ParameterSchema(), g.Name()); err != nil {
+		return "", false, err
+	}
+
+	if len(params) == 0 {
+		return "", false, nil
+	}
+	return ast.GetStringParam(params, "default", ""), true, nil
+}
+
+// args returns the git arguments that print the latest tag
+func (g *GitTagDecorator) args() []string {
+	return []string{"describe", "--tags", "--abbrev=0"}
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GitTagDecorator) ImportRequirements() decorators.ImportRequirement {
+	return gitValueImports()
+}
+
+// RequireCleanWorktreeDecorator implements the @require-clean-worktree guard block
+type RequireCleanWorktreeDecorator struct{}
+
+// Name returns the decorator name
+func (r *RequireCleanWorktreeDecorator) Name() string {
+	return "require-clean-worktree"
+}
+
+// Description returns a human-readable description
+func (r *RequireCleanWorktreeDecorator) Description() string {
+	return "Fail before running the block if the git worktree has uncommitted changes"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (r *RequireCleanWorktreeDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "untracked",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Treat untracked files as uncommitted changes (default: true)",
+		},
+	}
+}
+
+// ExecuteInterpreter checks the worktree and runs the block in interpreter mode
+func (r *RequireCleanWorktreeDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	untracked, err := r.extractUntracked(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	status, err := runGit(ctx.GetWorkingDir(), r.statusArgs(untracked)...)
+	if err != nil {
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: fmt.Errorf("failed to check git status: %w", err),
+		}
+	}
+	if status != "" {
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: fmt.Errorf("worktree has uncommitted changes:\n%s", summarizeStatus(status)),
+		}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content),
+	}
+}
+
+// GenerateTemplate generates template for the worktree check followed by the block
+func (r *RequireCleanWorktreeDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	untracked, err := r.extractUntracked(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmplStr := `// Require a clean git worktree
+{
+	statusCmd := execpkg.Command("git"{{range .StatusArgs}}, {{printf "%q" .}}{{end}})
+	statusCmd.Dir = ctx.Dir
+	status, err := statusCmd.Output()
+	if err != nil {
+		return fmt.Errorf("@require-clean-worktree: failed to check git status: %w", err)
+	}
+	if len(status) > 0 {
+		return fmt.Errorf("@require-clean-worktree: worktree has uncommitted changes:\n%s", strings.TrimRight(string(status), "\n"))
+	}
+}
+{{range .Content}}{{. | buildCommand}}
+{{end}}`
+
+	tmpl, err := template.New("require-clean-worktree").Funcs(ctx.GetTemplateFunctions()).Parse(tmplStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse require-clean-worktree template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			StatusArgs []string
+			Content    []ast.CommandContent
+		}{
+			StatusArgs: r.statusArgs(untracked),
+			Content:    content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (r *RequireCleanWorktreeDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	untracked, err := r.extractUntracked(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	// Report the current state without failing so dry runs still show the full plan
+	state := "clean"
+	if status, err := runGit(ctx.GetWorkingDir(), r.statusArgs(untracked)...); err != nil {
+		state = "unknown"
+	} else if status != "" {
+		state = fmt.Sprintf("dirty, %d changed paths - would fail", len(strings.Split(status, "\n")))
+	}
+
+	element := plan.Decorator("require-clean-worktree").
+		WithType("block").
+		WithParameter("untracked", fmt.Sprintf("%t", untracked)).
+		WithDescription(fmt.Sprintf("Require clean git worktree (currently %s)", state))
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractUntracked validates parameters and returns whether untracked files count as changes
+func (r *RequireCleanWorktreeDecorator) extractUntracked(params []ast.NamedParameter) (bool, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 1, r.Name()); err != nil {
+		return false, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, r.ParameterSchema(), r.Name()); err != nil {
+		return false, err
+	}
+
+	return ast.GetBoolParam(params, "untracked", true), nil
+}
+
+// statusArgs returns the git status arguments for the porcelain change listing
+func (r *RequireCleanWorktreeDecorator) statusArgs(untracked bool) []string {
+	if untracked {
+		return []string{"status", "--porcelain"}
+	}
+	return []string{"status", "--porcelain", "--untracked-files=no"}
+}
+
+// summarizeStatus formats porcelain status output, truncating long listings
+func summarizeStatus(status string) string {
+	lines := strings.Split(status, "\n")
+	if len(lines) <= maxDirtyFilesShown {
+		return "  " + strings.Join(lines, "\n  ")
+	}
+	shown := "  " + strings.Join(lines[:maxDirtyFilesShown], "\n  ")
+	return fmt.Sprintf("%s\n  ... and %d more", shown, len(lines)-maxDirtyFilesShown)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (r *RequireCleanWorktreeDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.StringImports, []string{"os/exec"})
+}
+
+// init registers the git decorators
+func init() {
+	decorators.RegisterValue(&GitBranchDecorator{})
+	decorators.RegisterValue(&GitShaDecorator{})
+	decorators.RegisterValue(&GitTagDecorator{})
+	decorators.RegisterBlock(&RequireCleanWorktreeDecorator{})
+}
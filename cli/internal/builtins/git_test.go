@@ -0,0 +1,208 @@
+package decorators
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// initGitRepo creates a repository with one commit on branch "main" and makes it the working directory
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=devcmd", "-c", "user.email=devcmd@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	t.Chdir(dir)
+	return dir
+}
+
+func TestGitBranchDecorator(t *testing.T) {
+	initGitRepo(t)
+
+	result := decoratortesting.NewDecoratorTest(t, &GitBranchDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("main").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`"rev-parse", "--abbrev-ref", "HEAD"`, "os.Exit(1)").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GitBranchDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestGitShaDecorator_Short(t *testing.T) {
+	dir := initGitRepo(t)
+
+	expected, err := runGit(dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		t.Fatalf("runGit failed: %v", err)
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &GitShaDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.BoolParam("short", true),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns(expected).
+		GeneratorSucceeds().
+		GeneratorCodeContains(`"rev-parse", "--short", "HEAD"`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GitShaDecorator short test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestGitTagDecorator(t *testing.T) {
+	dir := initGitRepo(t)
+
+	// Without tags the default is used, and without a default it fails
+	result := decoratortesting.NewDecoratorTest(t, &GitTagDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("default", "v0.0.0"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("v0.0.0").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`return "v0.0.0"`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GitTagDecorator default test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	result = decoratortesting.NewDecoratorTest(t, &GitTagDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{})
+
+	errors = decoratortesting.Assert(result).
+		InterpreterFails("git describe").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GitTagDecorator untagged test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	// Once tagged, the latest tag is returned
+	if _, err := runGit(dir, "tag", "v1.2.0"); err != nil {
+		t.Fatalf("failed to tag: %v", err)
+	}
+
+	result = decoratortesting.NewDecoratorTest(t, &GitTagDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{})
+
+	errors = decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("v1.2.0").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GitTagDecorator tagged test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestRequireCleanWorktreeDecorator_Clean(t *testing.T) {
+	initGitRepo(t)
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo releasing"),
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &RequireCleanWorktreeDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{}, content)
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorCodeContains(`"status", "--porcelain"`, "worktree has uncommitted changes").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequireCleanWorktreeDecorator clean test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestRequireCleanWorktreeDecorator_Dirty(t *testing.T) {
+	dir := initGitRepo(t)
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("wip"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	marker := filepath.Join(dir, "ran")
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("touch " + marker),
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &RequireCleanWorktreeDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{}, content)
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("notes.txt").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequireCleanWorktreeDecorator dirty test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("block should not run when the worktree is dirty")
+	}
+
+	// Untracked files can be ignored
+	result = decoratortesting.NewDecoratorTest(t, &RequireCleanWorktreeDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.BoolParam("untracked", false),
+		}, content)
+
+	errors = decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorCodeContains(`"--untracked-files=no"`).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequireCleanWorktreeDecorator untracked test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSummarizeStatus(t *testing.T) {
+	var lines []string
+	for i := 0; i < maxDirtyFilesShown+2; i++ {
+		lines = append(lines, "?? file")
+	}
+
+	summary := summarizeStatus(strings.Join(lines, "\n"))
+	if !strings.HasSuffix(summary, "... and 2 more") {
+		t.Errorf("expected truncated summary, got:\n%s", summary)
+	}
+}
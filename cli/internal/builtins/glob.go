@@ -0,0 +1,395 @@
+package decorators
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// globTemplate expands the pattern in generated code. It mirrors globFiles and
+// shellQuoteList, using the generated quoteShellValue helper, so generated CLIs and the
+// interpreter agree on the result.
+const globTemplate = `func(pattern string) string {
+	hidden := func(name string) bool { return strings.HasPrefix(name, ".") }
+	var match func(pattern, name []string) bool
+	match = func(pattern, name []string) bool {
+		for len(pattern) > 0 {
+			if pattern[0] == "**" {
+				for i := 0; i <= len(name); i++ {
+					if i > 0 && hidden(name[i-1]) {
+						return false
+					}
+					if match(pattern[1:], name[i:]) {
+						return true
+					}
+				}
+				return false
+			}
+			if len(name) == 0 || (hidden(name[0]) && !hidden(pattern[0])) {
+				return false
+			}
+			if ok, _ := path.Match(pattern[0], name[0]); !ok {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		}
+		return len(name) == 0
+	}
+
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	literal, recursive, dotted := 0, false, false
+	for i, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			logf("error", "@glob", "invalid pattern %q: %v", pattern, err)
+			os.Exit(1)
+		}
+		if literal == i && segment != "**" && !strings.ContainsAny(segment, "*?[\\") {
+			literal++
+			continue
+		}
+		recursive = recursive || segment == "**"
+		dotted = dotted || hidden(segment)
+	}
+	base, rest := strings.Join(segments[:literal], "/"), segments[literal:]
+	if literal == 1 && base == "" {
+		base = "/"
+	}
+	root := filepath.FromSlash(base)
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(ctx.Dir, root)
+	}
+	if root == "" {
+		root = "."
+	}
+
+	var matches []string
+	if len(rest) == 0 {
+		if _, err := os.Lstat(root); err == nil {
+			matches = append(matches, base)
+		}
+	} else {
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return nil
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if match(rest, parts) {
+				matches = append(matches, path.Join(base, filepath.ToSlash(rel)))
+			}
+			if d.IsDir() && ((!recursive && len(parts) >= len(rest)) || (hidden(d.Name()) && !dotted)) {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	if len(matches) == 0 {
+{{- if .AllowEmpty}}
+		return ""
+{{- else}}
+		logf("error", "@glob", "no files match %q", pattern)
+		os.Exit(1)
+{{- end}}
+	}
+
+	sort.Strings(matches)
+{{- if .Descending}}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+{{- end}}
+	quoted := make([]string, len(matches))
+	for i, m := range matches {
+		quoted[i] = quoteShellValue(filepath.FromSlash(m), 0)
+	}
+	return strings.Join(quoted, " ")
+}`
+
+// GlobDecorator implements the @glob decorator for expanding file patterns
+type GlobDecorator struct{}
+
+// Name returns the decorator name
+func (g *GlobDecorator) Name() string {
+	return "glob"
+}
+
+// Description returns a human-readable description
+func (g *GlobDecorator) Description() string {
+	return "Files matching a pattern, with ** for any number of directories, as a sorted, shell-quoted list"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GlobDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "pattern",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Pattern relative to the working directory, e.g. \"migrations/*.sql\" or \"src/**/*.go\"",
+		},
+		{
+			Name:        "sort",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Order of the matches: asc (default) or desc",
+		},
+		{
+			Name:        "allowEmpty",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, no matches expand to nothing instead of failing",
+		},
+	}
+}
+
+// ExpandInterpreter returns the matching files for interpreter mode
+func (g *GlobDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	pattern, descending, allowEmpty, err := g.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	matches, err := globFiles(ctx.GetWorkingDir(), pattern, descending)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@glob: %w", err)}
+	}
+	if len(matches) == 0 && !allowEmpty {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@glob: no files match %q", pattern)}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  shellQuoteList(matches),
+		Error: nil,
+	}
+}
+
+// GenerateTemplate returns template for Go code that expands the pattern when the command runs
+func (g *GlobDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	if _, _, _, err := g.extractParameters(ctx, params); err != nil {
+		return nil, err
+	}
+	params, _ = decorators.ResolvePositionalParameters(params, g.ParameterSchema())
+	expression, err := goValueExpression(ctx, ast.FindParameter(params, "pattern").Value)
+	if err != nil {
+		return nil, fmt.Errorf("@glob: %w", err)
+	}
+
+	tmpl, err := template.New("glob").Parse(globTemplate + "({{.Pattern}})")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse glob template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Pattern    string
+			Descending bool
+			AllowEmpty bool
+		}{
+			Pattern:    expression,
+			Descending: ast.GetStringParam(params, "sort", "asc") == "desc",
+			AllowEmpty: ast.GetBoolParam(params, "allowEmpty", false),
+		},
+	}, nil
+}
+
+// ExpandPlan returns description showing the matching files for plan mode
+func (g *GlobDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	description := describeTransform(g, params)
+
+	pattern, descending, allowEmpty, err := g.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: description + "<undefined>", Error: nil}
+	}
+	matches, err := globFiles(ctx.GetWorkingDir(), pattern, descending)
+	if err != nil || (len(matches) == 0 && !allowEmpty) {
+		return &execution.ExecutionResult{Data: description + "<unavailable>", Error: nil}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  description + strconv.Quote(shellQuoteList(matches)),
+		Error: nil,
+	}
+}
+
+// ReferencedVariables returns the names of the variables the pattern reads
+func (g *GlobDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+// extractParameters validates the parameters and returns the pattern with its variables
+// expanded, whether to sort in descending order, and whether no matches are allowed
+func (g *GlobDecorator) extractParameters(ctx execution.BaseContext, params []ast.NamedParameter) (pattern string, descending bool, allowEmpty bool, err error) {
+	if err := decorators.ValidateSchemaCompliance(params, g.ParameterSchema(), "glob"); err != nil {
+		return "", false, false, err
+	}
+	params, err = decorators.ResolvePositionalParameters(params, g.ParameterSchema())
+	if err != nil {
+		return "", false, false, fmt.Errorf("@glob: %w", err)
+	}
+
+	pattern, err = evaluateValue(ctx, ast.FindParameter(params, "pattern").Value)
+	if err != nil {
+		return "", false, false, fmt.Errorf("@glob: %w", err)
+	}
+	if err := validateGlobPattern(pattern); err != nil {
+		return "", false, false, fmt.Errorf("@glob: %w", err)
+	}
+
+	switch order := ast.GetStringParam(params, "sort", "asc"); order {
+	case "asc":
+	case "desc":
+		descending = true
+	default:
+		return "", false, false, fmt.Errorf("@glob sort must be \"asc\" or \"desc\", got %q", order)
+	}
+
+	return pattern, descending, ast.GetBoolParam(params, "allowEmpty", false), nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GlobDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"io/fs", "path", "path/filepath", "sort"})
+}
+
+// validateGlobPattern reports malformed pattern segments, such as an unclosed [
+func validateGlobPattern(pattern string) error {
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// GlobFiles returns the paths matching pattern as @glob does, sorted, relative to dir unless
+// the pattern is absolute
+func GlobFiles(dir, pattern string) ([]string, error) {
+	return globFiles(dir, pattern, false)
+}
+
+// globFiles returns the paths matching pattern, relative to dir unless the pattern is
+// absolute, sorted so the result doesn't depend on the platform or the shell.
+// Segments match as with path.Match, and a ** segment matches any number of directories.
+// As in the shell, wildcards don't match names starting with a dot unless the pattern does.
+func globFiles(dir, pattern string, descending bool) ([]string, error) {
+	if err := validateGlobPattern(pattern); err != nil {
+		return nil, err
+	}
+
+	// The leading segments without wildcards name the directory to search
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	literal, recursive, dotted := 0, false, false
+	for i, segment := range segments {
+		if literal == i && segment != "**" && !strings.ContainsAny(segment, `*?[\`) {
+			literal++
+			continue
+		}
+		recursive = recursive || segment == "**"
+		dotted = dotted || isHiddenName(segment)
+	}
+	base, rest := strings.Join(segments[:literal], "/"), segments[literal:]
+	if literal == 1 && base == "" {
+		base = "/"
+	}
+	root := filepath.FromSlash(base)
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(dir, root)
+	}
+	if root == "" {
+		root = "."
+	}
+
+	var matches []string
+	if len(rest) == 0 {
+		if _, err := os.Lstat(root); err == nil {
+			matches = append(matches, base)
+		}
+	} else {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			// Unreadable or missing directories have no matches
+			if err != nil || p == root {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return nil
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if matchGlobSegments(rest, parts) {
+				matches = append(matches, path.Join(base, filepath.ToSlash(rel)))
+			}
+			if d.IsDir() && ((!recursive && len(parts) >= len(rest)) || (isHiddenName(d.Name()) && !dotted)) {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(matches)
+	if descending {
+		sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	}
+	for i, m := range matches {
+		matches[i] = filepath.FromSlash(m)
+	}
+	return matches, nil
+}
+
+// matchGlobSegments reports whether the path segments in name match the pattern segments
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if i > 0 && isHiddenName(name[i-1]) {
+					return false
+				}
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 || (isHiddenName(name[0]) && !isHiddenName(pattern[0])) {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// isHiddenName reports whether a path segment names a dotfile
+func isHiddenName(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// shellQuoteList joins paths with spaces, single-quoting those the shell would split or expand
+func shellQuoteList(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = execution.QuoteShellValue(p, 0)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// init registers the glob decorator
+func init() {
+	decorators.RegisterValue(&GlobDecorator{})
+}
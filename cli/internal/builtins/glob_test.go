@@ -0,0 +1,187 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// createGlobTree creates the given files, and their directories, under a temporary directory
+func createGlobTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	return root
+}
+
+func TestGlobFiles(t *testing.T) {
+	root := createGlobTree(t,
+		"migrations/002_users.sql",
+		"migrations/001_init.sql",
+		"migrations/README.md",
+		"src/main.go",
+		"src/api/handler.go",
+		"src/api/v1/routes.go",
+		"src/.cache/stale.go",
+		"src/api/.generated.go",
+	)
+
+	tests := []struct {
+		name       string
+		pattern    string
+		descending bool
+		expected   []string
+	}{
+		{"single directory", "migrations/*.sql", false, []string{"migrations/001_init.sql", "migrations/002_users.sql"}},
+		{"descending", "migrations/*.sql", true, []string{"migrations/002_users.sql", "migrations/001_init.sql"}},
+		{"recursive", "src/**/*.go", false, []string{"src/api/handler.go", "src/api/v1/routes.go", "src/main.go"}},
+		{"recursive directories", "src/**/v1", false, []string{"src/api/v1"}},
+		{"dotfiles when the pattern has a dot", "src/**/.*.go", false, []string{"src/api/.generated.go"}},
+		{"character class", "migrations/00[2-9]_*", false, []string{"migrations/002_users.sql"}},
+		{"literal path", "src/main.go", false, []string{"src/main.go"}},
+		{"missing literal path", "src/missing.go", false, nil},
+		{"missing directory", "docs/*.md", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := globFiles(root, tt.pattern, tt.descending)
+			if err != nil {
+				t.Fatalf("globFiles(%q) failed: %v", tt.pattern, err)
+			}
+			var expected []string
+			for _, e := range tt.expected {
+				expected = append(expected, filepath.FromSlash(e))
+			}
+			if !reflect.DeepEqual(matches, expected) {
+				t.Errorf("globFiles(%q) = %q, want %q", tt.pattern, matches, expected)
+			}
+		})
+	}
+
+	absolute, err := globFiles("", filepath.Join(root, "migrations", "*.md"), false)
+	if err != nil {
+		t.Fatalf("globFiles with an absolute pattern failed: %v", err)
+	}
+	if want := []string{filepath.Join(root, "migrations", "README.md")}; !reflect.DeepEqual(absolute, want) {
+		t.Errorf("globFiles with an absolute pattern = %q, want %q", absolute, want)
+	}
+
+	if _, err := globFiles(root, "migrations/[0-9*.sql", false); err == nil {
+		t.Errorf("globFiles should reject a malformed pattern")
+	}
+}
+
+func TestShellQuoteList(t *testing.T) {
+	got := shellQuoteList([]string{"build/app-1.0.tar.gz", "my file.txt", "it's", "$HOME"})
+	if want := `build/app-1.0.tar.gz 'my file.txt' 'it'\''s' '$HOME'`; got != want {
+		t.Errorf("shellQuoteList = %s, want %s", got, want)
+	}
+}
+
+func TestGlobDecorator_WorkingDirectory(t *testing.T) {
+	root := createGlobTree(t, "migrations/001 init.sql", "migrations/002_users.sql")
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithWorkingDir(root)
+	ctx.SetVariable("DIR", "migrations")
+
+	result := (&GlobDecorator{}).ExpandInterpreter(ctx, []ast.NamedParameter{
+		decoratortesting.StringParam("pattern", "@var(DIR)/*.sql"),
+	})
+	if result.Error != nil {
+		t.Fatalf("@glob failed: %v", result.Error)
+	}
+	want := shellQuoteList([]string{filepath.FromSlash("migrations/001 init.sql"), filepath.FromSlash("migrations/002_users.sql")})
+	if result.Data != want {
+		t.Errorf("@glob = %q, want %q", result.Data, want)
+	}
+}
+
+func TestGlobDecorator_Generate(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &GlobDecorator{}).
+		WithVariable("DIR", "migrations").
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("pattern", "@var(DIR)/*.sql"),
+			decoratortesting.StringParam("sort", "desc"),
+			decoratortesting.BoolParam("allowEmpty", true),
+		})
+
+	errors := decoratortesting.Assert(result).
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(
+			"filepath.Join(ctx.Dir, root)",
+			"sort.Sort(sort.Reverse(sort.StringSlice(matches)))",
+			`}(DIR + "/*.sql")`,
+		).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GlobDecorator generate test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestGlobDecorator_NoMatches(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &GlobDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("pattern", "no-such-directory/*.sql"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("no files match").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`logf("error", "@glob", "no files match %q", pattern)`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GlobDecorator no matches test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	result = decoratortesting.NewDecoratorTest(t, &GlobDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("pattern", "no-such-directory/*.sql"),
+			decoratortesting.BoolParam("allowEmpty", true),
+		})
+
+	errors = decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("GlobDecorator allowEmpty test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestGlobDecorator_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"missing pattern":   nil,
+		"invalid sort":      {decoratortesting.StringParam("pattern", "*.go"), decoratortesting.StringParam("sort", "random")},
+		"malformed pattern": {decoratortesting.StringParam("pattern", "[a-")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &GlobDecorator{}).TestValueDecorator(params)
+			errors := decoratortesting.Assert(result).
+				InterpreterFails("").
+				GeneratorFails("").
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("GlobDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
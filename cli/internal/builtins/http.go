@@ -0,0 +1,496 @@
+package decorators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// maxErrorBodyLength caps how much of a failed response body is included in errors
+const maxErrorBodyLength = 512
+
+// sensitiveQueryParams lists query parameter names whose values are never logged
+var sensitiveQueryParams = []string{"token", "key", "secret", "password", "passwd", "signature", "sig", "auth", "credential"}
+
+// httpTemplate performs the request in generated code. It mirrors
+// HTTPDecorator.execute so generated CLIs and the interpreter behave the same.
+const httpTemplate = `func() error {
+	var secrets []string
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			value, ok := ctx.Env[name]
+			if !ok {
+				value = os.Getenv(name)
+			}
+			if len(value) >= 4 {
+				secrets = append(secrets, value)
+			}
+			return value
+		})
+	}
+	redact := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, "***")
+		}
+		return s
+	}
+
+	target := expand({{printf "%q" .URL}})
+	payload := expand({{printf "%q" .JSON}})
+	headers := map[string]string{ {{range $name, $value := .Headers}}
+		{{printf "%q" $name}}: expand({{printf "%q" $value}}),{{end}}
+	}
+	client := &http.Client{Timeout: time.Duration({{.Timeout}})}
+
+	var lastErr error
+	for attempt := 0; attempt <= {{.Retries}}; attempt++ {
+		if attempt > 0 {
+			logf("warn", "@http", "retrying in %s (attempt %d/%d): %s", time.Duration({{.RetryDelay}}), attempt+1, {{.Retries}}+1, lastErr)
+			time.Sleep(time.Duration({{.RetryDelay}}))
+		}
+
+		req, err := http.NewRequest({{printf "%q" .Method}}, target, strings.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("@http: invalid request to %s: %s", {{printf "%q" .DisplayURL}}, redact(err.Error()))
+		}
+		{{if .JSON}}req.Header.Set("Content-Type", "application/json")
+		{{end}}for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		logf("info", "@http", "→ %s %s", {{printf "%q" .Method}}, {{printf "%q" .DisplayURL}})
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			if cause := errors.Unwrap(err); cause != nil {
+				err = cause
+			}
+			lastErr = fmt.Errorf("@http: %s %s failed: %s", {{printf "%q" .Method}}, {{printf "%q" .DisplayURL}}, redact(err.Error()))
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("@http: failed to read response from %s: %s", {{printf "%q" .DisplayURL}}, redact(err.Error()))
+			continue
+		}
+		logf("info", "@http", "← %s (%s)", resp.Status, time.Since(start).Round(time.Millisecond))
+
+		if {{if .ExpectStatus}}resp.StatusCode != {{.ExpectStatus}}{{else}}resp.StatusCode < 200 || resp.StatusCode >= 300{{end}} {
+			detail := "{{if .ExpectStatus}}, expected {{.ExpectStatus}}{{end}}"
+			if len(body) > {{.MaxErrorBodyLength}} {
+				body = append(body[:{{.MaxErrorBodyLength}}], "..."...)
+			}
+			if text := redact(strings.TrimSpace(string(body))); text != "" {
+				detail += ": " + text
+			}
+			lastErr = fmt.Errorf("@http: %s %s returned %s%s", {{printf "%q" .Method}}, {{printf "%q" .DisplayURL}}, resp.Status, detail)
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				continue
+			}
+			return lastErr
+		}
+
+		{{if .SaveAs}}ctx.Env[{{printf "%q" .SaveAs}}] = string(body)
+		{{else}}os.Stdout.Write(body)
+		{{end}}return nil
+	}
+	return lastErr
+}()`
+
+// HTTPDecorator implements the @http decorator for making HTTP requests natively
+type HTTPDecorator struct{}
+
+// httpRequest holds the validated parameters for a single @http call
+type httpRequest struct {
+	URL          string
+	Method       string
+	JSON         string
+	Headers      map[string]string
+	ExpectStatus int
+	Retries      int
+	RetryDelay   time.Duration
+	Timeout      time.Duration
+	SaveAs       string
+}
+
+// Name returns the decorator name
+func (h *HTTPDecorator) Name() string {
+	return "http"
+}
+
+// Description returns a human-readable description
+func (h *HTTPDecorator) Description() string {
+	return "Make an HTTP request with status checks, retries, and response capture"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (h *HTTPDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "url",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Request URL; $VAR references are expanded at request time and never logged",
+		},
+		{
+			Name:        "method",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "HTTP method (default: GET, or POST when json is set)",
+		},
+		{
+			Name:        "json",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "JSON request body, sent with Content-Type: application/json",
+		},
+		{
+			Name:        "headers",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Request headers as \"Name: value; Other: value\"",
+		},
+		{
+			Name:        "expectStatus",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Required response status (default: any 2xx)",
+		},
+		{
+			Name:        "retries",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Retries on network errors, 5xx, and 429 responses (default: 0)",
+		},
+		{
+			Name:        "retryDelay",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "Delay between retries (default: 1s)",
+		},
+		{
+			Name:        "timeout",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "Timeout for each attempt (default: 30s)",
+		},
+		{
+			Name:        "saveAs",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Environment variable that receives the response body instead of stdout",
+		},
+	}
+}
+
+// ExpandInterpreter performs the request in interpreter mode
+func (h *HTTPDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	req, err := h.extractRequest(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	body, err := h.execute(ctx, req)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	if req.SaveAs != "" {
+		ctx.ExportEnv(req.SaveAs, body)
+	} else {
+		fmt.Fprint(os.Stdout, body)
+	}
+
+	return &execution.ExecutionResult{
+		Data:  "true", // Return "true" for shell chaining
+		Error: nil,
+	}
+}
+
+// GenerateTemplate returns template for Go code that performs the request
+func (h *HTTPDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	req, err := h.extractRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("http").Parse(httpTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse http template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			httpRequest
+			DisplayURL         string
+			Timeout            int64
+			RetryDelay         int64
+			MaxErrorBodyLength int
+		}{
+			httpRequest:        *req,
+			DisplayURL:         redactURL(req.URL),
+			Timeout:            int64(req.Timeout),
+			RetryDelay:         int64(req.RetryDelay),
+			MaxErrorBodyLength: maxErrorBodyLength,
+		},
+	}, nil
+}
+
+// ExpandPlan creates a plan element describing the request without sending it
+func (h *HTTPDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	req, err := h.extractRequest(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	description := fmt.Sprintf("%s %s", req.Method, redactURL(req.URL))
+	if req.ExpectStatus != 0 {
+		description += fmt.Sprintf(", expect %d", req.ExpectStatus)
+	}
+	if req.Retries > 0 {
+		description += fmt.Sprintf(", %d retries", req.Retries)
+	}
+	if req.SaveAs != "" {
+		description += fmt.Sprintf(" → $%s", req.SaveAs)
+	}
+
+	return &execution.ExecutionResult{
+		Data: map[string]interface{}{
+			"type":        "http",
+			"command":     fmt.Sprintf("@http(%s %s)", req.Method, redactURL(req.URL)),
+			"description": description,
+		},
+		Error: nil,
+	}
+}
+
+// execute sends the request, retrying transient failures, and returns the response body
+func (h *HTTPDecorator) execute(ctx execution.InterpreterContext, req *httpRequest) (string, error) {
+	var secrets []string
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			value, _ := ctx.GetEnv(name)
+			if len(value) >= 4 {
+				secrets = append(secrets, value)
+			}
+			return value
+		})
+	}
+	redact := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, "***")
+		}
+		return s
+	}
+
+	displayURL := redactURL(req.URL)
+	target := expand(req.URL)
+	payload := expand(req.JSON)
+	headers := make(map[string]string, len(req.Headers))
+	for name, value := range req.Headers {
+		headers[name] = expand(value)
+	}
+	client := &http.Client{Timeout: req.Timeout}
+
+	var lastErr error
+	for attempt := 0; attempt <= req.Retries; attempt++ {
+		if attempt > 0 {
+			ctx.Logger().Warnf("@http", "retrying in %s (attempt %d/%d): %s", req.RetryDelay, attempt+1, req.Retries+1, lastErr)
+			select {
+			case <-time.After(req.RetryDelay):
+			case <-ctx.Done():
+				return "", fmt.Errorf("@http: %s %s cancelled: %w", req.Method, displayURL, ctx.Err())
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.Method, target, strings.NewReader(payload))
+		if err != nil {
+			return "", fmt.Errorf("@http: invalid request to %s: %s", displayURL, redact(err.Error()))
+		}
+		if req.JSON != "" {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		for name, value := range headers {
+			httpReq.Header.Set(name, value)
+		}
+
+		ctx.Logger().Infof("@http", "→ %s %s", req.Method, displayURL)
+		start := time.Now()
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			// Drop the *url.Error wrapper, which repeats the expanded URL
+			if cause := errors.Unwrap(err); cause != nil {
+				err = cause
+			}
+			lastErr = fmt.Errorf("@http: %s %s failed: %s", req.Method, displayURL, redact(err.Error()))
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("@http: failed to read response from %s: %s", displayURL, redact(err.Error()))
+			continue
+		}
+		ctx.Logger().Infof("@http", "← %s (%s)", resp.Status, time.Since(start).Round(time.Millisecond))
+
+		ok := resp.StatusCode >= 200 && resp.StatusCode < 300
+		expectation := ""
+		if req.ExpectStatus != 0 {
+			ok = resp.StatusCode == req.ExpectStatus
+			expectation = fmt.Sprintf(", expected %d", req.ExpectStatus)
+		}
+		if !ok {
+			if len(body) > maxErrorBodyLength {
+				body = append(body[:maxErrorBodyLength], "..."...)
+			}
+			if detail := redact(strings.TrimSpace(string(body))); detail != "" {
+				expectation += ": " + detail
+			}
+			lastErr = fmt.Errorf("@http: %s %s returned %s%s", req.Method, displayURL, resp.Status, expectation)
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				continue
+			}
+			return "", lastErr
+		}
+
+		return string(body), nil
+	}
+	return "", lastErr
+}
+
+// extractRequest validates parameters and returns the request they describe
+func (h *HTTPDecorator) extractRequest(params []ast.NamedParameter) (*httpRequest, error) {
+	if err := decorators.ValidateSchemaCompliance(params, h.ParameterSchema(), "http"); err != nil {
+		return nil, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, h.ParameterSchema())
+	if err != nil {
+		return nil, fmt.Errorf("@http: %w", err)
+	}
+	if ast.FindParameter(params, "saveAs") != nil {
+		if err := decorators.ValidateEnvironmentVariableName(params, "saveAs", "http"); err != nil {
+			return nil, err
+		}
+	}
+
+	req := &httpRequest{
+		URL:          ast.GetStringParam(params, "url", ""),
+		Method:       strings.ToUpper(ast.GetStringParam(params, "method", "")),
+		JSON:         ast.GetStringParam(params, "json", ""),
+		ExpectStatus: ast.GetIntParam(params, "expectStatus", 0),
+		Retries:      ast.GetIntParam(params, "retries", 0),
+		RetryDelay:   ast.GetDurationParam(params, "retryDelay", time.Second),
+		Timeout:      ast.GetDurationParam(params, "timeout", 30*time.Second),
+		SaveAs:       ast.GetStringParam(params, "saveAs", ""),
+	}
+
+	if req.URL == "" {
+		return nil, fmt.Errorf("@http: url must not be empty")
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+		if req.JSON != "" {
+			req.Method = http.MethodPost
+		}
+	}
+	if strings.IndexFunc(req.Method, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return nil, fmt.Errorf("@http: invalid method %q", req.Method)
+	}
+	if req.JSON != "" && !strings.Contains(req.JSON, "$") && !json.Valid([]byte(req.JSON)) {
+		return nil, fmt.Errorf("@http: json body is not valid JSON")
+	}
+	if req.ExpectStatus != 0 && (req.ExpectStatus < 100 || req.ExpectStatus > 599) {
+		return nil, fmt.Errorf("@http: expectStatus must be between 100 and 599, got %d", req.ExpectStatus)
+	}
+	if req.Retries < 0 {
+		return nil, fmt.Errorf("@http: retries must not be negative, got %d", req.Retries)
+	}
+	if req.Timeout <= 0 {
+		return nil, fmt.Errorf("@http: timeout must be positive")
+	}
+
+	req.Headers, err = parseHeaders(ast.GetStringParam(params, "headers", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// parseHeaders parses "Name: value; Other: value" into a header map
+func parseHeaders(spec string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, found := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("@http: invalid header %q, expected \"Name: value\"", entry)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// redactURL hides credentials and sensitive query values so URLs are safe to log
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "***")
+		}
+	}
+
+	query := u.Query()
+	redacted := false
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, sensitive := range sensitiveQueryParams {
+			if strings.Contains(lower, sensitive) {
+				query[name] = []string{"***"}
+				redacted = true
+				break
+			}
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+
+	// Keep placeholders like ${TOKEN} readable instead of percent-encoded
+	display, err := url.PathUnescape(u.String())
+	if err != nil {
+		return u.String()
+	}
+	return display
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (h *HTTPDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, decorators.TimeImports, []string{"errors", "io", "net/http"})
+}
+
+// init registers the http decorator
+func init() {
+	decorators.RegisterAction(&HTTPDecorator{})
+}
@@ -0,0 +1,286 @@
+package decorators
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// KeyringServiceEnvVar names the OS keyring service secrets are stored under
+const KeyringServiceEnvVar = "DEVCMD_KEYRING_SERVICE"
+
+// DefaultKeyringService is the keyring service used when none is configured
+const DefaultKeyringService = "devcmd"
+
+// ErrSecretNotFound is returned when the keyring has no secret with the requested name
+var ErrSecretNotFound = errors.New("secret not found in the keyring")
+
+// keyringNamePattern restricts keyring service and secret names to characters that need no
+// quoting in any of the keyring tools
+var keyringNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*$`)
+
+// keyringTargetEnvVar passes the Windows credential name to the PowerShell scripts
+const keyringTargetEnvVar = "DEVCMD_KEYRING_TARGET"
+
+// windowsCredentialTypes declares the Credential Manager API for the PowerShell scripts
+const windowsCredentialTypes = `Add-Type -Namespace Devcmd -Name Cred -MemberDefinition @'
+[StructLayout(LayoutKind.Sequential, CharSet = CharSet.Unicode)]
+public struct CREDENTIAL {
+    public int Flags; public int Type; public string TargetName; public string Comment;
+    public long LastWritten; public int CredentialBlobSize; public IntPtr CredentialBlob;
+    public int Persist; public int AttributeCount; public IntPtr Attributes;
+    public string TargetAlias; public string UserName;
+}
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredReadW(string target, int type, int flags, out IntPtr credential);
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredWriteW(ref CREDENTIAL credential, int flags);
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredDeleteW(string target, int type, int flags);
+[DllImport("advapi32.dll")]
+public static extern void CredFree(IntPtr credential);
+'@
+[Console]::InputEncoding = [Text.Encoding]::UTF8
+[Console]::OutputEncoding = [Text.Encoding]::UTF8
+`
+
+// windowsCredReadScript prints a generic credential, exiting 2 when it doesn't exist
+const windowsCredReadScript = windowsCredentialTypes + `$p = [IntPtr]::Zero
+if (-not [Devcmd.Cred]::CredReadW($env:DEVCMD_KEYRING_TARGET, 1, 0, [ref]$p)) {
+    $err = [Runtime.InteropServices.Marshal]::GetLastWin32Error()
+    if ($err -eq 1168) { exit 2 }
+    [Console]::Error.Write("CredRead failed with error $err"); exit 1
+}
+$c = [Runtime.InteropServices.Marshal]::PtrToStructure($p, [type][Devcmd.Cred+CREDENTIAL])
+[Console]::Out.Write([Runtime.InteropServices.Marshal]::PtrToStringUni($c.CredentialBlob, $c.CredentialBlobSize / 2))
+[Devcmd.Cred]::CredFree($p)
+`
+
+// windowsCredWriteScript stores stdin as a generic credential
+const windowsCredWriteScript = windowsCredentialTypes + `$value = [Console]::In.ReadToEnd()
+$c = New-Object Devcmd.Cred+CREDENTIAL
+$c.Type = 1; $c.Persist = 2
+$c.TargetName = $env:DEVCMD_KEYRING_TARGET; $c.UserName = $env:USERNAME
+$c.CredentialBlobSize = $value.Length * 2
+$c.CredentialBlob = [Runtime.InteropServices.Marshal]::StringToCoTaskMemUni($value)
+$ok = [Devcmd.Cred]::CredWriteW([ref]$c, 0)
+$err = [Runtime.InteropServices.Marshal]::GetLastWin32Error()
+[Runtime.InteropServices.Marshal]::ZeroFreeCoTaskMemUnicode($c.CredentialBlob)
+if (-not $ok) { [Console]::Error.Write("CredWrite failed with error $err"); exit 1 }
+`
+
+// windowsCredDeleteScript removes a generic credential, exiting 2 when it doesn't exist
+const windowsCredDeleteScript = windowsCredentialTypes + `if (-not [Devcmd.Cred]::CredDeleteW($env:DEVCMD_KEYRING_TARGET, 1, 0)) {
+    $err = [Runtime.InteropServices.Marshal]::GetLastWin32Error()
+    if ($err -eq 1168) { exit 2 }
+    [Console]::Error.Write("CredDelete failed with error $err"); exit 1
+}
+`
+
+// keyringOp is an operation on the OS keyring
+type keyringOp int
+
+const (
+	keyringGet keyringOp = iota
+	keyringSet
+	keyringDelete
+)
+
+// keyringCommand is the command that performs a keyring operation on one platform
+type keyringCommand struct {
+	cmd      *exec.Cmd
+	notFound func(code int, stderr string) bool // Whether a failed run means the secret doesn't exist
+}
+
+// newKeyringCommand returns the keyring tool invocation for an operation: security on macOS,
+// Credential Manager through PowerShell on Windows, and libsecret's secret-tool elsewhere.
+// Values are passed on stdin, never as arguments.
+func newKeyringCommand(goos string, op keyringOp, service, key, value string) keyringCommand {
+	switch goos {
+	case "darwin":
+		// security exits 44 when the item could not be found in the keychain
+		notFound := func(code int, stderr string) bool { return code == 44 }
+		switch op {
+		case keyringGet:
+			return keyringCommand{exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w"), notFound}
+		case keyringSet:
+			// Interactive mode reads the command from stdin, and -X takes the value hex-encoded
+			cmd := exec.Command("security", "-i")
+			cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, key, hex.EncodeToString([]byte(value))))
+			return keyringCommand{cmd, notFound}
+		default:
+			return keyringCommand{exec.Command("security", "delete-generic-password", "-s", service, "-a", key), notFound}
+		}
+	case "windows":
+		script := windowsCredReadScript
+		switch op {
+		case keyringSet:
+			script = windowsCredWriteScript
+		case keyringDelete:
+			script = windowsCredDeleteScript
+		}
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), keyringTargetEnvVar+"="+service+":"+key)
+		if op == keyringSet {
+			cmd.Stdin = strings.NewReader(value)
+		}
+		return keyringCommand{cmd, func(code int, stderr string) bool { return code == 2 }}
+	default:
+		// secret-tool exits 1 without a message when nothing matches
+		notFound := func(code int, stderr string) bool { return code == 1 && stderr == "" }
+		switch op {
+		case keyringGet:
+			return keyringCommand{exec.Command("secret-tool", "lookup", "service", service, "account", key), notFound}
+		case keyringSet:
+			cmd := exec.Command("secret-tool", "store", "--label", service+" "+key, "service", service, "account", key)
+			cmd.Stdin = strings.NewReader(value)
+			return keyringCommand{cmd, notFound}
+		default:
+			return keyringCommand{exec.Command("secret-tool", "clear", "service", service, "account", key), notFound}
+		}
+	}
+}
+
+// runKeyring runs a keyring operation and returns its output
+func runKeyring(op keyringOp, service, key, value string) (string, error) {
+	if !keyringNamePattern.MatchString(service) {
+		return "", fmt.Errorf("invalid keyring service %q: use letters, digits, and _ . / -", service)
+	}
+	if !keyringNamePattern.MatchString(key) {
+		return "", fmt.Errorf("invalid secret name %q: use letters, digits, and _ . / -", key)
+	}
+
+	kc := newKeyringCommand(runtime.GOOS, op, service, key, value)
+	var stderr bytes.Buffer
+	kc.cmd.Stderr = &stderr
+	out, err := kc.cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && kc.notFound(exitErr.ExitCode(), msg) {
+			return "", fmt.Errorf("%w: %q (service %q)", ErrSecretNotFound, key, service)
+		}
+		if msg != "" {
+			err = fmt.Errorf("%s", msg)
+		}
+		return "", fmt.Errorf("keyring %s failed: %w", kc.cmd.Args[0], err)
+	}
+	return string(out), nil
+}
+
+// KeyringGet reads a secret from the OS keyring
+func KeyringGet(service, key string) (string, error) {
+	value, err := runKeyring(keyringGet, service, key, "")
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "darwin" {
+		// security -w ends the value with a newline
+		value = strings.TrimSuffix(value, "\n")
+	}
+	return value, nil
+}
+
+// KeyringSet stores a secret in the OS keyring, replacing any existing value
+func KeyringSet(service, key, value string) error {
+	_, err := runKeyring(keyringSet, service, key, value)
+	return err
+}
+
+// KeyringDelete removes a secret from the OS keyring
+func KeyringDelete(service, key string) error {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		// secret-tool clear succeeds whether or not anything matched
+		if _, err := KeyringGet(service, key); err != nil {
+			return err
+		}
+	}
+	_, err := runKeyring(keyringDelete, service, key, "")
+	return err
+}
+
+// keyringTemplate mirrors keyringProvider.Get in generated code
+var keyringTemplate = fmt.Sprintf(`func(name, path string, getenv func(string) string) (string, error) {
+		service := path
+		if service == "" {
+			service = getenv(%[1]q)
+		}
+		if service == "" {
+			service = %[2]q
+		}
+		var cmd *execpkg.Cmd
+		notFound := func(code int, stderr string) bool { return code == 1 && stderr == "" }
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = execpkg.Command("security", "find-generic-password", "-s", service, "-a", name, "-w")
+			notFound = func(code int, stderr string) bool { return code == 44 }
+		case "windows":
+			cmd = execpkg.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", %[3]q)
+			cmd.Env = append(os.Environ(), %[4]q+"="+service+":"+name)
+			notFound = func(code int, stderr string) bool { return code == 2 }
+		default:
+			cmd = execpkg.Command("secret-tool", "lookup", "service", service, "account", name)
+		}
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if exitErr, ok := err.(*execpkg.ExitError); ok && notFound(exitErr.ExitCode(), msg) {
+				return "", fmt.Errorf("secret %%q not found in the keyring (service %%q); add it with devcmd secret set %%s", name, service, name)
+			}
+			if msg != "" {
+				err = fmt.Errorf("%%s", msg)
+			}
+			return "", fmt.Errorf("keyring %%s failed: %%v", cmd.Args[0], err)
+		}
+		if runtime.GOOS == "darwin" {
+			return strings.TrimSuffix(string(out), "\n"), nil
+		}
+		return string(out), nil
+	}`, KeyringServiceEnvVar, DefaultKeyringService, windowsCredReadScript, keyringTargetEnvVar)
+
+// keyringProvider reads secrets from the OS keyring; the path parameter overrides the
+// secrets.service setting
+type keyringProvider struct{}
+
+func (keyringProvider) Name() string { return KeyringProvider }
+
+func (keyringProvider) Get(req SecretRequest) (string, error) {
+	service := keyringServiceFor(req)
+	value, err := KeyringGet(service, req.Name)
+	if errors.Is(err, ErrSecretNotFound) {
+		return "", fmt.Errorf("secret %q not found in the keyring (service %q); add it with devcmd secret set %s", req.Name, service, req.Name)
+	}
+	return value, err
+}
+
+func (keyringProvider) Source(req SecretRequest) string {
+	return fmt.Sprintf("keyring service %q", keyringServiceFor(req))
+}
+
+func (keyringProvider) Template() string { return keyringTemplate }
+
+func (keyringProvider) Imports() []string { return []string{"os/exec", "runtime"} }
+
+// keyringServiceFor returns the keyring service for a request
+func keyringServiceFor(req SecretRequest) string {
+	if req.Path != "" {
+		return req.Path
+	}
+	if service := req.Getenv(KeyringServiceEnvVar); service != "" {
+		return service
+	}
+	return DefaultKeyringService
+}
+
+// init registers the keyring secret provider
+func init() {
+	RegisterSecretProvider(keyringProvider{})
+}
@@ -0,0 +1,188 @@
+package decorators
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeSecretTool installs a secret-tool that keeps secrets as files in a directory
+func installFakeSecretTool(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the keyring uses secret-tool only on Linux and other Unix systems")
+	}
+	store := t.TempDir()
+	installFakeCLI(t, "secret-tool", fmt.Sprintf(`op=$1; shift
+[ "$op" = store ] && shift 2
+[ "$1 $3" = "service account" ] || { echo "unexpected arguments: $*" >&2; exit 2; }
+f=%q/"$2.$4"
+case $op in
+lookup) [ -f "$f" ] || exit 1; cat "$f" ;;
+store) cat > "$f" ;;
+clear) rm -f "$f" ;;
+esac`, store))
+}
+
+func TestKeyring_SetGetDelete(t *testing.T) {
+	installFakeSecretTool(t)
+
+	if err := KeyringSet("proj", "api_token", "tok 123\nline two"); err != nil {
+		t.Fatalf("KeyringSet failed: %v", err)
+	}
+	value, err := KeyringGet("proj", "api_token")
+	if err != nil || value != "tok 123\nline two" {
+		t.Fatalf("KeyringGet = %q, %v; want the stored value", value, err)
+	}
+	if _, err := KeyringGet("other", "api_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("KeyringGet in another service = %v, want ErrSecretNotFound", err)
+	}
+
+	if err := KeyringDelete("proj", "api_token"); err != nil {
+		t.Fatalf("KeyringDelete failed: %v", err)
+	}
+	if _, err := KeyringGet("proj", "api_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("KeyringGet after delete = %v, want ErrSecretNotFound", err)
+	}
+	if err := KeyringDelete("proj", "api_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("deleting a missing secret = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestKeyring_ToolFails(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the keyring uses secret-tool only on Linux and other Unix systems")
+	}
+	installFakeCLI(t, "secret-tool", `echo "Cannot autolaunch D-Bus without X11 \$DISPLAY" >&2; exit 1`)
+
+	_, err := KeyringGet("proj", "api_token")
+	if err == nil || errors.Is(err, ErrSecretNotFound) || !strings.Contains(err.Error(), "Cannot autolaunch D-Bus") {
+		t.Errorf("KeyringGet error = %v, want the secret-tool message", err)
+	}
+}
+
+func TestKeyring_InvalidNames(t *testing.T) {
+	for _, tc := range []struct{ service, key string }{
+		{"proj", "api token"},
+		{"proj", "-w"},
+		{"my proj", "api_token"},
+		{"", "api_token"},
+	} {
+		if _, err := KeyringGet(tc.service, tc.key); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("KeyringGet(%q, %q) error = %v, want an invalid name error", tc.service, tc.key, err)
+		}
+	}
+}
+
+func TestNewKeyringCommand_ValuesStayOffTheCommandLine(t *testing.T) {
+	for _, goos := range []string{"darwin", "windows", "linux"} {
+		t.Run(goos, func(t *testing.T) {
+			kc := newKeyringCommand(goos, keyringSet, "proj", "api_token", "hunter2")
+			if strings.Contains(strings.Join(kc.cmd.Args, " "), "hunter2") {
+				t.Errorf("the value is on the command line: %q", kc.cmd.Args)
+			}
+			if kc.cmd.Stdin == nil {
+				t.Fatal("the value is not passed on stdin")
+			}
+			stdin, err := io.ReadAll(kc.cmd.Stdin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(stdin), "hunter2") && !strings.Contains(string(stdin), hex.EncodeToString([]byte("hunter2"))) {
+				t.Errorf("stdin = %q, want the value", stdin)
+			}
+		})
+	}
+
+	kc := newKeyringCommand("windows", keyringGet, "proj", "api_token", "")
+	if !containsEnv(kc.cmd.Env, keyringTargetEnvVar+"=proj:api_token") {
+		t.Errorf("windows credential target is not set in the environment")
+	}
+}
+
+func containsEnv(env []string, entry string) bool {
+	for _, e := range env {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSecretDecorator_Keyring(t *testing.T) {
+	installFakeSecretTool(t)
+	t.Setenv(KeyringServiceEnvVar, "proj")
+	if err := KeyringSet("proj", "db.password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		params []ast.NamedParameter
+		env    string
+		call   string
+	}{
+		{"provider parameter", []ast.NamedParameter{decoratortesting.StringParam("key", "db.password"), decoratortesting.StringParam("provider", "keyring")}, "", `readSecret(ctx, "keyring", "db.password", "")`},
+		{"provider setting", []ast.NamedParameter{decoratortesting.StringParam("key", "db.password")}, "keyring", `readSecret(ctx, "", "db.password", "")`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(SecretsProviderEnvVar, tc.env)
+			result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).TestValueDecorator(tc.params)
+			errors := decoratortesting.Assert(result).
+				InterpreterSucceeds().
+				InterpreterReturns("hunter2").
+				GeneratorSucceeds().
+				GeneratorCodeContains(tc.call).
+				PlanSucceeds().
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+			if plan := fmt.Sprint(result.PlanResult.Data); plan != `@secret(db.password) → *** (keyring service "proj")` {
+				t.Errorf("plan = %q", plan)
+			}
+		})
+	}
+}
+
+func TestSecretDecorator_KeyringMissingSecret(t *testing.T) {
+	installFakeSecretTool(t)
+	t.Setenv(SecretsProviderEnvVar, "keyring")
+	t.Setenv(KeyringServiceEnvVar, "")
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "api_token")})
+	errors := decoratortesting.Assert(result).
+		InterpreterFails(`secret "api_token" not found in the keyring (service "devcmd"); add it with devcmd secret set api_token`).
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSecretDecorator_UnknownProvider(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "api_token"), decoratortesting.StringParam("provider", "lastpass")})
+	errors := decoratortesting.Assert(result).
+		InterpreterFails(`@secret provider: unknown secrets provider "lastpass": use command, env, file, keyring, oidc, sops, vault`).
+		GeneratorFails(`@secret provider: unknown secrets provider "lastpass": use command, env, file, keyring, oidc, sops, vault`).
+		PlanFails(`@secret provider: unknown secrets provider "lastpass": use command, env, file, keyring, oidc, sops, vault`).
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	t.Setenv(SecretsProviderEnvVar, "lastpass")
+	result = decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "api_token")})
+	if errors := decoratortesting.Assert(result).InterpreterFails(`unknown secrets provider "lastpass"`).Validate(); len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,331 @@
+package decorators
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// resourceLimits holds the limits requested by @limits; zero values are unset
+type resourceLimits struct {
+	CPU     float64 // Number of CPUs, enforced as a cgroup CPU quota
+	Memory  int64   // Bytes, enforced as a cgroup memory maximum or an address space rlimit
+	Nice    int     // Scheduling niceness, -20 to 19
+	HasNice bool
+
+	memorySize string // Memory as written, for descriptions
+}
+
+// limitsTemplate prefixes ctx.Shell with the commands that apply the limits. It mirrors limitsShell.
+const limitsTemplate = `// Run with resource limits: {{.Label}}
+{
+	ctx := ctx.Clone()
+	cpu, memory, nice, hasNice := float64({{.Limits.CPU}}), int64({{.Limits.Memory}}), {{.Limits.Nice}}, {{.Limits.HasNice}}
+	var prefix []string
+	cgroup := false
+	if (cpu > 0 || memory > 0) && runtime.GOOS == "linux" {
+		if path, err := execpkg.LookPath("systemd-run"); err == nil {
+			scope := []string{path, "--user", "--scope", "--quiet", "--collect"}
+			if cpu > 0 {
+				scope = append(scope, "-p", fmt.Sprintf("CPUQuota=%.0f%%", cpu*100))
+			}
+			if memory > 0 {
+				scope = append(scope, "-p", "MemoryMax="+strconv.FormatInt(memory, 10))
+			}
+			scope = append(scope, "--")
+			if execpkg.Command(scope[0], append(scope[1:], "true")...).Run() == nil {
+				prefix, cgroup = scope, true
+			}
+		}
+	}
+	if !cgroup {
+		if cpu > 0 {
+			logf("warn", "@limits", "cpu limit not applied (cgroup v2 limits need systemd-run --user on Linux)")
+		}
+		if memory > 0 {
+			logf("warn", "@limits", "cgroup v2 limits unavailable, limiting memory with ulimit -v")
+			prefix = append(prefix, "sh", "-c", "ulimit -v "+strconv.FormatInt((memory+1023)/1024, 10)+" 2>/dev/null; exec \"$@\"", "sh")
+		}
+	}
+	if hasNice {
+		if path, err := execpkg.LookPath("nice"); err == nil {
+			prefix = append(prefix, path, "-n", strconv.Itoa(nice))
+		} else {
+			logf("warn", "@limits", "nice not found in PATH, niceness not applied")
+		}
+	}
+	shell := ctx.Shell
+	if len(shell) == 0 {
+		shell = []string{"sh"}
+	}
+	ctx.Shell = append(prefix, shell...)
+
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// limitsShell returns the command prefix that runs shell steps through base with the limits
+// applied. CPU and memory limits use a transient systemd scope (cgroup v2) when one can be
+// created; otherwise memory falls back to an address space rlimit and the CPU limit is
+// skipped, with a warning. Niceness is applied with nice.
+func limitsShell(limits resourceLimits, base []string) []string {
+	var prefix []string
+	cgroup := false
+	if (limits.CPU > 0 || limits.Memory > 0) && runtime.GOOS == "linux" {
+		if path, err := exec.LookPath("systemd-run"); err == nil {
+			scope := []string{path, "--user", "--scope", "--quiet", "--collect"}
+			if limits.CPU > 0 {
+				scope = append(scope, "-p", fmt.Sprintf("CPUQuota=%.0f%%", limits.CPU*100))
+			}
+			if limits.Memory > 0 {
+				scope = append(scope, "-p", "MemoryMax="+strconv.FormatInt(limits.Memory, 10))
+			}
+			scope = append(scope, "--")
+			// Check once that the user manager accepts the scope before routing steps through it
+			if exec.Command(scope[0], append(scope[1:], "true")...).Run() == nil {
+				prefix, cgroup = scope, true
+			}
+		}
+	}
+	if !cgroup {
+		if limits.CPU > 0 {
+			logging.Warnf("@limits", "cpu limit not applied (cgroup v2 limits need systemd-run --user on Linux)")
+		}
+		if limits.Memory > 0 {
+			logging.Warnf("@limits", "cgroup v2 limits unavailable, limiting memory with ulimit -v")
+			prefix = append(prefix, "sh", "-c", "ulimit -v "+strconv.FormatInt((limits.Memory+1023)/1024, 10)+" 2>/dev/null; exec \"$@\"", "sh")
+		}
+	}
+	if limits.HasNice {
+		if path, err := exec.LookPath("nice"); err == nil {
+			prefix = append(prefix, path, "-n", strconv.Itoa(limits.Nice))
+		} else {
+			logging.Warnf("@limits", "nice not found in PATH, niceness not applied")
+		}
+	}
+
+	if len(base) == 0 {
+		base = []string{"sh"}
+	}
+	return append(prefix, base...)
+}
+
+// LimitsDecorator implements the @limits decorator for running commands with CPU, memory and niceness limits
+type LimitsDecorator struct{}
+
+// Name returns the decorator name
+func (l *LimitsDecorator) Name() string {
+	return "limits"
+}
+
+// Description returns a human-readable description
+func (l *LimitsDecorator) Description() string {
+	return "Run the block's commands with CPU, memory and scheduling priority limits"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (l *LimitsDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "cpu",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Number of CPUs the commands may use, e.g. 2 or 0.5 (Linux with cgroup v2)",
+		},
+		{
+			Name:        "memory",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Maximum memory, e.g. \"512M\" or \"1G\"",
+		},
+		{
+			Name:        "nice",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Scheduling niceness from -20 to 19; higher values yield to other processes",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block with the limits applied in interpreter mode
+func (l *LimitsDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	limits, err := l.extractLimits(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	limitsCtx := ctx.Child()
+	limitsCtx = limitsCtx.WithShell(limitsShell(limits, limitsCtx.GetShell()))
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(limitsCtx, content),
+	}
+}
+
+// GenerateTemplate generates template for running the block with the limits applied
+func (l *LimitsDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	limits, err := l.extractLimits(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("limits").Funcs(ctx.GetTemplateFunctions()).Parse(limitsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse limits template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Limits  resourceLimits
+			Label   string
+			Content []ast.CommandContent
+		}{
+			Limits:  limits,
+			Label:   limitsLabel(limits),
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (l *LimitsDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	limits, err := l.extractLimits(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(l.Name()).
+		WithType("block").
+		WithDescription("Run with resource limits: " + limitsLabel(limits))
+	if limits.CPU > 0 {
+		element = element.WithParameter("cpu", strconv.FormatFloat(limits.CPU, 'f', -1, 64))
+	}
+	if limits.Memory > 0 {
+		element = element.WithParameter("memory", limits.memorySize)
+	}
+	if limits.HasNice {
+		element = element.WithParameter("nice", strconv.Itoa(limits.Nice))
+	}
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractLimits validates parameters and returns the requested limits
+func (l *LimitsDecorator) extractLimits(params []ast.NamedParameter) (resourceLimits, error) {
+	var limits resourceLimits
+	if err := decorators.ValidateParameterCount(params, 1, 3, l.Name()); err != nil {
+		return limits, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, l.ParameterSchema(), l.Name()); err != nil {
+		return limits, err
+	}
+
+	if param := ast.FindParameter(params, "cpu"); param != nil {
+		num, ok := param.Value.(*ast.NumberLiteral)
+		if !ok {
+			return limits, fmt.Errorf("@%s: cpu must be a number", l.Name())
+		}
+		cpu, err := strconv.ParseFloat(num.Value, 64)
+		if err != nil || cpu <= 0 {
+			return limits, fmt.Errorf("@%s: cpu must be a positive number of CPUs", l.Name())
+		}
+		limits.CPU = cpu
+	}
+
+	if param := ast.FindParameter(params, "memory"); param != nil {
+		size := ast.GetStringParam(params, "memory", "")
+		memory, err := parseByteSize(size)
+		if err != nil {
+			return limits, fmt.Errorf("@%s: memory: %w", l.Name(), err)
+		}
+		limits.Memory, limits.memorySize = memory, size
+	}
+
+	if param := ast.FindParameter(params, "nice"); param != nil {
+		num, ok := param.Value.(*ast.NumberLiteral)
+		if !ok {
+			return limits, fmt.Errorf("@%s: nice must be a number", l.Name())
+		}
+		nice, err := strconv.Atoi(num.Value)
+		if err != nil || nice < -20 || nice > 19 {
+			return limits, fmt.Errorf("@%s: nice must be an integer from -20 to 19", l.Name())
+		}
+		limits.Nice, limits.HasNice = nice, true
+	}
+
+	return limits, nil
+}
+
+// parseByteSize parses a size such as "512M", "1G" or "1GiB" into bytes. Units are binary
+// (1K = 1024 bytes) and a bare number is bytes.
+func parseByteSize(size string) (int64, error) {
+	number := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B"), "I")
+	multiplier := int64(1)
+	if number != "" {
+		switch number[len(number)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			number = number[:len(number)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use a size such as \"512M\" or \"1G\")", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// limitsLabel describes the limits for plans and generated code
+func limitsLabel(limits resourceLimits) string {
+	var parts []string
+	if limits.CPU > 0 {
+		parts = append(parts, fmt.Sprintf("cpu %s", strconv.FormatFloat(limits.CPU, 'f', -1, 64)))
+	}
+	if limits.Memory > 0 {
+		parts = append(parts, fmt.Sprintf("memory %s", limits.memorySize))
+	}
+	if limits.HasNice {
+		parts = append(parts, fmt.Sprintf("nice %d", limits.Nice))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (l *LimitsDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"os/exec", "runtime", "strconv"})
+}
+
+// init registers the limits decorator
+func init() {
+	decorators.RegisterBlock(&LimitsDecorator{})
+}
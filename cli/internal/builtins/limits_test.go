@@ -0,0 +1,143 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestLimitsDecorator_SystemdScope(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args.txt")
+	// Record the scope arguments, then run the command after "--"
+	installFakeCLI(t, "systemd-run", `echo "$@" >> `+args+`
+while [ "$1" != "--" ]; do shift; done
+shift
+exec "$@"`)
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &LimitsDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "cpu", Value: &ast.NumberLiteral{Value: "1.5"}},
+			decoratortesting.StringParam("memory", "512M"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo limited > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`cpu, memory, nice, hasNice := float64(1.5), int64(536870912), 0, false`, "ctx.Shell = append(prefix, shell...)").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("LimitsDecorator systemd test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if got, err := os.ReadFile(out); err != nil || string(got) != "limited\n" {
+		t.Errorf("step output = %q (%v), want it to run inside the scope", got, err)
+	}
+	recorded, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatalf("systemd-run was not used: %v", err)
+	}
+	want := "--user --scope --quiet --collect -p CPUQuota=150% -p MemoryMax=536870912 --"
+	if !strings.Contains(string(recorded), want+" sh -c echo limited") {
+		t.Errorf("systemd-run args = %q, want %q", recorded, want)
+	}
+}
+
+func TestLimitsDecorator_Fallback(t *testing.T) {
+	// A user manager that refuses scopes leaves memory to ulimit
+	installFakeCLI(t, "systemd-run", "echo 'Failed to connect to bus' >&2; exit 1")
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &LimitsDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("memory", "256M"),
+			decoratortesting.IntParam("nice", 7),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo $(nice) $(ulimit -v) > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("LimitsDecorator fallback test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("step did not run: %v", err)
+	}
+	// Niceness is relative to the test process
+	fields := strings.Fields(string(got))
+	if len(fields) != 2 || fields[1] != "262144" {
+		t.Errorf("step saw %q, want the niceness and a 262144 KB ulimit", got)
+	}
+}
+
+func TestLimitsDecorator_InvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		error  string
+	}{
+		{"no limits", []ast.NamedParameter{}, "requires at least 1 parameter"},
+		{"zero cpu", []ast.NamedParameter{decoratortesting.IntParam("cpu", 0)}, "cpu must be a positive number of CPUs"},
+		{"bad memory", []ast.NamedParameter{decoratortesting.StringParam("memory", "lots")}, `memory: invalid size "lots"`},
+		{"nice out of range", []ast.NamedParameter{decoratortesting.IntParam("nice", 25)}, "nice must be an integer from -20 to 19"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &LimitsDecorator{}).
+				TestBlockDecorator(tc.params, []ast.CommandContent{decoratortesting.Shell("echo hi")})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.error).
+				GeneratorFails(tc.error).
+				PlanFails(tc.error).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("LimitsDecorator validation test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	testCases := map[string]int64{
+		"4096":  4096,
+		"512K":  512 << 10,
+		"512M":  512 << 20,
+		"1G":    1 << 30,
+		"1GiB":  1 << 30,
+		"1.5gb": 3 << 29,
+		"2T":    2 << 40,
+	}
+	for size, want := range testCases {
+		got, err := parseByteSize(size)
+		if err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", size, got, err, want)
+		}
+	}
+
+	for _, size := range []string{"", "G", "-1G", "1X"} {
+		if _, err := parseByteSize(size); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded, want an error", size)
+		}
+	}
+}
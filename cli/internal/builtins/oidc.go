@@ -0,0 +1,121 @@
+package decorators
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OIDCTokenEnvVar holds an OIDC ID token for CI systems that provide one in the environment
+// rather than on request, such as a GitLab CI id_token
+const OIDCTokenEnvVar = "DEVCMD_OIDC_TOKEN"
+
+// oidcTokenTemplate mirrors requestOIDCToken in generated code, as a function literal of type
+// func(audience string, getenv func(string) string) (string, error)
+const oidcTokenTemplate = `func(audience string, getenv func(string) string) (string, error) {
+		if requestURL := getenv("ACTIONS_ID_TOKEN_REQUEST_URL"); requestURL != "" {
+			if audience != "" {
+				separator := "?"
+				if strings.Contains(requestURL, "?") {
+					separator = "&"
+				}
+				requestURL += separator + "audience=" + url.QueryEscape(audience)
+			}
+			req, err := http.NewRequest("GET", requestURL, nil)
+			if err != nil {
+				return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+			resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+			if err != nil {
+				return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %v", err)
+			}
+			defer resp.Body.Close()
+			var body map[string]interface{}
+			if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&body) != nil {
+				return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %s", resp.Status)
+			}
+			token, _ := body["value"].(string)
+			if token == "" {
+				return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: the response has no token")
+			}
+			return token, nil
+		}
+		if token := getenv("DEVCMD_OIDC_TOKEN"); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("no OIDC token: run in GitHub Actions with the id-token: write permission, or set DEVCMD_OIDC_TOKEN")
+	}`
+
+// requestOIDCToken returns an OIDC ID token for audience from the CI system: requested from
+// GitHub Actions, or taken from DEVCMD_OIDC_TOKEN elsewhere
+func requestOIDCToken(audience string, getenv func(string) string) (string, error) {
+	requestURL := getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	if requestURL == "" {
+		if token := getenv(OIDCTokenEnvVar); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("no OIDC token: run in GitHub Actions with the id-token: write permission, or set %s", OIDCTokenEnvVar)
+	}
+
+	if audience != "" {
+		separator := "?"
+		if strings.Contains(requestURL, "?") {
+			separator = "&"
+		}
+		requestURL += separator + "audience=" + url.QueryEscape(audience)
+	}
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Value string `json:"value"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&body) != nil {
+		return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: %s", resp.Status)
+	}
+	if body.Value == "" {
+		return "", fmt.Errorf("failed to request a GitHub Actions OIDC token: the response has no token")
+	}
+	return body.Value, nil
+}
+
+// oidcProvider returns the CI system's OIDC ID token for the audience given as the secret name,
+// for tools that exchange it for cloud credentials themselves
+type oidcProvider struct{}
+
+func (oidcProvider) Name() string { return OIDCProvider }
+
+func (oidcProvider) Get(req SecretRequest) (string, error) {
+	return requestOIDCToken(req.Name, req.Getenv)
+}
+
+func (oidcProvider) Source(req SecretRequest) string {
+	return fmt.Sprintf("OIDC token for audience %q", req.Name)
+}
+
+func (oidcProvider) Template() string {
+	return `func(name, path string, getenv func(string) string) (string, error) {
+		return (` + oidcTokenTemplate + `)(name, getenv)
+	}`
+}
+
+func (oidcProvider) Imports() []string {
+	return []string{"encoding/json", "net/http", "net/url", "time"}
+}
+
+// init registers the OIDC token secret provider
+func init() {
+	RegisterSecretProvider(oidcProvider{})
+}
@@ -0,0 +1,75 @@
+package decorators
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeGitHubOIDC serves GitHub Actions OIDC token requests, returning "jwt-for-<audience>"
+func installFakeGitHubOIDC(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("api-version") != "2.0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"value": "jwt-for-" + r.URL.Query().Get("audience")})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+}
+
+func TestRequestOIDCToken_GitHubActions(t *testing.T) {
+	installFakeGitHubOIDC(t)
+
+	token, err := requestOIDCToken("sts.amazonaws.com", os.Getenv)
+	if err != nil || token != "jwt-for-sts.amazonaws.com" {
+		t.Errorf("token = %q, %v; want jwt-for-sts.amazonaws.com", token, err)
+	}
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "wrong")
+	if _, err := requestOIDCToken("vault", os.Getenv); err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("error with a bad request token = %v", err)
+	}
+}
+
+func TestRequestOIDCToken_Environment(t *testing.T) {
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv(OIDCTokenEnvVar, "gitlab-id-token")
+	if token, err := requestOIDCToken("vault", os.Getenv); err != nil || token != "gitlab-id-token" {
+		t.Errorf("token = %q, %v; want the DEVCMD_OIDC_TOKEN value", token, err)
+	}
+
+	t.Setenv(OIDCTokenEnvVar, "")
+	if _, err := requestOIDCToken("vault", os.Getenv); err == nil || !strings.Contains(err.Error(), "no OIDC token") {
+		t.Errorf("error outside CI = %v, want no OIDC token", err)
+	}
+}
+
+func TestSecretDecorator_OIDC(t *testing.T) {
+	installFakeGitHubOIDC(t)
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("key", "sts.amazonaws.com"),
+			decoratortesting.StringParam("provider", "oidc"),
+		})
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("jwt-for-sts.amazonaws.com").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`readSecret(ctx, "oidc", "sts.amazonaws.com", "")`).
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,307 @@
+package decorators
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// noOpenEnvVar disables @open when set. The --no-open flag sets it for headless environments.
+const noOpenEnvVar = "DEVCMD_NO_OPEN"
+
+// openPollInterval is how often @open checks whether the server is ready
+const openPollInterval = 250 * time.Millisecond
+
+// variableReference matches @var(NAME) references inside string parameters
+var variableReference = regexp.MustCompile(`@var\(([A-Za-z_][A-Za-z0-9_]*)\)`)
+
+// openTemplate waits for the server and launches the browser in generated code.
+// It mirrors OpenDecorator.execute so generated CLIs and the interpreter behave the same.
+const openTemplate = `func() error {
+	target := os.Expand({{printf "%q" .URL}}, func(name string) string {
+		if value, ok := ctx.Env[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("@open: url %q is empty after expanding environment variables", {{printf "%q" .URL}})
+	}
+
+	wait := time.Duration({{.Wait}})
+	client := &http.Client{Timeout: time.Duration({{.PollInterval}}) * 4}
+	for deadline := time.Now().Add(wait); wait > 0; time.Sleep(time.Duration({{.PollInterval}})) {
+		if resp, err := client.Get(target); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("@open: %s did not become ready within %s", target, wait)
+		}
+	}
+
+	if os.Getenv({{printf "%q" .NoOpenEnvVar}}) != "" || ctx.Env[{{printf "%q" .NoOpenEnvVar}}] != "" {
+		logf("info", "@open", "%s (browser disabled)", target)
+		return nil
+	}
+	var launcher *execpkg.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		launcher = execpkg.Command("open", target)
+	case "windows":
+		launcher = execpkg.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			logf("info", "@open", "%s (no display available)", target)
+			return nil
+		}
+		launcher = execpkg.Command("xdg-open", target)
+	}
+	if err := launcher.Start(); err != nil {
+		logf("warn", "@open", "failed to launch browser, visit %s: %v", target, err)
+		return nil
+	}
+	go launcher.Wait()
+	logf("info", "@open", "%s", target)
+	return nil
+}()`
+
+// OpenDecorator implements the @open decorator for opening URLs in the default browser
+type OpenDecorator struct{}
+
+// Name returns the decorator name
+func (o *OpenDecorator) Name() string {
+	return "open"
+}
+
+// Description returns a human-readable description
+func (o *OpenDecorator) Description() string {
+	return "Open a URL in the default browser, optionally waiting for the server to become ready"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (o *OpenDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "url",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "URL to open; @var(NAME) and $VAR references are expanded",
+		},
+		{
+			Name:        "wait",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "How long to wait for the URL to respond before opening it (default: open immediately)",
+		},
+	}
+}
+
+// ExpandInterpreter waits for the server and opens the URL in interpreter mode
+func (o *OpenDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	rawURL, wait, err := o.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	if err := o.execute(ctx, rawURL, wait); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  "true", // Return "true" for shell chaining
+		Error: nil,
+	}
+}
+
+// GenerateTemplate returns template for Go code that opens the URL
+func (o *OpenDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	rawURL, wait, err := o.extractParameters(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("open").Parse(openTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse open template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			URL          string
+			Wait         int64
+			PollInterval int64
+			NoOpenEnvVar string
+		}{
+			URL:          rawURL,
+			Wait:         int64(wait),
+			PollInterval: int64(openPollInterval),
+			NoOpenEnvVar: noOpenEnvVar,
+		},
+	}, nil
+}
+
+// ExpandPlan describes the URL that would be opened
+func (o *OpenDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	rawURL, wait, err := o.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	description := fmt.Sprintf("Open %s in the browser", rawURL)
+	if wait > 0 {
+		description += fmt.Sprintf(" once it responds (up to %s)", wait)
+	}
+
+	return &execution.ExecutionResult{
+		Data: map[string]interface{}{
+			"type":        "open",
+			"command":     fmt.Sprintf("@open(%s)", rawURL),
+			"description": description,
+		},
+		Error: nil,
+	}
+}
+
+// execute waits for the URL to respond, then launches the platform's browser opener.
+// Opening is best effort: when disabled, headless, or the opener fails, the URL is printed instead.
+func (o *OpenDecorator) execute(ctx execution.InterpreterContext, rawURL string, wait time.Duration) error {
+	target := os.Expand(rawURL, func(name string) string {
+		value, _ := ctx.GetEnv(name)
+		return value
+	})
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("@open: url %q is empty after expanding environment variables", rawURL)
+	}
+
+	if wait > 0 {
+		if err := waitForURL(ctx, target, wait); err != nil {
+			return err
+		}
+	}
+
+	if disabled, _ := ctx.GetEnv(noOpenEnvVar); disabled != "" {
+		ctx.Logger().Infof("@open", "%s (browser disabled)", target)
+		return nil
+	}
+
+	var launcher *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		launcher = exec.Command("open", target)
+	case "windows":
+		launcher = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		display, _ := ctx.GetEnv("DISPLAY")
+		wayland, _ := ctx.GetEnv("WAYLAND_DISPLAY")
+		if display == "" && wayland == "" {
+			ctx.Logger().Infof("@open", "%s (no display available)", target)
+			return nil
+		}
+		launcher = exec.Command("xdg-open", target)
+	}
+
+	if err := launcher.Start(); err != nil {
+		ctx.Logger().Warnf("@open", "failed to launch browser, visit %s: %v", target, err)
+		return nil
+	}
+	// Reap the opener in the background; it may outlive this step
+	go func() { _ = launcher.Wait() }()
+
+	ctx.Logger().Infof("@open", "%s", target)
+	return nil
+}
+
+// waitForURL polls target until it answers with a non-5xx response or wait elapses
+func waitForURL(ctx execution.InterpreterContext, target string, wait time.Duration) error {
+	client := &http.Client{Timeout: 4 * openPollInterval}
+	deadline := time.Now().Add(wait)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return fmt.Errorf("@open: invalid url %q: %w", target, err)
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("@open: %s did not become ready within %s", target, wait)
+		}
+
+		select {
+		case <-time.After(openPollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("@open: waiting for %s cancelled: %w", target, ctx.Err())
+		}
+	}
+}
+
+// extractParameters validates parameters and returns the URL with @var references resolved
+func (o *OpenDecorator) extractParameters(ctx execution.BaseContext, params []ast.NamedParameter) (string, time.Duration, error) {
+	if err := decorators.ValidateSchemaCompliance(params, o.ParameterSchema(), "open"); err != nil {
+		return "", 0, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, o.ParameterSchema())
+	if err != nil {
+		return "", 0, fmt.Errorf("@open: %w", err)
+	}
+
+	rawURL, err := resolveVariableReferences(ctx, ast.GetStringParam(params, "url", ""))
+	if err != nil {
+		return "", 0, fmt.Errorf("@open: %w", err)
+	}
+	if rawURL == "" {
+		return "", 0, fmt.Errorf("@open: url must not be empty")
+	}
+
+	wait := ast.GetDurationParam(params, "wait", 0)
+	if wait < 0 {
+		return "", 0, fmt.Errorf("@open: wait must not be negative")
+	}
+
+	return rawURL, wait, nil
+}
+
+// resolveVariableReferences substitutes @var(NAME) references in a string parameter
+// with the values of variables defined in the CLI file
+func resolveVariableReferences(ctx execution.BaseContext, s string) (string, error) {
+	var missing string
+	resolved := variableReference.ReplaceAllStringFunc(s, func(reference string) string {
+		name := variableReference.FindStringSubmatch(reference)[1]
+		value, exists := ctx.GetVariable(name)
+		if !exists && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable '%s' not defined in .cli file", missing)
+	}
+	return resolved, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (o *OpenDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, decorators.TimeImports, []string{"net/http", "os/exec", "runtime"})
+}
+
+// init registers the open decorator
+func init() {
+	decorators.RegisterAction(&OpenDecorator{})
+}
@@ -0,0 +1,153 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeOpener replaces xdg-open with a script that records the URL it was given
+func installFakeOpener(t *testing.T) string {
+	t.Helper()
+	opened := filepath.Join(t.TempDir(), "opened")
+	installFakeCLI(t, "xdg-open", `echo "$1" > `+opened)
+	t.Setenv("DISPLAY", ":0")
+	t.Setenv(noOpenEnvVar, "")
+	return opened
+}
+
+// waitForFile polls for a file written by a background process
+func waitForFile(t *testing.T, path string) string {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return strings.TrimSpace(string(data))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%s was not written", path)
+	return ""
+}
+
+func TestOpenDecorator_Basic(t *testing.T) {
+	opened := installFakeOpener(t)
+
+	result := decoratortesting.NewDecoratorTest(t, &OpenDecorator{}).
+		WithVariable("PORT", "3000").
+		TestActionDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("url", "http://localhost:@var(PORT)/app"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`os.Expand("http://localhost:3000/app"`, `execpkg.Command("xdg-open", target)`).
+		PlanSucceeds().
+		PlanReturnsElement("open").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("OpenDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if got := waitForFile(t, opened); got != "http://localhost:3000/app" {
+		t.Errorf("opened %q, want http://localhost:3000/app", got)
+	}
+}
+
+func TestOpenDecorator_WaitsForServer(t *testing.T) {
+	opened := installFakeOpener(t)
+
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	time.AfterFunc(300*time.Millisecond, func() { ready.Store(true) })
+
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	ctx.ExportEnv("APP_URL", server.URL)
+
+	start := time.Now()
+	if err := (&OpenDecorator{}).execute(ctx, "$APP_URL/health", 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("opened after %s, before the server was ready", elapsed)
+	}
+	if got := waitForFile(t, opened); got != server.URL+"/health" {
+		t.Errorf("opened %q, want %q", got, server.URL+"/health")
+	}
+}
+
+func TestOpenDecorator_WaitTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	err := (&OpenDecorator{}).execute(ctx, server.URL, 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not become ready within 100ms") {
+		t.Errorf("expected readiness timeout, got %v", err)
+	}
+}
+
+func TestOpenDecorator_NoOpen(t *testing.T) {
+	opened := installFakeOpener(t)
+	t.Setenv(noOpenEnvVar, "1")
+
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	if err := (&OpenDecorator{}).execute(ctx, "http://localhost:3000", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(opened); err == nil {
+		t.Error("browser was opened despite --no-open")
+	}
+}
+
+func TestOpenDecorator_InvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		errMsg string
+	}{
+		{"missing url", []ast.NamedParameter{}, "url"},
+		{"undefined variable", []ast.NamedParameter{
+			decoratortesting.StringParam("url", "http://localhost:@var(MISSING)"),
+		}, "variable 'MISSING' not defined"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &OpenDecorator{}).
+				TestActionDecorator(tc.params)
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.errMsg).
+				GeneratorFails(tc.errMsg).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("OpenDecorator invalid parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
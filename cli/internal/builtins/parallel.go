@@ -1,14 +1,26 @@
 package decorators
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/aledsdavies/devcmd/core/ast"
 	"github.com/aledsdavies/devcmd/core/plan"
 	"github.com/aledsdavies/devcmd/runtime/decorators"
 	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// Output modes for parallel branches
+const (
+	parallelOutputStream   = "stream"   // Interleave lines as they are written, prefixed with the branch's name
+	parallelOutputBuffered = "buffered" // Hold each branch's output and write it in one piece when the branch completes
 )
 
 // ParallelDecorator implements the @parallel decorator for concurrent command execution
@@ -27,17 +39,29 @@ func (p *ParallelDecorator) Description() string {
 // ParameterSchema returns the expected parameters for this decorator
 func (p *ParallelDecorator) ParameterSchema() []decorators.ParameterSchema {
 	return []decorators.ParameterSchema{
+		{
+			Name:        "limit",
+			Type:        ast.NumberType,
+			Required:    false,
+			Description: "Maximum number of commands to run at a time; the rest wait for a free worker and start in order (default: all, capped at CPU cores * 2)",
+		},
+		{
+			Name:        "failFast",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Cancel the other commands when one fails: those waiting never start and running ones are stopped (default: false)",
+		},
 		{
 			Name:        "concurrency",
 			Type:        ast.NumberType,
 			Required:    false,
-			Description: "Maximum number of commands to run concurrently (default: CPU cores * 2, capped for safety)",
+			Description: "Older name for limit",
 		},
 		{
 			Name:        "failOnFirstError",
 			Type:        ast.BooleanType,
 			Required:    false,
-			Description: "Cancel remaining tasks on first error (default: false)",
+			Description: "Older name for failFast",
 		},
 		{
 			Name:        "uncapped",
@@ -45,6 +69,12 @@ func (p *ParallelDecorator) ParameterSchema() []decorators.ParameterSchema {
 			Required:    false,
 			Description: "Disable CPU-based concurrency capping (default: false, use with caution)",
 		},
+		{
+			Name:        "output",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Output mode: stream (interleaved lines prefixed with the branch's name) or buffered (each branch's output written in one piece when it completes) (default: stream)",
+		},
 	}
 }
 
@@ -52,179 +82,362 @@ func (p *ParallelDecorator) ParameterSchema() []decorators.ParameterSchema {
 
 // ExecuteInterpreter executes commands concurrently in interpreter mode
 func (p *ParallelDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
-	concurrency, failOnFirstError, err := p.extractParallelParams(params, len(content))
+	limit, failFast, output, err := p.extractParallelParams(params, len(content))
 	if err != nil {
 		return execution.NewErrorResult(err)
 	}
 
-	return p.executeInterpreterImpl(ctx, concurrency, failOnFirstError, content)
+	return p.executeInterpreterImpl(ctx, limit, failFast, output, content)
 }
 
 // GenerateTemplate generates template-based Go code for parallel execution
 func (p *ParallelDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
-	concurrency, failOnFirstError, err := p.extractParallelParams(params, len(content))
+	limit, failFast, output, err := p.extractParallelParams(params, len(content))
 	if err != nil {
 		return nil, err
 	}
 
-	return p.generateTemplateImpl(ctx, concurrency, failOnFirstError, content)
+	return p.generateTemplateImpl(ctx, limit, failFast, output, content)
 }
 
 // ExecutePlan creates a plan element for dry-run mode
 func (p *ParallelDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
-	concurrency, failOnFirstError, err := p.extractParallelParams(params, len(content))
+	limit, failFast, output, err := p.extractParallelParams(params, len(content))
 	if err != nil {
 		return execution.NewErrorResult(err)
 	}
 
-	return p.executePlanImpl(ctx, concurrency, failOnFirstError, content)
+	return p.executePlanImpl(ctx, limit, failFast, output, content)
 }
 
-// extractParallelParams extracts and validates parallel parameters
-func (p *ParallelDecorator) extractParallelParams(params []ast.NamedParameter, contentLength int) (int, bool, error) {
+// extractParallelParams extracts and validates parallel parameters, returning the worker limit,
+// whether to fail fast and the output mode
+func (p *ParallelDecorator) extractParallelParams(params []ast.NamedParameter, contentLength int) (int, bool, string, error) {
 	// Use centralized validation
-	if err := decorators.ValidateParameterCount(params, 0, 3, "parallel"); err != nil {
-		return 0, false, err
+	if err := decorators.ValidateParameterCount(params, 0, 4, "parallel"); err != nil {
+		return 0, false, "", err
 	}
 
 	// Validate parameter schema compliance
 	if err := decorators.ValidateSchemaCompliance(params, p.ParameterSchema(), "parallel"); err != nil {
-		return 0, false, err
+		return 0, false, "", err
 	}
 
-	// Enhanced security validation for concurrency parameter
-	if err := decorators.ValidatePositiveInteger(params, "concurrency", "parallel"); err != nil {
-		// ValidatePositiveInteger returns error if parameter is invalid, but not if missing
-		// Check if the parameter exists first
-		if ast.FindParameter(params, "concurrency") != nil {
-			return 0, false, err
+	output := ast.GetStringParam(params, "output", parallelOutputStream)
+	if output != parallelOutputStream && output != parallelOutputBuffered {
+		return 0, false, "", fmt.Errorf("@parallel 'output' parameter must be %q or %q, got %q", parallelOutputStream, parallelOutputBuffered, output)
+	}
+
+	// limit and failFast were previously called concurrency and failOnFirstError
+	limitName, err := parallelParamName(params, "limit", "concurrency")
+	if err != nil {
+		return 0, false, "", err
+	}
+	failFastName, err := parallelParamName(params, "failFast", "failOnFirstError")
+	if err != nil {
+		return 0, false, "", err
+	}
+
+	// Enhanced security validation for the limit parameter
+	if ast.FindParameter(params, limitName) != nil {
+		if err := decorators.ValidatePositiveInteger(params, limitName, "parallel"); err != nil {
+			return 0, false, "", err
 		}
 	}
 
-	// Validate resource limits for concurrency to prevent DoS attacks
-	if err := decorators.ValidateResourceLimits(params, "concurrency", 1000, "parallel"); err != nil {
-		return 0, false, err
+	// Validate resource limits for the limit to prevent DoS attacks
+	if err := decorators.ValidateResourceLimits(params, limitName, 1000, "parallel"); err != nil {
+		return 0, false, "", err
 	}
 
 	// Parse parameters with defaults (validation passed, so these should be safe)
-	defaultConcurrency := contentLength
-	if defaultConcurrency == 0 {
-		defaultConcurrency = 1 // Always have a positive default
+	defaultLimit := contentLength
+	if defaultLimit == 0 {
+		defaultLimit = 1 // Always have a positive default
 	}
 
-	concurrency := ast.GetIntParam(params, "concurrency", defaultConcurrency)
-	failOnFirstError := ast.GetBoolParam(params, "failOnFirstError", false)
+	limit := ast.GetIntParam(params, limitName, defaultLimit)
+	failFast := ast.GetBoolParam(params, failFastName, false)
 	uncapped := ast.GetBoolParam(params, "uncapped", false)
 
 	// Apply intelligent CPU-based concurrency capping for production robustness
 	// This prevents resource exhaustion on systems with limited CPU cores
 	if !uncapped {
 		cpuCount := runtime.NumCPU()
-		maxRecommendedConcurrency := cpuCount * 2 // Allow some over-subscription for I/O bound tasks
+		maxRecommendedLimit := cpuCount * 2 // Allow some over-subscription for I/O bound tasks
 
-		if concurrency > maxRecommendedConcurrency {
-			// Cap concurrency but don't error - just limit to reasonable bounds
+		if limit > maxRecommendedLimit {
+			// Cap the limit but don't error - just limit to reasonable bounds
 			// This provides good defaults while still allowing explicit override via uncapped=true
-			concurrency = maxRecommendedConcurrency
+			limit = maxRecommendedLimit
 		}
 	}
 
-	return concurrency, failOnFirstError, nil
+	return limit, failFast, output, nil
 }
 
-// executeInterpreterImpl executes commands concurrently in interpreter mode
-func (p *ParallelDecorator) executeInterpreterImpl(ctx execution.InterpreterContext, concurrency int, failOnFirstError bool, content []ast.CommandContent) *execution.ExecutionResult {
-	// Use channels to coordinate execution and output
-	type commandResult struct {
-		index  int
-		result *execution.ExecutionResult
+// parallelParamName returns the name a parameter is given by: its current name, or the older
+// name it replaced. Giving both is an error.
+func parallelParamName(params []ast.NamedParameter, name, older string) (string, error) {
+	if ast.FindParameter(params, older) == nil {
+		return name, nil
+	}
+	if ast.FindParameter(params, name) != nil {
+		return "", fmt.Errorf("@parallel '%s' parameter is an older name for '%s'; give only one of them", older, name)
 	}
+	return older, nil
+}
 
-	resultChan := make(chan commandResult, len(content))
+// executeInterpreterImpl executes commands concurrently in interpreter mode. Commands start in
+// order as workers free up; with failFast the first failure cancels the block's context, so
+// commands still waiting never start and running shell steps are killed.
+func (p *ParallelDecorator) executeInterpreterImpl(ctx execution.InterpreterContext, limit int, failFast bool, output string, content []ast.CommandContent) *execution.ExecutionResult {
+	groupCtx, cancel := ctx.WithCancel()
+	defer cancel()
+
+	// Branches share the block's writers; the mutex keeps their lines and flushes whole
+	stdout, stderr := ctx.OutputWriters()
+	var outputMu sync.Mutex
+
+	// The first error is the block's error; a cancelled branch's error only follows it
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			if failFast {
+				cancel()
+			}
+		}
+	}
 
-	// Execute commands concurrently
+	logger := ctx.Logger()
+	names := parallelBranchNames(content)
+	workers := make(chan struct{}, limit)
 	for i, cmd := range content {
-		go func(cmdIndex int, command ast.CommandContent) {
-			// Create isolated context for each parallel command
-			isolatedCtx := ctx.Child()
+		select {
+		case workers <- struct{}{}:
+		case <-groupCtx.Done():
+		}
+		if groupCtx.Err() != nil {
+			break
+		}
+
+		// Create isolated context for each parallel command, writing through its own branch output.
+		// Children are created before starting the goroutine since Child updates the parent.
+		branchStdout, branchStderr, flush := newBranchOutput(output, names[i], stdout, stderr, &outputMu)
+		isolatedCtx := groupCtx.Child().WithOutput(branchStdout, branchStderr)
+
+		wg.Add(1)
+		go func(name string, command ast.CommandContent) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			logger.Debugf("@parallel", "branch %s started", name)
 
 			// Execute the command using the unified ExecuteCommandContent method
 			err := isolatedCtx.ExecuteCommandContent(command)
-			var result *execution.ExecutionResult
+			flush()
 			if err != nil {
-				result = execution.NewErrorResult(err)
-			} else {
-				result = &execution.ExecutionResult{Data: nil, Error: nil}
-			}
-
-			resultChan <- commandResult{index: cmdIndex, result: result}
-		}(i, cmd)
-	}
-
-	// Collect results - maintain order for consistent output
-	results := make([]*execution.ExecutionResult, len(content))
-	var firstError error
-
-	// Wait for all goroutines to complete
-	for i := 0; i < len(content); i++ {
-		cmdResult := <-resultChan
-		results[cmdResult.index] = cmdResult.result
-
-		if cmdResult.result.Error != nil && firstError == nil {
-			firstError = cmdResult.result.Error
-			if failOnFirstError {
-				// Still need to wait for all goroutines to complete
-				continue
+				logger.Log(logging.Debug, "@parallel", "branch "+name+" failed", logging.Fields{"error": err})
+				fail(err)
+				return
 			}
-		}
+			logger.Debugf("@parallel", "branch %s finished", name)
+		}(names[i], cmd)
 	}
+	wg.Wait()
 
-	// Display outputs in original command order for consistent behavior
-	for _, result := range results {
-		if result.Error == nil {
-			// Command succeeded - output should already be displayed by ExecuteShell
-			continue
-		}
+	// A block cancelled from outside, e.g. by @timeout, fails even if no branch reported it
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
 	}
 
 	// Return error if fail-fast is enabled and we have an error
-	if failOnFirstError && firstError != nil {
-		return execution.NewErrorResult(fmt.Errorf("parallel execution failed: %w", firstError))
+	if failFast && firstErr != nil {
+		return execution.NewErrorResult(fmt.Errorf("parallel execution failed: %w", firstErr))
 	}
 
 	return &execution.ExecutionResult{
 		Data:  nil,
-		Error: firstError, // Return first error even if not failing fast
+		Error: firstErr, // Return first error even if not failing fast
 	}
 }
 
-// generateTemplateImpl generates template for parallel execution
-func (p *ParallelDecorator) generateTemplateImpl(ctx execution.GeneratorContext, concurrency int, failOnFirstError bool, content []ast.CommandContent) (*execution.TemplateResult, error) {
+// generateTemplateImpl generates template for parallel execution, with errgroup semantics: each
+// branch waits for a worker before it starts, the first error is the block's error and, with
+// failFast, it cancels the branches still waiting and kills the running ones' processes
+func (p *ParallelDecorator) generateTemplateImpl(ctx execution.GeneratorContext, limit int, failFast bool, output string, content []ast.CommandContent) (*execution.TemplateResult, error) {
 	// Create template string for parallel execution
-	tmplStr := `// Parallel execution
+	tmplStr := `// Parallel execution ({{.Limit}} at a time{{if .FailFast}}, fail fast{{end}})
 {
 	var wg sync.WaitGroup
-	errs := make([]error, {{len .Content}})
-
-{{range $i, $cmd := .Content}}	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		// Branch {{$i}} with isolated context
-		branchCtx := ctx.Clone()
-		errs[{{$i}}] = func() error {
-			ctx := branchCtx
-			{{$cmd | buildCommand}}
-			return nil
-		}()
-	}()
+	workers := make(chan struct{}, {{.Limit}})
+	cancelled := make(chan struct{})
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+{{- if .FailFast}}
+			close(cancelled)
+{{- end}}
+		})
+	}
 
-{{end}}	wg.Wait()
+	// start waits for a free worker, reporting false once the block is cancelled
+	start := func() bool {
+		select {
+		case workers <- struct{}{}:
+		case <-cancelled:
+			return false
+		}
+		select {
+		case <-cancelled:
+			<-workers
+			return false
+		default:
+			return true
+		}
+	}
+{{- if .FailFast}}
+
+	// cancellable stops a branch's shell steps once the block is cancelled: steps yet to
+	// start fail, and a running step's process is killed unless another runner owns it
+	cancellable := func(run func(cmd *execpkg.Cmd) error) func(cmd *execpkg.Cmd) error {
+		return func(cmd *execpkg.Cmd) error {
+			select {
+			case <-cancelled:
+				return fmt.Errorf("cancelled")
+			default:
+			}
+			if run != nil {
+				return run(cmd)
+			}
+			if err := cmd.Start(); err != nil {
+				return err
+			}
+			waited := make(chan error, 1)
+			go func() { waited <- cmd.Wait() }()
+			select {
+			case err := <-waited:
+				return err
+			case <-cancelled:
+				cmd.Process.Kill()
+				<-waited
+				return fmt.Errorf("cancelled")
+			}
+		}
+	}
+{{- end}}
 
-	// Check for errors
-	for _, err := range errs {
+	// Branch output goes through a pipe: streamed as prefixed lines, or buffered and written in one piece
+	output := {{printf "%q" .Output}}
+	stdoutDst, stderrDst := os.Stdout, os.Stderr
+	if ctx.Stdout != nil {
+		stdoutDst = ctx.Stdout
+	}
+	if ctx.Stderr != nil {
+		stderrDst = ctx.Stderr
+	}
+	var outputMu sync.Mutex
+	relay := func(branch string, dst *os.File) (*os.File, func() []byte, error) {
+		r, w, err := os.Pipe()
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		var buffered bytes.Buffer
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer r.Close()
+			reader := bufio.NewReader(r)
+			for {
+				line, err := reader.ReadString('\n')
+				if line != "" {
+					if output == "buffered" {
+						buffered.WriteString(line)
+					} else {
+						if line[len(line)-1] != '\n' {
+							line += "\n"
+						}
+						outputMu.Lock()
+						fmt.Fprintf(dst, "[%s] %s", branch, line)
+						outputMu.Unlock()
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return w, func() []byte {
+			w.Close()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				// A process the branch started in the background, or left behind when it was
+				// cancelled, still holds the pipe open
+				r.Close()
+				<-done
+			}
+			return buffered.Bytes()
+		}, nil
+	}
+
+{{range $i, $cmd := .Content}}	if start() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			// Branch {{index $.Names $i}} with isolated context and its own output
+			logf("debug", "@parallel", "branch %s started", {{index $.Names $i | printf "%q"}})
+			branchCtx := ctx.Clone()
+{{- if $.FailFast}}
+			branchCtx.Run = cancellable(branchCtx.Run)
+{{- end}}
+			stdout, finishStdout, err := relay({{index $.Names $i | printf "%q"}}, stdoutDst)
+			if err != nil {
+				fail(err)
+				return
+			}
+			stderr, finishStderr, err := relay({{index $.Names $i | printf "%q"}}, stderrDst)
+			if err != nil {
+				finishStdout()
+				fail(err)
+				return
+			}
+			branchCtx.Stdout, branchCtx.Stderr = stdout, stderr
+			err = func() error {
+				ctx := branchCtx
+				{{$cmd | buildCommand}}
+				return nil
+			}()
+			stdoutData, stderrData := finishStdout(), finishStderr()
+			outputMu.Lock()
+			stdoutDst.Write(stdoutData)
+			stderrDst.Write(stderrData)
+			outputMu.Unlock()
+			if err != nil {
+				logf("debug", "@parallel", "branch %s failed: %v", {{index $.Names $i | printf "%q"}}, err)
+				fail(err)
+				return
+			}
+			logf("debug", "@parallel", "branch %s finished", {{index $.Names $i | printf "%q"}})
+		}()
+	}
+
+{{end}}	wg.Wait()
+	if firstErr != nil {
+{{- if .FailFast}}
+		return fmt.Errorf("parallel execution failed: %w", firstErr)
+{{- else}}
+		return firstErr
+{{- end}}
 	}
 }`
 
@@ -237,94 +450,174 @@ func (p *ParallelDecorator) generateTemplateImpl(ctx execution.GeneratorContext,
 	return &execution.TemplateResult{
 		Template: tmpl,
 		Data: struct {
-			Concurrency      int
-			FailOnFirstError bool
-			Content          []ast.CommandContent
+			Limit    int
+			FailFast bool
+			Output   string
+			Names    []string
+			Content  []ast.CommandContent
 		}{
-			Concurrency:      concurrency,
-			FailOnFirstError: failOnFirstError,
-			Content:          content,
+			Limit:    limit,
+			FailFast: failFast,
+			Output:   output,
+			Names:    parallelBranchNames(content),
+			Content:  content,
 		},
 	}, nil
 }
 
 // executePlanImpl creates a plan element for dry-run mode
-func (p *ParallelDecorator) executePlanImpl(ctx execution.PlanContext, concurrency int, failOnFirstError bool, content []ast.CommandContent) *execution.ExecutionResult {
+func (p *ParallelDecorator) executePlanImpl(ctx execution.PlanContext, limit int, failFast bool, output string, content []ast.CommandContent) *execution.ExecutionResult {
 	description := fmt.Sprintf("Execute %d commands concurrently", len(content))
-	if concurrency < len(content) {
-		description += fmt.Sprintf(" (max %d at a time)", concurrency)
+	if limit < len(content) {
+		description += fmt.Sprintf(" (max %d at a time)", limit)
 	}
-	if failOnFirstError {
-		description += ", stop on first error"
+	if failFast {
+		description += ", cancel the rest on first error"
 	} else {
 		description += ", continue on errors"
 	}
+	if output == parallelOutputBuffered {
+		description += ", output buffered per command"
+	}
 
 	element := plan.Decorator("parallel").
 		WithType("block").
+		WithConcurrency(limit).
 		WithDescription(description)
 
-	if concurrency < len(content) {
-		element = element.WithParameter("concurrency", fmt.Sprintf("%d", concurrency))
+	element = element.WithParameter("limit", fmt.Sprintf("%d", limit))
+	if failFast {
+		element = element.WithParameter("failFast", "true")
 	}
-	if failOnFirstError {
-		element = element.WithParameter("failOnFirstError", "true")
+	if output != parallelOutputStream {
+		element = element.WithParameter("output", output)
 	}
 
-	// Build child plan elements for each command in the parallel block
-	for _, cmd := range content {
-		switch c := cmd.(type) {
-		case *ast.ShellContent:
-			// Create plan element for shell command
-			result := ctx.GenerateShellPlan(c)
-			if result.Error != nil {
-				return execution.NewFormattedErrorResult("failed to create plan for shell content: %w", result.Error)
-			}
+	// Build a child plan element for each branch, named as its output and log records are
+	children, err := contentPlans(ctx, content)
+	if err != nil {
+		return execution.NewErrorResult(err)
+	}
+	for i, name := range parallelBranchNames(content) {
+		element = element.AddChild(plan.Annotate(children[i], "branch", name))
+	}
 
-			// Extract command string from result
-			if planData, ok := result.Data.(map[string]interface{}); ok {
-				if cmdStr, ok := planData["command"].(string); ok {
-					childDesc := "Execute shell command"
-					if desc, ok := planData["description"].(string); ok {
-						childDesc = desc
-					}
-					childElement := plan.Command(cmdStr).WithDescription(childDesc)
-					element = element.AddChild(childElement)
-				}
+	return execution.NewSuccessResult(element)
+}
+
+// parallelBranchNames names each branch for its output prefix and log records: by the command
+// it runs when it is a lone @cmd, unless another branch runs the same command, and otherwise
+// by its 1-based position
+func parallelBranchNames(content []ast.CommandContent) []string {
+	names := make([]string, len(content))
+	count := make(map[string]int)
+	for i, item := range content {
+		names[i] = parallelBranchCommand(item)
+		count[names[i]]++
+	}
+	for i, name := range names {
+		if name == "" || count[name] > 1 {
+			names[i] = strconv.Itoa(i + 1)
+		}
+	}
+	return names
+}
+
+// parallelBranchCommand returns the command a branch that is only @cmd(name) runs, or ""
+func parallelBranchCommand(item ast.CommandContent) string {
+	shell, ok := item.(*ast.ShellContent)
+	if !ok {
+		return ""
+	}
+	var name string
+	for _, part := range shell.Parts {
+		switch p := part.(type) {
+		case *ast.ActionDecorator:
+			if p.Name != "cmd" || name != "" {
+				return ""
 			}
-		case *ast.BlockDecorator:
-			// Execute nested decorator in plan mode to get its proper plan structure
-			blockDecorator, err := decorators.GetBlock(c.Name)
-			if err != nil {
-				// Fallback to placeholder if decorator not found
-				childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription(fmt.Sprintf("Unknown decorator: %s", c.Name))
-				element = element.AddChild(childElement)
-			} else {
-				// Execute the nested decorator in plan mode
-				result := blockDecorator.ExecutePlan(ctx, c.Args, c.Content)
-				if result.Error != nil {
-					// Fallback to placeholder if plan execution fails
-					childElement := plan.Command(fmt.Sprintf("@%s{error}", c.Name)).WithDescription(fmt.Sprintf("Error in %s: %v", c.Name, result.Error))
-					element = element.AddChild(childElement)
-				} else if planElement, ok := result.Data.(plan.PlanElement); ok {
-					// Add the nested decorator's plan element as a child
-					element = element.AddChild(planElement)
-				} else {
-					// Fallback if result format is unexpected
-					childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription(fmt.Sprintf("Nested decorator: %s", c.Name))
-					element = element.AddChild(childElement)
-				}
+			var err error
+			if name, err = (&CmdDecorator{}).extractCommandName(p.Args); err != nil {
+				return ""
 			}
+		case *ast.TextPart:
+			if strings.TrimSpace(p.Text) != "" {
+				return ""
+			}
+		default:
+			return ""
 		}
 	}
+	return name
+}
 
-	return execution.NewSuccessResult(element)
+// newBranchOutput returns the writers for one parallel branch and a flush function to call
+// when the branch completes. Streamed output is written line by line prefixed with the
+// branch's name in brackets; buffered output is written in one piece on flush. The mutex is
+// shared by all branches.
+func newBranchOutput(output string, branch string, stdout, stderr io.Writer, mu *sync.Mutex) (io.Writer, io.Writer, func()) {
+	if output == parallelOutputBuffered {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		return &stdoutBuf, &stderrBuf, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = stdout.Write(stdoutBuf.Bytes())
+			_, _ = stderr.Write(stderrBuf.Bytes())
+		}
+	}
+
+	prefix := "[" + branch + "] "
+	stdoutLines := &prefixWriter{mu: mu, dst: stdout, prefix: prefix}
+	stderrLines := &prefixWriter{mu: mu, dst: stderr, prefix: prefix}
+	return stdoutLines, stderrLines, func() {
+		stdoutLines.flush()
+		stderrLines.flush()
+	}
+}
+
+// prefixWriter writes each complete line to dst with a prefix, holding back a partial line
+type prefixWriter struct {
+	mu      *sync.Mutex
+	dst     io.Writer
+	prefix  string
+	partial []byte
+}
+
+// Write implements io.Writer
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		end := bytes.IndexByte(w.partial, '\n')
+		if end < 0 {
+			break
+		}
+		if err := w.writeLine(w.partial[:end+1]); err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[end+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes any unterminated final line
+func (w *prefixWriter) flush() {
+	if len(w.partial) > 0 {
+		_ = w.writeLine(append(w.partial, '\n'))
+		w.partial = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.dst.Write(append([]byte(w.prefix), line...))
+	return err
 }
 
 // ImportRequirements returns the dependencies needed for code generation
 func (p *ParallelDecorator) ImportRequirements() decorators.ImportRequirement {
-	// Parallel decorator only needs sync for WaitGroup, no strings or other imports
-	return decorators.StandardImportRequirement(decorators.ConcurrencyImports)
+	// sync for WaitGroup, Once and the output mutex; bufio, bytes, fmt, os and time to relay branch output
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.ConcurrencyImports, decorators.FileSystemImports, decorators.TimeImports, []string{"bufio", "bytes"})
 }
 
 // init registers the parallel decorator
@@ -1,11 +1,18 @@
 package decorators
 
 import (
+	"bytes"
+	"context"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
 	decoratortesting "github.com/aledsdavies/devcmd/testing"
 )
 
@@ -307,7 +314,7 @@ func TestParallelDecorator_ErrorHandling(t *testing.T) {
 	// Note: Interpreter might fail due to command failures, but generator and plan should work
 	errors := decoratortesting.Assert(result).
 		GeneratorSucceeds().
-		GeneratorCodeContains("errs := make([]error").
+		GeneratorCodeContains("errOnce.Do", "fail(err)", "return firstErr").
 		PlanSucceeds().
 		Validate()
 
@@ -465,3 +472,288 @@ func TestParallelDecorator_ReasonableConcurrencyDefaults(t *testing.T) {
 		t.Errorf("ParallelDecorator reasonable defaults test failed:\n%s", decoratortesting.JoinErrors(errors))
 	}
 }
+
+func TestParallelDecorator_OutputModes(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo 'branch one'"),
+		decoratortesting.Shell("echo 'branch two'"),
+	}
+
+	for _, mode := range []string{"stream", "buffered"} {
+		result := decoratortesting.NewDecoratorTest(t, decorator).
+			TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("output", mode)}, content)
+
+		errors := decoratortesting.Assert(result).
+			InterpreterSucceeds().
+			GeneratorSucceeds().
+			GeneratorProducesValidGo().
+			GeneratorCodeContains(`output := "`+mode+`"`, "os.Pipe()", "branchCtx.Stdout, branchCtx.Stderr = stdout, stderr").
+			PlanSucceeds().
+			Validate()
+
+		if len(errors) > 0 {
+			t.Errorf("ParallelDecorator output=%s test failed:\n%s", mode, decoratortesting.JoinErrors(errors))
+		}
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("output", "quiet")}, content)
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("must be \"stream\" or \"buffered\"").
+		GeneratorFails("must be \"stream\" or \"buffered\"").
+		PlanFails("must be \"stream\" or \"buffered\"").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParallelDecorator invalid output test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestParallelDecorator_InterpreterOutput(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo one; sleep 0.2; echo two"),
+		decoratortesting.Shell("sleep 0.1; echo three; printf four"),
+	}
+
+	run := func(mode string) string {
+		var out bytes.Buffer
+		ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithOutput(&out, &out)
+		result := decorator.ExecuteInterpreter(ctx, []ast.NamedParameter{decoratortesting.StringParam("output", mode)}, content)
+		if result.Error != nil {
+			t.Fatalf("output=%s: unexpected error: %v", mode, result.Error)
+		}
+		return out.String()
+	}
+
+	if got, want := run("stream"), "[1] one\n[2] three\n[2] four\n[1] two\n"; got != want {
+		t.Errorf("stream output = %q, want %q", got, want)
+	}
+	if got, want := run("buffered"), "three\nfourone\ntwo\n"; got != want {
+		t.Errorf("buffered output = %q, want %q", got, want)
+	}
+}
+
+func TestParallelDecorator_LimitAndFailFast(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo 'task 1'"),
+		decoratortesting.Shell("echo 'task 2'"),
+		decoratortesting.Shell("echo 'task 3'"),
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "limit", Value: &ast.NumberLiteral{Value: "2"}},
+			{Name: "failFast", Value: &ast.BooleanLiteral{Value: true}},
+		}, content)
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("workers := make(chan struct{}, 2)", "if start() {", "branchCtx.Run = cancellable(branchCtx.Run)", "close(cancelled)", "parallel execution failed: %w").
+		PlanSucceeds().
+		PlanReturnsElement("parallel").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParallelDecorator limit and failFast test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	// Without failFast nothing is cancelled
+	result = decoratortesting.NewDecoratorTest(t, decorator).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "limit", Value: &ast.NumberLiteral{Value: "1"}},
+			{Name: "failFast", Value: &ast.BooleanLiteral{Value: false}},
+		}, content)
+
+	errors = decoratortesting.Assert(result).
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("workers := make(chan struct{}, 1)").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParallelDecorator limit without failFast test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if code, _ := result.GeneratorResult.Data.(string); strings.Contains(code, "cancellable") || strings.Contains(code, "close(cancelled)") {
+		t.Errorf("generated code should only cancel with failFast:\n%s", code)
+	}
+
+	// The older names and the new ones are the same parameters
+	result = decoratortesting.NewDecoratorTest(t, decorator).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "limit", Value: &ast.NumberLiteral{Value: "2"}},
+			{Name: "concurrency", Value: &ast.NumberLiteral{Value: "2"}},
+		}, content)
+
+	errors = decoratortesting.Assert(result).
+		InterpreterFails("older name for 'limit'").
+		GeneratorFails("older name for 'limit'").
+		PlanFails("older name for 'limit'").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParallelDecorator duplicate limit test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestParallelDecorator_InterpreterLimit(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	// One worker runs the branches one after another, in order
+	content := []ast.CommandContent{
+		decoratortesting.Shell("sleep 0.1; echo one"),
+		decoratortesting.Shell("echo two"),
+		decoratortesting.Shell("echo three"),
+	}
+
+	var out bytes.Buffer
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithOutput(&out, &out)
+	params := []ast.NamedParameter{{Name: "limit", Value: &ast.NumberLiteral{Value: "1"}}}
+	if result := decorator.ExecuteInterpreter(ctx, params, content); result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if got, want := out.String(), "[1] one\n[2] two\n[3] three\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestParallelDecorator_InterpreterFailFast(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	run := func(failFast bool, content []ast.CommandContent) (string, error, time.Duration) {
+		var out bytes.Buffer
+		ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithOutput(&out, &out)
+		params := []ast.NamedParameter{
+			{Name: "limit", Value: &ast.NumberLiteral{Value: "2"}},
+			{Name: "failFast", Value: &ast.BooleanLiteral{Value: failFast}},
+		}
+		started := time.Now()
+		result := decorator.ExecuteInterpreter(ctx, params, content)
+		return out.String(), result.Error, time.Since(started)
+	}
+
+	// The failure kills the running branch and the waiting one never starts
+	content := []ast.CommandContent{
+		decoratortesting.Shell("sleep 0.1; exit 3"),
+		decoratortesting.Shell("sleep 5; echo slow"),
+		decoratortesting.Shell("echo waiting"),
+	}
+	out, err, elapsed := run(true, content)
+	if err == nil || !strings.Contains(err.Error(), "parallel execution failed: exit status 3") {
+		t.Errorf("expected the first failure, got %v", err)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("failFast should kill the running branch, took %s", elapsed)
+	}
+	if strings.Contains(out, "slow") || strings.Contains(out, "waiting") {
+		t.Errorf("cancelled branches should not write output, got %q", out)
+	}
+
+	// Without failFast every branch runs and the failure is still reported
+	content = []ast.CommandContent{
+		decoratortesting.Shell("exit 3"),
+		decoratortesting.Shell("sleep 0.1; echo slow"),
+		decoratortesting.Shell("echo waiting"),
+	}
+	out, err, _ = run(false, content)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("expected the failure, got %v", err)
+	}
+	if !strings.Contains(out, "[2] slow") || !strings.Contains(out, "[3] waiting") {
+		t.Errorf("every branch should run without failFast, got %q", out)
+	}
+}
+
+func TestParallelDecorator_PlanShowsLimit(t *testing.T) {
+	decorator := &ParallelDecorator{}
+
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo 'task 1'"),
+		decoratortesting.Shell("echo 'task 2'"),
+		decoratortesting.Shell("echo 'task 3'"),
+	}
+	ctx := execution.NewPlanContext(context.Background(), &ast.Program{})
+	params := []ast.NamedParameter{
+		{Name: "limit", Value: &ast.NumberLiteral{Value: "1"}},
+		{Name: "failFast", Value: &ast.BooleanLiteral{Value: true}},
+	}
+	result := decorator.ExecutePlan(ctx, params, content)
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	element, ok := result.Data.(plan.PlanElement)
+	if !ok {
+		t.Fatalf("expected a plan element, got %T", result.Data)
+	}
+	step := element.Build()
+	if want := "Execute 3 commands concurrently (max 1 at a time), cancel the rest on first error"; step.Description != want {
+		t.Errorf("description = %q, want %q", step.Description, want)
+	}
+	executionPlan := plan.NewExecutionPlan()
+	executionPlan.AddStep(step)
+	if text := executionPlan.StringNoColor(); !strings.Contains(text, "@parallel {1 concurrent, fail fast}") {
+		t.Errorf("plan should show the limit, got:\n%s", text)
+	}
+}
+
+func TestPrefixWriter_HoldsPartialLines(t *testing.T) {
+	var out bytes.Buffer
+	stdout, _, flush := newBranchOutput("stream", "3", &out, &out, &sync.Mutex{})
+
+	_, _ = stdout.Write([]byte("par"))
+	_, _ = stdout.Write([]byte("tial\nnext"))
+	if out.String() != "[3] partial\n" {
+		t.Errorf("complete lines should be written immediately, got %q", out.String())
+	}
+	flush()
+	if !strings.HasSuffix(out.String(), "[3] next\n") {
+		t.Errorf("flush should terminate the final line, got %q", out.String())
+	}
+}
+
+func TestParallelBranchNames(t *testing.T) {
+	cmd := func(name string) ast.CommandContent {
+		return &ast.ShellContent{Parts: []ast.ShellPart{
+			&ast.ActionDecorator{Name: "cmd", Args: []ast.NamedParameter{decoratortesting.IdentifierParam("name", name)}},
+		}}
+	}
+	content := []ast.CommandContent{
+		cmd("build"),
+		decoratortesting.Shell("go test ./..."),
+		cmd("lint"),
+		cmd("lint"),
+	}
+
+	// Branches running the same command can't be told apart by it
+	got := strings.Join(parallelBranchNames(content), ",")
+	if want := "build,2,3,4"; got != want {
+		t.Errorf("parallelBranchNames() = %s, want %s", got, want)
+	}
+}
+
+func TestParallelDecorator_LogsBranches(t *testing.T) {
+	previous := logging.Default()
+	defer logging.SetDefault(previous)
+	logging.SetDefault(logging.New(nil, logging.Debug, logging.Text))
+
+	var out bytes.Buffer
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithOutput(&out, &out)
+	result := (&ParallelDecorator{}).ExecuteInterpreter(ctx, nil, []ast.CommandContent{decoratortesting.Shell("exit 3")})
+	if result.Error == nil {
+		t.Fatal("expected the failing branch's error")
+	}
+
+	for _, want := range []string{"@parallel: branch 1 started\n", "[1] shell: running exit 3", "@parallel: branch 1 failed error=exit status 3\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
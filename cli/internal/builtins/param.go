@@ -0,0 +1,157 @@
+package decorators
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// ParamDecorator implements the @param decorator for the parameters a command declares,
+// e.g. replicas in deploy(replicas: number = 3): kubectl scale --replicas=@param(replicas)
+type ParamDecorator struct{}
+
+// Name returns the decorator name
+func (p *ParamDecorator) Name() string {
+	return "param"
+}
+
+// Description returns a human-readable description
+func (p *ParamDecorator) Description() string {
+	return "Reference a parameter of the command"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (p *ParamDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "name",
+			Type:        ast.IdentifierType,
+			Required:    true,
+			Description: "Name of the command parameter to reference",
+		},
+		{
+			Name:        "raw",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, the value is inserted as shell syntax instead of being quoted",
+		},
+	}
+}
+
+// ExpandInterpreter returns the value the command runs with for interpreter mode
+func (p *ParamDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	name, err := p.extractParamName(params)
+	if err != nil {
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: fmt.Errorf("param parameter error: %w", err),
+		}
+	}
+
+	if value, exists := ctx.GetParam(name); exists {
+		return &execution.ExecutionResult{
+			Data:  value,
+			Error: nil,
+		}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: fmt.Errorf("parameter '%s' has no value", name),
+	}
+}
+
+// GenerateTemplate returns template for Go code that reads the parameter for generator mode
+func (p *ParamDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	name, err := p.extractParamName(params)
+	if err != nil {
+		return nil, fmt.Errorf("param parameter error: %w", err)
+	}
+
+	// Generated commands validate their flags and arguments into ctx.Params before they run
+	tmpl, err := template.New("param").Parse(`ctx.Params[{{printf "%q" .Name}}]`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse param template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Name string
+		}{
+			Name: name,
+		},
+	}, nil
+}
+
+// ExpandPlan returns description for dry-run display in plan mode
+func (p *ParamDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	name, err := p.extractParamName(params)
+	if err != nil {
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: fmt.Errorf("param parameter error: %w", err),
+		}
+	}
+
+	if value, exists := ctx.GetParam(name); exists {
+		return &execution.ExecutionResult{
+			Data:  fmt.Sprintf("@param(%s) → %q", name, value),
+			Error: nil,
+		}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  fmt.Sprintf("@param(%s) → <unset>", name),
+		Error: nil,
+	}
+}
+
+// extractParamName extracts the command parameter name from decorator parameters
+func (p *ParamDecorator) extractParamName(params []ast.NamedParameter) (string, error) {
+	var nameParams []ast.NamedParameter
+	for _, param := range params {
+		if param.Name != "raw" {
+			nameParams = append(nameParams, param)
+		}
+	}
+	if err := decorators.ValidateParameterCount(nameParams, 1, 1, "param"); err != nil {
+		return "", err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, p.ParameterSchema(), "param"); err != nil {
+		return "", err
+	}
+
+	nameParam := ast.FindParameter(params, "name")
+	if nameParam == nil {
+		nameParam = &nameParams[0]
+	}
+	if ident, ok := nameParam.Value.(*ast.Identifier); ok {
+		return ident.Name, nil
+	}
+
+	return "", fmt.Errorf("@param decorator requires a valid identifier parameter")
+}
+
+// QuotesShellValue reports whether the parameter's value is quoted in shell commands, so
+// spaces and quotes in it don't split or break the command
+func (p *ParamDecorator) QuotesShellValue(params []ast.NamedParameter) bool {
+	return !ast.GetBoolParam(params, "raw", false)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (p *ParamDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.ImportRequirement{
+		StandardLibrary: []string{}, // Generated commands parse parameters with the CLI core's packages
+		ThirdParty:      []string{},
+		GoModules:       map[string]string{},
+	}
+}
+
+// init registers the param decorator
+func init() {
+	decorators.RegisterValue(&ParamDecorator{})
+}
@@ -0,0 +1,63 @@
+package decorators
+
+import (
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestParamDecorator_Basic(t *testing.T) {
+	decorator := &ParamDecorator{}
+
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		WithParam("replicas", "3").
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.IdentifierParam("", "replicas"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("3").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`ctx.Params["replicas"]`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParamDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestParamDecorator_NoValue(t *testing.T) {
+	decorator := &ParamDecorator{}
+
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.IdentifierParam("", "env"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("parameter 'env' has no value").
+		GeneratorSucceeds().
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ParamDecorator no value test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestParamDecorator_ShellQuoting(t *testing.T) {
+	decorator := &ParamDecorator{}
+
+	if !decorator.QuotesShellValue([]ast.NamedParameter{decoratortesting.IdentifierParam("", "env")}) {
+		t.Errorf("@param values should be quoted for the shell by default")
+	}
+	if decorator.QuotesShellValue([]ast.NamedParameter{
+		decoratortesting.IdentifierParam("", "flags"),
+		decoratortesting.BoolParam("raw", true),
+	}) {
+		t.Errorf("@param values should not be quoted with raw = true")
+	}
+}
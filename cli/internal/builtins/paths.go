@@ -0,0 +1,291 @@
+package decorators
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// pathTransform is a transform decorator whose result depends on the command's working
+// directory, so relative paths resolve the way the command's shell steps see them
+type pathTransform interface {
+	transformDecorator
+
+	// resolvePath computes the result from the parameter values by name, relative to dir
+	resolvePath(dir string, args map[string]string) (string, error)
+}
+
+// absolutePathTemplate is the Go function literal generated code uses to resolve a path
+// against the command's working directory, ctx.Dir, exiting when it can't
+const absolutePathTemplate = `func(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.Dir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		logf("error", "@%s", "%%v", err)
+		os.Exit(1)
+	}
+	return abs
+}`
+
+// absolutePath resolves path against dir, itself relative to the process's working
+// directory when not absolute, as commands run with that directory do
+func absolutePath(dir, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Abs(path)
+}
+
+// expandPathTransform applies a path decorator in interpreter mode
+func expandPathTransform(ctx execution.InterpreterContext, d pathTransform, params []ast.NamedParameter) *execution.ExecutionResult {
+	args, err := transformArgs(ctx, d, params, evaluateValue, "")
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	path, err := d.resolvePath(ctx.GetWorkingDir(), args)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@%s: %w", d.Name(), err)}
+	}
+	return &execution.ExecutionResult{Data: path, Error: nil}
+}
+
+// planPathTransform shows a path decorator's result for plan mode
+func planPathTransform(ctx execution.PlanContext, d pathTransform, params []ast.NamedParameter) *execution.ExecutionResult {
+	description := describeTransform(d, params)
+	args, err := transformArgs(ctx, d, params, evaluateValue, "")
+	if err != nil {
+		return &execution.ExecutionResult{Data: description + "<undefined>", Error: nil}
+	}
+	path, err := d.resolvePath(ctx.GetWorkingDir(), args)
+	if err != nil {
+		return &execution.ExecutionResult{Data: description + "<unavailable>", Error: nil}
+	}
+	return &execution.ExecutionResult{Data: description + strconv.Quote(path), Error: nil}
+}
+
+// pathImports are the imports needed by the path decorators' generated code
+func pathImports() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"path/filepath"})
+}
+
+// AbspathDecorator implements the @abspath decorator for absolute paths
+type AbspathDecorator struct{}
+
+// Name returns the decorator name
+func (a *AbspathDecorator) Name() string {
+	return "abspath"
+}
+
+// Description returns a human-readable description
+func (a *AbspathDecorator) Description() string {
+	return "Absolute form of a path relative to the command's working directory"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (a *AbspathDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "path",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Path to make absolute; relative paths resolve against the working directory",
+		},
+	}
+}
+
+// ExpandInterpreter returns the absolute path for interpreter mode
+func (a *AbspathDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandPathTransform(ctx, a, params)
+}
+
+// GenerateTemplate returns template for Go code that resolves the absolute path
+func (a *AbspathDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, a, params)
+}
+
+// ExpandPlan returns description showing the absolute path for plan mode
+func (a *AbspathDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planPathTransform(ctx, a, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (a *AbspathDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+func (a *AbspathDecorator) resolvePath(dir string, args map[string]string) (string, error) {
+	return absolutePath(dir, args["path"])
+}
+
+func (a *AbspathDecorator) goExpression(args map[string]string) string {
+	return fmt.Sprintf(absolutePathTemplate, a.Name()) + fmt.Sprintf("(%s)", args["path"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (a *AbspathDecorator) ImportRequirements() decorators.ImportRequirement {
+	return pathImports()
+}
+
+// JoinDecorator implements the @join decorator for joining paths with the platform separator
+type JoinDecorator struct{}
+
+// Name returns the decorator name
+func (j *JoinDecorator) Name() string {
+	return "join"
+}
+
+// Description returns a human-readable description
+func (j *JoinDecorator) Description() string {
+	return "Join two paths with the platform's separator"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (j *JoinDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "base",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Leading path",
+		},
+		{
+			Name:        "path",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Path to append; / separates elements on every platform, e.g. \"build/bin\"",
+		},
+	}
+}
+
+// ExpandInterpreter returns the joined path for interpreter mode
+func (j *JoinDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandPathTransform(ctx, j, params)
+}
+
+// GenerateTemplate returns template for Go code that joins the paths
+func (j *JoinDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, j, params)
+}
+
+// ExpandPlan returns description showing the joined path for plan mode
+func (j *JoinDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planPathTransform(ctx, j, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (j *JoinDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+// resolvePath joins the paths as written: the result is relative when base is
+func (j *JoinDecorator) resolvePath(dir string, args map[string]string) (string, error) {
+	return filepath.Join(args["base"], args["path"]), nil
+}
+
+func (j *JoinDecorator) goExpression(args map[string]string) string {
+	return fmt.Sprintf("filepath.Join(%s, %s)", args["base"], args["path"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (j *JoinDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement([]string{"path/filepath"})
+}
+
+// RelpathDecorator implements the @relpath decorator for paths relative to a base directory
+type RelpathDecorator struct{}
+
+// relativePathTemplate is the Go function literal generated code uses for @relpath
+const relativePathTemplate = `func(path, base string) string {
+	abs := %s
+	rel, err := filepath.Rel(abs(base), abs(path))
+	if err != nil {
+		logf("error", "@relpath", "%%v", err)
+		os.Exit(1)
+	}
+	return rel
+}`
+
+// Name returns the decorator name
+func (r *RelpathDecorator) Name() string {
+	return "relpath"
+}
+
+// Description returns a human-readable description
+func (r *RelpathDecorator) Description() string {
+	return "Path relative to a base directory, by default the command's working directory"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (r *RelpathDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "path",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Path to make relative",
+		},
+		{
+			Name:        "base",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Directory the result is relative to (defaults to the working directory)",
+		},
+	}
+}
+
+// ExpandInterpreter returns the relative path for interpreter mode
+func (r *RelpathDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandPathTransform(ctx, r, params)
+}
+
+// GenerateTemplate returns template for Go code that computes the relative path
+func (r *RelpathDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, r, params)
+}
+
+// ExpandPlan returns description showing the relative path for plan mode
+func (r *RelpathDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planPathTransform(ctx, r, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (r *RelpathDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+// resolvePath makes both paths absolute first, so either may be relative to the working
+// directory; an empty base is the working directory itself
+func (r *RelpathDecorator) resolvePath(dir string, args map[string]string) (string, error) {
+	path, err := absolutePath(dir, args["path"])
+	if err != nil {
+		return "", err
+	}
+	base, err := absolutePath(dir, args["base"])
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(base, path)
+}
+
+func (r *RelpathDecorator) goExpression(args map[string]string) string {
+	abs := fmt.Sprintf(absolutePathTemplate, r.Name())
+	return fmt.Sprintf(relativePathTemplate, abs) + fmt.Sprintf("(%s, %s)", args["path"], args["base"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (r *RelpathDecorator) ImportRequirements() decorators.ImportRequirement {
+	return pathImports()
+}
+
+// init registers the path decorators
+func init() {
+	decorators.RegisterValue(&AbspathDecorator{})
+	decorators.RegisterValue(&JoinDecorator{})
+	decorators.RegisterValue(&RelpathDecorator{})
+}
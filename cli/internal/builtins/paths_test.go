@@ -0,0 +1,167 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestAbspathDecorator_Basic(t *testing.T) {
+	want, err := filepath.Abs("build")
+	if err != nil {
+		t.Fatalf("failed to resolve expected path: %v", err)
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &AbspathDecorator{}).
+		WithVariable("OUT", "build").
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.IdentifierParam("path", "OUT"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns(want).
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("filepath.Join(ctx.Dir, path)", "filepath.Abs(path)", "}(OUT)").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("AbspathDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if plan, want := fmt.Sprint(result.PlanResult.Data), fmt.Sprintf("@abspath(OUT) → %q", want); plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestPathDecorators_WorkingDirectory(t *testing.T) {
+	root := t.TempDir()
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithWorkingDir(filepath.Join(root, "web"))
+
+	tests := []struct {
+		name      string
+		decorator decorators.ValueDecorator
+		params    []ast.NamedParameter
+		expected  string
+	}{
+		{
+			name:      "abspath relative",
+			decorator: &AbspathDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("path", "dist/app.js")},
+			expected:  filepath.Join(root, "web", "dist", "app.js"),
+		},
+		{
+			name:      "abspath absolute",
+			decorator: &AbspathDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("path", filepath.Join(root, "api"))},
+			expected:  filepath.Join(root, "api"),
+		},
+		{
+			name:      "join",
+			decorator: &JoinDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.StringParam("base", "dist"),
+				decoratortesting.StringParam("path", "static/../bin/app"),
+			},
+			expected: filepath.Join("dist", "bin", "app"),
+		},
+		{
+			name:      "relpath to working directory",
+			decorator: &RelpathDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("path", root)},
+			expected:  "..",
+		},
+		{
+			name:      "relpath to base",
+			decorator: &RelpathDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.StringParam("path", filepath.Join(root, "api", "main.go")),
+				decoratortesting.StringParam("base", ".."),
+			},
+			expected: filepath.Join("api", "main.go"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.decorator.ExpandInterpreter(ctx, tt.params)
+			if result.Error != nil {
+				t.Fatalf("@%s failed: %v", tt.decorator.Name(), result.Error)
+			}
+			if result.Data != tt.expected {
+				t.Errorf("@%s = %q, want %q", tt.decorator.Name(), result.Data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPathDecorators_Generate(t *testing.T) {
+	tests := []struct {
+		name      string
+		decorator decorators.ValueDecorator
+		params    []ast.NamedParameter
+		code      []string
+	}{
+		{
+			name:      "join",
+			decorator: &JoinDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.IdentifierParam("base", "ROOT"),
+				decoratortesting.StringParam("path", "bin/@var(APP)"),
+			},
+			code: []string{`filepath.Join(ROOT, "bin/" + APP)`},
+		},
+		{
+			name:      "relpath",
+			decorator: &RelpathDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.IdentifierParam("path", "ROOT")},
+			code:      []string{"filepath.Rel(abs(base), abs(path))", `}(ROOT, "")`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, tt.decorator).
+				WithVariable("ROOT", "/srv/app").
+				WithVariable("APP", "server").
+				TestValueDecorator(tt.params)
+
+			errors := decoratortesting.Assert(result).
+				GeneratorSucceeds().
+				GeneratorProducesValidGo().
+				GeneratorCodeContains(tt.code...).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("%s generate test failed:\n%s", tt.decorator.Name(), decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestPathDecorators_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"missing path":      {decoratortesting.StringParam("base", "dist")},
+		"unknown parameter": {decoratortesting.StringParam("base", "dist"), decoratortesting.StringParam("path", "bin"), decoratortesting.StringParam("sep", "/")},
+		"undefined":         {decoratortesting.StringParam("base", "@var(MISSING)"), decoratortesting.StringParam("path", "bin")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &JoinDecorator{}).TestValueDecorator(params)
+			errors := decoratortesting.Assert(result).
+				InterpreterFails("").
+				GeneratorFails("").
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("JoinDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package decorators
+
+import (
+	"fmt"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// contentPlan returns the plan element of a command inside a decorator's block or pattern
+// branch, planning nested decorators with their own ExecutePlan so dry runs show the whole tree
+func contentPlan(ctx execution.PlanContext, content ast.CommandContent) (plan.PlanElement, error) {
+	var result *execution.ExecutionResult
+	switch c := content.(type) {
+	case *ast.ShellContent:
+		result = ctx.GenerateShellPlan(c)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to create plan for shell content: %w", result.Error)
+		}
+	case *ast.BlockDecorator:
+		blockDecorator, err := decorators.GetBlock(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("block decorator @%s not found: %w", c.Name, err)
+		}
+		result = blockDecorator.ExecutePlan(ctx, c.Args, c.Content)
+		if result.Error != nil {
+			return nil, fmt.Errorf("@%s decorator plan execution failed: %w", c.Name, result.Error)
+		}
+	case *ast.PatternDecorator:
+		patternDecorator, err := decorators.GetPattern(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("pattern decorator @%s not found: %w", c.Name, err)
+		}
+		result = patternDecorator.ExecutePlan(ctx, c.Args, c.Patterns)
+		if result.Error != nil {
+			return nil, fmt.Errorf("@%s decorator plan execution failed: %w", c.Name, result.Error)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported command content type in plan mode: %T", content)
+	}
+
+	element, ok := result.Data.(plan.PlanElement)
+	if !ok {
+		return nil, fmt.Errorf("plan for %T should be a plan element, got %T", content, result.Data)
+	}
+	return element, nil
+}
+
+// contentPlans returns the plan elements of the commands inside a block or pattern branch
+func contentPlans(ctx execution.PlanContext, content []ast.CommandContent) ([]plan.PlanElement, error) {
+	elements := make([]plan.PlanElement, 0, len(content))
+	for _, cmd := range content {
+		element, err := contentPlan(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// addContentPlan adds plan children for the commands inside a block decorator
+func addContentPlan(ctx execution.PlanContext, element *plan.DecoratorElement, content []ast.CommandContent) (*plan.DecoratorElement, error) {
+	children, err := contentPlans(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		element = element.AddChild(child)
+	}
+	return element, nil
+}
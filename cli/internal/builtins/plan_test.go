@@ -0,0 +1,164 @@
+package decorators
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestBlockDecorators_PlanElementsConform(t *testing.T) {
+	tests := []struct {
+		name      string
+		decorator decorators.BlockDecorator
+		params    []ast.NamedParameter
+		stepType  plan.StepType
+		check     func(step plan.ExecutionStep) string
+	}{
+		{
+			name:      "timeout shows its bound",
+			decorator: &TimeoutDecorator{},
+			params:    []ast.NamedParameter{{Name: "duration", Value: &ast.DurationLiteral{Value: "5s"}}},
+			stepType:  plan.StepTimeout,
+			check: func(step plan.ExecutionStep) string {
+				if step.Timing == nil || step.Timing.Timeout == nil || *step.Timing.Timeout != 5*time.Second {
+					return "timing should hold the 5s timeout"
+				}
+				return ""
+			},
+		},
+		{
+			name:      "retry shows its policy",
+			decorator: &RetryDecorator{},
+			params: []ast.NamedParameter{
+				{Name: "attempts", Value: &ast.NumberLiteral{Value: "4"}},
+				{Name: "delay", Value: &ast.DurationLiteral{Value: "1s"}},
+			},
+			stepType: plan.StepRetry,
+			check: func(step plan.ExecutionStep) string {
+				if step.Timing == nil || step.Timing.RetryAttempts != 4 || step.Timing.RetryDelay == nil || *step.Timing.RetryDelay != time.Second {
+					return "timing should hold 4 attempts with 1s delay"
+				}
+				return ""
+			},
+		},
+		{
+			name:      "parallel names its branches",
+			decorator: &ParallelDecorator{},
+			params:    []ast.NamedParameter{{Name: "limit", Value: &ast.NumberLiteral{Value: "2"}}},
+			stepType:  plan.StepParallel,
+			check: func(step plan.ExecutionStep) string {
+				for i, child := range step.Children {
+					if want := []string{"1", "2"}[i]; child.Metadata["branch"] != want {
+						return "branch " + want + " should be named in its metadata"
+					}
+				}
+				return ""
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []ast.CommandContent{
+				decoratortesting.Shell("echo one"),
+				decoratortesting.Shell("echo two"),
+			}
+			result := decoratortesting.NewDecoratorTest(t, tt.decorator).
+				TestBlockDecorator(tt.params, content)
+
+			errors := decoratortesting.Assert(result).
+				Conforms().
+				PlanStepType(tt.stepType).
+				PlanDecoratorIs(tt.decorator.Name()).
+				PlanHasChildren(2).
+				Validate()
+			if step, ok := decoratortesting.PlanStep(result); ok {
+				if message := tt.check(step); message != "" {
+					errors = append(errors, message)
+				}
+				for _, child := range step.Children {
+					if child.Type != plan.StepShell || !strings.HasPrefix(child.Command, "echo ") {
+						errors = append(errors, "children should be the shell commands, got "+child.Description)
+					}
+				}
+			}
+
+			if len(errors) > 0 {
+				t.Errorf("@%s plan element does not conform:\n%s", tt.decorator.Name(), decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestContentPlan_NestedDecorators(t *testing.T) {
+	ctx := execution.NewPlanContext(context.Background(), &ast.Program{})
+	content := []ast.CommandContent{
+		&ast.BlockDecorator{
+			Name: "retry",
+			Args: []ast.NamedParameter{{Name: "attempts", Value: &ast.NumberLiteral{Value: "2"}}},
+			Content: []ast.CommandContent{
+				decoratortesting.Shell("make flaky"),
+			},
+		},
+	}
+	params := []ast.NamedParameter{{Name: "duration", Value: &ast.DurationLiteral{Value: "1m"}}}
+
+	result := (&TimeoutDecorator{}).ExecutePlan(ctx, params, content)
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	step := result.Data.(plan.PlanElement).Build()
+	if len(step.Children) != 1 || step.Children[0].Type != plan.StepRetry {
+		t.Fatalf("@timeout should hold the @retry step, got %+v", step.Children)
+	}
+	retry := step.Children[0]
+	if len(retry.Children) != 1 || retry.Children[0].Command != "make flaky" {
+		t.Errorf("@retry should hold its shell command, got %+v", retry.Children)
+	}
+
+	executionPlan := plan.NewExecutionPlan()
+	executionPlan.AddStep(step)
+	text := executionPlan.StringNoColor()
+	for _, want := range []string{"@timeout {1m0s timeout}", "@retry {2 attempts, 1s delay}", "make flaky"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("plan should show %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestPatternDecorators_PlanShellChildren(t *testing.T) {
+	ctx := execution.NewPlanContext(context.Background(), &ast.Program{})
+
+	tryResult := (&TryDecorator{}).ExecutePlan(ctx, nil, []ast.PatternBranch{
+		decoratortesting.PatternBranch("main", "make deploy"),
+		decoratortesting.PatternBranch("catch", "make rollback"),
+	})
+	if tryResult.Error != nil {
+		t.Fatalf("unexpected @try error: %v", tryResult.Error)
+	}
+	try := tryResult.Data.(plan.PlanElement).Build()
+	if len(try.Children) != 2 || try.Children[0].Command != "make deploy" || len(try.Children[1].Children) != 1 {
+		t.Errorf("@try should hold the main command and catch block, got %+v", try.Children)
+	}
+
+	whenCtx := execution.NewPlanContext(context.Background(), &ast.Program{})
+	whenCtx.SetVariable("ENV", "prod")
+	whenResult := (&WhenDecorator{}).ExecutePlan(whenCtx, []ast.NamedParameter{decoratortesting.StringParam("variable", "ENV")}, []ast.PatternBranch{
+		decoratortesting.PatternBranch("prod", "make release", "make notify"),
+		decoratortesting.PatternBranch("default", "make dev"),
+	})
+	if whenResult.Error != nil {
+		t.Fatalf("unexpected @when error: %v", whenResult.Error)
+	}
+	when := whenResult.Data.(plan.PlanElement).Build()
+	if len(when.Children) != 2 || when.Children[0].Command != "make release" || when.Children[1].Command != "make notify" {
+		t.Errorf("@when should hold every command of the selected branch, got %+v", when.Children)
+	}
+}
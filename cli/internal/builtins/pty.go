@@ -0,0 +1,321 @@
+package decorators
+
+import (
+	"fmt"
+	"runtime"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// ptyTemplate runs the block's shell steps under a pseudo-terminal. It mirrors runInPTY and
+// openPTY; ioctl request numbers are spelled out since the generated CLI is a single file.
+const ptyTemplate = `// Run in a pseudo-terminal
+{
+	ctx := ctx.Clone()
+	var getTermios, setTermios uintptr
+	switch runtime.GOOS + "/" + runtime.GOARCH {
+	case "linux/amd64", "linux/arm64", "linux/386", "linux/arm", "linux/riscv64":
+		getTermios, setTermios = 0x5401, 0x5402 // TCGETS, TCSETS
+	case "darwin/amd64", "darwin/arm64":
+		getTermios, setTermios = 0x40487413, 0x80487414 // TIOCGETA, TIOCSETA
+	default:
+		logf("warn", "@pty", "pseudo-terminals are not supported on %s, running without one", runtime.GOOS)
+	}
+	if getTermios != 0 {
+		ioctl := func(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+				return errno
+			}
+			return nil
+		}
+		isTerminal := func(fd int) bool {
+			var attrs syscall.Termios
+			return ioctl(uintptr(fd), getTermios, unsafe.Pointer(&attrs)) == nil
+		}
+		type windowSize struct {
+			Rows, Cols, XPixel, YPixel uint16
+		}
+		terminalSize := func() windowSize {
+			for _, fd := range []int{syscall.Stdin, syscall.Stdout, syscall.Stderr} {
+				var size windowSize
+				if ioctl(uintptr(fd), syscall.TIOCGWINSZ, unsafe.Pointer(&size)) == nil && size.Rows > 0 && size.Cols > 0 {
+					return size
+				}
+			}
+			size := windowSize{Rows: 24, Cols: 80}
+			if rows, err := strconv.ParseUint(os.Getenv("LINES"), 10, 16); err == nil && rows > 0 {
+				size.Rows = uint16(rows)
+			}
+			if cols, err := strconv.ParseUint(os.Getenv("COLUMNS"), 10, 16); err == nil && cols > 0 {
+				size.Cols = uint16(cols)
+			}
+			return size
+		}
+		openPTY := func() (*os.File, *os.File, error) {
+			master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			var slaveName string
+			if runtime.GOOS == "darwin" {
+				name := make([]byte, 128)
+				err = ioctl(master.Fd(), 0x20007454, nil) // TIOCPTYGRANT
+				if err == nil {
+					err = ioctl(master.Fd(), 0x20007452, nil) // TIOCPTYUNLK
+				}
+				if err == nil {
+					err = ioctl(master.Fd(), 0x40807453, unsafe.Pointer(&name[0])) // TIOCPTYGNAME
+				}
+				for i, b := range name {
+					if b == 0 {
+						name = name[:i]
+						break
+					}
+				}
+				slaveName = string(name)
+			} else {
+				var unlock int32
+				var number uint32
+				err = ioctl(master.Fd(), 0x40045431, unsafe.Pointer(&unlock)) // TIOCSPTLCK
+				if err == nil {
+					err = ioctl(master.Fd(), 0x80045430, unsafe.Pointer(&number)) // TIOCGPTN
+				}
+				slaveName = "/dev/pts/" + strconv.FormatUint(uint64(number), 10)
+			}
+			if err != nil {
+				master.Close()
+				return nil, nil, err
+			}
+			slave, err := os.OpenFile(slaveName, os.O_RDWR|syscall.O_NOCTTY, 0)
+			if err != nil {
+				master.Close()
+				return nil, nil, err
+			}
+			return master, slave, nil
+		}
+		makeRaw := func(fd int) (func(), error) {
+			var original syscall.Termios
+			if err := ioctl(uintptr(fd), getTermios, unsafe.Pointer(&original)); err != nil {
+				return nil, err
+			}
+			raw := original
+			raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+			raw.Oflag &^= syscall.OPOST
+			raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+			raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+			raw.Cflag |= syscall.CS8
+			raw.Cc[syscall.VMIN] = 1
+			raw.Cc[syscall.VTIME] = 0
+			if err := ioctl(uintptr(fd), setTermios, unsafe.Pointer(&raw)); err != nil {
+				return nil, err
+			}
+			return func() {
+				_ = ioctl(uintptr(fd), setTermios, unsafe.Pointer(&original))
+			}, nil
+		}
+		forwardStdin := func(dst io.Writer) func() {
+			fd, err := syscall.Dup(syscall.Stdin)
+			if err != nil {
+				return func() {}
+			}
+			if err := syscall.SetNonblock(fd, true); err != nil {
+				syscall.Close(fd)
+				return func() {}
+			}
+			stdin := os.NewFile(uintptr(fd), "/dev/stdin")
+			done := make(chan struct{})
+			go func() {
+				_, _ = io.Copy(dst, stdin)
+				close(done)
+			}()
+			return func() {
+				_ = stdin.SetReadDeadline(time.Now())
+				<-done
+				stdin.Close()
+				_ = syscall.SetNonblock(syscall.Stdin, false)
+			}
+		}
+
+		ctx.Run = func(cmd *execpkg.Cmd) error {
+			master, slave, err := openPTY()
+			if err != nil {
+				return fmt.Errorf("@pty: failed to allocate a pseudo-terminal: %w", err)
+			}
+			defer master.Close()
+
+			if !isTerminal(syscall.Stdout) {
+				var attrs syscall.Termios
+				if ioctl(slave.Fd(), getTermios, unsafe.Pointer(&attrs)) == nil {
+					attrs.Oflag &^= syscall.OPOST
+					_ = ioctl(slave.Fd(), setTermios, unsafe.Pointer(&attrs))
+				}
+			}
+			resize := func() {
+				size := terminalSize()
+				_ = ioctl(master.Fd(), syscall.TIOCSWINSZ, unsafe.Pointer(&size))
+			}
+			resize()
+
+			var output io.Writer = io.Discard
+			if cmd.Stdout != nil {
+				output = cmd.Stdout
+			}
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+			err = cmd.Start()
+			slave.Close()
+			if err != nil {
+				return err
+			}
+
+			winch := make(chan os.Signal, 1)
+			signal.Notify(winch, syscall.SIGWINCH)
+			done := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-winch:
+						resize()
+					case <-done:
+						return
+					}
+				}
+			}()
+			defer func() {
+				signal.Stop(winch)
+				close(done)
+			}()
+
+			if isTerminal(syscall.Stdin) {
+				if restore, err := makeRaw(syscall.Stdin); err == nil {
+					defer restore()
+				}
+				defer forwardStdin(master)()
+			}
+
+			copied := make(chan struct{})
+			go func() {
+				_, _ = io.Copy(output, master)
+				close(copied)
+			}()
+			err = cmd.Wait()
+			<-copied
+			return err
+		}
+	}
+
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// PtyDecorator implements the @pty decorator for running commands under a pseudo-terminal
+type PtyDecorator struct{}
+
+// Name returns the decorator name
+func (p *PtyDecorator) Name() string {
+	return "pty"
+}
+
+// Description returns a human-readable description
+func (p *PtyDecorator) Description() string {
+	return "Run the block's commands under a pseudo-terminal for tools that need a TTY"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (p *PtyDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{}
+}
+
+// ExecuteInterpreter runs the block under a pseudo-terminal in interpreter mode
+func (p *PtyDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	if err := p.validateParameters(params); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	ptyCtx := ctx.Child()
+	if ptySupported {
+		ptyCtx = ptyCtx.WithCommandRunner(runInPTY)
+	} else {
+		ctx.Logger().Warnf("@pty", "pseudo-terminals are not supported on %s, running without one", runtime.GOOS)
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ptyCtx, content),
+	}
+}
+
+// GenerateTemplate generates template for running the block under a pseudo-terminal
+func (p *PtyDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	if err := p.validateParameters(params); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("pty").Funcs(ctx.GetTemplateFunctions()).Parse(ptyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pty template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Content []ast.CommandContent
+		}{
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (p *PtyDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	if err := p.validateParameters(params); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(p.Name()).
+		WithType("block").
+		WithDescription("Run in a pseudo-terminal")
+
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// validateParameters rejects parameters, which @pty does not take
+func (p *PtyDecorator) validateParameters(params []ast.NamedParameter) error {
+	if err := decorators.ValidateParameterCount(params, 0, 0, p.Name()); err != nil {
+		return err
+	}
+	return decorators.ValidateSchemaCompliance(params, p.ParameterSchema(), p.Name())
+}
+
+// SupportedPlatforms returns the targets generated CLIs using @pty build for: the code uses
+// Unix terminal calls. Interpreted commands run anywhere, without a terminal where unsupported.
+func (p *PtyDecorator) SupportedPlatforms(mode decorators.Mode) []string {
+	if mode == decorators.GeneratorMode {
+		return []string{"darwin", "linux"}
+	}
+	return nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (p *PtyDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.TimeImports, []string{"io", "os/exec", "os/signal", "runtime", "strconv", "syscall", "unsafe"})
+}
+
+// init registers the pty decorator
+func init() {
+	decorators.RegisterBlock(&PtyDecorator{})
+}
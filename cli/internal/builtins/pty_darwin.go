@@ -0,0 +1,49 @@
+//go:build darwin
+
+package decorators
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests that read and write terminal attributes
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
+
+// openPTY allocates a pseudo-terminal through /dev/ptmx and returns its master and slave ends
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := ioctl(master.Fd(), syscall.TIOCPTYGRANT, nil); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("granting pseudo-terminal: %w", err)
+	}
+	if err := ioctl(master.Fd(), syscall.TIOCPTYUNLK, nil); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlocking pseudo-terminal: %w", err)
+	}
+	name := make([]byte, 128)
+	if err := ioctl(master.Fd(), syscall.TIOCPTYGNAME, unsafe.Pointer(&name[0])); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("reading pseudo-terminal name: %w", err)
+	}
+	if end := bytes.IndexByte(name, 0); end >= 0 {
+		name = name[:end]
+	}
+
+	slave, err := os.OpenFile(string(name), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests that read and write terminal attributes
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
+
+// openPTY allocates a pseudo-terminal through /dev/ptmx and returns its master and slave ends
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlocking pseudo-terminal: %w", err)
+	}
+	var number uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("reading pseudo-terminal number: %w", err)
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
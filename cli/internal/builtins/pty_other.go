@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package decorators
+
+import "os/exec"
+
+// ptySupported reports whether @pty can allocate pseudo-terminals on this platform
+const ptySupported = false
+
+// runInPTY runs cmd without a pseudo-terminal; @pty warns before falling back to it
+func runInPTY(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
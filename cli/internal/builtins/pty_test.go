@@ -0,0 +1,93 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestPtyDecorator_Basic(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &PtyDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{}, []ast.CommandContent{
+			decoratortesting.Shell("echo 'in a terminal'"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("ctx.Run = func(cmd *execpkg.Cmd) error", "cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("PtyDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestPtyDecorator_RejectsParameters(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &PtyDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("tty", "true")}, []ast.CommandContent{
+			decoratortesting.Shell("echo hello"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("@pty requires exactly 0 parameter(s)").
+		GeneratorFails("@pty requires exactly 0 parameter(s)").
+		PlanFails("@pty requires exactly 0 parameter(s)").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("PtyDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestPtyDecorator_RunsUnderTerminal(t *testing.T) {
+	if !ptySupported {
+		t.Skip("pseudo-terminals are not supported on this platform")
+	}
+
+	var out bytes.Buffer
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).WithOutput(&out, &out)
+	result := (&PtyDecorator{}).ExecuteInterpreter(ctx, nil, []ast.CommandContent{
+		decoratortesting.Shell("test -t 0 && test -t 1 && test -t 2 && echo 'is a tty'"),
+		decoratortesting.Shell("echo 'to stderr' >&2"),
+		decoratortesting.Shell("exit 3"),
+	})
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "exit status 3") {
+		t.Errorf("expected the exit status to be preserved, got %v", result.Error)
+	}
+
+	for _, want := range []string{"is a tty", "to stderr"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q: %q", want, out.String())
+		}
+	}
+}
+
+func TestTerminalSize_FallsBackToEnvironment(t *testing.T) {
+	if !ptySupported {
+		t.Skip("pseudo-terminals are not supported on this platform")
+	}
+	if isTerminal(0) || isTerminal(1) || isTerminal(2) {
+		t.Skip("size comes from the attached terminal")
+	}
+
+	t.Setenv("LINES", "30")
+	t.Setenv("COLUMNS", "100")
+	if size := terminalSize(); size.Rows != 30 || size.Cols != 100 {
+		t.Errorf("terminalSize() = %dx%d, want 30x100", size.Rows, size.Cols)
+	}
+
+	t.Setenv("LINES", "")
+	t.Setenv("COLUMNS", "wide")
+	if size := terminalSize(); size.Rows != 24 || size.Cols != 80 {
+		t.Errorf("terminalSize() = %dx%d, want 24x80", size.Rows, size.Cols)
+	}
+}
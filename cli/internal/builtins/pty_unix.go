@@ -0,0 +1,185 @@
+//go:build linux || darwin
+
+package decorators
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ptySupported reports whether @pty can allocate pseudo-terminals on this platform
+const ptySupported = true
+
+// windowSize mirrors struct winsize for TIOCGWINSZ and TIOCSWINSZ
+type windowSize struct {
+	Rows, Cols, XPixel, YPixel uint16
+}
+
+// runInPTY runs cmd with a pseudo-terminal as its stdin, stdout and stderr. The terminal's
+// output is copied to the writer cmd.Stdout was set to, so it can still be captured, and it
+// follows devcmd's terminal size. When devcmd's stdin is a terminal it is switched to raw
+// mode and forwarded key by key until cmd exits.
+func runInPTY(cmd *exec.Cmd) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("failed to allocate a pseudo-terminal: %w", err)
+	}
+	defer master.Close()
+
+	// Without a terminal to display it, output is passed on as written, without CRLF translation
+	if !isTerminal(syscall.Stdout) {
+		var attrs syscall.Termios
+		if ioctl(slave.Fd(), ioctlGetTermios, unsafe.Pointer(&attrs)) == nil {
+			attrs.Oflag &^= syscall.OPOST
+			_ = ioctl(slave.Fd(), ioctlSetTermios, unsafe.Pointer(&attrs))
+		}
+	}
+
+	resize := func() {
+		size := terminalSize()
+		_ = ioctl(master.Fd(), syscall.TIOCSWINSZ, unsafe.Pointer(&size))
+	}
+	resize()
+
+	output := cmd.Stdout
+	if output == nil {
+		output = io.Discard
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	// The child leads a new session with the terminal (its stdin) as controlling terminal
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	err = cmd.Start()
+	slave.Close()
+	if err != nil {
+		return err
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-winch:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer func() {
+		signal.Stop(winch)
+		close(done)
+	}()
+
+	if isTerminal(syscall.Stdin) {
+		if restore, err := makeRaw(syscall.Stdin); err == nil {
+			defer restore()
+		}
+		defer forwardStdin(master)()
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		// Reads fail once every process has closed the slave end
+		_, _ = io.Copy(output, master)
+		close(copied)
+	}()
+	err = cmd.Wait()
+	<-copied
+	return err
+}
+
+// ioctl performs an ioctl request on fd
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports whether fd refers to a terminal
+func isTerminal(fd int) bool {
+	var attrs syscall.Termios
+	return ioctl(uintptr(fd), ioctlGetTermios, unsafe.Pointer(&attrs)) == nil
+}
+
+// terminalSize returns the size of the terminal on devcmd's standard streams, falling back to
+// $LINES and $COLUMNS and then 24x80 when none of them is a terminal
+func terminalSize() windowSize {
+	for _, fd := range []int{syscall.Stdin, syscall.Stdout, syscall.Stderr} {
+		var size windowSize
+		if ioctl(uintptr(fd), syscall.TIOCGWINSZ, unsafe.Pointer(&size)) == nil && size.Rows > 0 && size.Cols > 0 {
+			return size
+		}
+	}
+
+	size := windowSize{Rows: 24, Cols: 80}
+	if rows, err := strconv.ParseUint(os.Getenv("LINES"), 10, 16); err == nil && rows > 0 {
+		size.Rows = uint16(rows)
+	}
+	if cols, err := strconv.ParseUint(os.Getenv("COLUMNS"), 10, 16); err == nil && cols > 0 {
+		size.Cols = uint16(cols)
+	}
+	return size
+}
+
+// makeRaw switches the terminal on fd to raw mode and returns a function that restores it
+func makeRaw(fd int) (func(), error) {
+	var original syscall.Termios
+	if err := ioctl(uintptr(fd), ioctlGetTermios, unsafe.Pointer(&original)); err != nil {
+		return nil, err
+	}
+
+	raw := original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(uintptr(fd), ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = ioctl(uintptr(fd), ioctlSetTermios, unsafe.Pointer(&original))
+	}, nil
+}
+
+// forwardStdin copies devcmd's stdin to dst until the returned stop function is called. It
+// reads through a non-blocking duplicate so the pending read can be cancelled, leaving no
+// goroutine behind to swallow input meant for later steps.
+func forwardStdin(dst io.Writer) (stop func()) {
+	fd, err := syscall.Dup(syscall.Stdin)
+	if err != nil {
+		return func() {}
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return func() {}
+	}
+	stdin := os.NewFile(uintptr(fd), "/dev/stdin")
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(dst, stdin)
+		close(done)
+	}()
+
+	return func() {
+		_ = stdin.SetReadDeadline(time.Now())
+		<-done
+		stdin.Close()
+		// The duplicate shares the open file description, so stdin was made non-blocking too
+		_ = syscall.SetNonblock(syscall.Stdin, false)
+	}
+}
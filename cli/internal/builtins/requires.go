@@ -0,0 +1,534 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// containerImageEnvPrefix prefixes the variables that map missing tools to container images.
+// The `containers` section of devcmd.settings provides defaults for them.
+const containerImageEnvPrefix = "DEVCMD_IMAGE_"
+
+// toolNamePattern matches executable names accepted by @requires
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// ContainerImageEnvVar returns the environment variable naming the container image
+// @requires falls back to when tool is missing, e.g. DEVCMD_IMAGE_TERRAFORM
+func ContainerImageEnvVar(tool string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, tool)
+	return containerImageEnvPrefix + strings.ToUpper(name)
+}
+
+// requiredTool is a tool checked by @requires and the variable mapping it to an image
+type requiredTool struct {
+	Name     string
+	ImageVar string
+}
+
+// requiresToolsTemplate checks the tools and sets missing, image and runtimeName for running the
+// block in a container. It mirrors RequiresDecorator.checkTools.
+const requiresToolsTemplate = `	lookupEnv := func(name string) string {
+		if value, ok := ctx.Env[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+	var missing, missingVars []string
+	for _, tool := range [][2]string{ {{range .Tools}}{ {{printf "%q" .Name}}, {{printf "%q" .ImageVar}} }, {{end}} } {
+		found := false
+		for _, dir := range filepath.SplitList(lookupEnv("PATH")) {
+			if info, err := os.Stat(filepath.Join(dir, tool[0])); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, tool[0])
+			missingVars = append(missingVars, tool[1])
+		}
+	}
+	if fallback := {{.Fallback}}; len(missing) > 0 && !fallback {
+		return fmt.Errorf("@requires: %s not found in PATH", strings.Join(missing, ", "))
+	}
+	image := ""
+	if len(missing) > 0 {
+		image = lookupEnv(missingVars[0])
+		for i, name := range missingVars {
+			if lookupEnv(name) == "" {
+				return fmt.Errorf("@requires: %s not found in PATH (install it or map an image in devcmd.settings: containers { %s = \"image\" })", missing[i], missing[i])
+			}
+			if lookupEnv(name) != image {
+				return fmt.Errorf("@requires: %s map to different container images; install them or map them to a single image", strings.Join(missing, ", "))
+			}
+		}
+	}
+` + containerRuntimeTemplate + `	if len(missing) > 0 && runtimeName == "" {
+		return fmt.Errorf("@requires: %s not found in PATH and no container runtime ({{.RuntimeList}}) is available to run %s", strings.Join(missing, ", "), image)
+	}
+`
+
+// requiresDiskTemplate is the body of a func() error checking free disk space. It mirrors
+// RequiresDecorator.checkDiskFree and diskFree.
+const requiresDiskTemplate = `		path := {{printf "%q" .Path}}
+		if !filepath.IsAbs(path) {
+			dir := ctx.Dir
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			path = filepath.Join(dir, path)
+		}
+		for {
+			if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+				break
+			}
+			path = filepath.Dir(path)
+		}
+		out, err := execpkg.Command("df", "-Pk", path).Output()
+		if err != nil {
+			return fmt.Errorf("@requires: cannot check free disk space at %s: df: %w", {{printf "%q" .Path}}, err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		fields := strings.Fields(lines[len(lines)-1])
+		free := int64(-1)
+		for i := 4; i < len(fields); i++ {
+			if strings.HasSuffix(fields[i], "%") {
+				if kb, err := strconv.ParseInt(fields[i-1], 10, 64); err == nil {
+					free = kb * 1024
+				}
+				break
+			}
+		}
+		if free < 0 {
+			return fmt.Errorf("@requires: cannot check free disk space at %s: unexpected df output %q", {{printf "%q" .Path}}, out)
+		}
+		if free < {{.DiskFree}} {
+			return fmt.Errorf("@requires: %s has %d MiB free, need %s", {{printf "%q" .Path}}, free>>20, {{printf "%q" .DiskFreeSize}})
+		}
+		return nil
+`
+
+// requiresTemplate checks the tools and free disk space and, when tools are missing and mapped
+// to an image, runs the block in a container. It mirrors RequiresDecorator.ExecuteInterpreter.
+const requiresTemplate = `// Requires {{.Label}}
+{
+	ctx := ctx.Clone()
+{{if .Tools}}` + requiresToolsTemplate + `{{end}}{{if .DiskFree}}	if err := func() error {
+` + requiresDiskTemplate + `	}(); err != nil {
+		return err
+	}
+{{end}}{{if .Tools}}	if len(missing) > 0 {
+		logf("warn", "@requires", "%s not found, running in %s", strings.Join(missing, ", "), image)
+` + containerShellTemplate + `	}
+{{end}}{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// requiresPreflightTemplate records each unmet requirement in failures. It mirrors
+// RequiresDecorator.PreflightInterpreter.
+const requiresPreflightTemplate = `// Pre-flight: requires {{.Label}}
+{{if .Tools}}if err := func() error {
+` + requiresToolsTemplate + `	return nil
+}(); err != nil {
+	failures = append(failures, err.Error())
+}
+{{end}}{{if .DiskFree}}if err := func() error {
+` + requiresDiskTemplate + `}(); err != nil {
+	failures = append(failures, err.Error())
+}
+{{end}}`
+
+// requirements holds what @requires checks before running its block
+type requirements struct {
+	Tools        []requiredTool
+	Fallback     bool
+	DiskFree     int64  // Bytes that must be free on the filesystem holding Path, 0 for no check
+	DiskFreeSize string // DiskFree as written, for messages
+	Path         string
+}
+
+// RequiresDecorator implements the @requires decorator for checking that tools are installed,
+// optionally running the block in a container image when they are not, and that enough disk
+// space is free
+type RequiresDecorator struct{}
+
+// Name returns the decorator name
+func (r *RequiresDecorator) Name() string {
+	return "requires"
+}
+
+// Description returns a human-readable description
+func (r *RequiresDecorator) Description() string {
+	return "Check that tools are installed and disk space is free before running the block, falling back to a mapped container image for missing tools"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (r *RequiresDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "tools",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Comma-separated executables the block needs, e.g. \"terraform, tflint\"",
+		},
+		{
+			Name:        "fallback",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Run the block in the image mapped to missing tools in the `containers` settings (default: true)",
+		},
+		{
+			Name:        "diskFree",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Free disk space the block needs, e.g. \"5G\"",
+		},
+		{
+			Name:        "path",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Path whose filesystem must have diskFree available (default: the working directory)",
+		},
+	}
+}
+
+// ExecuteInterpreter checks the requirements and runs the block in interpreter mode
+func (r *RequiresDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	reqs, err := r.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	missing, image, runtimeName, err := r.checkTools(ctx, reqs)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	if err := r.checkDiskFree(ctx, reqs); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	if len(missing) == 0 {
+		commandExecutor := decorators.NewCommandExecutor()
+		defer commandExecutor.Cleanup()
+
+		return &execution.ExecutionResult{
+			Data:  nil,
+			Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content),
+		}
+	}
+
+	ctx.Logger().Warnf("@requires", "%s not found, running in %s", toolNames(missing), image)
+	return executeInContainer(ctx, runtimeName, image, content)
+}
+
+// GenerateTemplate generates template for checking the requirements before running the block
+func (r *RequiresDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	reqs, err := r.extractParameters(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("requires").Funcs(ctx.GetTemplateFunctions()).Parse(requiresTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse requires template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data:     r.templateData(reqs, content),
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (r *RequiresDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	reqs, err := r.extractParameters(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	description := fmt.Sprintf("Require %s", requirementsLabel(reqs))
+	if len(reqs.Tools) > 0 && reqs.Fallback {
+		description += " (container fallback if missing)"
+	}
+
+	element := plan.Decorator(r.Name()).
+		WithType("block").
+		WithDescription(description)
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// PreflightInterpreter returns each unmet requirement without running the block
+func (r *RequiresDecorator) PreflightInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) []error {
+	reqs, err := r.extractParameters(ctx, params)
+	if err != nil {
+		return []error{err}
+	}
+
+	var failures []error
+	if _, _, _, err := r.checkTools(ctx, reqs); err != nil {
+		failures = append(failures, fmt.Errorf("@%s: %w", r.Name(), err))
+	}
+	if err := r.checkDiskFree(ctx, reqs); err != nil {
+		failures = append(failures, fmt.Errorf("@%s: %w", r.Name(), err))
+	}
+	return failures
+}
+
+// PreflightTemplate generates template for recording each unmet requirement before any step runs
+func (r *RequiresDecorator) PreflightTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	reqs, err := r.extractParameters(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("requires-preflight").Funcs(ctx.GetTemplateFunctions()).Parse(requiresPreflightTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse requires pre-flight template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data:     r.templateData(reqs, nil),
+	}, nil
+}
+
+// templateData returns the data for requiresTemplate and requiresPreflightTemplate
+func (r *RequiresDecorator) templateData(reqs requirements, content []ast.CommandContent) interface{} {
+	return struct {
+		requirements
+		Label       string
+		Runtimes    []string
+		RuntimeList string
+		Content     []ast.CommandContent
+	}{
+		requirements: reqs,
+		Label:        requirementsLabel(reqs),
+		Runtimes:     containerRuntimes,
+		RuntimeList:  strings.Join(containerRuntimes, " or "),
+		Content:      content,
+	}
+}
+
+// checkTools finds missing tools and, when they can run in a container instead, the image and
+// container runtime to use
+func (r *RequiresDecorator) checkTools(ctx execution.InterpreterContext, reqs requirements) ([]requiredTool, string, string, error) {
+	path, _ := ctx.GetEnv("PATH")
+	var missing []requiredTool
+	for _, tool := range reqs.Tools {
+		if !findInPath(path, tool.Name) {
+			missing = append(missing, tool)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, "", "", nil
+	}
+
+	names := toolNames(missing)
+	if !reqs.Fallback {
+		return nil, "", "", fmt.Errorf("%s not found in PATH", names)
+	}
+
+	image, err := fallbackImage(ctx, missing)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	runtimeName, err := findContainerRuntime()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%s not found in PATH and no container runtime (%s) is available to run %s", names, strings.Join(containerRuntimes, " or "), image)
+	}
+	return missing, image, runtimeName, nil
+}
+
+// checkDiskFree checks that the filesystem holding the requested path has enough free space
+func (r *RequiresDecorator) checkDiskFree(ctx execution.InterpreterContext, reqs requirements) error {
+	if reqs.DiskFree == 0 {
+		return nil
+	}
+
+	path := reqs.Path
+	if !filepath.IsAbs(path) {
+		dir := ctx.GetWorkingDir()
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		path = filepath.Join(dir, path)
+	}
+
+	free, err := diskFree(path)
+	if err != nil {
+		return fmt.Errorf("cannot check free disk space at %s: %w", reqs.Path, err)
+	}
+	if free < reqs.DiskFree {
+		return fmt.Errorf("%s has %d MiB free, need %s", reqs.Path, free>>20, reqs.DiskFreeSize)
+	}
+	return nil
+}
+
+// diskFree returns the bytes available to unprivileged users on the filesystem holding path,
+// as reported by POSIX `df -Pk`
+func diskFree(path string) (int64, error) {
+	// The path may not exist yet, e.g. a build output directory; check the filesystem it will be on
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	out, err := exec.Command("df", "-Pk", path).Output()
+	if err != nil {
+		return 0, fmt.Errorf("df: %w", err)
+	}
+
+	// Columns are filesystem, 1024-blocks, used, available, capacity and mount point; the
+	// filesystem name may contain spaces, so find available from the capacity percentage
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	for i := 4; i < len(fields); i++ {
+		if strings.HasSuffix(fields[i], "%") {
+			if kb, err := strconv.ParseInt(fields[i-1], 10, 64); err == nil {
+				return kb * 1024, nil
+			}
+			break
+		}
+	}
+	return 0, fmt.Errorf("unexpected df output %q", out)
+}
+
+// fallbackImage returns the single image that all missing tools are mapped to
+func fallbackImage(ctx execution.InterpreterContext, missing []requiredTool) (string, error) {
+	var image string
+	for _, tool := range missing {
+		mapped, _ := ctx.GetEnv(tool.ImageVar)
+		if mapped == "" {
+			return "", fmt.Errorf("%s not found in PATH (install it or map an image in devcmd.settings: containers { %s = \"image\" })", tool.Name, tool.Name)
+		}
+		if image != "" && mapped != image {
+			return "", fmt.Errorf("%s map to different container images; install them or map them to a single image", toolNames(missing))
+		}
+		image = mapped
+	}
+	return image, nil
+}
+
+// findInPath reports whether an executable named tool exists in one of the PATH directories
+func findInPath(path, tool string) bool {
+	for _, dir := range filepath.SplitList(path) {
+		if info, err := os.Stat(filepath.Join(dir, tool)); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// toolNames joins tool names for messages
+func toolNames(tools []requiredTool) string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// requirementsLabel describes the requirements for plans and generated code
+func requirementsLabel(reqs requirements) string {
+	var parts []string
+	if len(reqs.Tools) > 0 {
+		parts = append(parts, toolNames(reqs.Tools))
+	}
+	if reqs.DiskFree > 0 {
+		disk := reqs.DiskFreeSize + " of free disk space"
+		if reqs.Path != "." {
+			disk += " in " + reqs.Path
+		}
+		parts = append(parts, disk)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// extractParameters validates parameters and returns the requirements, with @var references in
+// path resolved
+func (r *RequiresDecorator) extractParameters(ctx execution.BaseContext, params []ast.NamedParameter) (requirements, error) {
+	var reqs requirements
+	if err := decorators.ValidateSchemaCompliance(params, r.ParameterSchema(), r.Name()); err != nil {
+		return reqs, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, r.ParameterSchema())
+	if err != nil {
+		return reqs, fmt.Errorf("@%s: %w", r.Name(), err)
+	}
+
+	if ast.FindParameter(params, "tools") != nil {
+		for _, name := range strings.FieldsFunc(ast.GetStringParam(params, "tools", ""), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			if !toolNamePattern.MatchString(name) {
+				return reqs, fmt.Errorf("@%s: invalid tool name %q", r.Name(), name)
+			}
+			reqs.Tools = append(reqs.Tools, requiredTool{Name: name, ImageVar: ContainerImageEnvVar(name)})
+		}
+		if len(reqs.Tools) == 0 {
+			return reqs, fmt.Errorf("@%s: tools must list at least one executable", r.Name())
+		}
+	}
+	reqs.Fallback = ast.GetBoolParam(params, "fallback", true)
+
+	if ast.FindParameter(params, "diskFree") != nil {
+		size := ast.GetStringParam(params, "diskFree", "")
+		free, err := parseByteSize(size)
+		if err != nil {
+			return reqs, fmt.Errorf("@%s: diskFree: %w", r.Name(), err)
+		}
+		reqs.DiskFree, reqs.DiskFreeSize = free, size
+	}
+
+	reqs.Path, err = resolveVariableReferences(ctx, ast.GetStringParam(params, "path", "."))
+	if err != nil {
+		return reqs, fmt.Errorf("@%s: %w", r.Name(), err)
+	}
+	if ast.FindParameter(params, "path") != nil && reqs.DiskFree == 0 {
+		return reqs, fmt.Errorf("@%s: path is only used with diskFree", r.Name())
+	}
+	if reqs.Path == "" || strings.ContainsAny(reqs.Path, "\r\n") {
+		return reqs, fmt.Errorf("@%s: invalid path %q", r.Name(), reqs.Path)
+	}
+
+	if len(reqs.Tools) == 0 && reqs.DiskFree == 0 {
+		return reqs, fmt.Errorf("@%s requires tools or diskFree", r.Name())
+	}
+	return reqs, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (r *RequiresDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"os/exec", "path/filepath", "sort", "strconv"})
+}
+
+// init registers the requires decorator
+func init() {
+	decorators.RegisterBlock(&RequiresDecorator{})
+}
@@ -0,0 +1,268 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestRequiresDecorator_ToolsInstalled(t *testing.T) {
+	isolateToolchainPath(t)
+	installFakeCLI(t, "terraform", `echo "Terraform v1.9.0"`)
+
+	out := filepath.Join(t.TempDir(), "version.txt")
+	result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Value: &ast.StringLiteral{Value: "terraform"}},
+		}, []ast.CommandContent{
+			decoratortesting.Shell("terraform > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`{ "terraform", "DEVCMD_IMAGE_TERRAFORM" }`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequiresDecorator installed tools test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "Terraform v1.9.0" {
+		t.Errorf("block output = %q, want the installed terraform", got)
+	}
+}
+
+func TestRequiresDecorator_ContainerFallback(t *testing.T) {
+	isolateToolchainPath(t)
+	args := installFakeDocker(t)
+	t.Setenv("DEVCMD_IMAGE_TERRAFORM", "hashicorp/terraform:1.9")
+	t.Setenv("DEVCMD_IMAGE_TFLINT", "hashicorp/terraform:1.9")
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("tools", "terraform, tflint"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo $IN_CONTAINER > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequiresDecorator container fallback test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "1" {
+		t.Errorf("block ran outside the container: %q", got)
+	}
+	recorded, _ := os.ReadFile(args)
+	if !strings.Contains(string(recorded), "--entrypoint sh hashicorp/terraform:1.9 -c") {
+		t.Errorf("unexpected docker arguments: %s", recorded)
+	}
+}
+
+func TestRequiresDecorator_MissingTools(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		images map[string]string
+		errMsg string
+	}{
+		{"no image mapped", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "terraform"),
+		}, nil, `terraform not found in PATH (install it or map an image in devcmd.settings: containers { terraform = "image" })`},
+		{"fallback disabled", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "terraform"),
+			decoratortesting.BoolParam("fallback", false),
+		}, map[string]string{"DEVCMD_IMAGE_TERRAFORM": "hashicorp/terraform:1.9"}, "terraform not found in PATH"},
+		{"different images", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "terraform kubectl"),
+		}, map[string]string{
+			"DEVCMD_IMAGE_TERRAFORM": "hashicorp/terraform:1.9",
+			"DEVCMD_IMAGE_KUBECTL":   "bitnami/kubectl:1.31",
+		}, "terraform, kubectl map to different container images"},
+		{"no container runtime", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "terraform"),
+		}, map[string]string{"DEVCMD_IMAGE_TERRAFORM": "hashicorp/terraform:1.9"}, "no container runtime (docker or podman) is available"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			isolateToolchainPath(t)
+			for name, image := range tc.images {
+				t.Setenv(name, image)
+			}
+
+			result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+				TestBlockDecorator(tc.params, []ast.CommandContent{
+					decoratortesting.Shell("echo should not run"),
+				})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.errMsg).
+				GeneratorSucceeds().
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("RequiresDecorator missing tools test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+// installFakeDf installs a df reporting availableKB free on a filesystem whose name contains spaces
+func installFakeDf(t *testing.T, availableKB string) {
+	t.Helper()
+	installFakeCLI(t, "df", `[ "$1" = "-Pk" ] || exit 2
+echo "Filesystem     1024-blocks    Used Available Capacity Mounted on"
+echo "map auto_home     10485760 2097152 `+availableKB+`      17% /"`)
+}
+
+func TestRequiresDecorator_DiskFree(t *testing.T) {
+	installFakeDf(t, "8388608") // 8G
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("diskFree", "5G"),
+			decoratortesting.StringParam("path", "./build/out"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo built > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`if free < 5368709120 {`, `// Requires 5G of free disk space in ./build/out`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequiresDecorator disk space test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if got, err := os.ReadFile(out); err != nil || strings.TrimSpace(string(got)) != "built" {
+		t.Errorf("block output = %q (%v), want it to run", got, err)
+	}
+}
+
+func TestRequiresDecorator_NotEnoughDiskFree(t *testing.T) {
+	installFakeDf(t, "1048576") // 1G
+
+	result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("diskFree", "5G"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo should not run"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails(". has 1024 MiB free, need 5G").
+		GeneratorSucceeds().
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RequiresDecorator low disk space test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestRequiresDecorator_PreflightReportsEveryFailure(t *testing.T) {
+	isolateToolchainPath(t)
+	installFakeDf(t, "1024")
+
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	failures := (&RequiresDecorator{}).PreflightInterpreter(ctx, []ast.NamedParameter{
+		decoratortesting.StringParam("tools", "terraform"),
+		decoratortesting.BoolParam("fallback", false),
+		decoratortesting.StringParam("diskFree", "1G"),
+	})
+
+	want := []string{"@requires: terraform not found in PATH", "@requires: . has 1 MiB free, need 1G"}
+	if len(failures) != len(want) {
+		t.Fatalf("PreflightInterpreter() = %v, want %d failures", failures, len(want))
+	}
+	for i, err := range failures {
+		if err.Error() != want[i] {
+			t.Errorf("failure %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestRequiresDecorator_InvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		errMsg string
+	}{
+		{"missing tools", []ast.NamedParameter{}, "tools"},
+		{"empty tools", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", " , "),
+		}, "tools must list at least one executable"},
+		{"path instead of name", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "./bin/terraform"),
+		}, `invalid tool name "./bin/terraform"`},
+		{"bad disk size", []ast.NamedParameter{
+			decoratortesting.StringParam("diskFree", "plenty"),
+		}, `diskFree: invalid size "plenty"`},
+		{"path without diskFree", []ast.NamedParameter{
+			decoratortesting.StringParam("tools", "go"),
+			decoratortesting.StringParam("path", "./build"),
+		}, "path is only used with diskFree"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &RequiresDecorator{}).
+				TestBlockDecorator(tc.params, []ast.CommandContent{})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.errMsg).
+				GeneratorFails(tc.errMsg).
+				PlanFails(tc.errMsg).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("RequiresDecorator invalid parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestContainerImageEnvVar(t *testing.T) {
+	testCases := map[string]string{
+		"terraform":      "DEVCMD_IMAGE_TERRAFORM",
+		"docker-compose": "DEVCMD_IMAGE_DOCKER_COMPOSE",
+		"g++":            "DEVCMD_IMAGE_G__",
+	}
+
+	for tool, want := range testCases {
+		if got := ContainerImageEnvVar(tool); got != want {
+			t.Errorf("ContainerImageEnvVar(%q) = %q, want %q", tool, got, want)
+		}
+	}
+}
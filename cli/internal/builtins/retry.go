@@ -2,6 +2,7 @@ package decorators
 
 import (
 	"fmt"
+	"os"
 	"text/template"
 	"time"
 
@@ -11,6 +12,16 @@ import (
 	"github.com/aledsdavies/devcmd/runtime/execution"
 )
 
+// Backoffs @retry accepts: the same delay before every retry, or doubling it each time
+const (
+	constantBackoff    = "constant"
+	exponentialBackoff = "exponential"
+)
+
+// retryLogEnvVar names the file each @retry block appends its attempts to, as
+// "<attempts> <failed attempts>". devcmd run sets it to report flaky steps.
+const retryLogEnvVar = "DEVCMD_RETRY_LOG"
+
 // RetryDecorator implements the @retry decorator for retrying failed command execution
 type RetryDecorator struct{}
 
@@ -21,7 +32,7 @@ func (r *RetryDecorator) Name() string {
 
 // Description returns a human-readable description
 func (r *RetryDecorator) Description() string {
-	return "Retry command execution on failure with configurable attempts and delay"
+	return "Retry command execution on failure with configurable attempts, delay, backoff and jitter"
 }
 
 // ParameterSchema returns the expected parameters for this decorator
@@ -37,7 +48,25 @@ func (r *RetryDecorator) ParameterSchema() []decorators.ParameterSchema {
 			Name:        "delay",
 			Type:        ast.DurationType,
 			Required:    false,
-			Description: "Delay between retry attempts (default: 1s)",
+			Description: "Delay between retry attempts, or before the first retry with exponential backoff (default: 1s)",
+		},
+		{
+			Name:        "backoff",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "constant, or exponential to double the delay before each further retry (default: constant)",
+		},
+		{
+			Name:        "maxDelay",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "Longest delay exponential backoff grows to (default: 1h)",
+		},
+		{
+			Name:        "jitter",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Wait a random duration from half of each delay to all of it, so retries of parallel runs spread out (default: false)",
 		},
 	}
 }
@@ -46,7 +75,7 @@ func (r *RetryDecorator) ParameterSchema() []decorators.ParameterSchema {
 
 // ExecuteInterpreter executes retry logic in interpreter mode
 func (r *RetryDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
-	maxAttempts, delay, err := r.extractRetryParams(params)
+	maxAttempts, policy, err := r.extractRetryParams(params)
 	if err != nil {
 		return &execution.ExecutionResult{
 			Data:  nil,
@@ -54,22 +83,22 @@ func (r *RetryDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, pa
 		}
 	}
 
-	return r.executeInterpreterImpl(ctx, maxAttempts, delay, content)
+	return r.executeInterpreterImpl(ctx, maxAttempts, policy, content)
 }
 
 // GenerateTemplate generates template for retry logic
 func (r *RetryDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
-	maxAttempts, delay, err := r.extractRetryParams(params)
+	maxAttempts, policy, err := r.extractRetryParams(params)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.generateTemplateImpl(ctx, maxAttempts, delay, content)
+	return r.generateTemplateImpl(ctx, maxAttempts, policy, content)
 }
 
 // ExecutePlan creates a plan element for dry-run mode
 func (r *RetryDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
-	maxAttempts, delay, err := r.extractRetryParams(params)
+	maxAttempts, policy, err := r.extractRetryParams(params)
 	if err != nil {
 		return &execution.ExecutionResult{
 			Data:  nil,
@@ -77,56 +106,81 @@ func (r *RetryDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.Nam
 		}
 	}
 
-	return r.executePlanImpl(ctx, maxAttempts, delay, content)
+	return r.executePlanImpl(ctx, maxAttempts, policy, content)
 }
 
 // extractRetryParams extracts and validates retry parameters
-func (r *RetryDecorator) extractRetryParams(params []ast.NamedParameter) (int, time.Duration, error) {
+func (r *RetryDecorator) extractRetryParams(params []ast.NamedParameter) (int, decorators.RetryPolicy, error) {
+	var policy decorators.RetryPolicy
+
 	// Use centralized validation
-	if err := decorators.ValidateParameterCount(params, 1, 2, "retry"); err != nil {
-		return 0, 0, err
+	if err := decorators.ValidateParameterCount(params, 1, 5, "retry"); err != nil {
+		return 0, policy, err
 	}
 
 	// Validate parameter schema compliance
 	if err := decorators.ValidateSchemaCompliance(params, r.ParameterSchema(), "retry"); err != nil {
-		return 0, 0, err
+		return 0, policy, err
 	}
 
 	// Validate attempts parameter is positive
 	if err := decorators.ValidatePositiveInteger(params, "attempts", "retry"); err != nil {
-		return 0, 0, err
+		return 0, policy, err
 	}
 
 	// Enhanced security validation for attempts to prevent resource exhaustion
 	if err := decorators.ValidateResourceLimits(params, "attempts", 100, "retry"); err != nil {
-		return 0, 0, err
+		return 0, policy, err
 	}
 
-	// Validate delay parameter if present (1ms to 1 hour range)
-	if err := decorators.ValidateDuration(params, "delay", 1*time.Millisecond, 1*time.Hour, "retry"); err != nil {
-		return 0, 0, err
-	}
+	// Validate delay and maxDelay parameters if present (1ms to 1 hour range)
+	for _, name := range []string{"delay", "maxDelay"} {
+		if err := decorators.ValidateDuration(params, name, 1*time.Millisecond, 1*time.Hour, "retry"); err != nil {
+			return 0, policy, err
+		}
 
-	// Enhanced security validation for timeout safety
-	if err := decorators.ValidateTimeoutSafety(params, "delay", 1*time.Hour, "retry"); err != nil {
-		return 0, 0, err
+		// Enhanced security validation for timeout safety
+		if err := decorators.ValidateTimeoutSafety(params, name, 1*time.Hour, "retry"); err != nil {
+			return 0, policy, err
+		}
 	}
 
 	// Parse parameters (validation passed, so these should be safe)
 	maxAttempts := ast.GetIntParam(params, "attempts", 3)
-	delay := ast.GetDurationParam(params, "delay", 1*time.Second)
+	policy.Delay = ast.GetDurationParam(params, "delay", 1*time.Second)
+	policy.MaxDelay = ast.GetDurationParam(params, "maxDelay", 1*time.Hour)
+	policy.Jitter = ast.GetBoolParam(params, "jitter", false)
+
+	switch backoff := ast.GetStringParam(params, "backoff", constantBackoff); backoff {
+	case constantBackoff:
+		if ast.FindParameter(params, "maxDelay") != nil {
+			return 0, policy, fmt.Errorf("@retry maxDelay needs backoff = %q", exponentialBackoff)
+		}
+	case exponentialBackoff:
+		policy.Exponential = true
+		if policy.MaxDelay < policy.Delay {
+			return 0, policy, fmt.Errorf("@retry maxDelay %s is shorter than delay %s", policy.MaxDelay, policy.Delay)
+		}
+	default:
+		return 0, policy, fmt.Errorf("@retry backoff must be %q or %q, got %q", constantBackoff, exponentialBackoff, backoff)
+	}
 
-	return maxAttempts, delay, nil
+	return maxAttempts, policy, nil
 }
 
 // executeInterpreterImpl executes retry logic in interpreter mode using utilities
-func (r *RetryDecorator) executeInterpreterImpl(ctx execution.InterpreterContext, maxAttempts int, delay time.Duration, content []ast.CommandContent) *execution.ExecutionResult {
-	// Create RetryExecutor with specified attempts and delay
-	retryExecutor := decorators.NewRetryExecutor(maxAttempts, delay)
+func (r *RetryDecorator) executeInterpreterImpl(ctx execution.InterpreterContext, maxAttempts int, policy decorators.RetryPolicy, content []ast.CommandContent) *execution.ExecutionResult {
+	// Create RetryExecutor with specified attempts and delays
+	retryExecutor := decorators.NewRetryExecutorWithPolicy(maxAttempts, policy)
 	defer retryExecutor.Cleanup()
 
-	// Execute all commands within the retry logic using the utility
+	logger := ctx.Logger()
+
+	// Execute all commands within the retry logic using the utility, counting the attempts
+	attempts, failed := 0, 0
 	err := retryExecutor.Execute(func() error {
+		attempts++
+
 		// Execute commands sequentially with isolated context
 		childCtx := ctx.Child()
 
@@ -134,8 +188,14 @@ func (r *RetryDecorator) executeInterpreterImpl(ctx execution.InterpreterContext
 		commandExecutor := decorators.NewCommandExecutor()
 		defer commandExecutor.Cleanup()
 
-		return commandExecutor.ExecuteCommandsWithInterpreter(childCtx, content)
+		err := commandExecutor.ExecuteCommandsWithInterpreter(childCtx, content)
+		if err != nil {
+			failed++
+			logger.Warnf("@retry", "attempt %d of %d failed: %v", attempts, maxAttempts, err)
+		}
+		return err
 	})
+	recordRetryAttempts(ctx, attempts, failed)
 
 	return &execution.ExecutionResult{
 		Data:  nil,
@@ -143,21 +203,44 @@ func (r *RetryDecorator) executeInterpreterImpl(ctx execution.InterpreterContext
 	}
 }
 
+// recordRetryAttempts appends the attempts of a block to the retry log, when the run keeps one
+func recordRetryAttempts(ctx execution.InterpreterContext, attempts, failed int) {
+	path, ok := ctx.GetEnv(retryLogEnvVar)
+	if !ok || path == "" {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintf(file, "%d %d\n", attempts, failed)
+}
+
 // generateTemplateImpl generates template for retry logic
-func (r *RetryDecorator) generateTemplateImpl(ctx execution.GeneratorContext, maxAttempts int, delay time.Duration, content []ast.CommandContent) (*execution.TemplateResult, error) {
-	// Create template for retry logic
-	tmplStr := `// Retry: {{.MaxAttempts}} attempts with {{.DelayDuration}} delay
+func (r *RetryDecorator) generateTemplateImpl(ctx execution.GeneratorContext, maxAttempts int, policy decorators.RetryPolicy, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	// Create template for retry logic. With backoff or jitter the loop keeps the next delay,
+	// and jitter comes from the clock so generated CLIs need no more imports.
+	tmplStr := `{{if .Backoff}}// Retry: {{.MaxAttempts}} attempts with {{.Backoff}}
+for attempt, delay := 1, {{.Delay | formatDuration}}; attempt <= {{.MaxAttempts}}; attempt++ {
+{{else}}// Retry: {{.MaxAttempts}} attempts with {{.DelayDuration}} delay
 for attempt := 1; attempt <= {{.MaxAttempts}}; attempt++ {
-	err := func() error {
+{{end}}	err := func() error {
 {{range .Content}}		{{. | buildCommand}}
 {{end}}		return nil
 	}()
 	if err == nil {
 		break
 	}
+	logf("warn", "@retry", "attempt %d of %d failed: %v", attempt, {{.MaxAttempts}}, err)
 	if attempt < {{.MaxAttempts}} {
-		time.Sleep({{.Delay | formatDuration}})
-	} else {
+{{if .Backoff}}{{if .Policy.Jitter}}		time.Sleep(delay/2 + time.Duration(time.Now().UnixNano()%int64(delay-delay/2+1)))
+{{else}}		time.Sleep(delay)
+{{end}}{{if .Policy.Exponential}}		if delay *= 2; delay > {{.Policy.MaxDelay | formatDuration}} {
+			delay = {{.Policy.MaxDelay | formatDuration}}
+		}
+{{end}}{{else}}		time.Sleep({{.Delay | formatDuration}})
+{{end}}	} else {
 		return fmt.Errorf("command failed after %d attempts: %w", {{.MaxAttempts}}, err)
 	}
 }`
@@ -174,63 +257,76 @@ for attempt := 1; attempt <= {{.MaxAttempts}}; attempt++ {
 			MaxAttempts   int
 			DelayDuration string
 			Delay         time.Duration
+			Backoff       string // Describes a backoff or jitter, empty for a constant delay
+			Policy        decorators.RetryPolicy
 			Content       []ast.CommandContent
 		}{
 			MaxAttempts:   maxAttempts,
-			DelayDuration: delay.String(),
-			Delay:         delay,
+			DelayDuration: policy.Delay.String(),
+			Delay:         policy.Delay,
+			Backoff:       describeBackoff(policy),
+			Policy:        policy,
 			Content:       content,
 		},
 	}, nil
 }
 
+// describeBackoff describes a policy with backoff or jitter, as in "exponential backoff from
+// 1s up to 30s, jittered", or returns "" for a constant delay
+func describeBackoff(policy decorators.RetryPolicy) string {
+	var description string
+	switch {
+	case policy.Exponential:
+		description = fmt.Sprintf("exponential backoff from %s up to %s", policy.Delay, policy.MaxDelay)
+	case policy.Jitter:
+		description = fmt.Sprintf("%s delay", policy.Delay)
+	default:
+		return ""
+	}
+	if policy.Jitter {
+		description += ", jittered"
+	}
+	return description
+}
+
 // executePlanImpl creates a plan element for dry-run mode
-func (r *RetryDecorator) executePlanImpl(ctx execution.PlanContext, maxAttempts int, delay time.Duration, content []ast.CommandContent) *execution.ExecutionResult {
-	delayStr := delay.String()
+func (r *RetryDecorator) executePlanImpl(ctx execution.PlanContext, maxAttempts int, policy decorators.RetryPolicy, content []ast.CommandContent) *execution.ExecutionResult {
+	delayStr := policy.Delay.String()
+	backoff := describeBackoff(policy)
 
 	description := fmt.Sprintf("Execute %d commands with up to %d attempts", len(content), maxAttempts)
-	if delayStr != "" && delayStr != "0s" {
+	if backoff != "" {
+		description += ", " + backoff
+	} else if delayStr != "" && delayStr != "0s" {
 		description += fmt.Sprintf(", %s delay between retries", delayStr)
 	}
 
 	element := plan.Decorator("retry").
 		WithType("block").
+		WithRetry(maxAttempts, policy.Delay).
 		WithParameter("attempts", fmt.Sprintf("%d", maxAttempts)).
 		WithDescription(description)
 
 	if delayStr != "" && delayStr != "0s" {
 		element = element.WithParameter("delay", delayStr)
 	}
+	// Delays that grow or are random show the whole schedule
+	if backoff != "" {
+		element = element.WithRetrySchedule(policy.Schedule(maxAttempts), policy.Jitter)
+		if policy.Exponential {
+			element = element.
+				WithParameter("backoff", exponentialBackoff).
+				WithParameter("maxDelay", policy.MaxDelay.String())
+		}
+		if policy.Jitter {
+			element = element.WithParameter("jitter", "true")
+		}
+	}
 
 	// Build child plan elements for each command in the retry block
-	for _, cmd := range content {
-		switch c := cmd.(type) {
-		case *ast.ShellContent:
-			// Create plan element for shell command
-			result := ctx.GenerateShellPlan(c)
-			if result.Error != nil {
-				return &execution.ExecutionResult{
-					Data:  nil,
-					Error: fmt.Errorf("failed to create plan for shell content: %w", result.Error),
-				}
-			}
-
-			// Extract command string from result
-			if planData, ok := result.Data.(map[string]interface{}); ok {
-				if cmdStr, ok := planData["command"].(string); ok {
-					childDesc := "Execute shell command"
-					if desc, ok := planData["description"].(string); ok {
-						childDesc = desc
-					}
-					childElement := plan.Command(cmdStr).WithDescription(childDesc)
-					element = element.AddChild(childElement)
-				}
-			}
-		case *ast.BlockDecorator:
-			// For nested decorators, just add a placeholder - they will be handled by the engine
-			childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator")
-			element = element.AddChild(childElement)
-		}
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
 	}
 
 	return &execution.ExecutionResult{
@@ -242,7 +338,7 @@ func (r *RetryDecorator) executePlanImpl(ctx execution.PlanContext, maxAttempts
 // ImportRequirements returns the dependencies needed for code generation
 func (r *RetryDecorator) ImportRequirements() decorators.ImportRequirement {
 	return decorators.ImportRequirement{
-		StandardLibrary: []string{"fmt", "time"}, // Required by RetryPattern
+		StandardLibrary: []string{"fmt", "os", "time"}, // Required by RetryPattern and the attempt messages
 		ThirdParty:      []string{},
 		GoModules:       map[string]string{},
 	}
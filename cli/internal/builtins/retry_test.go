@@ -1,10 +1,12 @@
 package decorators
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
 	decoratortesting "github.com/aledsdavies/devcmd/testing"
 )
 
@@ -39,6 +41,31 @@ func TestRetryDecorator_Basic(t *testing.T) {
 	}
 }
 
+func TestRetryDecorator_Conformance(t *testing.T) {
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo testing"),
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &RetryDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "attempts", Value: &ast.NumberLiteral{Value: "3"}},
+			{Name: "delay", Value: &ast.DurationLiteral{Value: "2s"}},
+		}, content)
+
+	errors := decoratortesting.Assert(result).
+		Conforms().
+		GeneratorCodeMatchesGolden("testdata/retry.golden").
+		PlanDecoratorIs("retry").
+		PlanHasParameter("attempts", "3").
+		PlanHasParameter("delay", "2s").
+		PlanHasChildren(1).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RetryDecorator conformance test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
 func TestRetryDecorator_WithDelay(t *testing.T) {
 	decorator := &RetryDecorator{}
 
@@ -297,6 +324,106 @@ func TestRetryDecorator_InvalidDelay(t *testing.T) {
 	}
 }
 
+func TestRetryDecorator_ExponentialBackoff(t *testing.T) {
+	content := []ast.CommandContent{
+		decoratortesting.Shell("echo testing"),
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &RetryDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "attempts", Value: &ast.NumberLiteral{Value: "5"}},
+			{Name: "delay", Value: &ast.DurationLiteral{Value: "10ms"}},
+			{Name: "backoff", Value: &ast.StringLiteral{Value: "exponential"}},
+			{Name: "maxDelay", Value: &ast.DurationLiteral{Value: "50ms"}},
+			{Name: "jitter", Value: &ast.BooleanLiteral{Value: true}},
+		}, content)
+
+	errors := decoratortesting.Assert(result).
+		Conforms().
+		GeneratorCodeMatchesGolden("testdata/retry_backoff.golden").
+		PlanHasParameter("backoff", "exponential").
+		PlanHasParameter("maxDelay", "50ms").
+		PlanHasParameter("jitter", "true").
+		Validate()
+	if step, ok := decoratortesting.PlanStep(result); ok {
+		executionPlan := plan.NewExecutionPlan()
+		executionPlan.AddStep(step)
+		if text := executionPlan.StringNoColor(); !strings.Contains(text, "@retry {5 attempts, delays 10ms, 20ms, 40ms, 50ms, jittered}") {
+			errors = append(errors, "plan should show the retry schedule, got:\n"+text)
+		}
+	}
+
+	if len(errors) > 0 {
+		t.Errorf("RetryDecorator exponential backoff test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestRetryDecorator_BackoffFailingCommand(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &RetryDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Name: "attempts", Value: &ast.NumberLiteral{Value: "3"}},
+			{Name: "delay", Value: &ast.DurationLiteral{Value: "1ms"}},
+			{Name: "backoff", Value: &ast.StringLiteral{Value: "exponential"}},
+			{Name: "jitter", Value: &ast.BooleanLiteral{Value: true}},
+		}, []ast.CommandContent{decoratortesting.Shell("false")})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("all 3 attempts failed").
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("RetryDecorator backoff failing command test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestRetryDecorator_InvalidBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []ast.NamedParameter
+		error  string
+	}{
+		{
+			name:   "unknown backoff",
+			params: []ast.NamedParameter{{Name: "backoff", Value: &ast.StringLiteral{Value: "linear"}}},
+			error:  `@retry backoff must be "constant" or "exponential", got "linear"`,
+		},
+		{
+			name:   "maxDelay without backoff",
+			params: []ast.NamedParameter{{Name: "maxDelay", Value: &ast.DurationLiteral{Value: "30s"}}},
+			error:  `@retry maxDelay needs backoff = "exponential"`,
+		},
+		{
+			name: "maxDelay shorter than delay",
+			params: []ast.NamedParameter{
+				{Name: "delay", Value: &ast.DurationLiteral{Value: "1m"}},
+				{Name: "backoff", Value: &ast.StringLiteral{Value: "exponential"}},
+				{Name: "maxDelay", Value: &ast.DurationLiteral{Value: "30s"}},
+			},
+			error: "@retry maxDelay 30s is shorter than delay 1m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := append([]ast.NamedParameter{{Name: "attempts", Value: &ast.NumberLiteral{Value: "3"}}}, tt.params...)
+			result := decoratortesting.NewDecoratorTest(t, &RetryDecorator{}).
+				TestBlockDecorator(params, []ast.CommandContent{decoratortesting.Shell("echo test")})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tt.error).
+				GeneratorFails(tt.error).
+				PlanFails(tt.error).
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("RetryDecorator invalid backoff test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
 func TestRetryDecorator_EmptyContent(t *testing.T) {
 	decorator := &RetryDecorator{}
 
@@ -0,0 +1,438 @@
+package decorators
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// SandboxOptions declares what sandboxed shell steps may do
+type SandboxOptions struct {
+	Write   []string // Paths that stay writable, relative to the working directory; "~" is the home directory
+	Network bool     // Allow network access
+}
+
+// String describes the sandbox for plans and errors
+func (s SandboxOptions) String() string {
+	network := "network allowed"
+	if !s.Network {
+		network = "network disabled"
+	}
+	return fmt.Sprintf("writes limited to %s and the temporary directory, %s", strings.Join(s.Write, ", "), network)
+}
+
+// sandboxMountScript is run by unshare as root of a new user and mount namespace when bwrap
+// is unavailable. Its arguments are the unshare path, the uid and gid to run as, the writable
+// paths, "--" and the command. It bind mounts the writable paths onto themselves, makes every
+// other mount read-only, enters the working directory again so it resolves to the new mounts,
+// and runs the command in a nested user namespace as the original user, which cannot remount
+// anything. A user namespace can't clear the nosuid, nodev, noexec and atime flags of mounts
+// it inherits, so remounts keep them; a mount that still can't be made read-only stops the
+// script with an error starting with sandboxErrorPrefix rather than leaving it writable.
+const sandboxMountScript = `set -ef
+unshare=$1 uid=$2 gid=$3
+shift 3
+for path in "$@"; do
+	[ "$path" = -- ] && break
+	if [ -e "$path" ]; then mount --rbind "$path" "$path"; fi
+done
+mounts=$(cut -d" " -f2,4 /proc/self/mounts)
+printf '%s\n' "$mounts" | while read -r mnt opts; do
+	mnt=$(printf '%b' "$mnt")
+	case "$mnt" in /dev|/dev/*|/proc|/proc/*) continue ;; esac
+	case ",$opts," in *,ro,*) continue ;; esac
+	keep=false
+	for path in "$@"; do
+		[ "$path" = -- ] && break
+		case "$mnt" in "$path"|"$path"/*) keep=true ;; esac
+	done
+	$keep && continue
+	flags=
+	for opt in $(printf '%s' "$opts" | tr , ' '); do
+		case "$opt" in nosuid|nodev|noexec|noatime|nodiratime|relatime|strictatime) flags="$flags,$opt" ;; esac
+	done
+	if ! mount -o "remount,bind,ro$flags" "$mnt" 2>/dev/null && ! mount -o remount,bind,ro "$mnt" 2>/dev/null; then
+		echo "@sandbox: could not make $mnt read-only, so it would stay writable" >&2
+		exit 1
+	fi
+done
+while [ "$1" != -- ]; do shift; done
+shift
+cd "$(pwd -P)"
+exec "$unshare" --user --map-user="$uid" --map-group="$gid" -- "$@"`
+
+// sandboxErrorPrefix starts the errors sandboxMountScript reports
+const sandboxErrorPrefix = "@sandbox: "
+
+// sandboxTemplate prefixes ctx.Shell with the sandbox sandboxPrefix returns
+const sandboxTemplate = `// Run sandboxed: {{.Label}}
+{
+	ctx := ctx.Clone()
+	prefix, err := sandboxPrefix({{printf "%#v" .Sandbox.Write}}, ctx.Dir, {{.Sandbox.Network}})
+	if err != nil {
+		return fmt.Errorf("@sandbox: %v", err)
+	}
+	shell := ctx.Shell
+	if len(shell) == 0 {
+		shell = []string{"sh"}
+	}
+	ctx.Shell = append(prefix, shell...)
+
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// sandboxHelperTemplate is the sandboxPrefix helper every @sandbox of a generated CLI calls.
+// It mirrors sandboxWritablePaths and sandboxPrefix.
+const sandboxHelperTemplate = `
+// sandboxMountScript confines the mounts of an unshare sandbox, see @sandbox
+const sandboxMountScript = {{printf "%q" .MountScript}}
+
+// sandboxPrefix returns the command prefix that confines shell steps to the writable paths,
+// resolved against dir, and the temporary directories, and optionally cuts off the network
+func sandboxPrefix(write []string, dir string, network bool) ([]string, error) {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	home, _ := os.UserHomeDir()
+	var writable []string
+	seen := make(map[string]bool)
+	for _, path := range append(append([]string{}, write...), os.TempDir(), "/tmp") {
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			path = filepath.Join(home, path[1:])
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if path = filepath.Clean(path); !seen[path] {
+			seen[path] = true
+			writable = append(writable, path)
+		}
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		if path, err := execpkg.LookPath("bwrap"); err == nil {
+			bwrap := []string{path, "--ro-bind", "/", "/", "--dev-bind", "/dev", "/dev", "--proc", "/proc", "--die-with-parent"}
+			for _, path := range writable {
+				bwrap = append(bwrap, "--bind-try", path, path)
+			}
+			if !network {
+				bwrap = append(bwrap, "--unshare-net")
+			}
+			bwrap = append(bwrap, "--")
+			if execpkg.Command(bwrap[0], append(bwrap[1:], "true")...).Run() == nil {
+				return bwrap, nil
+			}
+		}
+		if path, err := execpkg.LookPath("unshare"); err == nil {
+			namespaces := []string{path, "--user", "--map-root-user", "--mount"}
+			if !network {
+				namespaces = append(namespaces, "--net")
+			}
+			namespaces = append(namespaces, "--", "sh", "-c", sandboxMountScript, "sh", path, strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid()))
+			namespaces = append(append(namespaces, writable...), "--")
+			output, err := execpkg.Command(namespaces[0], append(namespaces[1:], "true")...).CombinedOutput()
+			if err == nil {
+				return namespaces, nil
+			}
+			if message, failed := strings.CutPrefix(strings.TrimSpace(string(output)), {{printf "%q" .ErrorPrefix}}); failed {
+				return nil, fmt.Errorf("%s", message)
+			}
+		}
+	case "darwin":
+		if path, err := execpkg.LookPath("sandbox-exec"); err == nil {
+			profile := "(version 1)\n(allow default)\n(deny file-write*)\n(allow file-write* (subpath \"/dev\")"
+			for _, path := range writable {
+				profile += fmt.Sprintf(" (subpath %q)", path)
+			}
+			profile += ")\n"
+			if !network {
+				profile += "(deny network*)\n"
+			}
+			return []string{path, "-p", profile}, nil
+		}
+	}
+	return nil, fmt.Errorf("no sandbox available on %s (install bubblewrap or allow unprivileged user namespaces on Linux; macOS needs sandbox-exec)", runtime.GOOS)
+}
+`
+
+// sandboxWritablePaths returns the absolute, symlink-free paths the sandbox leaves writable:
+// the declared paths, resolved against dir, and the temporary directories
+func sandboxWritablePaths(write []string, dir string) []string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	home, _ := os.UserHomeDir()
+
+	var writable []string
+	seen := make(map[string]bool)
+	for _, path := range append(append([]string{}, write...), os.TempDir(), "/tmp") {
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			path = filepath.Join(home, path[1:])
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		// Sandbox rules apply to real paths, e.g. /private/tmp rather than /tmp on macOS
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if path = filepath.Clean(path); !seen[path] {
+			seen[path] = true
+			writable = append(writable, path)
+		}
+	}
+	return writable
+}
+
+// sandboxPrefix returns the command prefix that confines shell steps to the writable paths,
+// and optionally cuts off the network. Linux uses bubblewrap, falling back to unshare with
+// sandboxMountScript; macOS uses sandbox-exec. Each is checked once before steps are routed
+// through it, and no sandbox, or one that can't make every other mount read-only, is an
+// error rather than running unconfined.
+func sandboxPrefix(writable []string, network bool) ([]string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if path, err := exec.LookPath("bwrap"); err == nil {
+			bwrap := []string{path, "--ro-bind", "/", "/", "--dev-bind", "/dev", "/dev", "--proc", "/proc", "--die-with-parent"}
+			for _, path := range writable {
+				bwrap = append(bwrap, "--bind-try", path, path)
+			}
+			if !network {
+				bwrap = append(bwrap, "--unshare-net")
+			}
+			bwrap = append(bwrap, "--")
+			if exec.Command(bwrap[0], append(bwrap[1:], "true")...).Run() == nil {
+				return bwrap, nil
+			}
+		}
+		if path, err := exec.LookPath("unshare"); err == nil {
+			namespaces := []string{path, "--user", "--map-root-user", "--mount"}
+			if !network {
+				namespaces = append(namespaces, "--net")
+			}
+			namespaces = append(namespaces, "--", "sh", "-c", sandboxMountScript, "sh", path, strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid()))
+			namespaces = append(append(namespaces, writable...), "--")
+			output, err := exec.Command(namespaces[0], append(namespaces[1:], "true")...).CombinedOutput()
+			if err == nil {
+				return namespaces, nil
+			}
+			// Namespaces work, but the mounts can't all be confined
+			if message, failed := strings.CutPrefix(strings.TrimSpace(string(output)), sandboxErrorPrefix); failed {
+				return nil, errors.New(message)
+			}
+		}
+	case "darwin":
+		if path, err := exec.LookPath("sandbox-exec"); err == nil {
+			profile := "(version 1)\n(allow default)\n(deny file-write*)\n(allow file-write* (subpath \"/dev\")"
+			for _, path := range writable {
+				profile += fmt.Sprintf(" (subpath %q)", path)
+			}
+			profile += ")\n"
+			if !network {
+				profile += "(deny network*)\n"
+			}
+			return []string{path, "-p", profile}, nil
+		}
+	}
+	return nil, fmt.Errorf("no sandbox available on %s (install bubblewrap or allow unprivileged user namespaces on Linux; macOS needs sandbox-exec)", runtime.GOOS)
+}
+
+// SandboxShell returns the command prefix that runs shell steps through base (sh if empty)
+// inside the sandbox, with relative writable paths resolved against dir
+func SandboxShell(sandbox SandboxOptions, dir string, base []string) ([]string, error) {
+	prefix, err := sandboxPrefix(sandboxWritablePaths(sandbox.Write, dir), sandbox.Network)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) == 0 {
+		base = []string{"sh"}
+	}
+	return append(prefix, base...), nil
+}
+
+// SandboxDecorator implements the @sandbox decorator for confining a block's commands to
+// declared writable paths, optionally without network access
+type SandboxDecorator struct{}
+
+// Name returns the decorator name
+func (s *SandboxDecorator) Name() string {
+	return "sandbox"
+}
+
+// Description returns a human-readable description
+func (s *SandboxDecorator) Description() string {
+	return "Run the block's commands with writes limited to declared paths and optionally no network"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *SandboxDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "write",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Comma-separated paths the commands may write to, besides the temporary directory (default: \".\")",
+		},
+		{
+			Name:        "network",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Allow network access (default: true)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block inside the sandbox in interpreter mode
+func (s *SandboxDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	sandbox, err := s.extractOptions(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	sandboxCtx := ctx.Child()
+	shell, err := SandboxShell(sandbox, sandboxCtx.GetWorkingDir(), sandboxCtx.GetShell())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@%s: %w", s.Name(), err)}
+	}
+	sandboxCtx = sandboxCtx.WithShell(shell)
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	if err := commandExecutor.ExecuteCommandsWithInterpreter(sandboxCtx, content); err != nil {
+		// Writes outside the sandbox fail with "Read-only file system", which alone doesn't say why
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("%w (ran in @%s: %s)", err, s.Name(), sandbox)}
+	}
+	return &execution.ExecutionResult{Data: nil, Error: nil}
+}
+
+// GenerateTemplate generates template for running the block inside the sandbox
+func (s *SandboxDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	sandbox, err := s.extractOptions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("sandbox").Funcs(ctx.GetTemplateFunctions()).Parse(sandboxTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sandbox template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Sandbox SandboxOptions
+			Label   string
+			Content []ast.CommandContent
+		}{
+			Sandbox: sandbox,
+			Label:   sandbox.String(),
+			Content: content,
+		},
+	}, nil
+}
+
+// GeneratedHelpers returns sandboxPrefix, for generated CLIs to emit once however many
+// blocks they sandbox
+func (s *SandboxDecorator) GeneratedHelpers() (map[string]string, error) {
+	tmpl, err := template.New("sandboxPrefix").Parse(sandboxHelperTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sandboxPrefix template: %w", err)
+	}
+	var code strings.Builder
+	err = tmpl.Execute(&code, struct {
+		MountScript string
+		ErrorPrefix string
+	}{
+		MountScript: sandboxMountScript,
+		ErrorPrefix: sandboxErrorPrefix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute sandboxPrefix template: %w", err)
+	}
+	return map[string]string{"sandboxPrefix": code.String()}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (s *SandboxDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	sandbox, err := s.extractOptions(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(s.Name()).
+		WithType("block").
+		WithParameter("write", strings.Join(sandbox.Write, ",")).
+		WithParameter("network", strconv.FormatBool(sandbox.Network)).
+		WithDescription("Run sandboxed: " + sandbox.String())
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractOptions validates parameters and returns the sandbox options
+func (s *SandboxDecorator) extractOptions(params []ast.NamedParameter) (SandboxOptions, error) {
+	sandbox := SandboxOptions{Write: []string{"."}, Network: true}
+	if err := decorators.ValidateParameterCount(params, 0, 2, s.Name()); err != nil {
+		return sandbox, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), s.Name()); err != nil {
+		return sandbox, err
+	}
+
+	if ast.FindParameter(params, "write") != nil {
+		sandbox.Write = nil
+		for _, path := range strings.Split(ast.GetStringParam(params, "write", ""), ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				sandbox.Write = append(sandbox.Write, path)
+			}
+		}
+		if len(sandbox.Write) == 0 {
+			return sandbox, fmt.Errorf("@%s: write must name at least one path", s.Name())
+		}
+	}
+	sandbox.Network = ast.GetBoolParam(params, "network", true)
+
+	return sandbox, nil
+}
+
+// SupportedPlatforms returns the platforms with a sandbox, where the block runs instead of
+// failing; plans describe it anywhere
+func (s *SandboxDecorator) SupportedPlatforms(mode decorators.Mode) []string {
+	if mode == decorators.PlanMode {
+		return nil
+	}
+	return []string{"darwin", "linux"}
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *SandboxDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"os/exec", "path/filepath", "runtime", "strconv"})
+}
+
+// init registers the sandbox decorator
+func init() {
+	decorators.RegisterBlock(&SandboxDecorator{})
+}
@@ -0,0 +1,158 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// requireSandbox skips tests that run commands in a sandbox where none is available
+func requireSandbox(t *testing.T) {
+	t.Helper()
+	if _, err := sandboxPrefix(sandboxWritablePaths([]string{"."}, ""), true); err != nil {
+		t.Skipf("no sandbox available: %v", err)
+	}
+}
+
+func TestSandboxDecorator_Basic(t *testing.T) {
+	requireSandbox(t)
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &SandboxDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.BoolParam("network", false),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo sandboxed > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`sandboxPrefix([]string{"."}, ctx.Dir, false)`, "ctx.Shell = append(prefix, shell...)").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SandboxDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "sandboxed\n" {
+		t.Errorf("temporary directory not writable in the sandbox: %q, %v", data, err)
+	}
+}
+
+func TestSandboxDecorator_GeneratedHelpers(t *testing.T) {
+	helpers, err := (&SandboxDecorator{}).GeneratedHelpers()
+	if err != nil {
+		t.Fatalf("GeneratedHelpers failed: %v", err)
+	}
+	code := helpers["sandboxPrefix"]
+	for _, want := range []string{
+		"func sandboxPrefix(write []string, dir string, network bool) ([]string, error) {",
+		fmt.Sprintf("const sandboxMountScript = %q", sandboxMountScript),
+		`"--unshare-net"`,
+		`"sandbox-exec"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("sandboxPrefix should contain %q, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestSandboxDecorator_BlocksWritesOutside(t *testing.T) {
+	requireSandbox(t)
+	// The package directory is outside the only writable path and the temporary directory
+	probe := "sandbox_probe.txt"
+	t.Cleanup(func() { os.Remove(probe) })
+
+	result := decoratortesting.NewDecoratorTest(t, &SandboxDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("write", "testdata/sandbox"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("touch " + probe),
+		})
+	if errors := decoratortesting.Assert(result).InterpreterFails("ran in @sandbox").Validate(); len(errors) > 0 {
+		t.Errorf("SandboxDecorator allowed a write outside the sandbox:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if _, err := os.Stat(probe); !os.IsNotExist(err) {
+		t.Errorf("%s was written outside the sandbox", probe)
+	}
+}
+
+func TestSandboxPrefix_MountLeftWritable(t *testing.T) {
+	requireSandbox(t)
+	mount, err := exec.LookPath("mount")
+	if err != nil {
+		t.Skip("mount not available")
+	}
+	// Without bubblewrap the unshare fallback is used, where no mount can be made read-only
+	installFakeCLI(t, "bwrap", "exit 1")
+	installFakeCLI(t, "mount", fmt.Sprintf(`case "$*" in *remount*) echo "mount: permission denied" >&2; exit 32 ;; esac
+exec %q "$@"`, mount))
+
+	_, err = sandboxPrefix(sandboxWritablePaths([]string{"."}, ""), true)
+	if err != nil && strings.Contains(err.Error(), "no sandbox available") {
+		t.Skip("unshare not available")
+	}
+	if err == nil || !strings.Contains(err.Error(), "read-only, so it would stay writable") {
+		t.Errorf("sandboxPrefix error = %v, want the mount left writable reported", err)
+	}
+}
+
+func TestSandboxDecorator_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []ast.NamedParameter
+	}{
+		{"empty write", []ast.NamedParameter{decoratortesting.StringParam("write", " , ")}},
+		{"network not a boolean", []ast.NamedParameter{decoratortesting.StringParam("network", "off")}},
+		{"unknown parameter", []ast.NamedParameter{decoratortesting.StringParam("read", ".")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SandboxDecorator{}).
+				TestBlockDecorator(tt.params, []ast.CommandContent{decoratortesting.Shell("true")})
+			if errors := decoratortesting.Assert(result).InterpreterFails("").Validate(); len(errors) > 0 {
+				t.Errorf("SandboxDecorator accepted invalid parameters:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestSandboxWritablePaths(t *testing.T) {
+	dir := t.TempDir()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	writable := sandboxWritablePaths([]string{".", "build", "~/.cache", "build/"}, dir)
+	resolved := evalOrSelf(dir)
+	want := []string{resolved, filepath.Join(resolved, "build"), evalOrSelf(filepath.Join(home, ".cache")), evalOrSelf(os.TempDir())}
+	seen := make(map[string]bool)
+	for _, path := range writable {
+		if seen[path] {
+			t.Errorf("%s listed twice in %v", path, writable)
+		}
+		seen[path] = true
+	}
+	for _, path := range want {
+		if !seen[path] {
+			t.Errorf("writable paths %v missing %s", writable, path)
+		}
+	}
+}
+
+// evalOrSelf resolves symlinks in path, or returns it unchanged when it doesn't exist
+func evalOrSelf(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
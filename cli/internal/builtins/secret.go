@@ -0,0 +1,517 @@
+package decorators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// SecretsFileEnvVar names the sops-encrypted file @secret reads, relative to the directory
+// devcmd runs in. The `secrets` section of devcmd.settings provides a default for it.
+const SecretsFileEnvVar = "DEVCMD_SECRETS_FILE"
+
+// secretTemplate reads the secret with readSecret when the command runs, so values never
+// appear in generated source. Value decorators can't return errors in generated code, so
+// failures exit.
+const secretTemplate = `func() string {
+	value, err := readSecret(ctx, {{printf "%q" .Provider}}, {{printf "%q" .Key}}, {{printf "%q" .Path}})
+	if err != nil {
+		logf("error", "@secret", "%v", err)
+		os.Exit(1)
+	}
+	addSecret(value)
+	return value
+}()`
+
+// readSecretTemplate is the helper every @secret of a generated CLI calls. It has every
+// provider, since which one is configured is only known when the command runs.
+const readSecretTemplate = `
+// secretProviders read @secret values, by provider name
+var secretProviders = map[string]func(name, path string, getenv func(string) string) (string, error){
+{{- range .Providers}}
+	{{printf "%q" .Name}}: {{.Template}},
+{{- end}}
+}
+
+// readSecret reads a secret from a provider: the one given, else the one {{.ProviderVar}}
+// names, else {{.DefaultProvider}}
+func readSecret(ctx ExecutionContext, provider, name, path string) (string, error) {
+	getenv := func(name string) string {
+		if value, ok := ctx.Env[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+	if provider == "" {
+		provider = getenv({{printf "%q" .ProviderVar}})
+	}
+	if provider == "" {
+		provider = {{printf "%q" .DefaultProvider}}
+	}
+	get, ok := secretProviders[provider]
+	if !ok {
+		return "", fmt.Errorf("unknown secrets provider %q: use %s", provider, {{printf "%q" .ProviderNames}})
+	}
+	return get(name, path, getenv)
+}
+`
+
+// SecretDecorator implements the @secret decorator for values from a secret provider: a
+// sops-encrypted file, the OS keyring, Vault, a CI OIDC token, or an environment variable,
+// file or command. Values it reads are masked in diagnostics and error messages.
+type SecretDecorator struct{}
+
+// Name returns the decorator name
+func (s *SecretDecorator) Name() string {
+	return "secret"
+}
+
+// Description returns a human-readable description
+func (s *SecretDecorator) Description() string {
+	return "Value from a secret provider (sops, keyring, vault, oidc, env, file, command), read when the command runs and masked in logs"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *SecretDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "key",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Secret name; dots select nested keys in sops files, e.g. db.password",
+		},
+		{
+			Name:        "provider",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Secret provider: sops, keyring, vault, oidc, env, file, or command (default: the secrets.provider setting, else sops)",
+		},
+		{
+			Name:        "path",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Where the provider finds the secret: a Vault secret path, a sops file, a keyring service, an environment variable, a file, or the command that prints it",
+		},
+		{
+			Name:        "source",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Alias for provider",
+		},
+		{
+			Name:        "name",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Alias for key",
+		},
+		{
+			Name:        "raw",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, the value is inserted as shell syntax instead of being quoted",
+		},
+	}
+}
+
+// secretParams are the resolved @secret parameters
+type secretParams struct {
+	key      string
+	provider string // "" when the secrets.provider setting decides
+	path     string
+}
+
+// ExpandInterpreter reads the secret from its provider for interpreter mode
+func (s *SecretDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	p, err := s.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	provider, err := resolveSecretProvider(p.provider, ctx)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@secret: %w", err)}
+	}
+
+	value, err := provider.Get(secretRequest(p, ctx))
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@secret: %w", err)}
+	}
+	registerSecret(value)
+	return &execution.ExecutionResult{Data: value, Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that reads the secret at runtime
+func (s *SecretDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	p, err := s.extractParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("secret").Parse(secretTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse secret template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Key      string
+			Path     string
+			Provider string
+		}{
+			Key:      p.key,
+			Path:     p.path,
+			Provider: p.provider,
+		},
+	}, nil
+}
+
+// GeneratedHelpers returns readSecret, with the code of every provider, for generated CLIs
+// to emit once however many secrets they read
+func (s *SecretDecorator) GeneratedHelpers() (map[string]string, error) {
+	names := SecretProviderNames()
+	type providerTemplate struct {
+		Name     string
+		Template string
+	}
+	var providers []providerTemplate
+	for _, name := range names {
+		provider, _ := GetSecretProvider(name)
+		providers = append(providers, providerTemplate{Name: name, Template: provider.Template()})
+	}
+
+	tmpl, err := template.New("readSecret").Parse(readSecretTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse readSecret template: %w", err)
+	}
+	var code strings.Builder
+	err = tmpl.Execute(&code, struct {
+		ProviderVar     string
+		DefaultProvider string
+		ProviderNames   string
+		Providers       []providerTemplate
+	}{
+		ProviderVar:     SecretsProviderEnvVar,
+		DefaultProvider: SopsProvider,
+		ProviderNames:   strings.Join(names, ", "),
+		Providers:       providers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute readSecret template: %w", err)
+	}
+	return map[string]string{"readSecret": code.String()}, nil
+}
+
+// ExpandPlan describes the secret without reading it, so plans never show its value
+func (s *SecretDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	p, err := s.extractParams(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	value, source, err := s.PlanValue(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@secret: %w", err)}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  fmt.Sprintf("@secret(%s) → %s (%s)", p.key, value, source),
+		Error: nil,
+	}
+}
+
+// PlanValue returns the masked value of the secret for plans, and the provider source it
+// would be read from, without reading it
+func (s *SecretDecorator) PlanValue(ctx execution.PlanContext, params []ast.NamedParameter) (value, source string, err error) {
+	p, err := s.extractParams(params)
+	if err != nil {
+		return "", "", err
+	}
+	provider, err := resolveSecretProvider(p.provider, ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return logging.Masked, provider.Source(secretRequest(p, ctx)), nil
+}
+
+// QuotesShellValue reports whether the secret is quoted in shell commands. Secrets are often
+// random strings, so $, quotes and spaces in them mustn't be read as shell syntax.
+func (s *SecretDecorator) QuotesShellValue(params []ast.NamedParameter) bool {
+	return !ast.GetBoolParam(params, "raw", false)
+}
+
+// extractParams validates the decorator parameters and returns the secret name, provider,
+// and path
+func (s *SecretDecorator) extractParams(params []ast.NamedParameter) (secretParams, error) {
+	if err := decorators.ValidateParameterCount(params, 1, 4, s.Name()); err != nil {
+		return secretParams{}, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), s.Name()); err != nil {
+		return secretParams{}, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, s.ParameterSchema())
+	if err != nil {
+		return secretParams{}, fmt.Errorf("@secret: %w", err)
+	}
+
+	var key string
+	for _, param := range params {
+		if param.Name != "key" && param.Name != "name" {
+			continue
+		}
+		if key != "" {
+			return secretParams{}, fmt.Errorf("@secret takes key or name, not both")
+		}
+		// Allow identifiers for convenience (e.g., @secret(API_TOKEN))
+		switch v := param.Value.(type) {
+		case *ast.StringLiteral:
+			key = v.Value
+		case *ast.Identifier:
+			key = v.Name
+		}
+	}
+	if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") || strings.Contains(key, "..") {
+		return secretParams{}, fmt.Errorf("@secret requires a secret name such as api_token or db.password, got %q", key)
+	}
+
+	provider := ast.GetStringParam(params, "provider", "")
+	if source := ast.GetStringParam(params, "source", ""); source != "" {
+		if provider != "" {
+			return secretParams{}, fmt.Errorf("@secret takes provider or source, not both")
+		}
+		provider = source
+	}
+	if provider != "" {
+		if err := ValidateSecretProvider(provider); err != nil {
+			return secretParams{}, fmt.Errorf("@secret provider: %w", err)
+		}
+	}
+	return secretParams{key: key, provider: provider, path: ast.GetStringParam(params, "path", "")}, nil
+}
+
+// registerSecret masks a secret that was read in diagnostics and error messages and, in
+// GitHub Actions, asks the runner to mask it in the job's log, which includes command output
+func registerSecret(value string) {
+	logging.AddSecret(value)
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return
+	}
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			fmt.Printf("::add-mask::%s\n", line)
+		}
+	}
+}
+
+// resolveSecretProvider returns the provider named by the decorator, else by the
+// secrets.provider setting, else sops
+func resolveSecretProvider(name string, ctx interface{ GetEnv(string) (string, bool) }) (SecretProvider, error) {
+	if name == "" {
+		name, _ = ctx.GetEnv(SecretsProviderEnvVar)
+	}
+	if name == "" {
+		name = SopsProvider
+	}
+	if err := ValidateSecretProvider(name); err != nil {
+		return nil, err
+	}
+	provider, _ := GetSecretProvider(name)
+	return provider, nil
+}
+
+// secretRequest builds a provider request that reads configuration from the context
+func secretRequest(p secretParams, ctx interface{ GetEnv(string) (string, bool) }) SecretRequest {
+	return SecretRequest{
+		Name: p.key,
+		Path: p.path,
+		Getenv: func(name string) string {
+			value, _ := ctx.GetEnv(name)
+			return value
+		},
+	}
+}
+
+// ImportRequirements returns the dependencies needed for code generation, which are those of
+// every provider a generated CLI may use
+func (s *SecretDecorator) ImportRequirements() decorators.ImportRequirement {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, name := range SecretProviderNames() {
+		provider, _ := GetSecretProvider(name)
+		for _, imp := range provider.Imports() {
+			if !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+	}
+	sort.Strings(imports)
+	imports = append(imports, "sort", "sync") // For addSecret
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, imports)
+}
+
+// sopsTemplate mirrors sopsProvider.Get: it decrypts the secrets file with sops and looks up
+// the dotted secret name
+const sopsTemplate = `func(name, path string, getenv func(string) string) (string, error) {
+		file := path
+		if file == "" {
+			file = getenv("DEVCMD_SECRETS_FILE")
+		}
+		if file == "" {
+			return "", fmt.Errorf("no secrets file: set secrets.file in devcmd.settings or DEVCMD_SECRETS_FILE")
+		}
+		cmd := execpkg.Command("sops", "--decrypt", "--output-type", "json", file)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%s", msg)
+			}
+			return "", fmt.Errorf("failed to decrypt %s with sops: %v", file, err)
+		}
+		decoder := json.NewDecoder(strings.NewReader(string(out)))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return "", fmt.Errorf("%s did not decrypt to a map of secrets: %v", file, err)
+		}
+		for _, key := range strings.Split(name, ".") {
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("secret %q not found in %s", name, file)
+			}
+			if value, ok = values[key]; !ok {
+				return "", fmt.Errorf("secret %q not found in %s", name, file)
+			}
+		}
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number, bool:
+			return fmt.Sprint(v), nil
+		}
+		return "", fmt.Errorf("secret %q in %s is not a single value", name, file)
+	}`
+
+// sopsProvider reads secrets from a sops-encrypted file; the path parameter overrides the
+// secrets.file setting
+type sopsProvider struct{}
+
+func (sopsProvider) Name() string { return SopsProvider }
+
+func (sopsProvider) Get(req SecretRequest) (string, error) {
+	file := sopsFile(req)
+	if file == "" {
+		return "", fmt.Errorf("no secrets file: set secrets.file in devcmd.settings or %s", SecretsFileEnvVar)
+	}
+	secrets, err := decryptSecrets(file)
+	if err != nil {
+		return "", err
+	}
+	return lookupSecret(secrets, req.Name, file)
+}
+
+func (sopsProvider) Source(req SecretRequest) string {
+	if file := sopsFile(req); file != "" {
+		return file
+	}
+	return "<no secrets file>"
+}
+
+func (sopsProvider) Template() string { return sopsTemplate }
+
+func (sopsProvider) Imports() []string { return []string{"encoding/json", "os/exec"} }
+
+// sopsFile returns the secrets file for a request
+func sopsFile(req SecretRequest) string {
+	if req.Path != "" {
+		return req.Path
+	}
+	return req.Getenv(SecretsFileEnvVar)
+}
+
+// decryptedSecrets caches decrypted secrets files by path and modification time, so commands
+// that use several secrets run sops once, and long-running modes see edits
+var decryptedSecrets = struct {
+	sync.Mutex
+	files map[string]cachedSecrets
+}{files: make(map[string]cachedSecrets)}
+
+// cachedSecrets is a decrypted secrets file and the modification time it was decrypted at
+type cachedSecrets struct {
+	modTime time.Time
+	secrets interface{}
+}
+
+// decryptSecrets decrypts a sops-encrypted file to its JSON document
+func decryptSecrets(file string) (interface{}, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file: %w", err)
+	}
+
+	decryptedSecrets.Lock()
+	defer decryptedSecrets.Unlock()
+	if cached, ok := decryptedSecrets.files[file]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.secrets, nil
+	}
+
+	cmd := exec.Command("sops", "--decrypt", "--output-type", "json", file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s", msg)
+		}
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", file, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	decoder.UseNumber()
+	var secrets interface{}
+	if err := decoder.Decode(&secrets); err != nil {
+		return nil, fmt.Errorf("%s did not decrypt to a map of secrets: %w", file, err)
+	}
+	decryptedSecrets.files[file] = cachedSecrets{modTime: info.ModTime(), secrets: secrets}
+	return secrets, nil
+}
+
+// lookupSecret finds a secret by its dotted name in a decrypted secrets document
+func lookupSecret(secrets interface{}, key, file string) (string, error) {
+	value := secrets
+	for _, part := range strings.Split(key, ".") {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("secret %q not found in %s", key, file)
+		}
+		if value, ok = values[part]; !ok {
+			return "", fmt.Errorf("secret %q not found in %s", key, file)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("secret %q in %s is not a single value", key, file)
+}
+
+// init registers the secret decorator and the sops secret provider
+func init() {
+	decorators.RegisterValue(&SecretDecorator{})
+	RegisterSecretProvider(sopsProvider{})
+}
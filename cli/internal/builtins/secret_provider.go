@@ -0,0 +1,89 @@
+package decorators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretsProviderEnvVar selects the provider @secret reads secrets from when the decorator
+// doesn't name one. The `secrets` section of devcmd.settings provides a default for it.
+const SecretsProviderEnvVar = "DEVCMD_SECRETS_PROVIDER"
+
+// Built-in secret providers
+const (
+	SopsProvider    = "sops"
+	KeyringProvider = "keyring"
+	VaultProvider   = "vault"
+	OIDCProvider    = "oidc"
+)
+
+// SecretRequest is a request for one secret from a SecretProvider
+type SecretRequest struct {
+	Name   string                   // Secret name, e.g. api_token
+	Path   string                   // Provider-specific location, e.g. a Vault secret path
+	Getenv func(name string) string // Looks up configuration, preferring command environment
+}
+
+// SecretProvider is a source of @secret values. Providers read secrets directly in
+// interpreter mode and contribute the equivalent code to generated CLIs.
+type SecretProvider interface {
+	// Name is the provider name used in settings and the provider parameter
+	Name() string
+
+	// Get reads a secret. Errors must never include secret values.
+	Get(req SecretRequest) (string, error)
+
+	// Source describes where a secret comes from for plans, without reading it
+	Source(req SecretRequest) string
+
+	// Template returns a Go function literal of type
+	// func(name, path string, getenv func(string) string) (string, error)
+	// that reads a secret in generated code, the way Get does
+	Template() string
+
+	// Imports lists the standard library packages Template needs besides fmt, os, and strings
+	Imports() []string
+}
+
+// secretProviders holds the registered secret providers by name
+var secretProviders = struct {
+	sync.RWMutex
+	byName map[string]SecretProvider
+}{byName: make(map[string]SecretProvider)}
+
+// RegisterSecretProvider makes a secret provider available to @secret
+func RegisterSecretProvider(provider SecretProvider) {
+	secretProviders.Lock()
+	defer secretProviders.Unlock()
+	secretProviders.byName[provider.Name()] = provider
+}
+
+// GetSecretProvider returns the secret provider registered under name
+func GetSecretProvider(name string) (SecretProvider, bool) {
+	secretProviders.RLock()
+	defer secretProviders.RUnlock()
+	provider, ok := secretProviders.byName[name]
+	return provider, ok
+}
+
+// SecretProviderNames returns the names of the registered secret providers, sorted
+func SecretProviderNames() []string {
+	secretProviders.RLock()
+	defer secretProviders.RUnlock()
+	names := make([]string, 0, len(secretProviders.byName))
+	for name := range secretProviders.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateSecretProvider reports an error naming the available providers when name isn't one
+func ValidateSecretProvider(name string) error {
+	if _, ok := GetSecretProvider(name); !ok {
+		return fmt.Errorf("unknown secrets provider %q: use %s", name, strings.Join(SecretProviderNames(), ", "))
+	}
+	return nil
+}
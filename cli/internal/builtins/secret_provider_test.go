@@ -0,0 +1,72 @@
+package decorators
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// staticProvider is a secret provider that returns its name and the requested secret
+type staticProvider struct{}
+
+func (staticProvider) Name() string { return "static" }
+
+func (staticProvider) Get(req SecretRequest) (string, error) {
+	return "static:" + req.Name + "@" + req.Path, nil
+}
+
+func (staticProvider) Source(req SecretRequest) string { return "static " + req.Path }
+
+func (staticProvider) Template() string {
+	return `func(name, path string, getenv func(string) string) (string, error) {
+		return "static:" + name + "@" + path, nil
+	}`
+}
+
+func (staticProvider) Imports() []string { return nil }
+
+func TestRegisterSecretProvider(t *testing.T) {
+	RegisterSecretProvider(staticProvider{})
+	t.Cleanup(func() {
+		secretProviders.Lock()
+		delete(secretProviders.byName, "static")
+		secretProviders.Unlock()
+	})
+
+	if names := strings.Join(SecretProviderNames(), ","); names != "command,env,file,keyring,oidc,sops,static,vault" {
+		t.Errorf("providers = %s", names)
+	}
+
+	t.Setenv(SecretsProviderEnvVar, "static")
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "token"), decoratortesting.StringParam("path", "team")})
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("static:token@team").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`readSecret(ctx, "", "token", "team")`).
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if helpers, err := (&SecretDecorator{}).GeneratedHelpers(); err != nil || !strings.Contains(helpers["readSecret"], `"static": func(name, path string`) {
+		t.Errorf("readSecret should read with the registered provider, got %v:\n%s", err, helpers["readSecret"])
+	}
+	if plan := fmt.Sprint(result.PlanResult.Data); plan != "@secret(token) → *** (static team)" {
+		t.Errorf("plan = %q", plan)
+	}
+}
+
+func TestValidateSecretProvider(t *testing.T) {
+	if err := ValidateSecretProvider("vault"); err != nil {
+		t.Errorf("vault: %v", err)
+	}
+	err := ValidateSecretProvider("lastpass")
+	if err == nil || err.Error() != `unknown secrets provider "lastpass": use command, env, file, keyring, oidc, sops, vault` {
+		t.Errorf("lastpass: %v", err)
+	}
+}
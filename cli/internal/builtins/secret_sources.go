@@ -0,0 +1,173 @@
+package decorators
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Secret providers that read a secret the machine already has, without a secrets store
+const (
+	EnvSecretProvider     = "env"
+	FileSecretProvider    = "file"
+	CommandSecretProvider = "command"
+)
+
+// DefaultSecretsDir is where the file provider looks for a secret named by its key, as
+// Docker and Kubernetes mount them
+const DefaultSecretsDir = "/run/secrets"
+
+// envSecretTemplate mirrors envSecretProvider.Get in generated code
+const envSecretTemplate = `func(name, path string, getenv func(string) string) (string, error) {
+		variable := path
+		if variable == "" {
+			variable = name
+		}
+		if value := getenv(variable); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", variable)
+	}`
+
+// envSecretProvider reads a secret from an environment variable, the key unless the path
+// parameter names another
+type envSecretProvider struct{}
+
+func (envSecretProvider) Name() string { return EnvSecretProvider }
+
+func (envSecretProvider) Get(req SecretRequest) (string, error) {
+	variable := secretVariable(req)
+	if value := req.Getenv(variable); value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", variable)
+}
+
+func (envSecretProvider) Source(req SecretRequest) string {
+	return "environment variable " + secretVariable(req)
+}
+
+func (envSecretProvider) Template() string { return envSecretTemplate }
+
+func (envSecretProvider) Imports() []string { return nil }
+
+// secretVariable returns the environment variable a request reads
+func secretVariable(req SecretRequest) string {
+	if req.Path != "" {
+		return req.Path
+	}
+	return req.Name
+}
+
+// fileSecretTemplate mirrors fileSecretProvider.Get in generated code
+var fileSecretTemplate = fmt.Sprintf(`func(name, path string, getenv func(string) string) (string, error) {
+		file := path
+		if file == "" {
+			file = filepath.Join(%q, name)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %%q: %%v", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}`, DefaultSecretsDir)
+
+// fileSecretProvider reads a secret from a file, without its trailing newline: the path
+// parameter, else the key in DefaultSecretsDir
+type fileSecretProvider struct{}
+
+func (fileSecretProvider) Name() string { return FileSecretProvider }
+
+func (fileSecretProvider) Get(req SecretRequest) (string, error) {
+	data, err := os.ReadFile(secretFile(req))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %q: %w", req.Name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (fileSecretProvider) Source(req SecretRequest) string { return secretFile(req) }
+
+func (fileSecretProvider) Template() string { return fileSecretTemplate }
+
+func (fileSecretProvider) Imports() []string { return []string{"path/filepath"} }
+
+// secretFile returns the file a request reads
+func secretFile(req SecretRequest) string {
+	if req.Path != "" {
+		return req.Path
+	}
+	return filepath.Join(DefaultSecretsDir, req.Name)
+}
+
+// commandSecretTemplate mirrors commandSecretProvider.Get in generated code
+const commandSecretTemplate = `func(name, path string, getenv func(string) string) (string, error) {
+		if path == "" {
+			return "", fmt.Errorf("the command provider needs the command that prints secret %q as path", name)
+		}
+		cmd := execpkg.Command("sh", "-c", path)
+		cmd.Stdin = os.Stdin
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%s", msg)
+			}
+			return "", fmt.Errorf("failed to read secret %q with %s: %v", name, path, err)
+		}
+		value := strings.TrimRight(string(out), "\r\n")
+		if value == "" {
+			return "", fmt.Errorf("%s printed no value for secret %q", path, name)
+		}
+		return value, nil
+	}`
+
+// commandSecretProvider reads a secret from the output of the shell command in the path
+// parameter, such as a password manager's CLI, without its trailing newline
+type commandSecretProvider struct{}
+
+func (commandSecretProvider) Name() string { return CommandSecretProvider }
+
+func (commandSecretProvider) Get(req SecretRequest) (string, error) {
+	if req.Path == "" {
+		return "", fmt.Errorf("the command provider needs the command that prints secret %q as path", req.Name)
+	}
+	cmd := exec.Command("sh", "-c", req.Path)
+	cmd.Stdin = os.Stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s", msg)
+		}
+		return "", fmt.Errorf("failed to read secret %q with %s: %w", req.Name, req.Path, err)
+	}
+	value := strings.TrimRight(string(out), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s printed no value for secret %q", req.Path, req.Name)
+	}
+	return value, nil
+}
+
+func (commandSecretProvider) Source(req SecretRequest) string {
+	if req.Path == "" {
+		return "<no command>"
+	}
+	return "command " + req.Path
+}
+
+func (commandSecretProvider) Template() string { return commandSecretTemplate }
+
+func (commandSecretProvider) Imports() []string { return []string{"os/exec"} }
+
+// init registers the env, file and command secret providers
+func init() {
+	RegisterSecretProvider(envSecretProvider{})
+	RegisterSecretProvider(fileSecretProvider{})
+	RegisterSecretProvider(commandSecretProvider{})
+}
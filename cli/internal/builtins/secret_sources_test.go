@@ -0,0 +1,118 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestSecretDecorator_Sources(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(file, []byte("file-s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_PASSWORD", "env-s3cret")
+	t.Setenv("PGPASSWORD", "pg-s3cret")
+
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		want   string
+		plan   string
+	}{
+		{
+			name:   "env",
+			params: []ast.NamedParameter{decoratortesting.StringParam("key", "DB_PASSWORD"), decoratortesting.StringParam("source", "env")},
+			want:   "env-s3cret",
+			plan:   "@secret(DB_PASSWORD) → *** (environment variable DB_PASSWORD)",
+		},
+		{
+			name:   "env variable named by path",
+			params: []ast.NamedParameter{decoratortesting.StringParam("key", "db_password"), decoratortesting.StringParam("source", "env"), decoratortesting.StringParam("path", "PGPASSWORD")},
+			want:   "pg-s3cret",
+			plan:   "@secret(db_password) → *** (environment variable PGPASSWORD)",
+		},
+		{
+			name:   "file",
+			params: []ast.NamedParameter{decoratortesting.StringParam("key", "db_password"), decoratortesting.StringParam("provider", "file"), decoratortesting.StringParam("path", file)},
+			want:   "file-s3cret",
+			plan:   fmt.Sprintf("@secret(db_password) → *** (%s)", file),
+		},
+		{
+			name:   "command",
+			params: []ast.NamedParameter{decoratortesting.StringParam("key", "db_password"), decoratortesting.StringParam("source", "command"), decoratortesting.StringParam("path", "printf cmd-; echo s3cret")},
+			want:   "cmd-s3cret",
+			plan:   "@secret(db_password) → *** (command printf cmd-; echo s3cret)",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).TestValueDecorator(tc.params)
+			errors := decoratortesting.Assert(result).
+				InterpreterSucceeds().
+				InterpreterReturns(tc.want).
+				GeneratorSucceeds().
+				GeneratorProducesValidGo().
+				PlanSucceeds().
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+			if plan := fmt.Sprint(result.PlanResult.Data); plan != tc.plan {
+				t.Errorf("plan should be %q, got %q", tc.plan, plan)
+			}
+
+			// Generated CLIs read the secret when they run, not when they are built
+			if code := fmt.Sprint(result.GeneratorResult.Data); strings.Contains(code, tc.want) || !strings.Contains(code, "addSecret(value)") {
+				t.Errorf("generated code should read the secret at runtime and mask it:\n%s", code)
+			}
+			if masked := logging.Mask("password " + tc.want); masked != "password ***" {
+				t.Errorf("a secret that was read should be masked in diagnostics, got %q", masked)
+			}
+		})
+	}
+}
+
+func TestSecretDecorator_SourceErrors(t *testing.T) {
+	t.Setenv("MISSING_SECRET", "")
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		error  string
+	}{
+		{"unset variable", []ast.NamedParameter{decoratortesting.StringParam("key", "MISSING_SECRET"), decoratortesting.StringParam("source", "env")}, "environment variable MISSING_SECRET is not set"},
+		{"missing file", []ast.NamedParameter{decoratortesting.StringParam("key", "token"), decoratortesting.StringParam("source", "file"), decoratortesting.StringParam("path", "/nonexistent/token")}, `failed to read secret "token"`},
+		{"no command", []ast.NamedParameter{decoratortesting.StringParam("key", "token"), decoratortesting.StringParam("source", "command")}, "the command provider needs the command that prints secret \"token\" as path"},
+		{"failing command", []ast.NamedParameter{decoratortesting.StringParam("key", "token"), decoratortesting.StringParam("source", "command"), decoratortesting.StringParam("path", "echo locked >&2; exit 1")}, `failed to read secret "token" with echo locked >&2; exit 1: locked`},
+		{"empty output", []ast.NamedParameter{decoratortesting.StringParam("key", "token"), decoratortesting.StringParam("source", "command"), decoratortesting.StringParam("path", "true")}, `true printed no value for secret "token"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).TestValueDecorator(tc.params)
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.error).
+				GeneratorSucceeds().
+				PlanSucceeds().
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).TestValueDecorator([]ast.NamedParameter{
+		decoratortesting.StringParam("key", "token"),
+		decoratortesting.StringParam("provider", "env"),
+		decoratortesting.StringParam("source", "file"),
+	})
+	if errors := decoratortesting.Assert(result).InterpreterFails("@secret takes provider or source, not both").Validate(); len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,211 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// installFakeSops installs a sops that prints decrypted as JSON for any file, recording each
+// call in a log file it returns
+func installFakeSops(t *testing.T, decrypted string) (secretsFile, calls string) {
+	t.Helper()
+	dir := t.TempDir()
+	secretsFile = filepath.Join(dir, "secrets.yaml")
+	if err := os.WriteFile(secretsFile, []byte("api_token: ENC[AES256_GCM,data:...]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	calls = filepath.Join(dir, "calls.log")
+	installFakeCLI(t, "sops", fmt.Sprintf(`echo "$@" >> %q
+[ "$1 $2 $3" = "--decrypt --output-type json" ] || { echo "unexpected arguments: $*" >&2; exit 1; }
+cat <<'EOF'
+%s
+EOF`, calls, decrypted))
+	t.Setenv(SecretsFileEnvVar, secretsFile)
+	return secretsFile, calls
+}
+
+const fakeSecrets = `{"api_token": "tok-123", "db": {"password": "hunter2", "port": 5432}, "debug": true}`
+
+func TestSecretDecorator(t *testing.T) {
+	installFakeSops(t, fakeSecrets)
+
+	testCases := []struct {
+		key  string
+		want string
+	}{
+		{"api_token", "tok-123"},
+		{"db.password", "hunter2"},
+		{"db.port", "5432"},
+		{"debug", "true"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+				TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", tc.key)})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterSucceeds().
+				InterpreterReturns(tc.want).
+				GeneratorSucceeds().
+				GeneratorCodeContains(fmt.Sprintf(`readSecret(ctx, "", %q, "")`, tc.key)).
+				PlanSucceeds().
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+
+			// true is also Go and PowerShell syntax, so only look for the other values
+			if code := fmt.Sprint(result.GeneratorResult.Data); tc.want != "true" && strings.Contains(code, tc.want) {
+				t.Errorf("generated code contains the secret value %q", tc.want)
+			}
+			if plan := fmt.Sprint(result.PlanResult.Data); strings.Contains(plan, tc.want) || !strings.Contains(plan, "***") {
+				t.Errorf("plan = %q, want the value masked", plan)
+			}
+		})
+	}
+}
+
+func TestSecretDecorator_GeneratedHelpers(t *testing.T) {
+	helpers, err := (&SecretDecorator{}).GeneratedHelpers()
+	if err != nil {
+		t.Fatalf("GeneratedHelpers failed: %v", err)
+	}
+	code := helpers["readSecret"]
+	for _, want := range []string{
+		"func readSecret(ctx ExecutionContext, provider, name, path string) (string, error) {",
+		`"sops", "--decrypt", "--output-type", "json"`,
+		`"secret-tool", "lookup", "service", service, "account", name`,
+		`"find-generic-password"`,
+		`"ACTIONS_ID_TOKEN_REQUEST_URL"`,
+		`"vault": func(name, path string, getenv func(string) string) (string, error)`,
+		`provider = getenv("DEVCMD_SECRETS_PROVIDER")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("readSecret should contain %q, got:\n%s", want, code)
+		}
+	}
+	for _, name := range SecretProviderNames() {
+		if n := strings.Count(code, fmt.Sprintf("%q: func(", name)); n != 1 {
+			t.Errorf("provider %s is emitted %d times, want once", name, n)
+		}
+	}
+}
+
+func TestSecretDecorator_DecryptsOnceAndNotForPlans(t *testing.T) {
+	_, calls := installFakeSops(t, fakeSecrets)
+
+	for _, key := range []string{"api_token", "db.password"} {
+		result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+			TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", key)})
+		if errors := decoratortesting.Assert(result).InterpreterSucceeds().PlanSucceeds().Validate(); len(errors) > 0 {
+			t.Fatalf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+		}
+	}
+
+	log, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("sops was never run: %v", err)
+	}
+	if n := strings.Count(string(log), "\n"); n != 1 {
+		t.Errorf("sops ran %d times, want once:\n%s", n, log)
+	}
+}
+
+func TestSecretDecorator_Errors(t *testing.T) {
+	installFakeSops(t, fakeSecrets)
+
+	testCases := []struct {
+		name  string
+		key   string
+		error string
+	}{
+		{"missing secret", "db.user", `secret "db.user" not found`},
+		{"path through a value", "api_token.x", `secret "api_token.x" not found`},
+		{"not a single value", "db", `secret "db" in `},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+				TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", tc.key)})
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.error).
+				GeneratorSucceeds().
+				PlanSucceeds().
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+			if strings.Contains(result.InterpreterResult.Error.Error(), "hunter2") {
+				t.Errorf("error reveals a secret: %v", result.InterpreterResult.Error)
+			}
+		})
+	}
+}
+
+func TestSecretDecorator_ShellQuoting(t *testing.T) {
+	decorator := &SecretDecorator{}
+
+	if !decorator.QuotesShellValue([]ast.NamedParameter{decoratortesting.StringParam("key", "TOKEN")}) {
+		t.Errorf("@secret values should be quoted for the shell by default")
+	}
+	if decorator.QuotesShellValue([]ast.NamedParameter{
+		decoratortesting.StringParam("key", "TOKEN"),
+		decoratortesting.BoolParam("raw", true),
+	}) {
+		t.Errorf("@secret values should not be quoted with raw = true")
+	}
+}
+
+func TestSecretDecorator_NoSecretsFile(t *testing.T) {
+	t.Setenv(SecretsFileEnvVar, "")
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "api_token")})
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("no secrets file: set secrets.file in devcmd.settings or DEVCMD_SECRETS_FILE").
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSecretDecorator_DecryptionFails(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secrets.yaml")
+	if err := os.WriteFile(file, []byte("x: y\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	installFakeCLI(t, "sops", `echo "Failed to get the data key required to decrypt the SOPS file." >&2; exit 128`)
+	t.Setenv(SecretsFileEnvVar, file)
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "x")})
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("failed to decrypt " + file + " with sops: Failed to get the data key").
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSecretDecorator_InvalidKey(t *testing.T) {
+	for _, key := range []string{"", ".token", "db.", "db..password"} {
+		result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+			TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", key)})
+		errors := decoratortesting.Assert(result).
+			InterpreterFails("@secret requires a secret name").
+			GeneratorFails("@secret requires a secret name").
+			PlanFails("@secret requires a secret name").
+			Validate()
+		if len(errors) > 0 {
+			t.Errorf("key %q:\n%s", key, decoratortesting.JoinErrors(errors))
+		}
+	}
+}
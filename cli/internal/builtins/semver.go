@@ -0,0 +1,247 @@
+package decorators
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/cli/internal/release"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// semverTemplate computes the next version in generated code. It mirrors
+// release.NewPlan so generated CLIs and the interpreter agree on the result.
+const semverTemplate = `func() string {
+	git := func(args ...string) string {
+		cmd := execpkg.Command("git", args...)
+		cmd.Dir = ctx.Dir
+		out, err := cmd.Output()
+		if err != nil {
+			logf("error", "@semver", "git %s failed: %v", args[0], err)
+			os.Exit(1)
+		}
+		return string(out)
+	}
+	parse := func(tag string) (prefix string, v [3]int, pre string, ok bool) {
+		s := tag
+		if strings.HasPrefix(s, "v") || strings.HasPrefix(s, "V") {
+			prefix, s = s[:1], s[1:]
+		}
+		if i := strings.Index(s, "+"); i >= 0 {
+			s = s[:i]
+		}
+		if i := strings.Index(s, "-"); i >= 0 {
+			s, pre = s[:i], s[i+1:]
+		}
+		parts := strings.Split(s, ".")
+		if len(parts) != 3 {
+			return "", v, "", false
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return "", v, "", false
+			}
+			v[i] = n
+		}
+		return prefix, v, pre, true
+	}
+	newer := func(a [3]int, aPre string, b [3]int, bPre string) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return a[i] > b[i]
+			}
+		}
+		if aPre == bPre {
+			return false
+		}
+		return aPre == "" || (bPre != "" && aPre > bPre)
+	}
+
+	latestTag, prefix, version, pre := "", "v", [3]int{}, ""
+	for _, tag := range strings.Fields(git("tag", "--merged", "HEAD")) {
+		if p, v, vPre, ok := parse(tag); ok && (latestTag == "" || newer(v, vPre, version, pre)) {
+			latestTag, prefix, version, pre = tag, p, v, vPre
+		}
+	}
+
+	bump := {{printf "%q" .Bump}}
+{{if .Auto}}	revision := "HEAD"
+	if latestTag != "" {
+		revision = latestTag + "..HEAD"
+	}
+	bump = "none"
+	rank := map[string]int{"none": 0, "patch": 1, "minor": 2, "major": 3}
+	for _, record := range strings.Split(git("log", "--format=%s%x1f%b%x1e", revision), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 2)
+		subject, body := fields[0], ""
+		if len(fields) == 2 {
+			body = fields[1]
+		}
+		// As release.ParseConventional: strip the "!", then the scope, then reject headers
+		// with whitespace, whose subjects aren't conventional and so only break by their body
+		header, breaking := "", false
+		if i := strings.Index(subject, ": "); i > 0 {
+			header = subject[:i]
+			breaking = strings.HasSuffix(header, "!")
+			header = strings.TrimSuffix(header, "!")
+			if i := strings.Index(header, "("); i > 0 && strings.HasSuffix(header, ")") {
+				header = header[:i]
+			}
+			if header == "" || strings.ContainsAny(header, " \t") {
+				header, breaking = "", false
+			}
+		}
+		kind := "patch"
+		switch {
+		case breaking, strings.Contains(body, "BREAKING CHANGE:"), strings.Contains(body, "BREAKING-CHANGE:"):
+			kind = "major"
+		case strings.ToLower(header) == "feat":
+			kind = "minor"
+		}
+		if rank[kind] > rank[bump] {
+			bump = kind
+		}
+	}
+{{end}}
+	if pre != "" && bump != "none" && (bump == "patch" || (bump == "minor" && version[2] == 0) || (bump == "major" && version[1] == 0 && version[2] == 0)) {
+		return fmt.Sprintf("%s%d.%d.%d", prefix, version[0], version[1], version[2])
+	}
+	switch bump {
+	case "major":
+		version = [3]int{version[0] + 1, 0, 0}
+	case "minor":
+		version = [3]int{version[0], version[1] + 1, 0}
+	case "patch":
+		version[2]++
+	default:
+		if pre != "" {
+			return fmt.Sprintf("%s%d.%d.%d-%s", prefix, version[0], version[1], version[2], pre)
+		}
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, version[0], version[1], version[2])
+}()`
+
+// SemverDecorator implements the @semver decorator for computing the next release version
+type SemverDecorator struct{}
+
+// Name returns the decorator name
+func (s *SemverDecorator) Name() string {
+	return "semver"
+}
+
+// Description returns a human-readable description
+func (s *SemverDecorator) Description() string {
+	return "Next semantic version from the latest git tag, bumped explicitly or from conventional commits"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *SemverDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "bump",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Version part to bump: auto, major, minor, or patch (default: auto, from conventional commits)",
+		},
+	}
+}
+
+// ExpandInterpreter returns the next version for interpreter mode
+func (s *SemverDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	bump, err := s.extractBump(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	plan, err := release.NewPlan(ctx.GetWorkingDir(), bump)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@semver: %w", err)}
+	}
+
+	return &execution.ExecutionResult{Data: plan.Next.String(), Error: nil}
+}
+
+// GenerateTemplate returns template for Go code that computes the next version at runtime
+func (s *SemverDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	bump, err := s.extractBump(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("semver").Parse(semverTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse semver template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Bump string
+			Auto bool
+		}{
+			Bump: string(bump),
+			Auto: bump == release.BumpAuto,
+		},
+	}, nil
+}
+
+// ExpandPlan returns description showing the computed version for plan mode
+func (s *SemverDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	bump, err := s.extractBump(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	plan, err := release.NewPlan(ctx.GetWorkingDir(), bump)
+	if err != nil {
+		return &execution.ExecutionResult{
+			Data:  fmt.Sprintf("@semver(%s) → <unavailable>", bump),
+			Error: nil,
+		}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  fmt.Sprintf("@semver(%s) → %q (%s bump from %s)", bump, plan.Next, plan.Bump, plan.Current),
+		Error: nil,
+	}
+}
+
+// extractBump validates parameters and returns the requested bump kind
+func (s *SemverDecorator) extractBump(params []ast.NamedParameter) (release.BumpKind, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 1, "semver"); err != nil {
+		return "", err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), "semver"); err != nil {
+		return "", err
+	}
+
+	bump := ast.GetStringParam(params, "bump", "")
+	if bump == "" && len(params) > 0 {
+		// Allow the bump as a positional identifier, e.g. @semver(minor)
+		if identifier, ok := params[0].Value.(*ast.Identifier); ok {
+			bump = identifier.Name
+		}
+	}
+
+	kind, err := release.ParseBump(bump)
+	if err != nil {
+		return "", fmt.Errorf("@semver: %w", err)
+	}
+	return kind, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *SemverDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"os/exec", "strconv"})
+}
+
+// init registers the semver decorator
+func init() {
+	decorators.RegisterValue(&SemverDecorator{})
+}
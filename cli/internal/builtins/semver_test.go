@@ -0,0 +1,73 @@
+package decorators
+
+import (
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestSemverDecorator_ExplicitBump(t *testing.T) {
+	dir := initGitRepo(t)
+	if _, err := runGit(dir, "tag", "v1.2.3"); err != nil {
+		t.Fatalf("failed to tag: %v", err)
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &SemverDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("bump", "minor"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("v1.3.0").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`bump := "minor"`, `"tag", "--merged", "HEAD"`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SemverDecorator explicit bump test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSemverDecorator_AutoBump(t *testing.T) {
+	dir := initGitRepo(t)
+	if _, err := runGit(dir, "tag", "v1.2.3"); err != nil {
+		t.Fatalf("failed to tag: %v", err)
+	}
+	if _, err := runGit(dir, "-c", "user.name=devcmd", "-c", "user.email=devcmd@example.com", "commit", "-q", "--allow-empty", "-m", "feat: add thing"); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	result := decoratortesting.NewDecoratorTest(t, &SemverDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("v1.3.0").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`"log", "--format=%s%x1f%b%x1e"`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SemverDecorator auto bump test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSemverDecorator_InvalidBump(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SemverDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("bump", "huge"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("invalid bump").
+		GeneratorFails("invalid bump").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SemverDecorator invalid bump test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,317 @@
+package decorators
+
+import (
+	"fmt"
+	"runtime"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// sessionTemplate runs the block's steps in one long-lived sh through ctx.Run. It mirrors
+// execution.ShellSession: the shell reads the path of each step's script from fd 3, sources
+// it, and writes its exit status to fd 4. Steps that would run differently in the session,
+// such as steps through a container shell or in another directory, run in their own process.
+const sessionTemplate = `// Run steps in one shell session
+{
+	ctx := ctx.Clone()
+	endSession := func() {}
+	if ctx.Run == nil && runtime.GOOS != "windows" {
+		type sessionShell struct {
+			cmd            *execpkg.Cmd
+			commands       *os.File
+			status         *os.File
+			statuses       *bufio.Reader
+			path, dir      string
+			stdin          string
+			stdout, stderr interface{}
+			env            map[string]string
+		}
+		var mu sync.Mutex
+		var shell *sessionShell
+		var scripts string
+		steps := 0
+
+		environMap := func(environ []string) map[string]string {
+			if environ == nil {
+				environ = os.Environ()
+			}
+			env := make(map[string]string, len(environ))
+			for _, kv := range environ {
+				if name, value, ok := strings.Cut(kv, "="); ok && name != "" {
+					env[name] = value
+				}
+			}
+			return env
+		}
+		isShellName := func(name string) bool {
+			for i, c := range name {
+				if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+					return false
+				}
+			}
+			return name != ""
+		}
+		wait := func() error {
+			err := shell.cmd.Wait()
+			shell.commands.Close()
+			shell.status.Close()
+			shell = nil
+			return err
+		}
+		start := func(cmd *execpkg.Cmd, stdin string) error {
+			if scripts == "" {
+				dir, err := os.MkdirTemp("", "devcmd-session-")
+				if err != nil {
+					return fmt.Errorf("failed to start shell session: %w", err)
+				}
+				scripts = dir
+			}
+			commandsR, commandsW, err := os.Pipe()
+			if err != nil {
+				return fmt.Errorf("failed to start shell session: %w", err)
+			}
+			defer commandsR.Close()
+			statusR, statusW, err := os.Pipe()
+			if err != nil {
+				commandsW.Close()
+				return fmt.Errorf("failed to start shell session: %w", err)
+			}
+			defer statusW.Close()
+
+			driver := ` + "`" + `while IFS= read -r __devcmd_step <&3; do { . "$__devcmd_step"; } 3<&- 4>&-; __devcmd_status=$?; echo "$__devcmd_status" >&4; done` + "`" + `
+			sh := execpkg.Command(cmd.Path, "-c", driver)
+			sh.Args[0] = cmd.Args[0]
+			sh.Dir, sh.Env = cmd.Dir, cmd.Env
+			sh.Stdout, sh.Stderr = cmd.Stdout, cmd.Stderr
+			if stdin == "" {
+				sh.Stdin = cmd.Stdin
+			}
+			sh.ExtraFiles = []*os.File{commandsR, statusW}
+			sh.WaitDelay = time.Second
+			if err := sh.Start(); err != nil {
+				commandsW.Close()
+				statusR.Close()
+				return err
+			}
+			shell = &sessionShell{
+				cmd:      sh,
+				commands: commandsW,
+				status:   statusR,
+				statuses: bufio.NewReader(statusR),
+				path:     cmd.Path,
+				dir:      cmd.Dir,
+				stdin:    stdin,
+				stdout:   cmd.Stdout,
+				stderr:   cmd.Stderr,
+				env:      environMap(cmd.Env),
+			}
+			return nil
+		}
+
+		ctx.Run = func(cmd *execpkg.Cmd) error {
+			stdinFile, ok := cmd.Stdin.(*os.File)
+			if !ok || len(cmd.Args) != 3 || cmd.Args[0] != "sh" || cmd.Args[1] != "-c" {
+				return cmd.Run()
+			}
+			stdin := ""
+			if stdinFile != os.Stdin {
+				stdin = stdinFile.Name()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if shell == nil {
+				if err := start(cmd, stdin); err != nil {
+					return err
+				}
+			} else if cmd.Path != shell.path || cmd.Dir != shell.dir || stdin != shell.stdin || interface{}(cmd.Stdout) != shell.stdout || interface{}(cmd.Stderr) != shell.stderr {
+				return cmd.Run()
+			}
+
+			// Write the step's script: its stdin, the environment changes since the last
+			// step, and the command, with strict mode undone afterwards
+			var script strings.Builder
+			if stdin != "" {
+				fmt.Fprintf(&script, "exec <%s\n", quoteShellValue(stdin, 0))
+			}
+			env := environMap(cmd.Env)
+			var names []string
+			for name := range env {
+				names = append(names, name)
+			}
+			for name := range shell.env {
+				if _, ok := env[name]; !ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value, ok := env[name]
+				old, had := shell.env[name]
+				if (ok && had && value == old) || !isShellName(name) {
+					continue
+				}
+				if ok {
+					fmt.Fprintf(&script, "export %s=%s\n", name, quoteShellValue(value, 0))
+				} else {
+					fmt.Fprintf(&script, "unset %s\n", name)
+				}
+			}
+			shell.env = env
+			command := cmd.Args[2]
+			if strings.HasPrefix(command, strictShellPrefix) {
+				script.WriteString("__devcmd_options=$(set +o)\n" + command + "\n__devcmd_status=$?; eval \"$__devcmd_options\"; return $__devcmd_status\n")
+			} else {
+				script.WriteString(command + "\n")
+			}
+			steps++
+			path := filepath.Join(scripts, "step-"+strconv.Itoa(steps)+".sh")
+			if err := os.WriteFile(path, []byte(script.String()), 0o600); err != nil {
+				return fmt.Errorf("failed to write session step: %w", err)
+			}
+			defer os.Remove(path)
+
+			// A step that exits the shell ends the session; the next step starts a new one
+			if _, err := fmt.Fprintln(shell.commands, path); err != nil {
+				return wait()
+			}
+			line, err := shell.statuses.ReadString('\n')
+			if err != nil {
+				return wait()
+			}
+			code, err := strconv.Atoi(strings.TrimSpace(line))
+			if err != nil {
+				return fmt.Errorf("shell session: unexpected status %q", line)
+			}
+			if code != 0 {
+				return fmt.Errorf("exit status %d", code)
+			}
+			return nil
+		}
+		endSession = func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if shell != nil {
+				shell.commands.Close()
+				_ = wait()
+			}
+			if scripts != "" {
+				os.RemoveAll(scripts)
+			}
+		}
+	} else if ctx.Run == nil {
+		logf("warn", "@session", "shell sessions are not supported on %s, running each step in its own process", runtime.GOOS)
+	}
+	err := func() error {
+		defer endSession()
+{{range .Content}}		{{. | buildCommand}}
+{{end}}		return nil
+	}()
+	if err != nil {
+		return err
+	}
+}`
+
+// SessionDecorator implements the @session decorator for running the block's shell steps in
+// one long-lived shell
+type SessionDecorator struct{}
+
+// Name returns the decorator name
+func (s *SessionDecorator) Name() string {
+	return "session"
+}
+
+// Description returns a human-readable description
+func (s *SessionDecorator) Description() string {
+	return "Run the block's steps in one shell, sharing its directory, variables and exports"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *SessionDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{}
+}
+
+// ExecuteInterpreter runs the block in a shell session in interpreter mode
+func (s *SessionDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	if err := s.validateParameters(params); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	if runtime.GOOS == "windows" {
+		ctx.Logger().Warnf("@session", "shell sessions are not supported on %s, running each step in its own process", runtime.GOOS)
+	}
+	session := execution.NewShellSession()
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	err := commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child().WithShellSession(session), content)
+	if closeErr := session.Close(); err == nil {
+		err = closeErr
+	}
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: err,
+	}
+}
+
+// GenerateTemplate generates template for running the block in a shell session
+func (s *SessionDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	if err := s.validateParameters(params); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("session").Funcs(ctx.GetTemplateFunctions()).Parse(sessionTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Content []ast.CommandContent
+		}{
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (s *SessionDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	if err := s.validateParameters(params); err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(s.Name()).
+		WithType("block").
+		WithDescription("Run steps in one shell session")
+
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// validateParameters checks that no parameters were given
+func (s *SessionDecorator) validateParameters(params []ast.NamedParameter) error {
+	return decorators.ValidateParameterCount(params, 0, 0, s.Name())
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *SessionDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, decorators.ConcurrencyImports, decorators.TimeImports, []string{"bufio", "os/exec", "path/filepath", "runtime", "sort", "strconv"})
+}
+
+// init registers the session decorator
+func init() {
+	decorators.RegisterBlock(&SessionDecorator{})
+}
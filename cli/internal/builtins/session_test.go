@@ -0,0 +1,116 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestSessionDecorator_SharesShellState(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &SessionDecorator{}).
+		TestBlockDecorator(nil, []ast.CommandContent{
+			decoratortesting.Shell("cd " + dir),
+			decoratortesting.Shell("greeting=hello"),
+			decoratortesting.Shell(`echo "$greeting $PWD" > ` + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("ctx.Run = func(cmd *execpkg.Cmd) error", "__devcmd_step").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SessionDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("the last step did not run: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if line := strings.TrimSpace(string(got)); line != "hello "+dir && line != "hello "+resolved {
+		t.Errorf("last step saw %q, want the variable and directory set by earlier steps", line)
+	}
+}
+
+func TestSessionDecorator_ReportsExitStatus(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SessionDecorator{}).
+		TestBlockDecorator(nil, []ast.CommandContent{
+			decoratortesting.Shell("true"),
+			decoratortesting.Shell("sh -c 'exit 3'"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("exit status 3").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SessionDecorator exit status test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSessionDecorator_InvalidParameters(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SessionDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("shell", "bash")}, []ast.CommandContent{
+			decoratortesting.Shell("true"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SessionDecorator invalid parameters test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestShellSession_ExportsOutputAndRestart(t *testing.T) {
+	session := execution.NewShellSession()
+	defer session.Close()
+
+	var stdout bytes.Buffer
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{}).
+		WithOutput(&stdout, os.Stderr).
+		WithShellSession(session)
+	run := func(ctx execution.InterpreterContext, text string) error {
+		t.Helper()
+		return ctx.ExecuteShell(decoratortesting.Shell(text).(*ast.ShellContent)).Error
+	}
+
+	scoped := ctx.Child()
+	scoped.ExportEnv("DEVCMD_SESSION_TEST", "scoped value")
+	if err := run(scoped, `echo "inside: $DEVCMD_SESSION_TEST"; kept=yes`); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if err := run(ctx, `echo "after: ${DEVCMD_SESSION_TEST-unset} $kept"`); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	err := run(ctx, "exit 7")
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 7 {
+		t.Fatalf("exit step returned %v, want exit status 7", err)
+	}
+	if err := run(ctx, `echo "restarted: ${kept-gone}"`); err != nil {
+		t.Fatalf("step after the shell exited failed: %v", err)
+	}
+
+	want := "inside: scoped value\nafter: unset yes\nrestarted: gone\n"
+	if stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+}
@@ -0,0 +1,175 @@
+package decorators
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// setTemplate assigns the Go variables the generator declares for @set targets
+const setTemplate = `func() error {
+{{range .}}	{{.Name}} = {{.Expression}}
+{{end}}	return nil
+}()`
+
+// SetDecorator implements the @set decorator for defining or updating variables mid-command
+type SetDecorator struct{}
+
+// assignment is a single NAME = value parameter of @set
+type assignment struct {
+	Name  string
+	Value ast.Expression
+}
+
+// Name returns the decorator name
+func (s *SetDecorator) Name() string {
+	return "set"
+}
+
+// Description returns a human-readable description
+func (s *SetDecorator) Description() string {
+	return "Define or update variables for the rest of the command"
+}
+
+// ParameterSchema returns the expected parameters for this decorator.
+// @set has no fixed parameters: each NAME = value parameter assigns the variable NAME.
+func (s *SetDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{}
+}
+
+// ExpandInterpreter computes each value and stores it in the context for later steps
+func (s *SetDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	assignments, err := s.extractAssignments(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	for _, a := range assignments {
+		value, err := evaluateValue(ctx, a.Value)
+		if err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@set: %s: %w", a.Name, err)}
+		}
+		ctx.SetVariable(a.Name, value)
+	}
+
+	return &execution.ExecutionResult{
+		Data:  "true", // Return "true" for shell chaining
+		Error: nil,
+	}
+}
+
+// GenerateTemplate returns template for Go code that assigns the variables.
+// The engine declares every @set target as a Go variable, see AssignedVariables.
+func (s *SetDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	assignments, err := s.extractAssignments(params)
+	if err != nil {
+		return nil, err
+	}
+
+	type goAssignment struct {
+		Name       string
+		Expression string
+	}
+	data := make([]goAssignment, 0, len(assignments))
+	for _, a := range assignments {
+		expression, err := goValueExpression(ctx, a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("@set: %s: %w", a.Name, err)
+		}
+		data = append(data, goAssignment{Name: a.Name, Expression: expression})
+
+		// Later references to the variable are valid from here on
+		if _, exists := ctx.GetVariable(a.Name); !exists {
+			ctx.SetVariable(a.Name, "")
+		}
+	}
+
+	tmpl, err := template.New("set").Parse(setTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse set template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data:     data,
+	}, nil
+}
+
+// ExpandPlan shows each assignment with its computed value, and records the value so
+// later steps of the plan display it
+func (s *SetDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	assignments, err := s.extractAssignments(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	descriptions := make([]string, 0, len(assignments))
+	for _, a := range assignments {
+		description := fmt.Sprintf("@set(%s = %s) → ", a.Name, formatValue(a.Value))
+		if value, err := evaluateValue(ctx, a.Value); err == nil {
+			ctx.SetVariable(a.Name, value)
+			description += fmt.Sprintf("%q", value)
+		} else {
+			description += "<undefined>"
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	return &execution.ExecutionResult{
+		Data:  strings.Join(descriptions, ", "),
+		Error: nil,
+	}
+}
+
+// AssignedVariables returns the names of the variables @set assigns
+func (s *SetDecorator) AssignedVariables(params []ast.NamedParameter) []string {
+	assignments, err := s.extractAssignments(params)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(assignments))
+	for _, a := range assignments {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// ReferencedVariables returns the names of the variables @set's values read
+func (s *SetDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+// extractAssignments returns the NAME = value parameters in order
+func (s *SetDecorator) extractAssignments(params []ast.NamedParameter) ([]assignment, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("@set requires at least one NAME = value assignment")
+	}
+
+	assignments := make([]assignment, 0, len(params))
+	for i, param := range params {
+		// The parser names positional parameters arg0, arg1, ... when there's no schema for them
+		if param.Name == "" || (param.NameToken == nil && param.Name == fmt.Sprintf("arg%d", i)) {
+			return nil, fmt.Errorf("@set takes NAME = value assignments, e.g. @set(TAG = \"v@var(VERSION)\")")
+		}
+		assignments = append(assignments, assignment{Name: param.Name, Value: param.Value})
+	}
+	return assignments, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *SetDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.ImportRequirement{
+		StandardLibrary: []string{}, // Assignments only use the generated variables
+		ThirdParty:      []string{},
+		GoModules:       map[string]string{},
+	}
+}
+
+// init registers the set decorator
+func init() {
+	decorators.RegisterAction(&SetDecorator{})
+}
@@ -0,0 +1,124 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestSetDecorator_Basic(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SetDecorator{}).
+		WithVariable("VERSION", "1.2.0").
+		WithVariable("SHA", "abc123").
+		TestActionDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("TAG", "v@var(VERSION)-@var(SHA)"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("true").
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`TAG = "v" + VERSION + "-" + SHA`).
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SetDecorator basic test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if plan, want := fmt.Sprint(result.PlanResult.Data), `@set(TAG = "v@var(VERSION)-@var(SHA)") → "v1.2.0-abc123"`; plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestSetDecorator_UpdatesContext(t *testing.T) {
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	ctx.SetVariable("VERSION", "1.2.0")
+
+	result := (&SetDecorator{}).ExpandInterpreter(ctx, []ast.NamedParameter{
+		decoratortesting.StringParam("VERSION", "2.0.0"),
+		decoratortesting.StringParam("TAG", "v@var(VERSION)"),
+		decoratortesting.IdentifierParam("RELEASE", "TAG"),
+		decoratortesting.IntParam("REPLICAS", 3),
+		decoratortesting.BoolParam("PUSH", true),
+	})
+	if result.Error != nil {
+		t.Fatalf("@set failed: %v", result.Error)
+	}
+
+	child := ctx.Child()
+	for name, want := range map[string]string{"VERSION": "2.0.0", "TAG": "v2.0.0", "RELEASE": "v2.0.0", "REPLICAS": "3", "PUSH": "true"} {
+		if value, _ := ctx.GetVariable(name); value != want {
+			t.Errorf("%s = %q, want %q", name, value, want)
+		}
+		if value, _ := child.GetVariable(name); value != want {
+			t.Errorf("%s in a child context = %q, want %q", name, value, want)
+		}
+	}
+}
+
+func TestSetDecorator_GeneratesLiterals(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SetDecorator{}).
+		WithVariable("TAG", "v1").
+		TestActionDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("EMPTY", ""),
+			decoratortesting.StringParam("SUFFIX", "@var(TAG)-rc"),
+			decoratortesting.IdentifierParam("RELEASE", "TAG"),
+			decoratortesting.IntParam("REPLICAS", 3),
+		})
+
+	errors := decoratortesting.Assert(result).
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`EMPTY = ""`, `SUFFIX = TAG + "-rc"`, `RELEASE = TAG`, `REPLICAS = "3"`).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SetDecorator literal test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestSetDecorator_UndefinedVariable(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SetDecorator{}).
+		TestActionDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("TAG", "v@var(MISSING)"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("variable 'MISSING' not defined").
+		GeneratorFails("variable 'MISSING' not defined").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("SetDecorator undefined variable test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if plan, want := fmt.Sprint(result.PlanResult.Data), `@set(TAG = "v@var(MISSING)") → <undefined>`; plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestSetDecorator_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"no assignments": nil,
+		"positional":     {{Name: "arg0", Value: &ast.StringLiteral{Value: "v1"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &SetDecorator{}).TestActionDecorator(params)
+			errors := decoratortesting.Assert(result).
+				InterpreterFails("@set").
+				GeneratorFails("@set").
+				PlanFails("@set").
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("SetDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package decorators
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// shellTemplate runs the block's shell steps with the named shell. It mirrors
+// execution.SelectShell.
+const shellTemplate = `// Run with {{.Name}}
+{
+	ctx := ctx.Clone()
+	if len(ctx.Shell) == 0 {
+		ctx.Shell = []string{ {{printf "%q" .Name}} }
+	} else if name := shellName(ctx.Shell); name == "sh" || name == "bash" || name == "zsh" || name == "pwsh" || name == "powershell" || name == "cmd" {
+		ctx.Shell = append(append([]string{}, ctx.Shell[:len(ctx.Shell)-1]...), {{printf "%q" .Name}})
+	} else {
+		return fmt.Errorf("@shell: can't run {{.Name}} in steps that %s runs", ctx.Shell[0])
+	}
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// ShellDecorator implements the @shell decorator for running the block's shell steps with a
+// given shell instead of the platform's default
+type ShellDecorator struct{}
+
+// Name returns the decorator name
+func (s *ShellDecorator) Name() string {
+	return "shell"
+}
+
+// Description returns a human-readable description
+func (s *ShellDecorator) Description() string {
+	return "Run the block's shell steps with bash, sh, pwsh or cmd"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *ShellDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "name",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "The shell to run the block's steps with: " + strings.Join(execution.Shells, ", "),
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block with the shell in interpreter mode
+func (s *ShellDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	name, err := s.extractName(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	shell, err := execution.SelectShell(ctx.GetShell(), name)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@%s: %w", s.Name(), err)}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child().WithShell(shell), content),
+	}
+}
+
+// GenerateTemplate generates template for running the block with the shell
+func (s *ShellDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	name, err := s.extractName(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("shell").Funcs(ctx.GetTemplateFunctions()).Parse(shellTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shell template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Name    string
+			Content []ast.CommandContent
+		}{
+			Name:    name,
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (s *ShellDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	name, err := s.extractName(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(s.Name()).
+		WithType("block").
+		WithParameter("name", name).
+		WithDescription("Run with " + name)
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractName validates parameters and returns the shell's name
+func (s *ShellDecorator) extractName(params []ast.NamedParameter) (string, error) {
+	if err := decorators.ValidateParameterCount(params, 1, 1, s.Name()); err != nil {
+		return "", err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), s.Name()); err != nil {
+		return "", err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, s.ParameterSchema())
+	if err != nil {
+		return "", fmt.Errorf("@%s: %w", s.Name(), err)
+	}
+	name := ast.GetStringParam(params, "name", "")
+	if !execution.IsShell(name) {
+		return "", fmt.Errorf("@%s: unknown shell %q; expected one of %s", s.Name(), name, strings.Join(execution.Shells, ", "))
+	}
+	return name, nil
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *ShellDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports)
+}
+
+// init registers the shell decorator
+func init() {
+	decorators.RegisterBlock(&ShellDecorator{})
+}
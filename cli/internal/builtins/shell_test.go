@@ -0,0 +1,55 @@
+package decorators
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestShellDecorator_RunsWithShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &ShellDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("name", "bash")}, []ast.CommandContent{
+			decoratortesting.Shell(`echo "${BASH_VERSION:+bash}" > ` + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`ctx.Shell = []string{ "bash" }`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ShellDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if got, err := os.ReadFile(out); err != nil || string(got) != "bash\n" {
+		t.Errorf("output = %q (%v), want the step run by bash", got, err)
+	}
+}
+
+func TestShellDecorator_UnknownShell(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &ShellDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("name", "fish")}, []ast.CommandContent{
+			decoratortesting.Shell("true"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails(`unknown shell "fish"; expected one of bash, sh, pwsh, cmd`).
+		GeneratorFails("").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ShellDecorator unknown shell test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,229 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// Sources shell steps can read stdin from
+const (
+	stdinInherit = "inherit" // devcmd's own stdin, usually the terminal
+	stdinNull    = "null"    // no input; reads see end of file immediately
+	stdinFile    = "file"    // a file, opened afresh for each step
+)
+
+// stdinTemplate points ctx.Stdin at the configured source. It mirrors StdinDecorator.ExecuteInterpreter.
+const stdinTemplate = `// {{.Label}}
+{
+	ctx := ctx.Clone()
+	switch mode, file := {{printf "%q" .Mode}}, {{printf "%q" .File}}; mode {
+	case "inherit":
+		ctx.Stdin = ""
+	case "null":
+		ctx.Stdin = os.DevNull
+	default:
+		if !filepath.IsAbs(file) {
+			dir := ctx.Dir
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			file = filepath.Join(dir, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("@stdin: %w", err)
+		}
+		ctx.Stdin = file
+	}
+
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// StdinDecorator implements the @stdin decorator for choosing what the block's commands read as stdin
+type StdinDecorator struct{}
+
+// Name returns the decorator name
+func (s *StdinDecorator) Name() string {
+	return "stdin"
+}
+
+// Description returns a human-readable description
+func (s *StdinDecorator) Description() string {
+	return "Choose the stdin of the block's commands: the terminal, nothing, or a file"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *StdinDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "mode",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "\"inherit\" to read devcmd's stdin, \"null\" for no input, or \"file\" (implied by file)",
+		},
+		{
+			Name:        "file",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "File each command reads as stdin, relative to the working directory",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block with the chosen stdin in interpreter mode
+func (s *StdinDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	mode, file, err := s.extractSource(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	var path string
+	switch mode {
+	case stdinNull:
+		path = os.DevNull
+	case stdinFile:
+		path = file
+		if !filepath.IsAbs(path) {
+			dir := ctx.GetWorkingDir()
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			path = filepath.Join(dir, path)
+		}
+		// Fail before running anything rather than at the first step
+		if _, err := os.Stat(path); err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: err}
+		}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child().WithStdin(path), content),
+	}
+}
+
+// GenerateTemplate generates template for running the block with the chosen stdin
+func (s *StdinDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	mode, file, err := s.extractSource(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("stdin").Funcs(ctx.GetTemplateFunctions()).Parse(stdinTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stdin template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Mode    string
+			File    string
+			Label   string
+			Content []ast.CommandContent
+		}{
+			Mode:    mode,
+			File:    file,
+			Label:   stdinLabel(mode, file),
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (s *StdinDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	mode, file, err := s.extractSource(ctx, params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(s.Name()).
+		WithType("block").
+		WithParameter("mode", mode).
+		WithDescription(stdinLabel(mode, file))
+	if mode == stdinFile {
+		element = element.WithParameter("file", file)
+	}
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractSource validates parameters and returns the stdin mode, and the file for file mode
+// with @var references resolved
+func (s *StdinDecorator) extractSource(ctx execution.BaseContext, params []ast.NamedParameter) (string, string, error) {
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), s.Name()); err != nil {
+		return "", "", err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, s.ParameterSchema())
+	if err != nil {
+		return "", "", fmt.Errorf("@%s: %w", s.Name(), err)
+	}
+
+	file, err := resolveVariableReferences(ctx, ast.GetStringParam(params, "file", ""))
+	if err != nil {
+		return "", "", fmt.Errorf("@%s: %w", s.Name(), err)
+	}
+	mode := ast.GetStringParam(params, "mode", "")
+	if mode == "" && file != "" {
+		mode = stdinFile
+	}
+
+	switch mode {
+	case stdinInherit, stdinNull:
+		if file != "" {
+			return "", "", fmt.Errorf("@%s: file cannot be used with mode %q", s.Name(), mode)
+		}
+	case stdinFile:
+		if file == "" {
+			return "", "", fmt.Errorf("@%s: mode \"file\" requires a file", s.Name())
+		}
+		if strings.ContainsAny(file, "\r\n") {
+			return "", "", fmt.Errorf("@%s: invalid file %q", s.Name(), file)
+		}
+	case "":
+		return "", "", fmt.Errorf("@%s requires a mode (\"inherit\" or \"null\") or a file", s.Name())
+	default:
+		return "", "", fmt.Errorf("@%s: mode must be \"inherit\", \"null\" or \"file\", got %q", s.Name(), mode)
+	}
+	return mode, file, nil
+}
+
+// stdinLabel describes the stdin source for plans and generated code
+func stdinLabel(mode, file string) string {
+	switch mode {
+	case stdinInherit:
+		return "Read stdin from the terminal"
+	case stdinNull:
+		return "Run without stdin"
+	default:
+		return fmt.Sprintf("Read stdin from %s", file)
+	}
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *StdinDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, []string{"path/filepath"})
+}
+
+// init registers the stdin decorator
+func init() {
+	decorators.RegisterBlock(&StdinDecorator{})
+}
@@ -0,0 +1,118 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestStdinDecorator_File(t *testing.T) {
+	dir := t.TempDir()
+	seed := filepath.Join(dir, "seed.sql")
+	if err := os.WriteFile(seed, []byte("select 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, second := filepath.Join(dir, "first.txt"), filepath.Join(dir, "second.txt")
+
+	result := decoratortesting.NewDecoratorTest(t, &StdinDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("file", seed)}, []ast.CommandContent{
+			decoratortesting.Shell("cat > " + first),
+			decoratortesting.Shell("cat > " + second),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`switch mode, file := "file", "`+seed+`"; mode {`, "ctx.Stdin = file").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StdinDecorator file test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	// Each step reads the file from the start
+	for _, out := range []string{first, second} {
+		got, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("step did not run: %v", err)
+		}
+		if string(got) != "select 1;\n" {
+			t.Errorf("%s = %q, want the seed file", filepath.Base(out), got)
+		}
+	}
+}
+
+func TestStdinDecorator_Modes(t *testing.T) {
+	for _, mode := range []string{"inherit", "null"} {
+		result := decoratortesting.NewDecoratorTest(t, &StdinDecorator{}).
+			TestBlockDecorator([]ast.NamedParameter{{Value: &ast.StringLiteral{Value: mode}}}, []ast.CommandContent{
+				decoratortesting.Shell("echo ok"),
+			})
+
+		errors := decoratortesting.Assert(result).
+			InterpreterSucceeds().
+			GeneratorSucceeds().
+			GeneratorProducesValidGo().
+			GeneratorCodeContains(`switch mode, file := "` + mode + `", ""; mode {`).
+			PlanSucceeds().
+			Validate()
+
+		if len(errors) > 0 {
+			t.Errorf("StdinDecorator mode %s test failed:\n%s", mode, decoratortesting.JoinErrors(errors))
+		}
+	}
+}
+
+func TestStdinDecorator_MissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.sql")
+	result := decoratortesting.NewDecoratorTest(t, &StdinDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("file", missing)}, []ast.CommandContent{
+			decoratortesting.Shell("echo should not run"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("no such file or directory").
+		GeneratorSucceeds().
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StdinDecorator missing file test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestStdinDecorator_InvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params []ast.NamedParameter
+		error  string
+	}{
+		{"no source", []ast.NamedParameter{}, "requires a mode"},
+		{"unknown mode", []ast.NamedParameter{decoratortesting.StringParam("mode", "tty")}, `mode must be "inherit", "null" or "file", got "tty"`},
+		{"file with null", []ast.NamedParameter{decoratortesting.StringParam("mode", "null"), decoratortesting.StringParam("file", "seed.sql")}, "file cannot be used with mode"},
+		{"file mode without file", []ast.NamedParameter{decoratortesting.StringParam("mode", "file")}, `mode "file" requires a file`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &StdinDecorator{}).
+				TestBlockDecorator(tc.params, []ast.CommandContent{decoratortesting.Shell("cat")})
+
+			errors := decoratortesting.Assert(result).
+				InterpreterFails(tc.error).
+				GeneratorFails(tc.error).
+				PlanFails(tc.error).
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("StdinDecorator validation test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
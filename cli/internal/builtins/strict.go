@@ -0,0 +1,144 @@
+package decorators
+
+import (
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// strictTemplate turns strict mode on or off for the block. It mirrors StrictDecorator.ExecuteInterpreter.
+const strictTemplate = `// {{.Label}}
+{
+	ctx := ctx.Clone()
+	ctx.Strict = {{.Enabled}}
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// StrictDecorator implements the @strict decorator for running the block's commands in strict
+// mode, overriding the strictShell setting
+type StrictDecorator struct{}
+
+// Name returns the decorator name
+func (s *StrictDecorator) Name() string {
+	return "strict"
+}
+
+// Description returns a human-readable description
+func (s *StrictDecorator) Description() string {
+	return "Run the block's commands with set -eu and pipefail, or without them when disabled"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (s *StrictDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "enabled",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "Whether strict mode is on for the block (default: true)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block in or out of strict mode in interpreter mode
+func (s *StrictDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	enabled, err := s.extractEnabled(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child().WithStrictShell(enabled), content),
+	}
+}
+
+// GenerateTemplate generates template for running the block in or out of strict mode
+func (s *StrictDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	enabled, err := s.extractEnabled(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("strict").Funcs(ctx.GetTemplateFunctions()).Parse(strictTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse strict template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Enabled bool
+			Label   string
+			Content []ast.CommandContent
+		}{
+			Enabled: enabled,
+			Label:   strictLabel(enabled),
+			Content: content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (s *StrictDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	enabled, err := s.extractEnabled(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(s.Name()).
+		WithType("block").
+		WithParameter("enabled", strconv.FormatBool(enabled)).
+		WithDescription(strictLabel(enabled))
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractEnabled validates parameters and returns whether strict mode is on
+func (s *StrictDecorator) extractEnabled(params []ast.NamedParameter) (bool, error) {
+	if err := decorators.ValidateParameterCount(params, 0, 1, s.Name()); err != nil {
+		return false, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, s.ParameterSchema(), s.Name()); err != nil {
+		return false, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, s.ParameterSchema())
+	if err != nil {
+		return false, fmt.Errorf("@%s: %w", s.Name(), err)
+	}
+	return ast.GetBoolParam(params, "enabled", true), nil
+}
+
+// strictLabel describes the mode for plans and generated code
+func strictLabel(enabled bool) string {
+	if enabled {
+		return "Strict shell: stop at the first failure (set -eu, pipefail)"
+	}
+	return "Shell without strict mode"
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (s *StrictDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports)
+}
+
+// init registers the strict decorator
+func init() {
+	decorators.RegisterBlock(&StrictDecorator{})
+}
@@ -0,0 +1,84 @@
+package decorators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestStrictDecorator_StopsAtFailure(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	result := decoratortesting.NewDecoratorTest(t, &StrictDecorator{}).
+		TestBlockDecorator(nil, []ast.CommandContent{
+			decoratortesting.Shell("false; echo continued > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("").
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains("ctx.Strict = true").
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StrictDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if _, err := os.Stat(out); err == nil {
+		t.Errorf("the command after the failure should not run in strict mode")
+	}
+}
+
+func TestStrictDecorator_UnsetVariable(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &StrictDecorator{}).
+		TestBlockDecorator(nil, []ast.CommandContent{
+			decoratortesting.Shell("echo $DEVCMD_STRICT_TEST_UNSET"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StrictDecorator unset variable test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestStrictDecorator_Disabled(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &StrictDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.BoolParam("enabled", false)}, []ast.CommandContent{
+			decoratortesting.Shell("false; true"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorCodeContains("ctx.Strict = false").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StrictDecorator disabled test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestStrictDecorator_InvalidParameters(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &StrictDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{decoratortesting.StringParam("enabled", "yes")}, []ast.CommandContent{
+			decoratortesting.Shell("true"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("").
+		GeneratorFails("").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("StrictDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
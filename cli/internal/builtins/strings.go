@@ -0,0 +1,516 @@
+package decorators
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// transformDecorator is a value decorator computed natively from its parameters, so
+// generated CLIs don't shell out to sed, tr or $(pwd). Every parameter is a string, with
+// @var(NAME) references expanded, or the name of a variable.
+type transformDecorator interface {
+	decorators.Decorator
+
+	// goExpression returns the Go expression computing the result from the Go
+	// expressions for the parameter values by name
+	goExpression(args map[string]string) string
+}
+
+// stringTransform is a transform decorator computed from its parameters alone
+type stringTransform interface {
+	transformDecorator
+
+	// transform computes the result from the parameter values by name
+	transform(args map[string]string) string
+}
+
+// stringValueParameter is the string every transform decorator takes first
+var stringValueParameter = decorators.ParameterSchema{
+	Name:        "value",
+	Type:        ast.StringType,
+	Required:    true,
+	Description: "String to transform: a variable name, or a string where @var(NAME) references are expanded",
+}
+
+// transformArgs validates a transform decorator's parameters and resolves each with
+// resolve, defaulting omitted optional parameters to empty
+func transformArgs(ctx execution.BaseContext, d decorators.Decorator, params []ast.NamedParameter, resolve func(execution.BaseContext, ast.Expression) (string, error), empty string) (map[string]string, error) {
+	schema := d.ParameterSchema()
+	if err := decorators.ValidateSchemaCompliance(params, schema, d.Name()); err != nil {
+		return nil, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, schema)
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", d.Name(), err)
+	}
+
+	args := make(map[string]string, len(schema))
+	for _, parameter := range schema {
+		args[parameter.Name] = empty
+		if param := ast.FindParameter(params, parameter.Name); param != nil {
+			value, err := resolve(ctx, param.Value)
+			if err != nil {
+				return nil, fmt.Errorf("@%s: %w", d.Name(), err)
+			}
+			args[parameter.Name] = value
+		}
+	}
+	return args, nil
+}
+
+// expandStringTransform applies a transform decorator in interpreter mode
+func expandStringTransform(ctx execution.InterpreterContext, d stringTransform, params []ast.NamedParameter) *execution.ExecutionResult {
+	args, err := transformArgs(ctx, d, params, evaluateValue, "")
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return &execution.ExecutionResult{Data: d.transform(args), Error: nil}
+}
+
+// generateTransform returns template for the Go expression of a transform decorator,
+// computed from the variables' values when the generated command runs
+func generateTransform(ctx execution.GeneratorContext, d transformDecorator, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	args, err := transformArgs(ctx, d, params, goValueExpression, `""`)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(d.Name()).Parse(`{{.}}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", d.Name(), err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data:     d.goExpression(args),
+	}, nil
+}
+
+// describeTransform returns the start of a transform decorator's plan description, the
+// decorator as written in the CLI file
+func describeTransform(d decorators.Decorator, params []ast.NamedParameter) string {
+	formatted := make([]string, 0, len(params))
+	for _, param := range params {
+		formatted = append(formatted, formatValue(param.Value))
+	}
+	return fmt.Sprintf("@%s(%s) → ", d.Name(), strings.Join(formatted, ", "))
+}
+
+// planStringTransform shows a transform decorator's result for plan mode
+func planStringTransform(ctx execution.PlanContext, d stringTransform, params []ast.NamedParameter) *execution.ExecutionResult {
+	description := describeTransform(d, params)
+	args, err := transformArgs(ctx, d, params, evaluateValue, "")
+	if err != nil {
+		return &execution.ExecutionResult{Data: description + "<undefined>", Error: nil}
+	}
+	return &execution.ExecutionResult{Data: description + strconv.Quote(d.transform(args)), Error: nil}
+}
+
+// UpperDecorator implements the @upper decorator for upper-casing strings
+type UpperDecorator struct{}
+
+// Name returns the decorator name
+func (u *UpperDecorator) Name() string {
+	return "upper"
+}
+
+// Description returns a human-readable description
+func (u *UpperDecorator) Description() string {
+	return "Convert a string to upper case"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (u *UpperDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{stringValueParameter}
+}
+
+// ExpandInterpreter returns the upper-cased string for interpreter mode
+func (u *UpperDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandStringTransform(ctx, u, params)
+}
+
+// GenerateTemplate returns template for Go code that upper-cases the string
+func (u *UpperDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, u, params)
+}
+
+// ExpandPlan returns description showing the upper-cased string for plan mode
+func (u *UpperDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planStringTransform(ctx, u, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (u *UpperDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+func (u *UpperDecorator) transform(args map[string]string) string {
+	return strings.ToUpper(args["value"])
+}
+
+func (u *UpperDecorator) goExpression(args map[string]string) string {
+	return fmt.Sprintf("strings.ToUpper(%s)", args["value"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (u *UpperDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.StringImports)
+}
+
+// LowerDecorator implements the @lower decorator for lower-casing strings
+type LowerDecorator struct{}
+
+// Name returns the decorator name
+func (l *LowerDecorator) Name() string {
+	return "lower"
+}
+
+// Description returns a human-readable description
+func (l *LowerDecorator) Description() string {
+	return "Convert a string to lower case"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (l *LowerDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{stringValueParameter}
+}
+
+// ExpandInterpreter returns the lower-cased string for interpreter mode
+func (l *LowerDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandStringTransform(ctx, l, params)
+}
+
+// GenerateTemplate returns template for Go code that lower-cases the string
+func (l *LowerDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, l, params)
+}
+
+// ExpandPlan returns description showing the lower-cased string for plan mode
+func (l *LowerDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planStringTransform(ctx, l, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (l *LowerDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+func (l *LowerDecorator) transform(args map[string]string) string {
+	return strings.ToLower(args["value"])
+}
+
+func (l *LowerDecorator) goExpression(args map[string]string) string {
+	return fmt.Sprintf("strings.ToLower(%s)", args["value"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (l *LowerDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.StringImports)
+}
+
+// TrimDecorator implements the @trim decorator for trimming whitespace or given characters
+type TrimDecorator struct{}
+
+// Name returns the decorator name
+func (t *TrimDecorator) Name() string {
+	return "trim"
+}
+
+// Description returns a human-readable description
+func (t *TrimDecorator) Description() string {
+	return "Remove leading and trailing whitespace, or the given characters, from a string"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (t *TrimDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		stringValueParameter,
+		{
+			Name:        "chars",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Characters to remove from both ends instead of whitespace",
+		},
+	}
+}
+
+// ExpandInterpreter returns the trimmed string for interpreter mode
+func (t *TrimDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandStringTransform(ctx, t, params)
+}
+
+// GenerateTemplate returns template for Go code that trims the string
+func (t *TrimDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, t, params)
+}
+
+// ExpandPlan returns description showing the trimmed string for plan mode
+func (t *TrimDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planStringTransform(ctx, t, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (t *TrimDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+func (t *TrimDecorator) transform(args map[string]string) string {
+	if args["chars"] == "" {
+		return strings.TrimSpace(args["value"])
+	}
+	return strings.Trim(args["value"], args["chars"])
+}
+
+func (t *TrimDecorator) goExpression(args map[string]string) string {
+	if args["chars"] == `""` {
+		return fmt.Sprintf("strings.TrimSpace(%s)", args["value"])
+	}
+	return fmt.Sprintf("func(value, chars string) string {\n\tif chars == \"\" {\n\t\treturn strings.TrimSpace(value)\n\t}\n\treturn strings.Trim(value, chars)\n}(%s, %s)", args["value"], args["chars"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (t *TrimDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.StringImports)
+}
+
+// ReplaceDecorator implements the @replace decorator for replacing substrings
+type ReplaceDecorator struct{}
+
+// Name returns the decorator name
+func (r *ReplaceDecorator) Name() string {
+	return "replace"
+}
+
+// Description returns a human-readable description
+func (r *ReplaceDecorator) Description() string {
+	return "Replace every occurrence of a substring in a string"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (r *ReplaceDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		stringValueParameter,
+		{
+			Name:        "old",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Substring to replace",
+		},
+		{
+			Name:        "new",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Replacement, which may be empty",
+		},
+	}
+}
+
+// ExpandInterpreter returns the string with replacements for interpreter mode
+func (r *ReplaceDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandStringTransform(ctx, r, params)
+}
+
+// GenerateTemplate returns template for Go code that replaces the substrings
+func (r *ReplaceDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, r, params)
+}
+
+// ExpandPlan returns description showing the string with replacements for plan mode
+func (r *ReplaceDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planStringTransform(ctx, r, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (r *ReplaceDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+func (r *ReplaceDecorator) transform(args map[string]string) string {
+	return strings.ReplaceAll(args["value"], args["old"], args["new"])
+}
+
+func (r *ReplaceDecorator) goExpression(args map[string]string) string {
+	return fmt.Sprintf("strings.ReplaceAll(%s, %s, %s)", args["value"], args["old"], args["new"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (r *ReplaceDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.StringImports)
+}
+
+// BasenameDecorator implements the @basename decorator for the last element of a path
+type BasenameDecorator struct{}
+
+// Name returns the decorator name
+func (b *BasenameDecorator) Name() string {
+	return "basename"
+}
+
+// Description returns a human-readable description
+func (b *BasenameDecorator) Description() string {
+	return "Last element of a path, optionally without a suffix such as a file extension"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (b *BasenameDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		stringValueParameter,
+		{
+			Name:        "suffix",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Suffix to remove from the result, e.g. \".tar.gz\", unless it is the whole name",
+		},
+	}
+}
+
+// ExpandInterpreter returns the last path element for interpreter mode
+func (b *BasenameDecorator) ExpandInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return expandStringTransform(ctx, b, params)
+}
+
+// GenerateTemplate returns template for Go code that computes the last path element
+func (b *BasenameDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return generateTransform(ctx, b, params)
+}
+
+// ExpandPlan returns description showing the last path element for plan mode
+func (b *BasenameDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedParameter) *execution.ExecutionResult {
+	return planStringTransform(ctx, b, params)
+}
+
+// ReferencedVariables returns the names of the variables the parameters read
+func (b *BasenameDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	return parameterVariableReferences(params)
+}
+
+// transform mirrors basename(1): the suffix is kept when it is the whole name
+func (b *BasenameDecorator) transform(args map[string]string) string {
+	base := filepath.Base(args["value"])
+	if suffix := args["suffix"]; suffix != "" && base != suffix {
+		return strings.TrimSuffix(base, suffix)
+	}
+	return base
+}
+
+func (b *BasenameDecorator) goExpression(args map[string]string) string {
+	if args["suffix"] == `""` {
+		return fmt.Sprintf("filepath.Base(%s)", args["value"])
+	}
+	return fmt.Sprintf("func(base, suffix string) string {\n\tif suffix != \"\" && base != suffix {\n\t\treturn strings.TrimSuffix(base, suffix)\n\t}\n\treturn base\n}(filepath.Base(%s), %s)", args["value"], args["suffix"])
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (b *BasenameDecorator) ImportRequirements() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.StringImports, []string{"path/filepath"})
+}
+
+// parameterVariableReferences returns the names of the variables decorator parameters read,
+// either by name or through @var references inside strings
+func parameterVariableReferences(params []ast.NamedParameter) []string {
+	var names []string
+	for _, param := range params {
+		switch v := param.Value.(type) {
+		case *ast.Identifier:
+			names = append(names, v.Name)
+		case *ast.StringLiteral:
+			for _, match := range variableReference.FindAllStringSubmatch(v.Value, -1) {
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names
+}
+
+// evaluateValue computes a parameter value, such as a @set value, from the variables
+// currently in the context
+func evaluateValue(ctx execution.BaseContext, value ast.Expression) (string, error) {
+	switch v := value.(type) {
+	case *ast.StringLiteral:
+		return resolveVariableReferences(ctx, v.Value)
+	case *ast.Identifier:
+		resolved, exists := ctx.GetVariable(v.Name)
+		if !exists {
+			return "", fmt.Errorf("variable '%s' not defined in .cli file", v.Name)
+		}
+		return resolved, nil
+	case *ast.NumberLiteral:
+		return v.Value, nil
+	case *ast.DurationLiteral:
+		return v.Value, nil
+	case *ast.BooleanLiteral:
+		return strconv.FormatBool(v.Value), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// goValueExpression returns the Go string expression for a parameter value, concatenating
+// the variables referenced with @var so generated code reads their current values
+func goValueExpression(ctx execution.BaseContext, value ast.Expression) (string, error) {
+	switch v := value.(type) {
+	case *ast.StringLiteral:
+		var parts []string
+		last := 0
+		for _, match := range variableReference.FindAllStringSubmatchIndex(v.Value, -1) {
+			name := v.Value[match[2]:match[3]]
+			if _, exists := ctx.GetVariable(name); !exists {
+				return "", fmt.Errorf("variable '%s' not defined in .cli file", name)
+			}
+			if match[0] > last {
+				parts = append(parts, strconv.Quote(v.Value[last:match[0]]))
+			}
+			parts = append(parts, name)
+			last = match[1]
+		}
+		if last < len(v.Value) || len(parts) == 0 {
+			parts = append(parts, strconv.Quote(v.Value[last:]))
+		}
+		return strings.Join(parts, " + "), nil
+	case *ast.Identifier:
+		if _, exists := ctx.GetVariable(v.Name); !exists {
+			return "", fmt.Errorf("variable '%s' not defined in .cli file", v.Name)
+		}
+		return v.Name, nil
+	default:
+		literal, err := evaluateValue(ctx, value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Quote(literal), nil
+	}
+}
+
+// formatValue formats a parameter value the way it's written in the CLI file
+func formatValue(value ast.Expression) string {
+	switch v := value.(type) {
+	case *ast.StringLiteral:
+		return strconv.Quote(v.Value)
+	case *ast.Identifier:
+		return v.Name
+	case *ast.NumberLiteral:
+		return v.Value
+	case *ast.DurationLiteral:
+		return v.Value
+	case *ast.BooleanLiteral:
+		return strconv.FormatBool(v.Value)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// init registers the string transform decorators
+func init() {
+	decorators.RegisterValue(&UpperDecorator{})
+	decorators.RegisterValue(&LowerDecorator{})
+	decorators.RegisterValue(&TrimDecorator{})
+	decorators.RegisterValue(&ReplaceDecorator{})
+	decorators.RegisterValue(&BasenameDecorator{})
+}
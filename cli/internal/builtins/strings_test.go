@@ -0,0 +1,197 @@
+package decorators
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestStringTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		decorator decorators.ValueDecorator
+		params    []ast.NamedParameter
+		expected  string
+		code      string
+	}{
+		{
+			name:      "upper variable",
+			decorator: &UpperDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.IdentifierParam("value", "BRANCH")},
+			expected:  "FEATURE/LOGIN-FORM",
+			code:      "strings.ToUpper(BRANCH)",
+		},
+		{
+			name:      "lower string with reference",
+			decorator: &LowerDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("value", "App-@var(BRANCH)")},
+			expected:  "app-feature/login-form",
+			code:      `strings.ToLower("App-" + BRANCH)`,
+		},
+		{
+			name:      "trim whitespace",
+			decorator: &TrimDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("value", "  v1.2.0\n")},
+			expected:  "v1.2.0",
+			code:      `strings.TrimSpace("  v1.2.0\n")`,
+		},
+		{
+			name:      "trim characters",
+			decorator: &TrimDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.StringParam("value", "/api/v1/"),
+				decoratortesting.StringParam("chars", "/"),
+			},
+			expected: "api/v1",
+			code:     `strings.Trim(value, chars)`,
+		},
+		{
+			name:      "replace",
+			decorator: &ReplaceDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.IdentifierParam("value", "BRANCH"),
+				decoratortesting.StringParam("old", "/"),
+				decoratortesting.StringParam("new", "-"),
+			},
+			expected: "feature-login-form",
+			code:     `strings.ReplaceAll(BRANCH, "/", "-")`,
+		},
+		{
+			name:      "basename",
+			decorator: &BasenameDecorator{},
+			params:    []ast.NamedParameter{decoratortesting.StringParam("value", "dist/app-linux.tar.gz")},
+			expected:  "app-linux.tar.gz",
+			code:      `filepath.Base("dist/app-linux.tar.gz")`,
+		},
+		{
+			name:      "basename without suffix",
+			decorator: &BasenameDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.StringParam("value", "dist/app-linux.tar.gz"),
+				decoratortesting.StringParam("suffix", ".tar.gz"),
+			},
+			expected: "app-linux",
+			code:     `strings.TrimSuffix(base, suffix)`,
+		},
+		{
+			name:      "basename keeps suffix that is the whole name",
+			decorator: &BasenameDecorator{},
+			params: []ast.NamedParameter{
+				decoratortesting.StringParam("value", "config/.env"),
+				decoratortesting.StringParam("suffix", ".env"),
+			},
+			expected: ".env",
+			code:     `filepath.Base("config/.env")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, tt.decorator).
+				WithVariable("BRANCH", "feature/login-form").
+				TestValueDecorator(tt.params)
+
+			errors := decoratortesting.Assert(result).
+				InterpreterSucceeds().
+				InterpreterReturns(tt.expected).
+				GeneratorSucceeds().
+				GeneratorProducesValidGo().
+				GeneratorCodeContains(tt.code).
+				PlanSucceeds().
+				Validate()
+
+			if len(errors) > 0 {
+				t.Errorf("%s test failed:\n%s", tt.decorator.Name(), decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestStringTransforms_Plan(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &ReplaceDecorator{}).
+		WithVariable("BRANCH", "feature/login-form").
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.IdentifierParam("value", "BRANCH"),
+			decoratortesting.StringParam("old", "/"),
+			decoratortesting.StringParam("new", "-"),
+		})
+
+	if plan, want := fmt.Sprint(result.PlanResult.Data), `@replace(BRANCH, "/", "-") → "feature-login-form"`; plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestStringTransforms_PositionalParameters(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &ReplaceDecorator{}).
+		WithVariable("BRANCH", "feature/login-form").
+		TestValueDecorator([]ast.NamedParameter{
+			{Value: &ast.Identifier{Name: "BRANCH"}},
+			{Value: &ast.StringLiteral{Value: "/"}},
+			{Value: &ast.StringLiteral{Value: ""}},
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("featurelogin-form").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`strings.ReplaceAll(BRANCH, "/", "")`).
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("ReplaceDecorator positional test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestStringTransforms_UndefinedVariable(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &UpperDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("value", "@var(MISSING)"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("variable 'MISSING' not defined").
+		GeneratorFails("variable 'MISSING' not defined").
+		PlanSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("UpperDecorator undefined variable test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	if plan, want := fmt.Sprint(result.PlanResult.Data), `@upper("@var(MISSING)") → <undefined>`; plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestStringTransforms_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"missing value":       nil,
+		"missing replacement": {decoratortesting.StringParam("value", "a/b"), decoratortesting.StringParam("old", "/")},
+		"unknown parameter":   {decoratortesting.StringParam("value", "a/b"), decoratortesting.StringParam("with", "-")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := decoratortesting.NewDecoratorTest(t, &ReplaceDecorator{}).TestValueDecorator(params)
+			errors := decoratortesting.Assert(result).
+				InterpreterFails("").
+				GeneratorFails("").
+				Validate()
+			if len(errors) > 0 {
+				t.Errorf("ReplaceDecorator parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
+			}
+		})
+	}
+}
+
+func TestStringTransforms_ReferencedVariables(t *testing.T) {
+	names := (&ReplaceDecorator{}).ReferencedVariables([]ast.NamedParameter{
+		decoratortesting.IdentifierParam("value", "BRANCH"),
+		decoratortesting.StringParam("old", "@var(PREFIX)/"),
+		decoratortesting.StringParam("new", ""),
+	})
+	if got, want := fmt.Sprint(names), "[BRANCH PREFIX]"; got != want {
+		t.Errorf("ReferencedVariables = %s, want %s", got, want)
+	}
+}
@@ -109,8 +109,10 @@ func (t *TimeoutDecorator) executeInterpreterImpl(ctx execution.InterpreterConte
 
 	// Execute all commands within the timeout using the utility
 	err := timeoutExecutor.Execute(func() error {
-		// Execute commands sequentially with isolated context
-		childCtx := ctx.Child()
+		// Execute commands sequentially with isolated context, under the deadline so shell
+		// steps stop with it and heartbeats can report the time left
+		childCtx, cancel := ctx.Child().WithTimeout(timeout)
+		defer cancel()
 
 		// Use CommandExecutor utility to handle all commands
 		commandExecutor := decorators.NewCommandExecutor()
@@ -183,34 +185,9 @@ func (t *TimeoutDecorator) executePlanImpl(ctx execution.PlanContext, timeout ti
 		WithDescription(description)
 
 	// Build child plan elements for each command in the timeout block
-	for _, cmd := range content {
-		switch c := cmd.(type) {
-		case *ast.ShellContent:
-			// Create plan element for shell command
-			result := ctx.GenerateShellPlan(c)
-			if result.Error != nil {
-				return &execution.ExecutionResult{
-					Data:  nil,
-					Error: fmt.Errorf("failed to create plan for shell content: %w", result.Error),
-				}
-			}
-
-			// Extract command string from result
-			if planData, ok := result.Data.(map[string]interface{}); ok {
-				if cmdStr, ok := planData["command"].(string); ok {
-					childDesc := "Execute shell command"
-					if desc, ok := planData["description"].(string); ok {
-						childDesc = desc
-					}
-					childElement := plan.Command(cmdStr).WithDescription(childDesc)
-					element = element.AddChild(childElement)
-				}
-			}
-		case *ast.BlockDecorator:
-			// For nested decorators, just add a placeholder - they will be handled by the engine
-			childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator")
-			element = element.AddChild(childElement)
-		}
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
 	}
 
 	return &execution.ExecutionResult{
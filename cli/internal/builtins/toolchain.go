@@ -0,0 +1,872 @@
+package decorators
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// toolchain describes how a block decorator finds or installs a language toolchain
+type toolchain struct {
+	Decorator   string            // Decorator name, used to prefix errors
+	Label       string            // Human-readable toolchain name, e.g. Node.js
+	Binary      string            // Executable whose version is checked
+	VersionArgs []string          // Arguments that make Binary print its version
+	Mise        string            // mise tool name
+	Asdf        string            // asdf plugin name
+	NVM         bool              // Whether nvm can provide the toolchain
+	IndexURL    string            // JSON release index for official downloads, empty if unsupported
+	ArchiveURL  string            // Official .tar.gz URL with {version}, {os}, and {arch} placeholders
+	Arch        map[string]string // Archive arch names for GOARCH values that differ
+	RootEnv     string            // Variable set to the toolchain root, e.g. GOROOT
+	Env         []cloudEnvVar     // Variables set whenever a toolchain is selected
+}
+
+// toolchainScope is a toolchain pinned to a requested version
+type toolchainScope struct {
+	*toolchain
+	Version string
+}
+
+// toolchainVersion validates requested versions: a major, major.minor, or full version
+var toolchainVersion = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// versionNumber matches the first dotted version number in a tool's version output
+var versionNumber = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// toolchainTemplate resolves the toolchain and runs the block with it first on PATH.
+// It mirrors toolchainScope.resolve so generated CLIs and the interpreter behave the same.
+const toolchainTemplate = `// Toolchain {{.Label}} {{.Version}}
+{
+	ctx := ctx.Clone()
+	binDir, source, err := func() (string, string, error) {
+		want := {{printf "%q" .Version}}
+		matches := func(version string) bool {
+			return version == want || strings.HasPrefix(version, want+".")
+		}
+		command := func(name string, args ...string) *execpkg.Cmd {
+			cmd := execpkg.Command(name, args...)
+			cmd.Dir = ctx.Dir
+			cmd.Env = os.Environ()
+			for k, v := range ctx.Env {
+				cmd.Env = append(cmd.Env, k+"="+v)
+			}
+			return cmd
+		}
+		output := func(name string, args ...string) (string, error) {
+			out, err := command(name, args...).Output()
+			if exitErr, ok := err.(*execpkg.ExitError); ok && len(exitErr.Stderr) > 0 {
+				return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return strings.TrimSpace(string(out)), err
+		}
+		install := func(name string, args ...string) error {
+			cmd := command(name, args...)
+			cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+			return cmd.Run()
+		}
+		findBin := func(root string) (string, error) {
+			for _, dir := range []string{filepath.Join(root, "bin"), filepath.Join(root, "go", "bin"), root} {
+				if _, err := os.Stat(filepath.Join(dir, {{printf "%q" .Binary}})); err == nil {
+					return dir, nil
+				}
+			}
+			return "", fmt.Errorf("%s not found in %s", {{printf "%q" .Binary}}, root)
+		}
+		compare := func(a, b string) int {
+			as, bs := strings.Split(a, "."), strings.Split(b, ".")
+			for i := 0; i < len(as) || i < len(bs); i++ {
+				var x, y int
+				if i < len(as) {
+					x, _ = strconv.Atoi(as[i])
+				}
+				if i < len(bs) {
+					y, _ = strconv.Atoi(bs[i])
+				}
+				if x != y {
+					return x - y
+				}
+			}
+			return 0
+		}
+
+		if out, err := output({{printf "%q" .Binary}}{{range .VersionArgs}}, {{printf "%q" .}}{{end}}); err == nil && matches(regexp.MustCompile(` + "`" + `\d+(\.\d+)+` + "`" + `).FindString(out)) {
+			return "", "", nil
+		}
+
+		if _, err := execpkg.LookPath("mise"); err == nil && {{printf "%q" .Mise}} != "" {
+			spec := {{printf "%q" .Mise}} + "@" + want
+			root, err := output("mise", "where", spec)
+			if err != nil {
+				if err := install("mise", "install", spec); err != nil {
+					return "", "", fmt.Errorf("mise install %s failed: %w", spec, err)
+				}
+				if root, err = output("mise", "where", spec); err != nil {
+					return "", "", fmt.Errorf("mise where %s failed: %w", spec, err)
+				}
+			}
+			dir, err := findBin(root)
+			return dir, "mise", err
+		}
+
+		if _, err := execpkg.LookPath("asdf"); err == nil && {{printf "%q" .Asdf}} != "" {
+			version, err := output("asdf", "latest", {{printf "%q" .Asdf}}, want)
+			if err != nil {
+				return "", "", fmt.Errorf("asdf could not resolve %s %s: %w", {{printf "%q" .Asdf}}, want, err)
+			}
+			root, err := output("asdf", "where", {{printf "%q" .Asdf}}, version)
+			if err != nil {
+				if err := install("asdf", "install", {{printf "%q" .Asdf}}, version); err != nil {
+					return "", "", fmt.Errorf("asdf install %s %s failed: %w", {{printf "%q" .Asdf}}, version, err)
+				}
+				if root, err = output("asdf", "where", {{printf "%q" .Asdf}}, version); err != nil {
+					return "", "", fmt.Errorf("asdf where %s %s failed: %w", {{printf "%q" .Asdf}}, version, err)
+				}
+			}
+			dir, err := findBin(root)
+			return dir, "asdf", err
+		}
+
+		nvmDir := os.Getenv("NVM_DIR")
+		if home, err := os.UserHomeDir(); nvmDir == "" && err == nil {
+			nvmDir = filepath.Join(home, ".nvm")
+		}
+		if _, err := os.Stat(filepath.Join(nvmDir, "nvm.sh")); err == nil && {{.NVM}} {
+			cmd := command("bash", "-c", ` + "`" + `. "$0" >/dev/null && nvm install "$1" >&2 && nvm which "$1"` + "`" + `, filepath.Join(nvmDir, "nvm.sh"), want)
+			cmd.Stderr = os.Stderr
+			out, err := cmd.Output()
+			if err != nil {
+				return "", "", fmt.Errorf("nvm install %s failed: %w", want, err)
+			}
+			return filepath.Dir(strings.TrimSpace(string(out))), "nvm", nil
+		}
+
+		if {{printf "%q" .IndexURL}} == "" {
+			return "", "", fmt.Errorf("%s %s is not installed and no version manager was found (install mise or asdf)", {{printf "%q" .Label}}, want)
+		}
+		version := want
+		if strings.Count(want, ".") < 2 {
+			resp, err := http.Get({{printf "%q" .IndexURL}})
+			if err != nil {
+				return "", "", fmt.Errorf("failed to fetch release index: %w", err)
+			}
+			var releases []struct {
+				Version string ` + "`" + `json:"version"` + "`" + `
+				Stable  *bool  ` + "`" + `json:"stable"` + "`" + `
+			}
+			err = json.NewDecoder(resp.Body).Decode(&releases)
+			resp.Body.Close()
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read release index: %w", err)
+			}
+			version = ""
+			for _, release := range releases {
+				candidate := strings.TrimLeft(release.Version, "gov")
+				if (release.Stable == nil || *release.Stable) && matches(candidate) && (version == "" || compare(candidate, version) > 0) {
+					version = candidate
+				}
+			}
+			if version == "" {
+				return "", "", fmt.Errorf("no %s release matches %s", {{printf "%q" .Label}}, want)
+			}
+		}
+
+		arch := runtime.GOARCH
+		if name, ok := map[string]string{ {{range $goarch, $name := .Arch}}{{printf "%q" $goarch}}: {{printf "%q" $name}}, {{end}}}[arch]; ok {
+			arch = name
+		}
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", "", err
+		}
+		root := filepath.Join(cacheDir, "devcmd", "toolchains", fmt.Sprintf("%s-%s-%s-%s", {{printf "%q" .Decorator}}, version, runtime.GOOS, arch))
+		if dir, err := findBin(root); err == nil {
+			return dir, "the devcmd cache", nil
+		}
+		if runtime.GOOS == "windows" {
+			return "", "", fmt.Errorf("%s %s is not installed and downloads are not supported on windows (install mise or asdf)", {{printf "%q" .Label}}, want)
+		}
+
+		archiveURL := strings.NewReplacer("{version}", version, "{os}", runtime.GOOS, "{arch}", arch).Replace({{printf "%q" .ArchiveURL}})
+		logf("info", "@{{.Decorator}}", "downloading %s", archiveURL)
+		resp, err := http.Get(archiveURL)
+		if err != nil {
+			return "", "", fmt.Errorf("download failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", "", fmt.Errorf("download of %s failed: %s", archiveURL, resp.Status)
+		}
+		if err := os.MkdirAll(filepath.Dir(root), 0o755); err != nil {
+			return "", "", err
+		}
+		tmp, err := os.MkdirTemp(filepath.Dir(root), ".download-")
+		if err != nil {
+			return "", "", err
+		}
+		defer os.RemoveAll(tmp)
+
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid archive %s: %w", archiveURL, err)
+		}
+		archive := tar.NewReader(gz)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("invalid archive %s: %w", archiveURL, err)
+			}
+			// Strip the archive's top-level directory
+			_, name, _ := strings.Cut(header.Name, "/")
+			target := filepath.Join(tmp, name)
+			if name == "" || !strings.HasPrefix(target, tmp+string(os.PathSeparator)) {
+				continue
+			}
+			switch header.Typeflag {
+			case tar.TypeDir:
+				err = os.MkdirAll(target, 0o755)
+			case tar.TypeSymlink:
+				err = os.Symlink(header.Linkname, target)
+			case tar.TypeReg:
+				if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+					var file *os.File
+					if file, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0o777); err == nil {
+						_, err = io.Copy(file, archive)
+						file.Close()
+					}
+				}
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+		if err := os.Rename(tmp, root); err != nil {
+			// Another process may have installed the same toolchain concurrently
+			if _, statErr := os.Stat(root); statErr != nil {
+				return "", "", err
+			}
+		}
+		dir, err := findBin(root)
+		return dir, "the devcmd cache", err
+	}()
+	if err != nil {
+		return fmt.Errorf("@{{.Decorator}}: %w", err)
+	}
+	if binDir != "" {
+		logf("info", "@{{.Decorator}}", "using %s %s from %s", {{printf "%q" .Label}}, {{printf "%q" .Version}}, source)
+		path, ok := ctx.Env["PATH"]
+		if !ok {
+			path = os.Getenv("PATH")
+		}
+		ctx.Env["PATH"] = binDir + string(os.PathListSeparator) + path
+{{if .RootEnv}}		ctx.Env[{{printf "%q" .RootEnv}}] = filepath.Dir(binDir)
+{{end}}{{range .Env}}		ctx.Env[{{printf "%q" .Name}}] = {{printf "%q" .Value}}
+{{end}}	}
+{{range .Content}}	{{. | buildCommand}}
+{{end}}}`
+
+// executeToolchainScope resolves the toolchain and runs the block with it first on PATH
+func executeToolchainScope(ctx execution.InterpreterContext, scope *toolchainScope, content []ast.CommandContent) *execution.ExecutionResult {
+	scopeCtx := ctx.Child()
+
+	binDir, source, err := scope.resolve(scopeCtx)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	if binDir != "" {
+		scopeCtx.Logger().Infof("@"+scope.Decorator, "using %s %s from %s", scope.Label, scope.Version, source)
+		path, _ := scopeCtx.GetEnv("PATH")
+		scopeCtx.ExportEnv("PATH", binDir+string(os.PathListSeparator)+path)
+		if scope.RootEnv != "" {
+			scopeCtx.ExportEnv(scope.RootEnv, filepath.Dir(binDir))
+		}
+		for _, v := range scope.Env {
+			scopeCtx.ExportEnv(v.Name, v.Value)
+		}
+	}
+
+	commandExecutor := decorators.NewCommandExecutor()
+	defer commandExecutor.Cleanup()
+
+	return &execution.ExecutionResult{
+		Data:  nil,
+		Error: commandExecutor.ExecuteCommandsWithInterpreter(scopeCtx, content),
+	}
+}
+
+// resolve finds the requested version and returns the directory containing its
+// binaries and where it came from. An empty directory means the toolchain already
+// on PATH matches. It tries mise, asdf, nvm, and finally an official download.
+func (s *toolchainScope) resolve(ctx execution.InterpreterContext) (string, string, error) {
+	if out, err := s.output(ctx, s.Binary, s.VersionArgs...); err == nil && s.matches(versionNumber.FindString(out)) {
+		return "", "", nil
+	}
+
+	if _, err := exec.LookPath("mise"); err == nil && s.Mise != "" {
+		spec := s.Mise + "@" + s.Version
+		root, err := s.output(ctx, "mise", "where", spec)
+		if err != nil {
+			if err := s.install(ctx, "mise", "install", spec); err != nil {
+				return "", "", fmt.Errorf("mise install %s failed: %w", spec, err)
+			}
+			if root, err = s.output(ctx, "mise", "where", spec); err != nil {
+				return "", "", fmt.Errorf("mise where %s failed: %w", spec, err)
+			}
+		}
+		dir, err := s.findBin(root)
+		return dir, "mise", err
+	}
+
+	if _, err := exec.LookPath("asdf"); err == nil && s.Asdf != "" {
+		version, err := s.output(ctx, "asdf", "latest", s.Asdf, s.Version)
+		if err != nil {
+			return "", "", fmt.Errorf("asdf could not resolve %s %s: %w", s.Asdf, s.Version, err)
+		}
+		root, err := s.output(ctx, "asdf", "where", s.Asdf, version)
+		if err != nil {
+			if err := s.install(ctx, "asdf", "install", s.Asdf, version); err != nil {
+				return "", "", fmt.Errorf("asdf install %s %s failed: %w", s.Asdf, version, err)
+			}
+			if root, err = s.output(ctx, "asdf", "where", s.Asdf, version); err != nil {
+				return "", "", fmt.Errorf("asdf where %s %s failed: %w", s.Asdf, version, err)
+			}
+		}
+		dir, err := s.findBin(root)
+		return dir, "asdf", err
+	}
+
+	if nvmScript := nvmScriptPath(ctx); s.NVM && nvmScript != "" {
+		// nvm is a shell function, so it has to be sourced and run through bash
+		cmd := s.command(ctx, "bash", "-c", `. "$0" >/dev/null && nvm install "$1" >&2 && nvm which "$1"`, nvmScript, s.Version)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", "", fmt.Errorf("nvm install %s failed: %w", s.Version, err)
+		}
+		return filepath.Dir(strings.TrimSpace(string(out))), "nvm", nil
+	}
+
+	if s.IndexURL == "" {
+		return "", "", fmt.Errorf("%s %s is not installed and no version manager was found (install mise or asdf)", s.Label, s.Version)
+	}
+	dir, err := s.download(ctx)
+	return dir, "the devcmd cache", err
+}
+
+// download installs an official release into the devcmd cache, reusing earlier downloads
+func (s *toolchainScope) download(ctx execution.InterpreterContext) (string, error) {
+	version := s.Version
+	if strings.Count(version, ".") < 2 {
+		latest, err := s.latestRelease(ctx)
+		if err != nil {
+			return "", err
+		}
+		version = latest
+	}
+
+	arch := runtime.GOARCH
+	if name, ok := s.Arch[arch]; ok {
+		arch = name
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(cacheDir, "devcmd", "toolchains", fmt.Sprintf("%s-%s-%s-%s", s.Decorator, version, runtime.GOOS, arch))
+	if dir, err := s.findBin(root); err == nil {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" {
+		return "", fmt.Errorf("%s %s is not installed and downloads are not supported on windows (install mise or asdf)", s.Label, s.Version)
+	}
+
+	archiveURL := strings.NewReplacer("{version}", version, "{os}", runtime.GOOS, "{arch}", arch).Replace(s.ArchiveURL)
+	ctx.Logger().Infof("@"+s.Decorator, "downloading %s", archiveURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download of %s failed: %s", archiveURL, resp.Status)
+	}
+
+	// Extract next to the final location and rename, so interrupted downloads are never used
+	if err := os.MkdirAll(filepath.Dir(root), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(root), ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extractTarGz(resp.Body, tmp); err != nil {
+		return "", fmt.Errorf("invalid archive %s: %w", archiveURL, err)
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		// Another process may have installed the same toolchain concurrently
+		if _, statErr := os.Stat(root); statErr != nil {
+			return "", err
+		}
+	}
+	return s.findBin(root)
+}
+
+// latestRelease returns the newest stable release in the index matching the requested version
+func (s *toolchainScope) latestRelease(ctx execution.InterpreterContext) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.IndexURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch release index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var releases []struct {
+		Version string `json:"version"`
+		Stable  *bool  `json:"stable"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", fmt.Errorf("failed to read release index: %w", err)
+	}
+
+	version := ""
+	for _, release := range releases {
+		// Indexes prefix versions with "go" or "v"
+		candidate := strings.TrimLeft(release.Version, "gov")
+		if (release.Stable == nil || *release.Stable) && s.matches(candidate) && (version == "" || compareVersions(candidate, version) > 0) {
+			version = candidate
+		}
+	}
+	if version == "" {
+		return "", fmt.Errorf("no %s release matches %s", s.Label, s.Version)
+	}
+	return version, nil
+}
+
+// matches reports whether version satisfies the requested version, e.g. 1.24.3 satisfies 1.24
+func (s *toolchainScope) matches(version string) bool {
+	return version == s.Version || strings.HasPrefix(version, s.Version+".")
+}
+
+// findBin returns the directory under root containing the toolchain's binary
+func (s *toolchainScope) findBin(root string) (string, error) {
+	// asdf's golang plugin nests the toolchain in a go/ directory
+	for _, dir := range []string{filepath.Join(root, "bin"), filepath.Join(root, "go", "bin"), root} {
+		if _, err := os.Stat(filepath.Join(dir, s.Binary)); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", s.Binary, root)
+}
+
+// command builds a command running in the block's working directory and environment
+func (s *toolchainScope) command(ctx execution.InterpreterContext, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = ctx.GetWorkingDir()
+	if path, ok := ctx.GetEnv("PATH"); ok {
+		cmd.Env = append(os.Environ(), "PATH="+path)
+	}
+	return cmd
+}
+
+// output runs a command and returns its trimmed stdout, or its stderr as the error
+func (s *toolchainScope) output(ctx execution.InterpreterContext, name string, args ...string) (string, error) {
+	out, err := s.command(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// install runs an installer, showing its progress on stderr
+func (s *toolchainScope) install(ctx execution.InterpreterContext, name string, args ...string) error {
+	cmd := s.command(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// nvmScriptPath returns the nvm.sh to source, or "" when nvm isn't installed
+func nvmScriptPath(ctx execution.InterpreterContext) string {
+	nvmDir, _ := ctx.GetEnv("NVM_DIR")
+	if home, err := os.UserHomeDir(); nvmDir == "" && err == nil {
+		nvmDir = filepath.Join(home, ".nvm")
+	}
+	script := filepath.Join(nvmDir, "nvm.sh")
+	if _, err := os.Stat(script); err != nil {
+		return ""
+	}
+	return script
+}
+
+// extractTarGz extracts a .tar.gz archive into dest, stripping its top-level directory
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, name, _ := strings.Cut(header.Name, "/")
+		target := filepath.Join(dest, name)
+		// Skip the top-level directory itself and entries that would escape dest
+		if name == "" || !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, target)
+		case tar.TypeReg:
+			err = writeArchiveFile(archive, target, os.FileMode(header.Mode)&0o777)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+	}
+}
+
+// writeArchiveFile writes one regular file from an archive
+func writeArchiveFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, r)
+	return err
+}
+
+// compareVersions compares dotted version numbers numerically
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// generateToolchainTemplate returns the block template for a toolchain scope
+func generateToolchainTemplate(ctx execution.GeneratorContext, scope *toolchainScope, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	tmpl, err := template.New(scope.Decorator).Funcs(ctx.GetTemplateFunctions()).Parse(toolchainTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", scope.Decorator, err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			*toolchainScope
+			Content []ast.CommandContent
+		}{
+			toolchainScope: scope,
+			Content:        content,
+		},
+	}, nil
+}
+
+// planToolchainScope creates a plan element for the toolchain without resolving it
+func planToolchainScope(ctx execution.PlanContext, scope *toolchainScope, content []ast.CommandContent) *execution.ExecutionResult {
+	sources := []string{"mise", "asdf"}
+	if scope.NVM {
+		sources = append(sources, "nvm")
+	}
+	if scope.IndexURL != "" {
+		sources = append(sources, "official download")
+	}
+
+	element := plan.Decorator(scope.Decorator).
+		WithType("block").
+		WithParameter("version", scope.Version).
+		WithDescription(fmt.Sprintf("Use %s %s (via %s)", scope.Label, scope.Version, strings.Join(sources, ", ")))
+
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// toolchainParameterSchema returns the parameters shared by toolchain decorators
+func toolchainParameterSchema(tc *toolchain, example string) []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "version",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: fmt.Sprintf("%s version, e.g. %q; a partial version selects the newest matching release", tc.Label, example),
+		},
+	}
+}
+
+// extractToolchainScope validates parameters and pins the toolchain to the requested version
+func extractToolchainScope(tc *toolchain, params []ast.NamedParameter, schema []decorators.ParameterSchema) (*toolchainScope, error) {
+	if err := decorators.ValidateSchemaCompliance(params, schema, tc.Decorator); err != nil {
+		return nil, err
+	}
+	params, err := decorators.ResolvePositionalParameters(params, schema)
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", tc.Decorator, err)
+	}
+
+	version := strings.TrimLeft(ast.GetStringParam(params, "version", ""), "v")
+	if !toolchainVersion.MatchString(version) {
+		return nil, fmt.Errorf("@%s: invalid version %q, expected a version like \"1.2\" or \"1.2.3\"", tc.Decorator, ast.GetStringParam(params, "version", ""))
+	}
+
+	return &toolchainScope{toolchain: tc, Version: version}, nil
+}
+
+// toolchainImports returns the imports needed by toolchainTemplate
+func toolchainImports() decorators.ImportRequirement {
+	return decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports,
+		[]string{"archive/tar", "compress/gzip", "encoding/json", "io", "net/http", "os/exec", "path/filepath", "regexp", "runtime", "strconv"})
+}
+
+// goToolchain installs official Go releases from go.dev
+var goToolchain = &toolchain{
+	Decorator:   "go",
+	Label:       "Go",
+	Binary:      "go",
+	VersionArgs: []string{"version"},
+	Mise:        "go",
+	Asdf:        "golang",
+	IndexURL:    "https://go.dev/dl/?mode=json&include=all",
+	ArchiveURL:  "https://go.dev/dl/go{version}.{os}-{arch}.tar.gz",
+	RootEnv:     "GOROOT",
+	// Stop the go command from switching to a different toolchain from go.mod
+	Env: []cloudEnvVar{{Name: "GOTOOLCHAIN", Value: "local"}},
+}
+
+// nodeToolchain installs official Node.js releases from nodejs.org
+var nodeToolchain = &toolchain{
+	Decorator:   "node",
+	Label:       "Node.js",
+	Binary:      "node",
+	VersionArgs: []string{"--version"},
+	Mise:        "node",
+	Asdf:        "nodejs",
+	NVM:         true,
+	IndexURL:    "https://nodejs.org/dist/index.json",
+	ArchiveURL:  "https://nodejs.org/dist/v{version}/node-v{version}-{os}-{arch}.tar.gz",
+	Arch:        map[string]string{"amd64": "x64"},
+}
+
+// pythonToolchain has no portable official builds, so it requires mise or asdf
+var pythonToolchain = &toolchain{
+	Decorator:   "python",
+	Label:       "Python",
+	Binary:      "python3",
+	VersionArgs: []string{"--version"},
+	Mise:        "python",
+	Asdf:        "python",
+}
+
+// GoDecorator implements the @go decorator for pinning the Go toolchain
+type GoDecorator struct{}
+
+// Name returns the decorator name
+func (g *GoDecorator) Name() string {
+	return "go"
+}
+
+// Description returns a human-readable description
+func (g *GoDecorator) Description() string {
+	return "Run the block with a specific Go version from mise, asdf, or an official download"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (g *GoDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return toolchainParameterSchema(goToolchain, "1.24")
+}
+
+// ExecuteInterpreter runs the block with the Go toolchain in interpreter mode
+func (g *GoDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(goToolchain, params, g.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return executeToolchainScope(ctx, scope, content)
+}
+
+// GenerateTemplate generates template for running the block with the Go toolchain
+func (g *GoDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	scope, err := extractToolchainScope(goToolchain, params, g.ParameterSchema())
+	if err != nil {
+		return nil, err
+	}
+	return generateToolchainTemplate(ctx, scope, content)
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (g *GoDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(goToolchain, params, g.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return planToolchainScope(ctx, scope, content)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (g *GoDecorator) ImportRequirements() decorators.ImportRequirement {
+	return toolchainImports()
+}
+
+// NodeDecorator implements the @node decorator for pinning the Node.js toolchain
+type NodeDecorator struct{}
+
+// Name returns the decorator name
+func (n *NodeDecorator) Name() string {
+	return "node"
+}
+
+// Description returns a human-readable description
+func (n *NodeDecorator) Description() string {
+	return "Run the block with a specific Node.js version from mise, asdf, nvm, or an official download"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (n *NodeDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return toolchainParameterSchema(nodeToolchain, "22")
+}
+
+// ExecuteInterpreter runs the block with the Node.js toolchain in interpreter mode
+func (n *NodeDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(nodeToolchain, params, n.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return executeToolchainScope(ctx, scope, content)
+}
+
+// GenerateTemplate generates template for running the block with the Node.js toolchain
+func (n *NodeDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	scope, err := extractToolchainScope(nodeToolchain, params, n.ParameterSchema())
+	if err != nil {
+		return nil, err
+	}
+	return generateToolchainTemplate(ctx, scope, content)
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (n *NodeDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(nodeToolchain, params, n.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return planToolchainScope(ctx, scope, content)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (n *NodeDecorator) ImportRequirements() decorators.ImportRequirement {
+	return toolchainImports()
+}
+
+// PythonDecorator implements the @python decorator for pinning the Python toolchain
+type PythonDecorator struct{}
+
+// Name returns the decorator name
+func (p *PythonDecorator) Name() string {
+	return "python"
+}
+
+// Description returns a human-readable description
+func (p *PythonDecorator) Description() string {
+	return "Run the block with a specific Python version from mise or asdf"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (p *PythonDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return toolchainParameterSchema(pythonToolchain, "3.12")
+}
+
+// ExecuteInterpreter runs the block with the Python toolchain in interpreter mode
+func (p *PythonDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(pythonToolchain, params, p.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return executeToolchainScope(ctx, scope, content)
+}
+
+// GenerateTemplate generates template for running the block with the Python toolchain
+func (p *PythonDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	scope, err := extractToolchainScope(pythonToolchain, params, p.ParameterSchema())
+	if err != nil {
+		return nil, err
+	}
+	return generateToolchainTemplate(ctx, scope, content)
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (p *PythonDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	scope, err := extractToolchainScope(pythonToolchain, params, p.ParameterSchema())
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+	return planToolchainScope(ctx, scope, content)
+}
+
+// ImportRequirements returns the dependencies needed for code generation
+func (p *PythonDecorator) ImportRequirements() decorators.ImportRequirement {
+	return toolchainImports()
+}
+
+// init registers the toolchain decorators
+func init() {
+	decorators.RegisterBlock(&GoDecorator{})
+	decorators.RegisterBlock(&NodeDecorator{})
+	decorators.RegisterBlock(&PythonDecorator{})
+}
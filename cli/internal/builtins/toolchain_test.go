@@ -0,0 +1,260 @@
+package decorators
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// isolateToolchainPath limits PATH to the shell so real version managers aren't used
+func isolateToolchainPath(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, tool := range []string{"sh", "cat", "touch", "mkdir", "chmod"} {
+		if path, err := lookPathIn("/usr/bin:/bin", tool); err == nil {
+			if err := os.Symlink(path, filepath.Join(dir, tool)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("NVM_DIR", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+// lookPathIn finds an executable in a specific PATH
+func lookPathIn(path, name string) (string, error) {
+	for _, dir := range filepath.SplitList(path) {
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func TestNodeDecorator_ActiveVersionMatches(t *testing.T) {
+	isolateToolchainPath(t)
+	installFakeCLI(t, "node", `echo v22.3.0`)
+
+	out := filepath.Join(t.TempDir(), "version.txt")
+	result := decoratortesting.NewDecoratorTest(t, &NodeDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("version", "22"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("node > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`want := "22"`, `"https://nodejs.org/dist/index.json"`).
+		PlanSucceeds().
+		PlanReturnsElement("decorator").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("NodeDecorator active version test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "v22.3.0" {
+		t.Errorf("block used node %q, want the active v22.3.0", strings.TrimSpace(string(got)))
+	}
+}
+
+func TestNodeDecorator_InstallsWithMise(t *testing.T) {
+	isolateToolchainPath(t)
+	installFakeCLI(t, "node", `echo v18.0.0`)
+
+	root := filepath.Join(t.TempDir(), "node-22.11.0")
+	installFakeCLI(t, "mise", `
+case "$1" in
+where) [ -d `+root+` ] && echo `+root+` || { echo "node@$2 is not installed" >&2; exit 1; } ;;
+install) mkdir -p `+root+`/bin && printf '#!/bin/sh\necho v22.11.0\n' > `+root+`/bin/node && chmod +x `+root+`/bin/node ;;
+esac`)
+
+	out := filepath.Join(t.TempDir(), "version.txt")
+	result := decoratortesting.NewDecoratorTest(t, &NodeDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			{Value: &ast.StringLiteral{Value: "22"}},
+		}, []ast.CommandContent{
+			decoratortesting.Shell("node > " + out),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("NodeDecorator mise test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "v22.11.0" {
+		t.Errorf("block used node %q, want v22.11.0 from mise", strings.TrimSpace(string(got)))
+	}
+}
+
+func TestGoToolchain_DownloadsOfficialRelease(t *testing.T) {
+	isolateToolchainPath(t)
+
+	archive := tarGz(t, map[string]string{
+		"go/bin/go":           "#!/bin/sh\necho go version go1.99.2\n",
+		"go/VERSION":          "go1.99.2\n",
+		"go/../../escape.txt": "outside\n",
+	})
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			w.Write([]byte(`[{"version":"go1.100rc1","stable":false},{"version":"go1.99.2","stable":true},{"version":"go1.99.1","stable":true}]`))
+		case "/go1.99.2.tar.gz":
+			downloads.Add(1)
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tc := *goToolchain
+	tc.IndexURL = server.URL + "/index.json"
+	tc.ArchiveURL = server.URL + "/go{version}.tar.gz"
+	scope := &toolchainScope{toolchain: &tc, Version: "1.99"}
+
+	out := filepath.Join(t.TempDir(), "env.txt")
+	ctx := execution.NewInterpreterContext(context.Background(), &ast.Program{})
+	result := executeToolchainScope(ctx, scope, []ast.CommandContent{
+		decoratortesting.Shell(`go > ` + out + ` && echo "$GOROOT $GOTOOLCHAIN" >> ` + out),
+	})
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("block did not run: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(got)), "\n")
+	if len(lines) != 2 || lines[0] != "go version go1.99.2" {
+		t.Fatalf("block output = %q, want the downloaded go1.99.2", got)
+	}
+	if !strings.HasSuffix(lines[1], "toolchains/go-1.99.2-"+runtime.GOOS+"-"+runtime.GOARCH+" local") {
+		t.Errorf("GOROOT/GOTOOLCHAIN = %q, want the cached toolchain and local", lines[1])
+	}
+
+	// The cached toolchain is reused without downloading again
+	if _, _, err := scope.resolve(ctx); err != nil {
+		t.Fatalf("second resolve failed: %v", err)
+	}
+	if downloads.Load() != 1 {
+		t.Errorf("downloaded %d times, want 1", downloads.Load())
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("XDG_CACHE_HOME"), "devcmd", "escape.txt")); err == nil {
+		t.Error("archive entries escaped the toolchain directory")
+	}
+}
+
+func TestPythonDecorator_RequiresVersionManager(t *testing.T) {
+	isolateToolchainPath(t)
+
+	result := decoratortesting.NewDecoratorTest(t, &PythonDecorator{}).
+		TestBlockDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("version", "3.12"),
+		}, []ast.CommandContent{
+			decoratortesting.Shell("echo should not run"),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterFails("Python 3.12 is not installed and no version manager was found").
+		GeneratorSucceeds().
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("PythonDecorator missing manager test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestToolchainDecorators_InvalidVersion(t *testing.T) {
+	params := []ast.NamedParameter{decoratortesting.StringParam("version", "latest")}
+	results := map[string]decoratortesting.ValidationResult{
+		"go":     decoratortesting.NewDecoratorTest(t, &GoDecorator{}).TestBlockDecorator(params, []ast.CommandContent{}),
+		"node":   decoratortesting.NewDecoratorTest(t, &NodeDecorator{}).TestBlockDecorator(params, []ast.CommandContent{}),
+		"python": decoratortesting.NewDecoratorTest(t, &PythonDecorator{}).TestBlockDecorator(params, []ast.CommandContent{}),
+	}
+
+	for name, result := range results {
+		errors := decoratortesting.Assert(result).
+			InterpreterFails("invalid version").
+			GeneratorFails("invalid version").
+			PlanFails("invalid version").
+			Validate()
+
+		if len(errors) > 0 {
+			t.Errorf("@%s invalid version test failed:\n%s", name, decoratortesting.JoinErrors(errors))
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.24.3", "1.24.3", 0},
+		{"1.24.10", "1.24.9", 1},
+		{"1.9", "1.24", -1},
+		{"22", "22.0.0", 0},
+	}
+
+	for _, tc := range testCases {
+		got := compareVersions(tc.a, tc.b)
+		if (got > 0) != (tc.want > 0) || (got < 0) != (tc.want < 0) {
+			t.Errorf("compareVersions(%q, %q) = %d, want sign of %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// tarGz builds an in-memory .tar.gz archive of executable files
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -233,102 +233,36 @@ func (t *TryDecorator) executePlanImpl(ctx execution.PlanContext, mainBranch, ca
 
 	// Add main commands directly as children (always executed first)
 	if mainBranch != nil {
-		for _, cmd := range mainBranch.Commands {
-			switch c := cmd.(type) {
-			case *ast.ShellContent:
-				result := ctx.GenerateShellPlan(c)
-				if result.Error != nil {
-					return &execution.ExecutionResult{
-						Data:  nil,
-						Error: fmt.Errorf("failed to create plan for main command: %w", result.Error),
-					}
-				}
-				if childPlan, ok := result.Data.(*plan.ExecutionStep); ok {
-					// Convert ExecutionStep to a Command element for the plan
-					cmdElement := plan.Command(childPlan.Command).WithDescription(childPlan.Description)
-					element = element.AddChild(cmdElement)
-				}
-			case *ast.BlockDecorator:
-				// For nested decorators, create a plan element
-				childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator")
-				element = element.AddChild(childElement)
-			default:
-				// Unknown command type
-				childElement := plan.Command(fmt.Sprintf("Unknown command: %T", cmd)).WithDescription("Unsupported command")
-				element = element.AddChild(childElement)
-			}
+		children, err := contentPlans(ctx, mainBranch.Commands)
+		if err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("main: %w", err)}
 		}
+		element = element.WithChildren(children...)
 	}
 
 	// Add catch block as a conditional child (executed only on error)
 	if catchBranch != nil {
-		// Create a conditional element for the catch block
-		catchElement := plan.Decorator("[on error]").WithType("conditional").WithDescription("Executed only if main block fails")
-
-		// Add catch commands as children of the conditional element
-		for _, cmd := range catchBranch.Commands {
-			switch c := cmd.(type) {
-			case *ast.ShellContent:
-				result := ctx.GenerateShellPlan(c)
-				if result.Error != nil {
-					return &execution.ExecutionResult{
-						Data:  nil,
-						Error: fmt.Errorf("failed to create plan for catch command: %w", result.Error),
-					}
-				}
-				if childPlan, ok := result.Data.(*plan.ExecutionStep); ok {
-					// Convert ExecutionStep to a Command element for the plan
-					cmdElement := plan.Command(childPlan.Command).WithDescription(childPlan.Description)
-					catchElement = catchElement.AddChild(cmdElement)
-				}
-			case *ast.BlockDecorator:
-				// For nested decorators in catch
-				childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator in catch")
-				catchElement = catchElement.AddChild(childElement)
-			default:
-				// Unknown command type in catch
-				childElement := plan.Command(fmt.Sprintf("Unknown command: %T", cmd)).WithDescription("Unsupported command in catch")
-				catchElement = catchElement.AddChild(childElement)
-			}
+		children, err := contentPlans(ctx, catchBranch.Commands)
+		if err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("catch: %w", err)}
 		}
-
-		// Add the catch element to the main try element
+		catchElement := plan.Decorator("[on error]").
+			WithType("conditional").
+			WithDescription("Executed only if main block fails").
+			WithChildren(children...)
 		element = element.AddChild(catchElement)
 	}
 
 	// Add finally block as an always-executed child
 	if finallyBranch != nil {
-		// Create an element for the finally block
-		finallyElement := plan.Decorator("[always]").WithType("block").WithDescription("Always executed regardless of success/failure")
-
-		// Add finally commands as children of the finally element
-		for _, cmd := range finallyBranch.Commands {
-			switch c := cmd.(type) {
-			case *ast.ShellContent:
-				result := ctx.GenerateShellPlan(c)
-				if result.Error != nil {
-					return &execution.ExecutionResult{
-						Data:  nil,
-						Error: fmt.Errorf("failed to create plan for finally command: %w", result.Error),
-					}
-				}
-				if childPlan, ok := result.Data.(*plan.ExecutionStep); ok {
-					// Convert ExecutionStep to a Command element for the plan
-					cmdElement := plan.Command(childPlan.Command).WithDescription(childPlan.Description)
-					finallyElement = finallyElement.AddChild(cmdElement)
-				}
-			case *ast.BlockDecorator:
-				// For nested decorators in finally
-				childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator in finally")
-				finallyElement = finallyElement.AddChild(childElement)
-			default:
-				// Unknown command type in finally
-				childElement := plan.Command(fmt.Sprintf("Unknown command: %T", cmd)).WithDescription("Unsupported command in finally")
-				finallyElement = finallyElement.AddChild(childElement)
-			}
+		children, err := contentPlans(ctx, finallyBranch.Commands)
+		if err != nil {
+			return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("finally: %w", err)}
 		}
-
-		// Add the finally element to the main try element
+		finallyElement := plan.Decorator("[always]").
+			WithType("block").
+			WithDescription("Always executed regardless of success/failure").
+			WithChildren(children...)
 		element = element.AddChild(finallyElement)
 	}
 
@@ -31,6 +31,12 @@ func (v *VarDecorator) ParameterSchema() []decorators.ParameterSchema {
 			Required:    true,
 			Description: "Variable name to reference",
 		},
+		{
+			Name:        "raw",
+			Type:        ast.BooleanType,
+			Required:    false,
+			Description: "If true, the value is inserted as shell syntax instead of being quoted",
+		},
 	}
 }
 
@@ -111,8 +117,14 @@ func (v *VarDecorator) ExpandPlan(ctx execution.PlanContext, params []ast.NamedP
 
 // extractVariableName extracts the variable name from decorator parameters
 func (v *VarDecorator) extractVariableName(params []ast.NamedParameter) (string, error) {
-	// Use centralized validation
-	if err := decorators.ValidateParameterCount(params, 1, 1, "var"); err != nil {
+	// Use centralized validation, the optional raw flag aside
+	var nameParams []ast.NamedParameter
+	for _, param := range params {
+		if param.Name != "raw" {
+			nameParams = append(nameParams, param)
+		}
+	}
+	if err := decorators.ValidateParameterCount(nameParams, 1, 1, "var"); err != nil {
 		return "", err
 	}
 
@@ -124,9 +136,9 @@ func (v *VarDecorator) extractVariableName(params []ast.NamedParameter) (string,
 	// Parse parameters (validation passed, so these should be safe)
 	// Try to get the "name" parameter first
 	nameParam := ast.FindParameter(params, "name")
-	if nameParam == nil && len(params) > 0 {
+	if nameParam == nil {
 		// Fallback to first parameter if no "name" parameter
-		nameParam = &params[0]
+		nameParam = &nameParams[0]
 	}
 
 	if nameParam != nil {
@@ -138,6 +150,12 @@ func (v *VarDecorator) extractVariableName(params []ast.NamedParameter) (string,
 	return "", fmt.Errorf("@var decorator requires a valid identifier parameter")
 }
 
+// QuotesShellValue reports whether the variable's value is quoted in shell commands, so
+// spaces and quotes in it don't split or break the command
+func (v *VarDecorator) QuotesShellValue(params []ast.NamedParameter) bool {
+	return !ast.GetBoolParam(params, "raw", false)
+}
+
 // ImportRequirements returns the dependencies needed for code generation
 func (v *VarDecorator) ImportRequirements() decorators.ImportRequirement {
 	return decorators.ImportRequirement{
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
 	decoratortesting "github.com/aledsdavies/devcmd/testing"
 )
 
@@ -113,3 +114,57 @@ func TestVarDecorator_NoParameter(t *testing.T) {
 		t.Errorf("VarDecorator no parameter test failed:\n%s", decoratortesting.JoinErrors(errors))
 	}
 }
+
+func TestVarDecorator_ShellQuoting(t *testing.T) {
+	decorator := &VarDecorator{}
+
+	if !decorator.QuotesShellValue([]ast.NamedParameter{decoratortesting.IdentifierParam("", "MY_VAR")}) {
+		t.Errorf("@var values should be quoted for the shell by default")
+	}
+	if decorator.QuotesShellValue([]ast.NamedParameter{
+		decoratortesting.IdentifierParam("", "MY_VAR"),
+		decoratortesting.BoolParam("raw", true),
+	}) {
+		t.Errorf("@var values should not be quoted with raw = true")
+	}
+
+	// The raw flag doesn't count towards the variable name
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		WithVariable("FLAGS", "-v -race").
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.IdentifierParam("", "FLAGS"),
+			decoratortesting.BoolParam("raw", true),
+		})
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("-v -race").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("VarDecorator raw test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
+
+func TestQuoteShellValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		quote    byte
+		expected string
+	}{
+		{"v1.2.3", 0, "v1.2.3"},
+		{"", 0, "''"},
+		{"my file.txt", 0, "'my file.txt'"},
+		{"it's", 0, `'it'\''s'`},
+		{"$HOME", 0, "'$HOME'"},
+		{"it's", '\'', `it'\''s`},
+		{"say \"$(id)\" `id`", '"', "say \\\"\\$(id)\\\" \\`id\\`"},
+		{"a\\b", '"', `a\\b`},
+	}
+
+	for _, tt := range tests {
+		if got := execution.QuoteShellValue(tt.value, tt.quote); got != tt.expected {
+			t.Errorf("QuoteShellValue(%q, %q) = %s, want %s", tt.value, tt.quote, got, tt.expected)
+		}
+	}
+}
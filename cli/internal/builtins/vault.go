@@ -0,0 +1,308 @@
+package decorators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault configuration for the vault secret provider. VAULT_ADDR, VAULT_TOKEN, and
+// VAULT_NAMESPACE are the variables the vault CLI uses; the `secrets.vault` section of
+// devcmd.settings provides defaults for the others.
+const (
+	VaultAddrEnvVar      = "VAULT_ADDR"
+	VaultTokenEnvVar     = "VAULT_TOKEN"
+	VaultNamespaceEnvVar = "VAULT_NAMESPACE"
+	VaultMountEnvVar     = "DEVCMD_VAULT_MOUNT"     // KV version 2 mount, default "secret"
+	VaultRoleEnvVar      = "DEVCMD_VAULT_ROLE"      // JWT auth role to log in as with a CI OIDC token
+	VaultAuthPathEnvVar  = "DEVCMD_VAULT_AUTH_PATH" // JWT auth mount, default "jwt"
+	VaultAudienceEnvVar  = "DEVCMD_VAULT_AUDIENCE"  // OIDC token audience, default "vault"
+)
+
+// vaultTemplate mirrors vaultProvider.Get in generated code
+const vaultTemplate = `func(name, path string, getenv func(string) string) (string, error) {
+		orDefault := func(value, fallback string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		}
+		addr := strings.TrimSuffix(getenv("VAULT_ADDR"), "/")
+		if addr == "" {
+			return "", fmt.Errorf("no Vault address: set secrets.vault.address in devcmd.settings or VAULT_ADDR")
+		}
+		if path == "" {
+			return "", fmt.Errorf("the vault provider needs the secret's path, e.g. @secret(%s, provider = \"vault\", path = \"myapp/prod\")", name)
+		}
+		request := func(method, endpoint, token string, body interface{}) (map[string]interface{}, int, error) {
+			var payload io.Reader
+			if body != nil {
+				data, err := json.Marshal(body)
+				if err != nil {
+					return nil, 0, err
+				}
+				payload = strings.NewReader(string(data))
+			}
+			req, err := http.NewRequest(method, addr+"/v1/"+endpoint, payload)
+			if err != nil {
+				return nil, 0, err
+			}
+			if token != "" {
+				req.Header.Set("X-Vault-Token", token)
+			}
+			if namespace := getenv("VAULT_NAMESPACE"); namespace != "" {
+				req.Header.Set("X-Vault-Namespace", namespace)
+			}
+			resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+			if err != nil {
+				return nil, 0, err
+			}
+			defer resp.Body.Close()
+			decoder := json.NewDecoder(resp.Body)
+			decoder.UseNumber()
+			var result map[string]interface{}
+			_ = decoder.Decode(&result)
+			if resp.StatusCode != http.StatusOK {
+				var messages []string
+				if errs, ok := result["errors"].([]interface{}); ok {
+					for _, e := range errs {
+						messages = append(messages, fmt.Sprint(e))
+					}
+				}
+				if len(messages) == 0 {
+					messages = append(messages, resp.Status)
+				}
+				return nil, resp.StatusCode, fmt.Errorf("%s", strings.Join(messages, "; "))
+			}
+			return result, resp.StatusCode, nil
+		}
+		token := getenv("VAULT_TOKEN")
+		if token == "" {
+			if role := getenv("DEVCMD_VAULT_ROLE"); role != "" {
+				oidcToken := ` + oidcTokenTemplate + `
+				if jwt, err := oidcToken(orDefault(getenv("DEVCMD_VAULT_AUDIENCE"), "vault"), getenv); err == nil {
+					authPath := strings.Trim(orDefault(getenv("DEVCMD_VAULT_AUTH_PATH"), "jwt"), "/")
+					result, _, err := request("POST", "auth/"+authPath+"/login", "", map[string]string{"role": role, "jwt": jwt})
+					if err != nil {
+						return "", fmt.Errorf("failed to log in to Vault as role %s: %v", role, err)
+					}
+					auth, _ := result["auth"].(map[string]interface{})
+					token, _ = auth["client_token"].(string)
+				}
+			}
+		}
+		if token == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+					token = strings.TrimSpace(string(data))
+				}
+			}
+		}
+		if token == "" {
+			return "", fmt.Errorf("no Vault token: set VAULT_TOKEN, run vault login, or set secrets.vault.role to log in with a CI OIDC token")
+		}
+		mount := strings.Trim(orDefault(getenv("DEVCMD_VAULT_MOUNT"), "secret"), "/")
+		secretPath := mount + "/" + strings.Trim(path, "/")
+		result, status, err := request("GET", mount+"/data/"+strings.Trim(path, "/"), token, nil)
+		if status == http.StatusNotFound {
+			return "", fmt.Errorf("no Vault secret at %s", secretPath)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s from Vault: %v", secretPath, err)
+		}
+		data, _ := result["data"].(map[string]interface{})
+		values, _ := data["data"].(map[string]interface{})
+		switch v := values[name].(type) {
+		case string:
+			return v, nil
+		case json.Number, bool:
+			return fmt.Sprint(v), nil
+		case nil:
+			return "", fmt.Errorf("secret %q not found in Vault at %s", name, secretPath)
+		}
+		return "", fmt.Errorf("secret %q in Vault at %s is not a single value", name, secretPath)
+	}`
+
+// vaultProvider reads secrets from a HashiCorp Vault KV version 2 engine, authenticating with
+// VAULT_TOKEN, a JWT login with the CI system's OIDC token, or the vault CLI's token file
+type vaultProvider struct{}
+
+func (vaultProvider) Name() string { return VaultProvider }
+
+func (vaultProvider) Get(req SecretRequest) (string, error) {
+	client, err := newVaultClient(req.Getenv)
+	if err != nil {
+		return "", err
+	}
+	if req.Path == "" {
+		return "", fmt.Errorf("the vault provider needs the secret's path, e.g. @secret(%s, provider = \"vault\", path = \"myapp/prod\")", req.Name)
+	}
+	token, err := client.token()
+	if err != nil {
+		return "", err
+	}
+
+	mount := strings.Trim(envOrDefault(req.Getenv, VaultMountEnvVar, "secret"), "/")
+	secretPath := mount + "/" + strings.Trim(req.Path, "/")
+	result, status, err := client.request("GET", mount+"/data/"+strings.Trim(req.Path, "/"), token, nil)
+	if status == http.StatusNotFound {
+		return "", fmt.Errorf("no Vault secret at %s", secretPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from Vault: %w", secretPath, err)
+	}
+
+	data, _ := result["data"].(map[string]interface{})
+	values, _ := data["data"].(map[string]interface{})
+	switch v := values[req.Name].(type) {
+	case string:
+		return v, nil
+	case json.Number, bool:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", fmt.Errorf("secret %q not found in Vault at %s", req.Name, secretPath)
+	}
+	return "", fmt.Errorf("secret %q in Vault at %s is not a single value", req.Name, secretPath)
+}
+
+func (vaultProvider) Source(req SecretRequest) string {
+	addr := req.Getenv(VaultAddrEnvVar)
+	if addr == "" {
+		addr = "<no Vault address>"
+	}
+	mount := strings.Trim(envOrDefault(req.Getenv, VaultMountEnvVar, "secret"), "/")
+	return fmt.Sprintf("Vault %s %s/%s", addr, mount, strings.Trim(req.Path, "/"))
+}
+
+func (vaultProvider) Template() string { return vaultTemplate }
+
+func (vaultProvider) Imports() []string {
+	return []string{"encoding/json", "io", "net/http", "net/url", "path/filepath", "time"}
+}
+
+// vaultClient makes Vault HTTP API requests for one configuration
+type vaultClient struct {
+	addr   string
+	getenv func(string) string
+	http   *http.Client
+}
+
+// newVaultClient returns a client for the Vault server at VAULT_ADDR
+func newVaultClient(getenv func(string) string) (*vaultClient, error) {
+	addr := strings.TrimSuffix(getenv(VaultAddrEnvVar), "/")
+	if addr == "" {
+		return nil, fmt.Errorf("no Vault address: set secrets.vault.address in devcmd.settings or %s", VaultAddrEnvVar)
+	}
+	return &vaultClient{addr: addr, getenv: getenv, http: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// vaultLogins caches tokens from JWT logins by server and role, so a run logs in once
+var vaultLogins = struct {
+	sync.Mutex
+	tokens map[string]string
+}{tokens: make(map[string]string)}
+
+// token returns the Vault token to use: VAULT_TOKEN, else a JWT login with the CI system's
+// OIDC token when a role is configured and a token is available, else ~/.vault-token
+func (c *vaultClient) token() (string, error) {
+	if token := c.getenv(VaultTokenEnvVar); token != "" {
+		return token, nil
+	}
+
+	if role := c.getenv(VaultRoleEnvVar); role != "" {
+		if jwt, err := requestOIDCToken(envOrDefault(c.getenv, VaultAudienceEnvVar, "vault"), c.getenv); err == nil {
+			authPath := strings.Trim(envOrDefault(c.getenv, VaultAuthPathEnvVar, "jwt"), "/")
+			cacheKey := c.addr + "\x00" + authPath + "\x00" + role
+
+			vaultLogins.Lock()
+			defer vaultLogins.Unlock()
+			if token, ok := vaultLogins.tokens[cacheKey]; ok {
+				return token, nil
+			}
+			result, _, err := c.request("POST", "auth/"+authPath+"/login", "", map[string]string{"role": role, "jwt": jwt})
+			if err != nil {
+				return "", fmt.Errorf("failed to log in to Vault as role %s: %w", role, err)
+			}
+			auth, _ := result["auth"].(map[string]interface{})
+			if token, _ := auth["client_token"].(string); token != "" {
+				vaultLogins.tokens[cacheKey] = token
+				return token, nil
+			}
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			if token := strings.TrimSpace(string(data)); token != "" {
+				return token, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no Vault token: set %s, run vault login, or set secrets.vault.role to log in with a CI OIDC token", VaultTokenEnvVar)
+}
+
+// request calls a Vault API endpoint, returning the decoded response and its status code.
+// Failures carry Vault's error messages.
+func (c *vaultClient) request(method, endpoint, token string, body interface{}) (map[string]interface{}, int, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.addr+"/v1/"+endpoint, payload)
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := c.getenv(VaultNamespaceEnvVar); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	var result map[string]interface{}
+	_ = decoder.Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		var messages []string
+		if errs, ok := result["errors"].([]interface{}); ok {
+			for _, e := range errs {
+				messages = append(messages, fmt.Sprint(e))
+			}
+		}
+		if len(messages) == 0 {
+			messages = append(messages, resp.Status)
+		}
+		return nil, resp.StatusCode, fmt.Errorf("%s", strings.Join(messages, "; "))
+	}
+	return result, resp.StatusCode, nil
+}
+
+// envOrDefault returns a configuration variable, or fallback when it's empty
+func envOrDefault(getenv func(string) string, name, fallback string) string {
+	if value := getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// init registers the Vault secret provider
+func init() {
+	RegisterSecretProvider(vaultProvider{})
+}
@@ -0,0 +1,185 @@
+package decorators
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+// fakeVault is a Vault server with one KV version 2 secret at secret/myapp/prod, readable with
+// the token "root" or the token from a JWT login as role "ci" with the token "jwt-for-vault"
+type fakeVault struct {
+	*httptest.Server
+	logins atomic.Int32
+}
+
+func startFakeVault(t *testing.T) *fakeVault {
+	t.Helper()
+	v := &fakeVault{}
+	v.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := func(status int, body interface{}) {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(body)
+		}
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/auth/jwt/login" {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["role"] != "ci" || body["jwt"] != "jwt-for-vault" {
+				reply(http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid jwt"}})
+				return
+			}
+			v.logins.Add(1)
+			reply(http.StatusOK, map[string]interface{}{"auth": map[string]string{"client_token": "ci-token"}})
+			return
+		}
+		if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "ci-token" {
+			reply(http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/myapp/prod" {
+			reply(http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"data": map[string]interface{}{"db_password": "hunter2", "port": 5432, "tls": map[string]string{"cert": "x"}},
+		}})
+	}))
+	t.Cleanup(v.Close)
+
+	t.Setenv(VaultAddrEnvVar, v.URL)
+	for _, name := range []string{VaultTokenEnvVar, VaultNamespaceEnvVar, VaultMountEnvVar, VaultRoleEnvVar, VaultAuthPathEnvVar, VaultAudienceEnvVar, OIDCTokenEnvVar, "ACTIONS_ID_TOKEN_REQUEST_URL"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+	return v
+}
+
+func getVaultSecret(name, path string) (string, error) {
+	return vaultProvider{}.Get(SecretRequest{Name: name, Path: path, Getenv: os.Getenv})
+}
+
+func TestVaultProvider_Token(t *testing.T) {
+	startFakeVault(t)
+	t.Setenv(VaultTokenEnvVar, "root")
+
+	for _, tc := range []struct {
+		name, path, want, error string
+	}{
+		{"db_password", "myapp/prod", "hunter2", ""},
+		{"port", "/myapp/prod/", "5432", ""},
+		{"api_key", "myapp/prod", "", `secret "api_key" not found in Vault at secret/myapp/prod`},
+		{"tls", "myapp/prod", "", `secret "tls" in Vault at secret/myapp/prod is not a single value`},
+		{"db_password", "myapp/dev", "", "no Vault secret at secret/myapp/dev"},
+		{"db_password", "", "", "the vault provider needs the secret's path"},
+	} {
+		value, err := getVaultSecret(tc.name, tc.path)
+		if tc.error != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.error) {
+				t.Errorf("%s at %q: error = %v, want %q", tc.name, tc.path, err, tc.error)
+			}
+			continue
+		}
+		if err != nil || value != tc.want {
+			t.Errorf("%s at %q = %q, %v; want %q", tc.name, tc.path, value, err, tc.want)
+		}
+	}
+}
+
+func TestVaultProvider_PermissionDenied(t *testing.T) {
+	startFakeVault(t)
+	t.Setenv(VaultTokenEnvVar, "expired")
+
+	_, err := getVaultSecret("db_password", "myapp/prod")
+	if err == nil || !strings.Contains(err.Error(), "failed to read secret/myapp/prod from Vault: permission denied") {
+		t.Errorf("error = %v, want Vault's message", err)
+	}
+}
+
+func TestVaultProvider_JWTLoginWithGitHubOIDC(t *testing.T) {
+	vault := startFakeVault(t)
+	installFakeGitHubOIDC(t)
+	t.Setenv(VaultRoleEnvVar, "ci")
+	vaultLogins.Lock()
+	vaultLogins.tokens = make(map[string]string)
+	vaultLogins.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if value, err := getVaultSecret("db_password", "myapp/prod"); err != nil || value != "hunter2" {
+			t.Fatalf("read %d = %q, %v; want hunter2", i, value, err)
+		}
+	}
+	if n := vault.logins.Load(); n != 1 {
+		t.Errorf("logged in %d times, want once", n)
+	}
+
+	t.Setenv(VaultRoleEnvVar, "admin")
+	if _, err := getVaultSecret("db_password", "myapp/prod"); err == nil || !strings.Contains(err.Error(), "failed to log in to Vault as role admin: invalid jwt") {
+		t.Errorf("login with the wrong role error = %v", err)
+	}
+}
+
+func TestVaultProvider_TokenFile(t *testing.T) {
+	startFakeVault(t)
+	// A role without an OIDC token available, as on a laptop, falls back to vault login's token
+	t.Setenv(VaultRoleEnvVar, "ci")
+
+	if _, err := getVaultSecret("db_password", "myapp/prod"); err == nil || !strings.Contains(err.Error(), "no Vault token") {
+		t.Errorf("error without a token = %v, want no Vault token", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(os.Getenv("HOME"), ".vault-token"), []byte("root\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := getVaultSecret("db_password", "myapp/prod"); err != nil || value != "hunter2" {
+		t.Errorf("read with the token file = %q, %v; want hunter2", value, err)
+	}
+}
+
+func TestVaultProvider_NoAddress(t *testing.T) {
+	t.Setenv(VaultAddrEnvVar, "")
+	if _, err := getVaultSecret("db_password", "myapp/prod"); err == nil || !strings.Contains(err.Error(), "no Vault address: set secrets.vault.address") {
+		t.Errorf("error = %v, want no Vault address", err)
+	}
+}
+
+func TestSecretDecorator_Vault(t *testing.T) {
+	vault := startFakeVault(t)
+	t.Setenv(VaultTokenEnvVar, "root")
+
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("name", "db_password"),
+			decoratortesting.StringParam("provider", "vault"),
+			decoratortesting.StringParam("path", "myapp/prod"),
+		})
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		InterpreterReturns("hunter2").
+		GeneratorSucceeds().
+		GeneratorCodeContains(`readSecret(ctx, "vault", "db_password", "myapp/prod")`).
+		PlanSucceeds().
+		Validate()
+	if len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+	if plan, want := fmt.Sprint(result.PlanResult.Data), fmt.Sprintf("@secret(db_password) → *** (Vault %s secret/myapp/prod)", vault.URL); plan != want {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+}
+
+func TestSecretDecorator_KeyAndName(t *testing.T) {
+	result := decoratortesting.NewDecoratorTest(t, &SecretDecorator{}).
+		TestValueDecorator([]ast.NamedParameter{decoratortesting.StringParam("key", "a"), decoratortesting.StringParam("name", "b")})
+	if errors := decoratortesting.Assert(result).InterpreterFails("@secret takes key or name, not both").Validate(); len(errors) > 0 {
+		t.Errorf("SecretDecorator test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -0,0 +1,460 @@
+package decorators
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/watch"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// watchTemplate runs the block, then again whenever the watched files change, until the CLI
+// is interrupted. It mirrors watch.Watcher, watch.Dirs, watchedFiles and the matching of
+// globFiles.
+const watchTemplate = `// Watch files: run the block, then again when {{.Label}} change
+{
+	hidden := func(name string) bool { return strings.HasPrefix(name, ".") }
+	var match func(pattern, name []string) bool
+	match = func(pattern, name []string) bool {
+		for len(pattern) > 0 {
+			if pattern[0] == "**" {
+				for i := 0; i <= len(name); i++ {
+					if i > 0 && hidden(name[i-1]) {
+						return false
+					}
+					if match(pattern[1:], name[i:]) {
+						return true
+					}
+				}
+				return false
+			}
+			if len(name) == 0 || (hidden(name[0]) && !hidden(pattern[0])) {
+				return false
+			}
+			if ok, _ := path.Match(pattern[0], name[0]); !ok {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		}
+		return len(name) == 0
+	}
+	glob := func(pattern string) []string {
+		segments := strings.Split(filepath.ToSlash(pattern), "/")
+		literal, recursive, dotted := 0, false, false
+		for i, segment := range segments {
+			if literal == i && segment != "**" && !strings.ContainsAny(segment, "*?[\\") {
+				literal++
+				continue
+			}
+			recursive = recursive || segment == "**"
+			dotted = dotted || hidden(segment)
+		}
+		base, rest := strings.Join(segments[:literal], "/"), segments[literal:]
+		if literal == 1 && base == "" {
+			base = "/"
+		}
+		root := filepath.FromSlash(base)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(ctx.Dir, root)
+		}
+		if root == "" {
+			root = "."
+		}
+		var matches []string
+		if len(rest) == 0 {
+			if _, err := os.Lstat(root); err == nil {
+				matches = append(matches, base)
+			}
+			return matches
+		}
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return nil
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if match(rest, parts) {
+				matches = append(matches, path.Join(base, filepath.ToSlash(rel)))
+			}
+			if d.IsDir() && ((!recursive && len(parts) >= len(rest)) || (hidden(d.Name()) && !dotted)) {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		return matches
+	}
+
+	// dirs returns the directories files matching pattern can be in, or the nearest existing
+	// parent of one that doesn't exist yet
+	dirs := func(pattern string) []string {
+		segments := strings.Split(filepath.ToSlash(pattern), "/")
+		literal, recursive, dotted := 0, false, false
+		for i, segment := range segments {
+			if literal == i && segment != "**" && !strings.ContainsAny(segment, "*?[\\") {
+				literal++
+				continue
+			}
+			recursive = recursive || segment == "**"
+			dotted = dotted || hidden(segment)
+		}
+		base, depth := strings.Join(segments[:literal], "/"), len(segments)-literal-1
+		if literal == len(segments) {
+			base, depth = strings.Join(segments[:literal-1], "/"), 0
+		}
+		if base == "" && literal > 0 && segments[0] == "" {
+			base = "/"
+		}
+		root := filepath.FromSlash(base)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(ctx.Dir, root)
+		}
+		if root == "" {
+			root = "."
+		}
+		for {
+			if info, err := os.Stat(root); err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(root)
+			if parent == root {
+				return nil
+			}
+			root, depth, recursive = parent, 0, false
+		}
+		found := []string{root}
+		if depth <= 0 && !recursive {
+			return found
+		}
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root || !d.IsDir() {
+				return nil
+			}
+			if hidden(d.Name()) && !dotted {
+				return filepath.SkipDir
+			}
+			found = append(found, p)
+			if rel, err := filepath.Rel(root, p); err == nil && !recursive && len(strings.Split(filepath.ToSlash(rel), "/")) >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		return found
+	}
+
+	patterns := []string{ {{range .Patterns}}{{printf "%q" .}}, {{end}}}
+	type fileState struct {
+		size    int64
+		modTime time.Time
+	}
+	snapshot := func() map[string]fileState {
+		files := map[string]fileState{}
+		for _, pattern := range patterns {
+		matches:
+			for _, name := range glob(pattern) {
+				for _, ignore := range []string{ {{range .Ignore}}{{printf "%q" .}}, {{end}}} {
+					if match(strings.Split(ignore, "/"), strings.Split(name, "/")) {
+						continue matches
+					}
+				}
+				file := filepath.FromSlash(name)
+				if !filepath.IsAbs(file) {
+					file = filepath.Join(ctx.Dir, file)
+				}
+				if info, err := os.Stat(file); err == nil && !info.IsDir() {
+					files[file] = fileState{size: info.Size(), modTime: info.ModTime()}
+				}
+			}
+		}
+		return files
+	}
+	sameFiles := func(a, b map[string]fileState) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for name, state := range a {
+			if other, ok := b[name]; !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+				return false
+			}
+		}
+		return true
+	}
+	run := func() {
+		if err := func() error {
+{{range .Content}}			{{. | buildCommand}}
+{{end}}			return nil
+		}(); err != nil {
+			logf("error", "@watch-files", "%v", err)
+		}
+		logf("info", "@watch-files", "watching %s", {{printf "%q" .Label}})
+	}
+
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		logf("error", "@watch-files", "failed to watch files: %v", err)
+		os.Exit(1)
+	}
+	defer events.Close()
+	watched := map[string]bool{}
+	watchDirs := func() {
+		for _, pattern := range patterns {
+			for _, dir := range dirs(pattern) {
+				if watched[dir] {
+					continue
+				}
+				if err := events.Add(dir); err != nil {
+					if os.IsNotExist(err) {
+						continue
+					}
+					logf("error", "@watch-files", "failed to watch %s: %v", dir, err)
+					os.Exit(1)
+				}
+				watched[dir] = true
+			}
+		}
+	}
+
+	run()
+	files := snapshot()
+	watchDirs()
+	var due <-chan time.Time
+	for {
+		select {
+		case event := <-events.Events:
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				delete(watched, event.Name)
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			due = time.After({{.Debounce | formatDuration}})
+		case <-events.Errors:
+			due = time.After({{.Debounce | formatDuration}})
+		case <-due:
+			due = nil
+			watchDirs()
+			if current := snapshot(); !sameFiles(files, current) {
+				files = current
+				logf("info", "@watch-files", "files changed, running the block")
+				run()
+			}
+		}
+	}
+}`
+
+// The fsnotify module generated CLIs with @watch-files require, the version devcmd uses
+const (
+	fsnotifyPackage = "github.com/fsnotify/fsnotify"
+	fsnotifyVersion = "v1.8.0"
+)
+
+// WatchFilesDecorator implements the @watch-files decorator for rerunning a block when files change
+type WatchFilesDecorator struct{}
+
+// Name returns the decorator name
+func (w *WatchFilesDecorator) Name() string {
+	return "watch-files"
+}
+
+// Description returns a human-readable description
+func (w *WatchFilesDecorator) Description() string {
+	return "Run the block, then again whenever files matching the patterns change"
+}
+
+// ParameterSchema returns the expected parameters for this decorator
+func (w *WatchFilesDecorator) ParameterSchema() []decorators.ParameterSchema {
+	return []decorators.ParameterSchema{
+		{
+			Name:        "patterns",
+			Type:        ast.StringType,
+			Required:    true,
+			Description: "Patterns of the files to watch, as @glob matches them, separated by commas, e.g. \"src/**/*.go,go.mod\"",
+		},
+		{
+			Name:        "ignore",
+			Type:        ast.StringType,
+			Required:    false,
+			Description: "Patterns of matched files to leave out, separated by commas, e.g. \"**/*_test.go\"",
+		},
+		{
+			Name:        "debounce",
+			Type:        ast.DurationType,
+			Required:    false,
+			Description: "How long changes must settle before the block runs again (default: 300ms)",
+		},
+	}
+}
+
+// ExecuteInterpreter runs the block and reruns it on changes until the context is cancelled
+func (w *WatchFilesDecorator) ExecuteInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	patterns, ignore, debounce, err := w.extractParameters(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	logger := ctx.Logger()
+	label := strings.Join(patterns, ", ")
+	run := func() {
+		commandExecutor := decorators.NewCommandExecutor()
+		defer commandExecutor.Cleanup()
+
+		// A failing run is reported and the block runs again on the next change
+		if err := commandExecutor.ExecuteCommandsWithInterpreter(ctx.Child(), content); err != nil {
+			logger.Errorf("@watch-files", "%v", err)
+		}
+		logger.Infof("@watch-files", "watching %s", label)
+	}
+
+	run()
+	watcher := &watch.Watcher{
+		Dir:      ctx.GetWorkingDir(),
+		Patterns: patterns,
+		Debounce: debounce,
+		Glob: func(dir, pattern string) ([]string, error) {
+			return watchedFiles(dir, pattern, ignore)
+		},
+	}
+	err = watcher.Watch(ctx, func() {
+		logger.Infof("@watch-files", "files changed, running the block")
+		run()
+	})
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: fmt.Errorf("@watch-files: %w", err)}
+	}
+	return &execution.ExecutionResult{Data: nil, Error: nil}
+}
+
+// GenerateTemplate generates template for running the block and rerunning it on changes
+func (w *WatchFilesDecorator) GenerateTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*execution.TemplateResult, error) {
+	patterns, ignore, debounce, err := w.extractParameters(params)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("watch-files").Funcs(ctx.GetTemplateFunctions()).Parse(watchTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse watch-files template: %w", err)
+	}
+
+	return &execution.TemplateResult{
+		Template: tmpl,
+		Data: struct {
+			Label    string
+			Patterns []string
+			Ignore   []string
+			Debounce time.Duration
+			Content  []ast.CommandContent
+		}{
+			Label:    strings.Join(patterns, ", "),
+			Patterns: patterns,
+			Ignore:   ignore,
+			Debounce: debounce,
+			Content:  content,
+		},
+	}, nil
+}
+
+// ExecutePlan creates a plan element for dry-run mode
+func (w *WatchFilesDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.NamedParameter, content []ast.CommandContent) *execution.ExecutionResult {
+	patterns, ignore, debounce, err := w.extractParameters(params)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	element := plan.Decorator(w.Name()).
+		WithType("block").
+		WithParameter("patterns", strings.Join(patterns, ",")).
+		WithParameter("debounce", debounce.String()).
+		WithDescription(fmt.Sprintf("Run, then again %s after %s change, until interrupted", debounce, strings.Join(patterns, ", ")))
+	if len(ignore) > 0 {
+		element = element.WithParameter("ignore", strings.Join(ignore, ","))
+	}
+
+	element, err = addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
+	}
+
+	return &execution.ExecutionResult{
+		Data:  element,
+		Error: nil,
+	}
+}
+
+// extractParameters validates parameters and returns the watched and ignored patterns and the debounce
+func (w *WatchFilesDecorator) extractParameters(params []ast.NamedParameter) (patterns []string, ignore []string, debounce time.Duration, err error) {
+	if err := decorators.ValidateParameterCount(params, 1, 3, w.Name()); err != nil {
+		return nil, nil, 0, err
+	}
+	if err := decorators.ValidateSchemaCompliance(params, w.ParameterSchema(), w.Name()); err != nil {
+		return nil, nil, 0, err
+	}
+	params, err = decorators.ResolvePositionalParameters(params, w.ParameterSchema())
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("@watch-files: %w", err)
+	}
+	if err := decorators.ValidateDuration(params, "debounce", 0, 1*time.Hour, w.Name()); err != nil {
+		return nil, nil, 0, err
+	}
+
+	patterns = splitPatterns(ast.GetStringParam(params, "patterns", ""))
+	if len(patterns) == 0 {
+		return nil, nil, 0, fmt.Errorf("@watch-files requires at least one pattern")
+	}
+	ignore = splitPatterns(ast.GetStringParam(params, "ignore", ""))
+	for _, pattern := range append(append([]string{}, patterns...), ignore...) {
+		if err := validateGlobPattern(pattern); err != nil {
+			return nil, nil, 0, fmt.Errorf("@watch-files: %w", err)
+		}
+	}
+	debounce = ast.GetDurationParam(params, "debounce", watch.DefaultDebounce)
+	return patterns, ignore, debounce, nil
+}
+
+// watchedFiles returns the paths matching pattern as globFiles does, without those matching
+// an ignore pattern
+func watchedFiles(dir, pattern string, ignore []string) ([]string, error) {
+	matches, err := globFiles(dir, pattern, false)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, match := range matches {
+		if !watchIgnored(filepath.ToSlash(match), ignore) {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// watchIgnored reports whether a matched path, with slashes, matches an ignore pattern
+func watchIgnored(name string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if matchGlobSegments(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportRequirements returns the dependencies needed for code generation, including fsnotify
+// for the file system events generated CLIs watch
+func (w *WatchFilesDecorator) ImportRequirements() decorators.ImportRequirement {
+	requirements := decorators.StandardImportRequirement(decorators.CoreImports, decorators.FileSystemImports, decorators.StringImports, []string{"io/fs", "path", "path/filepath", "time"})
+	requirements.ThirdParty = append(requirements.ThirdParty, fsnotifyPackage)
+	requirements.GoModules[fsnotifyPackage] = fsnotifyVersion
+	return requirements
+}
+
+// init registers the watch-files decorator
+func init() {
+	decorators.RegisterBlock(&WatchFilesDecorator{})
+}
@@ -0,0 +1,134 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	decoratortesting "github.com/aledsdavies/devcmd/testing"
+)
+
+func TestWatchedFiles_Ignore(t *testing.T) {
+	root := createGlobTree(t, "src/main.go", "src/main_test.go", "src/gen/api.go")
+
+	files, err := watchedFiles(root, "src/**/*.go", []string{"**/*_test.go", "src/gen/**"})
+	if err != nil {
+		t.Fatalf("watchedFiles failed: %v", err)
+	}
+	if got := strings.Join(files, ","); got != filepath.FromSlash("src/main.go") {
+		t.Errorf("watchedFiles = %q, want only src/main.go", got)
+	}
+}
+
+func TestWatchFilesDecorator_RerunsOnChange(t *testing.T) {
+	root := createGlobTree(t, "src/main.go", "src/main_test.go")
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := execution.NewInterpreterContext(base, &ast.Program{}).WithWorkingDir(root)
+	params := []ast.NamedParameter{
+		decoratortesting.StringParam("patterns", "src/*.go"),
+		decoratortesting.StringParam("ignore", "**/*_test.go"),
+		{Name: "debounce", Value: &ast.DurationLiteral{Value: "10ms"}},
+	}
+	content := []ast.CommandContent{decoratortesting.Shell("echo run >> runs")}
+
+	done := make(chan *execution.ExecutionResult)
+	go func() { done <- (&WatchFilesDecorator{}).ExecuteInterpreter(ctx, params, content) }()
+
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(root, "runs"))
+		return strings.Count(string(data), "run")
+	}
+	waitForRuns := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for runs() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("block ran %d times, want %d", runs(), want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	waitForRuns(1)
+	// Let the watcher take its first snapshot, then change an ignored file and a watched one
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(root, "src", "main_test.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(600 * time.Millisecond)
+	if got := runs(); got != 1 {
+		t.Errorf("block ran %d times after an ignored file changed, want 1", got)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForRuns(2)
+
+	cancel()
+	select {
+	case result := <-done:
+		if result.Error != nil {
+			t.Errorf("ExecuteInterpreter failed: %v", result.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ExecuteInterpreter didn't return after cancellation")
+	}
+}
+
+func TestWatchFilesDecorator_Generate(t *testing.T) {
+	params := []ast.NamedParameter{
+		decoratortesting.StringParam("patterns", "src/**/*.go, go.mod"),
+		decoratortesting.StringParam("ignore", "**/*_test.go"),
+	}
+	content := []ast.CommandContent{decoratortesting.Shell("go build ./...")}
+
+	// The harness runs every mode, and the block watches until it is interrupted, so the
+	// generator and planner are called with its contexts on their own
+	suite := decoratortesting.NewDecoratorTest(t, &WatchFilesDecorator{})
+	templateResult, err := (&WatchFilesDecorator{}).GenerateTemplate(suite.GeneratorContext(), params, content)
+	if err != nil {
+		t.Fatalf("GenerateTemplate failed: %v", err)
+	}
+	var code strings.Builder
+	if err := templateResult.Template.Execute(&code, templateResult.Data); err != nil {
+		t.Fatalf("template failed: %v", err)
+	}
+	for _, want := range []string{`[]string{ "src/**/*.go", "go.mod", }`, `[]string{ "**/*_test.go", }`, `fsnotify.NewWatcher()`, `time.After(300 * time.Millisecond)`} {
+		if !strings.Contains(code.String(), want) {
+			t.Errorf("generated code doesn't contain %s:\n%s", want, code.String())
+		}
+	}
+
+	result := (&WatchFilesDecorator{}).ExecutePlan(suite.PlanContext(), params, content)
+	if result.Error != nil {
+		t.Fatalf("ExecutePlan failed: %v", result.Error)
+	}
+	element, ok := result.Data.(*plan.DecoratorElement)
+	if !ok {
+		t.Fatalf("ExecutePlan = %#v, want a decorator element", result.Data)
+	}
+	if modules := (&WatchFilesDecorator{}).ImportRequirements().GoModules; modules[fsnotifyPackage] == "" {
+		t.Errorf("generated CLIs should require %s, got %v", fsnotifyPackage, modules)
+	}
+	if step := element.Build(); step.Decorator.Name != "watch-files" || step.Decorator.Parameters["debounce"] != "300ms" || len(step.Children) != 1 {
+		t.Errorf("plan step = %+v", step)
+	}
+}
+
+func TestWatchFilesDecorator_InvalidParameters(t *testing.T) {
+	for name, params := range map[string][]ast.NamedParameter{
+		"no patterns":    {decoratortesting.StringParam("patterns", " , ")},
+		"invalid ignore": {decoratortesting.StringParam("patterns", "*.go"), decoratortesting.StringParam("ignore", "[")},
+	} {
+		if _, _, _, err := (&WatchFilesDecorator{}).extractParameters(params); err == nil {
+			t.Errorf("%s: extractParameters succeeded", name)
+		}
+	}
+}
@@ -16,6 +16,7 @@ type WhenDecorator struct{}
 // WhenPatternData holds data for a single pattern branch
 type WhenPatternData struct {
 	Name      string
+	Case      string // Go case expression selecting the branch
 	IsDefault bool
 	Commands  []ast.CommandContent // AST commands for template processing
 }
@@ -45,11 +46,12 @@ func (w *WhenDecorator) ParameterSchema() []decorators.ParameterSchema {
 // decorators.PatternSchema defines what patterns @when accepts
 func (w *WhenDecorator) PatternSchema() decorators.PatternSchema {
 	return decorators.PatternSchema{
-		AllowedPatterns:     []string{}, // No specific patterns - any identifier is allowed
-		RequiredPatterns:    []string{}, // No required patterns
-		AllowsWildcard:      true,       // "default" wildcard is allowed
-		AllowsAnyIdentifier: true,       // Any identifier is allowed (production, staging, etc.)
-		Description:         "Accepts any identifier patterns and 'default' wildcard",
+		AllowedPatterns:      []string{}, // No specific patterns - any identifier is allowed
+		RequiredPatterns:     []string{}, // No required patterns
+		AllowsWildcard:       true,       // "default" wildcard is allowed
+		AllowsAnyIdentifier:  true,       // Any identifier is allowed (production, staging, etc.)
+		AllowsStringPatterns: true,       // Quoted globs ("release/*") and regexes ("/^v[0-9]+$/")
+		Description:          "Accepts any identifier patterns, quoted glob and /regex/ patterns, and 'default' wildcard",
 	}
 }
 
@@ -104,7 +106,7 @@ func (w *WhenDecorator) extractVariableName(params []ast.NamedParameter) (string
 	}
 
 	// Parse parameters (validation passed, so these should be safe)
-	varName := ast.GetStringParam(params, "variable", "")
+	varName := ast.GetNameParam(params, "variable", "")
 
 	// Additional check for empty variable name (shouldn't happen after validation)
 	if varName == "" {
@@ -114,6 +116,14 @@ func (w *WhenDecorator) extractVariableName(params []ast.NamedParameter) (string
 	return varName, nil
 }
 
+// ReferencedVariables returns the variable @when matches, so generated CLIs declare it
+func (w *WhenDecorator) ReferencedVariables(params []ast.NamedParameter) []string {
+	if varName := ast.GetNameParam(params, "variable", ""); varName != "" {
+		return []string{varName}
+	}
+	return nil
+}
+
 // executeInterpreterImpl executes pattern matching in interpreter mode
 func (w *WhenDecorator) executeInterpreterImpl(ctx execution.InterpreterContext, varName string, patterns []ast.PatternBranch) *execution.ExecutionResult {
 	// Get the variable value (check context first, then captured environment)
@@ -126,7 +136,14 @@ func (w *WhenDecorator) executeInterpreterImpl(ctx execution.InterpreterContext,
 
 	// Find matching pattern branch
 	for _, pattern := range patterns {
-		if w.matchesPattern(value, pattern.Pattern) {
+		matched, err := w.matchesPattern(value, pattern.Pattern)
+		if err != nil {
+			return &execution.ExecutionResult{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		if matched {
 			// Execute the commands in the matching pattern
 			if err := w.executeCommands(ctx, pattern.Commands); err != nil {
 				return &execution.ExecutionResult{
@@ -147,23 +164,28 @@ func (w *WhenDecorator) executeInterpreterImpl(ctx execution.InterpreterContext,
 
 // generateTemplateImpl generates template for pattern matching with runtime variable resolution
 func (w *WhenDecorator) generateTemplateImpl(ctx execution.GeneratorContext, varName string, patterns []ast.PatternBranch) (*execution.TemplateResult, error) {
-	// Track the variable for global environment capture in generated code
-	ctx.TrackEnvironmentVariableReference(varName, "")
+	// Variables are constants of the generated code, anything else is read from the environment
+	_, isVariable := ctx.GetVariable(varName)
+	if !isVariable {
+		// Track the variable for global environment capture in generated code
+		ctx.TrackEnvironmentVariableReference(varName, "")
+	}
 
 	// Create template for pattern matching
 	tmplStr := `// Pattern matching for variable: {{.VariableName}}
 var {{.VariableName}}Value string
-if ctxValue, exists := variableContext[{{printf "%q" .VariableName}}]; exists {
-	{{.VariableName}}Value = ctxValue
-} else if envValue, exists := ctx.EnvContext[{{printf "%q" .VariableName}}]; exists {
+{{if .IsVariable}}{{.VariableName}}Value = {{.VariableName}}
+{{else}}if envValue, exists := ctx.Env[{{printf "%q" .VariableName}}]; exists {
 	{{.VariableName}}Value = envValue
+} else {
+	{{.VariableName}}Value = os.Getenv({{printf "%q" .VariableName}})
 }
-
-switch {{.VariableName}}Value {
+{{end}}
+switch {{if not .Matching}}{{.VariableName}}Value {{end}}{
 {{range .Patterns}}
-{{if .IsDefault}}default:{{else}}case {{printf "%q" .Name}}:{{end}}
+{{if .IsDefault}}default:{{else}}case {{.Case}}:{{end}}
 	// Execute commands for pattern: {{.Name}}
-{{range .Commands}}\t{{. | buildCommand}}
+{{range .Commands}}	{{. | buildCommand}}
 {{end}}
 {{end}}
 }`
@@ -174,6 +196,17 @@ switch {{.VariableName}}Value {
 		return nil, fmt.Errorf("failed to parse when template: %w", err)
 	}
 
+	// Glob and regex branches switch on conditions, checked in order, rather than on the value
+	matching := false
+	for _, pattern := range patterns {
+		if stringPattern, ok := pattern.Pattern.(*ast.StringPattern); ok {
+			if err := stringPattern.Validate(); err != nil {
+				return nil, fmt.Errorf("@when: %w", err)
+			}
+			matching = true
+		}
+	}
+
 	// Convert patterns to template data
 	var patternData []WhenPatternData
 	for _, pattern := range patterns {
@@ -185,6 +218,7 @@ switch {{.VariableName}}Value {
 
 		patternData = append(patternData, WhenPatternData{
 			Name:      patternStr,
+			Case:      w.patternCase(varName+"Value", pattern.Pattern, matching),
 			IsDefault: isDefault,
 			Commands:  pattern.Commands, // Pass AST commands directly to template
 		})
@@ -194,9 +228,13 @@ switch {{.VariableName}}Value {
 		Template: tmpl,
 		Data: struct {
 			VariableName string
+			IsVariable   bool
+			Matching     bool
 			Patterns     []WhenPatternData
 		}{
 			VariableName: varName,
+			IsVariable:   isVariable,
+			Matching:     matching,
 			Patterns:     patternData,
 		},
 	}, nil
@@ -218,7 +256,14 @@ func (w *WhenDecorator) executePlanImpl(ctx execution.PlanContext, varName strin
 
 	for _, pattern := range patterns {
 		patternStr := w.patternToString(pattern.Pattern)
-		if w.matchesPattern(currentValue, pattern.Pattern) {
+		matched, err := w.matchesPattern(currentValue, pattern.Pattern)
+		if err != nil {
+			return &execution.ExecutionResult{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		if matched {
 			selectedPattern = patternStr
 			selectedCommands = pattern.Commands
 			break
@@ -237,34 +282,11 @@ func (w *WhenDecorator) executePlanImpl(ctx execution.PlanContext, varName strin
 	}
 
 	// Build child plan elements for the selected commands only
-	for _, cmd := range selectedCommands {
-		switch c := cmd.(type) {
-		case *ast.ShellContent:
-			// Create plan element for shell command
-			result := ctx.GenerateShellPlan(c)
-			if result.Error != nil {
-				return &execution.ExecutionResult{
-					Data:  nil,
-					Error: fmt.Errorf("failed to create plan for shell content: %w", result.Error),
-				}
-			}
-
-			// Add child plan element
-			if childPlan, ok := result.Data.(*plan.ExecutionStep); ok {
-				// Convert ExecutionStep to a Command element for the plan
-				cmdElement := plan.Command(childPlan.Command).WithDescription(childPlan.Description)
-				element = element.WithChildren(cmdElement)
-			}
-		case *ast.BlockDecorator:
-			// For nested decorators, create a plan element
-			childElement := plan.Command(fmt.Sprintf("@%s{...}", c.Name)).WithDescription("Nested decorator")
-			element = element.WithChildren(childElement)
-		default:
-			// Unknown command type
-			childElement := plan.Command(fmt.Sprintf("Unknown command type: %T", cmd)).WithDescription("Unsupported command")
-			element = element.WithChildren(childElement)
-		}
+	children, err := contentPlans(ctx, selectedCommands)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
 	}
+	element = element.WithChildren(children...)
 
 	return &execution.ExecutionResult{
 		Data:  element,
@@ -289,14 +311,39 @@ func (w *WhenDecorator) executeCommands(ctx execution.InterpreterContext, comman
 }
 
 // matchesPattern checks if a value matches a pattern
-func (w *WhenDecorator) matchesPattern(value string, pattern ast.Pattern) bool {
+func (w *WhenDecorator) matchesPattern(value string, pattern ast.Pattern) (bool, error) {
 	switch p := pattern.(type) {
 	case *ast.IdentifierPattern:
-		return value == p.Name
+		return value == p.Name, nil
+	case *ast.StringPattern:
+		matched, err := p.Matches(value)
+		if err != nil {
+			return false, fmt.Errorf("@when: %w", err)
+		}
+		return matched, nil
 	case *ast.WildcardPattern:
-		return true // Wildcard matches everything
+		return true, nil // Wildcard matches everything
+	default:
+		return false, nil
+	}
+}
+
+// patternCase returns the Go case expression selecting a branch for the value in valueVar:
+// the identifier itself in a switch on the value, or a condition when matching globs or regexes
+func (w *WhenDecorator) patternCase(valueVar string, pattern ast.Pattern, matching bool) string {
+	switch p := pattern.(type) {
+	case *ast.IdentifierPattern:
+		if matching {
+			return fmt.Sprintf("%s == %q", valueVar, p.Name)
+		}
+		return fmt.Sprintf("%q", p.Name)
+	case *ast.StringPattern:
+		if p.IsRegex() {
+			return fmt.Sprintf("regexp.MustCompile(%q).MatchString(%s)", p.Expression(), valueVar)
+		}
+		return fmt.Sprintf("func() bool { matched, _ := path.Match(%q, %s); return matched }()", p.Expression(), valueVar)
 	default:
-		return false
+		return ""
 	}
 }
 
@@ -305,6 +352,8 @@ func (w *WhenDecorator) patternToString(pattern ast.Pattern) string {
 	switch p := pattern.(type) {
 	case *ast.IdentifierPattern:
 		return p.Name
+	case *ast.StringPattern:
+		return p.String()
 	case *ast.WildcardPattern:
 		return "default"
 	default:
@@ -312,10 +361,11 @@ func (w *WhenDecorator) patternToString(pattern ast.Pattern) string {
 	}
 }
 
-// ImportRequirements returns the dependencies needed for code generation
+// ImportRequirements returns the dependencies needed for code generation. The path and
+// regexp packages glob and regex branches match with are imported for the CLIs using them.
 func (w *WhenDecorator) ImportRequirements() decorators.ImportRequirement {
 	return decorators.ImportRequirement{
-		StandardLibrary: []string{}, // No imports needed - generates string literals
+		StandardLibrary: []string{}, // Identifier branches compare string literals
 		ThirdParty:      []string{},
 		GoModules:       map[string]string{},
 	}
@@ -277,3 +277,54 @@ func TestWhenDecorator_GeneratorVariableResolution(t *testing.T) {
 		t.Errorf("WhenDecorator generator variable resolution test failed:\n%s", decoratortesting.JoinErrors(errors))
 	}
 }
+
+func TestWhenDecorator_GlobAndRegexPatterns(t *testing.T) {
+	decorator := &WhenDecorator{}
+
+	patterns := []ast.PatternBranch{
+		decoratortesting.PatternBranch("main", "echo 'main'"),
+		decoratortesting.PatternBranch(`"release/*"`, "echo 'release'"),
+		decoratortesting.PatternBranch(`"/^v[0-9]+$/"`, "echo 'version'"),
+		decoratortesting.PatternBranch("*", "echo 'other'"),
+	}
+
+	for value, want := range map[string]string{
+		"main":        "main",
+		"release/1.2": `"release/*"`,
+		"release":     "default",
+		"v12":         `"/^v[0-9]+$/"`,
+		"v12-rc":      "default",
+	} {
+		for _, pattern := range patterns {
+			matched, err := decorator.matchesPattern(value, pattern.Pattern)
+			if err != nil {
+				t.Fatalf("matchesPattern(%q, %s) failed: %v", value, pattern.Pattern, err)
+			}
+			if matched {
+				if got := decorator.patternToString(pattern.Pattern); got != want {
+					t.Errorf("%q matched %s, want %s", value, got, want)
+				}
+				break
+			}
+		}
+	}
+
+	t.Setenv("BRANCH", "release/2.0")
+	result := decoratortesting.NewDecoratorTest(t, decorator).
+		TestPatternDecorator([]ast.NamedParameter{
+			decoratortesting.StringParam("variable", "BRANCH"),
+		}, patterns)
+
+	errors := decoratortesting.Assert(result).
+		InterpreterSucceeds().
+		GeneratorSucceeds().
+		GeneratorProducesValidGo().
+		GeneratorCodeContains(`path.Match("release/*", BRANCHValue)`, `regexp.MustCompile("^v[0-9]+$").MatchString(BRANCHValue)`, `BRANCHValue == "main"`).
+		PlanSucceeds().
+		PlanReturnsElement("conditional").
+		Validate()
+
+	if len(errors) > 0 {
+		t.Errorf("WhenDecorator glob and regex patterns test failed:\n%s", decoratortesting.JoinErrors(errors))
+	}
+}
@@ -3,7 +3,6 @@ package decorators
 import (
 	"fmt"
 	"os"
-	"strings"
 	"text/template"
 
 	"github.com/aledsdavies/devcmd/core/ast"
@@ -81,7 +80,7 @@ func (d *WorkdirDecorator) ExecutePlan(ctx execution.PlanContext, params []ast.N
 		}
 	}
 
-	return d.executePlanImpl(pathParam, createIfNotExists, content)
+	return d.executePlanImpl(ctx, pathParam, createIfNotExists, content)
 }
 
 // extractWorkdirParams extracts and validates workdir parameters
@@ -117,7 +116,7 @@ func (d *WorkdirDecorator) extractWorkdirParams(params []ast.NamedParameter) (st
 // getPathParameter extracts and validates the path parameter (deprecated - use extractWorkdirParams)
 
 // executePlanImpl creates a plan element for dry-run display
-func (d *WorkdirDecorator) executePlanImpl(path string, createIfNotExists bool, content []ast.CommandContent) *execution.ExecutionResult {
+func (d *WorkdirDecorator) executePlanImpl(ctx execution.PlanContext, path string, createIfNotExists bool, content []ast.CommandContent) *execution.ExecutionResult {
 	description := fmt.Sprintf("@workdir(\"%s\")", path)
 	if createIfNotExists {
 		description += " (create if needed)"
@@ -133,20 +132,9 @@ func (d *WorkdirDecorator) executePlanImpl(path string, createIfNotExists bool,
 	}
 
 	// Add children for each content item to show nested structure
-	for _, cmdContent := range content {
-		switch c := cmdContent.(type) {
-		case *ast.ShellContent:
-			// Convert shell content to command element
-			if len(c.Parts) > 0 {
-				if text, ok := c.Parts[0].(*ast.TextPart); ok {
-					cmd := strings.TrimSpace(text.Text)
-					element.AddChild(plan.Command(cmd).WithDescription(cmd))
-				}
-			}
-		case *ast.BlockDecorator:
-			// For nested decorators, create a placeholder (the actual decorator will be processed separately)
-			element.AddChild(plan.Command(fmt.Sprintf("@%s", c.Name)).WithDescription(fmt.Sprintf("@%s decorator", c.Name)))
-		}
+	element, err := addContentPlan(ctx, element, content)
+	if err != nil {
+		return &execution.ExecutionResult{Data: nil, Error: err}
 	}
 
 	return &execution.ExecutionResult{
@@ -0,0 +1,102 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/lint"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+// Report holds the result of validating a single command file
+type Report struct {
+	File        string            `json:"file"`
+	Diagnostics []lint.Diagnostic `json:"diagnostics"`
+}
+
+// Run validates a command file without executing anything. It parses the input,
+// runs the linter, and performs a dry code generation pass to resolve decorators,
+// imports, and variables. Problems are reported as diagnostics; the returned error
+// is only set when the input cannot be read.
+func Run(file string, reader io.Reader) (*Report, error) {
+	report := &Report{
+		File:        file,
+		Diagnostics: []lint.Diagnostic{},
+	}
+
+	program, parseDiagnostics, err := parser.ParseDiagnostics(reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range parseDiagnostics {
+		report.Diagnostics = append(report.Diagnostics, lint.Diagnostic{
+			Rule:     "parse",
+			Severity: lint.SeverityError,
+			Message:  d.Message,
+			Line:     d.Line,
+			Column:   d.Column,
+		})
+	}
+	if program == nil {
+		return report, nil
+	}
+
+	lintDiagnostics := lint.Lint(program)
+	report.Diagnostics = append(report.Diagnostics, lintDiagnostics...)
+
+	// Code generation resolves decorators, imports, and variables; only run it on
+	// programs the linter considers valid so errors aren't reported twice
+	if !lint.HasErrors(lintDiagnostics) {
+		if _, err := engine.New(program).GenerateCode(program); err != nil {
+			report.Diagnostics = append(report.Diagnostics, lint.Diagnostic{
+				Rule:     "codegen",
+				Severity: lint.SeverityError,
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	lint.Sort(report.Diagnostics)
+	return report, nil
+}
+
+// HasErrors reports whether the report contains any error diagnostics
+func (r *Report) HasErrors() bool {
+	return lint.HasErrors(r.Diagnostics)
+}
+
+// Counts returns the number of error and warning diagnostics
+func (r *Report) Counts() (errorCount, warningCount int) {
+	for _, d := range r.Diagnostics {
+		switch d.Severity {
+		case lint.SeverityError:
+			errorCount++
+		case lint.SeverityWarning:
+			warningCount++
+		}
+	}
+	return errorCount, warningCount
+}
+
+// WriteText writes diagnostics in "file:line:col: severity: message [rule]" form
+func (r *Report) WriteText(w io.Writer) error {
+	for _, d := range r.Diagnostics {
+		separator := ":"
+		if d.Line == 0 {
+			separator = ": "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", r.File, separator, d.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
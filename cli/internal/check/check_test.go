@@ -0,0 +1,153 @@
+package check
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/lint"
+
+	// Import builtins to register decorators
+	_ "github.com/aledsdavies/devcmd/cli/internal/builtins"
+)
+
+func TestRun_ValidFile(t *testing.T) {
+	report, err := Run("commands.cli", strings.NewReader("var PORT = 8080\nserve: go run . --port @var(PORT)"))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.HasErrors() || len(report.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", report.Diagnostics)
+	}
+}
+
+func TestRun_ParseErrors(t *testing.T) {
+	report, err := Run("commands.cli", strings.NewReader("build: echo ok\nbad: @timeout("))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !report.HasErrors() {
+		t.Fatal("expected parse errors")
+	}
+	if report.Diagnostics[0].Rule != "parse" || report.Diagnostics[0].Line != 2 {
+		t.Errorf("unexpected diagnostic: %+v", report.Diagnostics[0])
+	}
+}
+
+func TestRun_LintErrors(t *testing.T) {
+	report, err := Run("commands.cli", strings.NewReader("var UNUSED = 1\nall: @cmd(missing)"))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	errorCount, warningCount := report.Counts()
+	if errorCount != 1 || warningCount != 1 {
+		t.Errorf("counts = %d errors, %d warnings; want 1, 1", errorCount, warningCount)
+	}
+	for _, d := range report.Diagnostics {
+		if d.Rule == "codegen" {
+			t.Errorf("codegen should be skipped when lint finds errors, got %+v", d)
+		}
+	}
+}
+
+func TestReport_Output(t *testing.T) {
+	report := &Report{
+		File: "commands.cli",
+		Diagnostics: []lint.Diagnostic{
+			{Rule: "codegen", Severity: lint.SeverityError, Message: "boom"},
+			{Rule: "unused-variable", Severity: lint.SeverityWarning, Message: "unused", Line: 2, Column: 1},
+		},
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	expected := "commands.cli: error: boom [codegen]\ncommands.cli:2:1: warning: unused [unused-variable]\n"
+	if text.String() != expected {
+		t.Errorf("WriteText =\n%s\nwant\n%s", text.String(), expected)
+	}
+
+	var out strings.Builder
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.File != "commands.cli" || len(decoded.Diagnostics) != 2 {
+		t.Errorf("unexpected decoded report: %+v", decoded)
+	}
+}
+
+func TestReport_WriteSARIF(t *testing.T) {
+	report, err := Run("config/commands.cli", strings.NewReader("var UNUSED = 1\nall: @cmd(missing)"))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var out strings.Builder
+	if err := report.WriteSARIF(&out, "v1.2.3"); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF envelope: %+v", log)
+	}
+
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "devcmd" || run.Tool.Driver.Version != "v1.2.3" {
+		t.Errorf("unexpected driver: %+v", run.Tool.Driver)
+	}
+
+	ruleIDs := make(map[string]bool)
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs[rule.ID] = true
+	}
+	for _, result := range run.Results {
+		if !ruleIDs[result.RuleID] {
+			t.Errorf("result references undeclared rule %q", result.RuleID)
+		}
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+	warning := run.Results[0]
+	if warning.Level != "warning" || warning.RuleID != "unused-variable" {
+		t.Errorf("unexpected first result: %+v", warning)
+	}
+	location := warning.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "config/commands.cli" || location.Region.StartLine != 1 {
+		t.Errorf("unexpected location: %+v", location)
+	}
+	if run.Results[1].Level != "error" {
+		t.Errorf("expected error level for @cmd result, got %q", run.Results[1].Level)
+	}
+}
+
+func TestReport_WriteSARIF_UnlocatedDiagnostic(t *testing.T) {
+	report := &Report{
+		File:        "commands.cli",
+		Diagnostics: []lint.Diagnostic{{Rule: "codegen", Severity: lint.SeverityError, Message: "boom"}},
+	}
+
+	var out strings.Builder
+	if err := report.WriteSARIF(&out, ""); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if region := log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region; region.StartLine != 1 {
+		t.Errorf("unlocated diagnostics should point at line 1, got %+v", region)
+	}
+}
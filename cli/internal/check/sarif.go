@@ -0,0 +1,140 @@
+package check
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/aledsdavies/devcmd/cli/internal/lint"
+)
+
+// SARIF 2.1.0 constants used for code scanning integrations
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "devcmd"
+	toolURI      = "https://github.com/aledsdavies/devcmd"
+)
+
+// sarifLog is the top-level SARIF document
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// reportRules describes the non-lint rules that check can report
+var reportRules = []sarifRule{
+	{ID: "parse", ShortDescription: sarifMessage{Text: "The command file could not be parsed"}},
+	{ID: "codegen", ShortDescription: sarifMessage{Text: "Decorators, imports, or variables could not be resolved"}},
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log for code scanning tools
+func (r *Report) WriteSARIF(w io.Writer, toolVersion string) error {
+	rules := append([]sarifRule{}, reportRules...)
+	for _, rule := range lint.Rules {
+		rules = append(rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+
+	uri := filepath.ToSlash(r.File)
+	results := make([]sarifResult, 0, len(r.Diagnostics))
+	for _, d := range r.Diagnostics {
+		// SARIF consumers require a region; unlocated diagnostics point at the file start
+		region := sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+		if region.StartLine == 0 {
+			region = sarifRegion{StartLine: 1}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:  d.Rule,
+			Level:   sarifLevel(d.Severity),
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region:           region,
+				},
+			}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				Version:        toolVersion,
+				InformationURI: toolURI,
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// sarifLevel maps diagnostic severity to a SARIF result level
+func sarifLevel(severity lint.Severity) string {
+	switch severity {
+	case lint.SeverityError:
+		return "error"
+	case lint.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
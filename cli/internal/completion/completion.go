@@ -0,0 +1,178 @@
+package completion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/settings"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// Command is a command name offered for completion, with its description
+type Command struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Candidates are the names shells complete from a project's commands and settings files
+type Candidates struct {
+	Commands  []Command `json:"commands"`
+	Variables []string  `json:"variables"`
+	Profiles  []string  `json:"profiles"`
+}
+
+// cacheEntry is the cached candidates of a project with the state of the files they were
+// read from, so changes to any of them are noticed
+type cacheEntry struct {
+	Files      map[string]string `json:"files"`
+	Candidates Candidates        `json:"candidates"`
+}
+
+// DefaultCacheDir returns devcmd/completion in the user cache directory, or "" when there is none
+func DefaultCacheDir() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "devcmd", "completion")
+}
+
+// Load returns the candidates from a commands file, its local override file and a settings
+// file. They are cached in cacheDir until one of the files changes; an empty cacheDir
+// turns the cache off.
+func Load(cacheDir, commandsFile, settingsFile string) (*Candidates, error) {
+	files := []string{commandsFile, parser.LocalFileName(commandsFile), settingsFile}
+	stamps := make(map[string]string, len(files))
+	for _, file := range files {
+		stamps[file] = fileStamp(file)
+	}
+
+	var cacheFile string
+	if cacheDir != "" {
+		absolute, err := filepath.Abs(commandsFile)
+		if err != nil {
+			absolute = commandsFile
+		}
+		sum := sha256.Sum256([]byte(absolute + "\x00" + settingsFile))
+		cacheFile = filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".json")
+
+		var cached cacheEntry
+		if data, err := os.ReadFile(cacheFile); err == nil && json.Unmarshal(data, &cached) == nil && sameStamps(cached.Files, stamps) {
+			return &cached.Candidates, nil
+		}
+	}
+
+	candidates, err := read(commandsFile, settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	// The cache only saves time; completion works without it
+	if cacheFile != "" {
+		if data, err := json.Marshal(cacheEntry{Files: stamps, Candidates: *candidates}); err == nil {
+			if os.MkdirAll(cacheDir, 0o755) == nil {
+				_ = os.WriteFile(cacheFile, data, 0o644)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// read parses the files for their candidates
+func read(commandsFile, settingsFile string) (*Candidates, error) {
+	source, err := os.ReadFile(commandsFile)
+	if err != nil {
+		return nil, err
+	}
+	program, err := parser.Parse(strings.NewReader(string(source)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", commandsFile, err)
+	}
+	localFile := parser.LocalFileName(commandsFile)
+	localSource, err := os.ReadFile(localFile)
+	var overrides *parser.LocalOverrides
+	if err == nil {
+		local, err := parser.Parse(strings.NewReader(string(localSource)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", localFile, err)
+		}
+		if program, overrides, err = parser.MergeLocal(program, local, commandsFile, localFile); err != nil {
+			return nil, err
+		}
+	} else if err := parser.CheckOverrides(program, commandsFile); err != nil {
+		return nil, err
+	}
+
+	candidates := &Candidates{Commands: []Command{}, Variables: []string{}, Profiles: []string{}}
+	seen := make(map[string]bool)
+	for _, command := range program.Commands {
+		// Watch and stop commands share a name
+		if seen[command.Name] {
+			continue
+		}
+		seen[command.Name] = true
+		commandSource := source
+		if overrides.IsLocalCommand(command.Name) {
+			commandSource = localSource
+		}
+		description, _, _ := strings.Cut(engine.CommandDescription(commandSource, command.Pos.Line), "\n")
+		candidates.Commands = append(candidates.Commands, Command{Name: command.Name, Description: description})
+	}
+
+	variables := append([]ast.VariableDecl{}, program.Variables...)
+	for _, group := range program.VarGroups {
+		variables = append(variables, group.Variables...)
+	}
+	for _, variable := range variables {
+		candidates.Variables = append(candidates.Variables, variable.Name)
+	}
+	sort.Strings(candidates.Variables)
+
+	projectSettings, err := settings.Load(settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]bool)
+	for _, profile := range program.EnvProfiles {
+		profiles[profile.Name] = true
+		candidates.Profiles = append(candidates.Profiles, profile.Name)
+	}
+	for _, key := range projectSettings.Keys() {
+		if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+			if profile, _, ok := strings.Cut(rest, "."); ok && !profiles[profile] {
+				profiles[profile] = true
+				candidates.Profiles = append(candidates.Profiles, profile)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// fileStamp identifies the state of a file by its size and modification time, or "" when it
+// doesn't exist
+func fileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+func sameStamps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, stamp := range a {
+		if other, ok := b[file]; !ok || other != stamp {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	commandsFile := filepath.Join(dir, "commands.cli")
+	settingsFile := filepath.Join(dir, "devcmd.settings")
+	writeFile(t, commandsFile, `var PORT = 8080
+var (
+    HOST = "localhost"
+)
+
+# Build the binaries
+# with the race detector
+build: go build -race ./...
+
+watch server: go run . --port @var(PORT)
+stop server: pkill server
+`)
+	writeFile(t, filepath.Join(dir, "commands.local.cli"), `var TOKEN = "dev"
+# Mine only
+mine: echo @var(TOKEN)
+`)
+	writeFile(t, settingsFile, `profiles {
+    prod {
+        HOST = "example.com"
+    }
+    staging {
+        HOST = "staging.example.com"
+    }
+}
+`)
+
+	got, err := Load("", commandsFile, settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Candidates{
+		Commands: []Command{
+			{Name: "build", Description: "Build the binaries"},
+			{Name: "server"},
+			{Name: "mine", Description: "Mine only"},
+		},
+		Variables: []string{"HOST", "PORT", "TOKEN"},
+		Profiles:  []string{"prod", "staging"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	commandsFile := filepath.Join(dir, "commands.cli")
+	settingsFile := filepath.Join(dir, "devcmd.settings")
+	writeFile(t, commandsFile, "build: go build\n")
+
+	if _, err := Load(cacheDir, commandsFile, settingsFile); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache entries = %v, %v; want one", entries, err)
+	}
+
+	// A changed file is read again rather than served from the cache
+	writeFile(t, commandsFile, "build: go build\ntest: go test\n")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(commandsFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(cacheDir, commandsFile, settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Commands) != 2 {
+		t.Errorf("commands after change = %v, want build and test", got.Commands)
+	}
+
+	// A corrupt cache entry is ignored
+	writeFile(t, filepath.Join(cacheDir, entries[0].Name()), "{")
+	if got, err := Load(cacheDir, commandsFile, settingsFile); err != nil || len(got.Commands) != 2 {
+		t.Errorf("Load() with corrupt cache = %v, %v", got, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load("", filepath.Join(dir, "missing.cli"), ""); err == nil {
+		t.Error("Load() of a missing commands file succeeded")
+	}
+	commandsFile := filepath.Join(dir, "commands.cli")
+	writeFile(t, commandsFile, "build {\n")
+	if _, err := Load("", commandsFile, ""); err == nil {
+		t.Error("Load() of an invalid commands file succeeded")
+	}
+}
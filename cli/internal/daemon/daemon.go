@@ -0,0 +1,574 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/metrics"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+)
+
+// SocketFile is the daemon's socket in the root of the process registry
+const SocketFile = "daemon.sock"
+
+// SupervisedEnvVar is set to the process name in processes the daemon starts, so a
+// generated CLI runs its watch command in the foreground instead of handing it back
+const SupervisedEnvVar = "DEVCMD_SUPERVISED"
+
+// Operations a Request can ask for
+const (
+	OpPing     = "ping"
+	OpStart    = "start"
+	OpStop     = "stop"
+	OpList     = "list"
+	OpShutdown = "shutdown"
+)
+
+// Restart policies for supervised processes
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// DefaultRestart is the restart policy of processes that don't name one
+const DefaultRestart = RestartOnFailure
+
+// ValidateRestart checks that policy is a known restart policy
+func ValidateRestart(policy string) error {
+	switch policy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return nil
+	}
+	return fmt.Errorf("unknown restart policy %q (expected %s, %s or %s)", policy, RestartNever, RestartOnFailure, RestartAlways)
+}
+
+// Request is a message from a client. Clients send one JSON request per connection and
+// read one Response. Generated CLIs contain copies of both types without tags, so JSON keys
+// must stay the field names in lower camel case.
+type Request struct {
+	Op        string   `json:"op"`
+	Namespace string   `json:"namespace,omitempty"`
+	Project   string   `json:"project,omitempty"` // Project directory, recorded in the namespace
+	Name      string   `json:"name,omitempty"`
+	Command   []string `json:"command,omitempty"` // Program and arguments to run
+	Dir       string   `json:"dir,omitempty"`
+	Env       []string `json:"env,omitempty"`
+	Restart   string   `json:"restart,omitempty"`
+}
+
+// Response is the daemon's reply to a Request
+type Response struct {
+	Error     string   `json:"error,omitempty"`
+	PID       int      `json:"pid,omitempty"`
+	LogFile   string   `json:"logFile,omitempty"`
+	Processes []Status `json:"processes,omitempty"`
+}
+
+// Status describes a supervised process
+type Status struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	PID       int    `json:"pid"`
+	Restart   string `json:"restart"`
+	Restarts  int    `json:"restarts"`
+}
+
+// SocketPath returns the path of the daemon socket for a registry
+func SocketPath(registry processes.Registry) string {
+	return filepath.Join(registry.Root, SocketFile)
+}
+
+// Call sends a request to the daemon of a registry and returns its response. Errors the
+// daemon reports are returned as errors.
+func Call(registry processes.Registry, request Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(registry), time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to read daemon response: %w", err)
+	}
+	if response.Error != "" {
+		return &response, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// Running reports whether a daemon answers on the registry's socket
+func Running(registry processes.Registry) bool {
+	_, err := Call(registry, Request{Op: OpPing})
+	return err == nil
+}
+
+// LogFile is the daemon's own log in the root of the process registry, when it is started
+// with StartDetached
+const LogFile = "daemon.log"
+
+// StartDetached starts command, a devcmd daemon for the registry, in the background, detached
+// from the terminal, and waits until it answers
+func StartDetached(registry processes.Registry, command []string) (int, error) {
+	if err := os.MkdirAll(registry.Root, 0o755); err != nil {
+		return 0, err
+	}
+	log, err := os.OpenFile(filepath.Join(registry.Root, LogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("daemon exited (%s); see %s", exitDescription(err), log.Name())
+		case <-time.After(50 * time.Millisecond):
+		}
+		if Running(registry) {
+			return cmd.Process.Pid, nil
+		}
+	}
+	return 0, fmt.Errorf("daemon didn't answer on %s; see %s", SocketPath(registry), log.Name())
+}
+
+// Daemon supervises background processes for generated CLIs. It records each process in
+// the registry like an unsupervised one, so devcmd ps and the CLIs' status and logs
+// subcommands work unchanged, appends its output to the process's log file, and restarts
+// it according to its restart policy until it is stopped.
+type Daemon struct {
+	Registry processes.Registry
+	// Backoff is the delay before the first restart of a failing process; it doubles with
+	// every further restart, up to MaxBackoff, and resets once a run lasts that long
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Metrics records each run of a supervised process as a run of the command of its name,
+	// and reports the supervised processes as running
+	Metrics *metrics.Registry
+
+	mu        sync.Mutex
+	processes map[string]*supervised
+	listener  net.Listener
+	shutdown  chan struct{}
+	closing   sync.Once
+	handlers  sync.WaitGroup
+}
+
+// supervised is a process the daemon started and restarts
+type supervised struct {
+	request  Request
+	cmd      *exec.Cmd
+	restarts int
+	stopping bool
+	stop     chan struct{} // Closed to stop the process and cancel pending restarts
+	done     chan struct{} // Closed when the process has exited for good
+}
+
+// New creates a daemon for a registry
+func New(registry processes.Registry) *Daemon {
+	d := &Daemon{
+		Registry:   registry,
+		Backoff:    time.Second,
+		MaxBackoff: 30 * time.Second,
+		Metrics:    metrics.NewRegistry(),
+		processes:  make(map[string]*supervised),
+		shutdown:   make(chan struct{}),
+	}
+	d.Metrics.SetProcessHealth(func() map[string]bool {
+		health := make(map[string]bool)
+		for _, status := range d.list() {
+			health[status.Name] = true
+		}
+		return health
+	})
+	return d
+}
+
+// Serve listens on the registry's socket and handles requests until ctx is cancelled or a
+// client asks the daemon to shut down, then stops every supervised process
+func (d *Daemon) Serve(ctx context.Context) error {
+	if err := os.MkdirAll(d.Registry.Root, 0o755); err != nil {
+		return err
+	}
+	socket := SocketPath(d.Registry)
+	if Running(d.Registry) {
+		return fmt.Errorf("a devcmd daemon is already running on %s", socket)
+	}
+	_ = os.Remove(socket) // Left behind by a daemon that didn't shut down cleanly
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	d.listener = listener
+	defer os.Remove(socket)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-d.shutdown:
+		}
+		d.close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-d.shutdown:
+				d.stopAll()
+				d.handlers.Wait()
+				return nil
+			default:
+				return err
+			}
+		}
+		d.handlers.Add(1)
+		go func() {
+			defer d.handlers.Done()
+			d.handle(conn)
+		}()
+	}
+}
+
+// close stops accepting connections, which makes Serve stop the processes and return once
+// the requests being handled are answered
+func (d *Daemon) close() {
+	d.closing.Do(func() {
+		close(d.shutdown)
+		if d.listener != nil {
+			_ = d.listener.Close()
+		}
+	})
+}
+
+// handle answers one request
+func (d *Daemon) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var request Request
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	var response Response
+	var err error
+	switch request.Op {
+	case OpPing:
+	case OpStart:
+		response, err = d.start(request)
+	case OpStop:
+		response, err = d.stopProcess(request.Namespace, request.Name)
+	case OpList:
+		response.Processes = d.list()
+	case OpShutdown:
+		d.close()
+		d.stopAll()
+	default:
+		err = fmt.Errorf("unknown operation %q", request.Op)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	_ = json.NewEncoder(conn).Encode(response)
+}
+
+// start launches a process and supervises it
+func (d *Daemon) start(request Request) (Response, error) {
+	if request.Namespace == "" || request.Name == "" || len(request.Command) == 0 {
+		return Response{}, fmt.Errorf("start needs a namespace, name and command")
+	}
+	if request.Restart == "" {
+		request.Restart = DefaultRestart
+	}
+	if err := ValidateRestart(request.Restart); err != nil {
+		return Response{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.shutdown:
+		return Response{}, fmt.Errorf("the daemon is shutting down")
+	default:
+	}
+
+	key := processKey(request.Namespace, request.Name)
+	if p, ok := d.processes[key]; ok {
+		return Response{}, fmt.Errorf("process %s is already running (PID: %d)", request.Name, p.cmd.Process.Pid)
+	}
+
+	if existing, ok := d.Registry.Lookup(request.Namespace, request.Name); ok && existing.Running {
+		return Response{}, fmt.Errorf("process %s is already running (PID: %d)", request.Name, existing.PID)
+	}
+
+	project := request.Project
+	if project == "" {
+		project = request.Dir
+	}
+	if err := d.Registry.Register(request.Namespace, project); err != nil {
+		return Response{}, fmt.Errorf("failed to register process %s: %w", request.Name, err)
+	}
+	logFile := d.logFile(request)
+	log, err := os.Create(logFile)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create log file: %w", err)
+	}
+	log.Close()
+
+	p := &supervised{request: request, stop: make(chan struct{}), done: make(chan struct{})}
+	if err := d.launch(p); err != nil {
+		return Response{}, err
+	}
+	d.processes[key] = p
+	go d.supervise(p)
+
+	return Response{PID: p.cmd.Process.Pid, LogFile: logFile}, nil
+}
+
+// launch starts a run of a process, appending its output to its log file, and records its PID
+func (d *Daemon) launch(p *supervised) error {
+	store := d.Registry.Store(p.request.Namespace)
+	_ = store.Remove(p.request.Name + ".ports")
+
+	log, err := os.OpenFile(d.logFile(p.request), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(p.request.Command[0], p.request.Command[1:]...)
+	cmd.Dir = p.request.Dir
+	cmd.Env = append(append([]string{}, p.request.Env...), SupervisedEnvVar+"="+p.request.Name)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = processGroupAttr()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", p.request.Name, err)
+	}
+	p.cmd = cmd
+
+	if err := store.Write(p.request.Name+".pid", []byte(strconv.Itoa(cmd.Process.Pid))); err != nil {
+		_ = terminate(cmd.Process, true)
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	_ = store.Record(processes.Event{Name: p.request.Name, Event: processes.EventStarted, PID: cmd.Process.Pid})
+	return nil
+}
+
+// supervise waits for each run of a process and restarts it as its policy says
+func (d *Daemon) supervise(p *supervised) {
+	defer close(p.done)
+	defer func() {
+		d.mu.Lock()
+		delete(d.processes, processKey(p.request.Namespace, p.request.Name))
+		d.mu.Unlock()
+	}()
+
+	backoff := d.Backoff
+	for {
+		started := time.Now()
+		err := p.cmd.Wait()
+		pid := p.cmd.Process.Pid
+
+		d.mu.Lock()
+		stopping := p.stopping || !d.ownsPIDFile(p.request, pid)
+		d.mu.Unlock()
+		if !stopping {
+			// Processes that are stopped don't fail
+			status := "success"
+			if err != nil {
+				status = "failed"
+			}
+			d.Metrics.ObserveCommand(p.request.Name, status, time.Since(started))
+			_ = d.Registry.Store(p.request.Namespace).Record(processes.Event{
+				Name:     p.request.Name,
+				Event:    processes.EventExited,
+				PID:      pid,
+				ExitCode: p.cmd.ProcessState.ExitCode(),
+			})
+		}
+		if stopping || !restart(p.request.Restart, err) {
+			d.cleanup(p.request, pid)
+			return
+		}
+
+		if time.Since(started) >= d.MaxBackoff {
+			backoff = d.Backoff
+		}
+		d.logf(p.request, "%s exited (%s); restarting in %s", p.request.Name, exitDescription(err), backoff)
+		select {
+		case <-p.stop:
+			d.cleanup(p.request, pid)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, d.MaxBackoff)
+
+		d.mu.Lock()
+		if p.stopping || !d.ownsPIDFile(p.request, pid) {
+			d.mu.Unlock()
+			d.cleanup(p.request, pid)
+			return
+		}
+		p.restarts++
+		err = d.launch(p)
+		d.mu.Unlock()
+		if err != nil {
+			d.logf(p.request, "%v", err)
+			d.cleanup(p.request, pid)
+			return
+		}
+	}
+}
+
+// stopProcess terminates a supervised process so it isn't restarted, giving it the stop
+// timeout its service records to exit before killing it
+func (d *Daemon) stopProcess(namespace, name string) (Response, error) {
+	timeout := processes.DefaultStopTimeout
+	if recorded, ok := d.Registry.Lookup(namespace, name); ok {
+		timeout = recorded.StopTimeout()
+	}
+
+	d.mu.Lock()
+	p, ok := d.processes[processKey(namespace, name)]
+	if !ok {
+		d.mu.Unlock()
+		return Response{}, fmt.Errorf("process %s is not supervised by the daemon", name)
+	}
+	pid := p.cmd.Process.Pid
+	if !p.stopping {
+		p.stopping = true
+		close(p.stop)
+		_ = terminate(p.cmd.Process, false)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+		_ = terminate(p.cmd.Process, true)
+		<-p.done
+	}
+	_ = d.Registry.Store(namespace).Record(processes.Event{Name: name, Event: processes.EventStopped, PID: pid})
+	return Response{PID: pid}, nil
+}
+
+// stopAll stops every supervised process, each before the processes its service depends on
+func (d *Daemon) stopAll() {
+	var supervised []processes.Process
+	for _, status := range d.list() {
+		process := processes.Process{Namespace: status.Namespace, Name: status.Name}
+		if recorded, ok := d.Registry.Lookup(status.Namespace, status.Name); ok {
+			process.Service = recorded.Service
+		}
+		supervised = append(supervised, process)
+	}
+	for _, process := range processes.OrderForStop(supervised) {
+		_, _ = d.stopProcess(process.Namespace, process.Name)
+	}
+}
+
+// list returns the supervised processes, sorted by namespace and name
+func (d *Daemon) list() []Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var list []Status
+	for _, p := range d.processes {
+		list = append(list, Status{
+			Namespace: p.request.Namespace,
+			Name:      p.request.Name,
+			PID:       p.cmd.Process.Pid,
+			Restart:   p.request.Restart,
+			Restarts:  p.restarts,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return processKey(list[i].Namespace, list[i].Name) < processKey(list[j].Namespace, list[j].Name)
+	})
+	return list
+}
+
+// ownsPIDFile reports whether a process's PID file still names pid. A process stopped
+// without the daemon, e.g. by devcmd ps --stop, has lost its PID file and isn't restarted.
+func (d *Daemon) ownsPIDFile(request Request, pid int) bool {
+	content, err := d.Registry.Store(request.Namespace).Read(request.Name + ".pid")
+	return err == nil && strings.TrimSpace(string(content)) == strconv.Itoa(pid)
+}
+
+// cleanup removes the PID and ports files of a process that has exited for good
+func (d *Daemon) cleanup(request Request, pid int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	store := d.Registry.Store(request.Namespace)
+	if d.ownsPIDFile(request, pid) {
+		_ = store.Remove(request.Name + ".pid")
+	}
+	_ = store.Remove(request.Name + ".ports")
+}
+
+// logf appends a daemon message to a process's log file
+func (d *Daemon) logf(request Request, format string, args ...interface{}) {
+	log, err := os.OpenFile(d.logFile(request), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer log.Close()
+	fmt.Fprintf(log, "[devcmd daemon] "+format+"\n", args...)
+}
+
+func (d *Daemon) logFile(request Request) string {
+	return filepath.Join(d.Registry.Dir(request.Namespace), request.Name+".log")
+}
+
+// restart reports whether a process that exited with err should be restarted under policy
+func restart(policy string, err error) bool {
+	switch policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// exitDescription describes how a run ended
+func exitDescription(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+func processKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,238 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+)
+
+// startDaemon serves a daemon for a temporary registry until the test ends
+func startDaemon(t *testing.T) (*Daemon, processes.Registry) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("tests run processes with sh")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	d := New(registry)
+	d.Backoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- d.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); !Running(registry); {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon didn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return d, registry
+}
+
+func shellRequest(name, script, restart string) Request {
+	return Request{
+		Op:        OpStart,
+		Namespace: "project-1234",
+		Project:   "/src/project",
+		Name:      name,
+		Command:   []string{"sh", "-c", script},
+		Env:       os.Environ(),
+		Restart:   restart,
+	}
+}
+
+// waitFor polls until condition holds
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDaemon_StartListStop(t *testing.T) {
+	_, registry := startDaemon(t)
+
+	started, err := Call(registry, shellRequest("api", `echo "supervised as $DEVCMD_SUPERVISED"; exec sleep 30`, ""))
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := Call(registry, shellRequest("api", "sleep 30", "")); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("starting a running process again returned %v, want an already running error", err)
+	}
+
+	list, err := registry.List("project-1234")
+	if err != nil || len(list) != 1 || !list[0].Running || list[0].PID != started.PID || list[0].Project != "/src/project" {
+		t.Fatalf("registry lists %+v, %v; want the running process", list, err)
+	}
+	listed, err := Call(registry, Request{Op: OpList})
+	if err != nil || len(listed.Processes) != 1 || listed.Processes[0].Restart != DefaultRestart {
+		t.Fatalf("list returned %+v, %v", listed, err)
+	}
+	waitFor(t, "output in the log", func() bool {
+		log, _ := os.ReadFile(started.LogFile)
+		return strings.Contains(string(log), "supervised as api")
+	})
+
+	if _, err := Call(registry, Request{Op: OpStop, Namespace: "project-1234", Name: "api"}); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	if list, _ := registry.List("project-1234"); len(list) != 0 {
+		t.Errorf("stopped process is still listed: %+v", list)
+	}
+	if _, err := Call(registry, Request{Op: OpStop, Namespace: "project-1234", Name: "api"}); err == nil {
+		t.Errorf("stopping a process that isn't supervised should fail")
+	}
+}
+
+func TestDaemon_RestartPolicies(t *testing.T) {
+	d, registry := startDaemon(t)
+	dir := registry.Dir("project-1234")
+
+	if _, err := Call(registry, shellRequest("flaky", "echo run; exit 1", RestartOnFailure)); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	waitFor(t, "restarts", func() bool {
+		log, _ := os.ReadFile(filepath.Join(dir, "flaky.log"))
+		return strings.Count(string(log), "run\n") >= 3 && strings.Contains(string(log), "[devcmd daemon] flaky exited (exit status 1); restarting")
+	})
+	if _, err := Call(registry, Request{Op: OpStop, Namespace: "project-1234", Name: "flaky"}); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	var metrics strings.Builder
+	if _, err := d.Metrics.WriteTo(&metrics); err != nil || !strings.Contains(metrics.String(), `devcmd_command_runs_total{command="flaky",status="failed"}`) {
+		t.Errorf("metrics = %s, %v, want the failed runs of flaky", metrics.String(), err)
+	}
+
+	if _, err := Call(registry, shellRequest("once", "echo run", RestartOnFailure)); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	waitFor(t, "the process to finish", func() bool {
+		_, err := os.Stat(filepath.Join(dir, "once.pid"))
+		return os.IsNotExist(err)
+	})
+	if log, _ := os.ReadFile(filepath.Join(dir, "once.log")); string(log) != "run\n" {
+		t.Errorf("a process that succeeded was restarted: %q", log)
+	}
+
+	if _, err := Call(registry, shellRequest("bad", "true", "sometimes")); err == nil || !strings.Contains(err.Error(), "unknown restart policy") {
+		t.Errorf("unknown restart policy returned %v", err)
+	}
+}
+
+func TestDaemon_ExternalStopIsNotRestarted(t *testing.T) {
+	_, registry := startDaemon(t)
+
+	started, err := Call(registry, shellRequest("worker", "exec sleep 30", RestartAlways))
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	list, err := registry.List("project-1234")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %+v, %v", list, err)
+	}
+	// devcmd ps --stop stops processes without asking the daemon
+	if err := registry.Stop(list[0]); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	waitFor(t, "the daemon to let the process go", func() bool {
+		listed, err := Call(registry, Request{Op: OpList})
+		return err == nil && len(listed.Processes) == 0
+	})
+	if _, err := os.Stat(filepath.Join(registry.Dir("project-1234"), "worker.pid")); !os.IsNotExist(err) {
+		t.Errorf("process %d was restarted after being stopped", started.PID)
+	}
+}
+
+func TestDaemon_ShutdownStopsProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tests run processes with sh")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	served := make(chan error, 1)
+	go func() { served <- New(registry).Serve(context.Background()) }()
+	waitFor(t, "the daemon to start", func() bool { return Running(registry) })
+
+	if err := New(registry).Serve(context.Background()); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("a second daemon returned %v, want an already running error", err)
+	}
+
+	started, err := Call(registry, shellRequest("api", "exec sleep 30", ""))
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := Call(registry, Request{Op: OpShutdown}); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+
+	if process, err := os.FindProcess(started.PID); err == nil && process.Signal(syscall.Signal(0)) == nil {
+		t.Errorf("process %d survived the daemon", started.PID)
+	}
+	if _, err := os.Stat(SocketPath(registry)); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed")
+	}
+}
+
+func TestDaemon_ShutdownStopsDependentsFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tests run processes with sh")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	served := make(chan error, 1)
+	go func() { served <- New(registry).Serve(context.Background()) }()
+	waitFor(t, "the daemon to start", func() bool { return Running(registry) })
+
+	dir := registry.Dir("project-1234")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	order := filepath.Join(t.TempDir(), "order")
+	services := map[string]processes.Service{
+		"db":  {},
+		"api": {DependsOn: []string{"db"}},
+		"web": {DependsOn: []string{"api"}, StopTimeout: 2 * time.Second},
+	}
+	for _, name := range []string{"db", "web", "api"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".service"), []byte(services[name].String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// web takes a while to shut down, within its stop timeout
+		script := `trap 'echo ` + name + ` >> "` + order + `"; exit 0' TERM; while true; do sleep 0.05; done`
+		if name == "web" {
+			script = `trap 'sleep 0.3; echo web >> "` + order + `"; exit 0' TERM; while true; do sleep 0.05; done`
+		}
+		if _, err := Call(registry, shellRequest(name, script, "")); err != nil {
+			t.Fatalf("start %s failed: %v", name, err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond) // Let the shells install their traps
+
+	if _, err := Call(registry, Request{Op: OpShutdown}); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+	got, _ := os.ReadFile(order)
+	if string(got) != "web\napi\ndb\n" {
+		t.Errorf("processes stopped in order %q, want web, api, db", got)
+	}
+}
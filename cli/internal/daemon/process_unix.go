@@ -0,0 +1,31 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// processGroupAttr starts a process in its own process group, so stopping it also stops
+// the commands it runs
+func processGroupAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// detachAttr starts the daemon in a new session, so it survives the terminal closing
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// terminate signals a process's group with SIGTERM, or SIGKILL when kill is set
+func terminate(process *os.Process, kill bool) error {
+	signal := syscall.SIGTERM
+	if kill {
+		signal = syscall.SIGKILL
+	}
+	if err := syscall.Kill(-process.Pid, signal); err != nil {
+		return process.Signal(signal)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// processGroupAttr starts a process in its own process group
+func processGroupAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// detachAttr starts the daemon without the console, so it survives the terminal closing
+func detachAttr() *syscall.SysProcAttr {
+	const detachedProcess = 0x00000008
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
+
+// terminate kills a process; Windows has no SIGTERM to ask it to exit
+func terminate(process *os.Process, kill bool) error {
+	return process.Kill()
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+)
+
+// PlanWithValues returns the plan of a command with the values it interpolates, as dry runs
+// and approvals show it
+func (e *Engine) PlanWithValues(command *ast.CommandDecl, environ []string, profile EnvProfile, overridden []string) (*plan.ExecutionPlan, error) {
+	executionPlan, err := e.ExecuteCommandPlan(command)
+	if err != nil {
+		return nil, err
+	}
+	if executionPlan.Values, err = e.PlanValues(command, environ, profile, overridden); err != nil {
+		return nil, err
+	}
+	return executionPlan, nil
+}
+
+// PlanDigest returns a digest of what a plan runs: its steps and the values they interpolate.
+// A plan computed again has the same digest unless something it runs has changed.
+func PlanDigest(executionPlan *plan.ExecutionPlan) (string, error) {
+	data, err := json.Marshal(struct {
+		Command interface{}          `json:"command"`
+		Steps   []plan.ExecutionStep `json:"steps"`
+		Values  []plan.ValueInfo     `json:"values"`
+	}{executionPlan.Context["command_name"], executionPlan.Steps, executionPlan.Values})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckApprovedPlan plans a command again just before it runs and returns an error unless
+// the plan has the digest of the one approved. This is a staleness check: the command still
+// runs from its definition, so what a plan doesn't show is evaluated again when it runs, such
+// as secret values, $VAR and $(...) in shell text, @glob matches and @freeport ports.
+func (e *Engine) CheckApprovedPlan(command *ast.CommandDecl, approved string, environ []string, profile EnvProfile, overridden []string) error {
+	executionPlan, err := e.PlanWithValues(command, environ, profile, overridden)
+	if err != nil {
+		return err
+	}
+	digest, err := PlanDigest(executionPlan)
+	if err != nil {
+		return err
+	}
+	if digest != approved {
+		return fmt.Errorf("the plan of %s changed after it was approved, so it was not run: plan it again", command.Name)
+	}
+	return nil
+}
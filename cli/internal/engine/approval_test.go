@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestCheckApprovedPlan(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`var API_URL = "http://localhost:8080"
+deploy: ./deploy.sh @var(API_URL) @env(REGION, default = "eu-west-1")`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	command := &program.Commands[0]
+	environ := []string{"REGION=us-east-1"}
+
+	executionPlan, err := eng.PlanWithValues(command, environ, EnvProfile{}, nil)
+	if err != nil {
+		t.Fatalf("PlanWithValues failed: %v", err)
+	}
+	approved, err := PlanDigest(executionPlan)
+	if err != nil {
+		t.Fatalf("PlanDigest failed: %v", err)
+	}
+	if err := eng.CheckApprovedPlan(command, approved, environ, EnvProfile{}, nil); err != nil {
+		t.Errorf("an unchanged plan should run: %v", err)
+	}
+
+	// Values the plan interpolates are part of what was approved
+	if err := eng.CheckApprovedPlan(command, approved, []string{"REGION=ap-south-1"}, EnvProfile{}, nil); err == nil || !strings.Contains(err.Error(), "the plan of deploy changed after it was approved") {
+		t.Errorf("expected a changed environment to be refused, got %v", err)
+	}
+	program.Variables[0].Value = &ast.StringLiteral{Value: "https://api.example.com"}
+	if err := eng.CheckApprovedPlan(command, approved, environ, EnvProfile{}, nil); err == nil {
+		t.Error("expected a changed variable to be refused")
+	}
+}
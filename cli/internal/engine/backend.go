@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// Backend generates a program in a target language from the engine's analysis of a commands
+// file, so targets share the checks and facts Analyze works out instead of each walking the
+// AST for them. The Go backend, which writes the CLIs devcmd builds, is registered by default;
+// other backends register themselves from their package's init, as decorators do.
+type Backend interface {
+	// Name identifies the backend, as given to devcmd generate --backend
+	Name() string
+	// Description returns a human-readable description
+	Description() string
+	// Generate returns the generated program. Generated code decorators contribute is Go, so
+	// backends for other targets run commands' steps through the interpreter or reject them.
+	Generate(e *Engine, analysis *Analysis, moduleName string) (*GenerationResult, error)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Backend)
+)
+
+// RegisterBackend makes a backend available by name, replacing one of the same name
+func RegisterBackend(backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[backend.Name()] = backend
+}
+
+// GetBackend returns the named backend
+func GetBackend(name string) (Backend, error) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	if backend, ok := backends[name]; ok {
+		return backend, nil
+	}
+	var names []string
+	for registered := range backends {
+		names = append(names, registered)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown backend %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Backends returns the registered backends, by name
+func Backends() []Backend {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	list := make([]Backend, 0, len(backends))
+	for _, backend := range backends {
+		list = append(list, backend)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Analysis is a program as backends generate it: checked, with its commands grouped and
+// ordered, and the facts about them that every target needs
+type Analysis struct {
+	Program *ast.Program
+	Groups  CommandGroups
+	// Commands are the regular commands, each after the commands it runs with @cmd
+	Commands []*ast.CommandDecl
+	// Variables are the program's variables in file order, then those only @set defines
+	Variables []AnalyzedVariable
+	// Aliases are the aliases of each command, from the aliases settings
+	Aliases map[string][]string
+	// StopOrder is the watch commands in the order stop --all stops them
+	StopOrder []string
+}
+
+// AnalyzedVariable is a variable with its resolved value and how the commands use it
+type AnalyzedVariable struct {
+	Name     string
+	Value    string
+	Used     bool // Referenced by a command or trigger
+	Assigned bool // Assigned by @set, so its value can change while commands run
+	Profiled bool // Given another value by an env profile, selected when the CLI starts
+}
+
+// Analyze checks program for generation and works out what backends generate from it
+func (e *Engine) Analyze(program *ast.Program) (*Analysis, error) {
+	// Validate @cmd decorator references before code generation
+	if err := e.validateCommandReferences(program); err != nil {
+		return nil, err
+	}
+	if err := e.validateTriggers(program); err != nil {
+		return nil, err
+	}
+	if err := validateNeeds(program); err != nil {
+		return nil, err
+	}
+	for event := range e.cliOptions.Hooks {
+		if _, err := execution.ParseEventType(event); err != nil {
+			return nil, fmt.Errorf("hooks: %w", err)
+		}
+	}
+
+	analysis := &Analysis{Program: program, Groups: e.analyzeCommands(program.Commands)}
+	var err error
+	if analysis.StopOrder, err = e.processStopOrder(analysis.Groups); err != nil {
+		return nil, err
+	}
+	if analysis.Aliases, err = e.resolveAliases(program); err != nil {
+		return nil, err
+	}
+
+	// Track which variables are used or assigned across all commands
+	usedVariables := make(map[string]bool)
+	assignedVariables := make(map[string]bool)
+	for _, cmd := range program.Commands {
+		e.trackVariableUsageInBody(&cmd.Body, usedVariables)
+		for _, content := range cmd.Body.Content {
+			e.trackVariableAssignments(content, assignedVariables)
+		}
+	}
+	for _, trigger := range program.Triggers {
+		e.trackVariableUsageInBody(&trigger.Body, usedVariables)
+		for _, content := range trigger.Body.Content {
+			e.trackVariableAssignments(content, assignedVariables)
+		}
+	}
+
+	for _, variable := range program.Variables {
+		value, err := e.resolveVariableValueSimple(variable.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve variable %s: %w", variable.Name, err)
+		}
+		analysis.Variables = append(analysis.Variables, AnalyzedVariable{
+			Name:     variable.Name,
+			Value:    value,
+			Used:     usedVariables[variable.Name],
+			Assigned: assignedVariables[variable.Name],
+			Profiled: isProfiled(program, variable.Name),
+		})
+		delete(assignedVariables, variable.Name)
+	}
+
+	// Variables that only @set defines are declared empty, so any command can reference them
+	newVariables := make([]string, 0, len(assignedVariables))
+	for name := range assignedVariables {
+		newVariables = append(newVariables, name)
+	}
+	sort.Strings(newVariables)
+	for _, name := range newVariables {
+		analysis.Variables = append(analysis.Variables, AnalyzedVariable{Name: name, Used: true, Assigned: true})
+	}
+
+	// Sort commands by dependencies to ensure proper declaration order
+	if analysis.Commands, err = e.sortCommandsByDependencies(analysis.Groups.RegularCommands); err != nil {
+		return nil, fmt.Errorf("failed to sort commands by dependencies: %w", err)
+	}
+
+	return analysis, nil
+}
+
+// isProfiled reports whether any env profile of program sets the variable
+func isProfiled(program *ast.Program, name string) bool {
+	for i := range program.EnvProfiles {
+		if program.EnvProfiles[i].Variable(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate generates program with the named backend
+func (e *Engine) Generate(backendName string, program *ast.Program, moduleName string) (*GenerationResult, error) {
+	backend, err := GetBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGeneratorSupport(program); err != nil {
+		return nil, err
+	}
+	analysis, err := e.Analyze(program)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Generate(e, analysis, moduleName)
+}
+
+// goBackend generates the Go source and go.mod of a standalone CLI, which devcmd build compiles
+type goBackend struct{}
+
+// GoBackend is the name of the backend generating Go CLIs
+const GoBackend = "go"
+
+// Name returns the backend name
+func (goBackend) Name() string {
+	return GoBackend
+}
+
+// Description returns a human-readable description
+func (goBackend) Description() string {
+	return "Go source and go.mod of a standalone CLI built on cobra"
+}
+
+// Generate generates the CLI's main.go and go.mod
+func (goBackend) Generate(e *Engine, analysis *Analysis, moduleName string) (*GenerationResult, error) {
+	identifiers, err := e.commandIdentifiers(analysis)
+	if err != nil {
+		return nil, err
+	}
+	return e.generateCodeWithTemplate(analysis, moduleName, identifiers)
+}
+
+// init registers the Go backend
+func init() {
+	RegisterBackend(goBackend{})
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+// outlineBackend lists the commands it is given, in order, with their variables
+type outlineBackend struct{}
+
+func (outlineBackend) Name() string        { return "outline" }
+func (outlineBackend) Description() string { return "Outline of the commands" }
+
+func (outlineBackend) Generate(e *Engine, analysis *Analysis, moduleName string) (*GenerationResult, error) {
+	result := &GenerationResult{FileName: "outline.txt"}
+	for _, command := range analysis.Commands {
+		fmt.Fprintf(&result.Code, "%s\n", command.Name)
+	}
+	for _, variable := range analysis.Variables {
+		fmt.Fprintf(&result.Code, "%s=%q used=%t assigned=%t\n", variable.Name, variable.Value, variable.Used, variable.Assigned)
+	}
+	return result, nil
+}
+
+func TestGenerate_Backends(t *testing.T) {
+	RegisterBackend(outlineBackend{})
+	defer func() {
+		backendsMu.Lock()
+		delete(backends, "outline")
+		backendsMu.Unlock()
+	}()
+
+	program, err := parser.Parse(strings.NewReader(`var VERSION = "1.0"
+var UNUSED = "x"
+release: @cmd(build) && @set(TAG = "v@var(VERSION)")
+build: go build`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+
+	result, err := eng.Generate("outline", program, "")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	want := `build
+release
+VERSION="1.0" used=true assigned=false
+UNUSED="x" used=false assigned=false
+TAG="" used=true assigned=true
+`
+	if result.String() != want {
+		t.Errorf("outline =\n%s\nwant\n%s", result.String(), want)
+	}
+
+	dir := t.TempDir()
+	if err := eng.WriteFiles(result, dir, "outline"); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "outline.txt" {
+		t.Errorf("WriteFiles wrote %v, want only outline.txt", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		t.Error("WriteFiles wrote a go.mod for a backend that generated none")
+	}
+
+	// The Go backend is the default that GenerateCode uses
+	goResult, err := eng.GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if goResult.FileName != "main.go" || !strings.Contains(goResult.GoModString(), cobraPackage) {
+		t.Errorf("GenerateCode = %s with go.mod %q, want main.go requiring cobra", goResult.FileName, goResult.GoModString())
+	}
+
+	if _, err := eng.Generate("makefile", program, ""); err == nil || !strings.Contains(err.Error(), `unknown backend "makefile" (available: go, outline)`) {
+		t.Errorf("Generate with an unknown backend error = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// CIProvider identifies a CI system with its own syntax for collapsible log groups
+type CIProvider string
+
+const (
+	CIGitHubActions CIProvider = "github" // ::group:: and ::error workflow commands
+	CIGitLab        CIProvider = "gitlab" // section_start/section_end collapsible sections
+)
+
+// DetectCI returns the CI system running this process, or "" outside CI
+func DetectCI() CIProvider {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHubActions
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	default:
+		return ""
+	}
+}
+
+// SetSourceFile sets the commands file path used in CI annotations and generated CLIs
+func (e *Engine) SetSourceFile(path string) {
+	e.sourceFile = path
+}
+
+// RegisterCILogging registers hooks that wrap each step's output in a collapsible group
+// and annotate failures with their position in the commands file
+func (e *Engine) RegisterCILogging(provider CIProvider, w io.Writer) {
+	// Commands run at once with --jobs report their failures concurrently
+	var mu sync.Mutex
+	failedSteps := make(map[string]bool)
+
+	e.AddHook(execution.EventPreStep, func(ev execution.Event) error {
+		fmt.Fprint(w, ciGroupStart(provider, ev.Command, ev.Step, ev.StepName, time.Now()))
+		return nil
+	})
+	e.AddHook(execution.EventPostStep, func(ev execution.Event) error {
+		fmt.Fprint(w, ciGroupEnd(provider, ev.Command, ev.Step, time.Now()))
+		if ev.Err != nil {
+			mu.Lock()
+			failedSteps[ev.Command] = true
+			mu.Unlock()
+			message := fmt.Sprintf("Step %d (%s) failed: %v", ev.Step, ev.StepName, ev.Err)
+			fmt.Fprint(w, ciError(provider, e.sourceFile, ev.Line, ev.Column, ev.Command, message))
+		}
+		return nil
+	})
+	e.AddHook(execution.EventFailure, func(ev execution.Event) error {
+		// Step failures are already annotated at the step's position
+		mu.Lock()
+		defer mu.Unlock()
+		if !failedSteps[ev.Command] {
+			message := fmt.Sprintf("Command failed: %v", ev.Err)
+			fmt.Fprint(w, ciError(provider, e.sourceFile, ev.Line, ev.Column, ev.Command, message))
+		}
+		delete(failedSteps, ev.Command)
+		return nil
+	})
+}
+
+// ciGroupStart opens a collapsible log group for a step
+func ciGroupStart(provider CIProvider, command string, step int, name string, now time.Time) string {
+	title := fmt.Sprintf("%s: %s", command, name)
+	switch provider {
+	case CIGitHubActions:
+		return "::group::" + githubEscapeData(title) + "\n"
+	case CIGitLab:
+		return fmt.Sprintf("\x1b[0Ksection_start:%d:%s\r\x1b[0K%s\n", now.Unix(), gitlabSectionName(command, step), title)
+	default:
+		return ""
+	}
+}
+
+// ciGroupEnd closes the log group opened by ciGroupStart
+func ciGroupEnd(provider CIProvider, command string, step int, now time.Time) string {
+	switch provider {
+	case CIGitHubActions:
+		return "::endgroup::\n"
+	case CIGitLab:
+		return fmt.Sprintf("\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", now.Unix(), gitlabSectionName(command, step))
+	default:
+		return ""
+	}
+}
+
+// ciError reports a failure outside any group so it stays visible. GitHub Actions turns it
+// into an annotation on the commands file; GitLab has no annotations, so it is highlighted.
+func ciError(provider CIProvider, file string, line, column int, command, message string) string {
+	switch provider {
+	case CIGitHubActions:
+		var properties []string
+		if file != "" {
+			properties = append(properties, "file="+githubEscapeProperty(file))
+			if line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", line), fmt.Sprintf("col=%d", column))
+			}
+		}
+		properties = append(properties, "title="+githubEscapeProperty("devcmd "+command))
+		return fmt.Sprintf("::error %s::%s\n", strings.Join(properties, ","), githubEscapeData(message))
+	case CIGitLab:
+		location := ""
+		if file != "" && line > 0 {
+			location = fmt.Sprintf("%s:%d:%d: ", file, line, column)
+		}
+		return fmt.Sprintf("\x1b[31;1m%s%s: %s\x1b[0m\n", location, command, message)
+	default:
+		return ""
+	}
+}
+
+// githubEscapeData escapes a workflow command message
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes a workflow command property value
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// gitlabSectionName builds a section name from the characters GitLab allows
+func gitlabSectionName(command string, step int) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, command)
+	return fmt.Sprintf("devcmd_%s_step_%d", name, step)
+}
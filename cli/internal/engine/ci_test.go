@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestDetectCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITLAB_CI", "")
+	if provider := DetectCI(); provider != "" {
+		t.Errorf("DetectCI() = %q outside CI", provider)
+	}
+
+	t.Setenv("GITLAB_CI", "true")
+	if provider := DetectCI(); provider != CIGitLab {
+		t.Errorf("DetectCI() = %q, want gitlab", provider)
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	if provider := DetectCI(); provider != CIGitHubActions {
+		t.Errorf("DetectCI() = %q, want github", provider)
+	}
+}
+
+func TestCILogging_GitHubActions(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: {\n  echo one\n  exit 4\n}"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	eng := New(program)
+	eng.SetSourceFile("ci/commands.cli")
+	eng.RegisterCILogging(CIGitHubActions, &out)
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err == nil {
+		t.Fatal("expected command to fail")
+	}
+
+	expected := "::group::build: echo one\n::endgroup::\n" +
+		"::group::build: exit 4\n::endgroup::\n" +
+		"::error file=ci/commands.cli,line=3,col=3,title=devcmd build::Step 2 (exit 4) failed: exit status 4\n"
+	if out.String() != expected {
+		t.Errorf("unexpected workflow commands:\n got: %q\nwant: %q", out.String(), expected)
+	}
+}
+
+func TestCILogging_GitLab(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("deploy: exit 1"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	eng := New(program)
+	eng.SetSourceFile("commands.cli")
+	eng.RegisterCILogging(CIGitLab, &out)
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err == nil {
+		t.Fatal("expected command to fail")
+	}
+
+	for _, want := range []string{
+		":devcmd_deploy_step_1\r\x1b[0Kdeploy: exit 1\n",
+		"\x1b[0Ksection_end:",
+		"commands.cli:1:9: deploy: Step 1 (exit 1) failed: exit status 1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("GitLab output missing %q:\n%q", want, out.String())
+		}
+	}
+	if strings.Count(out.String(), "failed") != 1 {
+		t.Errorf("failure should be reported once:\n%q", out.String())
+	}
+}
+
+func TestCIEscaping(t *testing.T) {
+	if got := githubEscapeData("50% done\nnext"); got != "50%25 done%0Anext" {
+		t.Errorf("githubEscapeData = %q", got)
+	}
+	if got := githubEscapeProperty("a:b,c"); got != "a%3Ab%2Cc" {
+		t.Errorf("githubEscapeProperty = %q", got)
+	}
+	if got := gitlabSectionName("db:migrate", 2); got != "devcmd_db_migrate_step_2" {
+		t.Errorf("gitlabSectionName = %q", got)
+	}
+}
+
+// TestGeneratedCliCILogging tests that generated CLIs group steps in GitHub Actions logs
+func TestGeneratedCliCILogging(t *testing.T) {
+	binaryPath := buildTestCLI(t, "build: {\n  echo one\n  exit 4\n}")
+
+	cmd := exec.Command(binaryPath, "build")
+	cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=true", "GITLAB_CI=")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected command to fail, output:\n%s", output)
+	}
+
+	for _, want := range []string{
+		"::group::build: echo one\none\n::endgroup::",
+		"::error title=devcmd build::Step 2 (exit 4) failed: exit status 4",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	// Outside CI the output is unchanged
+	cmd = exec.Command(binaryPath, "build")
+	cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=")
+	output, _ = cmd.CombinedOutput()
+	if strings.Contains(string(output), "::group::") {
+		t.Errorf("unexpected workflow commands outside CI:\n%s", output)
+	}
+}
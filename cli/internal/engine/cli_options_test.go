@@ -0,0 +1,376 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+// TestGeneratedCliAbbreviationsAndAliases tests prefix dispatch and aliases in generated CLIs
+func TestGeneratedCliAbbreviationsAndAliases(t *testing.T) {
+	input := `
+deploy: echo "deploying"
+destroy: echo "destroying"
+build: echo "building"
+`
+	binaryPath := buildTestCLIWithOptions(t, input, CLIOptions{
+		Abbreviations: true,
+		Aliases:       map[string]string{"b": "build"},
+	})
+
+	testCases := []struct {
+		name      string
+		args      []string
+		expectErr bool
+		contains  []string
+	}{
+		{name: "unambiguous prefix", args: []string{"dep"}, contains: []string{"deploying"}},
+		{name: "alias", args: []string{"b"}, contains: []string{"building"}},
+		{name: "full name", args: []string{"destroy"}, contains: []string{"destroying"}},
+		{
+			name:      "ambiguous prefix",
+			args:      []string{"de"},
+			expectErr: true,
+			contains:  []string{`ambiguous command "de"`, "deploy", "destroy"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			output, err := exec.CommandContext(ctx, binaryPath, tc.args...).CombinedOutput()
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expectErr=%v, got err=%v, output:\n%s", tc.expectErr, err, output)
+			}
+			for _, want := range tc.contains {
+				if !strings.Contains(string(output), want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+// TestGeneratedCliPrefixDisabledByDefault tests that prefixes are not dispatched without the setting
+func TestGeneratedCliPrefixDisabledByDefault(t *testing.T) {
+	binaryPath := buildTestCLI(t, `deploy: echo "deploying"`)
+
+	output, err := exec.Command(binaryPath, "dep").CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected prefix to be rejected without abbreviations, output:\n%s", output)
+	}
+	if strings.Contains(string(output), "deploying") {
+		t.Errorf("command should not run via prefix:\n%s", output)
+	}
+}
+
+// TestGeneratedCliDefaultEnv tests that baked-in environment defaults yield to the caller's environment
+func TestGeneratedCliDefaultEnv(t *testing.T) {
+	binaryPath := buildTestCLIWithOptions(t, `show: echo "image=$DEVCMD_IMAGE_TERRAFORM"`, CLIOptions{
+		DefaultEnv: map[string]string{"DEVCMD_IMAGE_TERRAFORM": "hashicorp/terraform:1.9"},
+	})
+
+	output, err := exec.Command(binaryPath, "show").CombinedOutput()
+	if err != nil {
+		t.Fatalf("command failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "image=hashicorp/terraform:1.9") {
+		t.Errorf("default not applied:\n%s", output)
+	}
+
+	cmd := exec.Command(binaryPath, "show")
+	cmd.Env = append(os.Environ(), "DEVCMD_IMAGE_TERRAFORM=custom/terraform:latest")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("command failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "image=custom/terraform:latest") {
+		t.Errorf("environment should override the default:\n%s", output)
+	}
+}
+
+// TestGeneratedCliEnvProfiles tests that --profile selects the values of an env profile,
+// other variables keeping their defaults
+func TestGeneratedCliEnvProfiles(t *testing.T) {
+	binaryPath := buildTestCLI(t, `var API_URL = "http://localhost:8080"
+var REPLICAS = 1
+env production {
+    API_URL = "https://api.example.com"
+    REPLICAS = 3
+}
+env staging {
+    API_URL = "https://staging.example.com"
+}
+deploy: echo "@var(API_URL) x@var(REPLICAS)"`)
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"deploy"}, "http://localhost:8080 x1"},
+		{[]string{"deploy", "--profile", "production"}, "https://api.example.com x3"},
+		{[]string{"--profile=staging", "deploy"}, "https://staging.example.com x1"},
+	} {
+		output, err := exec.Command(binaryPath, tt.args...).CombinedOutput()
+		if err != nil || !strings.Contains(string(output), tt.want) {
+			t.Errorf("%v should print %q (%v):\n%s", tt.args, tt.want, err, output)
+		}
+	}
+
+	output, err := exec.Command(binaryPath, "deploy", "--profile", "qa").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), `unknown profile "qa" (available: production, staging)`) {
+		t.Errorf("expected an unknown profile error (%v):\n%s", err, output)
+	}
+}
+
+// TestGeneratedCliConfigPrecedence tests that generated CLIs take their defaults from flags,
+// then the environment, then the project's defaults settings, then the user config
+func TestGeneratedCliConfigPrecedence(t *testing.T) {
+	binaryPath := buildTestCLIWithOptions(t, `var API_URL = "http://localhost:8080"
+env staging {
+    API_URL = "https://staging.example.com"
+}
+flaky: @retry(attempts = 2, delay = 10ms) { false }
+deploy: echo "@var(API_URL)"`, CLIOptions{Defaults: map[string]string{"log_level": "error"}})
+
+	userConfig := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(userConfig, []byte("# defaults\nlog_level = \"debug\"\nprofile = 'staging' # mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(env []string, args ...string) string {
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), append([]string{"DEVCMD_CONFIG=" + userConfig, "DEVCMD_LOG_LEVEL=", "DEVCMD_PROFILE="}, env...)...)
+		output, _ := cmd.CombinedOutput()
+		return string(output)
+	}
+
+	// The project's log level wins over the user config's, and the user config's profile applies
+	if output := run(nil, "flaky"); strings.Contains(output, "@retry") {
+		t.Errorf("the project's log_level = error should drop warnings:\n%s", output)
+	}
+	if output := run(nil, "deploy"); !strings.Contains(output, "https://staging.example.com") {
+		t.Errorf("the user config's profile should apply:\n%s", output)
+	}
+	if output := run([]string{"DEVCMD_LOG_LEVEL=warn"}, "flaky"); !strings.Contains(output, "@retry: attempt 1 of 2 failed") {
+		t.Errorf("DEVCMD_LOG_LEVEL should win over the project:\n%s", output)
+	}
+	if output := run([]string{"DEVCMD_LOG_LEVEL=error"}, "--log-level=warn", "flaky"); !strings.Contains(output, "@retry: attempt 1 of 2 failed") {
+		t.Errorf("--log-level should win over the environment:\n%s", output)
+	}
+
+	// A profile this CLI doesn't define is left out, unless a flag selects it
+	if output := run([]string{"DEVCMD_PROFILE=qa"}, "deploy"); !strings.Contains(output, "http://localhost:8080") {
+		t.Errorf("an unknown profile from the environment should be ignored:\n%s", output)
+	}
+	if output := run(nil, "--profile=qa", "deploy"); !strings.Contains(output, `unknown profile "qa"`) {
+		t.Errorf("expected an unknown profile error:\n%s", output)
+	}
+}
+
+// TestGeneratedCliMasksSecrets tests that generated CLIs read @secret values when they run
+// and mask them in their diagnostics
+func TestGeneratedCliMasksSecrets(t *testing.T) {
+	binaryPath := buildTestCLI(t, `login: echo token @secret("API_TOKEN", source = "env") > /dev/null`)
+
+	cmd := exec.Command(binaryPath, "--log-level=debug", "login")
+	cmd.Env = append(os.Environ(), "API_TOKEN=runtime-s3cret")
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "running echo token ***") || strings.Contains(string(output), "runtime-s3cret") {
+		t.Errorf("debug log should mask the secret (%v):\n%s", err, output)
+	}
+
+	cmd = exec.Command(binaryPath, "login")
+	cmd.Env = append(os.Environ(), "API_TOKEN=")
+	output, err = cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "environment variable API_TOKEN is not set") {
+		t.Errorf("expected the unset secret to fail the command (%v):\n%s", err, output)
+	}
+}
+
+// TestGeneratedCliQuotesSecrets tests that generated CLIs quote @secret values in shell steps,
+// so quotes and $ in a secret can't run commands
+func TestGeneratedCliQuotesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	out, pwned := filepath.Join(dir, "out"), filepath.Join(dir, "pwned")
+	binaryPath := buildTestCLI(t, `show: echo "token=@secret(TOKEN, source = "env")" > `+out)
+
+	token := `x"; touch ` + pwned + `; echo "$HOME`
+	cmd := exec.Command(binaryPath, "show")
+	cmd.Env = append(os.Environ(), "TOKEN="+token)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("show failed: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Error("the secret's value ran as shell syntax")
+	}
+	if data, err := os.ReadFile(out); err != nil || strings.TrimSpace(string(data)) != "token="+token {
+		t.Errorf("output = %q, %v, want the secret unchanged", data, err)
+	}
+}
+
+// TestGeneratedCliStrictShell tests that strict mode stops a step at its first failure unless a block opts out
+func TestGeneratedCliStrictShell(t *testing.T) {
+	binaryPath := buildTestCLIWithOptions(t, `
+strict: false; echo "strict continued"
+loose: @strict(false) { false; echo "loose continued" }
+`, CLIOptions{StrictShell: true})
+
+	output, err := exec.Command(binaryPath, "strict").CombinedOutput()
+	if err == nil || strings.Contains(string(output), "strict continued") {
+		t.Errorf("strict step should stop at the failure (%v):\n%s", err, output)
+	}
+
+	output, err = exec.Command(binaryPath, "loose").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "loose continued") {
+		t.Errorf("@strict(false) should run the rest of the step (%v):\n%s", err, output)
+	}
+}
+
+// TestGeneratedCliLogFlags tests that --log-level filters diagnostics and --log-format=json
+// writes them as JSON records
+func TestGeneratedCliLogFlags(t *testing.T) {
+	binaryPath := buildTestCLI(t, `flaky: @retry(attempts = 2, delay = 10ms) { false }`)
+
+	output, _ := exec.Command(binaryPath, "flaky").CombinedOutput()
+	if !strings.Contains(string(output), "@retry: attempt 1 of 2 failed: exit status 1\n") {
+		t.Errorf("text output missing the retry warning:\n%s", output)
+	}
+
+	output, _ = exec.Command(binaryPath, "--log-level=error", "flaky").CombinedOutput()
+	if strings.Contains(string(output), "@retry") {
+		t.Errorf("--log-level=error should drop warnings:\n%s", output)
+	}
+
+	output, _ = exec.Command(binaryPath, "--log-level=debug", "--log-format=json", "flaky").CombinedOutput()
+	for _, want := range []string{`"level":"debug","source":"shell","msg":"running false"`, `"level":"warn","source":"@retry","msg":"attempt 2 of 2 failed: exit status 1"`} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("JSON output missing %s:\n%s", want, output)
+		}
+	}
+
+	output, err := exec.Command(binaryPath, "--log-format=yaml", "flaky").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), `unsupported log format "yaml"`) {
+		t.Errorf("expected an unsupported format error (%v):\n%s", err, output)
+	}
+}
+
+// TestGeneratedCliAccessible tests that --accessible and DEVCMD_ACCESSIBLE print plain plans
+// and lead diagnostics with their level
+func TestGeneratedCliAccessible(t *testing.T) {
+	binaryPath := buildTestCLI(t, `flaky: @retry(attempts = 2, delay = 10ms) { false }`)
+
+	output, _ := exec.Command(binaryPath, "--accessible", "flaky").CombinedOutput()
+	if !strings.Contains(string(output), "Warning: @retry: attempt 1 of 2 failed: exit status 1\n") {
+		t.Errorf("accessible output missing the worded retry warning:\n%s", output)
+	}
+
+	cmd := exec.Command(binaryPath, "--dry-run", "flaky")
+	cmd.Env = append(os.Environ(), "DEVCMD_ACCESSIBLE=1")
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Step 1: @retry, 2 attempts, 10ms delay\nStep 1.1: run false\n") {
+		t.Errorf("expected a plain plan (%v):\n%s", err, output)
+	}
+}
+
+func TestGeneratedCliDocComments(t *testing.T) {
+	binaryPath := buildTestCLI(t, `# Build the binary
+#
+# Runs go build for every package.
+build: go build ./...
+
+# Run the API server
+watch api: go run ./cmd/api
+
+lint: golangci-lint run`)
+
+	output, err := exec.Command(binaryPath, "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("--help failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{"build       Build the binary", "api         Run the API server", "lint        \n"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("--help output missing %q:\n%s", want, output)
+		}
+	}
+
+	output, err = exec.Command(binaryPath, "build", "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("build --help failed: %v\n%s", err, output)
+	}
+	if !strings.HasPrefix(string(output), "Build the binary\n\nRuns go build for every package.\n") {
+		t.Errorf("build --help output doesn't start with the doc comment:\n%s", output)
+	}
+}
+
+func TestResolveAliasesValidation(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: echo build\ntest: echo test"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		aliases map[string]string
+		errText string
+	}{
+		{"undefined target", map[string]string{"d": "deploy"}, "undefined command 'deploy'"},
+		{"shadows command", map[string]string{"test": "build"}, "conflicts with a command"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng := New(program)
+			eng.SetCLIOptions(CLIOptions{Aliases: tc.aliases})
+			_, err := eng.GenerateCode(program)
+			if err == nil || !strings.Contains(err.Error(), tc.errText) {
+				t.Errorf("expected error containing %q, got %v", tc.errText, err)
+			}
+		})
+	}
+}
+
+// TestGeneratedCliDefaultTimeout tests that the config block's defaultTimeout bounds commands
+// without a @timeout of their own
+func TestGeneratedCliDefaultTimeout(t *testing.T) {
+	binaryPath := buildTestCLI(t, `config {
+    defaultTimeout = 200ms
+}
+slow: sleep 2
+own: @timeout(2s) { sleep 0.3 && echo own done }`)
+
+	output, err := exec.Command(binaryPath, "slow").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "Command 'slow' failed: timed out after 200ms (the defaultTimeout of the config block)") {
+		t.Errorf("slow should stop at the default timeout (%v):\n%s", err, output)
+	}
+
+	output, err = exec.Command(binaryPath, "own").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "own done") {
+		t.Errorf("own should run under its own @timeout (%v):\n%s", err, output)
+	}
+}
+
+func TestGeneratedCliShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	binaryPath := buildTestCLI(t, `config {
+    shell = "bash"
+}
+which: {
+    echo "bash:${BASH_VERSION:+yes}"
+    @shell("sh") { echo "sh:${BASH_VERSION:+yes}" }
+}`)
+
+	output, err := exec.Command(binaryPath, "which").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "bash:yes\nsh:\n") {
+		t.Errorf("steps should run with the config block's shell and @shell's (%v):\n%s", err, output)
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// commandTimeout returns the defaultTimeout of the program's config block that bounds
+// command, zero if the program has none. Watch commands run until stopped, and commands that
+// use @timeout anywhere are bounded only by their own timeouts.
+func commandTimeout(program *ast.Program, command *ast.CommandDecl) time.Duration {
+	timeout := program.DefaultTimeout()
+	if timeout == 0 || command.Type == ast.WatchCommand {
+		return 0
+	}
+	timed := false
+	ast.Walk(&command.Body, func(n ast.Node) bool {
+		if block, ok := n.(*ast.BlockDecorator); ok && block.Name == "timeout" {
+			timed = true
+		}
+		return !timed
+	})
+	if timed {
+		return 0
+	}
+	return timeout
+}
+
+// defaultTimeoutError is the error of a command stopped by the defaultTimeout of the config
+// block. runWithTimeout returns the same in generated CLIs.
+func defaultTimeoutError(timeout time.Duration) error {
+	return fmt.Errorf("timed out after %v (the defaultTimeout of the config block)", timeout)
+}
+
+// runWithTimeoutHelper is called by the commands of generated CLIs that the config block's
+// defaultTimeout bounds
+const runWithTimeoutHelper = `
+// runWithTimeout runs a command, returning an error if it doesn't finish within the
+// defaultTimeout of the config block
+func runWithTimeout(timeout time.Duration, run func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v (the defaultTimeout of the config block)", timeout)
+	}
+}
+`
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`config {
+    defaultTimeout = 200ms
+}
+slow: {
+    echo start
+    sleep 2
+}
+own: @timeout(2s) { sleep 0.3 }
+nested: @retry(attempts = 1) { @timeout(2s) { sleep 0.3 } }
+watch server: sleep 1`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := map[string]time.Duration{"slow": 200 * time.Millisecond, "own": 0, "nested": 0, "server": 0}
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		if got := commandTimeout(program, command); got != want[command.Name] {
+			t.Errorf("commandTimeout(%s) = %v, want %v", command.Name, got, want[command.Name])
+		}
+	}
+
+	engine := New(program)
+	start := time.Now()
+	_, err = engine.ExecuteCommand(&program.Commands[0])
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms (the defaultTimeout of the config block)") {
+		t.Errorf("slow error = %v, want the default timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slow ran for %v, want it stopped at its timeout", elapsed)
+	}
+	if _, err := engine.ExecuteCommand(&program.Commands[1]); err != nil {
+		t.Errorf("own should run under its own @timeout, got %v", err)
+	}
+
+	executionPlan, err := engine.ExecuteCommandPlan(&program.Commands[0])
+	if err != nil {
+		t.Fatalf("ExecuteCommandPlan failed: %v", err)
+	}
+	if text := executionPlan.StringNoColor(); !strings.Contains(text, "@timeout {200ms timeout, config defaultTimeout}\n   ├─ echo start") {
+		t.Errorf("plan should hold the steps under the default timeout, got:\n%s", text)
+	}
+}
+
+func TestConfigShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	out := filepath.Join(t.TempDir(), "out.txt")
+	program, err := parser.Parse(strings.NewReader(`config {
+    shell = "bash"
+}
+which: {
+    echo "bash:${BASH_VERSION:+yes}" >> ` + out + `
+    @shell("sh") { echo "sh:${BASH_VERSION:+yes}" >> ` + out + ` }
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if _, err := New(program).ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "bash:yes\nsh:\n" {
+		t.Errorf("output = %q, want the config block's shell outside @shell and sh inside it", got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/release"
 	"github.com/aledsdavies/devcmd/core/ast"
 )
 
@@ -553,3 +554,213 @@ func TestParallelWorkdirOutputConsistency(t *testing.T) {
 	t.Logf("Both modes tested - check logs above to compare outputs")
 	t.Logf("Expected: Both modes should show 'core: 0 issues.', 'runtime: 0 issues.', etc.")
 }
+
+// TestParallelOutputModes verifies that generated CLIs prefix streamed branch output and
+// keep buffered branch output together
+func TestParallelOutputModes(t *testing.T) {
+	binaryPath := buildTestCLI(t, `stream: @parallel {
+    echo one; sleep 0.2; echo two
+    sleep 0.1; echo three; printf four
+}
+buffered: @parallel(output = "buffered") {
+    echo one; sleep 0.2; echo two
+    sleep 0.1; echo three; printf four
+}`)
+
+	testCases := []struct {
+		command  string
+		expected string
+	}{
+		{"stream", "[1] one\n[2] three\n[2] four\n[1] two\n"},
+		{"buffered", "three\nfourone\ntwo\n"},
+	}
+
+	for _, tc := range testCases {
+		cmd := exec.Command(binaryPath, tc.command)
+		cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s failed: %v", tc.command, err)
+		}
+		if string(output) != tc.expected {
+			t.Errorf("%s output = %q, want %q", tc.command, output, tc.expected)
+		}
+	}
+}
+
+// TestGeneratedCliPty verifies that generated CLIs run @pty blocks under a terminal sized from
+// LINES and COLUMNS when they have none, capturing the terminal's output
+func TestGeneratedCliPty(t *testing.T) {
+	if _, err := exec.LookPath("stty"); err != nil {
+		t.Skip("stty not available")
+	}
+	binaryPath := buildTestCLI(t, `tty: @pty {
+    test -t 0 && test -t 1 && echo "is a tty"
+    stty size
+}`)
+
+	cmd := exec.Command(binaryPath, "tty")
+	cmd.Env = append(os.Environ(), "LINES=30", "COLUMNS=100", "GITHUB_ACTIONS=", "GITLAB_CI=")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("tty failed: %v\n%s", err, output)
+	}
+	if string(output) != "is a tty\n30 100\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+// TestGeneratedCliStdin verifies that @stdin closes, restores and redirects stdin per block
+func TestGeneratedCliStdin(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "seed.sql"), []byte("select 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, `seed: @stdin(file = "seed.sql") {
+    cat
+}
+quiet: @stdin("null") {
+    cat; echo "closed"
+    @stdin("inherit") {
+        cat
+    }
+}`)
+
+	testCases := []struct {
+		command  string
+		expected string
+	}{
+		{"seed", "select 1;\n"},
+		{"quiet", "closed\ntyped\n"},
+	}
+
+	for _, tc := range testCases {
+		cmd := exec.Command(binaryPath, tc.command)
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader("typed\n")
+		cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s failed: %v", tc.command, err)
+		}
+		if string(output) != tc.expected {
+			t.Errorf("%s output = %q, want %q", tc.command, output, tc.expected)
+		}
+	}
+}
+
+// TestGeneratedCliWatchFiles verifies that generated CLIs rerun @watch-files blocks when a
+// watched file changes, including in directories created after watching started
+func TestGeneratedCliWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := buildTestCLI(t, `dev: @watch-files("src/**/*.go", debounce = 20ms) {
+    echo run >> runs
+}`)
+
+	cmd := exec.Command(binaryPath, "dev")
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("dev failed to start: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	waitForRuns := func(want int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+			data, _ := os.ReadFile(filepath.Join(dir, "runs"))
+			if strings.Count(string(data), "run") >= want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("block ran %d times, want %d", strings.Count(string(data), "run"), want)
+			}
+		}
+	}
+
+	waitForRuns(1)
+	time.Sleep(100 * time.Millisecond) // Let the watches start
+	if err := os.MkdirAll(filepath.Join(dir, "src", "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "api", "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForRuns(2)
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "src", "api", "main.go"), []byte("package main // changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForRuns(3)
+}
+
+// TestSemverAutoBumpAgrees tests that the interpreter and generated CLIs derive the same
+// version from the same commits, as the generated classifier mirrors release.ParseConventional
+func TestSemverAutoBumpAgrees(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	binaryPath := buildTestCLI(t, `ver: echo @semver(bump = "auto")`)
+
+	tests := []struct {
+		subject string
+		body    string
+		want    string
+	}{
+		{subject: "fix: handle tabs", want: "v1.0.1"},
+		{subject: "feat: add release command", want: "v1.1.0"},
+		{subject: "feat(api): rename fields", want: "v1.1.0"},
+		{subject: "refactor!: drop v1 config", want: "v2.0.0"},
+		{subject: "feat(api client)!: drop v1", want: "v2.0.0"},
+		{subject: "feat(api client): add retries", want: "v1.1.0"},
+		{subject: "chore: bump deps", body: "BREAKING CHANGE: requires go 1.24", want: "v2.0.0"},
+		{subject: "Update README", want: "v1.0.1"},
+		{subject: "Merge branch main: conflicts", want: "v1.0.1"},
+		{subject: "new feat!: not conventional", want: "v1.0.1"},
+		{subject: "new feat: not conventional", body: "BREAKING CHANGE: still breaking", want: "v2.0.0"},
+		{subject: "!: no type", want: "v1.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			dir := t.TempDir()
+			message := tt.subject
+			if tt.body != "" {
+				message += "\n\n" + tt.body
+			}
+			for _, args := range [][]string{
+				{"init", "-q", "-b", "main"},
+				{"-c", "user.name=devcmd", "-c", "user.email=devcmd@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+				{"tag", "v1.0.0"},
+				{"-c", "user.name=devcmd", "-c", "user.email=devcmd@example.com", "commit", "-q", "--allow-empty", "-m", message},
+			} {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = dir
+				if output, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+				}
+			}
+
+			plan, err := release.NewPlan(dir, release.BumpAuto)
+			if err != nil {
+				t.Fatalf("NewPlan failed: %v", err)
+			}
+			if got := plan.Next.String(); got != tt.want {
+				t.Errorf("interpreter: next version = %s, want %s", got, tt.want)
+			}
+
+			cmd := exec.Command(binaryPath, "ver")
+			cmd.Dir = dir
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("ver failed: %v\n%s", err, output)
+			}
+			if got := strings.TrimSpace(string(output)); got != tt.want {
+				t.Errorf("generated CLI: next version = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
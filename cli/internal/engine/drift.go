@@ -0,0 +1,20 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SourceHash returns the hash of a commands file that generated CLIs embed to detect when the
+// file has changed since they were built. A local override file's contents follow the main
+// file's in source.
+func SourceHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetSourceHash sets the commands file hash embedded in generated CLIs. Together with the
+// source file set by SetSourceFile, it makes generated CLIs warn when the file has drifted.
+func (e *Engine) SetSourceHash(hash string) {
+	e.sourceHash = hash
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+const driftCommands = `hello: echo hello`
+
+func TestGeneratedCliDriftCheckRequiresSourceHash(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(driftCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if strings.Contains(result.String(), "checkSourceDrift") {
+		t.Error("CLI generated without a source hash checks for drift")
+	}
+}
+
+func TestGeneratedCliWarnsWhenSourceDrifts(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(driftCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	dir := t.TempDir()
+	eng := New(program)
+	eng.SetSourceFile(filepath.Join(dir, "commands.cli"))
+	eng.SetSourceHash(SourceHash([]byte(driftCommands)))
+	binaryPath := buildTestCLIFromEngine(t, eng, program)
+
+	runIn := func(wd, source string, env ...string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "commands.cli"), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(binaryPath, "hello")
+		cmd.Dir = wd
+		cmd.Env = append(os.Environ(), env...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("hello failed: %v\n%s", err, output)
+		}
+		return string(output)
+	}
+	run := func(source string, env ...string) string {
+		t.Helper()
+		return runIn(dir, source, env...)
+	}
+
+	const warning = "commands.cli has changed since this CLI was generated"
+	if output := run(driftCommands); strings.Contains(output, warning) {
+		t.Errorf("warned about an unchanged commands file:\n%s", output)
+	}
+	if output := run(driftCommands + "\nbye: echo bye"); !strings.Contains(output, warning) || !strings.Contains(output, "hello") {
+		t.Errorf("expected a drift warning before running hello:\n%s", output)
+	}
+	if output := run(driftCommands+"\nbye: echo bye", "DEVCMD_NO_DRIFT_CHECK=1"); strings.Contains(output, warning) {
+		t.Errorf("DEVCMD_NO_DRIFT_CHECK did not silence the warning:\n%s", output)
+	}
+
+	// From a subdirectory, the project's commands file is the one compared
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if output := runIn(sub, driftCommands+"\nbye: echo bye"); !strings.Contains(output, warning) {
+		t.Errorf("expected a drift warning in a subdirectory of the project:\n%s", output)
+	}
+
+	// A local override file is part of what the CLI was generated from
+	if err := os.WriteFile(filepath.Join(dir, "commands.local.cli"), []byte("mine: echo mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if output := run(driftCommands); !strings.Contains(output, "commands.local.cli has changed") {
+		t.Errorf("expected a drift warning for the new local file:\n%s", output)
+	}
+	if err := os.Remove(filepath.Join(dir, "commands.local.cli")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside the project, even next to another commands file, there is nothing to compare
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "commands.cli"), []byte("other: echo other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if output := runIn(other, driftCommands); strings.Contains(output, warning) {
+		t.Errorf("warned about the commands file of another project:\n%s", output)
+	}
+}
+
+func TestGeneratedCliRegenerateCompiles(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(driftCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	dir := t.TempDir()
+	eng.SetCLIOptions(CLIOptions{Regenerate: true})
+	eng.SetSourceFile(filepath.Join(dir, "commands.cli"))
+	eng.SetSourceHash(SourceHash([]byte(driftCommands)))
+
+	// Without devcmd on PATH a drifted CLI falls back to warning
+	binaryPath := buildTestCLIFromEngine(t, eng, program)
+	if err := os.WriteFile(filepath.Join(dir, "commands.cli"), []byte("changed: echo changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	pathDir := t.TempDir()
+	if err := os.Symlink(sh, filepath.Join(pathDir, "sh")); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(binaryPath, "hello")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+pathDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("hello failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "run 'devcmd build' to update it") {
+		t.Errorf("expected a drift warning without devcmd on PATH:\n%s", output)
+	}
+}
@@ -2,14 +2,21 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/aledsdavies/devcmd/cli/internal/daemon"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
 	"github.com/aledsdavies/devcmd/core/ast"
 	"github.com/aledsdavies/devcmd/core/plan"
 	"github.com/aledsdavies/devcmd/runtime/decorators"
@@ -29,10 +36,53 @@ type CommandGroups struct {
 	ProcessGroups   []ProcessGroup
 }
 
+// CLIOptions controls command dispatch behavior in generated CLIs
+type CLIOptions struct {
+	// Abbreviations allows invoking commands by an unambiguous prefix (e.g. "dep" for "deploy")
+	Abbreviations bool
+	// Aliases maps alternative names to command names (e.g. "b" -> "build")
+	Aliases map[string]string
+	// DefaultEnv holds environment defaults baked into the CLI; the caller's environment takes precedence
+	DefaultEnv map[string]string
+	// Regenerate rebuilds the CLI with devcmd when its commands file changes, instead of warning
+	Regenerate bool
+	// StrictShell runs every shell step with execution.StrictShellPrefix (set -eu and pipefail)
+	StrictShell bool
+	// Restart is the policy the devcmd daemon restarts watch commands with (daemon.DefaultRestart if empty)
+	Restart string
+	// Services holds the stop order and grace period of watch commands, by name
+	Services map[string]processes.Service
+	// Heartbeat is how long a shell step of an interpreted command may write no output before
+	// it reports that it is still running (0 never reports)
+	Heartbeat time.Duration
+	// Heartbeats overrides Heartbeat for commands, by name
+	Heartbeats map[string]time.Duration
+	// Defaults are the project's defaults settings by config name, such as log_level, which
+	// generated CLIs use when neither a flag nor the environment sets them
+	Defaults map[string]string
+	// Defines holds the build-time values the program was specialized with (see Specialize),
+	// passed on to devcmd build when the CLI regenerates itself
+	Defines map[string]string
+	// Hooks are the shell hooks of the project settings by event name, such as postRun, which
+	// generated CLIs run around the command they're invoked with and its steps
+	Hooks map[string]string
+}
+
 // Engine provides a unified AST walker for both interpreter and generator modes
 type Engine struct {
-	program   *ast.Program
-	goVersion string // Go version for generated code (e.g., "1.24")
+	program    *ast.Program
+	goVersion  string // Go version for generated code (e.g., "1.24")
+	hooks      map[execution.EventType][]execution.HookFunc
+	cliOptions CLIOptions
+	sourceFile string // Commands file path for CI annotations
+	sourceHash string // SHA-256 of the commands file, for drift detection in generated CLIs
+
+	forceRestart bool     // Restart watch commands that are already running
+	shell        []string // Command prefix shell steps run through, such as a sandbox; nil for the config block's shell
+	outputPrefix bool     // Prefix command output lines with the command name, for commands run at once
+
+	params map[string]string // Parameter values interpreted commands run with, by name
+	runs   runs              // First run of each command in this invocation, which needs reuse
 }
 
 // New creates a new execution engine
@@ -51,16 +101,38 @@ func NewWithGoVersion(program *ast.Program, goVersion string) *Engine {
 	}
 }
 
-// ExecuteCommand executes a single command in interpreter mode
-func (e *Engine) ExecuteCommand(command *ast.CommandDecl) (*CommandResult, error) {
-	// Create interpreter context with proper decorator setup
-	ctx := e.CreateInterpreterContext(context.Background(), e.program)
+// Program returns the program the engine runs
+func (e *Engine) Program() *ast.Program {
+	return e.program
+}
 
-	// Initialize variables if not already done
-	if err := ctx.InitializeVariables(); err != nil {
-		return nil, fmt.Errorf("failed to initialize variables: %w", err)
+// SetCLIOptions sets dispatch options used when generating CLIs
+func (e *Engine) SetCLIOptions(opts CLIOptions) {
+	e.cliOptions = opts
+}
+
+// SetShell runs the shell steps of interpreted commands through the given command prefix
+// (e.g. a sandbox ending in "sh") instead of the config block's shell or the platform's default
+func (e *Engine) SetShell(shell []string) {
+	e.shell = shell
+}
+
+// ExecuteCommand executes a single command in interpreter mode, after the commands it needs.
+// Commands needing it later in the invocation don't run it again.
+func (e *Engine) ExecuteCommand(command *ast.CommandDecl) (*CommandResult, error) {
+	if err := e.checkInterpreterSupport(command, runtime.GOOS); err != nil {
+		return &CommandResult{Name: command.Name, Status: "failed", Output: []string{}, Error: err.Error()}, err
+	}
+	run, first := e.runs.claim(command.Name)
+	cmdResult, err := e.executeCommand(command)
+	if first {
+		run.finish(err)
 	}
+	return cmdResult, err
+}
 
+// executeCommand runs the commands command needs that haven't run yet, then command itself
+func (e *Engine) executeCommand(command *ast.CommandDecl) (*CommandResult, error) {
 	cmdResult := &CommandResult{
 		Name:   command.Name,
 		Status: "success",
@@ -68,93 +140,256 @@ func (e *Engine) ExecuteCommand(command *ast.CommandDecl) (*CommandResult, error
 		Error:  "",
 	}
 
-	// Execute the command content directly
-	for _, content := range command.Body.Content {
-		switch c := content.(type) {
-		case *ast.ShellContent:
-			// Execute shell content using the execution context
-			result := ctx.ExecuteShell(c)
-			if result.Error != nil {
-				cmdResult.Status = "failed"
-				cmdResult.Error = result.Error.Error()
-				return cmdResult, result.Error
-			}
-		case *ast.BlockDecorator:
-			// Execute block decorator using the registry
-			blockDecorator, err := decorators.GetBlock(c.Name)
-			if err != nil {
-				err = fmt.Errorf("block decorator @%s not found: %w", c.Name, err)
-				cmdResult.Status = "failed"
-				cmdResult.Error = err.Error()
-				return cmdResult, err
-			}
+	if err := e.runNeeds(command); err != nil {
+		cmdResult.Status = "failed"
+		cmdResult.Error = err.Error()
+		return cmdResult, err
+	}
 
-			result := blockDecorator.ExecuteInterpreter(ctx, c.Args, c.Content)
-			if result.Error != nil {
-				err = fmt.Errorf("@%s decorator execution failed: %w", c.Name, result.Error)
-				cmdResult.Status = "failed"
-				cmdResult.Error = err.Error()
-				return cmdResult, err
-			}
-		default:
-			err := fmt.Errorf("unsupported command content type in interpreter mode: %T", content)
-			cmdResult.Status = "failed"
-			cmdResult.Error = err.Error()
-			return cmdResult, err
-		}
+	if err := e.emit(execution.Event{Type: execution.EventPreCommand, Command: command.Name, Line: command.Pos.Line, Column: command.Pos.Column}); err != nil {
+		cmdResult.Status = "failed"
+		cmdResult.Error = err.Error()
+		return cmdResult, err
+	}
+
+	start := time.Now()
+	err := e.executeCommandContent(command)
+
+	post := execution.Event{
+		Type:     execution.EventPostCommand,
+		Command:  command.Name,
+		Status:   "success",
+		Duration: time.Since(start),
+		Line:     command.Pos.Line,
+		Column:   command.Pos.Column,
+	}
+	if err != nil {
+		post.Status = "failed"
+		post.Err = err
+		// Failure hooks are best-effort; the command error takes precedence
+		_ = e.emit(execution.Event{Type: execution.EventFailure, Command: command.Name, Status: "failed", Duration: post.Duration, Err: err, Line: post.Line, Column: post.Column})
+	}
+	if hookErr := e.emit(post); hookErr != nil && err == nil {
+		err = hookErr
+	}
+	if triggerErr := e.runTriggers(command, err); triggerErr != nil && err == nil {
+		err = triggerErr
+	}
+
+	if err != nil {
+		cmdResult.Status = "failed"
+		cmdResult.Error = err.Error()
+		return cmdResult, err
 	}
 
 	return cmdResult, nil
 }
 
-// ExecuteCommandPlan generates an execution plan for a command without executing it
-func (e *Engine) ExecuteCommandPlan(command *ast.CommandDecl) (*plan.ExecutionPlan, error) {
-	// Create plan context
-	ctx := execution.NewPlanContext(context.Background(), e.program)
+// executeCommandContent runs each step of a command body, emitting step events
+func (e *Engine) executeCommandContent(command *ast.CommandDecl) error {
+	// Create interpreter context with proper decorator setup
+	ctx := e.CreateInterpreterContext(context.Background(), e.program)
 
 	// Initialize variables if not already done
 	if err := ctx.InitializeVariables(); err != nil {
-		return nil, fmt.Errorf("failed to initialize variables: %w", err)
+		return fmt.Errorf("failed to initialize variables: %w", err)
+	}
+
+	// Commands read the values of the parameters they declare with @param
+	params, err := ResolveParams(command, e.params)
+	if err != nil {
+		return err
+	}
+	ctx = ctx.WithParams(params)
+
+	// Watch commands run as a named process; decorators like @freeport record
+	// their allocations in the process's ports file beside its PID file, in the
+	// project's namespace of the process registry
+	registry, namespace := processes.Default(), e.ProcessNamespace()
+	store, portsFile := registry.Store(namespace), command.Name+".ports"
+	switch command.Type {
+	case ast.WatchCommand:
+		if running, err := e.checkRunning(registry, namespace, command.Name); running || err != nil {
+			return err
+		}
+		if err := registry.Register(namespace, e.projectDir()); err != nil {
+			return fmt.Errorf("failed to register process %s: %w", command.Name, err)
+		}
+		_ = store.Remove(portsFile)
+		ctx.ExportEnv("DEVCMD_PROCESS", command.Name)
+		ctx.ExportEnv("DEVCMD_PROCESS_DIR", registry.Dir(namespace))
+	case ast.StopCommand:
+		defer func() { _ = store.Remove(portsFile) }()
+	}
+
+	// Steps that stay silent report that they are still running; watch commands run long on
+	// purpose and don't
+	if command.Type != ast.WatchCommand {
+		interval, ok := e.cliOptions.Heartbeats[command.Name]
+		if !ok {
+			interval = e.cliOptions.Heartbeat
+		}
+		ctx = ctx.WithHeartbeat(interval)
+	}
+
+	if e.outputPrefix {
+		stdout, stderr, flush := newPrefixedOutput(command.Name)
+		defer flush()
+		ctx = ctx.WithOutput(stdout, stderr)
+	}
+
+	// Check assertions such as @requires up front so a late step can't fail after earlier ones ran
+	if err := e.preflight(ctx, command); err != nil {
+		return err
+	}
+
+	// @retry blocks report their attempts in the retry log, which each step's event carries
+	retries, err := newRetryLog()
+	if err == nil {
+		defer retries.Close()
+		ctx.ExportEnv(RetryLogEnvVar, retries.Path())
+	}
+
+	// The config block's defaultTimeout bounds commands without a @timeout of their own
+	timeout := commandTimeout(e.program, command)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = ctx.WithTimeout(timeout)
+		defer cancel()
+	}
+
+	// Execute the command content directly
+	for i, content := range command.Body.Content {
+		pos := content.Position()
+		step := execution.Event{Command: command.Name, Step: i + 1, StepName: describeStep(content), Line: pos.Line, Column: pos.Column}
+
+		pre := step
+		pre.Type = execution.EventPreStep
+		if err := e.emit(pre); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err := e.executeStep(ctx, content)
+		if err != nil && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = defaultTimeoutError(timeout)
+		}
+
+		post := step
+		post.Type = execution.EventPostStep
+		post.Status = "success"
+		post.Duration = time.Since(start)
+		if err != nil {
+			post.Status = "failed"
+			post.Err = err
+		}
+		if retries != nil {
+			post.Attempts, post.FailedAttempts = retries.Take()
+		}
+		if hookErr := e.emit(post); hookErr != nil && err == nil {
+			err = hookErr
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeStep executes a single top-level command step in interpreter mode
+func (e *Engine) executeStep(ctx execution.InterpreterContext, content ast.CommandContent) error {
+	switch c := content.(type) {
+	case *ast.ShellContent:
+		// Execute shell content using the execution context
+		result := ctx.ExecuteShell(c)
+		if result.Error != nil {
+			return result.Error
+		}
+	case *ast.BlockDecorator:
+		// Execute block decorator using the registry
+		blockDecorator, err := decorators.GetBlock(c.Name)
+		if err != nil {
+			return fmt.Errorf("block decorator @%s not found: %w", c.Name, err)
+		}
+
+		result := blockDecorator.ExecuteInterpreter(ctx, c.Args, c.Content)
+		if result.Error != nil {
+			return fmt.Errorf("@%s decorator execution failed: %w", c.Name, result.Error)
+		}
+	case *ast.PatternDecorator:
+		// Execute pattern decorator using the registry
+		patternDecorator, err := decorators.GetPattern(c.Name)
+		if err != nil {
+			return fmt.Errorf("pattern decorator @%s not found: %w", c.Name, err)
+		}
+
+		result := patternDecorator.ExecuteInterpreter(ctx, c.Args, c.Patterns)
+		if result.Error != nil {
+			return fmt.Errorf("@%s decorator execution failed: %w", c.Name, result.Error)
+		}
+	default:
+		return fmt.Errorf("unsupported command content type in interpreter mode: %T", content)
+	}
+
+	return nil
+}
+
+// ExecuteCommandPlan generates an execution plan for a command without executing it
+func (e *Engine) ExecuteCommandPlan(command *ast.CommandDecl) (*plan.ExecutionPlan, error) {
+	ctx, err := e.commandPlanContext(command)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a new execution plan
 	planBuilder := plan.NewPlan()
 
-	// Execute the command content in plan mode to collect plan elements
-	for _, content := range command.Body.Content {
-		switch c := content.(type) {
-		case *ast.ShellContent:
-			// Execute shell content in plan mode
-			result := ctx.GenerateShellPlan(c)
-			if result.Error != nil {
-				return nil, fmt.Errorf("failed to create plan for shell content: %w", result.Error)
-			}
-
-			// Convert the result to a plan element
-			if planData, ok := result.Data.(map[string]interface{}); ok {
-				if cmdStr, ok := planData["command"].(string); ok {
-					description := "Execute shell command"
-					if desc, ok := planData["description"].(string); ok {
-						description = desc
-					}
-					element := plan.Command(cmdStr).WithDescription(description)
-					planBuilder.Add(element)
-				}
-			}
-		case *ast.BlockDecorator:
-			// Execute block decorator in plan mode
-			result, err := e.executeDecoratorPlan(ctx, c)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create plan for block decorator: %w", err)
-			}
+	// The commands it needs run first
+	needs, err := e.needsPlan(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	if needs != nil {
+		planBuilder.Add(needs)
+	}
 
-			// Add the plan element returned by the decorator
-			if planElement, ok := result.Data.(plan.PlanElement); ok {
-				planBuilder.Add(planElement)
-			}
-		default:
-			return nil, fmt.Errorf("unsupported command content type in plan mode: %T", content)
+	// Execute the command content in plan mode to collect plan elements, under the config
+	// block's defaultTimeout if it bounds the command
+	var steps []plan.PlanElement
+	for _, content := range command.Body.Content {
+		element, err := e.contentPlan(ctx, content)
+		if err != nil {
+			return nil, err
 		}
+		if element != nil {
+			steps = append(steps, element)
+		}
+	}
+	if timeout := commandTimeout(e.program, command); timeout > 0 {
+		element := plan.Decorator("timeout").
+			WithType("block").
+			WithTimeout(timeout).
+			WithParameter("duration", timeout.String()).
+			WithParameter("source", "config defaultTimeout").
+			WithDescription(fmt.Sprintf("Execute %d commands with the config block's %s defaultTimeout (cancel if exceeded)", len(steps), timeout))
+		for _, step := range steps {
+			element = element.AddChild(step)
+		}
+		steps = []plan.PlanElement{element}
+	}
+	for _, element := range steps {
+		planBuilder.Add(element)
+	}
+
+	// Triggers run after the command, depending on how it finishes
+	triggers, err := e.triggerPlans(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range triggers {
+		planBuilder.Add(element)
 	}
 
 	// Build the plan and add command name to context
@@ -164,6 +399,54 @@ func (e *Engine) ExecuteCommandPlan(command *ast.CommandDecl) (*plan.ExecutionPl
 	return execPlan, nil
 }
 
+// commandPlanContext returns the plan context a command's steps are planned in, with the
+// program's variables and the command's parameters
+func (e *Engine) commandPlanContext(command *ast.CommandDecl) (execution.PlanContext, error) {
+	if err := checkPlanSupport(command); err != nil {
+		return nil, err
+	}
+	// Create plan context
+	ctx := execution.NewPlanContext(context.Background(), e.program)
+	e.setupPlanDecoratorLookups(ctx)
+
+	// Initialize variables if not already done
+	if err := ctx.InitializeVariables(); err != nil {
+		return nil, fmt.Errorf("failed to initialize variables: %w", err)
+	}
+	return ctx.WithParams(paramValues(command, e.params)), nil
+}
+
+// contentPlan returns the plan element of a top-level step, or nil if it has none
+func (e *Engine) contentPlan(ctx execution.PlanContext, content ast.CommandContent) (plan.PlanElement, error) {
+	switch c := content.(type) {
+	case *ast.ShellContent:
+		// Execute shell content in plan mode
+		result := ctx.GenerateShellPlan(c)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to create plan for shell content: %w", result.Error)
+		}
+
+		// Return the plan element the context built for the shell command
+		if planElement, ok := result.Data.(plan.PlanElement); ok {
+			return planElement, nil
+		}
+	case *ast.BlockDecorator:
+		// Execute block decorator in plan mode
+		result, err := e.executeDecoratorPlan(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create plan for block decorator: %w", err)
+		}
+
+		// Return the plan element returned by the decorator
+		if planElement, ok := result.Data.(plan.PlanElement); ok {
+			return planElement, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported command content type in plan mode: %T", content)
+	}
+	return nil, nil
+}
+
 // executeDecoratorPlan executes a decorator in plan mode
 func (e *Engine) executeDecoratorPlan(ctx execution.PlanContext, decorator *ast.BlockDecorator) (*execution.ExecutionResult, error) {
 	// Look up the decorator in the registry
@@ -188,135 +471,33 @@ func (e *Engine) GenerateCode(program *ast.Program) (*GenerationResult, error) {
 }
 
 func (e *Engine) GenerateCodeWithModule(program *ast.Program, moduleName string) (*GenerationResult, error) {
-	// Use the new template-based approach
-	return e.generateCodeWithTemplate(program, moduleName)
+	return e.Generate(GoBackend, program, moduleName)
 }
 
-// WriteFiles writes the generated Go code and go.mod to the specified directory
+// WriteFiles writes the generated code, the files beside it and, for Go, go.mod to the
+// specified directory
 func (e *Engine) WriteFiles(result *GenerationResult, targetDir string, moduleName string) error {
-	// Write main.go
-	mainGoPath := filepath.Join(targetDir, "main.go")
-	if err := os.WriteFile(mainGoPath, []byte(result.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write main.go: %w", err)
+	// Write main.go, or the file another backend names
+	fileName := result.FileName
+	if fileName == "" {
+		fileName = "main.go"
 	}
-
-	// Write go.mod
-	goModPath := filepath.Join(targetDir, "go.mod")
-	if err := os.WriteFile(goModPath, []byte(result.GoModString()), 0o644); err != nil {
-		return fmt.Errorf("failed to write go.mod: %w", err)
+	if err := os.WriteFile(filepath.Join(targetDir, fileName), []byte(result.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fileName, err)
 	}
-
-	return nil
-}
-
-// collectDecoratorImports collects import requirements from all decorators used in the program
-func (e *Engine) collectDecoratorImports(program *ast.Program, result *GenerationResult) error {
-	// Collect from commands
-	for _, cmd := range program.Commands {
-		if err := e.collectDecoratorImportsFromContent(cmd.Body.Content, result); err != nil {
-			return err
+	for name, content := range result.Files {
+		if err := os.WriteFile(filepath.Join(targetDir, name), []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
 		}
 	}
-	return nil
-}
-
-// collectDecoratorImportsFromContent recursively collects decorator imports from command content
-func (e *Engine) collectDecoratorImportsFromContent(content []ast.CommandContent, result *GenerationResult) error {
-	for _, item := range content {
-		switch c := item.(type) {
-		case *ast.ShellContent:
-			// Collect from decorators in shell parts
-			for _, part := range c.Parts {
-				if valueDecor, ok := part.(*ast.ValueDecorator); ok {
-					if err := e.addDecoratorImports("value", valueDecor.Name, result); err != nil {
-						return err
-					}
-				} else if actionDecor, ok := part.(*ast.ActionDecorator); ok {
-					if err := e.addDecoratorImports("action", actionDecor.Name, result); err != nil {
-						return err
-					}
-				}
-			}
-		case *ast.BlockDecorator:
-			if err := e.addDecoratorImports("block", c.Name, result); err != nil {
-				return err
-			}
-			// Recursively collect from block content
-			if err := e.collectDecoratorImportsFromContent(c.Content, result); err != nil {
-				return err
-			}
-		case *ast.PatternDecorator:
-			if err := e.addDecoratorImports("pattern", c.Name, result); err != nil {
-				return err
-			}
-			// Recursively collect from pattern branches
-			for _, pattern := range c.Patterns {
-				if err := e.collectDecoratorImportsFromContent(pattern.Commands, result); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// addDecoratorImports adds import requirements for a specific decorator
-func (e *Engine) addDecoratorImports(decoratorType, name string, result *GenerationResult) error {
-	var decorator decorators.Decorator
-	var err error
-
-	switch decoratorType {
-	case "value":
-		decorator, err = decorators.GetValue(name)
-	case "action":
-		decorator, err = decorators.GetAction(name)
-	case "block":
-		decorator, err = decorators.GetBlock(name)
-	case "pattern":
-		decorator, err = decorators.GetPattern(name)
-	default:
-		return fmt.Errorf("unknown decorator type: %s", decoratorType)
-	}
-
-	if err != nil {
-		return fmt.Errorf("decorator %s not found: %w", name, err)
+	if result.GoMod.Len() == 0 {
+		return nil
 	}
 
-	// Get import requirements if the decorator supports it
-	if importProvider, ok := decorator.(interface {
-		ImportRequirements() decorators.ImportRequirement
-	}); ok {
-		requirements := importProvider.ImportRequirements()
-
-		// Add standard library imports
-		for _, pkg := range requirements.StandardLibrary {
-			result.AddStandardImport(pkg)
-		}
-
-		// Add third-party imports
-		for _, pkg := range requirements.ThirdParty {
-			result.AddThirdPartyImport(pkg)
-		}
-
-		// Add Go modules
-		for module, version := range requirements.GoModules {
-			result.AddGoModule(module, version)
-		}
-
-		// Check if this decorator uses the plan package and inject devcmd dependency
-		needsDevcmdPlan := false
-		for _, pkg := range requirements.ThirdParty {
-			if strings.Contains(pkg, "github.com/aledsdavies/devcmd/core/plan") {
-				needsDevcmdPlan = true
-				break
-			}
-		}
-
-		// If decorator needs plan package, add devcmd dependency with current version
-		if needsDevcmdPlan {
-			devcmdVersion := e.getDevcmdVersion()
-			result.AddGoModule("github.com/aledsdavies/devcmd", devcmdVersion)
-		}
+	// Write go.mod
+	goModPath := filepath.Join(targetDir, "go.mod")
+	if err := os.WriteFile(goModPath, []byte(result.GoModString()), 0o644); err != nil {
+		return fmt.Errorf("failed to write go.mod: %w", err)
 	}
 
 	return nil
@@ -328,9 +509,7 @@ const goModTemplate = `module {{.ModuleName}}
 
 go {{.GoVersion}}
 
-require (
-	github.com/spf13/cobra v1.9.1{{if .NeedsDevcmd}}
-	github.com/aledsdavies/devcmd {{.DevcmdVersion}}{{end}}{{range .Modules}}
+require ({{range .Modules}}
 	{{.Module}} {{.Version}}{{end}}
 )
 {{if and .NeedsDevcmd .IsLocalDev}}
@@ -339,13 +518,12 @@ replace github.com/aledsdavies/devcmd => {{.LocalPath}}
 {{end}}`
 
 type GoModTemplateData struct {
-	ModuleName    string
-	GoVersion     string
-	NeedsDevcmd   bool
-	DevcmdVersion string
-	IsLocalDev    bool
-	LocalPath     string
-	Modules       []ModuleData
+	ModuleName  string
+	GoVersion   string
+	NeedsDevcmd bool
+	IsLocalDev  bool
+	LocalPath   string
+	Modules     []ModuleData // The modules the program's features require, by path
 }
 
 type ModuleData struct {
@@ -354,25 +532,15 @@ type ModuleData struct {
 }
 
 func (e *Engine) generateGoMod(result *GenerationResult, moduleName string) error {
-	// Check if we need devcmd module (for plan DSL, etc.)
-	needsDevcmd := false
-	for module := range result.GoModules {
-		if strings.Contains(module, "github.com/aledsdavies/devcmd") {
-			needsDevcmd = true
-			break
-		}
-	}
-
-	// Collect other modules (excluding cobra and devcmd)
+	_, needsDevcmd := result.GoModules[devcmdModule]
 	var modules []ModuleData
 	for module, version := range result.GoModules {
-		if module != "github.com/spf13/cobra" && !strings.Contains(module, "github.com/aledsdavies/devcmd") {
-			modules = append(modules, ModuleData{
-				Module:  module,
-				Version: version,
-			})
-		}
+		modules = append(modules, ModuleData{
+			Module:  module,
+			Version: version,
+		})
 	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Module < modules[j].Module })
 
 	// Use provided module name or fallback to default
 	if moduleName == "" {
@@ -380,13 +548,12 @@ func (e *Engine) generateGoMod(result *GenerationResult, moduleName string) erro
 	}
 
 	templateData := GoModTemplateData{
-		ModuleName:    moduleName,
-		GoVersion:     e.goVersion,
-		NeedsDevcmd:   needsDevcmd,
-		DevcmdVersion: e.getDevcmdVersion(),
-		IsLocalDev:    e.isLocalDevelopment(),
-		LocalPath:     e.getDevcmdLocalPath(),
-		Modules:       modules,
+		ModuleName:  moduleName,
+		GoVersion:   e.goVersion,
+		NeedsDevcmd: needsDevcmd,
+		IsLocalDev:  e.isLocalDevelopment(),
+		LocalPath:   e.getDevcmdLocalPath(),
+		Modules:     modules,
 	}
 
 	tmpl, err := template.New("goMod").Parse(goModTemplate)
@@ -447,27 +614,6 @@ func (e *Engine) getDevcmdLocalPath() string {
 	return "../../"
 }
 
-// toCamelCase converts a command name to camelCase for variable naming
-// Examples: "build" -> "build", "test-all" -> "testAll", "dev_flow" -> "devFlow"
-func toCamelCase(name string) string {
-	// Handle different separators: hyphens, underscores, and spaces
-	parts := strings.FieldsFunc(name, func(r rune) bool {
-		return r == '-' || r == '_' || r == ' '
-	})
-
-	if len(parts) == 0 {
-		return name
-	}
-
-	// First part stays lowercase, subsequent parts get title case
-	result := parts[0]
-	for i := 1; i < len(parts); i++ {
-		result += capitalizeFirst(parts[i])
-	}
-
-	return result
-}
-
 // capitalizeFirst capitalizes the first letter of a string (replacement for deprecated strings.Title)
 func capitalizeFirst(s string) string {
 	if len(s) == 0 {
@@ -482,10 +628,16 @@ func (e *Engine) trackVariableUsage(content ast.CommandContent, usedVars map[str
 	case *ast.ShellContent:
 		for _, part := range c.Parts {
 			if funcDec, ok := part.(*ast.ValueDecorator); ok {
-				if funcDec.Name == "var" && len(funcDec.Args) == 1 {
+				if funcDec.Name == "var" && len(funcDec.Args) > 0 {
 					if ident, ok := funcDec.Args[0].Value.(*ast.Identifier); ok {
 						usedVars[ident.Name] = true
 					}
+				} else if decoratorInterface, err := decorators.GetValue(funcDec.Name); err == nil {
+					trackReferencedVariables(decoratorInterface, funcDec.Args, usedVars)
+				}
+			} else if actionDec, ok := part.(*ast.ActionDecorator); ok {
+				if decoratorInterface, err := decorators.GetAction(actionDec.Name); err == nil {
+					trackReferencedVariables(decoratorInterface, actionDec.Args, usedVars)
 				}
 			}
 		}
@@ -494,6 +646,9 @@ func (e *Engine) trackVariableUsage(content ast.CommandContent, usedVars map[str
 			e.trackVariableUsage(item, usedVars)
 		}
 	case *ast.PatternDecorator:
+		if decoratorInterface, err := decorators.GetPattern(c.Name); err == nil {
+			trackReferencedVariables(decoratorInterface, c.Args, usedVars)
+		}
 		for _, pattern := range c.Patterns {
 			for _, cmd := range pattern.Commands {
 				e.trackVariableUsage(cmd, usedVars)
@@ -502,6 +657,44 @@ func (e *Engine) trackVariableUsage(content ast.CommandContent, usedVars map[str
 	}
 }
 
+// trackReferencedVariables marks the variables read by the parameters of decorators like
+// @upper and @set as used
+func trackReferencedVariables(decorator interface{}, params []ast.NamedParameter, usedVars map[string]bool) {
+	if referencer, ok := decorator.(decorators.VariableReferencer); ok {
+		for _, name := range referencer.ReferencedVariables(params) {
+			usedVars[name] = true
+		}
+	}
+}
+
+// trackVariableAssignments recursively tracks which variables decorators like @set assign
+func (e *Engine) trackVariableAssignments(content ast.CommandContent, assignedVars map[string]bool) {
+	switch c := content.(type) {
+	case *ast.ShellContent:
+		for _, part := range c.Parts {
+			if actionDec, ok := part.(*ast.ActionDecorator); ok {
+				if decoratorInterface, err := decorators.GetAction(actionDec.Name); err == nil {
+					if assigner, ok := decoratorInterface.(decorators.VariableAssigner); ok {
+						for _, name := range assigner.AssignedVariables(actionDec.Args) {
+							assignedVars[name] = true
+						}
+					}
+				}
+			}
+		}
+	case *ast.BlockDecorator:
+		for _, item := range c.Content {
+			e.trackVariableAssignments(item, assignedVars)
+		}
+	case *ast.PatternDecorator:
+		for _, pattern := range c.Patterns {
+			for _, cmd := range pattern.Commands {
+				e.trackVariableAssignments(cmd, assignedVars)
+			}
+		}
+	}
+}
+
 // trackVariableUsageInBody tracks variable usage in a command body
 func (e *Engine) trackVariableUsageInBody(body *ast.CommandBody, usedVars map[string]bool) {
 	for _, content := range body.Content {
@@ -517,8 +710,8 @@ func (e *Engine) generateShellCommandExpression(content *ast.ShellContent) (stri
 	// Get template functions and use buildCommand helper
 	funcs := generatorCtx.GetTemplateFunctions()
 	if buildCommand, ok := funcs["buildCommand"]; ok {
-		if buildFunc, ok := buildCommand.(func(interface{}) string); ok {
-			return buildFunc(content), nil
+		if buildFunc, ok := buildCommand.(func(interface{}) (string, error)); ok {
+			return buildFunc(content)
 		}
 	}
 
@@ -624,7 +817,8 @@ func (e *Engine) sortCommandsByDependencies(commands []*ast.CommandDecl) ([]*ast
 
 // findCommandDependencies scans a command for decorator dependencies using CommandDependencyProvider interface
 func (e *Engine) findCommandDependencies(cmd *ast.CommandDecl) []string {
-	dependencies := []string{}
+	// The commands it needs run before it, as those it runs with @cmd do
+	dependencies := cmd.NeedNames()
 
 	for _, content := range cmd.Body.Content {
 		deps := e.scanContentForDependencies(content)
@@ -776,8 +970,15 @@ import (
 
 // ExecutionContext carries minimal state needed for execution
 type ExecutionContext struct {
-	Dir string                // Working directory
-	Env map[string]string     // Environment variables
+	Dir    string                       // Working directory
+	Env    map[string]string            // Environment variables
+	Shell  []string                     // Command prefix that runs shell steps (e.g. a container); defaults to defaultShell
+	Stdout *os.File                     // Destination of shell step output; defaults to os.Stdout
+	Stderr *os.File                     // Destination of shell step errors; defaults to os.Stderr
+	Run    func(cmd *execpkg.Cmd) error // Runs shell step processes (e.g. under a PTY); defaults to cmd.Run
+	Stdin  string                       // File shell steps read as stdin; empty inherits os.Stdin
+	Strict bool                         // Run shell steps with strictShellPrefix
+	Params map[string]string            // Values of the running command's parameters, by name
 }
 
 // Clone creates an isolated copy of the context
@@ -786,34 +987,878 @@ func (c ExecutionContext) Clone() ExecutionContext {
 	for k, v := range c.Env {
 		newEnv[k] = v
 	}
-	return ExecutionContext{
-		Dir: c.Dir,
-		Env: newEnv,
+	return ExecutionContext{
+		Dir:    c.Dir,
+		Env:    newEnv,
+		Shell:  c.Shell,
+		Stdout: c.Stdout,
+		Stderr: c.Stderr,
+		Run:    c.Run,
+		Stdin:  c.Stdin,
+		Strict: c.Strict,
+		Params: c.Params,
+	}
+}
+
+// WithParams returns a copy of the context for a command run with the given parameter values
+func (c ExecutionContext) WithParams(params map[string]string) ExecutionContext {
+	c.Params = params
+	return c
+}
+
+// strictShellPrefix stops strict shell steps at the first failing command, unset variable,
+// or failure inside a pipeline where sh supports pipefail
+const strictShellPrefix = {{printf "%q" .StrictShellPrefix}}
+
+// defaultShell returns the shell that runs shell steps when none is selected: sh, except on
+// Windows, where it is the first of sh, pwsh and powershell in PATH, falling back to cmd.
+// It mirrors execution.DefaultShell.
+func defaultShell() []string {
+	if runtime.GOOS != "windows" {
+		return []string{"sh"}
+	}
+	for _, name := range []string{"sh", "pwsh", "powershell"} {
+		if _, err := execpkg.LookPath(name); err == nil {
+			return []string{name}
+		}
+	}
+	return []string{"cmd"}
+}
+
+// shellName returns the name of the program at the end of a shell prefix, without its
+// directory or .exe extension
+func shellName(shell []string) string {
+	name := shell[len(shell)-1]
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' || name[i] == '\\' {
+			name = name[i+1:]
+			break
+		}
+	}
+	if n := len(name); n > 4 && (name[n-4:] == ".exe" || name[n-4:] == ".EXE") {
+		name = name[:n-4]
+	}
+	return name
+}
+
+// messages are the CLI's user-facing messages by locale, each complete with English for what
+// the locale lacks
+var messages = {{.Messages}}
+
+// locale is the locale messages are shown in: DEVCMD_LOCALE, or else the first of LC_ALL,
+// LC_MESSAGES and LANG that is set, in the form "de-DE". It mirrors messages.LocaleFromEnv.
+var locale = func() string {
+	for _, name := range []string{"DEVCMD_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			var locale []byte
+			for i := 0; i < len(value) && value[i] != '.' && value[i] != '@'; i++ {
+				if value[i] == '_' {
+					locale = append(locale, '-')
+				} else {
+					locale = append(locale, value[i])
+				}
+			}
+			if string(locale) != "C" && string(locale) != "POSIX" {
+				return string(locale)
+			}
+			break
+		}
+	}
+	return "en"
+}()
+
+// msg returns a message in the locale, in its language for regional locales without it, or
+// in English
+func msg(id string) string {
+	candidates := []string{locale, "en"}
+	for i := 0; i < len(locale); i++ {
+		if locale[i] == '-' {
+			candidates = []string{locale, locale[:i], "en"}
+			break
+		}
+	}
+	for _, candidate := range candidates {
+		if message, ok := messages[candidate][id]; ok {
+			return message
+		}
+	}
+	return id
+}
+
+// exec runs a shell command with the given context
+func exec(ctx ExecutionContext, command string) error {
+	shell := defaultShell()
+	if len(ctx.Shell) > 0 {
+		shell = ctx.Shell
+	}
+	name := shellName(shell)
+	if ctx.Strict && name != "cmd" && name != "pwsh" && name != "powershell" {
+		command = strictShellPrefix + command
+	}
+	flag := "-c"
+	if name == "cmd" {
+		flag = "/C"
+	}
+	cmd := execpkg.Command(shell[0], append(append([]string{}, shell[1:]...), flag, command)...)
+	cmd.Dir = ctx.Dir
+	cmd.Stdout = os.Stdout
+	if ctx.Stdout != nil {
+		cmd.Stdout = ctx.Stdout
+	}
+	cmd.Stderr = os.Stderr
+	if ctx.Stderr != nil {
+		cmd.Stderr = ctx.Stderr
+	}
+	cmd.Stdin = os.Stdin
+	if ctx.Stdin != "" {
+		stdin, err := os.Open(ctx.Stdin)
+		if err != nil {
+			return err
+		}
+		defer stdin.Close()
+		cmd.Stdin = stdin
+	}
+	
+	// Set environment if provided
+	if len(ctx.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range ctx.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	
+	logf("debug", "shell", "running %s", command)
+	start := time.Now()
+	if ctx.Run != nil {
+		err := ctx.Run(cmd)
+		logf("debug", "shell", "finished in %s", time.Since(start).Round(time.Millisecond))
+		return err
+	}
+	err := cmd.Run()
+	logf("debug", "shell", "finished in %s", time.Since(start).Round(time.Millisecond))
+	return err
+}
+
+// logLevels orders the --log-level names; records below logLevel are dropped
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// logLevel and logJSON are set from --log-level and --log-format
+var (
+	logLevel = "info"
+	logJSON  = false
+)
+
+// accessible is set from --accessible or DEVCMD_ACCESSIBLE, for screen reader friendly
+// output: plain plans and diagnostics led by their level as a word
+var accessible = func() bool {
+	switch os.Getenv("DEVCMD_ACCESSIBLE") {
+	case "1", "t", "T", "true", "TRUE", "True":
+		return true
+	}
+	return false
+}()
+
+// logLevelWords lead diagnostics in accessible output, which can't rely on color
+var logLevelWords = map[string]string{"debug": "Debug", "info": "Info", "warn": "Warning", "error": "Error"}
+
+// projectConfig holds the defaults section of the project's settings, by config name
+var projectConfig = {{.ProjectConfig}}
+
+// userConfigValues caches the user config, read when a setting is first looked up
+var userConfigValues map[string]string
+
+// userConfigFile returns the user config file: $DEVCMD_CONFIG, or devcmd/config.toml in
+// $XDG_CONFIG_HOME or ~/.config. It mirrors config.UserFile.
+func userConfigFile() string {
+	if path := os.Getenv("DEVCMD_CONFIG"); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = home + string(os.PathSeparator) + ".config"
+	}
+	return dir + string(os.PathSeparator) + "devcmd" + string(os.PathSeparator) + "config.toml"
+}
+
+// userConfig reads the key = value lines of TOML in the user config file. It mirrors
+// config.Parse, skipping the lines it can't read, which devcmd config reports.
+func userConfig() map[string]string {
+	values := make(map[string]string)
+	data, err := os.ReadFile(userConfigFile())
+	if err != nil {
+		return values
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		name, raw, ok := bytes.Cut(line, []byte("="))
+		if !ok || bytes.HasPrefix(line, []byte("#")) {
+			continue
+		}
+		raw = bytes.TrimSpace(raw)
+		var value string
+		switch {
+		case bytes.HasPrefix(raw, []byte("\"")):
+			// TOML's basic strings are JSON's, and the decoder stops before a comment
+			if json.NewDecoder(bytes.NewReader(raw)).Decode(&value) != nil {
+				continue
+			}
+		case bytes.HasPrefix(raw, []byte("'")):
+			end := bytes.IndexByte(raw[1:], '\'')
+			if end < 0 {
+				continue
+			}
+			value = string(raw[1 : end+1])
+		default:
+			raw, _, _ = bytes.Cut(raw, []byte("#"))
+			value = string(bytes.TrimSpace(raw))
+		}
+		values[string(bytes.TrimSpace(name))] = value
+	}
+	return values
+}
+
+// configValue returns a setting from its environment variable, the project's defaults or the
+// user config, in that order, or "" when none sets it; flags win over all of them. It mirrors
+// config.Chain.Lookup.
+func configValue(name, envVar string) string {
+	if value := os.Getenv(envVar); value != "" {
+		return value
+	}
+	if value, ok := projectConfig[name]; ok {
+		return value
+	}
+	if userConfigValues == nil {
+		userConfigValues = userConfig()
+	}
+	return userConfigValues[name]
+}
+
+// configDisablesColor reports whether the config turns color off, as --no-color does
+func configDisablesColor() bool {
+	switch configValue("color", "DEVCMD_COLOR") {
+	case "0", "f", "F", "false", "FALSE", "False":
+		return true
+	}
+	return false
+}
+
+// maskSecrets replaces the values @secret has read with *** in diagnostics and error
+// messages; addSecret sets it in CLIs that use @secret
+var maskSecrets = func(message string) string { return message }
+
+// logf writes a diagnostic record from source (e.g. "@retry") to stderr, as "source: message"
+// or, with --log-format=json, as one JSON object per line
+func logf(level, source, format string, args ...interface{}) {
+	if logLevels[level] < logLevels[logLevel] {
+		return
+	}
+	message := maskSecrets(fmt.Sprintf(format, args...))
+	if !logJSON {
+		if accessible {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", logLevelWords[level], source, message)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", source, message)
+		return
+	}
+	record, _ := json.Marshal(struct {
+		Time   string "json:\"time\""
+		Level  string "json:\"level\""
+		Source string "json:\"source\""
+		Msg    string "json:\"msg\""
+	}{time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), level, source, message})
+	fmt.Fprintf(os.Stderr, "%s\n", record)
+}
+
+// ciSourceFile is the commands file this CLI was generated from, for CI annotations
+const ciSourceFile = {{printf "%q" .SourceFile}}
+
+{{if .SourceHash}}
+// sourceHash is the SHA-256 of the commands file this CLI was generated from, followed by
+// its local override file when there was one
+const sourceHash = {{printf "%q" .SourceHash}}
+
+// localSourceFile is the local override file merged after ciSourceFile
+const localSourceFile = {{printf "%q" .LocalSourceFile}}
+
+// sourceProject is the directory of the project this CLI was generated from, which
+// ciSourceFile and localSourceFile are found in
+const sourceProject = {{printf "%q" .ProjectDir}}
+
+// checkSourceDrift compares the commands file on disk with the one this CLI was generated from.
+// When they differ it warns{{if .Regenerate}}, or rebuilds the CLI with devcmd and runs the command
+// with the rebuilt one when devcmd is on PATH{{end}}. Run outside that project, the CLI has no
+// commands file of its own to compare. DEVCMD_NO_DRIFT_CHECK turns the check off.
+func checkSourceDrift() {
+	if os.Getenv("DEVCMD_NO_DRIFT_CHECK") != "" {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	project := sourceProject
+	if resolved, err := filepath.EvalSymlinks(project); err == nil {
+		project = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(wd); err == nil {
+		wd = resolved
+	}
+	if rel, err := filepath.Rel(project, wd); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+	sourceFile := filepath.Join(sourceProject, filepath.Base(ciSourceFile))
+	source, err := os.ReadFile(sourceFile)
+	if err != nil {
+		// The project no longer has the commands file; there is nothing to compare
+		return
+	}
+	sources := ciSourceFile
+	if local, err := os.ReadFile(filepath.Join(sourceProject, filepath.Base(localSourceFile))); err == nil {
+		source = append(source, local...)
+		sources += " or " + localSourceFile
+	}
+	sum := sha256.Sum256(source)
+	if hex.EncodeToString(sum[:]) == sourceHash {
+		return
+	}
+{{if .Regenerate}}
+	devcmd, lookErr := execpkg.LookPath("devcmd")
+	self, selfErr := os.Executable()
+	if lookErr == nil && selfErr == nil {
+		fmt.Fprintf(os.Stderr, "%s has changed since this CLI was generated; rebuilding %s\n", sources, filepath.Base(self))
+		build := execpkg.Command(devcmd, "build", "-f", filepath.Base(sourceFile), "-o", self{{range .DefineArgs}}, {{printf "%q" .}}{{end}})
+		build.Dir = sourceProject
+		build.Stdout = os.Stderr
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to rebuild %s: %v; running the stale CLI\n", filepath.Base(self), err)
+			return
+		}
+		rerun := execpkg.Command(self, os.Args[1:]...)
+		rerun.Stdin = os.Stdin
+		rerun.Stdout = os.Stdout
+		rerun.Stderr = os.Stderr
+		rerun.Env = append(os.Environ(), "DEVCMD_NO_DRIFT_CHECK=1")
+		if err := rerun.Run(); err != nil {
+			if exitErr, ok := err.(*execpkg.ExitError); ok {
+				os.Exit(exitErr.ExitCode())
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+{{end}}
+	fmt.Fprintf(os.Stderr, "warning: %s has changed since this CLI was generated; run 'devcmd build' to update it\n", sources)
+}
+{{end}}
+{{if .ProcessGroups}}
+// processProject is the directory of the project whose background processes this CLI
+// manages, and processNamespace its namespace in the process registry, where devcmd ps lists
+// the background processes of every generated CLI. They start as the project the CLI was
+// built from, which setProcessProject replaces with the one the CLI runs in.
+var processProject, processNamespace = {{printf "%q" .ProjectDir}}, {{printf "%q" .ProcessNamespace}}
+
+// processSourceName is the name of the commands file, which marks the project directory
+const processSourceName = {{printf "%q" .ProcessSourceName}}
+
+// processProjectFlag is the project directory --project names
+var processProjectFlag string
+
+// setProcessProject sets the project to the directory --project names or, without it, the
+// nearest directory holding the commands file from the working directory up. Outside any
+// project the CLI keeps the one it was built from.
+func setProcessProject() error {
+	project := processProjectFlag
+	if project != "" {
+		if info, err := os.Stat(project); err != nil || !info.IsDir() {
+			return fmt.Errorf("--project %s is not a directory", project)
+		}
+	} else if dir, err := os.Getwd(); err == nil && processSourceName != "" {
+		for ; project == ""; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(filepath.Join(dir, processSourceName)); err == nil {
+				project = dir
+			} else if filepath.Dir(dir) == dir {
+				return nil
+			}
+		}
+	}
+	if project == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(project)
+	if err != nil {
+		return err
+	}
+	processProject, processNamespace = abs, projectNamespace(abs)
+	return nil
+}
+
+// projectNamespace returns the namespace of the project in dir as processes.Namespace does:
+// its base name and a hash of its absolute path
+func projectNamespace(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, filepath.Base(dir))
+	if strings.Trim(name, ".-") == "" {
+		name = "project"
+	}
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// processRestart is how the devcmd daemon restarts watch commands it supervises
+const processRestart = {{printf "%q" .ProcessRestart}}
+
+// processRoot returns the process registry: $DEVCMD_REGISTRY, processes in the state
+// directory of the config, or devcmd/processes in the user cache directory
+func processRoot() string {
+	if root := os.Getenv("DEVCMD_REGISTRY"); root != "" {
+		return root
+	}
+	if state := configValue("state_dir", "DEVCMD_STATE_DIR"); state != "" {
+		if home, err := os.UserHomeDir(); err == nil && state[0] == '~' {
+			state = home + state[1:]
+		}
+		return filepath.Join(state, "processes")
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cache, "devcmd", "processes")
+	}
+	return filepath.Join(os.TempDir(), "devcmd", "processes")
+}
+
+// processDir returns the directory of this project's PID, log and ports files, its
+// namespace in the process registry
+func processDir() string {
+	return filepath.Join(processRoot(), processNamespace)
+}
+
+// processStopOrder lists the watch commands in the order stop --all stops them, each
+// before the processes it depends on
+var processStopOrder = []string{ {{range .ProcessStopOrder}}{{printf "%q" .}}, {{end}}}
+
+// processStopTimeouts is how long each process has to exit after SIGTERM before it is killed
+var processStopTimeouts = map[string]time.Duration{ {{range .ProcessGroups}}{{printf "%q" .Identifier}}: {{printf "%d" .StopTimeout}}, {{end}}}
+
+// processHealth probes a recorded process: "dead" when it has exited or is a zombie,
+// "unresponsive" when none of the ports it allocated with @freeport accept connections,
+// and "healthy" otherwise
+func processHealth(name string) (int, string) {
+	pidBytes, err := os.ReadFile(filepath.Join(processDir(), name+".pid"))
+	if err != nil {
+		return 0, "dead"
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+	if err != nil || !processAlive(pid) {
+		return pid, "dead"
+	}
+	ports, _ := os.ReadFile(filepath.Join(processDir(), name+".ports"))
+	allocations := strings.Fields(string(ports))
+	if len(allocations) == 0 {
+		return pid, "healthy"
+	}
+	for _, allocation := range allocations {
+		if i := strings.Index(allocation, "="); i >= 0 {
+			if conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", allocation[i+1:]), 200*time.Millisecond); err == nil {
+				conn.Close()
+				return pid, "healthy"
+			}
+		}
+	}
+	return pid, "unresponsive"
+}
+
+// printPlanStep prints a step of a built-in command's execution plan as a tree branch, or as a
+// numbered line in accessible output
+func printPlanStep(step int, text string) {
+	if accessible {
+		fmt.Printf("Step %d: %s\n", step, text)
+		return
+	}
+	fmt.Printf("├── %s\n", text)
+}
+
+// processAlive reports whether a process exists and isn't a zombie waiting to be reaped,
+// which signals still reach; only Linux exposes the process state, in /proc.
+// process_windows.go replaces it on Windows, where signals can't probe processes.
+var processAlive = func(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if pid <= 0 || err != nil || process.Signal(syscall.Signal(0)) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if end := strings.LastIndexByte(string(stat), ')'); err == nil && end >= 0 {
+		fields := strings.Fields(string(stat[end+1:]))
+		return len(fields) == 0 || fields[0] != "Z"
+	}
+	return true
+}
+
+// terminateProcess asks a process to exit with SIGTERM, or kills it with SIGKILL when kill
+// is set. process_windows.go replaces it on Windows, which has no SIGTERM.
+var terminateProcess = func(process *os.Process, kill bool) error {
+	if kill {
+		return process.Signal(syscall.SIGKILL)
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+// daemonRequest and daemonResponse are messages to and from the devcmd daemon, whose JSON
+// keys match the field names
+type daemonRequest struct {
+	Op        string
+	Namespace string
+	Project   string
+	Name      string
+	Command   []string
+	Dir       string
+	Env       []string
+	Restart   string
+}
+
+type daemonResponse struct {
+	Error   string
+	PID     int
+	LogFile string
+}
+
+// callDaemon sends a request to the devcmd daemon supervising background processes. It
+// reports false when no daemon is running.
+func callDaemon(request daemonRequest) (daemonResponse, bool, error) {
+	var response daemonResponse
+	conn, err := net.DialTimeout("unix", filepath.Join(processRoot(), "daemon.sock"), time.Second)
+	if err != nil {
+		return response, false, nil
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return response, true, err
+	}
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return response, true, err
+	}
+	if response.Error != "" {
+		return response, true, fmt.Errorf("%s", response.Error)
+	}
+	return response, true, nil
+}
+
+// stopProcess asks the devcmd daemon to stop a process it supervises, so it isn't
+// restarted, and otherwise terminates the process, killing it when it hasn't exited within
+// its stop timeout. The stop is recorded in the namespace's history; the daemon records
+// those of the processes it supervises.
+func stopProcess(name string, process *os.Process) error {
+	if _, running, err := callDaemon(daemonRequest{Op: "stop", Namespace: processNamespace, Name: name}); running && err == nil {
+		return nil
+	}
+	stopped := false
+	if err := terminateProcess(process, false); err == nil {
+		for deadline := time.Now().Add(processStopTimeouts[name]); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if stopped = !processAlive(process.Pid); stopped {
+				break
+			}
+		}
+	}
+	if !stopped {
+		if err := terminateProcess(process, true); err != nil && processAlive(process.Pid) {
+			return err
+		}
+	}
+	_ = recordProcessEvent(processDir(), name, "stopped", process.Pid)
+	return nil
+}
+{{end}}// ciProvider is the CI system whose log syntax step output uses, detected from the environment
+var ciProvider = func() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return "github"
+	case os.Getenv("GITLAB_CI") == "true":
+		return "gitlab"
+	}
+	return ""
+}()
+
+// ciStep runs a top-level command step, wrapping its output in a collapsible CI log group
+// and annotating failures with the step's position in the commands file
+func ciStep(command string, step int, name string, line, column int, fn func() error) error {
+{{if .Hooks}}	run := fn
+	fn = func() error { return runWithHooks(command, step, name, run) }
+{{end}}	if ciProvider == "" {
+		return fn()
+	}
+
+	section := []byte(command)
+	for i, c := range section {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '.' || c == '-') {
+			section[i] = '_'
+		}
+	}
+	title := command + ": " + name
+
+	if ciProvider == "github" {
+		fmt.Print("::group::" + ciEscape(title, false) + "\n")
+	} else {
+		fmt.Printf("\x1b[0Ksection_start:%d:devcmd_%s_step_%d\r\x1b[0K%s\n", time.Now().Unix(), section, step, title)
+	}
+	err := fn()
+	if ciProvider == "github" {
+		fmt.Print("::endgroup::\n")
+	} else {
+		fmt.Printf("\x1b[0Ksection_end:%d:devcmd_%s_step_%d\r\x1b[0K\n", time.Now().Unix(), section, step)
+	}
+	if err == nil {
+		return nil
+	}
+
+	message := maskSecrets(fmt.Sprintf("Step %d (%s) failed: %v", step, name, err))
+	if ciProvider == "github" {
+		properties := "title=" + ciEscape("devcmd "+command, true)
+		if ciSourceFile != "" {
+			properties = fmt.Sprintf("file=%s,line=%d,col=%d,%s", ciEscape(ciSourceFile, true), line, column, properties)
+		}
+		fmt.Printf("::error %s::%s\n", properties, ciEscape(message, false))
+	} else {
+		location := ""
+		if ciSourceFile != "" {
+			location = fmt.Sprintf("%s:%d:%d: ", ciSourceFile, line, column)
+		}
+		fmt.Printf("\x1b[31;1m%s%s: %s\x1b[0m\n", location, command, message)
+	}
+	return err
+}
+
+// ciEscape percent-encodes the characters GitHub workflow commands reserve in messages,
+// and additionally in property values
+func ciEscape(s string, property bool) string {
+	var escaped []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' || c == '\r' || c == '\n' || (property && (c == ':' || c == ',')) {
+			escaped = append(escaped, fmt.Sprintf("%%%02X", c)...)
+		} else {
+			escaped = append(escaped, c)
+		}
+	}
+	return string(escaped)
+}
+{{if .Hooks}}
+// lifecycleHooks are the shell hooks of the project settings, by event, run as devcmd run
+// runs them
+var lifecycleHooks = map[string]string{ {{range $event, $script := .Hooks}}{{printf "%q" $event}}: {{printf "%q" $script}}, {{end}}}
+
+// runHook runs the settings hook of a lifecycle event, if there is one, with the event in
+// DEVCMD_* environment variables
+func runHook(event, command string, step int, stepName, status string, duration time.Duration, err error) error {
+	script, ok := lifecycleHooks[event]
+	if !ok {
+		return nil
+	}
+	env := append(os.Environ(),
+		"DEVCMD_EVENT="+event,
+		"DEVCMD_COMMAND="+command,
+		"DEVCMD_STATUS="+status,
+		fmt.Sprintf("DEVCMD_DURATION_MS=%d", duration.Milliseconds()))
+	if step > 0 {
+		env = append(env, fmt.Sprintf("DEVCMD_STEP=%d", step), "DEVCMD_STEP_NAME="+stepName)
+	}
+	if err != nil {
+		env = append(env, "DEVCMD_ERROR="+err.Error())
+	}
+	cmd := execpkg.Command("sh", "-c", script)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	if runErr := cmd.Run(); runErr != nil {
+		return fmt.Errorf("%s hook %q failed: %w", event, script, runErr)
+	}
+	return nil
+}
+
+// runWithHooks runs the command this CLI was invoked with, or one of its top-level steps
+// when step is set, between its pre and post hooks, with the onFailure hook of a command
+// that fails. A failing pre hook stops the run; a failing post hook fails a run that succeeded.
+func runWithHooks(command string, step int, stepName string, fn func() error) error {
+	pre, post := "preRun", "postRun"
+	if step > 0 {
+		pre, post = "preStep", "postStep"
+	}
+	if err := runHook(pre, command, step, stepName, "", 0, nil); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := fn()
+	status := "success"
+	if err != nil {
+		status = "failed"
+		if step == 0 {
+			// Failure hooks are best-effort; the command error takes precedence
+			_ = runHook("onFailure", command, 0, "", status, time.Since(start), err)
+		}
+	}
+	if hookErr := runHook(post, command, step, stepName, status, time.Since(start), err); hookErr != nil && err == nil {
+		err = hookErr
 	}
+	return err
 }
+{{end}}
+// unknownCommandError reports an unknown command with the closest matching command names
+func unknownCommandError(root *cobra.Command, name string) error {
+	var candidates []string
+	for _, c := range root.Commands() {
+		if c.IsAvailableCommand() {
+			candidates = append(candidates, c.Name())
+		}
+	}
 
-// exec runs a shell command with the given context
-func exec(ctx ExecutionContext, command string) error {
-	cmd := execpkg.Command("sh", "-c", command)
-	cmd.Dir = ctx.Dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	
-	// Set environment if provided
-	if len(ctx.Env) > 0 {
-		cmd.Env = os.Environ()
-		for k, v := range ctx.Env {
-			cmd.Env = append(cmd.Env, k+"="+v)
+{{if .Abbreviations}}
+	// Prefix matching only dispatches unambiguous prefixes; list the candidates otherwise
+	var prefixMatches []string
+	for _, candidate := range candidates {
+		if len(candidate) > len(name) && candidate[:len(name)] == name {
+			prefixMatches = append(prefixMatches, candidate)
 		}
 	}
-	
-	return cmd.Run()
+	if len(prefixMatches) > 1 {
+		message := fmt.Sprintf("ambiguous command %q for %q, could be:", name, root.CommandPath())
+		for _, match := range prefixMatches {
+			message += "\n\t" + match
+		}
+		return fmt.Errorf("%s", message)
+	}
+{{end}}
+	message := fmt.Sprintf(msg("command.unknown"), name, root.CommandPath())
+	if suggestions := suggestCommands(name, candidates); len(suggestions) > 0 {
+		message += "\n\n" + msg("command.suggestion")
+		for _, suggestion := range suggestions {
+			message += "\n\t" + suggestion
+		}
+	}
+	return fmt.Errorf("%s\n\n%s", message, fmt.Sprintf(msg("command.runHelp"), root.CommandPath()))
 }
 
-// execCheck runs a command and returns success status
-func execCheck(ctx ExecutionContext, command string) bool {
-	return exec(ctx, command) == nil
+// suggestCommands returns up to three command names closest to input, using edit
+// distance with transpositions and matching individual segments of namespaced names
+func suggestCommands(input string, candidates []string) []string {
+	lower := func(s string) string {
+		runes := []rune(s)
+		for i, r := range runes {
+			if r >= 'A' && r <= 'Z' {
+				runes[i] = r + ('a' - 'A')
+			}
+		}
+		return string(runes)
+	}
+	hasPrefix := func(s, prefix string) bool {
+		return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+	}
+	splitSegments := func(name string) []string {
+		var segments []string
+		start := 0
+		for i, r := range name {
+			if r == ':' || r == '-' || r == '_' || r == '/' || r == '.' {
+				if i > start {
+					segments = append(segments, name[start:i])
+				}
+				start = i + 1
+			}
+		}
+		if start < len(name) {
+			segments = append(segments, name[start:])
+		}
+		return segments
+	}
+	distance := func(a, b string) int {
+		ra, rb := []rune(a), []rune(b)
+		d := make([][]int, len(ra)+1)
+		for i := range d {
+			d[i] = make([]int, len(rb)+1)
+			d[i][0] = i
+		}
+		for j := range d[0] {
+			d[0][j] = j
+		}
+		for i := 1; i <= len(ra); i++ {
+			for j := 1; j <= len(rb); j++ {
+				cost := 1
+				if ra[i-1] == rb[j-1] {
+					cost = 0
+				}
+				d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+				if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+					d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+				}
+			}
+		}
+		return d[len(ra)][len(rb)]
+	}
+
+	input = lower(input)
+	threshold := 2
+	if n := len([]rune(input)); n <= 2 {
+		threshold = 1
+	} else if n > 5 {
+		threshold = n/3 + 1
+	}
+
+	type match struct {
+		name  string
+		score int
+	}
+	var matches []match
+	for _, candidate := range candidates {
+		name := lower(candidate)
+		best := distance(input, name)
+		if name == input {
+			best = 0
+		} else if hasPrefix(name, input) {
+			best = 1
+		} else if segments := splitSegments(name); len(segments) > 1 {
+			inputSegments := splitSegments(input)
+			if len(inputSegments) == 1 {
+				for _, segment := range segments {
+					best = min(best, distance(input, segment)+1)
+					if hasPrefix(segment, input) {
+						best = min(best, 2)
+					}
+				}
+			} else if len(inputSegments) == len(segments) {
+				total := 0
+				for i := range segments {
+					total += distance(inputSegments[i], segments[i])
+				}
+				best = min(best, total)
+			}
+		}
+		if best <= threshold {
+			matches = append(matches, match{name: candidate, score: best})
+		}
+	}
+
+	// Order by score, then name
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0; j-- {
+			prev, cur := matches[j-1], matches[j]
+			if cur.score > prev.score || (cur.score == prev.score && cur.name >= prev.name) {
+				break
+			}
+			matches[j-1], matches[j] = cur, prev
+		}
+	}
+
+	var result []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result
 }
 
 func main() {
@@ -824,18 +1869,27 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Variables defined as constants
-	{{range .Variables}}{{if .Used}}const {{.Name}} = {{.Value}}
+	// Variables defined as constants, or as variables when @set or a profile assigns them
+	{{range .Variables}}{{if or .Assigned (and .Used .Profiled)}}var {{.Name}} = {{.Value}}
+	_ = {{.Name}}
+	{{else if .Used}}const {{.Name}} = {{.Value}}
 	{{end}}{{end}}
 
 	// Global flags for dry-run mode
 	var dryRun bool
 	var noColor bool
-
+	var noOpen bool
+{{if .Profiles}}	var selectedProfile string
+{{end}}{{if .ProcessGroups}}
+	// Restart watch commands that are already running
+	var forceRestart bool
+{{end}}
 	// Initialize root context
 	ctx := ExecutionContext{
-		Dir: workingDir,
-		Env: map[string]string{
+		Dir:    workingDir,
+		Strict: {{.StrictShell}},
+{{if .Shell}}		Shell:  []string{ {{printf "%q" .Shell}} },
+{{end}}		Env: map[string]string{
 			{{$trackedVars := .TrackedEnvVars}}{{range $envVar, $defaultValue := $trackedVars}}{{printf "%q" $envVar}}: func() string {
 				if val := os.Getenv({{printf "%q" $envVar}}); val != "" {
 					return val
@@ -845,29 +1899,105 @@ func main() {
 			{{end}}
 		},
 	}
-
+{{range $name, $value := .DefaultEnv}}
+	if _, set := os.LookupEnv({{printf "%q" $name}}); !set {
+		ctx.Env[{{printf "%q" $name}}] = {{printf "%q" $value}}
+	}
+{{end}}
+{{if .Abbreviations}}
+	// Allow commands to be invoked by an unambiguous prefix (e.g. "dep" for "deploy")
+	cobra.EnablePrefixMatching = true
+{{end}}
 	rootCmd := &cobra.Command{
 		Use:   "cli",
 		Short: "Generated CLI from devcmd",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			return unknownCommandError(cmd, args[0])
+		},
+		SilenceUsage: true,
 	}
+	// Help headings come from the messages, in the locale
+	cobra.AddTemplateFunc("msg", msg)
+	rootCmd.SetUsageTemplate({{printf "%q" .UsageTemplate}})
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show execution plan without running commands")
-	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output in dry-run mode")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", configDisablesColor(), "Disable colored output in dry-run mode")
+	rootCmd.PersistentFlags().BoolVar(&noOpen, "no-open", false, "Don't open URLs in a browser (for headless environments)")
+	rootCmd.PersistentFlags().BoolVar(&accessible, "accessible", accessible, "Screen reader friendly output: plain lines with status words, without colors or box drawing (or DEVCMD_ACCESSIBLE=1)")
+	var logFormat string
+	if level := configValue("log_level", "DEVCMD_LOG_LEVEL"); level != "" {
+		logLevel = level
+	}
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logLevel, "Lowest level of diagnostics to write: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of diagnostics on stderr: text or json")
+{{if .Profiles}}	rootCmd.PersistentFlags().StringVar(&selectedProfile, "profile", configValue("profile", "DEVCMD_PROFILE"), "Apply the variable values of an env profile: {{.ProfileNames}}")
+{{end}}{{if .ProcessGroups}}	rootCmd.PersistentFlags().StringVar(&processProjectFlag, "project", "", "Directory of the project whose background processes to manage (default: the nearest holding the commands file, else the one the CLI was built from)")
+{{end}}	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if _, ok := logLevels[logLevel]; !ok {
+			return fmt.Errorf("unsupported log level %q: expected debug, info, warn or error", logLevel)
+		}
+		if logFormat != "text" && logFormat != "json" {
+			return fmt.Errorf("unsupported log format %q: expected text or json", logFormat)
+		}
+		logJSON = logFormat == "json"
+{{if .Profiles}}		// A profile the config selects applies only if this CLI defines it
+		if !cmd.Flags().Changed("profile") {
+			switch selectedProfile {
+{{range .Profiles}}			case {{printf "%q" .Name}}:
+{{end}}			default:
+				selectedProfile = ""
+			}
+		}
+		// Variables a profile doesn't set keep their defaults
+		switch selectedProfile {
+		case "":
+{{range .Profiles}}		case {{printf "%q" .Name}}:
+{{range .Values}}			{{.Name}} = {{.Value}}
+{{end}}{{end}}		default:
+			return fmt.Errorf("unknown profile %q (available: {{.ProfileNames}})", selectedProfile)
+		}
+{{end}}{{if .ProcessGroups}}		if err := setProcessProject(); err != nil {
+			return err
+		}
+{{end}}{{if .SourceHash}}		checkSourceDrift()
+{{end}}		if noOpen {
+			os.Setenv("DEVCMD_NO_OPEN", "1")
+		}
+		return nil
+	}
 
 	// Execution functions for commands
 	{{range .Commands}}
 	execute{{.FunctionName | title}} := func(ctx ExecutionContext) error {
-		{{.ExecutionCode}}
-		return nil
+		{{if .DefaultTimeout}}return runWithTimeout({{printf "%d" .DefaultTimeout}}, func() error {
+			{{.ExecutionCode}}
+			return nil
+		}){{else}}{{.ExecutionCode}}
+		return nil{{end}}
 	}
 	{{end}}
 
 	{{range .Commands}}
 	// Command: {{.Name}}
 	{{.FunctionName}} := func(cmd *cobra.Command, args []string) {
-		if dryRun {
+		{{if .Params}}params, paramErr := commandParams(cmd, args, []commandParam{
+			{{range .Params}}{Name: {{printf "%q" .Name}}, Type: {{printf "%q" .Type}}, Default: {{printf "%q" .Default}}, Required: {{.Required}}},
+			{{end}}
+		})
+		if paramErr != nil {
+			fmt.Fprintf(os.Stderr, "Command '{{.Name}}': %v\n", paramErr)
+			os.Exit(1)
+		}
+		ctx := ctx.WithParams(params)
+		{{end}}if dryRun {
 			// Execute in plan mode using embedded execution plan
 			{{if .ExecutionPlan}}
-			if noColor {
+			if accessible {
+				fmt.Print({{.ExecutionPlanPlain}})
+			} else if noColor {
 				fmt.Print({{.ExecutionPlanNoColor}})
 			} else {
 				fmt.Print({{.ExecutionPlan}})
@@ -876,18 +2006,39 @@ func main() {
 			return
 		}
 		
-		// Normal execution - call the execution function
-		if err := execute{{.FunctionName | title}}(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "Command '{{.Name}}' failed: %v\n", err)
+		// Normal execution - call the execution function, then its triggers
+		{{if $.Hooks}}err := runWithHooks({{printf "%q" .Name}}, 0, "", func() error { return execute{{.FunctionName | title}}(ctx) }){{else}}err := execute{{.FunctionName | title}}(ctx){{end}}
+		{{if .OnFailureCode}}if err != nil {
+			// A failed recovery is reported, but the command's own failure is what exits
+			if triggerErr := func() error {
+				{{.OnFailureCode}}
+				return nil
+			}(); triggerErr != nil {
+				fmt.Fprintln(os.Stderr, fmt.Sprintf(msg("trigger.failed"), {{printf "%q" .Name}}, maskSecrets(triggerErr.Error())))
+			}
+		}
+		{{end}}{{if .OnSuccessCode}}if err == nil {
+			err = func() error {
+				{{.OnSuccessCode}}
+				return nil
+			}()
+		}
+		{{end}}if err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Sprintf(msg("command.failed"), {{printf "%q" .Name}}, maskSecrets(err.Error())))
 			os.Exit(1)
 		}
 	}
 
 	{{.CommandName}} := &cobra.Command{
-		Use:   "{{.Name}}",
-		Run:   {{.FunctionName}},
-	}
-	rootCmd.AddCommand({{.CommandName}})
+		Use:   {{printf "%q" .Use}},
+		{{if .Description}}Short: {{printf "%q" .Description}},
+		Long:  {{printf "%q" .Doc}},
+		{{end}}{{if .Aliases}}Aliases: []string{ {{range .Aliases}}{{printf "%q" .}}, {{end}}},
+		{{end}}Run:   {{.FunctionName}},
+	}
+	{{$command := .CommandName}}{{range .Params}}{{if eq .Type "boolean"}}{{$command}}.Flags().Bool({{printf "%q" .Name}}, {{if .Default}}{{.Default}}{{else}}false{{end}}, "boolean parameter{{if .Required}} (required){{end}}")
+	{{else}}{{$command}}.Flags().String({{printf "%q" .Name}}, {{printf "%q" .Default}}, "{{.Type}} parameter{{if .Required}} (required){{end}}")
+	{{end}}{{end}}rootCmd.AddCommand({{.CommandName}})
 	{{end}}
 
 	{{range .ProcessGroups}}
@@ -896,7 +2047,9 @@ func main() {
 		if dryRun {
 			// Execute in plan mode using embedded execution plan
 			{{if .WatchExecutionPlan}}
-			if noColor {
+			if accessible {
+				fmt.Print({{.WatchExecutionPlanPlain}})
+			} else if noColor {
 				fmt.Print({{.WatchExecutionPlanNoColor}})
 			} else {
 				fmt.Print({{.WatchExecutionPlan}})
@@ -907,22 +2060,87 @@ func main() {
 		
 		// Process management with PID tracking and log files
 		processName := "{{.Identifier}}"
-		pidFile := filepath.Join(os.TempDir(), processName+".pid")
-		logFile := filepath.Join(os.TempDir(), processName+".log")
+		logFile := filepath.Join(processDir(), processName+".log")
+		watch := func() error {
+			{{.WatchExecutionCode}}
+			return nil
+		}
+
+		// Run in the foreground when the devcmd daemon started this process; it records the
+		// PID and collects the output
+		if os.Getenv("DEVCMD_SUPERVISED") == processName {
+			// The daemon signals the whole process group to stop; rather than exit at once,
+			// wait for the watch command to shut down within its stop timeout
+			signal.Notify(make(chan os.Signal, 1), syscall.SIGTERM)
+			ctx.Env["DEVCMD_PROCESS"] = processName
+			ctx.Env["DEVCMD_PROCESS_DIR"] = processDir()
+			if err := watch(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 		
-		// Check if process is already running
-		if pidBytes, err := os.ReadFile(pidFile); err == nil {
-			if pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes))); err == nil {
+		// Leave a healthy instance running rather than start a second one that fights it for
+		// ports, unless --force restarts it
+		if pid, health := processHealth(processName); health != "dead" {
+			switch {
+			case forceRestart:
+				fmt.Printf("Restarting %s process (PID: %d)\n", processName, pid)
 				if process, err := os.FindProcess(pid); err == nil {
-					// Send signal 0 to check if process exists
-					if err := process.Signal(syscall.Signal(0)); err == nil {
-						fmt.Printf("Process %s is already running (PID: %d)\n", processName, pid)
-						return
+					if err := stopProcess(processName, process); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to stop process %d: %v\n", pid, err)
+						os.Exit(1)
 					}
 				}
+			case health == "unresponsive":
+				fmt.Fprintf(os.Stderr, "Process %s is already running (PID: %d) but doesn't accept connections on its ports; use --force to restart it\n", processName, pid)
+				os.Exit(1)
+			default:
+				fmt.Printf("Process %s is already running (PID: %d); use --force to restart it\n", processName, pid)
+				return
+			}
+		}
+		// Remove the PID file left by an instance that exited, and record the project for
+		// devcmd ps, and the process's stop order and timeout for devcmd ps --stop and the
+		// devcmd daemon
+		if err := removeProcessFiles(processDir(), processName+".pid"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to update process directory: %v\n", err)
+			return
+		}
+		_ = writeProcessFile(processDir(), "project", processProject+"\n")
+		_ = writeProcessFile(processDir(), processName+".service", {{printf "%q" .ServiceFile}})
+
+		// Hand the process to the devcmd daemon when one is running, to be restarted as
+		// processRestart says and outlive this terminal
+		if executable, err := os.Executable(); err == nil {
+			dir, _ := os.Getwd()
+			response, running, err := callDaemon(daemonRequest{
+				Op:        "start",
+				Namespace: processNamespace,
+				Project:   processProject,
+				Name:      processName,
+				Command:   append([]string{executable}, os.Args[1:]...),
+				Dir:       dir,
+				Env:       os.Environ(),
+				Restart:   processRestart,
+			})
+			if running {
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to start %s under the devcmd daemon: %v\n", processName, err)
+					return
+				}
+				fmt.Printf("Started %s process under the devcmd daemon (PID: %d)\n", processName, response.PID)
+				fmt.Printf("Logs: %s\n", response.LogFile)
+				return
 			}
 		}
 		
+		// Name the process for decorators like @freeport, starting with a fresh ports file
+		_ = removeProcessFiles(processDir(), processName+".ports")
+		ctx.Env["DEVCMD_PROCESS"] = processName
+		ctx.Env["DEVCMD_PROCESS_DIR"] = processDir()
+
 		// Create log file
 		logFileHandle, err := os.Create(logFile)
 		if err != nil {
@@ -951,16 +2169,19 @@ func main() {
 			}()
 			
 			// Execute the full command with decorators
-			{{.WatchExecutionCode}}
+			if err := watch(); err != nil {
+				fmt.Fprintf(logFileHandle, "Error: %v\n", err)
+			}
 		}()
 		
 		// Use current process PID since we're running as goroutines
 		// Note: This is a simplified process management approach for decorator support
 		pid := os.Getpid()
-		if err := os.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		if err := writeProcessFile(processDir(), processName+".pid", strconv.Itoa(pid)); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to write PID file: %v\n", err)
 			return
 		}
+		_ = recordProcessEvent(processDir(), processName, "started", pid)
 		
 		// Restore stdout/stderr immediately for the main process
 		os.Stdout = oldStdout
@@ -972,8 +2193,10 @@ func main() {
 
 	{{.CommandName}} := &cobra.Command{
 		Use:   "{{.Identifier}}",
-		Short: "Manage {{.Identifier}} process",
-		{{if .WatchExecutionCode}}Run:   {{.FunctionName}}Run, // Default action is to run{{end}}
+		Short: {{if .Description}}{{printf "%q" .Description}}{{else}}"Manage {{.Identifier}} process"{{end}},
+		{{if .Doc}}Long:  {{printf "%q" .Doc}},
+		{{end}}		{{if .Aliases}}Aliases: []string{ {{range .Aliases}}{{printf "%q" .}}, {{end}}},
+		{{end}}		{{if .WatchExecutionCode}}Run:   {{.FunctionName}}Run, // Default action is to run{{end}}
 	}
 
 	// Run subcommand (explicit)
@@ -982,6 +2205,8 @@ func main() {
 		Short: "Start {{.Identifier}} process (explicit)",
 		Run:   {{.FunctionName}}Run,
 	}
+	{{.CommandName}}.Flags().BoolVar(&forceRestart, "force", false, "Restart the process if it is already running")
+	{{.FunctionName}}RunCmd.Flags().BoolVar(&forceRestart, "force", false, "Restart the process if it is already running")
 	{{.CommandName}}.AddCommand({{.FunctionName}}RunCmd)
 
 	// Stop subcommand
@@ -989,7 +2214,9 @@ func main() {
 		if dryRun {
 			// Execute in plan mode using embedded execution plan
 			{{if .StopExecutionPlan}}
-			if noColor {
+			if accessible {
+				fmt.Print({{.StopExecutionPlanPlain}})
+			} else if noColor {
 				fmt.Print({{.StopExecutionPlanNoColor}})
 			} else {
 				fmt.Print({{.StopExecutionPlan}})
@@ -1000,7 +2227,7 @@ func main() {
 		
 		// Process management with PID tracking
 		processName := "{{.Identifier}}"
-		pidFile := filepath.Join(os.TempDir(), processName+".pid")
+		pidFile := filepath.Join(processDir(), processName+".pid")
 		
 		// Read PID from file
 		pidBytes, err := os.ReadFile(pidFile)
@@ -1031,23 +2258,17 @@ func main() {
 		}{{else}}{{.StopExecutionCode}}{{end}}
 		
 		// Also terminate the original process
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			// Try SIGKILL if SIGTERM fails
-			process.Signal(syscall.SIGKILL)
-		}
+		stopProcess(processName, process)
 		{{else}}
 		// Default stop: kill the process
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			// Try SIGKILL if SIGTERM fails
-			if err := process.Signal(syscall.SIGKILL); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to kill process %d: %v\n", pid, err)
-				return
-			}
+		if err := stopProcess(processName, process); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to kill process %d: %v\n", pid, err)
+			return
 		}
 		{{end}}
 		
-		// Clean up PID file
-		if err := os.Remove(pidFile); err != nil {
+		// Clean up PID file (the devcmd daemon removes those of processes it supervises)
+		if err := removeProcessFiles(processDir(), processName+".pid", processName+".ports"); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to remove PID file: %v\n", err)
 		}
 		
@@ -1067,14 +2288,14 @@ func main() {
 			// Execute in plan mode - status commands use simple default plan
 			fmt.Printf("=== Execution Plan ===\n")
 			fmt.Printf("Process: {{.Identifier}} (status)\n")
-			fmt.Printf("├── Check PID file and process status\n")
+			printPlanStep(1, "Check PID file and process status")
 			return
 		}
 		
 		// Process management status checking
 		processName := "{{.Identifier}}"
-		pidFile := filepath.Join(os.TempDir(), processName+".pid")
-		logFile := filepath.Join(os.TempDir(), processName+".log")
+		pidFile := filepath.Join(processDir(), processName+".pid")
+		logFile := filepath.Join(processDir(), processName+".log")
 		
 		// Check if PID file exists
 		pidBytes, err := os.ReadFile(pidFile)
@@ -1090,17 +2311,10 @@ func main() {
 		}
 		
 		// Check if process is actually running
-		process, err := os.FindProcess(pid)
-		if err != nil {
-			fmt.Printf("Process %s (PID: %d) not found\n", processName, pid)
-			return
-		}
-		
-		// Send signal 0 to check if process exists without affecting it
-		if err := process.Signal(syscall.Signal(0)); err != nil {
+		if !processAlive(pid) {
 			fmt.Printf("Process %s (PID: %d) is not running\n", processName, pid)
 			// Clean up stale PID file
-			os.Remove(pidFile)
+			_ = removeProcessFiles(processDir(), processName+".pid")
 			return
 		}
 		
@@ -1121,13 +2335,13 @@ func main() {
 			// Execute in plan mode - logs commands use simple default plan
 			fmt.Printf("=== Execution Plan ===\n")
 			fmt.Printf("Process: {{.Identifier}} (logs)\n")
-			fmt.Printf("├── Read and display log file\n")
+			printPlanStep(1, "Read and display log file")
 			return
 		}
 		
 		// Process management log reading
 		processName := "{{.Identifier}}"
-		logFile := filepath.Join(os.TempDir(), processName+".log")
+		logFile := filepath.Join(processDir(), processName+".log")
 		
 		// Check if log file exists
 		if _, err := os.Stat(logFile); err != nil {
@@ -1164,7 +2378,54 @@ func main() {
 
 	rootCmd.AddCommand({{.CommandName}})
 	{{end}}
+{{if .StopAll}}
+	// Stop every background process, each before the processes it depends on
+	var stopAllProcesses bool
+	stopAllProcessesCmd := &cobra.Command{
+		Use:   "stop --all",
+		Short: "Stop all background processes in dependency order",
+		Long:  "Stop the background processes of every watch command, each before the processes it depends on in the services settings, giving each its stop timeout to exit after SIGTERM. Custom stop commands aren't run.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !stopAllProcesses {
+				fmt.Fprintln(os.Stderr, "Use --all to stop every process, or '<process> stop' to stop one")
+				os.Exit(1)
+			}
+			if dryRun {
+				fmt.Printf("=== Execution Plan ===\n")
+				for i, name := range processStopOrder {
+					printPlanStep(i+1, fmt.Sprintf("Stop %s (timeout %s)", name, processStopTimeouts[name]))
+				}
+				return
+			}
 
+			failed := false
+			for _, name := range processStopOrder {
+				pid, health := processHealth(name)
+				if health == "dead" {
+					_ = removeProcessFiles(processDir(), name+".pid")
+					continue
+				}
+				process, err := os.FindProcess(pid)
+				if err == nil {
+					err = stopProcess(name, process)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to stop %s process (PID: %d): %v\n", name, pid, err)
+					failed = true
+					continue
+				}
+				_ = removeProcessFiles(processDir(), name+".pid", name+".ports")
+				fmt.Printf("Stopped %s process (PID: %d)\n", name, pid)
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	stopAllProcessesCmd.Flags().BoolVar(&stopAllProcesses, "all", false, "Stop every background process")
+	rootCmd.AddCommand(stopAllProcessesCmd)
+{{end}}
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -1177,20 +2438,52 @@ type CLITemplateData struct {
 	StandardImports   []string
 	ThirdPartyImports []string
 	Variables         []VariableData
+	Profiles          []ProfileData // Env profiles selected with --profile
+	ProfileNames      string        // Names of the profiles, for help and errors
 	Commands          []CommandData
 	ProcessGroups     []ProcessGroupData
 	TrackedEnvVars    map[string]string // Environment variables for ExecutionContext
+	Abbreviations     bool              // Enable unambiguous prefix matching for commands
+	DefaultEnv        map[string]string // Environment defaults applied when unset in the caller's environment
+	SourceFile        string            // Commands file path for CI annotations
+	SourceHash        string            // SHA-256 of the commands file, empty to skip drift detection
+	LocalSourceFile   string            // Local override file included in SourceHash when present
+	Regenerate        bool              // Rebuild with devcmd when the commands file has drifted
+	DefineArgs        []string          // --define flags the CLI was built with, for rebuilds
+	StrictShell       bool              // Run shell steps with StrictShellPrefix by default
+	StrictShellPrefix string            // execution.StrictShellPrefix, for the generated exec
+	Shell             string            // Shell of the config block, empty for defaultShell
+	Messages          string            // Go literal of the message catalog of every locale
+	ProjectConfig     string            // Go literal of the project's defaults settings, by config name
+	UsageTemplate     string            // UsageTemplate, for help in the locale
+	ProcessNamespace  string            // Namespace of the project's processes in the process registry
+	ProjectDir        string            // Project directory recorded in the namespace
+	ProcessSourceName string            // Name of the commands file, which marks the project directory
+	ProcessRestart    string            // Restart policy for watch commands the devcmd daemon supervises
+	ProcessStopOrder  []string          // Watch commands in the order stop --all stops them
+	StopAll           bool              // Generate the stop --all command
+	Identifiers       map[string]string // Go identifier of each command and watch command, by name
+	Hooks             map[string]string // Shell hooks of the project settings, by event name
 }
 
 type VariableData struct {
-	Name  string
-	Value string
-	Used  bool
+	Name     string
+	Value    string
+	Used     bool
+	Assigned bool // Updated by decorators like @set, so declared with var instead of const
+	Profiled bool // Set by an env profile, so declared with var when used
+}
+
+// ProfileData is an env profile and the values it gives the variables the CLI declares
+type ProfileData struct {
+	Name   string
+	Values []VariableData
 }
 
 type CommandData struct {
 	Name                 string
-	Description          string
+	Description          string // First line of the command's doc comment, for help listings
+	Doc                  string // The command's doc comment, for its --help
 	Dependencies         []string
 	FunctionName         string
 	CommandName          string
@@ -1198,10 +2491,19 @@ type CommandData struct {
 	ExecutionCode        string // Alias for Content
 	ExecutionPlan        string // Embedded execution plan for dry-run mode (with colors)
 	ExecutionPlanNoColor string // Embedded execution plan for dry-run mode (no colors)
+	ExecutionPlanPlain   string // Embedded execution plan for dry-run mode (accessible output)
+	Aliases              []string
+	Use                  string        // Usage line naming the parameters that may be given as arguments
+	Params               []paramData   // Parameters read from flags and arguments
+	OnSuccessCode        string        // Generated steps of the command's "on success" triggers
+	OnFailureCode        string        // Generated steps of the command's "on failure" triggers
+	DefaultTimeout       time.Duration // The config block's defaultTimeout bounding the command, zero if none
 }
 
 type ProcessGroupData struct {
 	Identifier                string
+	Description               string // First line of the watch command's doc comment, or the stop command's
+	Doc                       string // The doc comment Description starts
 	FunctionName              string
 	CommandName               string
 	RunFunctionName           string
@@ -1212,25 +2514,34 @@ type ProcessGroupData struct {
 	WatchExecutionPlanNoColor string // Embedded execution plan for watch command dry-run (no colors)
 	StopExecutionPlan         string // Embedded execution plan for stop command dry-run (with colors)
 	StopExecutionPlanNoColor  string // Embedded execution plan for stop command dry-run (no colors)
+	WatchExecutionPlanPlain   string // Embedded execution plan for watch command dry-run (accessible output)
+	StopExecutionPlanPlain    string // Embedded execution plan for stop command dry-run (accessible output)
 	WatchCommandString        string // Raw shell command for process management
 	StopCommandString         string // Raw shell command for stop process management
+	Aliases                   []string
+	ServiceFile               string        // Contents of the process's service file in the registry
+	StopTimeout               time.Duration // Grace period between SIGTERM and SIGKILL
 }
 
-// generateCodeWithTemplate uses a template-based approach instead of fragile WriteString calls
-func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName string) (*GenerationResult, error) {
+// generateCodeWithTemplate uses a template-based approach instead of fragile WriteString calls.
+// identifiers are the Go identifiers of the commands, from commandIdentifiers.
+func (e *Engine) generateCodeWithTemplate(analysis *Analysis, moduleName string, identifiers map[string]string) (*GenerationResult, error) {
+	program := analysis.Program
+
 	// Create generator context with decorator lookups
 	ctx := e.CreateGeneratorContext(context.Background(), program)
+	ctx.(*execution.GeneratorExecutionContext).SetCommandIdentifiers(identifiers)
 
 	// Initialize variables in the context first (critical for @var decorator)
 	if err := ctx.InitializeVariables(); err != nil {
 		return nil, fmt.Errorf("failed to initialize variables: %w", err)
 	}
 
-	// Analyze commands to separate regular commands from process management early
-	commandGroups := e.analyzeCommands(program.Commands)
+	commandGroups := analysis.Groups
 
 	// Initialize the result
 	result := &GenerationResult{
+		FileName:          "main.go",
 		Code:              strings.Builder{},
 		GoMod:             strings.Builder{},
 		StandardImports:   make(map[string]bool),
@@ -1238,128 +2549,173 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 		GoModules:         make(map[string]string),
 	}
 
-	// Add basic imports needed for generated CLI
-	result.AddStandardImport("fmt")
-	result.AddStandardImport("os") // Always needed for os.Stdout, os.Stderr, os.Stdin, os.Getwd, os.Exit
-	result.AddStandardImport("os/exec")
-
-	// Add strings import if ActionDecorator templates that use strings are used
-	if e.programUsesStringsInActionDecorators(program) {
-		result.AddStandardImport("strings") // Needed for ActionDecorator templates with string operations
-	}
-
-	// Add process management imports if we have process groups
-	if len(commandGroups.ProcessGroups) > 0 {
-		result.AddStandardImport("strings") // Needed for string operations in process management
-		result.AddStandardImport("path/filepath")
-		result.AddStandardImport("strconv")
-		result.AddStandardImport("syscall")
-		// io/ioutil and time are not used in current template implementation
-	}
-
-	// Collect imports from all decorators used in the program
-	if err := e.collectDecoratorImports(program, result); err != nil {
+	// Import and require only what the features the program uses need
+	features, err := e.Features(program)
+	if err != nil {
 		return nil, fmt.Errorf("failed to collect decorator imports: %w", err)
 	}
-
-	// Add cobra for CLI generation (always needed for generated CLIs)
-	result.AddThirdPartyImport("github.com/spf13/cobra")
-
-	// Validate @cmd decorator references before code generation
-	if err := e.validateCommandReferences(program); err != nil {
-		return nil, err
+	helpers := append([]generatedHelper(nil), generatedHelpers...)
+	for _, feature := range features {
+		helpers = append(helpers, feature.helpers...)
+		for _, pkg := range feature.Imports {
+			if isStandardPackage(pkg) {
+				result.AddStandardImport(pkg)
+			} else {
+				result.AddThirdPartyImport(pkg)
+			}
+		}
+		for module, version := range feature.Modules {
+			result.AddGoModule(module, version)
+		}
 	}
 
-	// Convert import maps to slices for template
-	var standardImports []string
-	for imp := range result.StandardImports {
-		standardImports = append(standardImports, imp)
-	}
-	var thirdPartyImports []string
-	for imp := range result.ThirdPartyImports {
-		thirdPartyImports = append(thirdPartyImports, imp)
-	}
+	stopOrder := analysis.StopOrder
 
 	// Prepare template data
 	templateData := CLITemplateData{
-		StandardImports:   standardImports,
-		ThirdPartyImports: thirdPartyImports,
 		Variables:         []VariableData{},
 		Commands:          []CommandData{},
 		ProcessGroups:     []ProcessGroupData{},
 		TrackedEnvVars:    ctx.GetTrackedEnvironmentVariableReferences(),
-	}
-
-	// Track which variables are used across all commands
-	usedVariables := make(map[string]bool)
-	for _, cmd := range program.Commands {
-		e.trackVariableUsageInBody(&cmd.Body, usedVariables)
-	}
-
-	// Add variables to template data, only including used ones
-	for _, variable := range program.Variables {
-		// Resolve variable value (reimplemented from removed engine method)
-		value, err := e.resolveVariableValueSimple(variable.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve variable %s: %w", variable.Name, err)
-		}
+		Abbreviations:     e.cliOptions.Abbreviations,
+		DefaultEnv:        e.cliOptions.DefaultEnv,
+		SourceFile:        e.sourceFile,
+		SourceHash:        e.sourceHash,
+		LocalSourceFile:   parser.LocalFileName(e.sourceFile),
+		Regenerate:        e.cliOptions.Regenerate,
+		DefineArgs:        DefineArgs(e.cliOptions.Defines),
+		StrictShell:       e.cliOptions.StrictShell,
+		StrictShellPrefix: execution.StrictShellPrefix,
+		Shell:             program.Shell(),
+		Messages:          messagesLiteral(),
+		ProjectConfig:     fmt.Sprintf("%#v", e.cliOptions.Defaults),
+		UsageTemplate:     UsageTemplate,
+		ProcessNamespace:  e.ProcessNamespace(),
+		ProjectDir:        e.projectDir(),
+		ProcessSourceName: e.sourceName(),
+		ProcessRestart:    e.cliOptions.Restart,
+		ProcessStopOrder:  stopOrder,
+		StopAll:           len(stopOrder) > 0 && !hasCommand(commandGroups, "stop"),
+		Identifiers:       identifiers,
+		Hooks:             e.cliOptions.Hooks,
+	}
+	if templateData.ProcessRestart == "" {
+		templateData.ProcessRestart = daemon.DefaultRestart
+	}
+
+	aliases := analysis.Aliases
+
+	// Add variables to template data; those only @set defines start empty
+	for i, variable := range analysis.Variables {
 		templateData.Variables = append(templateData.Variables, VariableData{
-			Name:  variable.Name,
-			Value: fmt.Sprintf("%q", value), // Quote the string value
-			Used:  usedVariables[variable.Name],
+			Name:     variable.Name,
+			Value:    fmt.Sprintf("%q", variable.Value), // Quote the string value
+			Used:     variable.Used,
+			Assigned: variable.Assigned,
+			Profiled: variable.Profiled,
 		})
+		if i >= len(program.Variables) {
+			ctx.SetVariable(variable.Name, "")
+		}
 	}
 
-	// Sort commands by dependencies to ensure proper declaration order
-	sortedCommands, err := e.sortCommandsByDependencies(commandGroups.RegularCommands)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sort commands by dependencies: %w", err)
+	// Profiles assign only the variables the CLI declares, as unused ones are left out
+	declared := make(map[string]bool)
+	for _, variable := range analysis.Variables {
+		declared[variable.Name] = variable.Used || variable.Assigned
 	}
-
-	// Add regular commands to template data using template-based approach
-	for _, cmd := range sortedCommands {
-		// Collect imports from all command content
-		if err := e.collectDecoratorImportsFromContent(cmd.Body.Content, result); err != nil {
-			return nil, fmt.Errorf("failed to collect imports for command %s: %w", cmd.Name, err)
+	var profileNames []string
+	for _, profile := range program.EnvProfiles {
+		data := ProfileData{Name: profile.Name}
+		for _, variable := range profile.Variables {
+			if !declared[variable.Name] {
+				continue
+			}
+			value, err := e.resolveVariableValueSimple(variable.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve variable %s of profile %s: %w", variable.Name, profile.Name, err)
+			}
+			data.Values = append(data.Values, VariableData{Name: variable.Name, Value: fmt.Sprintf("%q", value)})
 		}
+		templateData.Profiles = append(templateData.Profiles, data)
+		profileNames = append(profileNames, profile.Name)
+	}
+	templateData.ProfileNames = strings.Join(profileNames, ", ")
 
+	// Add regular commands to template data using template-based approach
+	for _, cmd := range analysis.Commands {
 		// Generate command body using template system - this works for both generator and plan modes
-		// The BuildCommandContent method delegates to decorators which handle their own template generation
-		templateResult, err := ctx.BuildCommandContent(cmd.Body.Content)
+		// The BuildCommandContent method delegates to decorators which handle their own template generation.
+		// Each top-level step runs through ciStep so CI systems can group its output.
+		var commandBody strings.Builder
+		needsCode, err := generateNeeds(program, cmd, identifiers)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build command content for %s: %w", cmd.Name, err)
+			return nil, err
 		}
-
-		commandBody, err := ctx.ExecuteTemplate(templateResult)
+		commandBody.WriteString(needsCode)
+		preflightCode, err := e.generatePreflight(ctx, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate pre-flight checks for %s: %w", cmd.Name, err)
+		}
+		commandBody.WriteString(preflightCode)
+		steps, err := e.generateSteps(ctx, cmd.Name, cmd.Body.Content)
 		if err != nil {
-			return nil, fmt.Errorf("failed to execute command template for %s: %w", cmd.Name, err)
+			return nil, err
+		}
+		commandBody.WriteString(steps)
+
+		// Triggers run after the command in its cobra handler, so @cmd doesn't run them
+		var onSuccess, onFailure strings.Builder
+		for _, trigger := range program.Triggers {
+			if trigger.Command != cmd.Name {
+				continue
+			}
+			steps, err := e.generateSteps(ctx, trigger.Name(), trigger.Body.Content)
+			if err != nil {
+				return nil, err
+			}
+			if trigger.Event == ast.TriggerOnFailure {
+				onFailure.WriteString(steps)
+			} else {
+				onSuccess.WriteString(steps)
+			}
 		}
 
 		// Add the command to template data
 		templateData.Commands = append(templateData.Commands, CommandData{
-			Name:         cmd.Name,
-			Description:  "",         // Commands don't have descriptions in AST
-			Dependencies: []string{}, // TODO: Extract dependencies when needed
-			Content:      commandBody,
+			Name:           cmd.Name,
+			Description:    cmd.Comments.Summary(),
+			Doc:            cmd.Comments.Doc(),
+			Dependencies:   []string{}, // TODO: Extract dependencies when needed
+			Content:        commandBody.String(),
+			Use:            commandUse(cmd),
+			Params:         commandParamData(cmd),
+			OnSuccessCode:  onSuccess.String(),
+			OnFailureCode:  onFailure.String(),
+			DefaultTimeout: commandTimeout(program, cmd),
 		})
 
 		// Generate execution plan for this command (both colored and no-color versions)
 		// This is for DryRun mode - still works with template system
 		executionPlan := ""
 		executionPlanNoColor := ""
+		executionPlanPlain := ""
 		if plan, err := e.ExecuteCommandPlan(cmd); err == nil {
 			executionPlan = fmt.Sprintf("%q", plan.String())
 			executionPlanNoColor = fmt.Sprintf("%q", plan.StringNoColor())
+			executionPlanPlain = fmt.Sprintf("%q", plan.StringPlain())
 		}
 
 		// Update command data with plan information
 		for i := range templateData.Commands {
 			if templateData.Commands[i].Name == cmd.Name {
-				templateData.Commands[i].FunctionName = toCamelCase(cmd.Name)
-				templateData.Commands[i].CommandName = toCamelCase(cmd.Name) + "Cmd"
+				templateData.Commands[i].FunctionName = identifiers[cmd.Name]
+				templateData.Commands[i].CommandName = identifiers[cmd.Name] + "Cmd"
 				templateData.Commands[i].ExecutionCode = templateData.Commands[i].Content
 				templateData.Commands[i].ExecutionPlan = executionPlan
 				templateData.Commands[i].ExecutionPlanNoColor = executionPlanNoColor
+				templateData.Commands[i].ExecutionPlanPlain = executionPlanPlain
+				templateData.Commands[i].Aliases = aliases[cmd.Name]
 				break
 			}
 		}
@@ -1370,10 +2726,22 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 		identifier := group.Identifier
 		processData := ProcessGroupData{
 			Identifier:      identifier,
-			FunctionName:    toCamelCase(identifier),
-			CommandName:     toCamelCase(identifier) + "Cmd",
-			RunFunctionName: toCamelCase(identifier) + "Run",
+			FunctionName:    identifiers[identifier],
+			CommandName:     identifiers[identifier] + "Cmd",
+			RunFunctionName: identifiers[identifier] + "Run",
 			HasCustomStop:   group.StopCommand != nil,
+			Aliases:         aliases[identifier],
+			ServiceFile:     e.cliOptions.Services[identifier].String(),
+			StopTimeout:     e.cliOptions.Services[identifier].StopTimeout,
+		}
+		if processData.StopTimeout == 0 {
+			processData.StopTimeout = processes.DefaultStopTimeout
+		}
+		for _, command := range []*ast.CommandDecl{group.WatchCommand, group.StopCommand} {
+			if command != nil && processData.Doc == "" {
+				processData.Description = command.Comments.Summary()
+				processData.Doc = command.Comments.Doc()
+			}
 		}
 
 		// Generate watch command execution code and extract raw shell commands
@@ -1391,15 +2759,15 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 					// Use template helper function to generate shell code
 					funcs := ctx.GetTemplateFunctions()
 					if buildCommand, ok := funcs["buildCommand"]; ok {
-						if buildFunc, ok := buildCommand.(func(interface{}) string); ok {
-							code := buildFunc(c)
-							watchCode.WriteString(code)
+						if buildFunc, ok := buildCommand.(func(interface{}) (string, error)); ok {
+							code, err := buildFunc(c)
+							if err != nil {
+								return nil, fmt.Errorf("failed to generate watch command %s: %w", identifier, err)
+							}
+							watchCode.WriteString(code + "\n")
 						}
 					}
 				case *ast.BlockDecorator:
-					if err := e.addDecoratorImports("block", c.Name, result); err != nil {
-						return nil, fmt.Errorf("failed to collect imports for @%s in watch command %s: %w", c.Name, identifier, err)
-					}
 					blockDecorator, err := decorators.GetBlock(c.Name)
 					if err != nil {
 						return nil, fmt.Errorf("block decorator @%s not found for watch command %s: %w", c.Name, identifier, err)
@@ -1420,7 +2788,7 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 						return nil, fmt.Errorf("@%s decorator execution failed in watch command %s: %w", c.Name, identifier, decoratorResult.Error)
 					}
 					if code, ok := decoratorResult.Data.(string); ok {
-						watchCode.WriteString(code)
+						watchCode.WriteString(code + "\n")
 					}
 				default:
 					return nil, fmt.Errorf("unsupported command content type %T in watch command %s", content, identifier)
@@ -1448,15 +2816,15 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 					// Use template helper function to generate shell code
 					funcs := ctx.GetTemplateFunctions()
 					if buildCommand, ok := funcs["buildCommand"]; ok {
-						if buildFunc, ok := buildCommand.(func(interface{}) string); ok {
-							code := buildFunc(c)
+						if buildFunc, ok := buildCommand.(func(interface{}) (string, error)); ok {
+							code, err := buildFunc(c)
+							if err != nil {
+								return nil, fmt.Errorf("failed to generate stop command %s: %w", identifier, err)
+							}
 							stopCode.WriteString(code)
 						}
 					}
 				case *ast.BlockDecorator:
-					if err := e.addDecoratorImports("block", c.Name, result); err != nil {
-						return nil, fmt.Errorf("failed to collect imports for @%s in stop command %s: %w", c.Name, identifier, err)
-					}
 					blockDecorator, err := decorators.GetBlock(c.Name)
 					if err != nil {
 						return nil, fmt.Errorf("block decorator @%s not found for stop command %s: %w", c.Name, identifier, err)
@@ -1489,48 +2857,53 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 		// Generate execution plans for watch and stop commands (both colored and no-color versions)
 		watchExecutionPlan := ""
 		watchExecutionPlanNoColor := ""
+		watchExecutionPlanPlain := ""
 		if group.WatchCommand != nil {
 			if plan, err := e.ExecuteCommandPlan(group.WatchCommand); err == nil {
 				watchExecutionPlan = fmt.Sprintf("%q", plan.String())
 				watchExecutionPlanNoColor = fmt.Sprintf("%q", plan.StringNoColor())
+				watchExecutionPlanPlain = fmt.Sprintf("%q", plan.StringPlain())
 			}
 		}
 
 		stopExecutionPlan := ""
 		stopExecutionPlanNoColor := ""
+		stopExecutionPlanPlain := ""
 		if group.StopCommand != nil {
 			if plan, err := e.ExecuteCommandPlan(group.StopCommand); err == nil {
 				stopExecutionPlan = fmt.Sprintf("%q", plan.String())
 				stopExecutionPlanNoColor = fmt.Sprintf("%q", plan.StringNoColor())
+				stopExecutionPlanPlain = fmt.Sprintf("%q", plan.StringPlain())
 			}
 		} else {
 			// Default stop plan (both versions are the same since no colors)
 			defaultPlan := fmt.Sprintf("└─ pkill -f '%s'", identifier)
 			stopExecutionPlan = fmt.Sprintf("%q", defaultPlan)
 			stopExecutionPlanNoColor = fmt.Sprintf("%q", defaultPlan)
+			stopExecutionPlanPlain = fmt.Sprintf("%q", fmt.Sprintf("Step 1: run pkill -f '%s'\n", identifier))
 		}
 
 		processData.WatchExecutionPlan = watchExecutionPlan
 		processData.WatchExecutionPlanNoColor = watchExecutionPlanNoColor
 		processData.StopExecutionPlan = stopExecutionPlan
 		processData.StopExecutionPlanNoColor = stopExecutionPlanNoColor
+		processData.WatchExecutionPlanPlain = watchExecutionPlanPlain
+		processData.StopExecutionPlanPlain = stopExecutionPlanPlain
 		processData.WatchCommandString = watchCommandString
 		processData.StopCommandString = stopCommandString
 
 		templateData.ProcessGroups = append(templateData.ProcessGroups, processData)
 	}
 
-	// Update template data with collected imports (convert maps to slices)
-	standardImports = []string{}
+	// Add the collected imports to the template data, in a stable order
 	for imp := range result.StandardImports {
-		standardImports = append(standardImports, imp)
+		templateData.StandardImports = append(templateData.StandardImports, imp)
 	}
-	thirdPartyImports = []string{}
 	for imp := range result.ThirdPartyImports {
-		thirdPartyImports = append(thirdPartyImports, imp)
+		templateData.ThirdPartyImports = append(templateData.ThirdPartyImports, imp)
 	}
-	templateData.StandardImports = standardImports
-	templateData.ThirdPartyImports = thirdPartyImports
+	sort.Strings(templateData.StandardImports)
+	sort.Strings(templateData.ThirdPartyImports)
 
 	// Execute the template with basic functions
 	tmpl, err := template.New("mainCLI").Funcs(template.FuncMap{
@@ -1548,8 +2921,11 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 		return nil, fmt.Errorf("failed to execute main CLI template: %w", err)
 	}
 
-	// Set the generated code
-	result.Code.WriteString(codeBuilder.String())
+	// Set the generated code, with the helpers it calls
+	result.Code.WriteString(appendHelpers(codeBuilder.String(), helpers))
+	if len(templateData.ProcessGroups) > 0 {
+		result.Files = map[string]string{processWindowsFile: processWindowsSource}
+	}
 
 	// Generate go.mod
 	if err := e.generateGoMod(result, moduleName); err != nil {
@@ -1559,54 +2935,6 @@ func (e *Engine) generateCodeWithTemplate(program *ast.Program, moduleName strin
 	return result, nil
 }
 
-// programUsesStringsInActionDecorators checks if any command uses ActionDecorator templates that require strings import
-func (e *Engine) programUsesStringsInActionDecorators(program *ast.Program) bool {
-	for _, cmd := range program.Commands {
-		for _, content := range cmd.Body.Content {
-			if e.commandUsesStringsInActionDecorators(content) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-// commandUsesStringsInActionDecorators checks if command content uses ActionDecorator templates that need strings import
-func (e *Engine) commandUsesStringsInActionDecorators(content ast.CommandContent) bool {
-	switch c := content.(type) {
-	case *ast.ShellContent:
-		// Only shell operators and non-@cmd ActionDecorators need strings import
-		for _, part := range c.Parts {
-			if actionDec, ok := part.(*ast.ActionDecorator); ok {
-				// @cmd decorators don't need strings import - they just call other functions
-				if actionDec.Name != "cmd" {
-					return true
-				}
-			}
-		}
-		// Shell operators in regular shell commands don't need strings import
-		// They're handled entirely by the shell via exec(ctx, "command")
-		// Only ActionDecorator templates might need strings processing
-	case *ast.BlockDecorator:
-		// Block decorators might contain ActionDecorators that need strings
-		for _, subContent := range c.Content {
-			if e.commandUsesStringsInActionDecorators(subContent) {
-				return true
-			}
-		}
-	case *ast.PatternDecorator:
-		// Pattern decorators might contain ActionDecorators that need strings
-		for _, pattern := range c.Patterns {
-			for _, command := range pattern.Commands {
-				if e.commandUsesStringsInActionDecorators(command) {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
 // resolveVariableValueSimple converts an AST expression to its string value (reimplemented from removed context method)
 func (e *Engine) resolveVariableValueSimple(expr ast.Expression) (string, error) {
 	switch v := expr.(type) {
@@ -1631,50 +2959,10 @@ func (e *Engine) resolveVariableValueSimple(expr ast.Expression) (string, error)
 func (e *Engine) setupDecoratorLookups(ctx execution.GeneratorContext) {
 	// Cast to the concrete type to access the setup methods
 	if generatorCtx, ok := ctx.(*execution.GeneratorExecutionContext); ok {
-		// Set up block decorator lookup function using the decorator registry
-		generatorCtx.SetBlockDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetBlock(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
-
-		// Set up pattern decorator lookup function using the decorator registry
-		generatorCtx.SetPatternDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetPattern(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
-
-		// Set up value decorator lookup function using the decorator registry
-		generatorCtx.SetValueDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetValue(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
-
-		// Set up block decorator lookup function using the decorator registry
-		generatorCtx.SetBlockDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetBlock(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
-
-		// Set up action decorator lookup function using the decorator registry
-		generatorCtx.SetActionDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetAction(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
+		generatorCtx.SetBlockDecoratorLookup(decorators.Lookup(decorators.BlockType))
+		generatorCtx.SetPatternDecoratorLookup(decorators.Lookup(decorators.PatternType))
+		generatorCtx.SetValueDecoratorLookup(decorators.Lookup(decorators.ValueType))
+		generatorCtx.SetActionDecoratorLookup(decorators.Lookup(decorators.ActionType))
 	}
 }
 
@@ -1683,29 +2971,19 @@ func (e *Engine) setupDecoratorLookups(ctx execution.GeneratorContext) {
 func (e *Engine) setupInterpreterDecoratorLookups(ctx execution.InterpreterContext) {
 	// Cast to the concrete type to access the setup methods
 	if interpreterCtx, ok := ctx.(*execution.InterpreterExecutionContext); ok {
-		// Set up action decorator lookup function using the decorator registry
-		interpreterCtx.SetActionDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, exists := decorators.GetActionDecorator(name)
-			return decorator, exists
-		})
-
-		// Set up value decorator lookup function using the decorator registry
-		interpreterCtx.SetValueDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetValue(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
+		interpreterCtx.SetActionDecoratorLookup(decorators.Lookup(decorators.ActionType))
+		interpreterCtx.SetValueDecoratorLookup(decorators.Lookup(decorators.ValueType))
+		interpreterCtx.SetBlockDecoratorLookup(decorators.Lookup(decorators.BlockType))
+	}
+}
 
-		// Set up block decorator lookup function using the decorator registry
-		interpreterCtx.SetBlockDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetBlock(name)
-			if err != nil {
-				return nil, false
-			}
-			return decorator, true
-		})
+// setupPlanDecoratorLookups configures decorator registry access for PlanContext
+// This lets action decorators like @set describe themselves in shell steps
+func (e *Engine) setupPlanDecoratorLookups(ctx execution.PlanContext) {
+	if planCtx, ok := ctx.(*execution.PlanExecutionContext); ok {
+		planCtx.SetActionDecoratorLookup(decorators.Lookup(decorators.ActionType))
+		// Value decorators describe whether their values are quoted, as @var's are
+		planCtx.SetValueDecoratorLookup(decorators.Lookup(decorators.ValueType))
 	}
 }
 
@@ -1720,7 +2998,84 @@ func (e *Engine) CreateGeneratorContext(ctx context.Context, program *ast.Progra
 func (e *Engine) CreateInterpreterContext(ctx context.Context, program *ast.Program) execution.InterpreterContext {
 	interpreterCtx := execution.NewInterpreterContext(ctx, program)
 	e.setupInterpreterDecoratorLookups(interpreterCtx)
-	return interpreterCtx
+	if e.cliOptions.StrictShell {
+		interpreterCtx = interpreterCtx.WithStrictShell(true)
+	}
+	if e.shell != nil {
+		interpreterCtx = interpreterCtx.WithShell(e.shell)
+	} else if shell := program.Shell(); shell != "" {
+		interpreterCtx = interpreterCtx.WithShell([]string{shell})
+	}
+	return interpreterCtx.WithNeedsRunner(e.runNeeds)
+}
+
+// resolveAliases validates configured command aliases and groups them by target command
+func (e *Engine) resolveAliases(program *ast.Program) (map[string][]string, error) {
+	commands := make(map[string]bool)
+	for _, cmd := range program.Commands {
+		commands[cmd.Name] = true
+	}
+
+	names := make([]string, 0, len(e.cliOptions.Aliases))
+	for alias := range e.cliOptions.Aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+
+	aliases := make(map[string][]string)
+	for _, alias := range names {
+		target := e.cliOptions.Aliases[alias]
+		if commands[alias] {
+			return nil, fmt.Errorf("alias '%s' conflicts with a command of the same name", alias)
+		}
+		if !commands[target] {
+			return nil, fmt.Errorf("alias '%s' refers to undefined command '%s'", alias, target)
+		}
+		aliases[target] = append(aliases[target], alias)
+	}
+	return aliases, nil
+}
+
+// generateSteps generates the top-level steps of a command or trigger body, each run through
+// ciStep so CI systems can group its output
+func (e *Engine) generateSteps(ctx execution.GeneratorContext, name string, content []ast.CommandContent) (string, error) {
+	var steps strings.Builder
+	for i, item := range content {
+		templateResult, err := ctx.BuildCommandContent([]ast.CommandContent{item})
+		if err != nil {
+			return "", fmt.Errorf("failed to build command content for %s: %w", name, err)
+		}
+
+		stepBody, err := ctx.ExecuteTemplate(templateResult)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute command template for %s: %w", name, err)
+		}
+
+		pos := item.Position()
+		fmt.Fprintf(&steps, "if err := ciStep(%q, %d, %q, %d, %d, func() error {\n%s\nreturn nil\n}); err != nil {\nreturn err\n}\n",
+			name, i+1, describeStep(item), pos.Line, pos.Column, stepBody)
+	}
+	return steps.String(), nil
+}
+
+// validateTriggers checks that generated CLIs can run the program's triggers: those of watch
+// and stop commands would have to run inside process management, which they don't. Change
+// triggers run under the devcmd daemon, not in generated CLIs.
+func (e *Engine) validateTriggers(program *ast.Program) error {
+	types := make(map[string]ast.CommandType, len(program.Commands))
+	for _, cmd := range program.Commands {
+		types[cmd.Name] = cmd.Type
+	}
+	for _, trigger := range program.Triggers {
+		if trigger.Event == ast.TriggerOnChange {
+			continue
+		}
+		if commandType := types[trigger.Command]; commandType != ast.Command {
+			return fmt.Errorf("%s (line %d): triggers of %s commands are not supported in generated CLIs",
+				trigger.Name(), trigger.Pos.Line, commandType)
+		}
+	}
+	return nil
 }
 
 // validateCommandReferences validates that all @cmd decorator references point to existing commands
@@ -1737,6 +3092,16 @@ func (e *Engine) validateCommandReferences(program *ast.Program) error {
 			return err
 		}
 	}
+	for _, trigger := range program.Triggers {
+		if trigger.Event != ast.TriggerOnChange && !availableCommands[trigger.Command] {
+			return fmt.Errorf("%s references non-existent command '%s'", trigger.Name(), trigger.Command)
+		}
+		for _, content := range trigger.Body.Content {
+			if err := e.validateCmdReferencesInContent(content, availableCommands); err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
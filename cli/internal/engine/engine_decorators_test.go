@@ -1,10 +1,14 @@
 package engine
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
 )
 
 // TestDecorators tests that decorators work properly with the new execution system
@@ -121,3 +125,85 @@ func TestDecoratorImports(t *testing.T) {
 		t.Error("Expected 'context' import for timeout decorator")
 	}
 }
+
+// TestWhenVariableForms tests that @when matches the same variable whether it is named by a
+// string or given as the variable itself, in the interpreter and in generated CLIs
+func TestWhenVariableForms(t *testing.T) {
+	input := `var ENV = "prod"
+quoted: @when("ENV") {
+    prod: echo "quoted prod"
+    default: echo "quoted other"
+}
+identifier: @when(ENV) {
+    prod: echo "identifier prod"
+    default: echo "identifier other"
+}`
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	engine := New(program)
+	for i := range program.Commands {
+		if _, err := engine.ExecuteCommand(&program.Commands[i]); err != nil {
+			t.Errorf("interpreter: %s failed: %v", program.Commands[i].Name, err)
+		}
+	}
+
+	binary := buildTestCLI(t, input)
+	for _, name := range []string{"quoted", "identifier"} {
+		output, err := exec.Command(binary, name).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s failed: %v\n%s", name, err, output)
+		}
+		if want := name + " prod"; !strings.Contains(string(output), want) {
+			t.Errorf("%s output = %q, want %q", name, output, want)
+		}
+	}
+}
+
+// TestGenerateDecoratorError tests that a decorator that can't generate its code fails
+// generation with its own error
+func TestGenerateDecoratorError(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`deploy: @when("ENV") {
+    prod: echo prod
+}`))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	program.Commands[0].Body.Content[0].(*ast.PatternDecorator).Args = nil
+
+	_, err = New(program).GenerateCode(program)
+	if err == nil || !strings.Contains(err.Error(), "@when") {
+		t.Errorf("GenerateCode error = %v, want @when's parameter error", err)
+	}
+}
+
+// TestGeneratedCliSandbox tests that generated CLIs run each @sandbox block through the
+// shared sandbox helper
+func TestGeneratedCliSandbox(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	binary := buildTestCLI(t, `inside: @sandbox(write = "`+dir+`") { echo sandboxed > `+out+` }
+outside: @sandbox(write = "`+dir+`") { touch sandbox_probe }`)
+
+	output, err := exec.Command(binary, "inside").CombinedOutput()
+	if strings.Contains(string(output), "no sandbox available") {
+		t.Skipf("no sandbox available: %s", output)
+	}
+	if err != nil {
+		t.Fatalf("inside failed: %v\n%s", err, output)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "sandboxed\n" {
+		t.Errorf("out = %q, %v, want the write inside the sandbox", data, err)
+	}
+
+	// The package directory is outside the writable path and the temporary directory
+	t.Cleanup(func() { os.Remove("sandbox_probe") })
+	if output, err := exec.Command(binary, "outside").CombinedOutput(); err == nil {
+		t.Errorf("outside should fail to write outside the sandbox:\n%s", output)
+	}
+	if _, err := os.Stat("sandbox_probe"); !os.IsNotExist(err) {
+		t.Error("sandbox_probe was written outside the sandbox")
+	}
+}
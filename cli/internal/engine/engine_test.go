@@ -10,7 +10,9 @@ import (
 	"testing"
 
 	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
 
 	// Import builtins to register decorators
 	_ "github.com/aledsdavies/devcmd/cli/internal/builtins"
@@ -18,7 +20,16 @@ import (
 
 // TestMain ensures cleanup happens after all tests
 func TestMain(m *testing.M) {
+	// Keep the namespaces watch commands register out of the user's process registry
+	registry, err := os.MkdirTemp("", "devcmd-engine-test-registry-")
+	if err == nil {
+		_ = os.Setenv(processes.RootEnvVar, registry)
+	}
+
 	code := m.Run()
+	if registry != "" {
+		_ = os.RemoveAll(registry)
+	}
 
 	// Final cleanup - ignore errors since we're cleaning up
 	_ = os.Remove("generated.go")
@@ -30,6 +41,24 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// TestBuiltinDecoratorRegistry checks that the parser, engine and generator agree on every
+// builtin decorator, as devcmd doctor does
+func TestBuiltinDecoratorRegistry(t *testing.T) {
+	for _, err := range decorators.Verify() {
+		t.Error(err)
+	}
+	for _, name := range []string{"var", "cmd", "parallel", "when"} {
+		info, ok := decorators.Describe(name)
+		if !ok {
+			t.Errorf("@%s isn't registered", name)
+			continue
+		}
+		if decorator, ok := decorators.Lookup(info.Type)(name); !ok || decorator.(decorators.Decorator).Name() != name {
+			t.Errorf("Lookup(%s)(%s) = %v, %v", info.Type, name, decorator, ok)
+		}
+	}
+}
+
 // TestCommandResultGeneration tests comprehensive CommandResult scenarios
 func TestCommandResultGeneration(t *testing.T) {
 	testCases := []struct {
@@ -506,6 +535,123 @@ build: make all`
 	}
 }
 
+// TestEngine_SetDeclaresVariables tests that @set targets are generated as assignable variables
+func TestEngine_SetDeclaresVariables(t *testing.T) {
+	input := `var VERSION = "1.2.0"
+var PORT = "8080"
+release: {
+    @set(TAG = "v@var(VERSION)")
+    @set(VERSION = "2.0.0")
+    git tag @var(TAG) --port @var(PORT)
+}
+snapshot: @set(TAG = "snapshot")`
+
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %v", err)
+	}
+
+	generatedCode := result.String()
+	for _, element := range []string{
+		"var VERSION = \"1.2.0\"",
+		"const PORT = \"8080\"",
+		"var TAG = \"\"",
+		"TAG = \"v\" + VERSION",
+		"VERSION = \"2.0.0\"",
+		"TAG = \"snapshot\"",
+	} {
+		if !strings.Contains(generatedCode, element) {
+			t.Errorf("Generated code should contain %q.\nGenerated code:\n%s", element, generatedCode)
+		}
+	}
+	if strings.Contains(generatedCode, "const VERSION") {
+		t.Errorf("VERSION is assigned by @set and must not be a constant")
+	}
+}
+
+func TestEngine_StringTransformsDeclareVariables(t *testing.T) {
+	input := `var BRANCH = "feature/login"
+var IMAGE = "app"
+tag: docker tag @var(IMAGE) @var(IMAGE):@replace(BRANCH, "/", "-")
+shout: echo @upper("@var(IMAGE)")`
+
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %v", err)
+	}
+
+	generatedCode := result.String()
+	for _, element := range []string{
+		"const BRANCH = \"feature/login\"",
+		"strings.ReplaceAll(BRANCH, \"/\", \"-\")",
+		"strings.ToUpper(IMAGE)",
+	} {
+		if !strings.Contains(generatedCode, element) {
+			t.Errorf("Generated code should contain %q.\nGenerated code:\n%s", element, generatedCode)
+		}
+	}
+}
+
+func TestEngine_QuotesShellValues(t *testing.T) {
+	input := `var MSG = "it's here"
+var FLAGS = "-v -race"
+greet: echo "@var(MSG)" 100% @var(MSG)
+test: go test @var(FLAGS, raw = true) ./...`
+
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %v", err)
+	}
+
+	generatedCode := result.String()
+	for _, element := range []string{
+		"func quoteShellValue(value string, quote byte) string",
+		"const FLAGS = \"-v -race\"",
+		`fmt.Sprintf("echo \"%s\" 100%% %s", quoteShellValue(MSG, '"'), quoteShellValue(MSG, 0))`,
+		`fmt.Sprintf("go test %s ./...", FLAGS)`,
+	} {
+		if !strings.Contains(generatedCode, element) {
+			t.Errorf("Generated code should contain %q.\nGenerated code:\n%s", element, generatedCode)
+		}
+	}
+}
+
+func TestEngine_QuotesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	out, pwned := filepath.Join(dir, "out"), filepath.Join(dir, "pwned")
+	token := `x"; touch ` + pwned + `; echo "$HOME`
+	t.Setenv("TOKEN", token)
+
+	program, err := parser.Parse(strings.NewReader(`show: echo "token=@secret(TOKEN, source = "env")" > ` + out))
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if _, err := New(program).ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Error("the secret's value ran as shell syntax")
+	}
+	if data, err := os.ReadFile(out); err != nil || strings.TrimSpace(string(data)) != "token="+token {
+		t.Errorf("output = %q, %v, want the secret unchanged", data, err)
+	}
+}
+
 // TestEngine_CommandExecution tests command execution structure
 func TestEngine_CommandExecution(t *testing.T) {
 	input := `greeting: echo "Hello World"`
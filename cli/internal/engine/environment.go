@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// Sources of the values in a command's environment
+const (
+	SourceEnvironment = "environment"  // Inherited from the caller
+	SourceSettings    = "settings"     // A default from devcmd.settings, such as a @requires image
+	SourceDefault     = "@env default" // The default of an @env reference
+	SourceVariable    = "var"          // A devcmd variable
+	SourceUnset       = "unset"        // Read with @env but not set anywhere
+)
+
+// EnvProfile is a profile selected with --profile: its environment values from the `profiles`
+// settings section override the caller's environment, and the values an env block of the
+// same name gives variables override their defaults
+type EnvProfile struct {
+	Name string
+	Env  map[string]string
+}
+
+// EnvVar is a variable in the resolved environment of a command and where its value comes from
+type EnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Required is set for a variable that a required @env reads and that has no usable value,
+	// which fails the command. Value is then the reference's default, as a suggestion.
+	Required bool `json:"required,omitempty"`
+}
+
+// Environment is the environment a command runs with: the devcmd variables it reads,
+// and the environment variables it reads with @env or that devcmd sets for it
+type Environment struct {
+	Command   string   `json:"command"`
+	Profile   string   `json:"profile,omitempty"`
+	Variables []EnvVar `json:"variables"`
+	Env       []EnvVar `json:"env"`
+}
+
+// envReference is an @env reference and its parameters
+type envReference struct {
+	Command    string // The command whose body has the reference
+	Key        string
+	Default    string
+	AllowEmpty bool
+	Required   bool
+}
+
+// ResolveEnvironment resolves the environment a command would run with in the given caller
+// environment (as from os.Environ), with the profile's values applied over it and settings
+// defaults filling what is unset. The @env references of commands it runs with @cmd are
+// included. With inherited, every variable of the caller's environment is listed too.
+func (e *Engine) ResolveEnvironment(command *ast.CommandDecl, environ []string, profile EnvProfile, inherited bool) (*Environment, error) {
+	env := make(map[string]EnvVar)
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok && name != "" {
+			env[name] = EnvVar{Name: name, Value: value, Source: SourceEnvironment}
+		}
+	}
+	for name, value := range profile.Env {
+		env[name] = EnvVar{Name: name, Value: value, Source: "profile " + profile.Name}
+	}
+	for name, value := range e.cliOptions.DefaultEnv {
+		if _, set := env[name]; !set {
+			env[name] = EnvVar{Name: name, Value: value, Source: SourceSettings}
+		}
+	}
+
+	// Only what the command reads, or what devcmd and the profile set, unless asked for everything
+	listed := make(map[string]EnvVar)
+	for name, variable := range env {
+		if inherited || variable.Source != SourceEnvironment {
+			listed[name] = variable
+		}
+	}
+
+	commands := e.commandClosure(command)
+	for _, ref := range envReferences(commands) {
+		variable, set := env[ref.Key]
+		switch {
+		case set && (ref.AllowEmpty || variable.Value != ""):
+		case ref.Required:
+			variable = EnvVar{Name: ref.Key, Value: ref.Default, Source: SourceUnset, Required: true}
+		case ref.Default != "":
+			variable = EnvVar{Name: ref.Key, Value: ref.Default, Source: SourceDefault}
+		case !set:
+			variable = EnvVar{Name: ref.Key, Source: SourceUnset}
+		}
+		// Where some references have a default, show the value they fall back to, unless
+		// another requires the variable
+		if previous, seen := listed[ref.Key]; seen && (previous.Required || previous.Source == SourceDefault && variable.Source == SourceUnset && !variable.Required) {
+			continue
+		}
+		listed[ref.Key] = variable
+	}
+
+	program, _ := WithEnvProfile(e.program, profile.Name)
+	ctx := e.CreateInterpreterContext(context.Background(), program)
+	if err := ctx.InitializeVariables(); err != nil {
+		return nil, fmt.Errorf("failed to initialize variables: %w", err)
+	}
+	usedVars := make(map[string]bool)
+	for _, cmd := range commands {
+		e.trackVariableUsageInBody(&cmd.Body, usedVars)
+	}
+	result := &Environment{Command: command.Name, Profile: profile.Name, Variables: []EnvVar{}, Env: []EnvVar{}}
+	for name := range usedVars {
+		value, _ := ctx.GetVariable(name)
+		source := SourceVariable
+		if envProfile := e.program.EnvProfile(profile.Name); envProfile != nil && envProfile.Variable(name) != nil {
+			source = "profile " + profile.Name
+		}
+		result.Variables = append(result.Variables, EnvVar{Name: name, Value: value, Source: source})
+	}
+	for _, variable := range listed {
+		result.Env = append(result.Env, variable)
+	}
+	sortEnvVars(result.Variables)
+	sortEnvVars(result.Env)
+	return result, nil
+}
+
+// WithEnvProfile returns program with the variables that its env profile of the given name
+// sets taking the profile's values, and whether the program defines the profile. Variables
+// the profile doesn't set keep their defaults. The program isn't modified.
+func WithEnvProfile(program *ast.Program, name string) (*ast.Program, bool) {
+	envProfile := program.EnvProfile(name)
+	if envProfile == nil {
+		return program, false
+	}
+
+	result := *program
+	result.Variables = profileVariables(envProfile, program.Variables)
+	result.VarGroups = make([]ast.VarGroup, len(program.VarGroups))
+	for i, group := range program.VarGroups {
+		result.VarGroups[i] = group
+		result.VarGroups[i].Variables = profileVariables(envProfile, group.Variables)
+	}
+	return &result, true
+}
+
+// profileVariables returns a copy of variables with the values the profile sets
+func profileVariables(envProfile *ast.EnvProfileDecl, variables []ast.VariableDecl) []ast.VariableDecl {
+	result := append([]ast.VariableDecl{}, variables...)
+	for i := range result {
+		if variable := envProfile.Variable(result[i].Name); variable != nil {
+			result[i].Value = variable.Value
+		}
+	}
+	return result
+}
+
+// commandClosure returns the command followed by the commands it runs with @cmd, transitively
+func (e *Engine) commandClosure(command *ast.CommandDecl) []*ast.CommandDecl {
+	commands := []*ast.CommandDecl{command}
+	seen := map[string]bool{command.Name: true}
+	for i := 0; i < len(commands); i++ {
+		for _, dep := range e.findCommandDependencies(commands[i]) {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			for j := range e.program.Commands {
+				// Watch and stop commands share a name; @cmd runs the watch command
+				if dependency := &e.program.Commands[j]; dependency.Name == dep && dependency.Type != ast.StopCommand {
+					commands = append(commands, dependency)
+					break
+				}
+			}
+		}
+	}
+	return commands
+}
+
+// envReferences returns the @env references in the bodies of the commands
+func envReferences(commands []*ast.CommandDecl) []envReference {
+	var refs []envReference
+	for _, command := range commands {
+		ast.Walk(&command.Body, func(n ast.Node) bool {
+			decorator, ok := n.(*ast.ValueDecorator)
+			if !ok || decorator.Name != "env" {
+				return true
+			}
+			ref := envReference{
+				Command:    command.Name,
+				Key:        ast.GetStringParam(decorator.Args, "key", ""),
+				Default:    ast.GetStringParam(decorator.Args, "default", ""),
+				AllowEmpty: ast.GetBoolParam(decorator.Args, "allowEmpty", false),
+				Required:   ast.GetBoolParam(decorator.Args, "required", false),
+			}
+			if ref.Key == "" && len(decorator.Args) > 0 {
+				switch v := decorator.Args[0].Value.(type) {
+				case *ast.StringLiteral:
+					ref.Key = v.Value
+				case *ast.Identifier:
+					ref.Key = v.Name
+				}
+			}
+			if ref.Key != "" {
+				refs = append(refs, ref)
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+func sortEnvVars(vars []EnvVar) {
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+}
+
+// WriteText writes the environment as aligned NAME=value lines with the source of each value
+func (env *Environment) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(env.Variables) > 0 {
+		fmt.Fprintln(tw, "Variables:")
+		for _, variable := range env.Variables {
+			fmt.Fprintf(tw, "  %s=%s\t(%s)\n", variable.Name, variable.Value, variable.Source)
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw, "Environment:")
+	if len(env.Env) == 0 {
+		fmt.Fprintln(tw, "  (none)")
+	}
+	for _, variable := range env.Env {
+		if variable.Required {
+			fmt.Fprintf(tw, "  %s\t(%s, required)\n", variable.Name, variable.Source)
+			continue
+		}
+		if variable.Source == SourceUnset {
+			fmt.Fprintf(tw, "  %s\t(%s)\n", variable.Name, variable.Source)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s=%s\t(%s)\n", variable.Name, variable.Value, variable.Source)
+	}
+	return tw.Flush()
+}
+
+// EnvChange is a difference between two environments of a command. Before or After is
+// nil when the variable is only in one of them.
+type EnvChange struct {
+	Name   string  `json:"name"`
+	Before *EnvVar `json:"before,omitempty"`
+	After  *EnvVar `json:"after,omitempty"`
+}
+
+// DiffEnvironments returns the variables whose values differ between two environments,
+// in name order. Variables whose value is the same are unchanged even if the source differs.
+func DiffEnvironments(before, after *Environment) []EnvChange {
+	index := func(env *Environment) map[string]EnvVar {
+		vars := make(map[string]EnvVar)
+		for _, list := range [][]EnvVar{env.Variables, env.Env} {
+			for _, variable := range list {
+				if variable.Source != SourceUnset {
+					vars[variable.Name] = variable
+				}
+			}
+		}
+		return vars
+	}
+	a, b := index(before), index(after)
+
+	var changes []EnvChange
+	for name, old := range a {
+		old := old
+		if updated, ok := b[name]; !ok {
+			changes = append(changes, EnvChange{Name: name, Before: &old})
+		} else if updated.Value != old.Value {
+			updated := updated
+			changes = append(changes, EnvChange{Name: name, Before: &old, After: &updated})
+		}
+	}
+	for name, added := range b {
+		added := added
+		if _, ok := a[name]; !ok {
+			changes = append(changes, EnvChange{Name: name, After: &added})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// WriteEnvDiff writes changes as diff lines: + for added, - for removed and ~ for changed variables
+func WriteEnvDiff(w io.Writer, changes []EnvChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	describe := func(variable *EnvVar) string {
+		return fmt.Sprintf("%q (%s)", variable.Value, variable.Source)
+	}
+	for _, change := range changes {
+		switch {
+		case change.Before == nil:
+			fmt.Fprintf(tw, "+ %s\t%s\n", change.Name, describe(change.After))
+		case change.After == nil:
+			fmt.Fprintf(tw, "- %s\t%s\n", change.Name, describe(change.Before))
+		default:
+			fmt.Fprintf(tw, "~ %s\t%s → %s\n", change.Name, describe(change.Before), describe(change.After))
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+const environmentCommands = `var PORT = 8080
+var UNUSED = 1
+serve: echo "on @var(PORT) against @env(API_URL, default = "http://localhost:3000") as @env(USER) with @env(TOKEN)"
+deploy: {
+    @cmd(serve)
+    echo @env(REGION, default = "eu-west-1")
+}`
+
+// resolveEnvironment resolves the environment of a command in environmentCommands
+func resolveEnvironment(t *testing.T, name string, environ []string, profile EnvProfile, inherited bool) *Environment {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(environmentCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	eng.SetCLIOptions(CLIOptions{DefaultEnv: map[string]string{"DEVCMD_IMAGE_TERRAFORM": "hashicorp/terraform:1.9", "USER": "settings"}})
+	for i := range program.Commands {
+		if program.Commands[i].Name == name {
+			env, err := eng.ResolveEnvironment(&program.Commands[i], environ, profile, inherited)
+			if err != nil {
+				t.Fatalf("ResolveEnvironment failed: %v", err)
+			}
+			return env
+		}
+	}
+	t.Fatalf("no command %s", name)
+	return nil
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	environ := []string{"USER=alice", "REGION=", "HOME=/home/alice"}
+	env := resolveEnvironment(t, "deploy", environ, EnvProfile{}, false)
+
+	var buf bytes.Buffer
+	if err := env.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"PORT=8080",
+		"API_URL=http://localhost:3000", "(@env default)",
+		"USER=alice", "(environment)",
+		"REGION=eu-west-1", // Empty values fall back to the default
+		"DEVCMD_IMAGE_TERRAFORM=hashicorp/terraform:1.9", "(settings)",
+		"TOKEN", "(unset)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("environment of deploy should contain %q, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"UNUSED", "HOME"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("environment of deploy should not list %s, got:\n%s", unwanted, got)
+		}
+	}
+
+	if env := resolveEnvironment(t, "deploy", environ, EnvProfile{}, true); !strings.Contains(envString(env), "HOME=/home/alice") {
+		t.Errorf("the inherited environment should be listed, got %s", envString(env))
+	}
+}
+
+func TestResolveEnvironment_Required(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`deploy: echo @env(API_KEY, default = "dev-key", required = true) @env(API_KEY, default = "other")`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	env, err := New(program).ResolveEnvironment(&program.Commands[0], nil, EnvProfile{}, false)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment failed: %v", err)
+	}
+	// The default isn't used, but is what the palette suggests
+	if len(env.Env) != 1 || env.Env[0] != (EnvVar{Name: "API_KEY", Value: "dev-key", Source: SourceUnset, Required: true}) {
+		t.Errorf("env = %+v, want API_KEY unset and required", env.Env)
+	}
+	var buf bytes.Buffer
+	if err := env.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(unset, required)") {
+		t.Errorf("environment should show API_KEY as required, got:\n%s", buf.String())
+	}
+}
+
+func TestResolveEnvironment_Profile(t *testing.T) {
+	prod := EnvProfile{Name: "prod", Env: map[string]string{"API_URL": "https://api.example.com", "USER": "deploy"}}
+	env := resolveEnvironment(t, "serve", []string{"USER=alice"}, prod, false)
+
+	for _, variable := range env.Env {
+		switch variable.Name {
+		case "API_URL", "USER":
+			if variable.Value != prod.Env[variable.Name] || variable.Source != "profile prod" {
+				t.Errorf("profile should override %s, got %+v", variable.Name, variable)
+			}
+		case "REGION":
+			t.Errorf("serve doesn't read REGION")
+		}
+	}
+}
+
+func TestResolveEnvironment_EnvProfile(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`var PORT = 8080
+var HOST = "localhost"
+env production {
+    PORT = 443
+}
+serve: echo "@var(HOST):@var(PORT)"`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	env, err := New(program).ResolveEnvironment(&program.Commands[0], nil, EnvProfile{Name: "production"}, false)
+	if err != nil {
+		t.Fatalf("ResolveEnvironment failed: %v", err)
+	}
+
+	for _, want := range []EnvVar{
+		{Name: "HOST", Value: "localhost", Source: SourceVariable},
+		{Name: "PORT", Value: "443", Source: "profile production"},
+	} {
+		found := false
+		for _, variable := range env.Variables {
+			found = found || variable == want
+		}
+		if !found {
+			t.Errorf("variables should contain %+v, got %+v", want, env.Variables)
+		}
+	}
+	if program.Variables[0].Value.(*ast.NumberLiteral).Value != "8080" {
+		t.Errorf("resolving a profile should leave the program's defaults alone")
+	}
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	environ := []string{"USER=alice", "HOME=/home/alice"}
+	prod := EnvProfile{Name: "prod", Env: map[string]string{"API_URL": "https://api.example.com", "HOME": "/home/alice", "LOG_LEVEL": "warn"}}
+	before := resolveEnvironment(t, "deploy", environ, EnvProfile{}, true)
+	after := resolveEnvironment(t, "deploy", environ, prod, true)
+
+	changes := DiffEnvironments(before, after)
+	var names []string
+	for _, change := range changes {
+		names = append(names, change.Name)
+	}
+	// HOME has the same value in the profile, so it isn't a change
+	if strings.Join(names, " ") != "API_URL LOG_LEVEL" {
+		t.Fatalf("changes = %v, want API_URL and LOG_LEVEL", names)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEnvDiff(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`~ API_URL`,
+		`"http://localhost:3000" (@env default) → "https://api.example.com" (profile prod)`,
+		`+ LOG_LEVEL`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff should contain %q, got:\n%s", want, got)
+		}
+	}
+
+	if changes := DiffEnvironments(after, before); len(changes) != 2 || changes[1].After != nil {
+		t.Errorf("reversed diff should remove LOG_LEVEL, got %+v", changes)
+	}
+}
+
+func envString(env *Environment) string {
+	var buf bytes.Buffer
+	_ = env.WriteText(&buf)
+	return buf.String()
+}
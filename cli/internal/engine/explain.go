@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// defaultNotePattern matches the note parameter descriptions give their default in,
+// e.g. "(default: 1s)" or "(defaults to the working directory)"
+var defaultNotePattern = regexp.MustCompile(`\bdefaults?(?: to|:)\s*([^)]+)\)`)
+
+// Explanation is a human-readable breakdown of a command: what it's for, the decorators it
+// uses with their effective parameters, what it depends on and needs, and its plan
+type Explanation struct {
+	Command      string
+	Type         ast.CommandType
+	Description  string // The comment lines directly above the command
+	Decorators   []DecoratorUse
+	Dependencies []string // Commands it runs with @cmd
+	Tools        []string // Executables @requires checks for
+	Env          *Environment
+	Plan         *plan.ExecutionPlan
+}
+
+// DecoratorUse is a decorator used by a command, with a value for each of its parameters
+type DecoratorUse struct {
+	Name        string
+	Description string
+	Params      []ExplainedParam
+}
+
+// ExplainedParam is a decorator parameter as it applies: the value given, or its default
+type ExplainedParam struct {
+	Name    string
+	Value   string // Empty when neither given nor documented with a default
+	Default bool   // Value is the parameter's default rather than given
+}
+
+// Explain breaks down a command. source is the file the command was parsed from, which its
+// description is read from; environ is the caller's environment (as from os.Environ).
+func (e *Engine) Explain(command *ast.CommandDecl, source []byte, environ []string) (*Explanation, error) {
+	explanation := &Explanation{
+		Command:      command.Name,
+		Type:         command.Type,
+		Description:  CommandDescription(source, command.Pos.Line),
+		Dependencies: uniqueStrings(e.findCommandDependencies(command)),
+	}
+
+	var err error
+	ast.Walk(&command.Body, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		var name string
+		var args []ast.NamedParameter
+		var decorator decorators.Decorator
+		switch node := n.(type) {
+		case *ast.ValueDecorator:
+			name, args = node.Name, node.Args
+			decorator, _ = decorators.GetValueDecorator(node.Name)
+		case *ast.ActionDecorator:
+			name, args = node.Name, node.Args
+			decorator, _ = decorators.GetActionDecorator(node.Name)
+		case *ast.BlockDecorator:
+			name, args = node.Name, node.Args
+			decorator, _ = decorators.GetBlockDecorator(node.Name)
+		case *ast.PatternDecorator:
+			name, args = node.Name, node.Args
+			decorator, _ = decorators.GetPatternDecorator(node.Name)
+		default:
+			return true
+		}
+		if decorator == nil {
+			err = fmt.Errorf("unknown decorator @%s", name)
+			return false
+		}
+		var use DecoratorUse
+		if use, err = explainDecorator(decorator, args); err != nil {
+			return false
+		}
+		explanation.Decorators = append(explanation.Decorators, use)
+		if name == "requires" {
+			for _, param := range use.Params {
+				if param.Name == "tools" && !param.Default {
+					explanation.Tools = append(explanation.Tools, strings.FieldsFunc(param.Value, func(r rune) bool {
+						return r == ',' || r == ' ' || r == '\t' || r == '"'
+					})...)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	explanation.Tools = uniqueStrings(explanation.Tools)
+
+	if explanation.Env, err = e.ResolveEnvironment(command, environ, EnvProfile{}, false); err != nil {
+		return nil, err
+	}
+	if len(command.Body.Content) > 0 {
+		if explanation.Plan, err = e.ExecuteCommandPlan(command); err != nil {
+			return nil, err
+		}
+	}
+	return explanation, nil
+}
+
+// explainDecorator fills in the parameters of a decorator use that weren't given with the
+// defaults their descriptions document
+func explainDecorator(decorator decorators.Decorator, args []ast.NamedParameter) (DecoratorUse, error) {
+	use := DecoratorUse{Name: decorator.Name(), Description: decorator.Description()}
+	schema := decorator.ParameterSchema()
+	params, err := decorators.ResolvePositionalParameters(args, schema)
+	if err != nil {
+		return use, fmt.Errorf("@%s: %w", decorator.Name(), err)
+	}
+
+	described := make(map[string]bool)
+	for _, parameter := range schema {
+		described[parameter.Name] = true
+		explained := ExplainedParam{Name: parameter.Name}
+		if given := ast.FindParameter(params, parameter.Name); given != nil {
+			explained.Value = expressionText(given.Value)
+		} else if match := defaultNotePattern.FindStringSubmatch(parameter.Description); match != nil {
+			explained.Value = strings.TrimSpace(match[1])
+			explained.Default = true
+		} else {
+			explained.Default = true
+		}
+		use.Params = append(use.Params, explained)
+	}
+	// Decorators like @set take parameters their schema doesn't list
+	for _, param := range params {
+		if !described[param.Name] {
+			use.Params = append(use.Params, ExplainedParam{Name: param.Name, Value: expressionText(param.Value)})
+		}
+	}
+	return use, nil
+}
+
+// expressionText returns an expression as written, quoting strings
+func expressionText(expr ast.Expression) string {
+	if str, ok := expr.(*ast.StringLiteral); ok {
+		return strconv.Quote(str.Value)
+	}
+	return expr.String()
+}
+
+// CommandDescription returns the text of the # comment lines directly above the line a
+// command is declared on, which describe it
+func CommandDescription(source []byte, line int) string {
+	lines := strings.Split(string(source), "\n")
+	var comment []string
+	for i := line - 2; i >= 0 && i < len(lines); i-- {
+		text, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), "#")
+		if !ok {
+			break
+		}
+		comment = append([]string{strings.TrimSpace(text)}, comment...)
+	}
+	return strings.TrimSpace(strings.Join(comment, "\n"))
+}
+
+// WriteText writes the explanation as sections, leaving out empty ones
+func (x *Explanation) WriteText(w io.Writer, color bool) error {
+	var b strings.Builder
+	kind := "command"
+	if x.Type != ast.Command {
+		kind = x.Type.String() + " command"
+	}
+	fmt.Fprintf(&b, "%s (%s)\n", x.Command, kind)
+	if x.Description != "" {
+		for _, line := range strings.Split(x.Description, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	if len(x.Env.Variables) > 0 {
+		b.WriteString("\nVariables:\n")
+		for _, variable := range x.Env.Variables {
+			fmt.Fprintf(&b, "  %s = %s\n", variable.Name, variable.Value)
+		}
+	}
+
+	if len(x.Decorators) > 0 {
+		b.WriteString("\nDecorators:\n")
+		for _, use := range x.Decorators {
+			fmt.Fprintf(&b, "  @%s: %s\n", use.Name, use.Description)
+			for _, param := range use.Params {
+				switch {
+				case !param.Default:
+					fmt.Fprintf(&b, "    %s = %s\n", param.Name, param.Value)
+				case param.Value != "":
+					fmt.Fprintf(&b, "    %s = %s (default)\n", param.Name, param.Value)
+				default:
+					fmt.Fprintf(&b, "    %s (not set)\n", param.Name)
+				}
+			}
+		}
+	}
+
+	if len(x.Dependencies) > 0 {
+		b.WriteString("\nDependencies:\n")
+		for _, dependency := range x.Dependencies {
+			fmt.Fprintf(&b, "  %s\n", dependency)
+		}
+	}
+
+	if len(x.Tools) > 0 || len(x.Env.Env) > 0 {
+		b.WriteString("\nRequires:\n")
+		if len(x.Tools) > 0 {
+			fmt.Fprintf(&b, "  tools: %s\n", strings.Join(x.Tools, ", "))
+		}
+		for _, variable := range x.Env.Env {
+			if variable.Source == SourceUnset {
+				fmt.Fprintf(&b, "  %s (unset)\n", variable.Name)
+			} else {
+				fmt.Fprintf(&b, "  %s = %s (%s)\n", variable.Name, variable.Value, variable.Source)
+			}
+		}
+	}
+
+	if x.Plan != nil {
+		b.WriteString("\nPlan:\n")
+		b.WriteString(PlanText(x.Plan, color))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// PlanText renders a plan as a tree, in color when color is set, or as plain numbered lines
+// in accessible output
+func PlanText(p *plan.ExecutionPlan, color bool) string {
+	switch {
+	case logging.Accessible():
+		return p.StringPlain()
+	case color:
+		return p.String()
+	default:
+		return p.StringNoColor()
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+const explainCommands = `var CLUSTER = "staging"
+
+build: go build ./...
+
+# Deploy the app to the cluster.
+# Needs kubectl and helm.
+deploy: @requires(tools = "kubectl, helm") {
+    @cmd(build)
+    @retry(attempts = 3) {
+        kubectl --context @var(CLUSTER) apply -f @env(MANIFEST, default = "k8s/")
+    }
+}`
+
+func TestExplain(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(explainCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	deploy := &program.Commands[1]
+	explanation, err := New(program).Explain(deploy, []byte(explainCommands), nil)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	if explanation.Description != "Deploy the app to the cluster.\nNeeds kubectl and helm." {
+		t.Errorf("Description = %q", explanation.Description)
+	}
+	if strings.Join(explanation.Dependencies, ",") != "build" {
+		t.Errorf("Dependencies = %v, want build", explanation.Dependencies)
+	}
+	if strings.Join(explanation.Tools, ",") != "kubectl,helm" {
+		t.Errorf("Tools = %v, want kubectl and helm", explanation.Tools)
+	}
+
+	var buf bytes.Buffer
+	if err := explanation.WriteText(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"deploy (command)",
+		"CLUSTER = staging",
+		`tools = "kubectl, helm"`,
+		"fallback = true (default)",
+		"diskFree (not set)",
+		"attempts = 3",
+		"delay = 1s (default)",
+		"MANIFEST = k8s/ (@env default)",
+		"Plan:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("explanation should contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// build has no comment above it, only a blank line
+	explanation, err = New(program).Explain(&program.Commands[0], []byte(explainCommands), nil)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if explanation.Description != "" || len(explanation.Decorators) != 0 {
+		t.Errorf("build should have no description or decorators: %+v", explanation)
+	}
+}
+
+func TestExplain_AccessiblePlan(t *testing.T) {
+	defer logging.SetAccessible(logging.Accessible())
+	logging.SetAccessible(true)
+	program, err := parser.Parse(strings.NewReader(explainCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	explanation, err := New(program).Explain(&program.Commands[1], []byte(explainCommands), nil)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := explanation.WriteText(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	// The plan is numbered lines, without box drawing or arrows
+	for _, want := range []string{
+		"Plan for deploy:\nStep 1: Require kubectl, helm",
+		"Step 1.1: @cmd(build)\n",
+		"Step 1.2: @retry, 3 attempts, 1s delay\n",
+		`Step 1.2.1: run kubectl --context staging apply -f @env(MANIFEST) is "k8s/"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("accessible explanation lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.ContainsAny(buf.String(), "└├│→\x1b") {
+		t.Errorf("accessible explanation has box drawing, arrows or colors:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,390 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+)
+
+const (
+	// cobraPackage is the CLI framework every generated CLI is built on
+	cobraPackage = "github.com/spf13/cobra"
+	// cobraVersion is the version of cobra generated go.mod files require
+	cobraVersion = "v1.9.1"
+	// devcmdModule is required by generated CLIs whose decorators import devcmd packages
+	devcmdModule = "github.com/aledsdavies/devcmd"
+)
+
+// Packages every generated CLI imports: os for its streams, working directory and exit code,
+// time for the timestamps of CI log sections in ciStep, encoding/json for logf's records,
+// runtime for exec to pick the platform's shell, and bytes for reading the user config
+var coreImports = []string{"bytes", "encoding/json", "fmt", "os", "os/exec", "runtime", "time"}
+
+// Packages generated CLIs with watch commands import to manage their processes, including
+// encoding/json and net for requests to the devcmd daemon
+var processImports = []string{"strings", "path/filepath", "strconv", "syscall", "encoding/json", "net", "time", "os/signal", "crypto/sha256", "encoding/hex"}
+
+// driftImports returns the packages checkSourceDrift needs to find the project's commands
+// file and hash it
+func (e *Engine) driftImports() []string {
+	if e.sourceHash == "" {
+		return nil
+	}
+	return []string{"crypto/sha256", "encoding/hex", "path/filepath", "strings"}
+}
+
+// Feature is something a generated CLI compiles in: the core every CLI has, a subsystem such
+// as process management, or a decorator. The generated code, its imports and its go.mod hold
+// only the features the program uses.
+type Feature struct {
+	Name     string            // e.g. "@http" or "process management"
+	Commands []string          // Commands that use it, none for features every CLI has
+	Imports  []string          // Packages the generated code imports for it
+	Modules  map[string]string // Modules go.mod requires for it, with their versions
+
+	helpers []generatedHelper // Helpers its generated code calls, for decorators
+}
+
+// Features lists the features the CLI generated for program compiles in, in the order they
+// are first used, from the decorators and commands the program actually has
+func (e *Engine) Features(program *ast.Program) ([]Feature, error) {
+	features := []Feature{{
+		Name:    "CLI core",
+		Imports: append(append([]string(nil), coreImports...), cobraPackage),
+		Modules: map[string]string{cobraPackage: cobraVersion},
+	}}
+	if imports := e.driftImports(); len(imports) > 0 {
+		features = append(features, Feature{Name: "drift check", Imports: imports})
+	}
+
+	// Watch and stop commands share a name
+	var processes, preflights, parameterized, needing []string
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		if len(command.Params) > 0 {
+			parameterized = append(parameterized, command.Name)
+		}
+		if len(command.Needs) > 0 {
+			needing = append(needing, command.Name)
+		}
+		switch {
+		case command.Type == ast.WatchCommand || command.Type == ast.StopCommand:
+			if !containsName(processes, command.Name) {
+				processes = append(processes, command.Name)
+			}
+		case len(e.requiredEnv(command)) > 0 || len(preflightSteps(command.Body.Content)) > 0:
+			preflights = append(preflights, command.Name)
+		}
+	}
+	if len(processes) > 0 {
+		features = append(features, Feature{Name: "process management", Commands: processes, Imports: processImports})
+	}
+	if len(preflights) > 0 {
+		// strings joins the failures generatePreflight collects
+		features = append(features, Feature{Name: "pre-flight checks", Commands: preflights, Imports: []string{"strings"}})
+	}
+	if len(parameterized) > 0 {
+		// strconv checks number and boolean values in commandParams
+		features = append(features, Feature{Name: "command parameters", Commands: parameterized, Imports: []string{"strconv"}})
+	}
+	if len(needing) > 0 {
+		// sync runs each needed command once in runNeed
+		features = append(features, Feature{Name: "needs", Commands: needing, Imports: []string{"sync"}})
+	}
+
+	// Decorators in the order they are first used, each with every command using it
+	index := make(map[string]int)
+	add := func(command, kind, name string) error {
+		key := "@" + name
+		i, seen := index[key]
+		if !seen {
+			feature, err := e.decoratorFeature(kind, name)
+			if err != nil {
+				return err
+			}
+			i = len(features)
+			index[key] = i
+			features = append(features, feature)
+		}
+		if commands := features[i].Commands; len(commands) == 0 || commands[len(commands)-1] != command {
+			features[i].Commands = append(commands, command)
+		}
+		return nil
+	}
+	// Commands whose pattern decorators have glob or regex branches
+	var globbing, regexing []string
+	var walk func(command string, content []ast.CommandContent) error
+	walk = func(command string, content []ast.CommandContent) error {
+		for _, item := range content {
+			switch c := item.(type) {
+			case *ast.ShellContent:
+				for _, part := range c.Parts {
+					var err error
+					switch d := part.(type) {
+					case *ast.ValueDecorator:
+						err = add(command, "value", d.Name)
+					case *ast.ActionDecorator:
+						err = add(command, "action", d.Name)
+					}
+					if err != nil {
+						return err
+					}
+				}
+			case *ast.BlockDecorator:
+				if err := add(command, "block", c.Name); err != nil {
+					return err
+				}
+				if err := walk(command, c.Content); err != nil {
+					return err
+				}
+			case *ast.PatternDecorator:
+				if err := add(command, "pattern", c.Name); err != nil {
+					return err
+				}
+				for _, branch := range c.Patterns {
+					if pattern, ok := branch.Pattern.(*ast.StringPattern); ok {
+						if pattern.IsRegex() && !containsName(regexing, command) {
+							regexing = append(regexing, command)
+						} else if !pattern.IsRegex() && !containsName(globbing, command) {
+							globbing = append(globbing, command)
+						}
+					}
+					if err := walk(command, branch.Commands); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, command := range program.Commands {
+		if err := walk(command.Name, command.Body.Content); err != nil {
+			return nil, err
+		}
+	}
+	// Trigger steps are generated into the CLI too, except those of on change triggers
+	for _, trigger := range program.Triggers {
+		if trigger.Event == ast.TriggerOnChange {
+			continue
+		}
+		if err := walk(trigger.Name(), trigger.Body.Content); err != nil {
+			return nil, err
+		}
+	}
+	if len(globbing) > 0 {
+		// path matches glob branches of @when
+		features = append(features, Feature{Name: "glob patterns", Commands: globbing, Imports: []string{"path"}})
+	}
+	if len(regexing) > 0 {
+		// regexp matches regex branches of @when
+		features = append(features, Feature{Name: "regex patterns", Commands: regexing, Imports: []string{"regexp"}})
+	}
+	return features, nil
+}
+
+// decoratorFeature returns the feature of a decorator with what its ImportRequirements
+// declare. Decorators importing devcmd packages require the devcmd module this devcmd is.
+func (e *Engine) decoratorFeature(kind, name string) (Feature, error) {
+	feature := Feature{Name: "@" + name}
+	var decorator decorators.Decorator
+	var err error
+	switch kind {
+	case "value":
+		decorator, err = decorators.GetValue(name)
+	case "action":
+		decorator, err = decorators.GetAction(name)
+	case "block":
+		decorator, err = decorators.GetBlock(name)
+	case "pattern":
+		decorator, err = decorators.GetPattern(name)
+	default:
+		err = fmt.Errorf("unknown decorator type: %s", kind)
+	}
+	if err != nil {
+		return feature, fmt.Errorf("decorator %s not found: %w", name, err)
+	}
+
+	if helperProvider, ok := decorator.(decorators.HelperProvider); ok {
+		helpers, err := helperProvider.GeneratedHelpers()
+		if err != nil {
+			return feature, fmt.Errorf("@%s helpers: %w", name, err)
+		}
+		names := make([]string, 0, len(helpers))
+		for name := range helpers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			feature.helpers = append(feature.helpers, generatedHelper{Name: name, Code: helpers[name]})
+		}
+	}
+
+	provider, ok := decorator.(interface {
+		ImportRequirements() decorators.ImportRequirement
+	})
+	if !ok {
+		return feature, nil
+	}
+	requirements := provider.ImportRequirements()
+	feature.Imports = append(append([]string(nil), requirements.StandardLibrary...), requirements.ThirdParty...)
+	modules := make(map[string]string)
+	for module, version := range requirements.GoModules {
+		modules[module] = version
+	}
+	for _, pkg := range requirements.ThirdParty {
+		if pkg == devcmdModule || strings.HasPrefix(pkg, devcmdModule+"/") {
+			modules[devcmdModule] = e.getDevcmdVersion()
+		}
+	}
+	if len(modules) > 0 {
+		feature.Modules = modules
+	}
+	return feature, nil
+}
+
+// isStandardPackage reports whether an import path is in the standard library, whose paths
+// have no dot in their first element
+func isStandardPackage(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// generatedHelper is a function of generated CLIs that only some generated code calls
+type generatedHelper struct {
+	Name string
+	Code string
+}
+
+// generatedHelpers are emitted only into CLIs whose code calls them, with those of the
+// decorators a CLI uses
+var generatedHelpers = []generatedHelper{
+	{Name: "quoteShellValue", Code: quoteShellValueHelper},
+	{Name: "execCheck", Code: execCheckHelper},
+	{Name: "commandParams", Code: commandParamsHelper},
+	{Name: "runNeed", Code: runNeedHelper},
+	{Name: "addSecret", Code: addSecretHelper},
+	{Name: "runWithTimeout", Code: runWithTimeoutHelper},
+	{Name: "writeProcessFile", Code: processStoreHelper},
+	{Name: "appendProcessFile", Code: processStoreHelper},
+	{Name: "removeProcessFiles", Code: processStoreHelper},
+	{Name: "recordProcessEvent", Code: processStoreHelper},
+}
+
+// addSecretHelper is called by @secret with each value it reads. It mirrors logging.AddSecret
+// and the decorator's registerSecret.
+const addSecretHelper = `
+// secretValues are the values @secret has read, longest first so a secret containing
+// another is masked whole
+var secretValues struct {
+	sync.RWMutex
+	values []string
+}
+
+// addSecret masks a value @secret read in diagnostics and error messages and, in GitHub
+// Actions, asks the runner to mask it in the job's log
+func addSecret(value string) {
+	candidates := []string{value}
+	if strings.Contains(value, "\n") {
+		candidates = append(candidates, strings.Split(value, "\n")...)
+	}
+	secretValues.Lock()
+	defer secretValues.Unlock()
+	for _, candidate := range candidates {
+		candidate = strings.TrimRight(candidate, "\r")
+		if strings.TrimSpace(candidate) == "" {
+			continue
+		}
+		known := false
+		for _, v := range secretValues.values {
+			known = known || v == candidate
+		}
+		if known {
+			continue
+		}
+		secretValues.values = append(secretValues.values, candidate)
+		if os.Getenv("GITHUB_ACTIONS") == "true" && !strings.Contains(candidate, "\n") {
+			fmt.Printf("::add-mask::%s\n", candidate)
+		}
+	}
+	sort.SliceStable(secretValues.values, func(i, j int) bool { return len(secretValues.values[i]) > len(secretValues.values[j]) })
+}
+
+func init() {
+	maskSecrets = func(message string) string {
+		secretValues.RLock()
+		defer secretValues.RUnlock()
+		for _, value := range secretValues.values {
+			message = strings.ReplaceAll(message, value, "***")
+		}
+		return message
+	}
+}
+`
+
+// quoteShellValueHelper is called by shell steps with quoted @var and @env values, and
+// decorators such as @glob that substitute paths
+const quoteShellValueHelper = `
+// quoteShellValue returns value so the shell reads it literally where quote is open: 0
+// outside quotes, or the single or double quote character inside quotes
+func quoteShellValue(value string, quote byte) string {
+	var quoted []byte
+	switch quote {
+	case '\'':
+		for i := 0; i < len(value); i++ {
+			if value[i] == '\'' {
+				quoted = append(quoted, "'\\''"...)
+			} else {
+				quoted = append(quoted, value[i])
+			}
+		}
+	case '"':
+		for i := 0; i < len(value); i++ {
+			switch value[i] {
+			case '\\', '"', '$', '` + "`" + `':
+				quoted = append(quoted, '\\')
+			}
+			quoted = append(quoted, value[i])
+		}
+	default:
+		for i := 0; i < len(value); i++ {
+			c := value[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '@' || c == '%' || c == '+' || c == '=' || c == ':' || c == ',' || c == '.' || c == '/' || c == '-' || c == '_') {
+				return "'" + quoteShellValue(value, '\'') + "'"
+			}
+		}
+		if value == "" {
+			return "''"
+		}
+		return value
+	}
+	return string(quoted)
+}
+`
+
+// execCheckHelper is for decorator templates that test whether a command succeeds
+const execCheckHelper = `
+// execCheck runs a command and returns success status
+func execCheck(ctx ExecutionContext, command string) bool {
+	return exec(ctx, command) == nil
+}
+`
+
+// appendHelpers appends the helpers code calls, including those only other helpers call.
+// Helpers listed under several names are appended once.
+func appendHelpers(code string, helpers []generatedHelper) string {
+	emitted := make(map[string]bool)
+	for added := true; added; {
+		added = false
+		for _, helper := range helpers {
+			if !emitted[helper.Code] && strings.Contains(code, helper.Name+"(") {
+				code += helper.Code
+				emitted[helper.Code] = true
+				added = true
+			}
+		}
+	}
+	return code
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestFeatures(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`fetch: @timeout(1m) { curl example.com }
+slow: @timeout(5m) { sleep 1 }
+watch api: go run .
+stop api: pkill api
+check: @requires(tools = "go") { go vet ./... }
+on failure of slow: @timeout(1m) { echo cleanup }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	eng.SetSourceHash("abc")
+	features, err := eng.Features(program)
+	if err != nil {
+		t.Fatalf("Features failed: %v", err)
+	}
+
+	var names []string
+	for _, feature := range features {
+		names = append(names, feature.Name+"["+strings.Join(feature.Commands, ",")+"]")
+	}
+	want := "CLI core[],drift check[],process management[api],pre-flight checks[check],@timeout[fetch,slow,on failure of slow],@requires[check]"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("features = %s, want %s", got, want)
+	}
+	if imports := strings.Join(features[1].Imports, ","); imports != "crypto/sha256,encoding/hex,path/filepath,strings" {
+		t.Errorf("drift check imports = %s", imports)
+	}
+}
+
+func TestGenerateCode_EmitsOnlyUsedFeatures(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		contains []string
+		excludes []string
+		once     []string // Contained exactly once
+	}{
+		{
+			name:     "a plain command has only the core",
+			input:    "build: go build ./...",
+			contains: []string{`"fmt"`, `"github.com/spf13/cobra"`},
+			excludes: []string{`"strings"`, `"net"`, "func quoteShellValue(", "func execCheck(", "func processRoot(", "func checkSourceDrift(", "func readSecret("},
+		},
+		{
+			name:     "quoted values emit the quoting helper",
+			input:    "var NAME = \"a b\"\ngreet: echo @var(NAME)",
+			contains: []string{"func quoteShellValue("},
+			excludes: []string{"func execCheck("},
+		},
+		{
+			name:     "pre-flight checks import strings",
+			input:    `check: @requires(tools = "go") { go vet ./... }`,
+			contains: []string{`"strings"`},
+		},
+		{
+			name:     "watch commands emit process management",
+			input:    "watch api: go run .",
+			contains: []string{`"net"`, `"syscall"`, "func processRoot("},
+		},
+		{
+			name:  "decorator helpers are emitted once",
+			input: "login: echo @secret(USER, source = \"env\") @secret(TOKEN, source = \"env\")\nlogout: echo @secret(TOKEN, source = \"env\")",
+			once:  []string{"func readSecret(", `"env": func(`, `"sops": func(`},
+		},
+		{
+			name:  "sandboxed blocks share the sandbox helper",
+			input: "test: @sandbox { go test ./... }\nlint: @sandbox(network = false) { go vet ./... }",
+			once:  []string{"func sandboxPrefix(", "const sandboxMountScript", `"--unshare-net"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := parser.Parse(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			result, err := New(program).GenerateCode(program)
+			if err != nil {
+				t.Fatalf("GenerateCode failed: %v", err)
+			}
+			code := result.String()
+			for _, want := range tc.contains {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated code does not contain %s", want)
+				}
+			}
+			for _, want := range tc.once {
+				if n := strings.Count(code, want); n != 1 {
+					t.Errorf("generated code contains %s %d times, want once", want, n)
+				}
+			}
+			for _, unwanted := range tc.excludes {
+				if strings.Contains(code, unwanted) {
+					t.Errorf("generated code contains %s", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCode_GoModRequiresUsedModules(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: go build ./..."))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	goMod := result.GoModString()
+	if !strings.Contains(goMod, "require (\n\tgithub.com/spf13/cobra v1.9.1\n)") {
+		t.Errorf("go.mod should require only cobra, got:\n%s", goMod)
+	}
+	if strings.Contains(goMod, devcmdModule) {
+		t.Errorf("go.mod requires devcmd without a decorator that imports it:\n%s", goMod)
+	}
+}
+
+func TestAppendHelpers(t *testing.T) {
+	code := appendHelpers("func main() { fmt.Println(quoteShellValue(os.Args[1], 0)) }\n", generatedHelpers)
+	if strings.Count(code, "func quoteShellValue(") != 1 {
+		t.Errorf("quoteShellValue should be emitted once, got:\n%s", code)
+	}
+	if strings.Contains(code, "func execCheck(") {
+		t.Error("execCheck is emitted without a call")
+	}
+	code = appendHelpers("func main() { writeProcessFile(dir, \"api.pid\", \"1\"); recordProcessEvent(dir, \"api\", \"started\", 1) }\n", generatedHelpers)
+	if strings.Count(code, "func lockProcessDir(") != 1 {
+		t.Errorf("the process store helper should be emitted once, got:\n%s", code)
+	}
+	if code := appendHelpers("func main() {}\n", generatedHelpers); code != "func main() {}\n" {
+		t.Errorf("helpers are emitted into code that doesn't call them:\n%s", code)
+	}
+}
+
+func TestIsStandardPackage(t *testing.T) {
+	for path, want := range map[string]bool{
+		"fmt":                        true,
+		"net/http":                   true,
+		"github.com/spf13/cobra":     false,
+		"golang.org/x/sync/errgroup": false,
+	} {
+		if got := isStandardPackage(path); got != want {
+			t.Errorf("isStandardPackage(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// StepFilter selects the steps of a run by the commands they invoke with @cmd, as set by
+// `devcmd run --only` and `--skip`
+type StepFilter struct {
+	Only []string // Run only the steps leading to these commands, and these commands in full
+	Skip []string // Leave out every step that invokes these commands
+}
+
+// Skips reports whether the filter leaves out the named command
+func (f StepFilter) Skips(name string) bool {
+	for _, skipped := range f.Skip {
+		if skipped == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterSteps returns a copy of program in which the commands reachable from targets keep only
+// the steps the filter selects, along with warnings about the result: commands that still run
+// without a skipped dependency, filter names no target runs, and targets left with nothing to
+// run. Steps are selected through the @cmd dependency graph, so a command kept by --only still
+// runs the commands it depends on. program itself is not modified.
+func FilterSteps(program *ast.Program, targets []string, filter StepFilter) (*ast.Program, []string, error) {
+	e := New(program)
+	commands := make(map[string]*ast.CommandDecl, len(program.Commands))
+	for i := range program.Commands {
+		commands[program.Commands[i].Name] = &program.Commands[i]
+	}
+	for _, list := range []struct {
+		flag  string
+		names []string
+	}{{"--only", filter.Only}, {"--skip", filter.Skip}} {
+		for _, name := range list.names {
+			if commands[name] == nil {
+				return nil, nil, fmt.Errorf("%s: unknown command %q", list.flag, name)
+			}
+		}
+	}
+
+	// Commands in --only run in full, as do the commands they depend on
+	only := make(map[string]bool)
+	for _, name := range filter.Only {
+		only[name] = true
+	}
+	full := make(map[string]bool)
+	var markFull func(name string)
+	markFull = func(name string) {
+		if full[name] || commands[name] == nil {
+			return
+		}
+		full[name] = true
+		for _, dep := range e.findCommandDependencies(commands[name]) {
+			markFull(dep)
+		}
+	}
+	for name := range only {
+		markFull(name)
+	}
+
+	// leadsToOnly reports whether a command is in --only or depends on one that is
+	leads := make(map[string]bool)
+	var leadsToOnly func(name string, visiting map[string]bool) bool
+	leadsToOnly = func(name string, visiting map[string]bool) bool {
+		if result, ok := leads[name]; ok {
+			return result
+		}
+		if only[name] {
+			return true
+		}
+		if visiting[name] || commands[name] == nil {
+			return false
+		}
+		visiting[name] = true
+		result := false
+		for _, dep := range e.findCommandDependencies(commands[name]) {
+			if leadsToOnly(dep, visiting) {
+				result = true
+				break
+			}
+		}
+		leads[name] = result
+		return result
+	}
+
+	// keep returns the part of a step to run; restrict drops steps that don't lead to --only
+	var filterContent func(content []ast.CommandContent, restrict bool) []ast.CommandContent
+	keep := func(item ast.CommandContent, restrict bool) (ast.CommandContent, bool) {
+		switch c := item.(type) {
+		case *ast.BlockDecorator:
+			inner := filterContent(c.Content, restrict)
+			if len(inner) == 0 {
+				return nil, false
+			}
+			block := *c
+			block.Content = inner
+			return &block, true
+		case *ast.PatternDecorator:
+			pattern := *c
+			pattern.Patterns = make([]ast.PatternBranch, len(c.Patterns))
+			runs := false
+			for i, branch := range c.Patterns {
+				branch.Commands = filterContent(branch.Commands, restrict)
+				runs = runs || len(branch.Commands) > 0
+				pattern.Patterns[i] = branch
+			}
+			return &pattern, runs
+		default:
+			deps := e.scanContentForDependencies(item)
+			leadsToSelection := false
+			for _, dep := range deps {
+				if filter.Skips(dep) {
+					return nil, false
+				}
+				leadsToSelection = leadsToSelection || leadsToOnly(dep, make(map[string]bool))
+			}
+			if restrict && !leadsToSelection {
+				return nil, false
+			}
+			return item, true
+		}
+	}
+	filterContent = func(content []ast.CommandContent, restrict bool) []ast.CommandContent {
+		var kept []ast.CommandContent
+		for _, item := range content {
+			if filtered, ok := keep(item, restrict); ok {
+				kept = append(kept, filtered)
+			}
+		}
+		return kept
+	}
+
+	filtered := *program
+	filtered.Commands = make([]ast.CommandDecl, len(program.Commands))
+	copy(filtered.Commands, program.Commands)
+	for i := range filtered.Commands {
+		command := &filtered.Commands[i]
+		if filter.Skips(command.Name) {
+			command.Body.Content = nil
+			continue
+		}
+		restrict := len(only) > 0 && !full[command.Name]
+		command.Body.Content = filterContent(command.Body.Content, restrict)
+	}
+
+	// Walk what still runs to find commands that lost a dependency, and filter names never reached
+	filteredEngine := New(&filtered)
+	filteredCommands := make(map[string]*ast.CommandDecl, len(filtered.Commands))
+	for i := range filtered.Commands {
+		filteredCommands[filtered.Commands[i].Name] = &filtered.Commands[i]
+	}
+	isTarget := make(map[string]bool)
+	for _, name := range targets {
+		isTarget[name] = true
+	}
+	runs := make(map[string]bool)
+	var walk func(name string)
+	walk = func(name string) {
+		if runs[name] || filteredCommands[name] == nil || len(filteredCommands[name].Body.Content) == 0 {
+			return
+		}
+		runs[name] = true
+		for _, dep := range filteredEngine.findCommandDependencies(filteredCommands[name]) {
+			walk(dep)
+		}
+	}
+	for _, name := range targets {
+		walk(name)
+	}
+	if len(runs) == 0 {
+		return nil, nil, fmt.Errorf("nothing to run: no step of %s is selected", strings.Join(targets, ", "))
+	}
+
+	reached := make(map[string]bool)
+	var reach func(name string)
+	reach = func(name string) {
+		if reached[name] || commands[name] == nil {
+			return
+		}
+		reached[name] = true
+		for _, dep := range e.findCommandDependencies(commands[name]) {
+			reach(dep)
+		}
+	}
+	for _, name := range targets {
+		reach(name)
+	}
+
+	var warnings []string
+	var running []string
+	for name := range runs {
+		running = append(running, name)
+	}
+	sort.Strings(running)
+	for _, name := range running {
+		if isTarget[name] {
+			continue
+		}
+		for _, dep := range uniqueStrings(e.findCommandDependencies(commands[name])) {
+			if filter.Skips(dep) {
+				warnings = append(warnings, fmt.Sprintf("%s depends on %s, which is skipped; it may fail or produce incomplete results", name, dep))
+			}
+		}
+	}
+	for _, list := range []struct {
+		flag  string
+		names []string
+	}{{"--only", filter.Only}, {"--skip", filter.Skip}} {
+		for _, name := range list.names {
+			if !reached[name] {
+				warnings = append(warnings, fmt.Sprintf("%s %s: %s does not run %s", list.flag, name, strings.Join(targets, ", "), name))
+			}
+		}
+	}
+	for _, name := range targets {
+		if !runs[name] && !filter.Skips(name) {
+			warnings = append(warnings, fmt.Sprintf("%s has no selected steps and will not run", name))
+		}
+	}
+
+	return &filtered, warnings, nil
+}
+
+// uniqueStrings returns values without duplicates, in their original order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+const filterCommands = `gen: echo gen
+lint: echo lint
+build: {
+    @cmd(gen)
+    echo build
+}
+test: echo test
+ci: {
+    @cmd(lint)
+    @timeout(1m) {
+        @cmd(build)
+        @cmd(test)
+    }
+    echo ci done
+}`
+
+// commandSteps returns the body of a command in a program as source text, one step per line
+func commandSteps(t *testing.T, program *ast.Program, name string) []string {
+	t.Helper()
+	for _, command := range program.Commands {
+		if command.Name == name {
+			var steps []string
+			for _, content := range command.Body.Content {
+				steps = append(steps, strings.Join(strings.Fields(content.String()), " "))
+			}
+			return steps
+		}
+	}
+	t.Fatalf("command %s not found", name)
+	return nil
+}
+
+func TestFilterSteps(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   StepFilter
+		ci       []string
+		build    []string
+		warnings []string
+	}{
+		{
+			name:   "skip a step",
+			filter: StepFilter{Skip: []string{"lint"}},
+			ci:     []string{"@timeout(1m) { @cmd(build); @cmd(test) }", "echo ci done"},
+			build:  []string{"@cmd(gen)", "echo build"},
+		},
+		{
+			name:     "skip a dependency of a step that still runs",
+			filter:   StepFilter{Skip: []string{"gen"}},
+			ci:       []string{"@cmd(lint)", "@timeout(1m) { @cmd(build); @cmd(test) }", "echo ci done"},
+			build:    []string{"echo build"},
+			warnings: []string{"build depends on gen, which is skipped"},
+		},
+		{
+			name:   "only a step keeps its dependencies",
+			filter: StepFilter{Only: []string{"build"}},
+			ci:     []string{"@timeout(1m) { @cmd(build) }"},
+			build:  []string{"@cmd(gen)", "echo build"},
+		},
+		{
+			name:   "only a dependency keeps the steps leading to it",
+			filter: StepFilter{Only: []string{"gen"}},
+			ci:     []string{"@timeout(1m) { @cmd(build) }"},
+			build:  []string{"@cmd(gen)"},
+		},
+		{
+			name:     "only and skip together",
+			filter:   StepFilter{Only: []string{"build", "test"}, Skip: []string{"gen"}},
+			ci:       []string{"@timeout(1m) { @cmd(build); @cmd(test) }"},
+			build:    []string{"echo build"},
+			warnings: []string{"build depends on gen, which is skipped"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := parser.Parse(strings.NewReader(filterCommands))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			original := program.String()
+
+			filtered, warnings, err := FilterSteps(program, []string{"ci"}, tc.filter)
+			if err != nil {
+				t.Fatalf("FilterSteps failed: %v", err)
+			}
+
+			if got := commandSteps(t, filtered, "ci"); strings.Join(got, "\n") != strings.Join(tc.ci, "\n") {
+				t.Errorf("ci steps = %q, want %q", got, tc.ci)
+			}
+			if got := commandSteps(t, filtered, "build"); strings.Join(got, "\n") != strings.Join(tc.build, "\n") {
+				t.Errorf("build steps = %q, want %q", got, tc.build)
+			}
+			if len(warnings) != len(tc.warnings) {
+				t.Fatalf("warnings = %q, want %d", warnings, len(tc.warnings))
+			}
+			for i, want := range tc.warnings {
+				if !strings.Contains(warnings[i], want) {
+					t.Errorf("warning %q does not mention %q", warnings[i], want)
+				}
+			}
+			if program.String() != original {
+				t.Error("FilterSteps modified the original program")
+			}
+		})
+	}
+}
+
+func TestFilterSteps_Errors(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(filterCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		targets []string
+		filter  StepFilter
+		error   string
+	}{
+		{"unknown command", []string{"ci"}, StepFilter{Skip: []string{"deploy"}}, `--skip: unknown command "deploy"`},
+		{"nothing selected", []string{"test"}, StepFilter{Only: []string{"gen"}}, "nothing to run: no step of test is selected"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := FilterSteps(program, tc.targets, tc.filter)
+			if err == nil || !strings.Contains(err.Error(), tc.error) {
+				t.Errorf("FilterSteps error = %v, want %q", err, tc.error)
+			}
+		})
+	}
+}
+
+func TestFilterSteps_UnreachedNames(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(filterCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	_, warnings, err := FilterSteps(program, []string{"build", "lint"}, StepFilter{Skip: []string{"lint", "test"}})
+	if err != nil {
+		t.Fatalf("FilterSteps failed: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] != "--skip test: build, lint does not run test" {
+		t.Errorf("warnings = %q, want one about test", warnings)
+	}
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aledsdavies/devcmd/runtime/config"
+)
+
+// RetryLogEnvVar names the file @retry appends the attempts of each block to, one
+// "<attempts> <failed attempts>" line per block, so runs can report flaky steps
+const RetryLogEnvVar = "DEVCMD_RETRY_LOG"
+
+// FlakinessStats are the @retry outcomes of a command over the runs in its history
+type FlakinessStats struct {
+	Command        string  `json:"command"`
+	Runs           int     `json:"runs"`            // Runs in which a @retry block ran
+	FlakyRuns      int     `json:"flaky_runs"`      // Runs that needed retries and then succeeded
+	FailedRuns     int     `json:"failed_runs"`     // Runs that failed after retries
+	Attempts       int     `json:"attempts"`        // Attempts made by @retry blocks
+	FailedAttempts int     `json:"failed_attempts"` // Attempts that failed
+	SuccessRate    float64 `json:"success_rate"`    // Percent of attempts that succeeded
+}
+
+// retryLog is the file @retry blocks of a command report their attempts in
+type retryLog struct {
+	file *os.File
+}
+
+// newRetryLog creates an empty retry log in the temporary directory
+func newRetryLog() (*retryLog, error) {
+	file, err := os.CreateTemp("", "devcmd-retries-*.log")
+	if err != nil {
+		return nil, err
+	}
+	return &retryLog{file: file}, nil
+}
+
+// Path returns the file @retry appends to
+func (l *retryLog) Path() string {
+	return l.file.Name()
+}
+
+// Take returns the attempts reported since the last call, and how many of them failed
+func (l *retryLog) Take() (attempts, failed int) {
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0
+	}
+	scanner := bufio.NewScanner(l.file)
+	for scanner.Scan() {
+		var blockAttempts, blockFailed int
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &blockAttempts, &blockFailed); err == nil {
+			attempts += blockAttempts
+			failed += blockFailed
+		}
+	}
+	_ = l.file.Truncate(0)
+	return attempts, failed
+}
+
+// Close removes the retry log
+func (l *retryLog) Close() {
+	l.file.Close()
+	os.Remove(l.file.Name())
+}
+
+// FlakinessFile returns the file the flakiness history of a project's commands is kept in:
+// flakiness/<namespace>.json in the state directory of the config, or devcmd/flakiness in
+// the user cache directory, or in the temporary directory when there is none
+func FlakinessFile(namespace string) string {
+	if state := config.StateDirFromEnv(); state != "" {
+		return filepath.Join(state, "flakiness", namespace+".json")
+	}
+	dir := os.TempDir()
+	if cache, err := os.UserCacheDir(); err == nil {
+		dir = cache
+	}
+	return filepath.Join(dir, "devcmd", "flakiness", namespace+".json")
+}
+
+// HasRetries reports whether any step of the run retried a failed attempt
+func (s *RunSummary) HasRetries() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, step := range s.Steps {
+		if step.FailedAttempts > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateFlakiness adds the @retry outcomes of the run's commands to the history in path,
+// and sets the summary's flakiness statistics to those of the commands that ran @retry
+func (s *RunSummary) UpdateFlakiness(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type outcome struct{ attempts, failed int }
+	runs := make(map[string]*outcome)
+	for _, step := range s.Steps {
+		if step.Attempts == 0 {
+			continue
+		}
+		if runs[step.Command] == nil {
+			runs[step.Command] = &outcome{}
+		}
+		runs[step.Command].attempts += step.Attempts
+		runs[step.Command].failed += step.FailedAttempts
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+
+	history := make(map[string]FlakinessStats)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &history); err != nil {
+			return fmt.Errorf("%s is not a flakiness history: %w", path, err)
+		}
+	}
+
+	s.Flakiness = []FlakinessStats{}
+	for _, command := range s.Commands {
+		run, ok := runs[command.Name]
+		if !ok {
+			continue
+		}
+		stats := history[command.Name]
+		stats.Command = command.Name
+		stats.Runs++
+		if run.failed > 0 {
+			if command.Status == "failed" {
+				stats.FailedRuns++
+			} else {
+				stats.FlakyRuns++
+			}
+		}
+		stats.Attempts += run.attempts
+		stats.FailedAttempts += run.failed
+		stats.SuccessRate = float64(stats.Attempts-stats.FailedAttempts) / float64(stats.Attempts) * 100
+		history[command.Name] = stats
+		s.Flakiness = append(s.Flakiness, stats)
+	}
+	sort.Slice(s.Flakiness, func(i, j int) bool { return s.Flakiness[i].Command < s.Flakiness[j].Command })
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// writeFlakiness writes the flakiness statistics of commands that have retried attempts
+func (s *RunSummary) writeFlakiness(w io.Writer) {
+	header := false
+	for _, stats := range s.Flakiness {
+		if stats.FailedAttempts == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\nFlaky commands (@retry history):")
+			header = true
+		}
+		fmt.Fprintf(w, "  %s: %d of %d runs needed retries (%d still failed), %.1f%% of %d attempts succeeded\n",
+			stats.Command, stats.FlakyRuns+stats.FailedRuns, stats.Runs, stats.FailedRuns, stats.SuccessRate, stats.Attempts)
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestSummary_RetryAttemptsAndFlakiness(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	source := `flaky: @retry(attempts = 3, delay = 1ms) {
+    test -f ` + marker + ` || { touch ` + marker + `; exit 1; }
+}
+solid: @retry(attempts = 2, delay = 1ms) {
+    echo ok
+}
+plain: echo plain`
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	history := filepath.Join(t.TempDir(), "flakiness", "project.json")
+
+	run := func() *RunSummary {
+		eng := New(program)
+		summary := eng.Summarize()
+		for i := range program.Commands {
+			if _, err := eng.ExecuteCommand(&program.Commands[i]); err != nil {
+				t.Fatalf("%s failed: %v", program.Commands[i].Name, err)
+			}
+		}
+		summary.Finish(FailOnAny)
+		if err := summary.UpdateFlakiness(history); err != nil {
+			t.Fatalf("UpdateFlakiness failed: %v", err)
+		}
+		return summary
+	}
+
+	summary := run()
+	attempts := make(map[string][2]int)
+	for _, step := range summary.Steps {
+		attempts[step.Command] = [2]int{step.Attempts, step.FailedAttempts}
+	}
+	if attempts["flaky"] != [2]int{2, 1} || attempts["solid"] != [2]int{1, 0} || attempts["plain"] != [2]int{0, 0} {
+		t.Errorf("step attempts = %v, want flaky 2/1, solid 1/0, plain none", attempts)
+	}
+	if !summary.HasRetries() {
+		t.Error("HasRetries() = false after a retried attempt")
+	}
+
+	// The second run's statistics include the first run from the history
+	summary = run()
+	if len(summary.Flakiness) != 2 {
+		t.Fatalf("flakiness = %+v, want flaky and solid", summary.Flakiness)
+	}
+	flaky := summary.Flakiness[0]
+	if flaky.Command != "flaky" || flaky.Runs != 2 || flaky.FlakyRuns != 1 || flaky.Attempts != 3 || flaky.FailedAttempts != 1 {
+		t.Errorf("flaky stats = %+v, want 2 runs, 1 flaky, 1 of 3 attempts failed", flaky)
+	}
+	if solid := summary.Flakiness[1]; solid.Runs != 2 || solid.SuccessRate != 100 {
+		t.Errorf("solid stats = %+v, want 2 runs at 100%%", solid)
+	}
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if want := "flaky: 1 of 2 runs needed retries (0 still failed), 66.7% of 3 attempts succeeded"; !strings.Contains(text.String(), want) {
+		t.Errorf("text summary missing %q:\n%s", want, text.String())
+	}
+	if strings.Contains(text.String(), "solid:") {
+		t.Errorf("text summary lists solid, which never retried:\n%s", text.String())
+	}
+}
+
+func TestSummary_UpdateFlakinessWithoutRetries(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: echo build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	summary.Finish(FailOnAny)
+
+	history := filepath.Join(t.TempDir(), "project.json")
+	if err := summary.UpdateFlakiness(history); err != nil {
+		t.Fatalf("UpdateFlakiness failed: %v", err)
+	}
+	if summary.Flakiness != nil {
+		t.Errorf("flakiness = %+v, want none without @retry", summary.Flakiness)
+	}
+	if _, err := os.Stat(history); !os.IsNotExist(err) {
+		t.Errorf("history file written without @retry: %v", err)
+	}
+}
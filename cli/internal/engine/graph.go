@@ -0,0 +1,398 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// CommandGraph is the dependency graph of a program's commands, with an edge for each @cmd
+// reference or need from one command to another
+type CommandGraph struct {
+	Commands     []GraphNode `json:"commands"`
+	Cycles       [][]string  `json:"cycles"`        // Each cycle starts and ends with the same command
+	Orphans      []string    `json:"orphans"`       // Commands with no dependencies or dependents
+	CriticalPath []string    `json:"critical_path"` // Slowest chain of commands, from recorded durations
+}
+
+// GraphNode is a command and the commands it needs or runs with @cmd
+type GraphNode struct {
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies"`
+	DurationMs   int64    `json:"duration_ms,omitempty"` // Recorded duration, 0 if unknown
+}
+
+// CommandGraph builds the dependency graph of the program's commands. durations holds recorded
+// command durations in milliseconds, such as those from ReadRecordedDurations.
+func (e *Engine) CommandGraph(durations map[string]int64) *CommandGraph {
+	g := &CommandGraph{Commands: []GraphNode{}, Cycles: [][]string{}, Orphans: []string{}, CriticalPath: []string{}}
+
+	known := make(map[string]bool)
+	for _, command := range e.program.Commands {
+		known[command.Name] = true
+	}
+	// Watch and stop commands share a name; they are one node
+	added := make(map[string]int)
+	for i := range e.program.Commands {
+		command := &e.program.Commands[i]
+		index, seen := added[command.Name]
+		if !seen {
+			index = len(g.Commands)
+			added[command.Name] = index
+			g.Commands = append(g.Commands, GraphNode{Name: command.Name, Dependencies: []string{}, DurationMs: durations[command.Name]})
+		}
+		for _, dep := range uniqueStrings(e.findCommandDependencies(command)) {
+			if known[dep] && !containsString(g.Commands[index].Dependencies, dep) {
+				g.Commands[index].Dependencies = append(g.Commands[index].Dependencies, dep)
+			}
+		}
+	}
+
+	g.Cycles = g.findCycles()
+
+	dependents := g.dependents()
+	for _, node := range g.Commands {
+		if len(node.Dependencies) == 0 && len(dependents[node.Name]) == 0 {
+			g.Orphans = append(g.Orphans, node.Name)
+		}
+	}
+
+	g.CriticalPath = g.criticalPath()
+	return g
+}
+
+// ReadRecordedDurations reads command durations from run summaries written by
+// `devcmd run --output=json`, which may follow the run's own output. Later files override
+// earlier ones; skipped commands are ignored.
+func ReadRecordedDurations(paths []string) (map[string]int64, error) {
+	durations := make(map[string]int64)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Command output may come before the summary on the same stream
+		if start := bytes.LastIndex(data, []byte("\n{\n")); start >= 0 && !bytes.HasPrefix(data, []byte("{")) {
+			data = data[start+1:]
+		}
+		var summary RunSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return nil, fmt.Errorf("%s is not a run summary: %w", path, err)
+		}
+		for _, command := range summary.Commands {
+			if command.Status != "skipped" {
+				durations[command.Name] = command.DurationMs
+			}
+		}
+	}
+	return durations, nil
+}
+
+// node returns the named command's node
+func (g *CommandGraph) node(name string) *GraphNode {
+	for i := range g.Commands {
+		if g.Commands[i].Name == name {
+			return &g.Commands[i]
+		}
+	}
+	return nil
+}
+
+// dependents maps each command to the commands that run it
+func (g *CommandGraph) dependents() map[string][]string {
+	dependents := make(map[string][]string)
+	for _, node := range g.Commands {
+		for _, dep := range node.Dependencies {
+			dependents[dep] = append(dependents[dep], node.Name)
+		}
+	}
+	return dependents
+}
+
+// roots returns the commands no other command runs, in file order
+func (g *CommandGraph) roots() []string {
+	dependents := g.dependents()
+	var roots []string
+	for _, node := range g.Commands {
+		if len(dependents[node.Name]) == 0 {
+			roots = append(roots, node.Name)
+		}
+	}
+	return roots
+}
+
+// findCycles returns each dependency cycle once, starting from its first command in file order
+func (g *CommandGraph) findCycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	order := make(map[string]int)
+	for i, node := range g.Commands {
+		order[node.Name] = i
+	}
+
+	cycles := [][]string{}
+	seen := make(map[string]bool)
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g.node(name).Dependencies {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// The stack from dep to here is a cycle; rotate it to start at its earliest command
+				start := len(stack) - 1
+				for stack[start] != dep {
+					start--
+				}
+				cycle := append([]string{}, stack[start:]...)
+				first := 0
+				for i, member := range cycle {
+					if order[member] < order[cycle[first]] {
+						first = i
+					}
+				}
+				cycle = append(cycle[first:], cycle[:first]...)
+				cycle = append(cycle, cycle[0])
+				if key := strings.Join(cycle, "\x00"); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, node := range g.Commands {
+		if state[node.Name] == unvisited {
+			visit(node.Name)
+		}
+	}
+	return cycles
+}
+
+// criticalPath starts at the slowest command no other command runs and follows the slowest
+// dependency at each level. Recorded durations include the dependencies a command runs, so
+// this is the chain that dominates the slowest run. It is empty without recorded durations.
+func (g *CommandGraph) criticalPath() []string {
+	path := []string{}
+	current := ""
+	for _, root := range g.roots() {
+		if node := g.node(root); node.DurationMs > 0 && (current == "" || node.DurationMs > g.node(current).DurationMs) {
+			current = root
+		}
+	}
+	onPath := make(map[string]bool)
+	for current != "" && !onPath[current] {
+		path = append(path, current)
+		onPath[current] = true
+		next := ""
+		for _, dep := range g.node(current).Dependencies {
+			if node := g.node(dep); node.DurationMs > 0 && !onPath[dep] && (next == "" || node.DurationMs > g.node(next).DurationMs) {
+				next = dep
+			}
+		}
+		current = next
+	}
+	return path
+}
+
+// label returns a command's name with its recorded duration, if any
+func (g *CommandGraph) label(name string) string {
+	if node := g.node(name); node != nil && node.DurationMs > 0 {
+		return fmt.Sprintf("%s (%s)", name, formatDurationMs(node.DurationMs))
+	}
+	return name
+}
+
+// WriteTree writes the graph as an ASCII tree under each command no other command runs,
+// followed by cycles, orphans and the critical path. In accessible output the tree is
+// indented without box-drawing characters, and arrows and markers are words.
+func (g *CommandGraph) WriteTree(w io.Writer) error {
+	plain := logging.Accessible()
+	branches := [2]string{"├─ ", "└─ "}
+	indents := [2]string{"│  ", "   "}
+	marker, arrow := " *", " → "
+	if plain {
+		branches = [2]string{"  ", "  "}
+		indents = [2]string{"  ", "  "}
+		marker, arrow = " (critical path)", " then "
+	}
+
+	critical := make(map[string]bool)
+	for _, name := range g.CriticalPath {
+		critical[name] = true
+	}
+
+	var b strings.Builder
+	expanded := make(map[string]bool)
+	var writeNode func(name, prefix, branch, indent string, ancestors map[string]bool)
+	writeNode = func(name, prefix, branch, indent string, ancestors map[string]bool) {
+		line := g.label(name)
+		deps := g.node(name).Dependencies
+		switch {
+		case ancestors[name]:
+			line += " [cycle]"
+			deps = nil
+		case expanded[name] && len(deps) > 0:
+			line += " [see above]"
+			deps = nil
+		}
+		if critical[name] {
+			line += marker
+		}
+		b.WriteString(prefix + branch + line + "\n")
+		expanded[name] = true
+		if len(deps) == 0 {
+			return
+		}
+
+		ancestors[name] = true
+		for i, dep := range deps {
+			if i == len(deps)-1 {
+				writeNode(dep, prefix+indent, branches[1], indents[1], ancestors)
+			} else {
+				writeNode(dep, prefix+indent, branches[0], indents[0], ancestors)
+			}
+		}
+		delete(ancestors, name)
+	}
+
+	for _, root := range g.roots() {
+		writeNode(root, "", "", "", make(map[string]bool))
+	}
+	// Commands only reachable through a cycle have no root above them
+	for _, node := range g.Commands {
+		if !expanded[node.Name] {
+			writeNode(node.Name, "", "", "", make(map[string]bool))
+		}
+	}
+
+	if len(g.Cycles) > 0 {
+		b.WriteString("\nCycles:\n")
+		for _, cycle := range g.Cycles {
+			b.WriteString("  " + strings.Join(cycle, arrow) + "\n")
+		}
+	}
+	if len(g.Orphans) > 0 {
+		b.WriteString("\nOrphans (no dependencies or dependents): " + strings.Join(g.Orphans, ", ") + "\n")
+	}
+	if len(g.CriticalPath) > 0 {
+		labels := make([]string, len(g.CriticalPath))
+		for i, name := range g.CriticalPath {
+			labels[i] = g.label(name)
+		}
+		if plain {
+			b.WriteString("\nCritical path: " + strings.Join(labels, arrow) + "\n")
+		} else {
+			b.WriteString("\nCritical path (*): " + strings.Join(labels, arrow) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with cycle edges in red, the critical path
+// in bold and orphans dashed
+func (g *CommandGraph) WriteDOT(w io.Writer) error {
+	cycleEdges := make(map[[2]string]bool)
+	for _, cycle := range g.Cycles {
+		for i := 0; i+1 < len(cycle); i++ {
+			cycleEdges[[2]string{cycle[i], cycle[i+1]}] = true
+		}
+	}
+	criticalEdges := make(map[[2]string]bool)
+	critical := make(map[string]bool)
+	for i, name := range g.CriticalPath {
+		critical[name] = true
+		if i > 0 {
+			criticalEdges[[2]string{g.CriticalPath[i-1], name}] = true
+		}
+	}
+	orphans := make(map[string]bool)
+	for _, name := range g.Orphans {
+		orphans[name] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph commands {\n  rankdir=LR;\n  node [shape=box];\n")
+	for _, node := range g.Commands {
+		var attrs []string
+		if node.DurationMs > 0 {
+			attrs = append(attrs, fmt.Sprintf("label=%q", node.Name+"\n"+formatDurationMs(node.DurationMs)))
+		}
+		if critical[node.Name] {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if orphans[node.Name] {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %q", node.Name)
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	for _, node := range g.Commands {
+		for _, dep := range node.Dependencies {
+			var attrs []string
+			edge := [2]string{node.Name, dep}
+			if cycleEdges[edge] {
+				attrs = append(attrs, "color=red")
+			}
+			if criticalEdges[edge] {
+				attrs = append(attrs, "penwidth=2")
+			}
+			fmt.Fprintf(&b, "  %q -> %q", node.Name, dep)
+			if len(attrs) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the graph as indented JSON
+func (g *CommandGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// CycleError returns an error describing the dependency cycles, or nil when there are none
+func (g *CommandGraph) CycleError() error {
+	if len(g.Cycles) == 0 {
+		return nil
+	}
+	cycles := make([]string, len(g.Cycles))
+	for i, cycle := range g.Cycles {
+		cycles[i] = strings.Join(cycle, " → ")
+	}
+	return fmt.Errorf("dependency cycle: %s", strings.Join(cycles, "; "))
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
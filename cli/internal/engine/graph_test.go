@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+const graphCommands = `gen: echo gen
+lint: echo lint
+build: {
+    @cmd(gen)
+    echo build
+}
+test: {
+    @cmd(gen)
+    echo test
+}
+ci: {
+    @cmd(lint)
+    @parallel {
+        @cmd(build)
+        @cmd(test)
+    }
+}
+lonely: echo lonely`
+
+// parseGraph builds the dependency graph of source with the given durations
+func parseGraph(t *testing.T, source string, durations map[string]int64) *CommandGraph {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return New(program).CommandGraph(durations)
+}
+
+func TestCommandGraph_Tree(t *testing.T) {
+	graph := parseGraph(t, graphCommands, map[string]int64{"ci": 5000, "build": 4000, "test": 900, "gen": 1500})
+
+	var out bytes.Buffer
+	if err := graph.WriteTree(&out); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+	want := `ci (5s) *
+├─ lint
+├─ build (4s) *
+│  └─ gen (1.5s) *
+└─ test (900ms)
+   └─ gen (1.5s) *
+lonely
+
+Orphans (no dependencies or dependents): lonely
+
+Critical path (*): ci (5s) → build (4s) → gen (1.5s)
+`
+	if out.String() != want {
+		t.Errorf("tree =\n%s\nwant\n%s", out.String(), want)
+	}
+	if err := graph.CycleError(); err != nil {
+		t.Errorf("unexpected cycle error: %v", err)
+	}
+}
+
+func TestCommandGraph_TreeAccessible(t *testing.T) {
+	defer logging.SetAccessible(logging.Accessible())
+	logging.SetAccessible(true)
+	graph := parseGraph(t, graphCommands, map[string]int64{"ci": 5000, "build": 4000, "test": 900, "gen": 1500})
+
+	var out bytes.Buffer
+	if err := graph.WriteTree(&out); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+	want := `ci (5s) (critical path)
+  lint
+  build (4s) (critical path)
+    gen (1.5s) (critical path)
+  test (900ms)
+    gen (1.5s) (critical path)
+lonely
+
+Orphans (no dependencies or dependents): lonely
+
+Critical path: ci (5s) then build (4s) then gen (1.5s)
+`
+	if out.String() != want {
+		t.Errorf("tree =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestCommandGraph_Cycles(t *testing.T) {
+	// The parser rejects cycles, so the program is put together from files without one
+	program, err := parser.Parse(strings.NewReader("a: @cmd(b)\nc: echo c"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	other, err := parser.Parse(strings.NewReader("b: {\n    @cmd(c)\n    @cmd(a)\n}"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	program.Commands = []ast.CommandDecl{program.Commands[0], other.Commands[0], program.Commands[1]}
+	graph := New(program).CommandGraph(nil)
+
+	if len(graph.Cycles) != 1 || strings.Join(graph.Cycles[0], " ") != "a b a" {
+		t.Fatalf("cycles = %q, want [a b a]", graph.Cycles)
+	}
+	err = graph.CycleError()
+	if err == nil || err.Error() != "dependency cycle: a → b → a" {
+		t.Errorf("CycleError() = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := graph.WriteTree(&out); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+	for _, want := range []string{"a\n└─ b\n   ├─ c\n   └─ a [cycle]\n", "Cycles:\n  a → b → a\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("tree does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := graph.WriteDOT(&out); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	for _, want := range []string{`"a" -> "b" [color=red];`, `"b" -> "a" [color=red];`, `"b" -> "c";`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("DOT does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCommandGraph_DOT(t *testing.T) {
+	graph := parseGraph(t, graphCommands, map[string]int64{"ci": 5000, "build": 4000, "gen": 1500})
+
+	var out bytes.Buffer
+	if err := graph.WriteDOT(&out); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	for _, want := range []string{
+		"digraph commands {",
+		`"ci" [label="ci\n5s", penwidth=2];`,
+		`"lint";`,
+		`"lonely" [style=dashed];`,
+		`"ci" -> "build" [penwidth=2];`,
+		`"ci" -> "test";`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("DOT does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCommandGraph_JSON(t *testing.T) {
+	graph := parseGraph(t, graphCommands, nil)
+
+	var out bytes.Buffer
+	if err := graph.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded CommandGraph
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded.Commands) != 6 {
+		t.Fatalf("commands = %d, want 6", len(decoded.Commands))
+	}
+	if ci := decoded.Commands[4]; ci.Name != "ci" || strings.Join(ci.Dependencies, ",") != "lint,build,test" {
+		t.Errorf("ci node = %+v", ci)
+	}
+	if strings.Join(decoded.Orphans, ",") != "lonely" || len(decoded.CriticalPath) != 0 {
+		t.Errorf("orphans = %q, critical path = %q", decoded.Orphans, decoded.CriticalPath)
+	}
+}
+
+func TestReadRecordedDurations(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	// The second summary follows the run's own output, as with `devcmd run --output=json > file`
+	if err := os.WriteFile(first, []byte(`{"status":"success","commands":[{"name":"build","status":"success","duration_ms":100},{"name":"test","status":"success","duration_ms":50}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("building\n{\n  \"status\": \"failed\",\n  \"commands\": [{\"name\": \"build\", \"status\": \"failed\", \"duration_ms\": 300}, {\"name\": \"test\", \"status\": \"skipped\", \"duration_ms\": 0}]\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	durations, err := ReadRecordedDurations([]string{first, second})
+	if err != nil {
+		t.Fatalf("ReadRecordedDurations failed: %v", err)
+	}
+	if durations["build"] != 300 || durations["test"] != 50 {
+		t.Errorf("durations = %v, want build=300 test=50", durations)
+	}
+
+	if _, err := ReadRecordedDurations([]string{filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// runWithHeartbeat runs the shell step of a one-line command with the heartbeat interval and
+// timeout, and returns what it wrote
+func runWithHeartbeat(t *testing.T, command string, interval, timeout time.Duration) string {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader("step: " + command))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	shell, ok := program.Commands[0].Body.Content[0].(*ast.ShellContent)
+	if !ok {
+		t.Fatalf("step is %T, want shell content", program.Commands[0].Body.Content[0])
+	}
+
+	var out bytes.Buffer
+	ctx := New(program).CreateInterpreterContext(context.Background(), program).
+		WithOutput(&out, &out).
+		WithHeartbeat(interval)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = ctx.WithTimeout(timeout)
+		defer cancel()
+	}
+	if result := ctx.ExecuteShell(shell); result.Error != nil {
+		t.Fatalf("step failed: %v", result.Error)
+	}
+	return out.String()
+}
+
+func TestHeartbeat_SilentStep(t *testing.T) {
+	out := runWithHeartbeat(t, "sleep 0.35", 100*time.Millisecond, time.Minute)
+	if count := strings.Count(out, "still running: sleep 0.35 — "); count < 2 {
+		t.Errorf("got %d heartbeats, want at least 2:\n%s", count, out)
+	}
+	if !strings.Contains(out, "s until timeout") {
+		t.Errorf("heartbeat doesn't show the time until the timeout:\n%s", out)
+	}
+
+	out = runWithHeartbeat(t, "sleep 0.25", 100*time.Millisecond, 0)
+	if !strings.Contains(out, "still running: sleep 0.25 — ") || strings.Contains(out, "until timeout") {
+		t.Errorf("heartbeat without a deadline should show only the time elapsed:\n%s", out)
+	}
+}
+
+func TestHeartbeat_OutputResetsSilence(t *testing.T) {
+	out := runWithHeartbeat(t, "for i in 1 2 3 4 5; do echo $i; sleep 0.1; done", 300*time.Millisecond, 0)
+	if strings.Contains(out, "still running") {
+		t.Errorf("step writing output every 100ms got a 300ms heartbeat:\n%s", out)
+	}
+}
+
+func TestHeartbeat_Disabled(t *testing.T) {
+	out := runWithHeartbeat(t, "sleep 0.2", 0, 0)
+	if out != "" {
+		t.Errorf("step with no heartbeat wrote %q", out)
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// AddHook registers a callback for a lifecycle event of the commands the engine runs.
+// Callbacks registered with execution.RegisterHook run after the engine's own.
+func (e *Engine) AddHook(eventType execution.EventType, fn execution.HookFunc) {
+	if e.hooks == nil {
+		e.hooks = make(map[execution.EventType][]execution.HookFunc)
+	}
+	e.hooks[eventType] = append(e.hooks[eventType], fn)
+}
+
+// RegisterShellHooks registers shell hooks from a settings section such as
+// `hooks { postRun = "./notify.sh" }`, keyed by event type name
+func (e *Engine) RegisterShellHooks(hooks map[string]string) error {
+	// Register in a stable order so hooks behave the same on every run
+	keys := make([]string, 0, len(hooks))
+	for key := range hooks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		eventType, err := execution.ParseEventType(key)
+		if err != nil {
+			return err
+		}
+		e.AddHook(eventType, execution.ShellHook(hooks[key]))
+	}
+	return nil
+}
+
+// emit delivers an event to all registered hooks, stopping at the first error
+func (e *Engine) emit(event execution.Event) error {
+	for _, fns := range [][]execution.HookFunc{e.hooks[event.Type], execution.RegisteredHooks(event.Type)} {
+		for _, fn := range fns {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeStep returns a short, unexpanded description of a command step for hooks
+func describeStep(content ast.CommandContent) string {
+	switch c := content.(type) {
+	case *ast.ShellContent:
+		var builder strings.Builder
+		for _, part := range c.Parts {
+			switch p := part.(type) {
+			case *ast.TextPart:
+				builder.WriteString(p.Text)
+			case *ast.ValueDecorator:
+				builder.WriteString("@" + p.Name + "(...)")
+			case *ast.ActionDecorator:
+				builder.WriteString("@" + p.Name + "(...)")
+			}
+		}
+		return strings.TrimSpace(builder.String())
+	case *ast.BlockDecorator:
+		return "@" + c.Name
+	case *ast.PatternDecorator:
+		return "@" + c.Name
+	default:
+		return fmt.Sprintf("%T", content)
+	}
+}
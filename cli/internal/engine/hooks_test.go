@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+func TestHooks_EventOrder(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: {\n  echo one\n  echo two\n}"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	var events []string
+	for _, eventType := range execution.EventTypes {
+		eng.AddHook(eventType, func(event execution.Event) error {
+			events = append(events, fmt.Sprintf("%s:%d:%s", event.Type, event.Step, event.Status))
+			return nil
+		})
+	}
+
+	result, err := eng.ExecuteCommand(&program.Commands[0])
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if result.Status != "success" {
+		t.Fatalf("expected success, got %s", result.Status)
+	}
+
+	expected := []string{
+		"preRun:0:",
+		"preStep:1:",
+		"postStep:1:success",
+		"preStep:2:",
+		"postStep:2:success",
+		"postRun:0:success",
+	}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected event order:\n got: %v\nwant: %v", events, expected)
+	}
+}
+
+func TestHooks_FailureEvents(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("broken: {\n  exit 3\n  echo unreachable\n}"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	var failure *execution.Event
+	var post *execution.Event
+	steps := 0
+	eng.AddHook(execution.EventFailure, func(event execution.Event) error {
+		failure = &event
+		return nil
+	})
+	eng.AddHook(execution.EventPostCommand, func(event execution.Event) error {
+		post = &event
+		return nil
+	})
+	eng.AddHook(execution.EventPreStep, func(event execution.Event) error {
+		steps++
+		return nil
+	})
+
+	result, err := eng.ExecuteCommand(&program.Commands[0])
+	if err == nil {
+		t.Fatal("expected command to fail")
+	}
+	if result.Status != "failed" {
+		t.Errorf("expected failed status, got %s", result.Status)
+	}
+	if steps != 1 {
+		t.Errorf("expected execution to stop after the failing step, ran %d steps", steps)
+	}
+	if failure == nil || failure.Err == nil || failure.Command != "broken" {
+		t.Errorf("expected onFailure event with error, got %+v", failure)
+	}
+	if post == nil || post.Status != "failed" {
+		t.Errorf("expected postRun event with failed status, got %+v", post)
+	}
+}
+
+func TestHooks_PreHookAbortsCommand(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	program, err := parser.Parse(strings.NewReader(fmt.Sprintf("guarded: touch %s", marker)))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	eng.AddHook(execution.EventPreCommand, func(event execution.Event) error {
+		return fmt.Errorf("not allowed")
+	})
+
+	result, err := eng.ExecuteCommand(&program.Commands[0])
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected pre hook error, got %v", err)
+	}
+	if result.Status != "failed" {
+		t.Errorf("expected failed status, got %s", result.Status)
+	}
+	if _, statErr := os.Stat(marker); statErr == nil {
+		t.Error("command ran despite pre hook failure")
+	}
+}
+
+func TestRegisterShellHooks(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.out")
+	program, err := parser.Parse(strings.NewReader("hello: echo hi"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	err = eng.RegisterShellHooks(map[string]string{
+		"postRun": fmt.Sprintf(`echo "$DEVCMD_EVENT $DEVCMD_COMMAND $DEVCMD_STATUS" > %s`, out),
+	})
+	if err != nil {
+		t.Fatalf("RegisterShellHooks failed: %v", err)
+	}
+
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "postRun hello success" {
+		t.Errorf("hook output = %q, want %q", got, "postRun hello success")
+	}
+
+	if err := eng.RegisterShellHooks(map[string]string{"afterEverything": "true"}); err == nil {
+		t.Error("expected error for unknown hook name")
+	}
+}
+
+func TestRegisterHook(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("registered: echo hi"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Registered hooks run for every engine, so only this test's command is recorded
+	var events []string
+	execution.RegisterHook(execution.EventPostCommand, func(event execution.Event) error {
+		if event.Command == "registered" {
+			events = append(events, string(event.Type)+":"+event.Status)
+		}
+		return nil
+	})
+
+	eng := New(program)
+	eng.AddHook(execution.EventPostCommand, func(event execution.Event) error {
+		events = append(events, "engine")
+		return nil
+	})
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if strings.Join(events, ",") != "engine,postRun:success" {
+		t.Errorf("events = %v, want the engine's hook before the registered one", events)
+	}
+}
+
+// TestGeneratedCliHooks tests that generated CLIs run the settings hooks around the command
+// they're invoked with and its steps, with the same events as devcmd run
+func TestGeneratedCliHooks(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	record := `echo "$DEVCMD_EVENT $DEVCMD_COMMAND $DEVCMD_STEP $DEVCMD_STATUS" >> ` + log
+	binaryPath := buildTestCLIWithOptions(t, "ok: {\n  echo one\n  echo two\n}\nbroken: exit 3\n", CLIOptions{
+		Hooks: map[string]string{"preRun": record, "postStep": record, "onFailure": record, "postRun": record},
+	})
+
+	if output, err := exec.Command(binaryPath, "ok").CombinedOutput(); err != nil {
+		t.Fatalf("ok failed (%v):\n%s", err, output)
+	}
+	if err := exec.Command(binaryPath, "broken").Run(); err == nil {
+		t.Fatal("broken should fail")
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("hooks didn't run: %v", err)
+	}
+	want := []string{
+		"preRun ok",
+		"postStep ok 1 success",
+		"postStep ok 2 success",
+		"postRun ok success",
+		"preRun broken",
+		"postStep broken 1 failed",
+		"onFailure broken failed",
+		"postRun broken failed",
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("hook events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestGenerateUnknownHook(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("hello: echo hi"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	eng.SetCLIOptions(CLIOptions{Hooks: map[string]string{"afterEverything": "true"}})
+	if _, err := eng.GenerateCode(program); err == nil || !strings.Contains(err.Error(), "afterEverything") {
+		t.Errorf("GenerateCode returned %v, want an unknown hook error", err)
+	}
+}
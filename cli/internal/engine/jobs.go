@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// outputMu serializes the prefixed output lines of commands running at once
+var outputMu sync.Mutex
+
+// SetOutputPrefix prefixes each line commands write with "[command] ", so the output of
+// commands running at once can be told apart
+func (e *Engine) SetOutputPrefix(prefix bool) {
+	e.outputPrefix = prefix
+}
+
+// Jobs returns how many commands run at once for a --jobs value: the machine's CPU count for
+// 0 or less
+func Jobs(jobs int) int {
+	if jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return jobs
+}
+
+// Schedule runs each command with run, up to jobs at once. A command starts once the commands
+// before it that it runs with @cmd, directly or through other commands, have finished, so
+// `run lint test build` can run lint and test together and build after them when it runs
+// @cmd(test). Commands that run each other start in the order given. Once stop reports true,
+// commands that haven't started are passed to skip instead.
+func (e *Engine) Schedule(commands []*ast.CommandDecl, jobs int, run func(*ast.CommandDecl), stop func() bool, skip func(*ast.CommandDecl)) {
+	graph := e.CommandGraph(nil)
+	reaches := func(from, to string) bool {
+		seen := make(map[string]bool)
+		var visit func(name string) bool
+		visit = func(name string) bool {
+			if name == to {
+				return true
+			}
+			if seen[name] {
+				return false
+			}
+			seen[name] = true
+			if node := graph.node(name); node != nil {
+				for _, dep := range node.Dependencies {
+					if visit(dep) {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		return visit(from)
+	}
+
+	// waits[j] holds the commands that must finish before command j starts
+	waits := make([][]int, len(commands))
+	for j := range commands {
+		for i := range commands {
+			if i == j {
+				continue
+			}
+			dependency := reaches(commands[j].Name, commands[i].Name)
+			if dependency && (i < j || !reaches(commands[i].Name, commands[j].Name)) {
+				waits[j] = append(waits[j], i)
+			}
+		}
+	}
+
+	// Start every ready command in order while there are free slots, then wait for one to finish
+	limit := Jobs(jobs)
+	started := make([]bool, len(commands))
+	finished := make([]bool, len(commands))
+	ready := func(j int) bool {
+		for _, i := range waits[j] {
+			if !finished[i] {
+				return false
+			}
+		}
+		return true
+	}
+	completions := make(chan int)
+	running, remaining := 0, len(commands)
+	for remaining > 0 {
+		for j, command := range commands {
+			if running >= limit {
+				break
+			}
+			if started[j] || !ready(j) {
+				continue
+			}
+			started[j] = true
+			if stop() {
+				skip(command)
+				finished[j] = true
+				remaining--
+				continue
+			}
+			running++
+			go func() {
+				run(command)
+				completions <- j
+			}()
+		}
+		if running == 0 {
+			// Skipped commands may have made others ready
+			continue
+		}
+		j := <-completions
+		running--
+		finished[j] = true
+		remaining--
+	}
+}
+
+// newPrefixedOutput returns writers that write each complete line to stdout and stderr with
+// the command's name as a prefix, and a function that writes any unterminated last line
+func newPrefixedOutput(command string) (io.Writer, io.Writer, func()) {
+	prefix := fmt.Sprintf("[%s] ", command)
+	stdout := &prefixWriter{dst: os.Stdout, prefix: prefix}
+	stderr := &prefixWriter{dst: os.Stderr, prefix: prefix}
+	return stdout, stderr, func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// prefixWriter writes each complete line to dst with a prefix, holding back a partial line
+type prefixWriter struct {
+	dst     io.Writer
+	prefix  string
+	partial []byte
+}
+
+// Write implements io.Writer
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		end := bytes.IndexByte(w.partial, '\n')
+		if end < 0 {
+			break
+		}
+		if err := w.writeLine(w.partial[:end+1]); err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[end+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes any unterminated final line
+func (w *prefixWriter) flush() {
+	if len(w.partial) > 0 {
+		_ = w.writeLine(append(w.partial, '\n'))
+		w.partial = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	_, err := w.dst.Write(append([]byte(w.prefix), line...))
+	return err
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// scheduleCommands schedules the named commands of source, recording when each starts and
+// ends, and returns the records and the most commands that ran at once
+func scheduleCommands(t *testing.T, source string, names []string, jobs int, stop func() bool) ([]string, int) {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var commands []*ast.CommandDecl
+	for _, name := range names {
+		for i := range program.Commands {
+			if program.Commands[i].Name == name {
+				commands = append(commands, &program.Commands[i])
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var records []string
+	running, most := 0, 0
+	record := func(entry string, delta int) {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, entry)
+		running += delta
+		most = max(most, running)
+	}
+	if stop == nil {
+		stop = func() bool { return false }
+	}
+	New(program).Schedule(commands, jobs, func(command *ast.CommandDecl) {
+		record("start "+command.Name, 1)
+		time.Sleep(50 * time.Millisecond)
+		record("end "+command.Name, -1)
+	}, stop, func(command *ast.CommandDecl) {
+		record("skip "+command.Name, 0)
+	})
+	return records, most
+}
+
+// indexOf returns the position of entry in records, or -1
+func indexOf(records []string, entry string) int {
+	for i, record := range records {
+		if record == entry {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSchedule_WaitsForDependencies(t *testing.T) {
+	source := `lint: echo lint
+test: echo test
+build: {
+    @cmd(test)
+    echo build
+}`
+	records, most := scheduleCommands(t, source, []string{"build", "lint", "test"}, 3, nil)
+	if most != 2 {
+		t.Errorf("ran %d commands at once, want lint and test together: %v", most, records)
+	}
+	if indexOf(records, "start build") < indexOf(records, "end test") {
+		t.Errorf("build started before test, which it runs with @cmd, finished: %v", records)
+	}
+}
+
+func TestSchedule_OneJobKeepsOrder(t *testing.T) {
+	source := "a: echo a\nb: echo b\nc: echo c"
+	records, most := scheduleCommands(t, source, []string{"c", "a", "b"}, 1, nil)
+	want := []string{"start c", "end c", "start a", "end a", "start b", "end b"}
+	if strings.Join(records, ", ") != strings.Join(want, ", ") || most != 1 {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
+
+func TestSchedule_StopSkipsUnstarted(t *testing.T) {
+	source := "a: echo a\nb: echo b\nc: echo c"
+	started := 0
+	records, _ := scheduleCommands(t, source, []string{"a", "b", "c"}, 1, func() bool {
+		started++
+		return started > 1
+	})
+	want := []string{"start a", "end a", "skip b", "skip c"}
+	if strings.Join(records, ", ") != strings.Join(want, ", ") {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
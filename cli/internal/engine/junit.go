@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the steps of one command
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one step, or a whole command that failed before its steps or was skipped
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+// junitFailure carries the error of a failed step
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the summary as a JUnit XML report, with one test suite per
+// command and one test case per step, so CI systems can display the run natively
+func (s *RunSummary) WriteJUnit(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := junitTestSuites{Name: "devcmd", Time: junitSeconds(s.DurationMs)}
+	suiteIndex := make(map[string]int)
+	for _, step := range s.Steps {
+		i, exists := suiteIndex[step.Command]
+		if !exists {
+			i = len(report.Suites)
+			suiteIndex[step.Command] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: step.Command, Time: junitSeconds(0)})
+		}
+		suite := &report.Suites[i]
+
+		testCase := junitTestCase{ClassName: step.Command, Name: step.Command, Time: junitSeconds(step.DurationMs)}
+		if step.Step > 0 {
+			testCase.Name = fmt.Sprintf("%d. %s", step.Step, step.Name)
+		}
+		switch step.Status {
+		case "failed":
+			testCase.Failure = &junitFailure{Message: step.Error, Text: step.Error}
+			suite.Failures++
+			report.Failures++
+		case "skipped":
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+			report.Skipped++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+	}
+
+	// Suites take the command's duration, which includes time outside its steps
+	for i := range report.Suites {
+		suite := &report.Suites[i]
+		for _, c := range s.Commands {
+			if c.Name == suite.Name {
+				suite.Time = junitSeconds(c.DurationMs)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitSeconds formats milliseconds as the seconds JUnit time attributes expect
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestSummary_WriteJUnit(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("lint: {\n  echo one\n  echo two\n}\ntest: {\n  echo '<ok>'\n  exit 3\n}\nbuild: echo build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("lint failed: %v", err)
+	}
+	if _, err := eng.ExecuteCommand(&program.Commands[1]); err == nil {
+		t.Fatal("expected test to fail")
+	}
+	summary.Skip("build")
+	summary.Finish(FailOnAny)
+
+	var out bytes.Buffer
+	if err := summary.WriteJUnit(&out); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, out.String())
+	}
+	if report.Tests != 5 || report.Failures != 1 || report.Skipped != 1 || len(report.Suites) != 3 {
+		t.Fatalf("unexpected report totals:\n%s", out.String())
+	}
+
+	testSuite := report.Suites[1]
+	if testSuite.Name != "test" || testSuite.Failures != 1 || len(testSuite.Cases) != 2 {
+		t.Fatalf("unexpected test suite:\n%s", out.String())
+	}
+	if testSuite.Cases[0].Name != "1. echo '<ok>'" {
+		t.Errorf("step name not preserved: %q", testSuite.Cases[0].Name)
+	}
+	if failure := testSuite.Cases[1].Failure; failure == nil || failure.Message != "exit status 3" {
+		t.Errorf("expected failure with exit status, got %+v", failure)
+	}
+	if build := report.Suites[2].Cases[0]; build.Name != "build" || build.Skipped == nil {
+		t.Errorf("expected skipped build case, got %+v", build)
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/aledsdavies/devcmd/runtime/messages"
+)
+
+// UsageTemplate is cobra's usage template with its headings taken from the message catalog,
+// through a msg template function, for devcmd and generated CLIs to show help in the locale
+const UsageTemplate = `{{msg "help.usage"}}{{if .Runnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} {{msg "help.subcommand"}}{{end}}{{if gt (len .Aliases) 0}}
+
+{{msg "help.aliases"}}
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+{{msg "help.examples"}}
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{msg "help.commands"}}{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+{{msg "help.flags"}}
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+{{msg "help.globalFlags"}}
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+{{msg "help.topics"}}{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{printf (msg "help.moreCommand") .CommandPath}}{{end}}
+`
+
+// UsageMessage returns a message of the catalog by ID in the selected locale, as
+// UsageTemplate's msg function
+func UsageMessage(id string) string {
+	return messages.Get(messages.ID(id))
+}
+
+// messagesLiteral returns the catalog of every registered locale as a Go map literal, for
+// generated CLIs to show their messages in the locale they run in
+func messagesLiteral() string {
+	catalogs := make(map[string]map[string]string)
+	for _, locale := range messages.Locales() {
+		catalog := make(map[string]string)
+		for id, message := range messages.Catalog(locale) {
+			catalog[string(id)] = message
+		}
+		catalogs[locale] = catalog
+	}
+	return fmt.Sprintf("%#v", catalogs)
+}
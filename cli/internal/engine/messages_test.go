@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/runtime/messages"
+	"github.com/spf13/cobra"
+)
+
+// helpCommand returns a command with a subcommand, an alias and flags, and its help
+func helpCommand(usageTemplate string) string {
+	root := &cobra.Command{Use: "dev", Short: "Development commands", Run: func(*cobra.Command, []string) {}}
+	root.PersistentFlags().Bool("dry-run", false, "Show what would run")
+	root.AddCommand(&cobra.Command{Use: "build", Aliases: []string{"b"}, Short: "Build it", Run: func(*cobra.Command, []string) {}})
+	if usageTemplate != "" {
+		root.SetUsageTemplate(usageTemplate)
+	}
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"--help"})
+	_ = root.Execute()
+	return out.String()
+}
+
+func TestUsageTemplate(t *testing.T) {
+	defer messages.SetLocale(messages.Locale())
+	cobra.AddTemplateFunc("msg", UsageMessage)
+
+	// In English, help is cobra's own
+	messages.SetLocale("en")
+	if got, want := helpCommand(UsageTemplate), helpCommand(""); got != want {
+		t.Errorf("help =\n%s\nwant cobra's\n%s", got, want)
+	}
+
+	messages.SetLocale(messages.Pseudo)
+	help := helpCommand(UsageTemplate)
+	for _, want := range []string{"[Úšáĝé:]", "[Áṽáíļáƀļé Çóɱɱáñðš:]", `[Úšé "dev [çóɱɱáñð] --ĥéļþ"`} {
+		if !strings.Contains(help, want) {
+			t.Errorf("pseudo-localized help lacks %q:\n%s", want, help)
+		}
+	}
+}
+
+func TestMessagesLiteral(t *testing.T) {
+	literal := messagesLiteral()
+	for _, want := range []string{`"en":map[string]string{`, `"en-XA":map[string]string{`, `"confirm.answers":"y,yes"`} {
+		if !strings.Contains(literal, want) {
+			t.Errorf("messagesLiteral() lacks %s", want)
+		}
+	}
+}
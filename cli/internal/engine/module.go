@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ModuleOptions controls how WriteModule completes the module of a generated CLI
+type ModuleOptions struct {
+	// Vendor copies the module's dependencies into a vendor directory, so it builds
+	// without the module cache or network
+	Vendor bool
+	// Offline resolves dependencies from the local module cache only, never downloading them
+	Offline bool
+}
+
+// Env returns the environment go commands run in for a generated module: outside any go.work
+// of the directory devcmd runs from and, offline, with downloads turned off
+func (o ModuleOptions) Env() []string {
+	env := append(os.Environ(), "GOWORK=off")
+	if o.Offline {
+		env = append(env, "GOPROXY=off", "GOFLAGS=-mod=mod")
+	}
+	return env
+}
+
+// WriteModule writes the generated CLI to targetDir as a complete module that go build
+// builds as is: main.go, go.mod with the versions its decorators require, go.sum with the
+// checksums of every dependency and, with Vendor, a vendor directory holding them
+func (e *Engine) WriteModule(result *GenerationResult, targetDir string, moduleName string, opts ModuleOptions) error {
+	if err := e.WriteFiles(result, targetDir, moduleName); err != nil {
+		return err
+	}
+
+	// tidy adds the dependencies of the required modules to go.mod and writes go.sum
+	steps := [][]string{{"mod", "tidy"}}
+	if opts.Vendor {
+		steps = append(steps, []string{"mod", "vendor"})
+	}
+	for _, args := range steps {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = targetDir
+		cmd.Env = opts.Env()
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if opts.Offline {
+				return fmt.Errorf("go %s failed using only the local module cache; build once without --offline to fill it: %w\n%s",
+					strings.Join(args, " "), err, output)
+			}
+			return fmt.Errorf("go %s failed: %w\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestWriteModule(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`build: echo "Building..."`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	result, err := eng.GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := eng.WriteModule(result, dir, "testcli", ModuleOptions{}); err != nil {
+		t.Fatalf("WriteModule failed: %v", err)
+	}
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatalf("reading go.mod: %v", err)
+	}
+	if !strings.Contains(string(goMod), cobraPackage+" "+cobraVersion) || !strings.Contains(string(goMod), "// indirect") {
+		t.Errorf("go.mod should pin cobra %s and its indirect dependencies:\n%s", cobraVersion, goMod)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.sum")); err != nil {
+		t.Errorf("WriteModule didn't write go.sum: %v", err)
+	}
+
+	// With the module cache filled, the module can be written offline and vendored
+	dir = t.TempDir()
+	opts := ModuleOptions{Vendor: true, Offline: true}
+	if err := eng.WriteModule(result, dir, "testcli", opts); err != nil {
+		t.Fatalf("WriteModule offline failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt")); err != nil {
+		t.Errorf("WriteModule didn't vendor the dependencies: %v", err)
+	}
+
+	build := exec.Command("go", "build", "-o", filepath.Join(dir, "testcli"), ".")
+	build.Dir = dir
+	build.Env = append(opts.Env(), "GOFLAGS=-mod=vendor")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building the vendored module failed: %v\n%s", err, output)
+	}
+}
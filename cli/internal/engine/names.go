@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// mainLocals are the variables the main function of generated CLIs declares next to the
+// functions of its commands
+var mainLocals = []string{
+	"ctx", "dryRun", "err", "forceRestart", "logFormat", "noColor", "noOpen", "rootCmd",
+	"stopAllProcesses", "stopAllProcessesCmd", "workingDir",
+}
+
+var (
+	topLevelDecl   = regexp.MustCompile(`(?m)^(?:func|var|const|type) (\w+)`)
+	topLevelGroup  = regexp.MustCompile(`(?ms)^var \(\n(.*?)^\)`)
+	groupedVarName = regexp.MustCompile(`(?m)^\t(\w+)`)
+)
+
+// commandIdentifiers maps each command and watch command to the Go identifier the Go backend
+// names it by. The identifier derives from the name, as in execution.CommandIdentifier, but
+// names can give the same one: build-all and build_all both become buildAll, and a name like
+// go, string or ctx would collide with Go itself or with the generated code around the
+// commands. Commands claim their identifiers in file order, and a later command whose
+// identifier is taken gets the first free one with a suffix, buildAll_2, which no name
+// derives. Help text, completion and the generated functions all take names from this
+// mapping. A command and a watch command of the same name would both be one command of the
+// CLI, which is an error.
+func (e *Engine) commandIdentifiers(analysis *Analysis) (map[string]string, error) {
+	taken, err := e.reservedIdentifiers(analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each command claims its identifier and those derived from it
+	type claim struct {
+		command  *ast.CommandDecl
+		name     string
+		suffixes []string
+		execute  bool // Claims execute + the identifier, title-cased, for @cmd and needs
+	}
+	var claims []claim
+	for _, command := range analysis.Commands {
+		claims = append(claims, claim{command, command.Name, []string{"", "Cmd"}, true})
+	}
+	for _, group := range analysis.Groups.ProcessGroups {
+		command := group.WatchCommand
+		if command == nil {
+			command = group.StopCommand
+		}
+		suffixes := []string{"", "Cmd", "Run", "RunCmd", "Stop", "StopCmd", "Status", "StatusCmd", "Logs", "LogsCmd"}
+		claims = append(claims, claim{command, group.Identifier, suffixes, false})
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		a, b := claims[i].command.Pos, claims[j].command.Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	identifiers := make(map[string]string)
+	owners := make(map[string]*ast.CommandDecl)
+	for _, c := range claims {
+		if other := owners[c.name]; other != nil {
+			return nil, fmt.Errorf("%s '%s' at line %d, column %d and %s '%s' at line %d, column %d are both the command %s of the generated CLI; rename one of them",
+				commandKind(other.Type), other.Name, other.Pos.Line, other.Pos.Column,
+				commandKind(c.command.Type), c.command.Name, c.command.Pos.Line, c.command.Pos.Column, c.name)
+		}
+		owners[c.name] = c.command
+
+		base := execution.CommandIdentifier(c.name)
+		for n := 1; ; n++ {
+			identifier := base
+			if n > 1 {
+				identifier = fmt.Sprintf("%s_%d", base, n)
+			}
+			claimed := claimedIdentifiers(identifier, c.suffixes, c.execute)
+			if !anyTaken(claimed, taken) {
+				for _, name := range claimed {
+					taken[name] = true
+				}
+				identifiers[c.name] = identifier
+				break
+			}
+		}
+	}
+	return identifiers, nil
+}
+
+// claimedIdentifiers returns the identifiers generated code declares for a command named by
+// identifier
+func claimedIdentifiers(identifier string, suffixes []string, execute bool) []string {
+	var claimed []string
+	for _, suffix := range suffixes {
+		claimed = append(claimed, identifier+suffix)
+	}
+	if execute {
+		claimed = append(claimed, "execute"+capitalizeFirst(identifier))
+	}
+	return claimed
+}
+
+func anyTaken(identifiers []string, taken map[string]bool) bool {
+	for _, identifier := range identifiers {
+		if taken[identifier] || token.IsKeyword(identifier) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedIdentifiers returns the identifiers commands can't take in generated code: Go's
+// predeclared names that generated code uses, the packages the CLI imports, what the template and its helpers declare
+// at the top level, the locals of main and the program's variables, which main declares as
+// constants
+func (e *Engine) reservedIdentifiers(analysis *Analysis) (map[string]bool, error) {
+	reserved := make(map[string]bool)
+	for _, name := range types.Universe.Names() {
+		// Generated code uses Go's types and constants, but of its builtin functions only
+		// these, so commands can still be called clear, copy or print
+		switch types.Universe.Lookup(name).(type) {
+		case *types.Builtin:
+			reserved[name] = name == "append" || name == "len" || name == "make"
+		default:
+			reserved[name] = true
+		}
+	}
+	features, err := e.Features(analysis.Program)
+	if err != nil {
+		return nil, err
+	}
+	helpers := append([]generatedHelper(nil), generatedHelpers...)
+	for _, feature := range features {
+		for _, pkg := range feature.Imports {
+			reserved[path.Base(pkg)] = true
+		}
+		helpers = append(helpers, feature.helpers...)
+	}
+	reserved["execpkg"] = true // os/exec is imported as execpkg, as exec runs shell steps
+	sources := []string{mainCLITemplate}
+	for _, helper := range helpers {
+		sources = append(sources, helper.Code)
+	}
+	for _, source := range sources {
+		for _, match := range topLevelDecl.FindAllStringSubmatch(source, -1) {
+			reserved[match[1]] = true
+		}
+		for _, group := range topLevelGroup.FindAllStringSubmatch(source, -1) {
+			for _, match := range groupedVarName.FindAllStringSubmatch(group[1], -1) {
+				reserved[match[1]] = true
+			}
+		}
+	}
+	delete(reserved, "main") // Nothing calls main, so a command can share its name
+	for _, name := range mainLocals {
+		reserved[name] = true
+	}
+	for _, variable := range analysis.Variables {
+		reserved[variable.Name] = true
+	}
+	return reserved, nil
+}
+
+// commandKind names a kind of command in errors
+func commandKind(commandType ast.CommandType) string {
+	switch commandType {
+	case ast.WatchCommand:
+		return "watch command"
+	case ast.StopCommand:
+		return "stop command"
+	default:
+		return "command"
+	}
+}
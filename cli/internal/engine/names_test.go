@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestCommandIdentifiers(t *testing.T) {
+	for source, want := range map[string]map[string]string{
+		"build-all: echo a\nbuild_all: echo b\nbuildAll: echo c":  {"build-all": "buildAll", "build_all": "buildAll_2", "buildAll": "buildAll_3"},
+		"build: echo a\nBuild: echo b":                            {"build": "build", "Build": "Build_2"},
+		"go: go build\nctx: echo context\nfmt: gofmt -l .":        {"go": "go_2", "ctx": "ctx_2", "fmt": "fmt_2"},
+		"var deploy = \"x\"\ndeploy: echo @var(deploy)":           {"deploy": "deploy_2"},
+		"watch api: air\nstop api: pkill air\napi-run: echo":      {"api": "api", "api-run": "apiRun_2"},
+		"main: echo main\nclear: echo clear\nstatus: echo status": {"main": "main", "clear": "clear", "status": "status"},
+	} {
+		program, err := parser.Parse(strings.NewReader(source))
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", source, err)
+		}
+		e := New(program)
+		analysis, err := e.Analyze(program)
+		if err != nil {
+			t.Fatalf("Analyze(%q) failed: %v", source, err)
+		}
+		got, err := e.commandIdentifiers(analysis)
+		if err != nil {
+			t.Errorf("commandIdentifiers(%q) failed: %v", source, err)
+			continue
+		}
+		for name, identifier := range want {
+			if got[name] != identifier {
+				t.Errorf("commandIdentifiers(%q)[%s] = %q, want %q", source, name, got[name], identifier)
+			}
+		}
+	}
+}
+
+func TestCommandIdentifiers_Generated(t *testing.T) {
+	source := "build-all: echo a\nbuild_all: echo b\nall: needs(build-all) {\n  @cmd(build_all)\n}"
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	code := result.String()
+	for _, want := range []string{"executeBuildAll := func", "executeBuildAll_2 := func", "return executeBuildAll(ctx)", "executeBuildAll_2(ctx)", "buildAll_2Cmd := &cobra.Command{"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code doesn't contain %q", want)
+		}
+	}
+}
+
+func TestCommandIdentifiers_SameCommand(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("dev: go build\nwatch dev: air"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = New(program).GenerateCode(program)
+	want := "command 'dev' at line 1, column 1 and watch command 'dev' at line 2, column 1 are both the command dev of the generated CLI"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("GenerateCode error = %v, want one containing %q", err, want)
+	}
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// commandRun is a run of a command in this invocation, which the commands needing it wait on
+type commandRun struct {
+	done chan struct{}
+	err  error
+}
+
+// runs holds the first run of each command in an invocation, so a command that several
+// commands need runs once
+type runs struct {
+	mu   sync.Mutex
+	runs map[string]*commandRun
+}
+
+// claim returns the first run of the named command, and whether the caller starts it and
+// must finish it
+func (r *runs) claim(name string) (*commandRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if run, ok := r.runs[name]; ok {
+		return run, false
+	}
+	if r.runs == nil {
+		r.runs = make(map[string]*commandRun)
+	}
+	run := &commandRun{done: make(chan struct{})}
+	r.runs[name] = run
+	return run, true
+}
+
+// finish records how a claimed run ended and wakes the commands waiting on it
+func (run *commandRun) finish(err error) {
+	run.err = err
+	close(run.done)
+}
+
+// neededCommand returns the regular command of program that command needs by name, which a
+// command with parameters must be able to run with their defaults
+func neededCommand(program *ast.Program, command *ast.CommandDecl, name string) (*ast.CommandDecl, error) {
+	var watched bool
+	for i := range program.Commands {
+		needed := &program.Commands[i]
+		if needed.Name != name {
+			continue
+		}
+		if needed.Type != ast.Command {
+			watched = true
+			continue
+		}
+		for _, param := range needed.Params {
+			if param.Required() {
+				return nil, fmt.Errorf("command '%s' needs '%s', whose parameter %s has no default", command.Name, name, param.Name)
+			}
+		}
+		return needed, nil
+	}
+	if watched {
+		return nil, fmt.Errorf("command '%s' needs '%s', which is a watch command; only regular commands can be needed", command.Name, name)
+	}
+	return nil, fmt.Errorf("command '%s' needs non-existent command '%s'", command.Name, name)
+}
+
+// validateNeeds checks that every command a command needs can run as a need
+func validateNeeds(program *ast.Program) error {
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		for _, name := range command.NeedNames() {
+			if _, err := neededCommand(program, command, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runNeeds runs the commands command needs, in the order declared, skipping those that already
+// ran in this invocation. A need that is still running, as when commands run with --jobs, is
+// waited for, and a need that failed fails every command needing it.
+func (e *Engine) runNeeds(command *ast.CommandDecl) error {
+	for _, name := range command.NeedNames() {
+		needed, err := neededCommand(e.program, command, name)
+		if err != nil {
+			return err
+		}
+		run, first := e.runs.claim(name)
+		if first {
+			_, err := e.executeCommand(needed)
+			run.finish(err)
+		}
+		<-run.done
+		if run.err != nil {
+			return fmt.Errorf("%s needs %s, which failed: %w", command.Name, name, run.err)
+		}
+	}
+	return nil
+}
+
+// needsPlan returns the plan element of the commands command needs: each need with the needs
+// it brings in nested under it, and needs reached before marked as already run
+func (e *Engine) needsPlan(ctx execution.PlanContext, command *ast.CommandDecl) (plan.PlanElement, error) {
+	if len(command.Needs) == 0 {
+		return nil, nil
+	}
+	planned := make(map[string]bool)
+	var needs func(command *ast.CommandDecl) ([]plan.PlanElement, error)
+	needs = func(command *ast.CommandDecl) ([]plan.PlanElement, error) {
+		var elements []plan.PlanElement
+		for _, name := range command.NeedNames() {
+			needed, err := neededCommand(e.program, command, name)
+			if err != nil {
+				return nil, err
+			}
+			element := plan.Sequence().WithDescription(name)
+			if planned[name] {
+				elements = append(elements, element.WithDescription(name+" (already run)"))
+				continue
+			}
+			planned[name] = true
+
+			children, err := needs(needed)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				element.AddChild(child)
+			}
+			stepCtx := ctx.WithParams(paramValues(needed, nil))
+			for _, content := range needed.Body.Content {
+				child, err := e.contentPlan(stepCtx, content)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				if child != nil {
+					element.AddChild(child)
+				}
+			}
+			elements = append(elements, element)
+		}
+		return elements, nil
+	}
+
+	children, err := needs(command)
+	if err != nil {
+		return nil, err
+	}
+	element := plan.Sequence().WithDescription("needs " + strings.Join(command.NeedNames(), ", "))
+	for _, child := range children {
+		element.AddChild(child)
+	}
+	return element, nil
+}
+
+// generateNeeds returns the code an execute function starts with to run the commands command
+// needs through runNeed, so each runs once per invocation, with the defaults of its parameters.
+// identifiers are the Go identifiers of the commands, from commandIdentifiers.
+func generateNeeds(program *ast.Program, command *ast.CommandDecl, identifiers map[string]string) (string, error) {
+	var code strings.Builder
+	for _, name := range command.NeedNames() {
+		needed, err := neededCommand(program, command, name)
+		if err != nil {
+			return "", err
+		}
+		needCtx := "ctx"
+		if len(needed.Params) > 0 {
+			var defaults []string
+			for _, param := range needed.Params {
+				defaults = append(defaults, fmt.Sprintf("%q: %q", param.Name, param.Default.String()))
+			}
+			needCtx = "ctx.WithParams(map[string]string{" + strings.Join(defaults, ", ") + "})"
+		}
+		fmt.Fprintf(&code, "if err := runNeed(%q, func() error { return execute%s(%s) }); err != nil {\n", name, capitalizeFirst(identifiers[name]), needCtx)
+		fmt.Fprintf(&code, "\t\t\treturn fmt.Errorf(%q, err)\n\t\t}\n\t\t", command.Name+" needs "+name+", which failed: %w")
+	}
+	return code.String(), nil
+}
+
+// runNeedHelper runs each command that others need once per invocation of a generated CLI
+const runNeedHelper = `
+// needRuns holds the first run of each command other commands need
+var (
+	needRunsMu sync.Mutex
+	needRuns   = map[string]*needRun{}
+)
+
+// needRun is a run of a needed command, which later commands needing it share
+type needRun struct {
+	once sync.Once
+	err  error
+}
+
+// runNeed runs a command another command needs, unless it already ran in this invocation
+func runNeed(name string, execute func() error) error {
+	needRunsMu.Lock()
+	run, ok := needRuns[name]
+	if !ok {
+		run = &needRun{}
+		needRuns[name] = run
+	}
+	needRunsMu.Unlock()
+	run.once.Do(func() { run.err = execute() })
+	return run.err
+}
+`
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestNeeds_RunOncePerInvocation(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	source := `build: echo build >> ` + log + `
+test: needs(build) echo test >> ` + log + `
+deploy: needs(build, test) echo deploy >> ` + log + `
+release: @cmd(deploy)`
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	for _, name := range []string{"build", "deploy", "release"} {
+		if _, err := eng.ExecuteCommand(findCommand(program, name)); err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("reading log: %v", err)
+	}
+	// build ran before deploy needed it, and release runs deploy's body through @cmd
+	if got, want := strings.Join(strings.Fields(string(data)), " "), "build test deploy deploy"; got != want {
+		t.Errorf("ran %s, want %s", got, want)
+	}
+}
+
+func TestNeeds_FailedNeed(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	source := `broken: exit 3
+deploy: needs(broken) echo deploy >> ` + log
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	_, err = New(program).ExecuteCommand(findCommand(program, "deploy"))
+	if err == nil || !strings.Contains(err.Error(), "deploy needs broken, which failed") {
+		t.Errorf("err = %v, want the failed need", err)
+	}
+	if _, statErr := os.Stat(log); statErr == nil {
+		t.Error("deploy ran after the command it needs failed")
+	}
+}
+
+func TestNeeds_Plan(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`build: go build
+test: needs(build) go test
+deploy: needs(build, test) kubectl apply -f k8s`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	execPlan, err := New(program).ExecuteCommandPlan(findCommand(program, "deploy"))
+	if err != nil {
+		t.Fatalf("ExecuteCommandPlan failed: %v", err)
+	}
+	output := execPlan.StringNoColor()
+	for _, want := range []string{"needs build, test", "go build", "build (already run)", "go test", "kubectl apply -f k8s"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("plan does not show %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "go build") != 1 {
+		t.Errorf("plan shows build more than once:\n%s", output)
+	}
+}
+
+func TestNeeds_GenerateCode(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`build(target: string = "linux"): go build
+deploy: needs(build) kubectl apply -f k8s`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	code := result.Code.String()
+	for _, want := range []string{
+		`runNeed("build", func() error { return executeBuild(ctx.WithParams(map[string]string{"target": "linux"})) })`,
+		"func runNeed(name string, execute func() error) error",
+		`"sync"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code does not contain %s", want)
+		}
+	}
+	if strings.Index(code, "executeBuild :=") > strings.Index(code, "executeDeploy :=") {
+		t.Error("deploy is declared before the build it needs")
+	}
+}
+
+func TestNeeds_Validation(t *testing.T) {
+	for source, want := range map[string]string{
+		"deploy: needs(missing) echo":                                "command 'deploy' needs non-existent command 'missing'",
+		"watch api: go run .\ndeploy: needs(api) echo":               "which is a watch command",
+		"build(target: string): go build\ndeploy: needs(build) echo": "whose parameter target has no default",
+	} {
+		program, err := parser.Parse(strings.NewReader(source))
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", source, err)
+		}
+		if _, err := New(program).GenerateCode(program); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("GenerateCode(%q) error = %v, want one containing %q", source, err, want)
+		}
+	}
+}
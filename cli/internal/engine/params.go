@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// SetParams sets the parameter values interpreted commands run with, by parameter name.
+// Each command takes the values of the parameters it declares and the defaults of the rest.
+func (e *Engine) SetParams(values map[string]string) {
+	e.params = values
+}
+
+// UnusedParams returns the names of values that none of commands declares as a parameter
+func UnusedParams(commands []*ast.CommandDecl, values map[string]string) []string {
+	var unused []string
+	for name := range values {
+		declared := false
+		for _, command := range commands {
+			if command.FindParam(name) != nil {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// ResolveParams returns the values command runs with: those given for its parameters and
+// the defaults of the rest. Every parameter without a default must be given, and each value
+// must be of its parameter's type; booleans are normalized to true or false.
+func ResolveParams(command *ast.CommandDecl, values map[string]string) (map[string]string, error) {
+	resolved := paramValues(command, values)
+	var missing []string
+	for _, param := range command.Params {
+		value, ok := resolved[param.Name]
+		if !ok {
+			missing = append(missing, param.Name)
+			continue
+		}
+		normalized, err := checkParamValue(param, value)
+		if err != nil {
+			return nil, err
+		}
+		resolved[param.Name] = normalized
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing value for parameter %s of %s", strings.Join(missing, ", "), command.Name)
+	}
+	return resolved, nil
+}
+
+// ArgParams returns values with args given to command's parameters without a default, in
+// order, skipping those values already sets, as generated CLIs take a command's arguments
+func ArgParams(command *ast.CommandDecl, args []string, values map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(values)+len(args))
+	for name, value := range values {
+		merged[name] = value
+	}
+	for _, param := range command.Params {
+		if len(args) == 0 {
+			break
+		}
+		if _, ok := merged[param.Name]; ok || !param.Required() {
+			continue
+		}
+		merged[param.Name], args = args[0], args[1:]
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("unexpected argument %q: %s has no more parameters without a default", args[0], command.Name)
+	}
+	return merged, nil
+}
+
+// paramValues returns the values given for command's parameters and the defaults of the
+// rest, leaving out required parameters that weren't given, as dry runs show them
+func paramValues(command *ast.CommandDecl, values map[string]string) map[string]string {
+	resolved := make(map[string]string, len(command.Params))
+	for _, param := range command.Params {
+		if value, ok := values[param.Name]; ok {
+			resolved[param.Name] = value
+		} else if !param.Required() {
+			resolved[param.Name] = param.Default.String()
+		}
+	}
+	return resolved
+}
+
+// checkParamValue checks value against the type of param, returning it as the command sees it
+func checkParamValue(param ast.CommandParam, value string) (string, error) {
+	switch param.Type {
+	case ast.NumberType:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("parameter %s must be a number, got %q", param.Name, value)
+		}
+	case ast.BooleanType:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s must be true or false, got %q", param.Name, value)
+		}
+		return strconv.FormatBool(b), nil
+	}
+	return value, nil
+}
+
+// paramData describes a command parameter to the CLI template
+type paramData struct {
+	Name     string
+	Type     string // "string", "number" or "boolean"
+	Default  string
+	Required bool
+}
+
+// commandParamData returns the template data of command's parameters
+func commandParamData(command *ast.CommandDecl) []paramData {
+	var params []paramData
+	for _, param := range command.Params {
+		data := paramData{Name: param.Name, Type: param.Type.String(), Required: param.Required()}
+		if !data.Required {
+			data.Default = param.Default.String()
+		}
+		params = append(params, data)
+	}
+	return params
+}
+
+// commandUse returns the cobra Use line of a command, naming the parameters without a
+// default that may be given as arguments in order, e.g. "deploy [env]"
+func commandUse(command *ast.CommandDecl) string {
+	use := command.Name
+	for _, param := range command.Params {
+		if param.Required() {
+			use += " [" + param.Name + "]"
+		}
+	}
+	return use
+}
+
+// commandParamsHelper is called by commands with parameters to read them from their flags
+// and arguments, checked as ResolveParams checks them for interpreted commands
+const commandParamsHelper = `
+// commandParam is a typed parameter a command declares
+type commandParam struct {
+	Name     string
+	Type     string // "string", "number" or "boolean"
+	Default  string
+	Required bool
+}
+
+// commandParams returns the values a command runs with from its flags and the defaults of
+// its parameters. Parameters without a default may instead be given as arguments, in order.
+func commandParams(cmd *cobra.Command, args []string, params []commandParam) (map[string]string, error) {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		value := param.Default
+		if flag := cmd.Flags().Lookup(param.Name); flag != nil && flag.Changed {
+			value = flag.Value.String()
+		} else if param.Required {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("missing value for parameter %s: pass --%s or give it as an argument", param.Name, param.Name)
+			}
+			value, args = args[0], args[1:]
+		}
+		switch param.Type {
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("parameter %s must be a number, got %q", param.Name, value)
+			}
+		case "boolean":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s must be true or false, got %q", param.Name, value)
+			}
+			value = strconv.FormatBool(b)
+		}
+		values[param.Name] = value
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", args[0])
+	}
+	return values, nil
+}
+`
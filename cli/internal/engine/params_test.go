@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// paramCommands declares parameters of each type and runs greet, with its default, with @cmd
+const paramCommands = `deploy(env: string, replicas: number = 3, dry: boolean = false): echo "@param(env) @param(replicas) @param(dry)" > deployed.txt
+greet(name: string = "world wide"): echo hello @param(name) > greeted.txt
+all: @cmd(greet)`
+
+func TestResolveParams(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(paramCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	deploy := &program.Commands[0]
+
+	testCases := []struct {
+		name    string
+		values  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:   "defaults fill in the parameters that aren't given",
+			values: map[string]string{"env": "prod"},
+			want:   map[string]string{"env": "prod", "replicas": "3", "dry": "false"},
+		},
+		{
+			name:   "booleans are normalized",
+			values: map[string]string{"env": "prod", "replicas": "2.5", "dry": "1"},
+			want:   map[string]string{"env": "prod", "replicas": "2.5", "dry": "true"},
+		},
+		{
+			name:    "a parameter without a default must be given",
+			values:  map[string]string{"replicas": "2"},
+			wantErr: "missing value for parameter env of deploy",
+		},
+		{
+			name:    "numbers are checked",
+			values:  map[string]string{"env": "prod", "replicas": "many"},
+			wantErr: `parameter replicas must be a number, got "many"`,
+		},
+		{
+			name:    "booleans are checked",
+			values:  map[string]string{"env": "prod", "dry": "maybe"},
+			wantErr: `parameter dry must be true or false, got "maybe"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveParams(deploy, tc.values)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ResolveParams error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveParams failed: %v", err)
+			}
+			for name, value := range tc.want {
+				if got[name] != value {
+					t.Errorf("%s = %q, want %q", name, got[name], value)
+				}
+			}
+		})
+	}
+
+	commands := []*ast.CommandDecl{deploy, &program.Commands[1]}
+	if unused := UnusedParams(commands, map[string]string{"env": "prod", "name": "x", "region": "eu"}); strings.Join(unused, ",") != "region" {
+		t.Errorf("UnusedParams = %v, want region", unused)
+	}
+}
+
+func TestArgParams(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(paramCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	deploy := &program.Commands[0]
+
+	// Arguments go to the parameters without a default, like the generated CLI's
+	got, err := ArgParams(deploy, []string{"prod"}, map[string]string{"replicas": "2"})
+	if err != nil || got["env"] != "prod" || got["replicas"] != "2" || len(got) != 2 {
+		t.Errorf("ArgParams = %v, %v, want env=prod and replicas=2", got, err)
+	}
+	if _, err := ArgParams(deploy, []string{"prod", "5"}, nil); err == nil || !strings.Contains(err.Error(), `unexpected argument "5"`) {
+		t.Errorf("extra argument: err = %v", err)
+	}
+	if _, err := ArgParams(deploy, []string{"prod"}, map[string]string{"env": "dev"}); err == nil {
+		t.Error("an argument for a parameter --param set should be unexpected")
+	}
+}
+
+func TestExecuteCommand_Params(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	program, err := parser.Parse(strings.NewReader(paramCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	eng.SetParams(map[string]string{"env": "it's prod", "dry": "true"})
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	// @cmd runs greet with its default
+	if _, err := eng.ExecuteCommand(&program.Commands[2]); err != nil {
+		t.Fatalf("all failed: %v", err)
+	}
+
+	for file, want := range map[string]string{"deployed.txt": "it's prod 3 true\n", "greeted.txt": "hello world wide\n"} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", file, data, want)
+		}
+	}
+}
+
+func TestGenerateCode_CmdNeedsParamDefaults(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`deploy(env: string): echo @param(env)
+all: @cmd(deploy)`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = New(program).GenerateCode(program)
+	if err == nil || !strings.Contains(err.Error(), "its parameter env has no default") {
+		t.Errorf("GenerateCode error = %v, want one about env having no default", err)
+	}
+}
+
+func TestGeneratedCliParams(t *testing.T) {
+	binaryPath := buildTestCLI(t, paramCommands)
+
+	run := func(args ...string) (string, error) {
+		dir := t.TempDir()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return string(output), err
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "deployed.txt"))
+		return string(data), nil
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"deploy", "prod"}, "prod 3 false\n"},
+		{[]string{"deploy", "--env", "it's prod", "--replicas", "5", "--dry"}, "it's prod 5 true\n"},
+	} {
+		output, err := run(tc.args...)
+		if err != nil {
+			t.Fatalf("%v failed: %v\n%s", tc.args, err, output)
+		}
+		if output != tc.want {
+			t.Errorf("%v wrote %q, want %q", tc.args, output, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"deploy"}, "missing value for parameter env: pass --env or give it as an argument"},
+		{[]string{"deploy", "prod", "--replicas", "many"}, `parameter replicas must be a number, got "many"`},
+		{[]string{"deploy", "prod", "extra"}, `unexpected argument "extra"`},
+	} {
+		output, err := run(tc.args...)
+		if err == nil || !strings.Contains(output, tc.want) {
+			t.Errorf("%v = %v with output %q, want it to fail with %q", tc.args, err, output, tc.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// preflightSteps returns the top-level steps of a command whose decorators assert preconditions.
+// Assertions nested in other blocks are checked when they run, since enclosing decorators such
+// as @workdir or @go change what they would see.
+func preflightSteps(content []ast.CommandContent) []*ast.BlockDecorator {
+	var steps []*ast.BlockDecorator
+	for _, item := range content {
+		block, ok := item.(*ast.BlockDecorator)
+		if !ok {
+			continue
+		}
+		if decorator, err := decorators.GetBlock(block.Name); err == nil {
+			if _, ok := decorator.(decorators.PreflightChecker); ok {
+				steps = append(steps, block)
+			}
+		}
+	}
+	return steps
+}
+
+// requiredVariable is an environment variable that a command, or a command it runs with
+// @cmd, reads with a required @env
+type requiredVariable struct {
+	Key        string
+	AllowEmpty bool     // Every reference allows an empty value
+	Commands   []string // The commands reading it, in the order they run
+}
+
+// requiredEnv returns the variables a command needs set before it runs, in order of first use
+func (e *Engine) requiredEnv(command *ast.CommandDecl) []requiredVariable {
+	var required []requiredVariable
+	index := make(map[string]int)
+	for _, ref := range envReferences(e.commandClosure(command)) {
+		if !ref.Required {
+			continue
+		}
+		i, seen := index[ref.Key]
+		if !seen {
+			i = len(required)
+			index[ref.Key] = i
+			required = append(required, requiredVariable{Key: ref.Key, AllowEmpty: true})
+		}
+		variable := &required[i]
+		variable.AllowEmpty = variable.AllowEmpty && ref.AllowEmpty
+		if !containsName(variable.Commands, ref.Command) {
+			variable.Commands = append(variable.Commands, ref.Command)
+		}
+	}
+	return required
+}
+
+// failure describes the variable missing, as unset or empty
+func (v requiredVariable) failure(state string) string {
+	return fmt.Sprintf("environment variable %s is required by %s but is %s", v.Key, joinNames(v.Commands), state)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// preflight checks the environment variables a command requires and the assertions of its
+// top-level steps before any step runs, reporting every unmet one together
+func (e *Engine) preflight(ctx execution.InterpreterContext, command *ast.CommandDecl) error {
+	var failures []string
+	for _, variable := range e.requiredEnv(command) {
+		value, exists := ctx.GetEnv(variable.Key)
+		switch {
+		case !exists:
+			failures = append(failures, variable.failure("unset"))
+		case value == "" && !variable.AllowEmpty:
+			failures = append(failures, variable.failure("empty"))
+		}
+	}
+	for _, step := range preflightSteps(command.Body.Content) {
+		decorator, _ := decorators.GetBlock(step.Name)
+		for _, err := range decorator.(decorators.PreflightChecker).PreflightInterpreter(ctx, step.Args) {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pre-flight checks failed:\n  %s", strings.Join(failures, "\n  "))
+}
+
+// generatePreflight generates code that checks the environment variables a command requires
+// and the assertions of its top-level steps before any step runs. It mirrors preflight and
+// returns "" when there is nothing to check.
+func (e *Engine) generatePreflight(ctx execution.GeneratorContext, command *ast.CommandDecl) (string, error) {
+	required := e.requiredEnv(command)
+	steps := preflightSteps(command.Body.Content)
+	if len(required) == 0 && len(steps) == 0 {
+		return "", nil
+	}
+
+	var checks strings.Builder
+	for _, variable := range required {
+		// ctx.Env holds what devcmd sets, such as settings defaults, over the caller's environment
+		value, empty := "val", ""
+		if variable.AllowEmpty {
+			value = "_"
+		} else {
+			empty = fmt.Sprintf(" else if val == \"\" {\nfailures = append(failures, %q)\n}", variable.failure("empty"))
+		}
+		fmt.Fprintf(&checks, "// Pre-flight: requires environment variable %[1]s\n{\n%[2]s, exists := ctx.Env[%[1]q]\nif !exists {\n%[2]s, exists = os.LookupEnv(%[1]q)\n}\nif !exists {\nfailures = append(failures, %[3]q)\n}%[4]s\n}\n",
+			variable.Key, value, variable.failure("unset"), empty)
+	}
+	for _, step := range steps {
+		decorator, _ := decorators.GetBlock(step.Name)
+		templateResult, err := decorator.(decorators.PreflightChecker).PreflightTemplate(ctx, step.Args)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate pre-flight check for @%s: %w", step.Name, err)
+		}
+		code, err := ctx.ExecuteTemplate(templateResult)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute pre-flight template for @%s: %w", step.Name, err)
+		}
+		checks.WriteString(code)
+		checks.WriteString("\n")
+	}
+
+	return fmt.Sprintf("{\nvar failures []string\n%sif len(failures) > 0 {\nreturn fmt.Errorf(\"pre-flight checks failed:\\n  %%s\", strings.Join(failures, \"\\n  \"))\n}\n}\n", checks.String()), nil
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+// preflightCommands runs a step before two @requires blocks that can't be satisfied
+const preflightCommands = `ci: {
+    echo started > started.txt
+    @requires(tools = "devcmd-missing-tool", fallback = false) {
+        echo lint
+    }
+    @requires(diskFree = "1000000T", path = "./build") {
+        echo build
+    }
+}`
+
+func TestPreflight_ReportsAllFailuresBeforeRunning(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	program, err := parser.Parse(strings.NewReader(preflightCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	_, err = eng.ExecuteCommand(&program.Commands[0])
+	if err == nil {
+		t.Fatal("expected pre-flight checks to fail")
+	}
+
+	for _, want := range []string{
+		"pre-flight checks failed:",
+		"@requires: devcmd-missing-tool not found in PATH",
+		"@requires: ./build has ",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "started.txt")); statErr == nil {
+		t.Error("first step ran before the pre-flight checks failed")
+	}
+}
+
+func TestPreflight_NestedAssertionsRunInPlace(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// The tool is only on PATH once the first step has created it
+	program, err := parser.Parse(strings.NewReader(`setup: {
+    mkdir -p bin && printf '#!/bin/sh\necho hi\n' > bin/devcmd-made-tool && chmod +x bin/devcmd-made-tool
+    @timeout(10s) {
+        @requires(tools = "devcmd-made-tool", fallback = false) {
+            devcmd-made-tool
+        }
+    }
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	t.Setenv("PATH", filepath.Join(dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if _, err := New(program).ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Errorf("nested @requires was checked before the step that satisfies it: %v", err)
+	}
+}
+
+func TestGeneratedCliPreflight(t *testing.T) {
+	binaryPath := buildTestCLI(t, preflightCommands)
+
+	dir := t.TempDir()
+	cmd := exec.Command(binaryPath, "ci")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected pre-flight checks to fail, got:\n%s", output)
+	}
+
+	for _, want := range []string{
+		"pre-flight checks failed:",
+		"@requires: devcmd-missing-tool not found in PATH",
+		"@requires: ./build has ",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("output does not mention %q:\n%s", want, output)
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "started.txt")); statErr == nil {
+		t.Error("first step ran before the pre-flight checks failed")
+	}
+}
+
+// requiredEnvCommands reads required variables in a command and in one it runs with @cmd
+const requiredEnvCommands = `token: echo "token=@env(DEVCMD_TEST_TOKEN, required = true)"
+deploy: {
+    echo started > started.txt
+    @cmd(token)
+    echo "key=@env("DEVCMD_TEST_KEY", default = "dev-key", required = true)"
+}`
+
+func TestPreflight_RequiredEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("DEVCMD_TEST_TOKEN", "")
+	t.Setenv("DEVCMD_TEST_KEY", "")
+	os.Unsetenv("DEVCMD_TEST_KEY")
+
+	program, err := parser.Parse(strings.NewReader(requiredEnvCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	_, err = New(program).ExecuteCommand(&program.Commands[1])
+	if err == nil {
+		t.Fatal("expected pre-flight checks to fail")
+	}
+	for _, want := range []string{
+		"environment variable DEVCMD_TEST_KEY is required by deploy but is unset",
+		"environment variable DEVCMD_TEST_TOKEN is required by token but is empty",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "started.txt")); statErr == nil {
+		t.Error("first step ran before the pre-flight checks failed")
+	}
+}
+
+func TestGeneratedCliPreflight_RequiredEnvironment(t *testing.T) {
+	binaryPath := buildTestCLI(t, requiredEnvCommands)
+
+	dir := t.TempDir()
+	cmd := exec.Command(binaryPath, "deploy")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=", "DEVCMD_TEST_TOKEN=")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected pre-flight checks to fail, got:\n%s", output)
+	}
+	for _, want := range []string{
+		"environment variable DEVCMD_TEST_KEY is required by deploy but is unset",
+		"environment variable DEVCMD_TEST_TOKEN is required by token but is empty",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("output does not mention %q:\n%s", want, output)
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "started.txt")); statErr == nil {
+		t.Error("first step ran before the pre-flight checks failed")
+	}
+
+	// Set, the values come from the caller's environment
+	cmd = exec.Command(binaryPath, "deploy")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GITHUB_ACTIONS=", "GITLAB_CI=", "DEVCMD_TEST_TOKEN=t0k", "DEVCMD_TEST_KEY=real-key")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("deploy failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "token=t0k") || !strings.Contains(string(output), "key=real-key") {
+		t.Errorf("output does not show the caller's values:\n%s", output)
+	}
+}
@@ -1,27 +1,28 @@
 package engine
 
 import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aledsdavies/devcmd/cli/internal/daemon"
 	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
 	"github.com/aledsdavies/devcmd/core/ast"
 )
 
 // TestProcessManagementGeneration tests the generation of process management commands
 func TestProcessManagementGeneration(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		expected struct {
-			hasProcessGroup bool
-			identifier      string
-			hasDefaultRun   bool
-			hasRunSubcmd    bool
-			hasStopSubcmd   bool
-			hasStatusSubcmd bool
-			hasLogsSubcmd   bool
-		}
+		name  string
+		input string
+		want  []string // Lines the generated code must contain
 	}{
 		{
 			name: "watch command only",
@@ -29,22 +30,18 @@ func TestProcessManagementGeneration(t *testing.T) {
 var PROJECT = "test-app"
 watch web: echo "Starting @var(PROJECT) web server"
 			`,
-			expected: struct {
-				hasProcessGroup bool
-				identifier      string
-				hasDefaultRun   bool
-				hasRunSubcmd    bool
-				hasStopSubcmd   bool
-				hasStatusSubcmd bool
-				hasLogsSubcmd   bool
-			}{
-				hasProcessGroup: true,
-				identifier:      "web",
-				hasDefaultRun:   true,
-				hasRunSubcmd:    true,
-				hasStopSubcmd:   true, // Generated with default logic
-				hasStatusSubcmd: true, // Auto-generated
-				hasLogsSubcmd:   true, // Auto-generated
+			want: []string{
+				"webCmd := &cobra.Command{",
+				"Run:   webRun, // Default action is to run",
+				"webRunCmd := &cobra.Command{",
+				"webStopCmd := &cobra.Command{", // Generated with default logic
+				"webStatusCmd := &cobra.Command{",
+				"webLogsCmd := &cobra.Command{",
+				"webCmd.AddCommand(webRunCmd)",
+				"webCmd.AddCommand(webStopCmd)",
+				"webCmd.AddCommand(webStatusCmd)",
+				"webCmd.AddCommand(webLogsCmd)",
+				"rootCmd.AddCommand(webCmd)",
 			},
 		},
 		{
@@ -54,22 +51,18 @@ var PROJECT = "test-app"
 watch api: echo "Starting @var(PROJECT) API server"
 stop api: echo "Gracefully shutting down @var(PROJECT) API"
 			`,
-			expected: struct {
-				hasProcessGroup bool
-				identifier      string
-				hasDefaultRun   bool
-				hasRunSubcmd    bool
-				hasStopSubcmd   bool
-				hasStatusSubcmd bool
-				hasLogsSubcmd   bool
-			}{
-				hasProcessGroup: true,
-				identifier:      "api",
-				hasDefaultRun:   true,
-				hasRunSubcmd:    true,
-				hasStopSubcmd:   true, // Uses custom stop logic
-				hasStatusSubcmd: true, // Auto-generated
-				hasLogsSubcmd:   true, // Auto-generated
+			want: []string{
+				"apiCmd := &cobra.Command{",
+				"Run:   apiRun, // Default action is to run",
+				"apiRunCmd := &cobra.Command{",
+				"apiStopCmd := &cobra.Command{", // Uses custom stop logic
+				"apiStatusCmd := &cobra.Command{",
+				"apiLogsCmd := &cobra.Command{",
+				"apiCmd.AddCommand(apiRunCmd)",
+				"apiCmd.AddCommand(apiStopCmd)",
+				"apiCmd.AddCommand(apiStatusCmd)",
+				"apiCmd.AddCommand(apiLogsCmd)",
+				"rootCmd.AddCommand(apiCmd)",
 			},
 		},
 		{
@@ -81,108 +74,126 @@ watch dev: echo "Starting @var(PROJECT) development"
 test: echo "Running tests for @var(PROJECT)"
 stop dev: echo "Stopping @var(PROJECT) development"
 			`,
-			expected: struct {
-				hasProcessGroup bool
-				identifier      string
-				hasDefaultRun   bool
-				hasRunSubcmd    bool
-				hasStopSubcmd   bool
-				hasStatusSubcmd bool
-				hasLogsSubcmd   bool
-			}{
-				hasProcessGroup: true,
-				identifier:      "dev",
-				hasDefaultRun:   true,
-				hasRunSubcmd:    true,
-				hasStopSubcmd:   true,
-				hasStatusSubcmd: true,
-				hasLogsSubcmd:   true,
+			want: []string{
+				"devCmd := &cobra.Command{",
+				"Run:   devRun, // Default action is to run",
+				"devRunCmd := &cobra.Command{",
+				"devStopCmd := &cobra.Command{",
+				"devStatusCmd := &cobra.Command{",
+				"devLogsCmd := &cobra.Command{",
+				"devCmd.AddCommand(devRunCmd)",
+				"devCmd.AddCommand(devStopCmd)",
+				"devCmd.AddCommand(devStatusCmd)",
+				"devCmd.AddCommand(devLogsCmd)",
+				"rootCmd.AddCommand(devCmd)",
+			},
+		},
+		{
+			name:  "hyphenated watch command",
+			input: `watch api-server: echo "Starting the API server"`,
+			want: []string{
+				"apiServerCmd := &cobra.Command{",
+				"Run:   apiServerRun, // Default action is to run",
+				"apiServerRunCmd := &cobra.Command{",
+				"apiServerStopCmd := &cobra.Command{",
+				"apiServerStatusCmd := &cobra.Command{",
+				"apiServerLogsCmd := &cobra.Command{",
+				"apiServerCmd.AddCommand(apiServerRunCmd)",
+				"rootCmd.AddCommand(apiServerCmd)",
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Parse the input
 			program, err := parser.Parse(strings.NewReader(tt.input))
 			if err != nil {
 				t.Fatalf("Failed to parse input: %v", err)
 			}
 
-			// Create engine and generate code
-			engine := New(program)
-			result, err := engine.GenerateCode(program)
+			result, err := New(program).GenerateCode(program)
 			if err != nil {
 				t.Fatalf("Failed to generate code: %v", err)
 			}
 
 			generatedCode := result.String()
-
-			if tt.expected.hasProcessGroup {
-				// Check that process management structure is generated
-				expectedMainCmd := tt.expected.identifier + "Cmd := &cobra.Command{"
-				if !strings.Contains(generatedCode, expectedMainCmd) {
-					t.Errorf("Expected main process command %s not found in generated code", expectedMainCmd)
-				}
-
-				if tt.expected.hasDefaultRun {
-					// Check that main command has Run function for default behavior
-					expectedDefaultRun := "Run:   " + toCamelCase(tt.expected.identifier) + "Run, // Default action is to run"
-					if !strings.Contains(generatedCode, expectedDefaultRun) {
-						t.Errorf("Expected default run behavior not found: %s", expectedDefaultRun)
-					}
-				}
-
-				if tt.expected.hasRunSubcmd {
-					// Check that explicit run subcommand exists
-					expectedRunSubcmd := toCamelCase(tt.expected.identifier) + "RunCmd := &cobra.Command{"
-					if !strings.Contains(generatedCode, expectedRunSubcmd) {
-						t.Errorf("Expected run subcommand not found: %s", expectedRunSubcmd)
-					}
-				}
-
-				if tt.expected.hasStopSubcmd {
-					// Check that stop subcommand exists
-					expectedStopSubcmd := toCamelCase(tt.expected.identifier) + "StopCmd := &cobra.Command{"
-					if !strings.Contains(generatedCode, expectedStopSubcmd) {
-						t.Errorf("Expected stop subcommand not found: %s", expectedStopSubcmd)
-					}
-				}
-
-				if tt.expected.hasStatusSubcmd {
-					// Check that status subcommand exists
-					expectedStatusSubcmd := toCamelCase(tt.expected.identifier) + "StatusCmd := &cobra.Command{"
-					if !strings.Contains(generatedCode, expectedStatusSubcmd) {
-						t.Errorf("Expected status subcommand not found: %s", expectedStatusSubcmd)
-					}
+			for _, want := range tt.want {
+				if !strings.Contains(generatedCode, want) {
+					t.Errorf("Expected %q in generated code", want)
 				}
+			}
+		})
+	}
+}
 
-				if tt.expected.hasLogsSubcmd {
-					// Check that logs subcommand exists
-					expectedLogsSubcmd := toCamelCase(tt.expected.identifier) + "LogsCmd := &cobra.Command{"
-					if !strings.Contains(generatedCode, expectedLogsSubcmd) {
-						t.Errorf("Expected logs subcommand not found: %s", expectedLogsSubcmd)
-					}
-				}
+// TestProcessManagementGeneration_Collisions tests that process groups whose identifiers
+// collide with other commands or with Go get suffixed identifiers
+func TestProcessManagementGeneration_Collisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // Lines the generated code must contain
+	}{
+		{
+			name: "command taking a process group's run function",
+			input: `watch server: echo serve
+server-run: echo build`,
+			want: []string{
+				"serverCmd := &cobra.Command{",
+				"Run:   serverRun, // Default action is to run",
+				"serverRunCmd := &cobra.Command{",
+				"serverRun_2Cmd := &cobra.Command{",
+				"rootCmd.AddCommand(serverCmd)",
+				"rootCmd.AddCommand(serverRun_2Cmd)",
+			},
+		},
+		{
+			name: "watch commands with the same identifier",
+			input: `watch api-server: echo a
+watch api_server: echo b`,
+			want: []string{
+				"apiServerCmd := &cobra.Command{",
+				"apiServerRunCmd := &cobra.Command{",
+				"apiServer_2Cmd := &cobra.Command{",
+				"Run:   apiServer_2Run, // Default action is to run",
+				"apiServer_2RunCmd := &cobra.Command{",
+				"apiServer_2StopCmd := &cobra.Command{",
+				"apiServer_2StatusCmd := &cobra.Command{",
+				"apiServer_2LogsCmd := &cobra.Command{",
+				"apiServer_2Cmd.AddCommand(apiServer_2RunCmd)",
+				"rootCmd.AddCommand(apiServer_2Cmd)",
+			},
+		},
+		{
+			name:  "watch command named like a Go type",
+			input: `watch string: echo s`,
+			want: []string{
+				"string_2Cmd := &cobra.Command{",
+				"Run:   string_2Run, // Default action is to run",
+				"string_2RunCmd := &cobra.Command{",
+				"string_2StopCmd := &cobra.Command{",
+				"string_2Cmd.AddCommand(string_2StopCmd)",
+				"rootCmd.AddCommand(string_2Cmd)",
+			},
+		},
+	}
 
-				// Check that all subcommands are added to main command
-				expectedAddCommands := []string{
-					tt.expected.identifier + "Cmd.AddCommand(" + toCamelCase(tt.expected.identifier) + "RunCmd)",
-					tt.expected.identifier + "Cmd.AddCommand(" + toCamelCase(tt.expected.identifier) + "StopCmd)",
-					tt.expected.identifier + "Cmd.AddCommand(" + toCamelCase(tt.expected.identifier) + "StatusCmd)",
-					tt.expected.identifier + "Cmd.AddCommand(" + toCamelCase(tt.expected.identifier) + "LogsCmd)",
-				}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := parser.Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Failed to parse input: %v", err)
+			}
 
-				for _, expectedAdd := range expectedAddCommands {
-					if !strings.Contains(generatedCode, expectedAdd) {
-						t.Errorf("Expected subcommand addition not found: %s", expectedAdd)
-					}
-				}
+			result, err := New(program).GenerateCode(program)
+			if err != nil {
+				t.Fatalf("Failed to generate code: %v", err)
+			}
 
-				// Check that main command is added to root
-				expectedAddToRoot := "rootCmd.AddCommand(" + toCamelCase(tt.expected.identifier) + "Cmd)"
-				if !strings.Contains(generatedCode, expectedAddToRoot) {
-					t.Errorf("Expected main command addition to root not found: %s", expectedAddToRoot)
+			generatedCode := result.String()
+			for _, want := range tt.want {
+				if !strings.Contains(generatedCode, want) {
+					t.Errorf("Expected %q in generated code", want)
 				}
 			}
 		})
@@ -282,6 +293,223 @@ watch web: echo "Starting web server"
 	}
 }
 
+// TestProcessManagementWindowsFile tests that CLIs with watch commands get their Windows
+// process management in a build-tagged file beside main.go, and others don't
+func TestProcessManagementWindowsFile(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("watch web: echo web\nbuild: echo build"))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	engine := New(program)
+	result, err := engine.GenerateCode(program)
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+	source := result.Files["process_windows.go"]
+	for _, want := range []string{"//go:build windows", "syscall.OpenProcess", `"taskkill"`, "processAlive = windowsProcessAlive"} {
+		if !strings.Contains(source, want) {
+			t.Errorf("process_windows.go is missing %q", want)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := engine.WriteFiles(result, dir, "web"); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "process_windows.go")); err != nil {
+		t.Errorf("WriteFiles didn't write process_windows.go: %v", err)
+	}
+
+	program, err = parser.Parse(strings.NewReader("build: echo build"))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	result, err = New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+	if len(result.Files) > 0 {
+		t.Errorf("GenerateCode() files = %v, want none without watch commands", result.Files)
+	}
+}
+
+// TestGeneratedCliDaemonHandoff tests that a generated CLI hands its watch command to a
+// running devcmd daemon, which restarts it, and stops it through the daemon
+func TestGeneratedCliDaemonHandoff(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the daemon socket is a Unix domain socket")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	t.Setenv(processes.RootEnvVar, registry.Root)
+
+	binaryPath := buildTestCLIWithOptions(t, `
+watch api: {
+	echo "api run"
+	exit 1
+}
+`, CLIOptions{Restart: daemon.RestartOnFailure})
+
+	supervisor := daemon.New(registry)
+	supervisor.Backoff = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- supervisor.Serve(ctx) }()
+	defer func() {
+		cancel()
+		<-served
+	}()
+	for deadline := time.Now().Add(5 * time.Second); !daemon.Running(registry); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon didn't start")
+		}
+	}
+
+	output, err := exec.Command(binaryPath, "api").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "under the devcmd daemon") {
+		t.Fatalf("api wasn't handed to the daemon (%v):\n%s", err, output)
+	}
+
+	// CLIs built without a commands file are namespaced by the working directory
+	logFile := filepath.Join(registry.Dir(New(&ast.Program{}).ProcessNamespace()), "api.log")
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		log, _ := os.ReadFile(logFile)
+		if strings.Count(string(log), "api run") >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("api wasn't restarted after failing:\n%s", log)
+		}
+	}
+
+	output, err = exec.Command(binaryPath, "api", "stop").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Stopped api process") {
+		t.Fatalf("stop failed (%v):\n%s", err, output)
+	}
+	if response, err := daemon.Call(registry, daemon.Request{Op: daemon.OpList}); err != nil || len(response.Processes) != 0 {
+		t.Errorf("daemon still supervises %+v (%v)", response, err)
+	}
+
+	// The daemon records each run and the stop in the namespace's history
+	history, err := registry.History(New(&ast.Program{}).ProcessNamespace())
+	if err != nil || len(history) < 4 || history[0].Event != processes.EventStarted ||
+		history[1].Event != processes.EventExited || history[1].ExitCode != 1 ||
+		history[len(history)-1].Event != processes.EventStopped {
+		t.Errorf("History() = %+v, %v; want runs that started and exited, then the stop", history, err)
+	}
+}
+
+// TestWatchCommandDoubleStart tests that a watch command leaves a running instance alone,
+// fails when the instance doesn't accept connections, and replaces it with force
+func TestWatchCommandDoubleStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	t.Setenv(processes.RootEnvVar, registry.Root)
+
+	marker := filepath.Join(t.TempDir(), "started")
+	program, err := parser.Parse(strings.NewReader("watch api: touch " + marker))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	eng := New(program)
+	dir := registry.Dir(eng.ProcessNamespace())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sleep := exec.Command("sleep", "30")
+	if err := sleep.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- sleep.Wait() }()
+	if err := os.WriteFile(filepath.Join(dir, "api.pid"), []byte(strconv.Itoa(sleep.Process.Pid)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("a running instance should be left alone: %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("a second instance was started")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "api.ports"), []byte("API_PORT=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err == nil || !strings.Contains(err.Error(), "doesn't accept connections") {
+		t.Fatalf("an unresponsive instance returned %v, want an error", err)
+	}
+
+	eng.SetForceRestart(true)
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("forced restart failed: %v", err)
+	}
+	if err := <-exited; err == nil {
+		t.Errorf("the running instance should have been stopped")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("the command didn't run after the restart: %v", err)
+	}
+}
+
+// TestProcessStopOrder tests that services settings order stop --all and are checked against
+// the watch commands
+func TestProcessStopOrder(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`
+watch db: echo db
+watch api: echo api
+watch web: echo web
+build: echo build
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		services map[string]processes.Service
+		want     string
+		wantErr  string
+	}{
+		{"by name without settings", nil, "api db web", ""},
+		{"dependents first", map[string]processes.Service{
+			"web": {DependsOn: []string{"api"}},
+			"api": {DependsOn: []string{"db"}, StopTimeout: 10 * time.Second},
+		}, "web api db", ""},
+		{"unknown service", map[string]processes.Service{"build": {}}, "", "services.build: no watch command"},
+		{"unknown dependency", map[string]processes.Service{"api": {DependsOn: []string{"cache"}}}, "", "services.api.dependsOn: no watch command named cache"},
+		{"cycle", map[string]processes.Service{
+			"api": {DependsOn: []string{"db"}},
+			"db":  {DependsOn: []string{"api"}},
+		}, "", "dependency cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := New(program)
+			eng.SetCLIOptions(CLIOptions{Services: tt.services})
+			result, err := eng.GenerateCode(program)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateCode returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateCode failed: %v", err)
+			}
+			order, _ := eng.processStopOrder(eng.analyzeCommands(program.Commands))
+			if got := strings.Join(order, " "); got != tt.want {
+				t.Errorf("stop order = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(result.String(), `Use:   "stop --all"`) {
+				t.Errorf("generated CLI has no stop --all command")
+			}
+		})
+	}
+}
+
 // TestProcessManagementCustomStopLogic tests custom stop command generation
 func TestProcessManagementCustomStopLogic(t *testing.T) {
 	input := `
@@ -405,3 +633,106 @@ func findCommandByName(commands []ast.CommandDecl, name string) *ast.CommandDecl
 	}
 	return nil
 }
+
+// TestGeneratedCliProcessStore tests that generated CLIs keep a watch command's files and
+// history, and @freeport's allocations, in the form the process registry reads
+func TestGeneratedCliProcessStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	t.Setenv(processes.RootEnvVar, registry.Root)
+
+	binaryPath := buildTestCLI(t, `watch api: sleep 30
+watch web: echo @freeport("WEB_PORT")`)
+	output, err := exec.Command(binaryPath, "api").CombinedOutput()
+	if err != nil {
+		t.Fatalf("api failed (%v):\n%s", err, output)
+	}
+
+	namespace := New(&ast.Program{}).ProcessNamespace()
+	if _, ok := registry.Lookup(namespace, "api"); !ok {
+		t.Fatalf("api isn't recorded in the registry:\n%s", output)
+	}
+	history, err := registry.History(namespace)
+	if err != nil || len(history) != 1 || history[0].Name != "api" || history[0].Event != processes.EventStarted || history[0].PID == 0 {
+		t.Errorf("History() = %+v, %v; want the start", history, err)
+	}
+
+	// Run in the foreground, as under the daemon, so the allocation is made before it exits
+	cmd := exec.Command(binaryPath, "web")
+	cmd.Env = append(os.Environ(), "DEVCMD_SUPERVISED=web")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("web failed (%v):\n%s", err, output)
+	}
+	ports, err := registry.Store(namespace).Read("web.ports")
+	if err != nil || !strings.HasPrefix(string(ports), "WEB_PORT=") {
+		t.Errorf("web.ports = %q, %v; want the @freeport allocation", ports, err)
+	}
+	if files, _ := registry.Store(namespace).Files(".*"); len(files) != 0 {
+		t.Errorf("temporary and lock files were left behind: %v", files)
+	}
+}
+
+// TestGeneratedCliProcessProject tests that generated CLIs record processes in the namespace
+// of the project they run in: the one --project names, or the nearest directory holding the
+// commands file, and outside any project the one they were built from
+func TestGeneratedCliProcessProject(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available")
+	}
+	registry := processes.Registry{Root: t.TempDir()}
+	t.Setenv(processes.RootEnvVar, registry.Root)
+
+	program, err := parser.Parse(strings.NewReader("watch api: sleep 1"))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	built := t.TempDir()
+	eng := New(program)
+	eng.SetSourceFile(filepath.Join(built, "commands.cli"))
+	binaryPath := buildTestCLIFromEngine(t, eng, program)
+
+	checkout := t.TempDir()
+	if err := os.WriteFile(filepath.Join(checkout, "commands.cli"), []byte("watch api: sleep 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(checkout, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	named := t.TempDir()
+
+	tests := []struct {
+		name    string
+		dir     string
+		args    []string
+		project string
+	}{
+		{"nearest commands file", filepath.Join(checkout, "src"), nil, checkout},
+		{"project flag", checkout, []string{"--project", named}, named},
+		{"outside a project", t.TempDir(), nil, built},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, append([]string{"api"}, tt.args...)...)
+			cmd.Dir = tt.dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("api failed (%v):\n%s", err, output)
+			}
+			namespace := processes.Namespace(tt.project)
+			if _, ok := registry.Lookup(namespace, "api"); !ok {
+				namespaces, _ := registry.Namespaces()
+				t.Fatalf("api isn't recorded in %s, the namespace of %s; namespaces: %v", namespace, tt.project, namespaces)
+			}
+			if project, _ := registry.Store(namespace).Read(processes.ProjectFile); strings.TrimSpace(string(project)) != tt.project {
+				t.Errorf("project file = %q, want %s", project, tt.project)
+			}
+			_ = registry.Store(namespace).Remove("api.pid")
+		})
+	}
+
+	cmd := exec.Command(binaryPath, "api", "--project", filepath.Join(named, "missing"))
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "is not a directory") {
+		t.Errorf("a missing --project directory returned %v:\n%s", err, output)
+	}
+}
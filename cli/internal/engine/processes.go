@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/cli/internal/daemon"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+)
+
+// ProcessNamespace returns the namespace the background processes of this project are
+// recorded under in the process registry: the directory of the commands file set by
+// SetSourceFile, or the working directory for commands read from stdin
+func (e *Engine) ProcessNamespace() string {
+	return processes.Namespace(e.projectDir())
+}
+
+// projectDir returns the absolute directory of the project, for the registry to show
+func (e *Engine) projectDir() string {
+	dir, err := filepath.Abs(filepath.Dir(e.sourceFile))
+	if err != nil {
+		return filepath.Dir(e.sourceFile)
+	}
+	return dir
+}
+
+// sourceName returns the name of the commands file, which generated CLIs look for to find
+// the project they run in, or "" for commands read from stdin
+func (e *Engine) sourceName() string {
+	if e.sourceFile == "" {
+		return ""
+	}
+	return filepath.Base(e.sourceFile)
+}
+
+// SetForceRestart makes watch commands stop an instance of themselves that is already
+// running and start again, instead of leaving it running
+func (e *Engine) SetForceRestart(force bool) {
+	e.forceRestart = force
+}
+
+// checkRunning looks for a running instance of a watch command in the registry before the
+// command starts, so two copies don't fight over ports. It reports true when a healthy
+// instance is left running, and fails when the instance is alive but doesn't accept
+// connections on the ports it allocated. With SetForceRestart either is stopped instead.
+// A dead instance's PID and ports files are removed.
+func (e *Engine) checkRunning(registry processes.Registry, namespace, name string) (bool, error) {
+	existing, ok := registry.Lookup(namespace, name)
+	if !ok {
+		return false, nil
+	}
+
+	health := existing.Health()
+	switch {
+	case health == processes.Dead:
+		return false, registry.Stop(existing)
+	case e.forceRestart:
+		fmt.Fprintf(os.Stderr, "Restarting %s (PID: %d)\n", name, existing.PID)
+		return false, stopProcess(registry, existing)
+	case health == processes.Unresponsive:
+		return false, fmt.Errorf("%s is already running (PID: %d) but doesn't accept connections on %s; use --force to restart it",
+			name, existing.PID, strings.Join(existing.Ports, ", "))
+	default:
+		fmt.Fprintf(os.Stderr, "%s is already running (PID: %d); use --force to restart it\n", name, existing.PID)
+		return true, nil
+	}
+}
+
+// stopProcess stops a process through the devcmd daemon when it supervises it, so it isn't
+// restarted, and otherwise terminates it; either way it waits for the process to exit
+func stopProcess(registry processes.Registry, process processes.Process) error {
+	if _, err := daemon.Call(registry, daemon.Request{Op: daemon.OpStop, Namespace: process.Namespace, Name: process.Name}); err == nil {
+		return nil
+	}
+	if err := registry.Stop(process); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", process.Name, err)
+	}
+	return nil
+}
+
+// processStopOrder checks the services settings against the watch commands and returns the
+// watch commands in the order stop --all stops them: each before the ones it depends on
+func (e *Engine) processStopOrder(groups CommandGroups) ([]string, error) {
+	var names []string
+	watched := make(map[string]bool)
+	for _, group := range groups.ProcessGroups {
+		if group.WatchCommand != nil {
+			names = append(names, group.Identifier)
+			watched[group.Identifier] = true
+		}
+	}
+
+	var configured []string
+	for name := range e.cliOptions.Services {
+		configured = append(configured, name)
+	}
+	sort.Strings(configured)
+	for _, name := range configured {
+		service := e.cliOptions.Services[name]
+		if !watched[name] {
+			return nil, fmt.Errorf("services.%s: no watch command named %s", name, name)
+		}
+		for _, dependency := range service.DependsOn {
+			if !watched[dependency] {
+				return nil, fmt.Errorf("services.%s.dependsOn: no watch command named %s", name, dependency)
+			}
+		}
+	}
+
+	order, err := processes.StopOrder(names, func(name string) []string {
+		return e.cliOptions.Services[name].DependsOn
+	})
+	if err != nil {
+		return nil, fmt.Errorf("services: %w", err)
+	}
+	return order, nil
+}
+
+// hasCommand reports whether a regular command or process group is named name
+func hasCommand(groups CommandGroups, name string) bool {
+	for _, command := range groups.RegularCommands {
+		if command.Name == name {
+			return true
+		}
+	}
+	for _, group := range groups.ProcessGroups {
+		if group.Identifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+// processWindowsFile is the file generated CLIs with watch commands keep their Windows
+// process management in, built only for Windows
+const processWindowsFile = "process_windows.go"
+
+// processWindowsSource replaces the signal-based processAlive and terminateProcess of
+// generated CLIs on Windows: processes are probed with OpenProcess and stopped, with the
+// processes they started, by taskkill
+const processWindowsSource = `//go:build windows
+
+package main
+
+import (
+	"os"
+	execpkg "os/exec"
+	"strconv"
+	"syscall"
+)
+
+func init() {
+	processAlive = windowsProcessAlive
+	terminateProcess = windowsTerminateProcess
+}
+
+// stillActive is the exit code GetExitCodeProcess reports for a process that hasn't exited
+const stillActive = 259
+
+// windowsProcessAlive reports whether a process exists and hasn't exited
+func windowsProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	const processQueryLimitedInformation = 0x1000
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+	var code uint32
+	return syscall.GetExitCodeProcess(handle, &code) == nil && code == stillActive
+}
+
+// windowsTerminateProcess asks a process and the processes it started to close, or ends
+// them when kill is set. Console processes can only be ended, so asking fails for them and
+// stopProcess kills them at once. Without taskkill, only the process itself is killed.
+func windowsTerminateProcess(process *os.Process, kill bool) error {
+	args := []string{"/PID", strconv.Itoa(process.Pid), "/T"}
+	if kill {
+		args = append(args, "/F")
+	}
+	cmd := execpkg.Command("taskkill", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if err := cmd.Run(); err != nil {
+		if kill {
+			return process.Kill()
+		}
+		return err
+	}
+	return nil
+}
+`
+
+// processStoreHelper changes the files of a namespace of the process registry the way
+// processes.DirStore does, holding its lock file and replacing files through a temporary
+// file, and appends to its history, so generated CLIs, devcmd and the daemon don't
+// interleave their writes
+const processStoreHelper = `
+// lockProcessDir takes the lock file of a namespace of the process registry, breaking one
+// left by a writer that died holding it, and returns the func that releases it
+func lockProcessDir(dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, ".lock")
+	for deadline := time.Now().Add(15 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		lock, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > 10*time.Second {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the lock %s", path)
+		}
+	}
+}
+
+// replaceProcessFile writes a file of a namespace through a temporary file renamed over it;
+// the caller holds the lock
+func replaceProcessFile(dir, file string, content []byte) error {
+	temp, err := os.CreateTemp(dir, "."+file+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), filepath.Join(dir, file))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// writeProcessFile replaces the contents of a file of a namespace
+func writeProcessFile(dir, file, content string) error {
+	unlock, err := lockProcessDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return replaceProcessFile(dir, file, []byte(content))
+}
+
+// appendProcessFile adds a line to a file of a namespace
+func appendProcessFile(dir, file, line string) error {
+	unlock, err := lockProcessDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	content, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return replaceProcessFile(dir, file, append(content, line+"\n"...))
+}
+
+// removeProcessFiles removes files of a namespace, which need not exist
+func removeProcessFiles(dir string, files ...string) error {
+	unlock, err := lockProcessDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, file := range files {
+		if err := os.Remove(filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordProcessEvent appends an event to the history of a namespace, moving a history past
+// 1MiB to history.jsonl.1
+func recordProcessEvent(dir, name, event string, pid int) error {
+	line, err := json.Marshal(map[string]interface{}{"time": time.Now(), "name": name, "event": event, "pid": pid})
+	if err != nil {
+		return err
+	}
+	unlock, err := lockProcessDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	path := filepath.Join(dir, "history.jsonl")
+	if info, err := os.Stat(path); err == nil && info.Size() > 1<<20 {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+`
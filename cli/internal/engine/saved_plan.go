@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+)
+
+// SavedPlanVersion is the version of the saved plan format. devcmd apply refuses plans saved
+// in another version.
+const SavedPlanVersion = 1
+
+// SavedPlan is the plan of a run saved by devcmd plan --out for devcmd apply to run later, on
+// this machine or another. It holds what the run was planned with, so apply runs it the same
+// way, and fingerprints of the commands file and environment it was planned in, so apply
+// refuses a plan that is stale. Saved plans show the values the commands run with, other
+// than secrets, so they are written readable only by their owner and should be kept as
+// carefully as the environment they were planned in.
+type SavedPlan struct {
+	Version  int               `json:"version"`
+	Devcmd   string            `json:"devcmd"`            // The devcmd version that saved it
+	Created  time.Time         `json:"created"`           // When it was saved
+	Source   string            `json:"source"`            // SourceHash of the commands file and its local override file
+	Platform string            `json:"platform"`          // GOOS/GOARCH it was planned on
+	Env      map[string]string `json:"env"`               // Fingerprint of each environment variable the commands read
+	Profile  string            `json:"profile,omitempty"` // --profile
+	Vars     []string          `json:"vars,omitempty"`    // --var values, as NAME=value
+	Params   []string          `json:"params,omitempty"`  // --param values, as name=value
+	Only     []string          `json:"only,omitempty"`    // --only
+	Skip     []string          `json:"skip,omitempty"`    // --skip
+	Commands []SavedCommand    `json:"commands"`
+}
+
+// SavedCommand is the plan of one command of a saved plan, with its PlanDigest
+type SavedCommand struct {
+	Name   string              `json:"name"`
+	Digest string              `json:"digest"`
+	Plan   *plan.ExecutionPlan `json:"plan"`
+}
+
+// Platform returns the platform plans are saved with
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// EnvFingerprint returns a fingerprint of each environment variable the commands read in the
+// given caller environment, including those profiles and settings set: a hash of its value, or
+// "unset". Credentials, the variables a required @env or @secret reads, are only "set" or
+// "unset", as a short unsalted hash of a token or password could be guessed from the plan.
+func (e *Engine) EnvFingerprint(commands []*ast.CommandDecl, environ []string, profile EnvProfile) (map[string]string, error) {
+	fingerprint := make(map[string]string)
+	for _, command := range commands {
+		env, err := e.ResolveEnvironment(command, environ, profile, false)
+		if err != nil {
+			return nil, err
+		}
+		credentials := credentialVariables(e.commandClosure(command))
+		for _, variable := range env.Env {
+			switch {
+			case variable.Source == SourceUnset:
+				fingerprint[variable.Name] = "unset"
+			case credentials[variable.Name]:
+				fingerprint[variable.Name] = "set"
+			default:
+				sum := sha256.Sum256([]byte(variable.Value))
+				fingerprint[variable.Name] = hex.EncodeToString(sum[:8])
+			}
+		}
+	}
+	return fingerprint, nil
+}
+
+// credentialVariables returns the environment variables the commands read with a required
+// @env, and those @secret may read them from: the path of an @secret, else its key
+func credentialVariables(commands []*ast.CommandDecl) map[string]bool {
+	credentials := make(map[string]bool)
+	for _, ref := range envReferences(commands) {
+		if ref.Required {
+			credentials[ref.Key] = true
+		}
+	}
+	for _, command := range commands {
+		ast.Walk(&command.Body, func(n ast.Node) bool {
+			decorator, ok := n.(*ast.ValueDecorator)
+			if !ok || decorator.Name != "secret" {
+				return true
+			}
+			variable := ast.GetStringParam(decorator.Args, "path", "")
+			if variable == "" {
+				variable = ast.GetStringParam(decorator.Args, "key", ast.GetStringParam(decorator.Args, "name", ""))
+			}
+			if variable == "" && len(decorator.Args) > 0 {
+				switch v := decorator.Args[0].Value.(type) {
+				case *ast.StringLiteral:
+					variable = v.Value
+				case *ast.Identifier:
+					variable = v.Name
+				}
+			}
+			if variable != "" {
+				credentials[variable] = true
+			}
+			return true
+		})
+	}
+	return credentials
+}
+
+// Digests returns the PlanDigest of each command of the saved plan, by name
+func (s *SavedPlan) Digests() map[string]string {
+	digests := make(map[string]string, len(s.Commands))
+	for _, command := range s.Commands {
+		digests[command.Name] = command.Digest
+	}
+	return digests
+}
+
+// CheckStale returns an error naming what has changed since the plan was saved: the commands
+// file, the platform, or environment variables the commands read
+func (s *SavedPlan) CheckStale(source, platform string, env map[string]string) error {
+	var changes []string
+	if source != s.Source {
+		changes = append(changes, "the commands file changed")
+	}
+	if platform != s.Platform {
+		changes = append(changes, fmt.Sprintf("it was planned on %s, not %s", s.Platform, platform))
+	}
+	names := make([]string, 0, len(env)+len(s.Env))
+	for name := range s.Env {
+		names = append(names, name)
+	}
+	for name := range env {
+		if _, ok := s.Env[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if env[name] != s.Env[name] {
+			changes = append(changes, fmt.Sprintf("environment variable %s changed", name))
+		}
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("the plan is stale: %s since it was saved; plan it again", strings.Join(changes, ", "))
+	}
+	return nil
+}
+
+// WriteSavedPlan writes a saved plan to a file as JSON
+func WriteSavedPlan(path string, saved *SavedPlan) error {
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// ReadSavedPlan reads a plan saved by WriteSavedPlan
+func ReadSavedPlan(path string) (*SavedPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var saved SavedPlan
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s is not a saved plan: %w", path, err)
+	}
+	if saved.Version != SavedPlanVersion {
+		return nil, fmt.Errorf("%s is a saved plan of version %d, but this devcmd applies version %d", path, saved.Version, SavedPlanVersion)
+	}
+	if len(saved.Commands) == 0 {
+		return nil, fmt.Errorf("%s plans no commands", path)
+	}
+	return &saved, nil
+}
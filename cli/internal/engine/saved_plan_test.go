@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestSavedPlan(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`deploy: ./deploy.sh @env(REGION, default = "eu-west-1") @env(TOKEN)`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	command := &program.Commands[0]
+	environ := []string{"TOKEN=s3cret"}
+
+	executionPlan, err := eng.PlanWithValues(command, environ, EnvProfile{}, nil)
+	if err != nil {
+		t.Fatalf("PlanWithValues failed: %v", err)
+	}
+	digest, err := PlanDigest(executionPlan)
+	if err != nil {
+		t.Fatalf("PlanDigest failed: %v", err)
+	}
+	env, err := eng.EnvFingerprint([]*ast.CommandDecl{command}, environ, EnvProfile{})
+	if err != nil {
+		t.Fatalf("EnvFingerprint failed: %v", err)
+	}
+	if len(env) != 2 || env["TOKEN"] == "" || env["TOKEN"] == "s3cret" {
+		t.Errorf("fingerprint = %v, want a hash of REGION's default and TOKEN", env)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	err = WriteSavedPlan(path, &SavedPlan{
+		Version:  SavedPlanVersion,
+		Source:   SourceHash([]byte("deploy: ...")),
+		Platform: Platform(),
+		Env:      env,
+		Params:   []string{"region=eu"},
+		Commands: []SavedCommand{{Name: "deploy", Digest: digest, Plan: executionPlan}},
+	})
+	if err != nil {
+		t.Fatalf("WriteSavedPlan failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("saved plan mode = %v, %v, want it readable only by its owner", info.Mode().Perm(), err)
+	}
+	saved, err := ReadSavedPlan(path)
+	if err != nil {
+		t.Fatalf("ReadSavedPlan failed: %v", err)
+	}
+	if saved.Digests()["deploy"] != digest || len(saved.Params) != 1 {
+		t.Errorf("saved plan = %+v, want what was written", saved)
+	}
+
+	if err := saved.CheckStale(saved.Source, Platform(), env); err != nil {
+		t.Errorf("an unchanged plan should not be stale: %v", err)
+	}
+	changed, _ := eng.EnvFingerprint([]*ast.CommandDecl{command}, []string{"TOKEN=s3cret", "REGION=us-east-1"}, EnvProfile{})
+	err = saved.CheckStale(SourceHash([]byte("deploy: changed")), "plan9/386", changed)
+	want := "the plan is stale: the commands file changed, it was planned on " + Platform() + ", not plan9/386, environment variable REGION changed since it was saved"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("CheckStale error = %v, want %q", err, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99, "commands": [{"name": "deploy"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSavedPlan(path); err == nil || !strings.Contains(err.Error(), "is a saved plan of version 99, but this devcmd applies version 1") {
+		t.Errorf("expected another version to be refused, got %v", err)
+	}
+}
+
+func TestEnvFingerprint_Credentials(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`deploy: ./deploy.sh @env(REGION) @env(API_KEY, required = true) @secret(TOKEN, source = "env") @env(TOKEN)`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	eng := New(program)
+	commands := []*ast.CommandDecl{&program.Commands[0]}
+
+	env, err := eng.EnvFingerprint(commands, []string{"REGION=eu-west-1", "API_KEY=k3y", "TOKEN=s3cret"}, EnvProfile{})
+	if err != nil {
+		t.Fatalf("EnvFingerprint failed: %v", err)
+	}
+	if env["API_KEY"] != "set" || env["TOKEN"] != "set" {
+		t.Errorf("fingerprint = %v, want credentials only set or unset", env)
+	}
+	if env["REGION"] == "set" || env["REGION"] == "unset" {
+		t.Errorf("fingerprint = %v, want a hash of REGION", env)
+	}
+
+	// A new credential value doesn't make the plan stale, but removing it does
+	rotated, _ := eng.EnvFingerprint(commands, []string{"REGION=eu-west-1", "API_KEY=rotated", "TOKEN=rotated"}, EnvProfile{})
+	saved := &SavedPlan{Env: env}
+	if err := saved.CheckStale("", "", rotated); err != nil {
+		t.Errorf("rotated credentials made the plan stale: %v", err)
+	}
+	removed, _ := eng.EnvFingerprint(commands, []string{"REGION=eu-west-1", "TOKEN=s3cret"}, EnvProfile{})
+	if err := saved.CheckStale("", "", removed); err == nil || !strings.Contains(err.Error(), "environment variable API_KEY changed") {
+		t.Errorf("CheckStale error = %v, want API_KEY to have changed", err)
+	}
+}
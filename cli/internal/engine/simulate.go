@@ -0,0 +1,447 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/config"
+)
+
+// Sources of the durations a simulation assigns to steps
+const (
+	SourceEstimate = "estimate" // Annotated with --estimate or the estimates settings section
+	SourceHistory  = "history"  // The mean of the step's successful runs
+	SourceUnknown  = "unknown"  // Neither; the step is assumed to take no time
+)
+
+// DurationStats are the durations of a command's successful runs in its history
+type DurationStats struct {
+	Command string                   `json:"command"`
+	Runs    int                      `json:"runs"`    // Successful runs
+	MeanMs  int64                    `json:"mean_ms"` // Mean duration of those runs
+	Steps   map[string]StepDurations `json:"steps"`   // Each top-level step, by its 1-based position
+}
+
+// StepDurations are the durations of a top-level step in the successful runs of its command
+type StepDurations struct {
+	Name   string `json:"name"` // As run summaries name it; a step renamed since starts afresh
+	Runs   int    `json:"runs"`
+	MeanMs int64  `json:"mean_ms"`
+}
+
+// DurationsFile returns the file the duration history of a project's commands is kept in:
+// durations/<namespace>.json in the state directory of the config, or devcmd/durations in
+// the user cache directory, or in the temporary directory when there is none
+func DurationsFile(namespace string) string {
+	if state := config.StateDirFromEnv(); state != "" {
+		return filepath.Join(state, "durations", namespace+".json")
+	}
+	dir := os.TempDir()
+	if cache, err := os.UserCacheDir(); err == nil {
+		dir = cache
+	}
+	return filepath.Join(dir, "devcmd", "durations", namespace+".json")
+}
+
+// LoadDurations reads the duration history in path, which is empty if there is no file yet
+func LoadDurations(path string) (map[string]DurationStats, error) {
+	history := make(map[string]DurationStats)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("%s is not a duration history: %w", path, err)
+	}
+	return history, nil
+}
+
+// UpdateDurations adds the durations of the run's successful commands and their steps to
+// the history in path, for --simulate to estimate them
+func (s *RunSummary) UpdateDurations(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	succeeded := make(map[string]int64)
+	for _, command := range s.Commands {
+		if command.Status == "success" {
+			succeeded[command.Name] = command.DurationMs
+		}
+	}
+	if len(succeeded) == 0 {
+		return nil
+	}
+
+	history, err := LoadDurations(path)
+	if err != nil {
+		return err
+	}
+	for name, ms := range succeeded {
+		stats := history[name]
+		stats.Command = name
+		stats.Runs++
+		stats.MeanMs += (ms - stats.MeanMs) / int64(stats.Runs)
+		if stats.Steps == nil {
+			stats.Steps = make(map[string]StepDurations)
+		}
+		history[name] = stats
+	}
+	for _, step := range s.Steps {
+		if _, ok := succeeded[step.Command]; !ok || step.Step == 0 {
+			continue
+		}
+		stats := history[step.Command]
+		key := strconv.Itoa(step.Step)
+		durations := stats.Steps[key]
+		if durations.Name != step.Name {
+			durations = StepDurations{Name: step.Name}
+		}
+		durations.Runs++
+		durations.MeanMs += (step.DurationMs - durations.MeanMs) / int64(durations.Runs)
+		stats.Steps[key] = durations
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ParseEstimates reads --estimate values such as "go test ./...=3m" or "lint=20s" into the
+// durations of the steps, by their text, and commands, by their name, that they annotate
+func ParseEstimates(values []string) (map[string]time.Duration, error) {
+	estimates := make(map[string]time.Duration)
+	for _, value := range values {
+		i := strings.LastIndex(value, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected step=duration, got %q", value)
+		}
+		duration, err := time.ParseDuration(value[i+1:])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("%q: expected a duration such as 30s, got %q", value[:i], value[i+1:])
+		}
+		estimates[strings.TrimSpace(value[:i])] = duration
+	}
+	return estimates, nil
+}
+
+// Simulation is the expected wall-clock time of a command, from the durations of its steps
+// laid out as they run: in order, and @parallel branches on as many workers as its limit
+type Simulation struct {
+	Command      string          `json:"command"`
+	Duration     time.Duration   `json:"duration"`
+	CriticalPath []SimulatedStep `json:"critical_path"` // The steps that the duration adds up
+	Sources      map[string]int  `json:"sources"`       // How many steps' durations came from each source
+}
+
+// SimulatedStep is a step of a simulation's critical path
+type SimulatedStep struct {
+	Step     string        `json:"step"`
+	Start    time.Duration `json:"start"` // Since the command started
+	Duration time.Duration `json:"duration"`
+	Source   string        `json:"source"`
+}
+
+// simulator assigns durations to the steps of commands' plans
+type simulator struct {
+	engine    *Engine
+	estimates map[string]time.Duration
+	history   map[string]DurationStats
+	sources   map[string]int
+	ran       map[string]time.Duration // Mean durations of the shell steps in history, by text
+	visiting  map[string]bool          // Commands being simulated, so @cmd cycles end
+}
+
+// Simulate estimates how long a command takes from the durations of its steps: those
+// annotated in estimates, by step text or command name, or else the mean of their runs in
+// history. Shell steps are found in history by their text wherever they ran as top-level
+// steps, so moving a step into a @parallel block keeps its duration. Blocks are taken apart
+// into the steps they run, and shell steps with no duration are assumed to take no time.
+// Triggers, which depend on the outcome, are left out.
+func (e *Engine) Simulate(command *ast.CommandDecl, estimates map[string]time.Duration, history map[string]DurationStats) (*Simulation, error) {
+	s := &simulator{
+		engine:    e,
+		estimates: estimates,
+		history:   history,
+		sources:   make(map[string]int),
+		ran:       make(map[string]time.Duration),
+		visiting:  make(map[string]bool),
+	}
+	for _, stats := range history {
+		for _, step := range stats.Steps {
+			if !strings.HasPrefix(step.Name, "@") {
+				s.ran[step.Name] = time.Duration(step.MeanMs) * time.Millisecond
+			}
+		}
+	}
+	duration, path, err := s.command(command)
+	if err != nil {
+		return nil, err
+	}
+	return &Simulation{Command: command.Name, Duration: duration, CriticalPath: path, Sources: s.sources}, nil
+}
+
+// command simulates a command's needs and top-level steps one after another, bounded by the
+// config block's defaultTimeout
+func (s *simulator) command(command *ast.CommandDecl) (time.Duration, []SimulatedStep, error) {
+	s.visiting[command.Name] = true
+	defer delete(s.visiting, command.Name)
+
+	var total time.Duration
+	var path []SimulatedStep
+	add := func(duration time.Duration, steps []SimulatedStep) {
+		for _, step := range steps {
+			step.Start += total
+			path = append(path, step)
+		}
+		total += duration
+	}
+
+	for _, name := range command.NeedNames() {
+		duration, steps, err := s.named(name, "needs "+name)
+		if err != nil {
+			return 0, nil, err
+		}
+		add(duration, steps)
+	}
+
+	ctx, err := s.engine.commandPlanContext(command)
+	if err != nil {
+		return 0, nil, err
+	}
+	stats := s.history[command.Name]
+	for i, content := range command.Body.Content {
+		element, err := s.engine.contentPlan(ctx, content)
+		if err != nil {
+			return 0, nil, err
+		}
+		if element == nil {
+			continue
+		}
+		sources := make(map[string]int, len(s.sources))
+		for source, n := range s.sources {
+			sources[source] = n
+		}
+		duration, steps, err := s.step(element.Build())
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// A block none of whose steps has a known duration takes as long as it took in the
+		// command's runs, if it is the block that ran there
+		name := describeStep(content)
+		known := s.sources[SourceEstimate] > sources[SourceEstimate] || s.sources[SourceHistory] > sources[SourceHistory]
+		if ran, ok := stats.Steps[strconv.Itoa(i+1)]; ok && ran.Name == name && !known {
+			s.sources = sources
+			duration, steps = s.leaf(name, time.Duration(ran.MeanMs)*time.Millisecond, SourceHistory)
+		}
+		add(duration, steps)
+	}
+
+	if timeout := commandTimeout(s.engine.program, command); timeout > 0 && total > timeout {
+		return timeout, cutPath(path, timeout), nil
+	}
+	return total, path, nil
+}
+
+// named simulates the command a step or need runs by name: its annotation, the mean of its
+// runs, or its steps
+func (s *simulator) named(name, label string) (time.Duration, []SimulatedStep, error) {
+	if duration, ok := s.estimates[name]; ok {
+		d, path := s.leaf(label, duration, SourceEstimate)
+		return d, path, nil
+	}
+	if stats, ok := s.history[name]; ok && stats.Runs > 0 {
+		d, path := s.leaf(label, time.Duration(stats.MeanMs)*time.Millisecond, SourceHistory)
+		return d, path, nil
+	}
+	command := s.engine.findCommand(name)
+	if command == nil || s.visiting[name] {
+		d, path := s.leaf(label, 0, SourceUnknown)
+		return d, path, nil
+	}
+	return s.command(command)
+}
+
+// leaf records a step whose duration is known as a whole
+func (s *simulator) leaf(name string, duration time.Duration, source string) (time.Duration, []SimulatedStep) {
+	s.sources[source]++
+	return duration, []SimulatedStep{{Step: name, Duration: duration, Source: source}}
+}
+
+// step simulates a step of a plan, returning its duration and critical path from its start
+func (s *simulator) step(step plan.ExecutionStep) (time.Duration, []SimulatedStep, error) {
+	name := simulatedName(step)
+	if duration, ok := s.estimates[name]; ok {
+		d, path := s.leaf(name, duration, SourceEstimate)
+		return d, path, nil
+	}
+
+	if step.Decorator == nil && len(step.Children) == 0 {
+		if target, ok := strings.CutPrefix(step.Command, "@cmd("); ok && strings.HasSuffix(target, ")") {
+			return s.named(strings.TrimSuffix(target, ")"), name)
+		}
+		if duration, ok := s.ran[name]; ok {
+			d, path := s.leaf(name, duration, SourceHistory)
+			return d, path, nil
+		}
+		d, path := s.leaf(name, 0, SourceUnknown)
+		return d, path, nil
+	}
+
+	if step.Type == plan.StepParallel {
+		return s.parallel(step)
+	}
+
+	var total time.Duration
+	var path []SimulatedStep
+	for _, child := range step.Children {
+		// A @try's catch block runs only when its main block fails
+		if child.Decorator != nil && child.Decorator.Type == "conditional" {
+			continue
+		}
+		duration, steps, err := s.step(child)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, simulated := range steps {
+			simulated.Start += total
+			path = append(path, simulated)
+		}
+		total += duration
+	}
+	if step.Timing != nil && step.Timing.Timeout != nil && total > *step.Timing.Timeout {
+		return *step.Timing.Timeout, cutPath(path, *step.Timing.Timeout), nil
+	}
+	return total, path, nil
+}
+
+// parallel simulates a @parallel block: each branch starts, in order, on the first worker to
+// become free, and the critical path follows the branch that finishes last back through the
+// branches that ran before it on its worker
+func (s *simulator) parallel(step plan.ExecutionStep) (time.Duration, []SimulatedStep, error) {
+	workers := len(step.Children)
+	if step.Timing != nil && step.Timing.ConcurrencyLimit > 0 && step.Timing.ConcurrencyLimit < workers {
+		workers = step.Timing.ConcurrencyLimit
+	}
+	if workers == 0 {
+		return 0, nil, nil
+	}
+
+	type branch struct {
+		start, end time.Duration
+		path       []SimulatedStep
+		previous   int // The branch that ran before it on its worker, or -1
+	}
+	free := make([]time.Duration, workers)
+	last := make([]int, workers)
+	for i := range last {
+		last[i] = -1
+	}
+	branches := make([]branch, len(step.Children))
+	finish := -1
+	for i, child := range step.Children {
+		duration, path, err := s.step(child)
+		if err != nil {
+			return 0, nil, err
+		}
+		worker := 0
+		for w := range free {
+			if free[w] < free[worker] {
+				worker = w
+			}
+		}
+		branches[i] = branch{start: free[worker], end: free[worker] + duration, path: path, previous: last[worker]}
+		free[worker], last[worker] = branches[i].end, i
+		if finish < 0 || branches[i].end > branches[finish].end {
+			finish = i
+		}
+	}
+
+	var path []SimulatedStep
+	for i := finish; i >= 0; i = branches[i].previous {
+		steps := make([]SimulatedStep, len(branches[i].path))
+		for j, simulated := range branches[i].path {
+			simulated.Start += branches[i].start
+			steps[j] = simulated
+		}
+		path = append(steps, path...)
+	}
+	return branches[finish].end, path, nil
+}
+
+// simulatedName names a plan step as annotations and critical paths refer to it: a shell step
+// by its text, and a decorator by its name
+func simulatedName(step plan.ExecutionStep) string {
+	switch {
+	case step.Decorator != nil:
+		return "@" + step.Decorator.Name
+	case step.Command != "":
+		return step.Command
+	default:
+		return step.Description
+	}
+}
+
+// cutPath drops the steps of a critical path that a timeout stops before they start, and
+// shortens the one it stops
+func cutPath(path []SimulatedStep, timeout time.Duration) []SimulatedStep {
+	var cut []SimulatedStep
+	for _, step := range path {
+		if step.Start >= timeout {
+			break
+		}
+		if step.Start+step.Duration > timeout {
+			step.Duration = timeout - step.Start
+		}
+		cut = append(cut, step)
+	}
+	return cut
+}
+
+// findCommand returns the program's command with the given name, or nil
+func (e *Engine) findCommand(name string) *ast.CommandDecl {
+	for i := range e.program.Commands {
+		if e.program.Commands[i].Name == name {
+			return &e.program.Commands[i]
+		}
+	}
+	return nil
+}
+
+// WriteText writes the simulated duration and the critical path behind it
+func (sim *Simulation) WriteText(w io.Writer) {
+	var counts []string
+	for _, source := range []string{SourceEstimate, SourceHistory, SourceUnknown} {
+		if n := sim.Sources[source]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, source))
+		}
+	}
+	fmt.Fprintf(w, "\nSimulated %s: %s (step durations: %s)\n", sim.Command, sim.Duration, strings.Join(counts, ", "))
+	if len(sim.CriticalPath) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Critical path:")
+	for _, step := range sim.CriticalPath {
+		fmt.Fprintf(w, "  %8s  +%-8s %s (%s)\n", step.Start, step.Duration, truncateStepName(step.Step), step.Source)
+	}
+	if sim.Sources[SourceUnknown] > 0 {
+		fmt.Fprintln(w, "Steps without a history or --estimate are assumed to take no time.")
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestSimulate_ParallelCriticalPath(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`build: {
+    echo setup
+    @parallel(limit = 2) {
+        sleep 1
+        sleep 2
+        sleep 3
+    }
+    @cmd(lint)
+}
+lint: echo lint`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	estimates, err := ParseEstimates([]string{"echo setup=500ms", "sleep 1=1s", "sleep 2=2s", "sleep 3=3s", "lint=250ms"})
+	if err != nil {
+		t.Fatalf("ParseEstimates failed: %v", err)
+	}
+
+	sim, err := New(program).Simulate(&program.Commands[0], estimates, nil)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+
+	// Two workers: sleep 1 and sleep 2 start at once, and sleep 3 starts when sleep 1 ends
+	if want := 500*time.Millisecond + 4*time.Second + 250*time.Millisecond; sim.Duration != want {
+		t.Errorf("Duration = %s, want %s", sim.Duration, want)
+	}
+	var path []string
+	for _, step := range sim.CriticalPath {
+		path = append(path, step.Step+"@"+step.Start.String())
+	}
+	if got, want := strings.Join(path, ", "), "echo setup@0s, sleep 1@500ms, sleep 3@1.5s, @cmd(lint)@4.5s"; got != want {
+		t.Errorf("critical path = %s, want %s", got, want)
+	}
+
+	var out bytes.Buffer
+	sim.WriteText(&out)
+	if !strings.Contains(out.String(), "Simulated build: 4.75s (step durations: 5 estimate)") {
+		t.Errorf("WriteText() =\n%s", out.String())
+	}
+}
+
+func TestSimulate_History(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`build: {
+    sleep 0.2
+    sleep 0.1
+}
+fast: @parallel {
+    sleep 0.2
+    sleep 0.1
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "durations", "project.json")
+
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	summary.Finish(FailOnAny)
+	if err := summary.UpdateDurations(path); err != nil {
+		t.Fatalf("UpdateDurations failed: %v", err)
+	}
+	history, err := LoadDurations(path)
+	if err != nil {
+		t.Fatalf("LoadDurations failed: %v", err)
+	}
+	if stats := history["build"]; stats.Runs != 1 || len(stats.Steps) != 2 || stats.Steps["1"].Name != "sleep 0.2" {
+		t.Fatalf("history = %+v, want one run of build's two steps", history)
+	}
+
+	// The steps build ran keep their durations inside fast's @parallel, which takes as long
+	// as the slower of them
+	sim, err := eng.Simulate(&program.Commands[1], nil, history)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if sim.Sources[SourceHistory] != 2 || sim.Duration < 200*time.Millisecond || sim.Duration > 280*time.Millisecond {
+		t.Errorf("simulation = %+v, want the duration of sleep 0.2 from history", sim)
+	}
+}
+
+func TestSimulate_Timeout(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`config {
+    defaultTimeout = 1m
+}
+build: {
+    go build ./...
+    go test ./...
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	estimates := map[string]time.Duration{"go build ./...": 40 * time.Second, "go test ./...": time.Minute}
+
+	sim, err := New(program).Simulate(&program.Commands[0], estimates, nil)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if sim.Duration != time.Minute || len(sim.CriticalPath) != 2 || sim.CriticalPath[1].Duration != 20*time.Second {
+		t.Errorf("simulation = %+v, want go test stopped by the defaultTimeout after 20s", sim)
+	}
+}
+
+func TestParseEstimates_Errors(t *testing.T) {
+	for _, value := range []string{"lint", "=1s", "lint=soon", "lint=-1s"} {
+		if _, err := ParseEstimates([]string{value}); err == nil {
+			t.Errorf("ParseEstimates(%q) succeeded", value)
+		}
+	}
+}
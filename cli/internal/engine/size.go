@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FeatureSize is the part of a binary a feature is alone in pulling in
+type FeatureSize struct {
+	Feature
+	Size     int64    // Bytes of the packages only this feature imports, directly or not
+	Packages []string // Those packages, largest first
+}
+
+// SizeReport attributes the size of a generated CLI binary to its features
+type SizeReport struct {
+	Binary   int64         // Size of the binary file
+	Features []FeatureSize // Largest first
+	Shared   int64         // Packages that several features import
+	Rest     int64         // The Go runtime, generated code and everything not in a symbol
+}
+
+// minSuggestedSize is the smallest part of a binary worth suggesting a removal for
+const minSuggestedSize = 64 << 10
+
+// NewSizeReport attributes the packages of a binary to the features that import them. deps
+// holds the dependencies of each imported package, as `go list` reports them in .Deps, and
+// sizes the bytes of each package's symbols, as read by ParseSymbolSizes.
+func NewSizeReport(binary int64, features []Feature, deps map[string][]string, sizes map[string]int64) *SizeReport {
+	// importers lists the features that pull each package in
+	importers := make(map[string][]int)
+	for i, feature := range features {
+		seen := make(map[string]bool)
+		for _, pkg := range feature.Imports {
+			for _, dep := range append([]string{pkg}, deps[pkg]...) {
+				if !seen[dep] {
+					seen[dep] = true
+					importers[dep] = append(importers[dep], i)
+				}
+			}
+		}
+	}
+
+	report := &SizeReport{Binary: binary}
+	attributed := make([]FeatureSize, len(features))
+	for i, feature := range features {
+		attributed[i].Feature = feature
+	}
+	var symbols int64
+	for pkg, size := range sizes {
+		symbols += size
+		switch users := importers[pkg]; len(users) {
+		case 0:
+			report.Rest += size
+		case 1:
+			attributed[users[0]].Size += size
+			attributed[users[0]].Packages = append(attributed[users[0]].Packages, pkg)
+		default:
+			report.Shared += size
+		}
+	}
+	if binary > symbols {
+		report.Rest += binary - symbols
+	}
+
+	for _, feature := range attributed {
+		sort.Slice(feature.Packages, func(i, j int) bool {
+			if sizes[feature.Packages[i]] != sizes[feature.Packages[j]] {
+				return sizes[feature.Packages[i]] > sizes[feature.Packages[j]]
+			}
+			return feature.Packages[i] < feature.Packages[j]
+		})
+		report.Features = append(report.Features, feature)
+	}
+	sort.SliceStable(report.Features, func(i, j int) bool {
+		return report.Features[i].Size > report.Features[j].Size
+	})
+	return report
+}
+
+// Suggestions names the features worth removing: those that only some commands use and
+// that add at least 64 KiB on their own
+func (r *SizeReport) Suggestions() []string {
+	var suggestions []string
+	for _, feature := range r.Features {
+		if len(feature.Commands) == 0 || feature.Size < minSuggestedSize {
+			continue
+		}
+		what := "removing it from " + joinNames(feature.Commands)
+		if feature.Name == "process management" {
+			what = "running " + joinNames(feature.Commands) + " some other way than as a watch command"
+		}
+		suggestions = append(suggestions, fmt.Sprintf("%s adds %s; %s would leave out %s",
+			feature.Name, FormatSize(feature.Size), what, countedNames(feature.Packages, 3, "package")))
+	}
+	return suggestions
+}
+
+// String renders the report as a table, largest part first, followed by the suggestions
+func (r *SizeReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s in total\n", FormatSize(r.Binary))
+	width := 0
+	for _, feature := range r.Features {
+		width = max(width, len(feature.Name))
+	}
+	for _, feature := range r.Features {
+		if feature.Size == 0 {
+			continue
+		}
+		line := fmt.Sprintf("  %9s  %-*s  %s", FormatSize(feature.Size), width, feature.Name, countedNames(feature.Packages, 3, "package"))
+		if len(feature.Commands) > 0 {
+			line += "; used by " + joinNames(feature.Commands)
+		}
+		b.WriteString(line + "\n")
+	}
+	fmt.Fprintf(&b, "  %9s  shared by several features\n", FormatSize(r.Shared))
+	fmt.Fprintf(&b, "  %9s  Go runtime and generated code\n", FormatSize(r.Rest))
+	if suggestions := r.Suggestions(); len(suggestions) > 0 {
+		b.WriteString("Suggestions:\n")
+		for _, suggestion := range suggestions {
+			b.WriteString("  - " + suggestion + "\n")
+		}
+	}
+	return b.String()
+}
+
+// ParseSymbolSizes sums the sizes of the symbols listed by `go tool nm -size` by package.
+// Only code and data count: zeroed (BSS) and undefined symbols take no space in the file.
+// Symbols that belong to no package, such as those the linker creates, are left out.
+func ParseSymbolSizes(r io.Reader) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// address size type name, where undefined symbols have no address
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || len(fields[2]) != 1 || !strings.Contains("TtRrDd", fields[2]) {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if pkg := symbolPackage(strings.Join(fields[3:], " ")); pkg != "" {
+			sizes[pkg] += size
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+// symbolPackage returns the import path of the package a symbol belongs to, e.g. "net/http"
+// for "net/http.(*Client).Do" or "type:*net/http.Client", or "" when it has none
+func symbolPackage(symbol string) string {
+	// Other go: symbols, such as go:string.* and go:func.*, are the linker's
+	if strings.HasPrefix(symbol, "go:") && !strings.HasPrefix(symbol, "go:itab.") {
+		return ""
+	}
+	for _, prefix := range []string{"type:.eq.", "type:.hash.", "type:", "go:itab.", "*"} {
+		symbol = strings.TrimPrefix(symbol, prefix)
+	}
+	// The package ends at the first dot after its last slash, before any receiver or type argument
+	end := strings.IndexAny(symbol, "([,")
+	if end < 0 {
+		end = len(symbol)
+	}
+	start := strings.LastIndex(symbol[:end], "/") + 1
+	dot := strings.Index(symbol[start:end], ".")
+	if dot <= 0 {
+		return ""
+	}
+	return symbol[:start+dot]
+}
+
+// FormatSize formats a byte count with a binary unit, e.g. "1.5 MiB"
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// joinNames lists names as "a, b and c"
+func joinNames(names []string) string {
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// countedNames lists up to limit names, counting the rest, e.g. "net/http, crypto/tls and 12 more packages"
+func countedNames(names []string, limit int, noun string) string {
+	if len(names) == 0 {
+		return "no " + noun + "s"
+	}
+	if len(names) <= limit {
+		return joinNames(names)
+	}
+	more := len(names) - limit
+	plural := noun
+	if more > 1 {
+		plural += "s"
+	}
+	return fmt.Sprintf("%s and %d more %s", strings.Join(names[:limit], ", "), more, plural)
+}
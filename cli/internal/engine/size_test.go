@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbolPackage(t *testing.T) {
+	testCases := map[string]string{
+		"net/http.(*Client).Do":                     "net/http",
+		"github.com/spf13/cobra.(*Command).Execute": "github.com/spf13/cobra",
+		"runtime.mallocgc":                          "runtime",
+		"type:*net/http.Client":                     "net/http",
+		"type:.eq.net/url.URL":                      "net/url",
+		"go:itab.*os.File,io.Reader":                "os",
+		"main.main.func12":                          "main",
+		"sync.(*Map).Load[go.shape.string]":         "sync",
+		"go:func.*":                                 "",
+		"_cgo_init":                                 "",
+	}
+	for symbol, want := range testCases {
+		if got := symbolPackage(symbol); got != want {
+			t.Errorf("symbolPackage(%q) = %q, want %q", symbol, got, want)
+		}
+	}
+}
+
+func TestParseSymbolSizes(t *testing.T) {
+	nm := `  4a2f20       1200 T net/http.(*Client).Do
+  4a3000        300 t net/http.send
+  b2b020      93464 B runtime.mheap_
+  af79c0        800 D runtime.buildVersion
+                  0 U pthread_create
+  9a5470     465104 r go:func.*
+`
+	sizes, err := ParseSymbolSizes(strings.NewReader(nm))
+	if err != nil {
+		t.Fatalf("ParseSymbolSizes failed: %v", err)
+	}
+	if len(sizes) != 2 || sizes["net/http"] != 1500 || sizes["runtime"] != 800 {
+		t.Errorf("sizes = %v, want net/http 1500 and runtime 800 without BSS symbols", sizes)
+	}
+}
+
+func TestNewSizeReport(t *testing.T) {
+	features := []Feature{
+		{Name: "CLI core", Imports: []string{"fmt", "os"}},
+		{Name: "process management", Commands: []string{"api"}, Imports: []string{"net", "os"}},
+		{Name: "@http", Commands: []string{"fetch", "deploy"}, Imports: []string{"net/http"}},
+		{Name: "@timeout", Commands: []string{"slow"}, Imports: []string{"time"}},
+	}
+	deps := map[string][]string{
+		"fmt":      {"runtime"},
+		"os":       {"runtime"},
+		"net":      {"os", "runtime", "internal/poll"},
+		"net/http": {"net", "os", "internal/poll", "crypto/tls", "runtime"},
+	}
+	sizes := map[string]int64{
+		"runtime":       400 << 10,
+		"fmt":           50 << 10,
+		"net":           200 << 10,
+		"internal/poll": 20 << 10,
+		"net/http":      900 << 10,
+		"crypto/tls":    600 << 10,
+		"main":          30 << 10,
+	}
+	report := NewSizeReport(4<<20, features, deps, sizes)
+
+	want := map[string]int64{
+		"@http":              1500 << 10,
+		"CLI core":           50 << 10,
+		"process management": 0, // net and internal/poll are shared with @http
+		"@timeout":           0, // time has no symbols here
+	}
+	for _, feature := range report.Features {
+		if feature.Size != want[feature.Name] {
+			t.Errorf("%s size = %d, want %d", feature.Name, feature.Size, want[feature.Name])
+		}
+	}
+	if report.Features[0].Name != "@http" || strings.Join(report.Features[0].Packages, ",") != "net/http,crypto/tls" {
+		t.Errorf("largest feature = %s with %v, want @http with net/http and crypto/tls", report.Features[0].Name, report.Features[0].Packages)
+	}
+	if report.Shared != 620<<10 {
+		t.Errorf("shared = %d, want %d", report.Shared, 620<<10)
+	}
+	// main, which no import pulls in, plus what isn't in a symbol
+	if report.Rest != 30<<10+(4<<20-2200<<10) {
+		t.Errorf("rest = %d, want %d", report.Rest, 30<<10+(4<<20-2200<<10))
+	}
+
+	suggestions := report.Suggestions()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "@http adds 1.5 MiB; removing it from fetch and deploy would leave out net/http and crypto/tls") {
+		t.Errorf("suggestions = %q, want one for @http", suggestions)
+	}
+	if text := report.String(); !strings.Contains(text, "4.0 MiB in total") || !strings.Contains(text, "used by fetch and deploy") {
+		t.Errorf("report =\n%s", text)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		512:        "512 B",
+		1536:       "1.5 KiB",
+		10 << 20:   "10.0 MiB",
+		3 << 30:    "3.0 GiB",
+		1023 << 10: "1023.0 KiB",
+	} {
+		if got := FormatSize(bytes); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
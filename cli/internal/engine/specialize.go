@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// Specialize returns a copy of program for a CLI built with `devcmd build --define`: variables
+// named in defines take their build-time values, and each @when on a defined name is replaced
+// by the steps of the branch that value selects, so the other branches, and the secrets and
+// commands only they use, are left out of the binary. A @when whose value selects no branch
+// is dropped. program itself is not modified.
+func Specialize(program *ast.Program, defines map[string]string) (*ast.Program, error) {
+	if len(defines) == 0 {
+		return program, nil
+	}
+
+	used := make(map[string]bool)
+	define := func(variables []ast.VariableDecl) []ast.VariableDecl {
+		variables = append([]ast.VariableDecl(nil), variables...)
+		for i := range variables {
+			if value, ok := defines[variables[i].Name]; ok {
+				variables[i].Value = &ast.StringLiteral{Value: value, Pos: variables[i].Value.Position()}
+				used[variables[i].Name] = true
+			}
+		}
+		return variables
+	}
+
+	var specializeContent func(content []ast.CommandContent) []ast.CommandContent
+	specializeContent = func(content []ast.CommandContent) []ast.CommandContent {
+		var specialized []ast.CommandContent
+		for _, item := range content {
+			switch c := item.(type) {
+			case *ast.BlockDecorator:
+				block := *c
+				block.Content = specializeContent(c.Content)
+				specialized = append(specialized, &block)
+			case *ast.PatternDecorator:
+				name := ast.GetNameParam(c.Args, "variable", "")
+				if value, ok := defines[name]; ok && c.Name == "when" {
+					used[name] = true
+					if branch := selectBranch(c.Patterns, value); branch != nil {
+						specialized = append(specialized, specializeContent(branch.Commands)...)
+					}
+					continue
+				}
+				pattern := *c
+				pattern.Patterns = make([]ast.PatternBranch, len(c.Patterns))
+				for i, branch := range c.Patterns {
+					branch.Commands = specializeContent(branch.Commands)
+					pattern.Patterns[i] = branch
+				}
+				specialized = append(specialized, &pattern)
+			default:
+				specialized = append(specialized, item)
+			}
+		}
+		return specialized
+	}
+
+	result := *program
+	result.Variables = define(program.Variables)
+	result.VarGroups = append([]ast.VarGroup(nil), program.VarGroups...)
+	for i := range result.VarGroups {
+		result.VarGroups[i].Variables = define(result.VarGroups[i].Variables)
+	}
+	result.Commands = append([]ast.CommandDecl(nil), program.Commands...)
+	for i := range result.Commands {
+		result.Commands[i].Body.Content = specializeContent(result.Commands[i].Body.Content)
+	}
+	result.Triggers = append([]ast.TriggerDecl(nil), program.Triggers...)
+	for i := range result.Triggers {
+		result.Triggers[i].Body.Content = specializeContent(result.Triggers[i].Body.Content)
+	}
+
+	var unused []string
+	for name := range defines {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("--define %s: not a variable and not used by @when", strings.Join(unused, ", "))
+	}
+	return &result, nil
+}
+
+// selectBranch returns the first branch of a @when that matches value, as the interpreter
+// picks it, or nil when none does
+func selectBranch(branches []ast.PatternBranch, value string) *ast.PatternBranch {
+	for i := range branches {
+		switch pattern := branches[i].Pattern.(type) {
+		case *ast.IdentifierPattern:
+			if pattern.Name == value {
+				return &branches[i]
+			}
+		case *ast.StringPattern:
+			// The parser rejects malformed patterns
+			if matched, _ := pattern.Matches(value); matched {
+				return &branches[i]
+			}
+		case *ast.WildcardPattern:
+			return &branches[i]
+		}
+	}
+	return nil
+}
+
+// DefineArgs returns the --define flags that build a CLI with defines, in a stable order
+func DefineArgs(defines map[string]string) []string {
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		args = append(args, "--define", name+"="+defines[name])
+	}
+	return args
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+const specializeCommands = `var REGION = "eu"
+deploy: @when("ENV") {
+    prod: kubectl apply -f prod.yaml
+    dev: kubectl apply -f dev.yaml
+    default: echo "unknown environment"
+}
+region: @when("REGION") {
+    us: echo us
+}
+show: echo @var(REGION)
+on success of deploy: @when("ENV") {
+    prod: echo notify prod
+    dev: echo notify dev
+}`
+
+// steps returns a body's steps as source text, one step per line
+func steps(content []ast.CommandContent) string {
+	var lines []string
+	for _, item := range content {
+		lines = append(lines, strings.Join(strings.Fields(item.String()), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestSpecialize(t *testing.T) {
+	deployWhen := `@when(ENV) { prod: kubectl apply -f prod.yaml; dev: kubectl apply -f dev.yaml; *: echo "unknown environment" }`
+	testCases := []struct {
+		name    string
+		defines map[string]string
+		deploy  string
+		region  string
+		trigger string
+	}{
+		{
+			name:    "the selected branch is inlined",
+			defines: map[string]string{"ENV": "prod"},
+			deploy:  "kubectl apply -f prod.yaml",
+			region:  "@when(REGION) { us: echo us }",
+			trigger: "echo notify prod",
+		},
+		{
+			name:    "an unmatched value takes the default branch",
+			defines: map[string]string{"ENV": "qa"},
+			deploy:  `echo "unknown environment"`,
+			region:  "@when(REGION) { us: echo us }",
+			trigger: "",
+		},
+		{
+			name:    "a @when without a matching branch is dropped",
+			defines: map[string]string{"REGION": "ap"},
+			deploy:  deployWhen,
+			region:  "",
+			trigger: "@when(ENV) { prod: echo notify prod; dev: echo notify dev }",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := parser.Parse(strings.NewReader(specializeCommands))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			original := program.String()
+
+			specialized, err := Specialize(program, tc.defines)
+			if err != nil {
+				t.Fatalf("Specialize failed: %v", err)
+			}
+			if got := steps(specialized.Commands[0].Body.Content); got != tc.deploy {
+				t.Errorf("deploy steps = %q, want %q", got, tc.deploy)
+			}
+			if got := steps(specialized.Commands[1].Body.Content); got != tc.region {
+				t.Errorf("region steps = %q, want %q", got, tc.region)
+			}
+			if got := steps(specialized.Triggers[0].Body.Content); got != tc.trigger {
+				t.Errorf("trigger steps = %q, want %q", got, tc.trigger)
+			}
+			if program.String() != original {
+				t.Errorf("Specialize modified the original program")
+			}
+		})
+	}
+}
+
+func TestSpecialize_DefinesVariables(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(specializeCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	specialized, err := Specialize(program, map[string]string{"REGION": "us"})
+	if err != nil {
+		t.Fatalf("Specialize failed: %v", err)
+	}
+	if value := specialized.Variables[0].Value.String(); !strings.Contains(value, "us") {
+		t.Errorf("REGION = %s, want the defined value", value)
+	}
+	if value := program.Variables[0].Value.String(); !strings.Contains(value, "eu") {
+		t.Errorf("original REGION = %s, want it unchanged", value)
+	}
+}
+
+func TestSpecialize_GlobAndRegexBranches(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`release: @when("BRANCH") {
+    main: echo main
+    "release/*": echo release
+    "/^v[0-9]+$/": echo version
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for branch, want := range map[string]string{"main": "echo main", "release/1.2": "echo release", "v3": "echo version", "v3-rc": ""} {
+		specialized, err := Specialize(program, map[string]string{"BRANCH": branch})
+		if err != nil {
+			t.Fatalf("Specialize failed: %v", err)
+		}
+		if got := steps(specialized.Commands[0].Body.Content); got != want {
+			t.Errorf("BRANCH=%s: release = %q, want %q", branch, got, want)
+		}
+	}
+}
+
+func TestSpecialize_UnusedDefine(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(specializeCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = Specialize(program, map[string]string{"ENV": "prod", "ENVV": "prod"})
+	if err == nil || !strings.Contains(err.Error(), "ENVV") || strings.Contains(err.Error(), "ENV,") {
+		t.Errorf("Specialize error = %v, want one naming only ENVV", err)
+	}
+}
+
+func TestSpecialize_GeneratedCodeLeavesOutOtherBranches(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(specializeCommands))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	defines := map[string]string{"ENV": "dev"}
+	specialized, err := Specialize(program, defines)
+	if err != nil {
+		t.Fatalf("Specialize failed: %v", err)
+	}
+
+	eng := New(specialized)
+	eng.SetCLIOptions(CLIOptions{Regenerate: true, Defines: defines})
+	eng.SetSourceFile("commands.cli")
+	eng.SetSourceHash(SourceHash([]byte(specializeCommands)))
+	result, err := eng.GenerateCode(specialized)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	code := result.Code.String()
+
+	if !strings.Contains(code, "dev.yaml") || !strings.Contains(code, "notify dev") {
+		t.Errorf("generated code is missing the selected branches")
+	}
+	for _, left := range []string{"prod.yaml", "notify prod", "unknown environment", "ENVValue"} {
+		if strings.Contains(code, left) {
+			t.Errorf("generated code contains %q from a branch that wasn't selected", left)
+		}
+	}
+	if !strings.Contains(code, `"--define", "ENV=dev"`) {
+		t.Errorf("regeneration doesn't pass on --define ENV=dev")
+	}
+}
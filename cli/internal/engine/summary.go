@@ -0,0 +1,300 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// FailurePolicy decides whether a run with failed commands fails as a whole
+type FailurePolicy string
+
+const (
+	FailOnAny  FailurePolicy = "any"  // Fail if any command failed
+	FailOnAll  FailurePolicy = "all"  // Fail only if every command that ran failed
+	FailOnNone FailurePolicy = "none" // Never fail; the summary only reports
+)
+
+// ParseFailurePolicy resolves a --fail-on value to a policy
+func ParseFailurePolicy(value string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(value); policy {
+	case FailOnAny, FailOnAll, FailOnNone:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported failure policy %q: expected any, all, or none", value)
+	}
+}
+
+// StepSummary is one row of a run summary: a top-level step, or a whole command
+// when it failed before any step ran or was skipped
+type StepSummary struct {
+	Command    string `json:"command"`
+	Step       int    `json:"step,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Status     string `json:"status"` // success, failed, or skipped
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	Attempts       int `json:"attempts,omitempty"`        // Attempts made by @retry blocks in the step
+	FailedAttempts int `json:"failed_attempts,omitempty"` // Attempts that failed and were retried or exhausted
+}
+
+// CommandSummary is the outcome of one command in a run
+type CommandSummary struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // success, failed, or skipped
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// RunSummary collects the outcome of every command and step in a run
+type RunSummary struct {
+	Status     string           `json:"status"` // success or failed, after applying the policy
+	Policy     FailurePolicy    `json:"policy"`
+	DurationMs int64            `json:"duration_ms"`
+	Commands   []CommandSummary `json:"commands"`
+	Steps      []StepSummary    `json:"steps"`
+	Flakiness  []FlakinessStats `json:"flakiness,omitempty"` // History of the commands that ran @retry, after UpdateFlakiness
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+// Summarize registers hooks that record step and command outcomes into a new summary
+func (e *Engine) Summarize() *RunSummary {
+	s := &RunSummary{
+		Commands: []CommandSummary{},
+		Steps:    []StepSummary{},
+		start:    time.Now(),
+	}
+
+	e.AddHook(execution.EventPostStep, func(ev execution.Event) error {
+		s.addStep(StepSummary{
+			Command:    ev.Command,
+			Step:       ev.Step,
+			Name:       ev.StepName,
+			Status:     ev.Status,
+			DurationMs: ev.Duration.Milliseconds(),
+			Error:      errorString(ev.Err),
+
+			Attempts:       ev.Attempts,
+			FailedAttempts: ev.FailedAttempts,
+		})
+		return nil
+	})
+	e.AddHook(execution.EventFailure, func(ev execution.Event) error {
+		// Failures before the first step, such as variable initialization, have no step row
+		if !s.hasFailedStep(ev.Command) {
+			s.addStep(StepSummary{
+				Command:    ev.Command,
+				Status:     "failed",
+				DurationMs: ev.Duration.Milliseconds(),
+				Error:      errorString(ev.Err),
+			})
+		}
+		return nil
+	})
+	e.AddHook(execution.EventPostCommand, func(ev execution.Event) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.Commands = append(s.Commands, CommandSummary{Name: ev.Command, Status: ev.Status, DurationMs: ev.Duration.Milliseconds()})
+		return nil
+	})
+
+	return s
+}
+
+// Record adds a command whose outcome the hooks did not see, such as one vetoed by a preRun hook
+func (s *RunSummary) Record(result *CommandResult) {
+	s.mu.Lock()
+	for _, c := range s.Commands {
+		if c.Name == result.Name {
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.Commands = append(s.Commands, CommandSummary{Name: result.Name, Status: result.Status})
+	s.mu.Unlock()
+	if result.Status == "failed" {
+		s.addStep(StepSummary{Command: result.Name, Status: "failed", Error: result.Error})
+	}
+}
+
+// Skip records a command that was not run because an earlier command failed
+func (s *RunSummary) Skip(command string) {
+	s.mu.Lock()
+	s.Commands = append(s.Commands, CommandSummary{Name: command, Status: "skipped"})
+	s.mu.Unlock()
+	s.addStep(StepSummary{Command: command, Status: "skipped"})
+}
+
+// Finish applies the failure policy and records the total duration.
+// It reports whether the run failed.
+func (s *RunSummary) Finish(policy FailurePolicy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ran, failed int
+	for _, c := range s.Commands {
+		switch c.Status {
+		case "success":
+			ran++
+		case "failed":
+			ran++
+			failed++
+		}
+	}
+
+	runFailed := false
+	switch policy {
+	case FailOnAny:
+		runFailed = failed > 0
+	case FailOnAll:
+		runFailed = ran > 0 && failed == ran
+	}
+
+	s.Policy = policy
+	s.Status = "success"
+	if runFailed {
+		s.Status = "failed"
+	}
+	s.DurationMs = time.Since(s.start).Milliseconds()
+	return runFailed
+}
+
+// Counts returns the number of summary rows that succeeded, failed, and were skipped
+func (s *RunSummary) Counts() (succeeded, failed, skipped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, step := range s.Steps {
+		switch step.Status {
+		case "success":
+			succeeded++
+		case "failed":
+			failed++
+		case "skipped":
+			skipped++
+		}
+	}
+	return succeeded, failed, skipped
+}
+
+// WriteText writes the summary as a step → status → duration table
+func (s *RunSummary) WriteText(w io.Writer) error {
+	succeeded, failed, skipped := s.Counts()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "\nSummary: %d succeeded, %d failed, %d skipped in %s\n",
+		succeeded, failed, skipped, formatDurationMs(s.DurationMs))
+	if logging.Accessible() {
+		s.writeLines(w)
+		s.writeFlakiness(w)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  COMMAND\tSTEP\tSTATUS\tDURATION")
+	for _, step := range s.Steps {
+		name := "-"
+		if step.Step > 0 {
+			name = fmt.Sprintf("%d. %s", step.Step, truncateStepName(step.Name))
+		}
+		duration := "-"
+		if step.Status != "skipped" {
+			duration = formatDurationMs(step.DurationMs)
+		}
+		status := step.Status
+		if step.FailedAttempts > 0 {
+			status += fmt.Sprintf(" (%d of %d attempts failed)", step.FailedAttempts, step.Attempts)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", step.Command, name, status, duration)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	s.writeFlakiness(w)
+	return nil
+}
+
+// writeLines writes each step of the summary as a sentence rather than a table row, for
+// accessible output: "build, step 1, go build ./...: failed after 2.1s"
+func (s *RunSummary) writeLines(w io.Writer) {
+	for _, step := range s.Steps {
+		name := step.Command
+		if step.Step > 0 {
+			name += fmt.Sprintf(", step %d, %s", step.Step, truncateStepName(step.Name))
+		}
+		status := step.Status
+		switch step.Status {
+		case "skipped":
+		case "failed":
+			status += " after " + formatDurationMs(step.DurationMs)
+		default:
+			status += " in " + formatDurationMs(step.DurationMs)
+		}
+		if step.FailedAttempts > 0 {
+			status += fmt.Sprintf(", %d of %d attempts failed", step.FailedAttempts, step.Attempts)
+		}
+		fmt.Fprintf(w, "%s: %s\n", name, status)
+	}
+}
+
+// WriteJSON writes the summary as indented JSON
+func (s *RunSummary) WriteJSON(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// addStep appends a summary row
+func (s *RunSummary) addStep(step StepSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Steps = append(s.Steps, step)
+}
+
+// hasFailedStep reports whether a failed step was already recorded for the command
+func (s *RunSummary) hasFailedStep(command string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, step := range s.Steps {
+		if step.Command == command && step.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// errorString returns the error message, or empty for nil
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// formatDurationMs formats a millisecond duration for the summary table
+func formatDurationMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// truncateStepName keeps long shell steps to a single readable table cell
+func truncateStepName(name string) string {
+	if i := strings.IndexByte(name, '\n'); i >= 0 {
+		name = name[:i] + " …"
+	}
+	if len([]rune(name)) > 48 {
+		name = string([]rune(name)[:47]) + "…"
+	}
+	return name
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+func TestSummary_RecordsStepsAndSkips(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("lint: {\n  echo one\n  echo two\n}\ntest: {\n  exit 3\n  echo unreachable\n}\nbuild: echo build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err != nil {
+		t.Fatalf("lint failed: %v", err)
+	}
+	if _, err := eng.ExecuteCommand(&program.Commands[1]); err == nil {
+		t.Fatal("expected test to fail")
+	}
+	summary.Skip("build")
+
+	if !summary.Finish(FailOnAny) {
+		t.Error("run should fail with policy any")
+	}
+
+	var rows []string
+	for _, step := range summary.Steps {
+		rows = append(rows, step.Command+":"+step.Name+":"+step.Status)
+	}
+	expected := []string{"lint:echo one:success", "lint:echo two:success", "test:exit 3:failed", "build::skipped"}
+	if strings.Join(rows, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected summary rows:\n got: %v\nwant: %v", rows, expected)
+	}
+	if succeeded, failed, skipped := summary.Counts(); succeeded != 2 || failed != 1 || skipped != 1 {
+		t.Errorf("Counts() = %d, %d, %d, want 2, 1, 1", succeeded, failed, skipped)
+	}
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	for _, want := range []string{"Summary: 2 succeeded, 1 failed, 1 skipped", "2. echo two", "build    -"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text summary missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := summary.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded struct {
+		Status   string
+		Commands []CommandSummary
+		Steps    []StepSummary
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if decoded.Status != "failed" || len(decoded.Commands) != 3 || decoded.Steps[2].Error != "exit status 3" {
+		t.Errorf("unexpected JSON summary:\n%s", out.String())
+	}
+}
+
+func TestSummary_Accessible(t *testing.T) {
+	defer logging.SetAccessible(logging.Accessible())
+	logging.SetAccessible(true)
+	program, err := parser.Parse(strings.NewReader("test: {\n  echo one\n  exit 3\n}\nbuild: echo build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err == nil {
+		t.Fatal("expected test to fail")
+	}
+	summary.Skip("build")
+	summary.Finish(FailOnAny)
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	for _, want := range []string{"test, step 1, echo one: success in ", "test, step 2, exit 3: failed after ", "build: skipped\n"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("accessible summary missing %q:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestSummary_FailureBeforeFirstStep(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: echo build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	summary := eng.Summarize()
+	eng.AddHook(execution.EventPreCommand, func(event execution.Event) error {
+		return errors.New("preRun hook failed")
+	})
+	result, err := eng.ExecuteCommand(&program.Commands[0])
+	if err == nil {
+		t.Fatal("expected command to fail")
+	}
+	summary.Record(result)
+
+	if !summary.Finish(FailOnAny) {
+		t.Error("a vetoed command should fail the run")
+	}
+	if len(summary.Steps) != 1 || summary.Steps[0].Step != 0 || summary.Steps[0].Error != "preRun hook failed" {
+		t.Errorf("expected a command-level failure row, got %+v", summary.Steps)
+	}
+}
+
+func TestSummary_FailurePolicies(t *testing.T) {
+	testCases := []struct {
+		policy   FailurePolicy
+		statuses []string
+		want     bool
+	}{
+		{FailOnAny, []string{"success", "failed"}, true},
+		{FailOnAny, []string{"success", "success"}, false},
+		{FailOnAll, []string{"success", "failed"}, false},
+		{FailOnAll, []string{"failed", "failed"}, true},
+		{FailOnAll, []string{"failed", "skipped"}, true},
+		{FailOnNone, []string{"failed", "failed"}, false},
+	}
+
+	for _, tc := range testCases {
+		summary := &RunSummary{}
+		for _, status := range tc.statuses {
+			summary.Commands = append(summary.Commands, CommandSummary{Status: status})
+		}
+		if got := summary.Finish(tc.policy); got != tc.want {
+			t.Errorf("Finish(%s) with %v = %v, want %v", tc.policy, tc.statuses, got, tc.want)
+		}
+	}
+
+	if _, err := ParseFailurePolicy("some"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
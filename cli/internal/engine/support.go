@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+)
+
+// TargetOS returns the operating system generated CLIs are built for: GOOS, which go build
+// reads from the environment devcmd build runs it in, or this platform's
+func TargetOS() string {
+	if goos := os.Getenv("GOOS"); goos != "" {
+		return goos
+	}
+	return runtime.GOOS
+}
+
+// unsupportedDecorators returns why each decorator used in node can't run in mode on goos,
+// with where it is used
+func unsupportedDecorators(node ast.Node, mode decorators.Mode, goos string) []string {
+	var problems []string
+	ast.Walk(node, func(n ast.Node) bool {
+		var name string
+		var pos ast.Position
+		switch d := n.(type) {
+		case *ast.ValueDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.ActionDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.BlockDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.PatternDecorator:
+			name, pos = d.Name, d.Pos
+		default:
+			return true
+		}
+		// Unknown decorators are reported where they are looked up
+		if info, ok := decorators.Describe(name); ok {
+			if err := info.Supports(mode, goos); err != nil {
+				problems = append(problems, fmt.Sprintf("%d:%d: %v", pos.Line, pos.Column, err))
+			}
+		}
+		return true
+	})
+	return problems
+}
+
+// checkSupport fails when the commands use decorators that can't run in mode on goos,
+// reporting every one together before anything runs or is generated
+func checkSupport(mode decorators.Mode, goos string, commands []*ast.CommandDecl, triggers []*ast.TriggerDecl) error {
+	var problems []string
+	for _, command := range commands {
+		for _, problem := range unsupportedDecorators(&command.Body, mode, goos) {
+			problems = append(problems, fmt.Sprintf("%s: %s", command.Name, problem))
+		}
+	}
+	for _, trigger := range triggers {
+		for _, problem := range unsupportedDecorators(&trigger.Body, mode, goos) {
+			problems = append(problems, fmt.Sprintf("%s: %s", trigger.Name(), problem))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unsupported decorators:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// checkInterpreterSupport checks the decorators of command, of the commands it runs with @cmd
+// or needs, and of their success and failure triggers, on the platform goos
+func (e *Engine) checkInterpreterSupport(command *ast.CommandDecl, goos string) error {
+	var commands []*ast.CommandDecl
+	var triggers []*ast.TriggerDecl
+	seen := map[string]bool{command.Name: true}
+	queue := []*ast.CommandDecl{command}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		commands = append(commands, current)
+		triggers = append(triggers, e.triggersOf(current.Name, ast.TriggerOnSuccess)...)
+		triggers = append(triggers, e.triggersOf(current.Name, ast.TriggerOnFailure)...)
+		for _, name := range e.findCommandDependencies(current) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			for i := range e.program.Commands {
+				if dependency := &e.program.Commands[i]; dependency.Name == name && dependency.Type == ast.Command {
+					queue = append(queue, dependency)
+				}
+			}
+		}
+	}
+	return checkSupport(decorators.InterpreterMode, goos, commands, triggers)
+}
+
+// checkPlanSupport checks the decorators of command can describe themselves in a plan
+func checkPlanSupport(command *ast.CommandDecl) error {
+	return checkSupport(decorators.PlanMode, runtime.GOOS, []*ast.CommandDecl{command}, nil)
+}
+
+// checkGeneratorSupport checks the decorators of everything generated into the CLI for
+// program, for the platform it is built for
+func checkGeneratorSupport(program *ast.Program) error {
+	var commands []*ast.CommandDecl
+	var triggers []*ast.TriggerDecl
+	for i := range program.Commands {
+		commands = append(commands, &program.Commands[i])
+	}
+	// On change triggers run under the devcmd daemon rather than in the CLI
+	for i := range program.Triggers {
+		if program.Triggers[i].Event != ast.TriggerOnChange {
+			triggers = append(triggers, &program.Triggers[i])
+		}
+	}
+	return checkSupport(decorators.GeneratorMode, TargetOS(), commands, triggers)
+}
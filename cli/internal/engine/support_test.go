@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestGenerate_RejectsDecoratorsUnsupportedOnTarget(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("term: @pty { top }\ncheck: @sandbox { go test ./... }\nbuild: go build"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	t.Setenv("GOOS", "windows")
+	_, err = New(program).GenerateCode(program)
+	if err == nil {
+		t.Fatal("expected generation for windows to fail")
+	}
+	for _, want := range []string{
+		"unsupported decorators:",
+		"term: 1:7: @pty is not supported in CLIs built for windows (it supports darwin, linux)",
+		"check: 2:8: @sandbox is not supported in CLIs built for windows (it supports darwin, linux)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+
+	t.Setenv("GOOS", "linux")
+	if _, err := New(program).GenerateCode(program); err != nil {
+		t.Errorf("generation for linux failed: %v", err)
+	}
+}
+
+func TestCheckInterpreterSupport_FollowsCommandReferences(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("check: @cmd(test)\ntest: @sandbox { go test ./... }"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	err = eng.checkInterpreterSupport(&program.Commands[0], "windows")
+	if err == nil || !strings.Contains(err.Error(), "test: 2:7: @sandbox is not supported on windows") {
+		t.Errorf("err = %v, want @sandbox in test reported as unsupported", err)
+	}
+	if err := eng.checkInterpreterSupport(&program.Commands[0], "linux"); err != nil {
+		t.Errorf("checkInterpreterSupport on linux failed: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// triggersOf returns the triggers of a command for an outcome, in the order they are declared
+func (e *Engine) triggersOf(command string, event ast.TriggerEvent) []*ast.TriggerDecl {
+	var triggers []*ast.TriggerDecl
+	for i := range e.program.Triggers {
+		trigger := &e.program.Triggers[i]
+		if trigger.Command == command && trigger.Event == event {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+// runTriggers runs the triggers of a command that finished, failing with err or succeeding when
+// it's nil. Like a failed hook, a failed trigger is reported as the command's failure only if
+// the command itself succeeded.
+func (e *Engine) runTriggers(command *ast.CommandDecl, err error) error {
+	event := ast.TriggerOnSuccess
+	if err != nil {
+		event = ast.TriggerOnFailure
+	}
+
+	var triggerErr error
+	for _, trigger := range e.triggersOf(command.Name, event) {
+		if err := e.RunTrigger(trigger); err != nil && triggerErr == nil {
+			triggerErr = err
+		}
+	}
+	return triggerErr
+}
+
+// RunTrigger runs the body of a trigger as a command of its own, named as the trigger is
+// written, so hooks and summaries see it
+func (e *Engine) RunTrigger(trigger *ast.TriggerDecl) error {
+	decl := &ast.CommandDecl{Name: trigger.Name(), Body: trigger.Body, Pos: trigger.Pos}
+	if _, err := e.ExecuteCommand(decl); err != nil {
+		return fmt.Errorf("%s: %w", decl.Name, err)
+	}
+	return nil
+}
+
+// triggerPlans returns a plan element for each trigger of a command, showing the steps it runs
+// and on which outcome
+func (e *Engine) triggerPlans(ctx execution.PlanContext, command *ast.CommandDecl) ([]plan.PlanElement, error) {
+	var elements []plan.PlanElement
+	for _, event := range []ast.TriggerEvent{ast.TriggerOnSuccess, ast.TriggerOnFailure} {
+		for _, trigger := range e.triggersOf(command.Name, event) {
+			element := plan.Sequence().WithDescription(trigger.Name())
+			for _, content := range trigger.Body.Content {
+				child, err := e.contentPlan(ctx, content)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", trigger.Name(), err)
+				}
+				if child != nil {
+					element.AddChild(child)
+				}
+			}
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestTriggers_RunOnOutcome(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	source := `deploy: echo deploy >> ` + log + ` && exit 3
+build: echo build >> ` + log + `
+rollback: echo rollback >> ` + log + `
+on failure of deploy: @cmd(rollback)
+on success of deploy: echo never >> ` + log + `
+on success of build: echo notify >> ` + log
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	eng := New(program)
+	summary := eng.Summarize()
+	if _, err := eng.ExecuteCommand(&program.Commands[0]); err == nil {
+		t.Fatal("deploy succeeded, want its own failure despite the rollback")
+	}
+	if _, err := eng.ExecuteCommand(&program.Commands[1]); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("reading log: %v", err)
+	}
+	if got, want := strings.Fields(string(data)), "deploy rollback build notify"; strings.Join(got, " ") != want {
+		t.Errorf("ran %v, want %s", got, want)
+	}
+
+	var names []string
+	for _, command := range summary.Commands {
+		names = append(names, command.Name+"="+command.Status)
+	}
+	if got, want := strings.Join(names, ","), "deploy=failed,on failure of deploy=success,build=success,on success of build=success"; got != want {
+		t.Errorf("summary commands = %s, want %s", got, want)
+	}
+}
+
+func TestTriggers_FailedTriggerFailsCommand(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("build: echo build\non success of build: exit 1"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	_, err = New(program).ExecuteCommand(&program.Commands[0])
+	if err == nil || !strings.Contains(err.Error(), "on success of build") {
+		t.Errorf("err = %v, want the failed trigger", err)
+	}
+}
+
+func TestTriggers_Plan(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("deploy: ./deploy.sh\nrollback: ./rollback.sh\non failure of deploy: @cmd(rollback)"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	execPlan, err := New(program).ExecuteCommandPlan(&program.Commands[0])
+	if err != nil {
+		t.Fatalf("ExecuteCommandPlan failed: %v", err)
+	}
+	output := execPlan.StringNoColor()
+	if !strings.Contains(output, "on failure of deploy") || !strings.Contains(output, "@cmd") {
+		t.Errorf("plan does not show the trigger:\n%s", output)
+	}
+}
+
+func TestTriggers_GenerateCode(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("deploy: ./deploy.sh\nrollback: ./rollback.sh\non failure of deploy: @cmd(rollback)\non success of deploy: echo deployed"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	code := result.String()
+	for _, want := range []string{`ciStep("on failure of deploy", 1`, `ciStep("on success of deploy", 1`, `fmt.Sprintf(msg("trigger.failed"), "deploy"`} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code is missing %q", want)
+		}
+	}
+
+	program, err = parser.Parse(strings.NewReader("watch server: ./serve\non failure of server: echo crashed"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := New(program).GenerateCode(program); err == nil || !strings.Contains(err.Error(), "triggers of watch commands") {
+		t.Errorf("GenerateCode error = %v, want triggers of watch commands rejected", err)
+	}
+}
+
+func TestTriggers_GenerateCodeImportsTriggerDecorators(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader("deploy: ./deploy.sh\non failure of deploy: @timeout(1m) { ./rollback.sh }"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result, err := New(program).GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if !result.StandardImports["context"] {
+		t.Errorf("generated code doesn't import context for the trigger's @timeout")
+	}
+}
@@ -18,9 +18,12 @@ type CommandResult struct {
 	Error  string   // Error message if failed
 }
 
-// GenerationResult represents the result of generating Go code
+// GenerationResult represents the result of generating code with a backend. The imports,
+// modules and go.mod are only set by the Go backend.
 type GenerationResult struct {
-	Code              strings.Builder   // Generated Go code
+	FileName          string            // Name Code is written as, e.g. main.go
+	Code              strings.Builder   // Generated code
+	Files             map[string]string // Files written beside Code by name, e.g. build-tagged sources
 	GoMod             strings.Builder   // Generated go.mod file
 	StandardImports   map[string]bool   // Standard library imports
 	ThirdPartyImports map[string]bool   // Third-party imports
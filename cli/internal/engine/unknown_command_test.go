@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// buildTestCLI generates, compiles, and returns the path to a CLI binary for the given input
+func buildTestCLI(t *testing.T, input string) string {
+	t.Helper()
+	return buildTestCLIWithOptions(t, input, CLIOptions{})
+}
+
+// buildTestCLIWithOptions is buildTestCLI with custom dispatch options
+func buildTestCLIWithOptions(t *testing.T, input string, opts CLIOptions) string {
+	t.Helper()
+
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+
+	eng := New(program)
+	eng.SetCLIOptions(opts)
+	return buildTestCLIFromEngine(t, eng, program)
+}
+
+// buildTestCLIFromEngine generates and builds a CLI for program with a configured engine
+func buildTestCLIFromEngine(t *testing.T, eng *Engine, program *ast.Program) string {
+	t.Helper()
+
+	result, err := eng.GenerateCode(program)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	if err := eng.WriteModule(result, tmpDir, "testcli", ModuleOptions{}); err != nil {
+		t.Fatalf("WriteModule failed: %v", err)
+	}
+
+	binaryPath := filepath.Join(tmpDir, "testcli")
+	buildCmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	buildCmd.Dir = tmpDir
+	buildCmd.Env = ModuleOptions{}.Env()
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build CLI binary: %v\nOutput: %s\nCode:\n%s", err, output, result.String())
+	}
+
+	return binaryPath
+}
+
+// TestGeneratedCliSuggestsUnknownCommands tests that typos suggest the closest commands
+func TestGeneratedCliSuggestsUnknownCommands(t *testing.T) {
+	binaryPath := buildTestCLI(t, `
+build: echo "Building..."
+test: echo "Testing..."
+db-migrate: echo "Migrating..."
+`)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		contains []string
+		absent   []string
+	}{
+		{
+			name:     "transposed letters",
+			args:     []string{"biuld"},
+			contains: []string{`unknown command "biuld"`, "Did you mean this?", "build"},
+		},
+		{
+			name:     "namespace segment",
+			args:     []string{"migrate"},
+			contains: []string{"Did you mean this?", "db-migrate"},
+		},
+		{
+			name:     "nothing close",
+			args:     []string{"xyzzy"},
+			contains: []string{`unknown command "xyzzy"`},
+			absent:   []string{"Did you mean this?"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			output, err := exec.CommandContext(ctx, binaryPath, tc.args...).CombinedOutput()
+			if err == nil {
+				t.Fatalf("expected non-zero exit for unknown command, output:\n%s", output)
+			}
+
+			for _, want := range tc.contains {
+				if !strings.Contains(string(output), want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+			for _, unwanted := range tc.absent {
+				if strings.Contains(string(output), unwanted) {
+					t.Errorf("output should not contain %q:\n%s", unwanted, output)
+				}
+			}
+		})
+	}
+}
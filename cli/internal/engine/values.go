@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// Sources of values only plans show
+const (
+	SourceOverride     = "--var"   // A variable set on the command line
+	SourceParam        = "--param" // A parameter set on the command line
+	SourceParamDefault = "default" // The default of a command parameter
+)
+
+// PlanValues returns the values the plan of a command interpolates and where each comes from,
+// for dry runs to show next to the plan: the devcmd variables it reads with the line giving
+// their value, the environment variables it reads with @env, its parameters, and what other
+// value decorators resolve to. Secrets are masked. overridden names the variables set with
+// --var, whose values the program already has.
+func (e *Engine) PlanValues(command *ast.CommandDecl, environ []string, profile EnvProfile, overridden []string) ([]plan.ValueInfo, error) {
+	env, err := e.ResolveEnvironment(command, environ, profile, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []plan.ValueInfo
+	for _, variable := range env.Variables {
+		values = append(values, plan.ValueInfo{
+			Name:   fmt.Sprintf("@var(%s)", variable.Name),
+			Value:  variable.Value,
+			Source: e.variableSource(variable.Name, profile.Name, overridden),
+		})
+	}
+
+	commands := e.commandClosure(command)
+	read := make(map[string]bool)
+	for _, ref := range envReferences(commands) {
+		read[ref.Key] = true
+	}
+	for _, variable := range env.Env {
+		if !read[variable.Name] {
+			continue
+		}
+		value, source := variable.Value, variable.Source
+		if variable.Source == SourceUnset {
+			value = "<unset>"
+			if variable.Required {
+				source += ", required"
+			}
+		}
+		values = append(values, plan.ValueInfo{Name: fmt.Sprintf("@env(%s)", variable.Name), Value: value, Source: source})
+	}
+
+	params := paramValues(command, e.params)
+	for _, param := range command.Params {
+		value, ok := params[param.Name]
+		if !ok {
+			continue
+		}
+		source := SourceParamDefault
+		if _, set := e.params[param.Name]; set {
+			source = SourceParam
+		}
+		values = append(values, plan.ValueInfo{Name: fmt.Sprintf("@param(%s)", param.Name), Value: value, Source: source})
+	}
+
+	decorated, err := e.decoratorValues(commands, params)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, decorated...)
+
+	for i := range values {
+		values[i].Value = logging.Mask(values[i].Value)
+	}
+	return values, nil
+}
+
+// variableSource returns where the value of a devcmd variable comes from: --var, the env
+// block of the selected profile, or its declaration, with the line giving the value
+func (e *Engine) variableSource(name, profile string, overridden []string) string {
+	for _, override := range overridden {
+		if override == name {
+			return SourceOverride
+		}
+	}
+	if envProfile := e.program.EnvProfile(profile); envProfile != nil {
+		if variable := envProfile.Variable(name); variable != nil {
+			return fmt.Sprintf("profile %s, line %d", profile, variable.Pos.Line)
+		}
+	}
+	declarations := e.program.Variables
+	for _, group := range e.program.VarGroups {
+		declarations = append(declarations[:len(declarations):len(declarations)], group.Variables...)
+	}
+	for _, variable := range declarations {
+		if variable.Name == name && variable.Pos.Line > 0 {
+			return fmt.Sprintf("%s, line %d", SourceVariable, variable.Pos.Line)
+		}
+	}
+	return SourceVariable
+}
+
+// planValuer is implemented by value decorators that describe the value they resolve to for
+// plans, such as @secret with its masked value and provider
+type planValuer interface {
+	PlanValue(ctx execution.PlanContext, params []ast.NamedParameter) (value, source string, err error)
+}
+
+// decoratorValues returns what the value decorators other than @var, @env and @param in the
+// bodies of the commands resolve to, in the order they are first used. Decorators without
+// PlanValue show the value their plan description gives, with the decorator as the source.
+func (e *Engine) decoratorValues(commands []*ast.CommandDecl, params map[string]string) ([]plan.ValueInfo, error) {
+	ctx := execution.NewPlanContext(context.Background(), e.program)
+	e.setupPlanDecoratorLookups(ctx)
+	if err := ctx.InitializeVariables(); err != nil {
+		return nil, fmt.Errorf("failed to initialize variables: %w", err)
+	}
+	planCtx := ctx.WithParams(params)
+
+	var values []plan.ValueInfo
+	seen := make(map[string]bool)
+	var walkErr error
+	for _, command := range commands {
+		ast.Walk(&command.Body, func(n ast.Node) bool {
+			node, ok := n.(*ast.ValueDecorator)
+			if !ok || walkErr != nil {
+				return walkErr == nil
+			}
+			switch node.Name {
+			case "var", "env", "param":
+				return true
+			}
+			decorator, err := decorators.GetValue(node.Name)
+			if err != nil {
+				return true
+			}
+			var value, source string
+			if valuer, ok := decorator.(planValuer); ok {
+				if value, source, err = valuer.PlanValue(planCtx, node.Args); err != nil {
+					walkErr = fmt.Errorf("@%s: %w", node.Name, err)
+					return false
+				}
+			} else {
+				result := decorator.ExpandPlan(planCtx, node.Args)
+				description, ok := result.Data.(string)
+				if result.Error != nil || !ok {
+					return true
+				}
+				_, value, _ = strings.Cut(description, "→ ")
+				source = "@" + node.Name
+			}
+			name := node.String()
+			if !seen[name] {
+				seen[name] = true
+				values = append(values, plan.ValueInfo{Name: name, Value: value, Source: source})
+			}
+			return true
+		})
+	}
+	return values, walkErr
+}
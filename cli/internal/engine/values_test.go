@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
+)
+
+func TestPlanValues(t *testing.T) {
+	program, err := parser.Parse(strings.NewReader(`var API_URL = "http://localhost:8080"
+var REPLICAS = 1
+env production {
+    API_URL = "https://api.example.com"
+}
+deploy(region: string = "eu"): ./deploy.sh @var(API_URL) x@var(REPLICAS) @param(region) @env(HOME) @env(DEPLOY_KEY, default = "none") @secret(TOKEN, source = "env")`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// As devcmd run applies a profile and --var before planning
+	program, _ = WithEnvProfile(program, "production")
+	program.Variables[1].Value.(*ast.NumberLiteral).Value = "3"
+	eng := New(program)
+	eng.SetParams(map[string]string{})
+
+	values, err := eng.PlanValues(&program.Commands[0], []string{"HOME=/home/alice", "TOKEN=s3cret"}, EnvProfile{Name: "production"}, []string{"REPLICAS"})
+	if err != nil {
+		t.Fatalf("PlanValues failed: %v", err)
+	}
+	want := []plan.ValueInfo{
+		{Name: "@var(API_URL)", Value: "https://api.example.com", Source: "profile production, line 4"},
+		{Name: "@var(REPLICAS)", Value: "3", Source: "--var"},
+		{Name: "@env(DEPLOY_KEY)", Value: "none", Source: "@env default"},
+		{Name: "@env(HOME)", Value: "/home/alice", Source: "environment"},
+		{Name: "@param(region)", Value: "eu", Source: "default"},
+		{Name: "@secret(TOKEN, source = env)", Value: "***", Source: "environment variable TOKEN"},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("expected %d values, got %+v", len(want), values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("value %d: expected %+v, got %+v", i, want[i], values[i])
+		}
+	}
+
+	executionPlan := plan.NewExecutionPlan()
+	executionPlan.Values = values
+	text := executionPlan.StringNoColor()
+	for _, line := range []string{
+		"values:\n",
+		"   @var(REPLICAS) = 3                       (--var)\n",
+		"   @secret(TOKEN, source = env) = ***       (environment variable TOKEN)\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("plan should contain %q, got:\n%s", line, text)
+		}
+	}
+	if strings.Contains(text, "s3cret") {
+		t.Errorf("plan should not show the secret:\n%s", text)
+	}
+}
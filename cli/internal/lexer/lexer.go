@@ -68,8 +68,13 @@ type Lexer struct {
 	mode LexerMode
 
 	// Minimal context tracking
-	braceLevel        int // Track brace nesting for mode transitions
-	patternBraceLevel int // Track the brace level where we entered pattern decorator
+	braceLevel         int   // Track brace nesting for mode transitions
+	paramParenLevel    int   // Track top-level (...) nesting, where ':' types a command parameter
+	patternBraceLevel  int   // Track the brace level where we entered pattern decorator
+	outerPatternLevels []int // Pattern brace levels of the pattern decorators enclosing the current one
+
+	// True inside a command's needs(...) list, after which shell content starts
+	inNeeds bool
 
 	// Function decorator state
 	inFunctionDecorator bool // True when we're inside a function decorator sequence
@@ -125,6 +130,29 @@ func New(reader io.Reader) *Lexer {
 	return l
 }
 
+// isAfterEnvProfile checks if the { at bracePos opens an env profile, the line before it
+// being just "env" and the profile's name
+func (l *Lexer) isAfterEnvProfile(bracePos int) bool {
+	lineStart := strings.LastIndexByte(l.input[:bracePos], '\n') + 1
+	words := strings.Fields(l.input[lineStart:bracePos])
+	if len(words) != 2 || words[0] != "env" {
+		return false
+	}
+	for i, ch := range words[1] {
+		if ch >= 128 || !isIdentPart[ch] || (i == 0 && !isIdentStart[ch]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAfterConfig checks if the { at bracePos opens the config block, the line before it being
+// just "config"
+func (l *Lexer) isAfterConfig(bracePos int) bool {
+	lineStart := strings.LastIndexByte(l.input[:bracePos], '\n') + 1
+	return strings.TrimSpace(l.input[lineStart:bracePos]) == "config"
+}
+
 // isAfterPatternDecorator checks if we just parsed a pattern decorator by looking back
 func (l *Lexer) isAfterPatternDecorator() bool {
 	// Look back through recent input to find any pattern decorator using the registry
@@ -315,6 +343,15 @@ func (l *Lexer) lexLanguageMode() types.Token {
 
 	case ':':
 		l.readChar()
+		// A colon in a command's parameter list, e.g. deploy(env: string):, types the parameter
+		if l.paramParenLevel > 0 {
+			return l.createToken(types.COLON, ":", start, startLine, startColumn)
+		}
+		// A command's needs(...) list is language, and its shell content starts after it
+		if l.braceLevel == 0 && l.startsNeeds() {
+			l.inNeeds = true
+			return l.createToken(types.COLON, ":", start, startLine, startColumn)
+		}
 		// Transition to ShellMode after colon (ShellMode can handle both simple and complex shell content)
 		l.mode = ShellMode
 		return l.createToken(types.COLON, ":", start, startLine, startColumn)
@@ -329,10 +366,24 @@ func (l *Lexer) lexLanguageMode() types.Token {
 
 	case '(':
 		l.readChar()
+		if l.braceLevel == 0 && !l.inFunctionDecorator {
+			l.paramParenLevel++
+		}
 		return l.createToken(types.LPAREN, "(", start, startLine, startColumn)
 
 	case ')':
 		l.readChar()
+		if l.paramParenLevel > 0 && l.braceLevel == 0 && !l.inFunctionDecorator {
+			l.paramParenLevel--
+			if l.inNeeds && l.paramParenLevel == 0 {
+				// The command body follows the needs list, as it follows a colon
+				l.inNeeds = false
+				token := l.createToken(types.RPAREN, ")", start, startLine, startColumn)
+				l.skipWhitespace()
+				l.mode = ShellMode
+				return token
+			}
+		}
 		// Check if we're ending a function decorator sequence
 		if l.inFunctionDecorator {
 			l.inFunctionDecorator = false
@@ -359,9 +410,15 @@ func (l *Lexer) lexLanguageMode() types.Token {
 	case '{':
 		l.readChar()
 		l.braceLevel++
-		// Simple rule: { after pattern decorator → PatternMode, otherwise → CommandMode
-		if l.isAfterPatternDecorator() {
+		// Simple rule: { after pattern decorator → PatternMode, after an env profile's name or
+		// config → LanguageMode for its settings, otherwise → CommandMode
+		if l.braceLevel == 1 && (l.isAfterEnvProfile(start) || l.isAfterConfig(start)) {
+			l.mode = LanguageMode
+		} else if l.isAfterPatternDecorator() {
 			l.mode = PatternMode
+			if l.patternBraceLevel > 0 {
+				l.outerPatternLevels = append(l.outerPatternLevels, l.patternBraceLevel)
+			}
 			l.patternBraceLevel = l.braceLevel // Remember where we entered pattern mode
 		} else {
 			l.mode = CommandMode
@@ -375,6 +432,7 @@ func (l *Lexer) lexLanguageMode() types.Token {
 		if l.braceLevel <= 0 {
 			l.mode = LanguageMode
 			l.patternBraceLevel = 0 // Clear pattern context
+			l.outerPatternLevels = nil
 		}
 		// Otherwise stay in current mode - parent context will handle mode transitions
 		return l.createToken(types.RBRACE, "}", start, startLine, startColumn)
@@ -432,6 +490,21 @@ func (l *Lexer) lexLanguageMode() types.Token {
 	}
 }
 
+// startsNeeds reports whether the input at the current position starts a needs(...) list
+// naming at least one command, e.g. "needs(build, test)", rather than shell text
+func (l *Lexer) startsNeeds() bool {
+	rest := strings.TrimLeft(l.input[l.position:], " \t")
+	if !strings.HasPrefix(rest, "needs") {
+		return false
+	}
+	rest = strings.TrimLeft(rest[len("needs"):], " \t")
+	if !strings.HasPrefix(rest, "(") {
+		return false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t")
+	return rest != "" && rest[0] < 128 && isIdentStart[rest[0]]
+}
+
 // lexCommandMode handles shell content parsing inside command bodies
 // Recognizes: Shell text, Line continuations, Decorators, Block boundaries
 func (l *Lexer) lexCommandMode() types.Token {
@@ -466,6 +539,7 @@ func (l *Lexer) lexCommandMode() types.Token {
 		if l.braceLevel <= 0 {
 			l.mode = LanguageMode
 			l.patternBraceLevel = 0 // Clear pattern context
+			l.outerPatternLevels = nil
 		} else if l.isInPatternContext() && l.braceLevel == l.patternBraceLevel {
 			// Only return to PatternMode if we're back to the exact pattern brace level
 			// (exiting a pattern branch block, not a nested block within the pattern)
@@ -603,12 +677,22 @@ func (l *Lexer) lexPatternMode() types.Token {
 		// Closing brace - exit pattern mode
 		l.readChar()
 		l.braceLevel--
+		if l.braceLevel < l.patternBraceLevel {
+			// The pattern decorator's own block closed: what follows belongs to the enclosing
+			// pattern decorator, if any
+			l.patternBraceLevel = 0
+			if n := len(l.outerPatternLevels); n > 0 {
+				l.patternBraceLevel = l.outerPatternLevels[n-1]
+				l.outerPatternLevels = l.outerPatternLevels[:n-1]
+			}
+		}
 		// Simple rule: completely exited → LanguageMode, otherwise determine by context
 		if l.braceLevel <= 0 {
 			l.mode = LanguageMode
 			l.patternBraceLevel = 0 // Clear pattern context
-		} else if l.isInPatternContext() {
-			// Still inside a pattern decorator, return to PatternMode for more pattern branches
+			l.outerPatternLevels = nil
+		} else if l.isInPatternContext() && l.braceLevel == l.patternBraceLevel {
+			// Back at a pattern decorator's level, return to PatternMode for more pattern branches
 			l.mode = PatternMode
 		} else {
 			// Regular block context, return to CommandMode
@@ -629,6 +713,22 @@ func (l *Lexer) lexPatternMode() types.Token {
 		l.mode = CommandMode
 		return l.createToken(types.LBRACE, "{", start, startLine, startColumn)
 
+	case '#':
+		// Comments between pattern branches, as at the top level
+		return l.lexComment(start, startLine, startColumn)
+
+	case '"', '\'':
+		// Quoted glob or regex patterns ("release/*", "/^v[0-9]+$/")
+		return l.lexString(l.ch, start, startLine, startColumn)
+
+	case '/':
+		if l.peekChar() == '*' {
+			return l.lexMultilineComment(start, startLine, startColumn)
+		}
+		char := string(l.ch)
+		l.readChar()
+		return l.createToken(types.ILLEGAL, char, start, startLine, startColumn)
+
 	default:
 		// Pattern identifiers (prod, dev, main, error, finally, default)
 		if (l.ch < 128 && isIdentStart[l.ch]) || (l.ch >= 128 && (unicode.IsLetter(l.ch) || l.ch == '_')) {
@@ -721,6 +821,7 @@ func (l *Lexer) lexShellMode() types.Token {
 				if l.braceLevel <= 0 {
 					l.mode = LanguageMode
 					l.patternBraceLevel = 0 // Clear pattern context
+					l.outerPatternLevels = nil
 				} else if l.isInPatternContext() && l.braceLevel == l.patternBraceLevel {
 					l.mode = PatternMode
 				} else {
@@ -741,6 +842,7 @@ func (l *Lexer) lexShellMode() types.Token {
 				if l.braceLevel <= 0 {
 					l.mode = LanguageMode
 					l.patternBraceLevel = 0 // Clear pattern context
+					l.outerPatternLevels = nil
 				} else if l.isInPatternContext() && l.braceLevel == l.patternBraceLevel {
 					l.mode = PatternMode
 				} else {
@@ -894,6 +996,16 @@ func (l *Lexer) lexShellTextWithContext(start, startLine, startColumn int) types
 			break
 		}
 
+		// A backslash outside single quotes escapes the next character, so an escaped quote or
+		// brace is text (echo it\'s); before a newline it continues the line, handled above
+		if l.ch == '\\' && !inSingleQuote && l.peekChar() != '\n' && l.peekChar() != 0 {
+			result.WriteRune(l.ch)
+			l.readChar()
+			result.WriteRune(l.ch)
+			l.readChar()
+			continue
+		}
+
 		// Stop at closing brace (block boundary) - unless inside quotes or shell constructs
 		if l.ch == '}' && !inSingleQuote && !inDoubleQuote && !inBacktick {
 			if l.shellBraceLevel > 0 {
@@ -918,6 +1030,8 @@ func (l *Lexer) lexShellTextWithContext(start, startLine, startColumn int) types
 					savedPos := l.position
 					savedReadPos := l.readPos
 					savedCh := l.ch
+					savedLine := l.line
+					savedColumn := l.column
 
 					// Skip @ and read identifier
 					l.readChar()
@@ -940,6 +1054,8 @@ func (l *Lexer) lexShellTextWithContext(start, startLine, startColumn int) types
 					l.position = savedPos
 					l.readPos = savedReadPos
 					l.ch = savedCh
+					l.line = savedLine
+					l.column = savedColumn
 
 					// Break for block/pattern decorators (they switch to LanguageMode)
 					if decorators.IsBlockDecorator(identifier) || decorators.IsPatternDecorator(identifier) {
@@ -1119,6 +1235,16 @@ func (l *Lexer) lexShellText(start, startLine, startColumn int) types.Token {
 			break
 		}
 
+		// A backslash outside single quotes escapes the next character, so an escaped quote or
+		// brace is text (echo it\'s); before a newline it continues the line, handled above
+		if l.ch == '\\' && !inSingleQuote && l.peekChar() != '\n' && l.peekChar() != 0 {
+			result.WriteRune(l.ch)
+			l.readChar()
+			result.WriteRune(l.ch)
+			l.readChar()
+			continue
+		}
+
 		// Stop at closing brace (block boundary) - unless inside quotes or shell constructs
 		if l.ch == '}' && !inSingleQuote && !inDoubleQuote && !inBacktick {
 			if shellBraceLevel > 0 {
@@ -1143,6 +1269,8 @@ func (l *Lexer) lexShellText(start, startLine, startColumn int) types.Token {
 					savedPos := l.position
 					savedReadPos := l.readPos
 					savedCh := l.ch
+					savedLine := l.line
+					savedColumn := l.column
 
 					// Skip @ and read identifier
 					l.readChar()
@@ -1165,6 +1293,8 @@ func (l *Lexer) lexShellText(start, startLine, startColumn int) types.Token {
 					l.position = savedPos
 					l.readPos = savedReadPos
 					l.ch = savedCh
+					l.line = savedLine
+					l.column = savedColumn
 
 					// Break for block/pattern decorators (they switch to LanguageMode)
 					if decorators.IsBlockDecorator(identifier) || decorators.IsPatternDecorator(identifier) {
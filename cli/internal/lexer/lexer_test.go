@@ -822,6 +822,89 @@ func TestPatternDecorators(t *testing.T) {
 				{types.EOF, ""},
 			},
 		},
+		{
+			name: "block decorator after pattern block",
+			input: `deploy: {
+  @when(ENV) {
+    prod: echo prod
+  }
+  @timeout(1s) {
+    kubectl apply
+  }
+}`,
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "deploy"},
+				{types.COLON, ":"},
+				{types.LBRACE, "{"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "when"},
+				{types.LPAREN, "("},
+				{types.IDENTIFIER, "ENV"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.IDENTIFIER, "prod"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo prod"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "timeout"},
+				{types.LPAREN, "("},
+				{types.DURATION, "1s"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.SHELL_TEXT, "kubectl apply"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.RBRACE, "}"},
+				{types.EOF, ""},
+			},
+		},
+		{
+			name: "pattern block nested in pattern branch",
+			input: `deploy: @when(ENV) {
+  prod: {
+    @when(REGION) {
+      eu: echo eu
+    }
+    echo after
+  }
+  dev: echo dev
+}`,
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "deploy"},
+				{types.COLON, ":"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "when"},
+				{types.LPAREN, "("},
+				{types.IDENTIFIER, "ENV"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.IDENTIFIER, "prod"},
+				{types.COLON, ":"},
+				{types.LBRACE, "{"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "when"},
+				{types.LPAREN, "("},
+				{types.IDENTIFIER, "REGION"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.IDENTIFIER, "eu"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo eu"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.SHELL_TEXT, "echo after"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.IDENTIFIER, "dev"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo dev"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.EOF, ""},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -982,6 +1065,41 @@ func TestModeTransitions(t *testing.T) {
 				{types.EOF, ""},                  // LanguageMode
 			},
 		},
+		{
+			name:  "env profile stays in language mode",
+			input: "env production {\n  PORT = 443\n}\nenv: echo hi",
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "env"},        // LanguageMode
+				{types.IDENTIFIER, "production"}, // LanguageMode
+				{types.LBRACE, "{"},              // Stays in LanguageMode
+				{types.IDENTIFIER, "PORT"},       // LanguageMode
+				{types.EQUALS, "="},              // LanguageMode
+				{types.NUMBER, "443"},            // LanguageMode
+				{types.RBRACE, "}"},              // LanguageMode
+				{types.IDENTIFIER, "env"},        // A command named env
+				{types.COLON, ":"},               // LanguageMode → CommandMode
+				{types.SHELL_TEXT, "echo hi"},    // CommandMode
+				{types.SHELL_END, ""},            // End of shell command
+				{types.EOF, ""},                  // LanguageMode
+			},
+		},
+		{
+			name:  "config block stays in language mode",
+			input: "config {\n  defaultTimeout = 10m\n}\nconfig: echo hi",
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "config"},         // LanguageMode
+				{types.LBRACE, "{"},                  // Stays in LanguageMode
+				{types.IDENTIFIER, "defaultTimeout"}, // LanguageMode
+				{types.EQUALS, "="},                  // LanguageMode
+				{types.DURATION, "10m"},              // LanguageMode
+				{types.RBRACE, "}"},                  // LanguageMode
+				{types.IDENTIFIER, "config"},         // A command named config
+				{types.COLON, ":"},                   // LanguageMode → CommandMode
+				{types.SHELL_TEXT, "echo hi"},        // CommandMode
+				{types.SHELL_END, ""},                // End of shell command
+				{types.EOF, ""},                      // LanguageMode
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1074,6 +1192,32 @@ func TestComments(t *testing.T) {
 				{types.EOF, ""},
 			},
 		},
+		{
+			name:  "comments between pattern branches",
+			input: "deploy: @when(ENV) {\n  # Production\n  prod: echo p\n  /* Anything else */\n  default: echo d\n}",
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "deploy"},
+				{types.COLON, ":"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "when"},
+				{types.LPAREN, "("},
+				{types.IDENTIFIER, "ENV"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.COMMENT, "# Production"},
+				{types.IDENTIFIER, "prod"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo p"},
+				{types.SHELL_END, ""},
+				{types.MULTILINE_COMMENT, "/* Anything else */"},
+				{types.IDENTIFIER, "default"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo d"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.EOF, ""},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1806,6 +1950,39 @@ func TestSpecialCharacters(t *testing.T) {
 				{types.EOF, ""},
 			},
 		},
+		{
+			name:  "escaped quotes outside quotes",
+			input: "escaped: echo it\\'s \\\" done\nnext: echo 'it'\\''s' \"say \\\"hi\\\"\"",
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "escaped"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, `echo it\'s \" done`},
+				{types.SHELL_END, ""},
+				{types.IDENTIFIER, "next"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, `echo 'it'\''s' "say \"hi\""`},
+				{types.SHELL_END, ""},
+				{types.EOF, ""},
+			},
+		},
+		{
+			name:  "escaped brace in block",
+			input: `braces: @timeout(5s) { echo \} }`,
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "braces"},
+				{types.COLON, ":"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "timeout"},
+				{types.LPAREN, "("},
+				{types.DURATION, "5s"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.SHELL_TEXT, `echo \}`},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.EOF, ""},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,6 +81,39 @@ func TestPatternDecoratorEdgeCases(t *testing.T) {
 			},
 			failing: false,
 		},
+		{
+			name: "WORKING: quoted glob and regex patterns",
+			input: `deploy: @when(BRANCH) {
+  "release/*": echo release
+  '/^v[0-9]+$/': echo version
+  default: echo other
+}`,
+			expected: []tokenExpectation{
+				{types.IDENTIFIER, "deploy"},
+				{types.COLON, ":"},
+				{types.AT, "@"},
+				{types.IDENTIFIER, "when"},
+				{types.LPAREN, "("},
+				{types.IDENTIFIER, "BRANCH"},
+				{types.RPAREN, ")"},
+				{types.LBRACE, "{"},
+				{types.STRING, "release/*"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo release"},
+				{types.SHELL_END, ""},
+				{types.STRING, "/^v[0-9]+$/"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo version"},
+				{types.SHELL_END, ""},
+				{types.IDENTIFIER, "default"},
+				{types.COLON, ":"},
+				{types.SHELL_TEXT, "echo other"},
+				{types.SHELL_END, ""},
+				{types.RBRACE, "}"},
+				{types.EOF, ""},
+			},
+			failing: false,
+		},
 		{
 			name: "TEST CASE: simple shell after pattern - isolated",
 			input: `test: {
@@ -0,0 +1,182 @@
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// String returns the name of the mode, as devcmd lex --debug shows it
+func (m LexerMode) String() string {
+	switch m {
+	case LanguageMode:
+		return "language"
+	case CommandMode:
+		return "command"
+	case ShellMode:
+		return "shell"
+	case PatternMode:
+		return "pattern"
+	default:
+		return "mode(" + strconv.Itoa(int(m)) + ")"
+	}
+}
+
+// MarshalText encodes the mode by name
+func (m LexerMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// State is a snapshot of the lexer's mode and the nesting and quoting it tracks, which decide
+// where shell text ends and how braces are read
+type State struct {
+	Mode                LexerMode `json:"mode"`
+	BraceLevel          int       `json:"brace_level"`
+	ParamParenLevel     int       `json:"param_paren_level"`
+	PatternBraceLevel   int       `json:"pattern_brace_level"`
+	InNeeds             bool      `json:"in_needs"`
+	InFunctionDecorator bool      `json:"in_function_decorator"`
+	ShellBraceLevel     int       `json:"shell_brace_level"`     // ${...}
+	ShellParenLevel     int       `json:"shell_paren_level"`     // $(...)
+	ShellAnyBraceLevel  int       `json:"shell_any_brace_level"` // {...} in shell text
+	ShellInSingleQuote  bool      `json:"shell_in_single_quote"`
+	ShellInDoubleQuote  bool      `json:"shell_in_double_quote"`
+	ShellInBacktick     bool      `json:"shell_in_backtick"`
+	NeedsShellEnd       bool      `json:"needs_shell_end"`
+}
+
+// State returns the lexer's current state
+func (l *Lexer) State() State {
+	return State{
+		Mode:                l.mode,
+		BraceLevel:          l.braceLevel,
+		ParamParenLevel:     l.paramParenLevel,
+		PatternBraceLevel:   l.patternBraceLevel,
+		InNeeds:             l.inNeeds,
+		InFunctionDecorator: l.inFunctionDecorator,
+		ShellBraceLevel:     l.shellBraceLevel,
+		ShellParenLevel:     l.shellParenLevel,
+		ShellAnyBraceLevel:  l.shellAnyBraceLevel,
+		ShellInSingleQuote:  l.shellInSingleQuote,
+		ShellInDoubleQuote:  l.shellInDoubleQuote,
+		ShellInBacktick:     l.shellInBacktick,
+		NeedsShellEnd:       l.needsShellEnd,
+	}
+}
+
+// Changes describes the fields that differ from before to s, as "name old→new", in field order
+func (s State) Changes(before State) []string {
+	var changes []string
+	add := func(name string, old, new interface{}) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s %v→%v", name, old, new))
+		}
+	}
+	add("mode", before.Mode, s.Mode)
+	add("braces", before.BraceLevel, s.BraceLevel)
+	add("param-parens", before.ParamParenLevel, s.ParamParenLevel)
+	add("pattern-braces", before.PatternBraceLevel, s.PatternBraceLevel)
+	add("needs", before.InNeeds, s.InNeeds)
+	add("function-decorator", before.InFunctionDecorator, s.InFunctionDecorator)
+	add("shell-${", before.ShellBraceLevel, s.ShellBraceLevel)
+	add("shell-$(", before.ShellParenLevel, s.ShellParenLevel)
+	add("shell-braces", before.ShellAnyBraceLevel, s.ShellAnyBraceLevel)
+	add("single-quote", before.ShellInSingleQuote, s.ShellInSingleQuote)
+	add("double-quote", before.ShellInDoubleQuote, s.ShellInDoubleQuote)
+	add("backtick", before.ShellInBacktick, s.ShellInBacktick)
+	add("needs-shell-end", before.NeedsShellEnd, s.NeedsShellEnd)
+	return changes
+}
+
+// TraceStep is a token with the lexer's state before and after reading it
+type TraceStep struct {
+	Token  types.Token
+	Before State
+	After  State
+}
+
+// Trace is the token stream of an input with the state transitions of the lexer reading it,
+// for diagnosing how an input is tokenized
+type Trace []TraceStep
+
+// Trace tokenizes the rest of the input, recording the state around each token
+func (l *Lexer) Trace() Trace {
+	var trace Trace
+	for {
+		before := l.State()
+		token := l.NextToken()
+		trace = append(trace, TraceStep{Token: token, Before: before, After: l.State()})
+		if token.Type == types.EOF {
+			return trace
+		}
+	}
+}
+
+// WriteText writes one line per token: its position, type and quoted value and, with debug,
+// the state changes reading it caused
+func (t Trace) WriteText(w io.Writer, debug bool) error {
+	if debug && len(t) > 0 {
+		if _, err := fmt.Fprintf(w, "start: mode %s\n", t[0].Before.Mode); err != nil {
+			return err
+		}
+	}
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, step := range t {
+		token := step.Token
+		fmt.Fprintf(tw, "%d:%d-%d:%d\t%s\t%s", token.Span.Start.Line, token.Span.Start.Column,
+			token.Span.End.Line, token.Span.End.Column, token.Type, strconv.Quote(token.Value))
+		if debug {
+			fmt.Fprintf(tw, "\t%s", strings.Join(step.After.Changes(step.Before), ", "))
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Tokens that changed nothing leave their changes column blank
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traceToken is a token as WriteJSON writes it
+type traceToken struct {
+	Type   string               `json:"type"`
+	Value  string               `json:"value"`
+	Start  types.SourcePosition `json:"start"`
+	End    types.SourcePosition `json:"end"`
+	Before *State               `json:"before,omitempty"`
+	After  *State               `json:"after,omitempty"`
+}
+
+// WriteJSON writes the tokens as a JSON array, each with its span and, with debug, the full
+// state before and after it
+func (t Trace) WriteJSON(w io.Writer, debug bool) error {
+	tokens := make([]traceToken, len(t))
+	for i, step := range t {
+		tokens[i] = traceToken{
+			Type:  step.Token.Type.String(),
+			Value: step.Token.Value,
+			Start: step.Token.Span.Start,
+			End:   step.Token.Span.End,
+		}
+		if debug {
+			before, after := step.Before, step.After
+			tokens[i].Before, tokens[i].After = &before, &after
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tokens)
+}
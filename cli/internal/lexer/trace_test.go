@@ -0,0 +1,82 @@
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+func TestTrace_MatchesTokens(t *testing.T) {
+	input := "build: go build\ndeploy: @timeout(30s) {\n  echo \"${HOME}\"\n}\n"
+	tokens := New(strings.NewReader(input)).TokenizeToSlice()
+	trace := New(strings.NewReader(input)).Trace()
+
+	if len(trace) != len(tokens) {
+		t.Fatalf("trace has %d tokens, want %d", len(trace), len(tokens))
+	}
+	for i, step := range trace {
+		if step.Token != tokens[i] {
+			t.Errorf("token %d = %v, want %v", i, step.Token, tokens[i])
+		}
+		if i > 0 && step.Before != trace[i-1].After {
+			t.Errorf("token %d starts in %+v, but the previous one ended in %+v", i, step.Before, trace[i-1].After)
+		}
+	}
+}
+
+func TestTrace_WriteText(t *testing.T) {
+	trace := New(strings.NewReader("build: {\n  go build\n}")).Trace()
+
+	var plain, debug bytes.Buffer
+	if err := trace.WriteText(&plain, false); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if err := trace.WriteText(&debug, true); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(plain.String()), "\n")
+	if len(lines) != len(trace) || !strings.HasPrefix(lines[0], "1:1-1:6") || !strings.Contains(lines[0], `IDENTIFIER  "build"`) {
+		t.Errorf("text output:\n%s", plain.String())
+	}
+	if strings.Contains(plain.String(), "→") {
+		t.Errorf("text output without debug shows state changes:\n%s", plain.String())
+	}
+	for _, want := range []string{"start: mode language", "mode language→shell", "braces 0→1", "mode shell→command", "braces 1→0"} {
+		if !strings.Contains(debug.String(), want) {
+			t.Errorf("debug output does not show %q:\n%s", want, debug.String())
+		}
+	}
+	for _, line := range strings.Split(debug.String(), "\n") {
+		if strings.HasSuffix(line, " ") {
+			t.Errorf("debug line %q has trailing spaces", line)
+		}
+	}
+}
+
+func TestTrace_WriteJSON(t *testing.T) {
+	trace := New(strings.NewReader("build: go build")).Trace()
+
+	var out bytes.Buffer
+	if err := trace.WriteJSON(&out, true); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var tokens []struct {
+		Type  string               `json:"type"`
+		Value string               `json:"value"`
+		Start types.SourcePosition `json:"start"`
+		After map[string]any       `json:"after"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &tokens); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(tokens) != len(trace) || tokens[1].Type != "COLON" || tokens[1].Start.Column != 6 {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+	if mode := tokens[1].After["mode"]; mode != "shell" {
+		t.Errorf("mode after the colon = %v, want shell", mode)
+	}
+}
@@ -0,0 +1,436 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+)
+
+// Severity indicates how serious a diagnostic is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// Diagnostic is a single problem found in a command file.
+// Line and Column are 1-based and zero when the location is unknown.
+type Diagnostic struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+// String formats the diagnostic as "line:col: severity: message [rule]"
+func (d Diagnostic) String() string {
+	location := ""
+	if d.Line > 0 {
+		location = fmt.Sprintf("%d:%d: ", d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s%s: %s [%s]", location, d.Severity, d.Message, d.Rule)
+}
+
+// Rule is a single lint check over a parsed program
+type Rule struct {
+	ID          string
+	Description string
+	Check       func(program *ast.Program) []Diagnostic
+}
+
+// Rules lists all built-in lint rules
+var Rules = []Rule{
+	{
+		ID:          "undefined-variable",
+		Description: "@var references a variable that is not declared",
+		Check:       checkUndefinedVariables,
+	},
+	{
+		ID:          "unused-variable",
+		Description: "A declared variable is never referenced",
+		Check:       checkUnusedVariables,
+	},
+	{
+		ID:          "unknown-command-reference",
+		Description: "@cmd or a trigger references a command that is not defined",
+		Check:       checkCommandReferences,
+	},
+	{
+		ID:          "empty-command",
+		Description: "A command has no content to execute",
+		Check:       checkEmptyCommands,
+	},
+	{
+		ID:          "unsupported-decorator",
+		Description: "A decorator can't run in some execution modes or on some platforms",
+		Check:       checkDecoratorSupport,
+	},
+	{
+		ID:          "shell-syntax",
+		Description: "Shell text has a syntax error, such as an unterminated quote",
+		Check:       shellRule("shell-syntax"),
+	},
+	{
+		ID:          "pipeline-exit-status",
+		Description: "A pipeline ignores the failures of all but its last command",
+		Check:       shellRule("pipeline-exit-status"),
+	},
+}
+
+// Lint runs all rules against a program and returns diagnostics sorted by location
+func Lint(program *ast.Program) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range Rules {
+		diagnostics = append(diagnostics, rule.Check(program)...)
+	}
+	Sort(diagnostics)
+	return diagnostics
+}
+
+// Sort orders diagnostics by line and column, keeping unlocated diagnostics first
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredVariables returns all variable declarations, individual and grouped
+func declaredVariables(program *ast.Program) []ast.VariableDecl {
+	vars := append([]ast.VariableDecl{}, program.Variables...)
+	for _, group := range program.VarGroups {
+		vars = append(vars, group.Variables...)
+	}
+	return vars
+}
+
+// variableReferencePattern matches @var(NAME) references inside string parameters, as in @set
+var variableReferencePattern = regexp.MustCompile(`@var\(([A-Za-z_][A-Za-z0-9_]*)\)`)
+
+// expandingDecorators are the decorators that expand @var references inside their string
+// parameters, such as @set(TAG = "v@var(VERSION)") or @upper("@var(BRANCH)")
+var expandingDecorators = map[string]bool{
+	"set":      true,
+	"upper":    true,
+	"lower":    true,
+	"trim":     true,
+	"replace":  true,
+	"basename": true,
+	"abspath":  true,
+	"join":     true,
+	"relpath":  true,
+	"glob":     true,
+}
+
+// setDecorators returns the @set decorators in the program, which assign the variables
+// named by their parameters
+func setDecorators(program *ast.Program) []*ast.ActionDecorator {
+	var sets []*ast.ActionDecorator
+	ast.Walk(program, func(n ast.Node) bool {
+		if action, ok := n.(*ast.ActionDecorator); ok && action.Name == "set" {
+			sets = append(sets, action)
+		}
+		return true
+	})
+	return sets
+}
+
+// stringReference is an @var reference inside a decorator's string parameter
+type stringReference struct {
+	Name string
+	Pos  ast.Position
+}
+
+// stringVariableReferences returns the variables referenced with @var inside the string
+// parameters of expanding decorators, positioned at the decorator
+func stringVariableReferences(program *ast.Program) []stringReference {
+	var refs []stringReference
+	collect := func(name string, args []ast.NamedParameter, pos ast.Position) {
+		if !expandingDecorators[name] {
+			return
+		}
+		for _, arg := range args {
+			if str, ok := arg.Value.(*ast.StringLiteral); ok {
+				for _, match := range variableReferencePattern.FindAllStringSubmatch(str.Value, -1) {
+					refs = append(refs, stringReference{Name: match[1], Pos: pos})
+				}
+			}
+		}
+	}
+	ast.Walk(program, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ActionDecorator:
+			collect(node.Name, node.Args, node.Pos)
+		case *ast.ValueDecorator:
+			collect(node.Name, node.Args, node.Pos)
+		}
+		return true
+	})
+	return refs
+}
+
+// variableReferenceName returns the variable name referenced by an @var decorator
+func variableReferenceName(ref *ast.ValueDecorator) string {
+	if len(ref.Args) == 0 {
+		return ""
+	}
+	if identifier, ok := ref.Args[0].Value.(*ast.Identifier); ok {
+		return identifier.Name
+	}
+	return ""
+}
+
+// checkUndefinedVariables reports @var references to undeclared variables
+func checkUndefinedVariables(program *ast.Program) []Diagnostic {
+	defined := make(map[string]bool)
+	for _, v := range declaredVariables(program) {
+		defined[v.Name] = true
+	}
+	for _, set := range setDecorators(program) {
+		for _, arg := range set.Args {
+			defined[arg.Name] = true
+		}
+	}
+
+	var diagnostics []Diagnostic
+	undefined := func(name string, pos ast.Position) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Rule:     "undefined-variable",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("undefined variable '%s'", name),
+			Line:     pos.Line,
+			Column:   pos.Column,
+		})
+	}
+	for _, ref := range ast.FindVariableReferences(program) {
+		if name := variableReferenceName(ref); name != "" && !defined[name] {
+			undefined(name, ref.Pos)
+		}
+	}
+	for _, ref := range stringVariableReferences(program) {
+		if !defined[ref.Name] {
+			undefined(ref.Name, ref.Pos)
+		}
+	}
+	return diagnostics
+}
+
+// checkUnusedVariables reports declared variables that nothing references.
+// Identifiers passed to decorators (e.g. @when(ENV)) count as references, as do the
+// variables decorators read through string parameters (e.g. @when("ENV")).
+func checkUnusedVariables(program *ast.Program) []Diagnostic {
+	used := make(map[string]bool)
+	referenced := func(name string, args []ast.NamedParameter) {
+		decorator, _, err := decorators.GetAny(name)
+		if err != nil {
+			return
+		}
+		if referencer, ok := decorator.(decorators.VariableReferencer); ok {
+			for _, name := range referencer.ReferencedVariables(args) {
+				used[name] = true
+			}
+		}
+	}
+	ast.Walk(program, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueDecorator:
+			if node.Name == "var" {
+				used[variableReferenceName(node)] = true
+			}
+			referenced(node.Name, node.Args)
+		case *ast.ActionDecorator:
+			referenced(node.Name, node.Args)
+		case *ast.BlockDecorator:
+			referenced(node.Name, node.Args)
+		case *ast.PatternDecorator:
+			referenced(node.Name, node.Args)
+		case *ast.Identifier:
+			used[node.Name] = true
+		}
+		return true
+	})
+	for _, ref := range stringVariableReferences(program) {
+		used[ref.Name] = true
+	}
+
+	var diagnostics []Diagnostic
+	for _, v := range declaredVariables(program) {
+		if !used[v.Name] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     "unused-variable",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("variable '%s' is declared but never used", v.Name),
+				Line:     v.Pos.Line,
+				Column:   v.Pos.Column,
+			})
+		}
+	}
+	return diagnostics
+}
+
+// checkCommandReferences reports @cmd references and triggers of undefined commands
+func checkCommandReferences(program *ast.Program) []Diagnostic {
+	defined := make(map[string]bool)
+	for _, cmd := range program.Commands {
+		defined[cmd.Name] = true
+	}
+
+	var diagnostics []Diagnostic
+	for _, trigger := range program.Triggers {
+		if trigger.Event != ast.TriggerOnChange && !defined[trigger.Command] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     "unknown-command-reference",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("trigger references undefined command '%s'", trigger.Command),
+				Line:     trigger.CommandToken.Line,
+				Column:   trigger.CommandToken.Column,
+			})
+		}
+	}
+	ast.Walk(program, func(n ast.Node) bool {
+		action, ok := n.(*ast.ActionDecorator)
+		if !ok || action.Name != "cmd" || len(action.Args) == 0 {
+			return true
+		}
+		if identifier, ok := action.Args[0].Value.(*ast.Identifier); ok && !defined[identifier.Name] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     "unknown-command-reference",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("@cmd references undefined command '%s'", identifier.Name),
+				Line:     action.Pos.Line,
+				Column:   action.Pos.Column,
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// checkDecoratorSupport warns about each use of a decorator that doesn't run in every mode or
+// on every platform, which commands fail with there before any step runs
+func checkDecoratorSupport(program *ast.Program) []Diagnostic {
+	var diagnostics []Diagnostic
+	ast.Walk(program, func(n ast.Node) bool {
+		var name string
+		var pos ast.Position
+		switch d := n.(type) {
+		case *ast.ValueDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.ActionDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.BlockDecorator:
+			name, pos = d.Name, d.Pos
+		case *ast.PatternDecorator:
+			name, pos = d.Name, d.Pos
+		default:
+			return true
+		}
+		info, ok := decorators.Describe(name)
+		if !ok {
+			return true
+		}
+		if restrictions := supportRestrictions(info); len(restrictions) > 0 {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     "unsupported-decorator",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("@%s %s", name, strings.Join(restrictions, "; ")),
+				Line:     pos.Line,
+				Column:   pos.Column,
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// supportRestrictions describes the modes a decorator doesn't run in and the platforms it is
+// limited to, grouping modes limited to the same platforms
+func supportRestrictions(info decorators.DecoratorInfo) []string {
+	var restrictions, unsupported, platformLists []string
+	modesByPlatforms := make(map[string][]string)
+	for _, mode := range decorators.Modes {
+		if info.Supports(mode, "") == nil {
+			continue
+		}
+		platforms, restricted := info.Platforms[mode]
+		if !restricted || !containsMode(info.Modes, mode) {
+			unsupported = append(unsupported, string(mode))
+			continue
+		}
+		list := strings.Join(platforms, ", ")
+		if _, seen := modesByPlatforms[list]; !seen {
+			platformLists = append(platformLists, list)
+		}
+		modesByPlatforms[list] = append(modesByPlatforms[list], string(mode))
+	}
+	if len(unsupported) > 0 {
+		restrictions = append(restrictions, fmt.Sprintf("can't run in %s mode", strings.Join(unsupported, " or ")))
+	}
+	for _, list := range platformLists {
+		restrictions = append(restrictions, fmt.Sprintf("only runs on %s in %s mode", list, strings.Join(modesByPlatforms[list], " and ")))
+	}
+	return restrictions
+}
+
+func containsMode(modes []decorators.Mode, mode decorators.Mode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEmptyCommands reports commands whose body has nothing to execute
+func checkEmptyCommands(program *ast.Program) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, cmd := range program.Commands {
+		if !isEmptyBody(cmd.Body.Content) {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Rule:     "empty-command",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("command '%s' has no content", cmd.Name),
+			Line:     cmd.Pos.Line,
+			Column:   cmd.Pos.Column,
+		})
+	}
+	return diagnostics
+}
+
+// isEmptyBody reports whether command content contains only blank shell text
+func isEmptyBody(content []ast.CommandContent) bool {
+	for _, c := range content {
+		shell, ok := c.(*ast.ShellContent)
+		if !ok {
+			return false
+		}
+		for _, part := range shell.Parts {
+			text, ok := part.(*ast.TextPart)
+			if !ok || strings.TrimSpace(text.Text) != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+
+	// Import builtins to register decorators
+	_ "github.com/aledsdavies/devcmd/cli/internal/builtins"
+)
+
+func lintSource(t *testing.T, input string) []Diagnostic {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return Lint(program)
+}
+
+func rulesOf(diagnostics []Diagnostic) []string {
+	rules := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		rules[i] = d.Rule
+	}
+	return rules
+}
+
+func TestLint_Rules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "clean program",
+			input:    "var PORT = 8080\nserve: go run . --port @var(PORT)",
+			expected: []string{},
+		},
+		{
+			name:     "undefined variable",
+			input:    "serve: go run . --port @var(PORT)",
+			expected: []string{"undefined-variable"},
+		},
+		{
+			name:     "unused variable",
+			input:    "var PORT = 8080\nserve: go run .",
+			expected: []string{"unused-variable"},
+		},
+		{
+			name:     "variable used as decorator identifier",
+			input:    "var ENV = \"dev\"\ndeploy: @when(ENV) { dev: echo dev\n default: echo other }",
+			expected: []string{},
+		},
+		{
+			name:     "variable matched by @when",
+			input:    "var ENV = \"dev\"\ndeploy: @when(\"ENV\") { dev: echo dev\n default: echo other }",
+			expected: []string{},
+		},
+		{
+			name:     "variable defined by @set",
+			input:    "var VERSION = \"1.0\"\nrelease: {\n @set(TAG = \"v@var(VERSION)\")\n git tag @var(TAG)\n}",
+			expected: []string{},
+		},
+		{
+			name:     "undefined variable in @set",
+			input:    "release: {\n @set(TAG = \"v@var(VERSION)\")\n git tag @var(TAG)\n}",
+			expected: []string{"undefined-variable"},
+		},
+		{
+			name:     "variable used in string transform",
+			input:    "var BRANCH = \"main\"\ntag: docker build -t app:@lower(\"@var(BRANCH)\") .",
+			expected: []string{},
+		},
+		{
+			name:     "undefined variable in string transform",
+			input:    "tag: docker build -t app:@lower(\"@var(BRANCH)\") .",
+			expected: []string{"undefined-variable"},
+		},
+		{
+			name:     "unknown command reference",
+			input:    "all: @cmd(missing)",
+			expected: []string{"unknown-command-reference"},
+		},
+		{
+			name:     "known command reference",
+			input:    "build: go build\nall: @cmd(build)",
+			expected: []string{},
+		},
+		{
+			name:     "trigger of unknown command",
+			input:    "rollback: echo rollback\non failure of deploy: @cmd(rollback)",
+			expected: []string{"unknown-command-reference"},
+		},
+		{
+			name:     "change trigger",
+			input:    "codegen: buf generate\non change \"proto/**/*.proto\": @cmd(codegen)",
+			expected: []string{},
+		},
+		{
+			name:     "decorator limited to some platforms",
+			input:    "check: @sandbox { go test ./... }",
+			expected: []string{"unsupported-decorator"},
+		},
+		{
+			name:     "trigger running unknown command",
+			input:    "deploy: echo deploy\non failure of deploy: @cmd(rollback)",
+			expected: []string{"unknown-command-reference"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rulesOf(lintSource(t, tc.input))
+			if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("rules = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLint_UnsupportedDecoratorMessage(t *testing.T) {
+	diagnostics := lintSource(t, "term: @pty { top }\ncheck: @sandbox { go test ./... }")
+	want := []string{
+		"1:7: warning: @pty only runs on darwin, linux in generator mode [unsupported-decorator]",
+		"2:8: warning: @sandbox only runs on darwin, linux in interpreter and generator mode [unsupported-decorator]",
+	}
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLint_SortedAndLocated(t *testing.T) {
+	diagnostics := lintSource(t, "var A = 1\nvar B = 2\nall: @cmd(missing)")
+
+	if len(diagnostics) != 3 {
+		t.Fatalf("expected 3 diagnostics, got %v", diagnostics)
+	}
+	for i := 1; i < len(diagnostics); i++ {
+		if diagnostics[i].Line < diagnostics[i-1].Line {
+			t.Errorf("diagnostics not sorted by line: %v", diagnostics)
+		}
+	}
+	if diagnostics[0].Line != 1 || diagnostics[0].Severity != SeverityWarning {
+		t.Errorf("unexpected first diagnostic: %+v", diagnostics[0])
+	}
+	if !HasErrors(diagnostics) {
+		t.Error("expected HasErrors to report the @cmd error")
+	}
+}
+
+func TestDiagnostic_String(t *testing.T) {
+	d := Diagnostic{Rule: "unused-variable", Severity: SeverityWarning, Message: "unused", Line: 3, Column: 1}
+	if got := d.String(); got != "3:1: warning: unused [unused-variable]" {
+		t.Errorf("String() = %q", got)
+	}
+
+	d.Line, d.Column = 0, 0
+	if got := d.String(); got != "warning: unused [unused-variable]" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,373 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// shellPlaceholder stands in for the decorators interpolated into shell text: a plain word
+// that is valid wherever a decorator's value can appear
+const shellPlaceholder = "x"
+
+// shellSource is the text of a shell command, with its decorators stubbed, and the
+// command file position of each byte of it
+type shellSource struct {
+	text      string
+	positions []ast.Position
+}
+
+// newShellSource stubs the decorators in content and maps the result back to positions in
+// the command file. Line continuations are joined by the lexer, so positions after one
+// point past the end of the first line.
+func newShellSource(content *ast.ShellContent) shellSource {
+	var text strings.Builder
+	var positions []ast.Position
+	for _, part := range content.Parts {
+		pos := part.Position()
+		if pos.Line == 0 {
+			pos = content.Pos
+		}
+		textPart, ok := part.(*ast.TextPart)
+		if !ok {
+			text.WriteString(shellPlaceholder)
+			for range shellPlaceholder {
+				positions = append(positions, pos)
+			}
+			continue
+		}
+		for i := 0; i < len(textPart.Text); i++ {
+			text.WriteByte(textPart.Text[i])
+			positions = append(positions, pos)
+			if textPart.Text[i] == '\n' {
+				pos = ast.Position{Line: pos.Line + 1, Column: 1}
+			} else {
+				pos.Column++
+			}
+		}
+	}
+	return shellSource{text: text.String(), positions: positions}
+}
+
+// position returns the command file position of the byte at offset
+func (s shellSource) position(offset int) ast.Position {
+	if offset < len(s.positions) {
+		return s.positions[offset]
+	}
+	if len(s.positions) == 0 {
+		return ast.Position{}
+	}
+	last := s.positions[len(s.positions)-1]
+	return ast.Position{Line: last.Line, Column: last.Column + 1}
+}
+
+// shellFinding is a problem found in shell text, at a byte offset into it
+type shellFinding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Offset   int
+}
+
+// shellFrame is a construct the scanner is inside of: a quote, a substitution, or a
+// subshell. Frames that hold commands track the operators between them.
+type shellFrame struct {
+	kind   string // "", "'", "\"", "`", "(", "$(", "${" or "$(("
+	offset int
+
+	commandStarted bool   // A word has been seen since the last operator
+	operator       string // The last operator, when it still needs a command after it
+	operatorOffset int
+	caseDepth      int  // Open case statements, whose patterns end with ')'
+	casePattern    bool // In a case pattern, where '|' separates alternatives
+	pipeOffset     int  // The first '|' of the current pipeline, or -1
+	parens         int  // Open parentheses inside $((
+}
+
+// checkShellText scans the POSIX shell text run by sh -c for syntax errors and for
+// pipelines whose failures go unnoticed. It is a scanner, not a full parser: it follows
+// quotes, substitutions, subshells and command separators, which is enough to catch the
+// mistakes that otherwise only show up when the command runs.
+func checkShellText(text string) []shellFinding {
+	var findings []shellFinding
+	report := func(rule string, severity Severity, offset int, format string, args ...interface{}) {
+		findings = append(findings, shellFinding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...), Offset: offset})
+	}
+	syntaxError := func(offset int, format string, args ...interface{}) {
+		report("shell-syntax", SeverityError, offset, format, args...)
+	}
+
+	stack := []*shellFrame{{pipeOffset: -1}}
+	push := func(kind string, offset int) {
+		stack = append(stack, &shellFrame{kind: kind, offset: offset, pipeOffset: -1})
+	}
+
+	// endPipeline reports a pipeline that ended at a separator, when its exit status
+	// hides the failures of all but its last command
+	endPipeline := func(f *shellFrame, end int) {
+		if f.kind == "" && f.pipeOffset >= 0 && !strings.Contains(text[:end], "pipefail") {
+			report("pipeline-exit-status", SeverityWarning, f.pipeOffset,
+				"only the exit status of the last command in the pipeline is checked, so failures of the commands before '|' are ignored")
+		}
+		f.pipeOffset = -1
+	}
+	// missingCommand reports an operator left without a command after it
+	missingCommand := func(f *shellFrame) {
+		if f.operator != "" {
+			syntaxError(f.operatorOffset, "missing command after '%s'", f.operator)
+			f.operator = ""
+		}
+	}
+	// separate handles an operator between commands
+	separate := func(f *shellFrame, operator string, offset int) {
+		switch operator {
+		case "|", "&&", "||":
+			if operator == "|" && f.casePattern {
+				return
+			}
+			if !f.commandStarted {
+				syntaxError(offset, "missing command before '%s'", operator)
+			} else if operator == "|" {
+				if f.pipeOffset < 0 {
+					f.pipeOffset = offset
+				}
+			} else {
+				endPipeline(f, offset)
+			}
+			f.operator, f.operatorOffset = operator, offset
+		case "\n":
+			// A newline after a binary operator continues the command
+			if f.operator == "" {
+				endPipeline(f, offset)
+			}
+		case ";;":
+			if f.caseDepth == 0 {
+				syntaxError(offset, "unexpected ';;' outside a case statement")
+			}
+			missingCommand(f)
+			endPipeline(f, offset)
+			f.casePattern = f.caseDepth > 0
+		default: // ";" and "&"
+			if !f.commandStarted && f.operator == "" {
+				syntaxError(offset, "missing command before '%s'", operator)
+			}
+			missingCommand(f)
+			endPipeline(f, offset)
+		}
+		f.commandStarted = false
+	}
+	// word handles a word starting at offset in a command frame
+	word := func(f *shellFrame, offset int) {
+		if !f.commandStarted {
+			end := offset
+			for end < len(text) && strings.IndexByte(" \t\n;&|()<>", text[end]) < 0 {
+				end++
+			}
+			switch text[offset:end] {
+			case "case":
+				f.caseDepth++
+				f.casePattern = true
+			case "esac":
+				if f.caseDepth > 0 {
+					f.caseDepth--
+				}
+				f.casePattern = false
+			}
+		}
+		f.commandStarted = true
+		f.operator = ""
+	}
+
+	for i := 0; i < len(text); i++ {
+		f := stack[len(stack)-1]
+		c := text[i]
+		next := byte(0)
+		if i+1 < len(text) {
+			next = text[i+1]
+		}
+
+		switch f.kind {
+		case "'":
+			if c == '\'' {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		case "\"", "${":
+			switch {
+			case c == '\\':
+				i++
+			case c == '"' && f.kind == "\"":
+				stack = stack[:len(stack)-1]
+			case c == '}' && f.kind == "${":
+				stack = stack[:len(stack)-1]
+			case c == '"':
+				push("\"", i)
+			case c == '`':
+				push("`", i)
+			case c == '$' && next == '{':
+				push("${", i)
+				i++
+			case c == '$' && next == '(':
+				if i+2 < len(text) && text[i+2] == '(' {
+					push("$((", i)
+					i += 2
+				} else {
+					push("$(", i)
+					i++
+				}
+			}
+			continue
+		case "$((":
+			switch {
+			case c == '(':
+				f.parens++
+			case c == ')' && f.parens > 0:
+				f.parens--
+			case c == ')' && next == ')':
+				stack = stack[:len(stack)-1]
+				i++
+			case c == ')':
+				syntaxError(i, "'$((' must be closed with '))'")
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		// The frame holds commands
+		startsWord := i == 0 || strings.IndexByte(" \t\n;&|()", text[i-1]) >= 0
+		switch {
+		case c == ' ' || c == '\t':
+		case c == '\n':
+			separate(f, "\n", i)
+		case c == '#' && startsWord:
+			for i+1 < len(text) && text[i+1] != '\n' {
+				i++
+			}
+		case c == '\\':
+			word(f, i)
+			i++
+		case c == '\'' || c == '"':
+			word(f, i)
+			push(string(c), i)
+		case c == '`':
+			if f.kind == "`" {
+				missingCommand(f)
+				stack = stack[:len(stack)-1]
+				break
+			}
+			word(f, i)
+			push("`", i)
+		case c == '$' && next == '{':
+			word(f, i)
+			push("${", i)
+			i++
+		case c == '$' && next == '(':
+			word(f, i)
+			if i+2 < len(text) && text[i+2] == '(' {
+				push("$((", i)
+				i += 2
+			} else {
+				push("$(", i)
+				i++
+			}
+		case c == '(':
+			switch {
+			case next == ')' && f.commandStarted:
+				// A function definition, as in f() { ...; }
+				i++
+			case f.casePattern:
+				// The optional start of a case pattern
+			case f.commandStarted:
+				// Scan on as a subshell, so its ')' isn't reported too
+				syntaxError(i, "unexpected '(': quote it to pass it to the command")
+				push("(", i)
+			default:
+				word(f, i)
+				push("(", i)
+			}
+		case c == ')':
+			switch {
+			case f.kind == "(" || f.kind == "$(":
+				if !f.commandStarted && f.operator == "" && f.kind == "(" {
+					syntaxError(f.offset, "empty subshell")
+				}
+				missingCommand(f)
+				stack = stack[:len(stack)-1]
+			case f.caseDepth > 0:
+				// The end of a case pattern
+				f.commandStarted = false
+				f.casePattern = false
+			default:
+				syntaxError(i, "unmatched ')'")
+			}
+		case c == '|' && next == '|', c == '&' && next == '&', c == ';' && next == ';':
+			separate(f, text[i:i+2], i)
+			i++
+		case c == '|' || c == ';' || c == '&':
+			separate(f, string(c), i)
+		case (c == '>' || c == '<') && (next == '&' || next == '|'):
+			// Redirections such as 2>&1 and >|
+			word(f, i)
+			i++
+		default:
+			word(f, i)
+		}
+	}
+
+	// Anything still open when the text ends is unterminated; the innermost construct is
+	// the one the shell reports
+	f := stack[len(stack)-1]
+	switch f.kind {
+	case "":
+		missingCommand(f)
+		endPipeline(f, len(text))
+	case "'":
+		syntaxError(f.offset, "unterminated single quote")
+	case "\"":
+		syntaxError(f.offset, "unterminated double quote")
+	case "`":
+		syntaxError(f.offset, "unterminated backquote")
+	default:
+		syntaxError(f.offset, "'%s' is never closed", f.kind)
+	}
+	return findings
+}
+
+// checkShellCommands reports syntax errors and unchecked pipeline failures in the shell
+// text of each command, with interpolated decorators stubbed
+func checkShellCommands(program *ast.Program) []Diagnostic {
+	var diagnostics []Diagnostic
+	ast.Walk(program, func(n ast.Node) bool {
+		content, ok := n.(*ast.ShellContent)
+		if !ok {
+			return true
+		}
+		source := newShellSource(content)
+		for _, finding := range checkShellText(source.text) {
+			pos := source.position(finding.Offset)
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     finding.Rule,
+				Severity: finding.Severity,
+				Message:  finding.Message,
+				Line:     pos.Line,
+				Column:   pos.Column,
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// shellRule returns the check for one of the rules reported by checkShellCommands
+func shellRule(id string) func(program *ast.Program) []Diagnostic {
+	return func(program *ast.Program) []Diagnostic {
+		var diagnostics []Diagnostic
+		for _, d := range checkShellCommands(program) {
+			if d.Rule == id {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+		return diagnostics
+	}
+}
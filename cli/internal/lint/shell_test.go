@@ -0,0 +1,84 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestCheckShellText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string // "rule@offset: message" for each finding
+	}{
+		{"simple command", "go build -o bin/app ./cmd/app", nil},
+		{"quotes", `echo "it's $HOME" 'say "hi"' \"`, nil},
+		{"substitutions", `echo "$(git describe --tags) ${VERSION:-dev} $((1 + (2 * 3)))" ` + "`date`", nil},
+		{"redirections", "go test ./... 2>&1 >|out.log", nil},
+		{"subshell", "(cd web && npm ci) && echo done", nil},
+		{"case", "case x in (a) echo a;; b|c) echo b;; esac", nil},
+		{"function", "f() { echo hi; }; f", nil},
+		{"comment", "echo hi # it's fine", nil},
+		{"background", "sleep 1 & wait", nil},
+		{"operator before newline", "make &&\nmake install", nil},
+		{"unterminated single quote", "echo 'it", []string{"shell-syntax@5: unterminated single quote"}},
+		{"unterminated double quote", `echo "$(date)`, []string{"shell-syntax@5: unterminated double quote"}},
+		{"unterminated substitution", "echo $(date", []string{"shell-syntax@5: '$(' is never closed"}},
+		{"quote in unterminated substitution", `echo "$(date"`, []string{"shell-syntax@12: unterminated double quote"}},
+		{"unterminated backquote", "echo `date", []string{"shell-syntax@5: unterminated backquote"}},
+		{"unmatched paren", "echo hi)", []string{"shell-syntax@7: unmatched ')'"}},
+		{"unquoted paren", "echo (hi)", []string{"shell-syntax@5: unexpected '(': quote it to pass it to the command"}},
+		{"empty subshell", "()", []string{"shell-syntax@0: empty subshell"}},
+		{"missing command after", "go test &&", []string{"shell-syntax@8: missing command after '&&'"}},
+		{"missing command before", "| grep x", []string{"shell-syntax@0: missing command before '|'"}},
+		{"doubled separator", "echo a;; echo b", []string{"shell-syntax@6: unexpected ';;' outside a case statement"}},
+		{"missing command in substitution", "echo $(date |)", []string{"shell-syntax@12: missing command after '|'"}},
+		{"pipeline", "go test ./... | tee test.log", []string{"pipeline-exit-status@14: only the exit status of the last command in the pipeline is checked, so failures of the commands before '|' are ignored"}},
+		{"pipeline with fallback", "git status | head -5 || echo none", []string{"pipeline-exit-status@11: only the exit status of the last command in the pipeline is checked, so failures of the commands before '|' are ignored"}},
+		{"pipeline with pipefail", "set -o pipefail; go test ./... | tee test.log", nil},
+		{"pipeline in substitution", "echo $(find . -name '*.go' | wc -l)", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range checkShellText(tc.text) {
+				got = append(got, fmt.Sprintf("%s@%d: %s", f.Rule, f.Offset, f.Message))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("checkShellText(%q) =\n%s\nwant\n%s", tc.text, strings.Join(got, "\n"), strings.Join(tc.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckShellCommands_Positions(t *testing.T) {
+	input := "var MSG = \"hi\"\ntest: {\n  go vet ./...\n  go test ./... | tee @var(MSG).log\n}\nbuild: echo @var(MSG) 'done"
+
+	var got []string
+	for _, d := range lintSource(t, input) {
+		got = append(got, d.String())
+	}
+	expected := []string{
+		"4:17: warning: only the exit status of the last command in the pipeline is checked, so failures of the commands before '|' are ignored [pipeline-exit-status]",
+		"6:23: error: unterminated single quote [shell-syntax]",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// Decorators in the shell text are stubbed with a word
+	source := newShellSource(&ast.ShellContent{Parts: []ast.ShellPart{
+		&ast.TextPart{Text: "echo ", Pos: ast.Position{Line: 1, Column: 8}},
+		&ast.ValueDecorator{Name: "var", Pos: ast.Position{Line: 1, Column: 13}},
+	}})
+	if source.text != "echo "+shellPlaceholder {
+		t.Errorf("text = %q", source.text)
+	}
+	if pos := source.position(5); pos.Line != 1 || pos.Column != 13 {
+		t.Errorf("position(5) = %+v, want the decorator's position", pos)
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the duration histogram buckets in seconds, sized for
+// developer commands that range from sub-second checks to long builds
+var DefaultBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// ProcessHealthFunc reports whether each background process is currently running
+type ProcessHealthFunc func() map[string]bool
+
+// Registry collects command execution metrics and renders them in the
+// Prometheus text exposition format
+type Registry struct {
+	mu            sync.Mutex
+	buckets       []float64
+	commands      map[string]*commandStats
+	processHealth ProcessHealthFunc
+	startTime     time.Time
+}
+
+// commandStats holds the counters and duration histogram for one command
+type commandStats struct {
+	runs         map[string]uint64 // keyed by status
+	bucketCounts []uint64
+	durationSum  float64
+	durationN    uint64
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:   DefaultBuckets,
+		commands:  make(map[string]*commandStats),
+		startTime: time.Now(),
+	}
+}
+
+// SetProcessHealth sets the provider used to report background process health
+func (r *Registry) SetProcessHealth(fn ProcessHealthFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processHealth = fn
+}
+
+// ObserveCommand records a completed command run
+func (r *Registry) ObserveCommand(command, status string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.commands[command]
+	if !ok {
+		stats = &commandStats{
+			runs:         make(map[string]uint64),
+			bucketCounts: make([]uint64, len(r.buckets)),
+		}
+		r.commands[command] = stats
+	}
+
+	seconds := duration.Seconds()
+	stats.runs[status]++
+	stats.durationSum += seconds
+	stats.durationN++
+	for i, bound := range r.buckets {
+		if seconds <= bound {
+			stats.bucketCounts[i]++
+		}
+	}
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("# HELP devcmd_command_runs_total Total number of command runs by final status.\n")
+	b.WriteString("# TYPE devcmd_command_runs_total counter\n")
+	for _, name := range names {
+		stats := r.commands[name]
+		statuses := make([]string, 0, len(stats.runs))
+		for status := range stats.runs {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "devcmd_command_runs_total{command=%s,status=%s} %d\n",
+				quoteLabel(name), quoteLabel(status), stats.runs[status])
+		}
+	}
+
+	b.WriteString("# HELP devcmd_command_failure_ratio Fraction of command runs that failed.\n")
+	b.WriteString("# TYPE devcmd_command_failure_ratio gauge\n")
+	for _, name := range names {
+		stats := r.commands[name]
+		ratio := 0.0
+		if stats.durationN > 0 {
+			ratio = float64(stats.runs["failed"]) / float64(stats.durationN)
+		}
+		fmt.Fprintf(&b, "devcmd_command_failure_ratio{command=%s} %s\n", quoteLabel(name), formatFloat(ratio))
+	}
+
+	b.WriteString("# HELP devcmd_command_duration_seconds Command run duration in seconds.\n")
+	b.WriteString("# TYPE devcmd_command_duration_seconds histogram\n")
+	for _, name := range names {
+		stats := r.commands[name]
+		label := quoteLabel(name)
+		for i, bound := range r.buckets {
+			fmt.Fprintf(&b, "devcmd_command_duration_seconds_bucket{command=%s,le=\"%s\"} %d\n",
+				label, formatFloat(bound), stats.bucketCounts[i])
+		}
+		fmt.Fprintf(&b, "devcmd_command_duration_seconds_bucket{command=%s,le=\"+Inf\"} %d\n", label, stats.durationN)
+		fmt.Fprintf(&b, "devcmd_command_duration_seconds_sum{command=%s} %s\n", label, formatFloat(stats.durationSum))
+		fmt.Fprintf(&b, "devcmd_command_duration_seconds_count{command=%s} %d\n", label, stats.durationN)
+	}
+
+	if r.processHealth != nil {
+		health := r.processHealth()
+		processes := make([]string, 0, len(health))
+		for name := range health {
+			processes = append(processes, name)
+		}
+		sort.Strings(processes)
+
+		b.WriteString("# HELP devcmd_background_process_up Whether a background process is running (1) or not (0).\n")
+		b.WriteString("# TYPE devcmd_background_process_up gauge\n")
+		for _, name := range processes {
+			up := 0
+			if health[name] {
+				up = 1
+			}
+			fmt.Fprintf(&b, "devcmd_background_process_up{process=%s} %d\n", quoteLabel(name), up)
+		}
+	}
+
+	b.WriteString("# HELP devcmd_uptime_seconds Time since the devcmd server started.\n")
+	b.WriteString("# TYPE devcmd_uptime_seconds gauge\n")
+	fmt.Fprintf(&b, "devcmd_uptime_seconds %s\n", formatFloat(time.Since(r.startTime).Seconds()))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP exposes the registry as a Prometheus scrape endpoint
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+// quoteLabel quotes a label value using the exposition format escaping rules
+func quoteLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// formatFloat formats a sample value without exponent noise for common values
+func formatFloat(v float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%f", v), "0"), ".")
+}
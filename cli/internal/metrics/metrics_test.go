@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_CommandMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveCommand("build", "success", 2*time.Second)
+	r.ObserveCommand("build", "failed", 40*time.Second)
+	r.ObserveCommand("test", "success", 300*time.Millisecond)
+
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	text := out.String()
+
+	expected := []string{
+		"# TYPE devcmd_command_runs_total counter",
+		`devcmd_command_runs_total{command="build",status="failed"} 1`,
+		`devcmd_command_runs_total{command="build",status="success"} 1`,
+		`devcmd_command_runs_total{command="test",status="success"} 1`,
+		`devcmd_command_failure_ratio{command="build"} 0.5`,
+		`devcmd_command_failure_ratio{command="test"} 0`,
+		"# TYPE devcmd_command_duration_seconds histogram",
+		`devcmd_command_duration_seconds_bucket{command="build",le="2.5"} 1`,
+		`devcmd_command_duration_seconds_bucket{command="build",le="60"} 2`,
+		`devcmd_command_duration_seconds_bucket{command="build",le="+Inf"} 2`,
+		`devcmd_command_duration_seconds_sum{command="build"} 42`,
+		`devcmd_command_duration_seconds_count{command="build"} 2`,
+		`devcmd_command_duration_seconds_bucket{command="test",le="0.5"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing metric line %q in output:\n%s", line, text)
+		}
+	}
+}
+
+func TestRegistry_ProcessHealth(t *testing.T) {
+	r := NewRegistry()
+	r.SetProcessHealth(func() map[string]bool {
+		return map[string]bool{"api": true, "db": false}
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	text := rec.Body.String()
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	for _, line := range []string{
+		`devcmd_background_process_up{process="api"} 1`,
+		`devcmd_background_process_up{process="db"} 0`,
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("missing metric line %q in output:\n%s", line, text)
+		}
+	}
+}
+
+func TestQuoteLabel(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quoteLabel = %s", got)
+	}
+}
@@ -0,0 +1,169 @@
+package palette
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// ErrCancelled is returned when the user quits or input ends before anything is picked
+var ErrCancelled = errors.New("no command picked")
+
+// Item is a command offered by the palette
+type Item struct {
+	Name        string
+	Description string // First line is shown next to the name
+}
+
+// Pick lists the items and reads queries from in until the user picks one. A query narrows
+// the list to the items whose name contains its characters in order, or whose description
+// contains it; a number picks an item from the list shown, and an empty line picks the only
+// item left. "q" quits.
+func Pick(in *bufio.Reader, out io.Writer, items []Item) (Item, error) {
+	shown := items
+	for {
+		if len(shown) == 0 {
+			fmt.Fprintln(out, "No matching commands")
+			shown = items
+		}
+		if err := list(out, shown); err != nil {
+			return Item{}, err
+		}
+		if len(shown) == 1 {
+			fmt.Fprintf(out, "Press enter to run %s, or search again (q to quit): ", shown[0].Name)
+		} else {
+			fmt.Fprint(out, "Search, or pick a number (q to quit): ")
+		}
+
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(out)
+			if err == io.EOF {
+				return Item{}, ErrCancelled
+			}
+			return Item{}, err
+		}
+		query := strings.TrimSpace(line)
+
+		switch {
+		case query == "q":
+			return Item{}, ErrCancelled
+		case query == "":
+			if len(shown) == 1 {
+				return shown[0], nil
+			}
+			shown = items
+		default:
+			if n, err := strconv.Atoi(query); err == nil {
+				if n >= 1 && n <= len(shown) {
+					return shown[n-1], nil
+				}
+				fmt.Fprintf(out, "Pick a number from 1 to %d\n", len(shown))
+				continue
+			}
+			shown = Filter(query, items)
+		}
+	}
+}
+
+// Filter returns the items matching query, best match first: names starting with it, then
+// names containing it, then names containing its characters in order, then descriptions
+// containing it
+func Filter(query string, items []Item) []Item {
+	query = strings.ToLower(query)
+	type match struct {
+		item  Item
+		score int
+	}
+	var matches []match
+	for _, item := range items {
+		if score, ok := score(query, item); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+
+	result := make([]Item, len(matches))
+	for i, m := range matches {
+		result[i] = m.item
+	}
+	return result
+}
+
+// score ranks how well an item matches a lower-case query, lower being better
+func score(query string, item Item) (int, bool) {
+	name := strings.ToLower(item.Name)
+	switch {
+	case strings.HasPrefix(name, query):
+		return 0, true
+	case strings.Contains(name, query):
+		return 1, true
+	}
+	// Characters in order, closer together ranking higher: "dpl" matches "deploy"
+	start, next := -1, 0
+	for i := 0; i < len(name) && next < len(query); i++ {
+		if name[i] == query[next] {
+			if start < 0 {
+				start = i
+			}
+			next++
+			if next == len(query) {
+				return 2 + (i - start + 1 - len(query)), true
+			}
+		}
+	}
+	if strings.Contains(strings.ToLower(item.Description), query) {
+		return 1 << 20, true
+	}
+	return 0, false
+}
+
+// list writes the items numbered, with the first line of their descriptions, in columns or,
+// for accessible output, as "1. build: Build everything" lines a screen reader reads whole
+func list(out io.Writer, items []Item) error {
+	if logging.Accessible() {
+		for i, item := range items {
+			description, _, _ := strings.Cut(item.Description, "\n")
+			if description == "" {
+				fmt.Fprintf(out, "%d. %s\n", i+1, item.Name)
+			} else {
+				fmt.Fprintf(out, "%d. %s: %s\n", i+1, item.Name, description)
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, item := range items {
+		description, _, _ := strings.Cut(item.Description, "\n")
+		fmt.Fprintf(tw, "%3d  %s\t%s\n", i+1, item.Name, description)
+	}
+	return tw.Flush()
+}
+
+// Prompt asks for a value, returning defaultValue when the user enters nothing
+func Prompt(in *bufio.Reader, out io.Writer, label, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(out, "%s [%s]: ", label, defaultValue)
+	} else {
+		fmt.Fprintf(out, "%s: ", label)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(out)
+		if err == io.EOF {
+			return "", ErrCancelled
+		}
+		return "", err
+	}
+	if value := strings.TrimRight(line, "\r\n"); value != "" {
+		return value, nil
+	}
+	return defaultValue, nil
+}
@@ -0,0 +1,113 @@
+package palette
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+var items = []Item{
+	{Name: "build", Description: "Build the binaries"},
+	{Name: "deploy", Description: "Deploy to the cluster\nNeeds kubectl"},
+	{Name: "db-migrate", Description: "Run database migrations"},
+	{Name: "lint"},
+}
+
+func names(items []Item) string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.Name)
+	}
+	return strings.Join(result, " ")
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"d", "deploy db-migrate build"}, // Prefixes first
+		{"dep", "deploy"},
+		{"mig", "db-migrate"},
+		{"dpl", "deploy"},
+		{"DB", "db-migrate"},
+		{"cluster", "deploy"},
+		{"xyz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := names(Filter(tt.query, items)); got != tt.want {
+				t.Errorf("Filter(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		output string
+	}{
+		{"number", "2\n", "deploy", "  2  deploy      Deploy to the cluster\n"},
+		{"search then enter", "mig\n\n", "db-migrate", "Press enter to run db-migrate"},
+		{"number from the filtered list", "d\n2\n", "db-migrate", ""},
+		{"out of range", "9\n1\n", "build", "Pick a number from 1 to 4"},
+		{"no match", "xyz\n4\n", "lint", "No matching commands"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			picked, err := Pick(bufio.NewReader(strings.NewReader(tt.input)), &out, items)
+			if err != nil {
+				t.Fatalf("Pick failed: %v\n%s", err, out.String())
+			}
+			if picked.Name != tt.want {
+				t.Errorf("picked %q, want %q", picked.Name, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output should contain %q, got:\n%s", tt.output, out.String())
+			}
+		})
+	}
+
+	// Input that ends after a query has nothing left to pick with
+	for _, input := range []string{"q\n", "", "lint"} {
+		if _, err := Pick(bufio.NewReader(strings.NewReader(input)), &bytes.Buffer{}, items); err != ErrCancelled {
+			t.Errorf("Pick with input %q returned %v, want ErrCancelled", input, err)
+		}
+	}
+}
+
+func TestPick_Accessible(t *testing.T) {
+	defer logging.SetAccessible(logging.Accessible())
+	logging.SetAccessible(true)
+	var out bytes.Buffer
+	if _, err := Pick(bufio.NewReader(strings.NewReader("1\n")), &out, items); err != nil {
+		t.Fatalf("Pick failed: %v", err)
+	}
+	if want := "1. build: Build the binaries\n2. deploy: Deploy to the cluster\n3. db-migrate: Run database migrations\n4. lint\n"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("output =\n%s\nwant it to start with\n%s", out.String(), want)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("\nprod\n"))
+	var out bytes.Buffer
+	if value, err := Prompt(in, &out, "MANIFEST", "k8s/"); err != nil || value != "k8s/" {
+		t.Errorf("empty answer = %q, %v; want the default", value, err)
+	}
+	if value, err := Prompt(in, &out, "CLUSTER", ""); err != nil || value != "prod" {
+		t.Errorf("answer = %q, %v; want prod", value, err)
+	}
+	if out.String() != "MANIFEST [k8s/]: CLUSTER: " {
+		t.Errorf("prompts = %q", out.String())
+	}
+	if _, err := Prompt(in, &out, "TOKEN", ""); err != ErrCancelled {
+		t.Errorf("prompt at the end of input returned %v, want ErrCancelled", err)
+	}
+}
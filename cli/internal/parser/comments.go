@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// leadingComments takes the comments collected before the token that belong to it: the run of
+// comments that ends on the line above it, or on its own line, with no blank line between
+// them. The comments before a blank line belong to no declaration, and are kept in the
+// program.
+func (p *Parser) leadingComments(token types.Token) []ast.Comment {
+	start, line := len(p.comments), token.Line
+	for start > 0 {
+		if end := p.endLine(p.comments[start-1].Token); end != line && end != line-1 {
+			break
+		}
+		start--
+		line = p.comments[start].Pos.Line
+	}
+	var leading []ast.Comment
+	if start < len(p.comments) {
+		leading = append(leading, p.comments[start:]...)
+	}
+	p.program.Comments = append(p.program.Comments, p.comments[:start]...)
+	p.comments = nil
+	return leading
+}
+
+// trailingComment takes the comment after a declaration on the line it ends on, if any
+func (p *Parser) trailingComment() *ast.Comment {
+	if !p.match(types.COMMENT, types.MULTILINE_COMMENT) {
+		return nil
+	}
+	last := p.pos - 1
+	for last > 0 && p.tokens[last].Type == types.SHELL_END {
+		last--
+	}
+	if last < 0 || p.endLine(p.tokens[last]) != p.current().Line {
+		return nil
+	}
+	comment := newComment(p.advance())
+	return &comment
+}
+
+// flushComments keeps the comments collected since the last declaration in the program, as
+// those before the end of a block belong to no declaration
+func (p *Parser) flushComments() {
+	p.program.Comments = append(p.program.Comments, p.comments...)
+	p.comments = nil
+}
+
+// endLine returns the line a token ends on. The lexer's span ends where the next token starts,
+// which is on the next line for a token at the end of one.
+func (p *Parser) endLine(token types.Token) int {
+	start, end := token.Span.Start.Offset, token.Span.End.Offset
+	if start < 0 || end > len(p.input) || start > end {
+		return token.Line
+	}
+	return token.Line + strings.Count(strings.TrimRight(p.input[start:end], " \t\r\n"), "\n")
+}
+
+func newComment(token types.Token) ast.Comment {
+	return ast.Comment{
+		Text:  token.Value,
+		Pos:   ast.Position{Line: token.Line, Column: token.Column},
+		Token: token,
+	}
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+const commentedSource = `# Project commands
+
+# The port the server listens on
+var PORT = 8080 # default for local runs
+var (
+    # Where to deploy
+    ENV = "dev" # or prod
+)
+/* Builds everything,
+   including the generated code */
+build: {
+    go generate ./...
+    go build ./...
+} # slow
+deploy: {
+    @when(ENV) {
+        # Production needs approval
+        prod: {
+            ./approve.sh
+            ./deploy.sh
+        } # manual
+        default: ./deploy.sh
+    }
+}
+
+# Keep going
+`
+
+func TestComments(t *testing.T) {
+	program := mustParse(t, commentedSource)
+
+	assertComments(t, "PORT", program.Variables[0].Comments, []string{"# The port the server listens on"}, "# default for local runs")
+	assertComments(t, "var group", program.VarGroups[0].Comments, nil, "")
+	assertComments(t, "ENV", program.VarGroups[0].Variables[0].Comments, []string{"# Where to deploy"}, "# or prod")
+	assertComments(t, "build", program.Commands[0].Comments, []string{"/* Builds everything,\n   including the generated code */"}, "# slow")
+	assertComments(t, "deploy", program.Commands[1].Comments, nil, "")
+
+	patterns := program.Commands[1].Body.Content[0].(*ast.PatternDecorator).Patterns
+	assertComments(t, "prod", patterns[0].Comments, []string{"# Production needs approval"}, "# manual")
+	assertComments(t, "default", patterns[1].Comments, nil, "")
+
+	var floating []string
+	for _, comment := range program.Comments {
+		floating = append(floating, comment.Text)
+	}
+	if strings.Join(floating, "|") != "# Project commands|# Keep going" {
+		t.Errorf("program comments = %q, want the header and the last comment", floating)
+	}
+
+	if got := program.Commands[0].Comments.LeadingText(); strings.Join(got, "|") != "Builds everything,|including the generated code" {
+		t.Errorf("LeadingText() = %q", got)
+	}
+}
+
+func TestComments_Doc(t *testing.T) {
+	program := mustParse(t, "#\n# Run the tests\n#\n# Includes the integration tests.\n#\ntest: go test ./...\nlint: golangci-lint run")
+
+	if doc := program.Commands[0].Comments.Doc(); doc != "Run the tests\n\nIncludes the integration tests." {
+		t.Errorf("Doc() = %q", doc)
+	}
+	if summary := program.Commands[0].Comments.Summary(); summary != "Run the tests" {
+		t.Errorf("Summary() = %q", summary)
+	}
+	if doc := program.Commands[1].Comments.Doc(); doc != "" {
+		t.Errorf("Doc() of an undocumented command = %q", doc)
+	}
+}
+
+func TestComments_Format(t *testing.T) {
+	program := mustParse(t, commentedSource)
+
+	formatted := ast.Format(program)
+	if formatted != commentedSource {
+		t.Errorf("Format() =\n%s\nwant\n%s", formatted, commentedSource)
+	}
+	if err := RoundTrip(program); err != nil {
+		t.Errorf("RoundTrip: %v", err)
+	}
+}
+
+func assertComments(t *testing.T, name string, trivia ast.Trivia, leading []string, trailing string) {
+	t.Helper()
+	var got []string
+	for _, comment := range trivia.Leading {
+		got = append(got, comment.Text)
+	}
+	if strings.Join(got, "|") != strings.Join(leading, "|") {
+		t.Errorf("%s leading comments = %q, want %q", name, got, leading)
+	}
+	gotTrailing := ""
+	if trivia.Trailing != nil {
+		gotTrailing = trivia.Trailing.Text
+	}
+	if gotTrailing != trailing {
+		t.Errorf("%s trailing comment = %q, want %q", name, gotTrailing, trailing)
+	}
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	program := mustParse(t, `# Settings of every command
+config {
+    defaultTimeout = 10m # long enough for a release
+    shell = "bash"
+}
+
+build: go build ./...
+config: echo a command named config`)
+
+	if len(program.Commands) != 2 || program.Commands[1].Name != "config" {
+		t.Fatalf("commands = %v, want build and config", program.Commands)
+	}
+	config := program.Config
+	if config == nil || config.Pos.Line != 2 || len(config.Settings) != 2 {
+		t.Fatalf("config = %+v, want two settings at line 2", config)
+	}
+	if program.DefaultTimeout() != 10*time.Minute {
+		t.Errorf("DefaultTimeout() = %v, want 10m", program.DefaultTimeout())
+	}
+	if program.Shell() != "bash" {
+		t.Errorf("Shell() = %q, want bash", program.Shell())
+	}
+	if len(config.Comments.Leading) != 1 || config.Settings[0].Comments.Trailing == nil {
+		t.Errorf("comments = %+v, want the leading and trailing comments kept", config.Comments)
+	}
+	if got := config.String(); got != "config {\n  defaultTimeout = 10m\n  shell = bash\n}" {
+		t.Errorf("String() = %q", got)
+	}
+	if program := mustParse(t, "build: go build"); program.DefaultTimeout() != 0 {
+		t.Errorf("DefaultTimeout() without a config block = %v, want 0", program.DefaultTimeout())
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{"config {\n    retries = 3\n}", "unknown config setting 'retries'; the config block can set defaultTimeout and shell"},
+		{"config {\n    shell = \"fish\"\n}", `config setting 'shell' must be one of bash, sh, pwsh, cmd, got "fish"`},
+		{"config {\n    shell = 3\n}", `config setting 'shell' must be a string, e.g. "bash", got number`},
+		{"config {\n    defaultTimeout = \"10m\"\n}", "config setting 'defaultTimeout' must be a duration, e.g. 10m, got string"},
+		{"config {\n    defaultTimeout = 0s\n}", "config setting 'defaultTimeout' must be a positive duration, got 0s"},
+		{"config {\n    defaultTimeout = 1m\n    defaultTimeout = 2m\n}", "duplicate setting 'defaultTimeout'"},
+		{"config {\n    defaultTimeout = 1m\n}\nconfig {\n    defaultTimeout = 2m\n}", "duplicate config block: first defined at line 1, column 1"},
+		{"override config {\n    defaultTimeout = 1m\n}", "override only applies to variables and commands"},
+	} {
+		_, err := Parse(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/types"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+)
+
+// commandRef is a decorator, such as @cmd, or a need that runs another command
+type commandRef struct {
+	target string
+	via    string      // How it runs the command, such as "@cmd" or "needs"
+	token  types.Token // The "@" of the decorator or the name of the need
+}
+
+// commandRefs returns the commands each command of program runs through decorators such as
+// @cmd or needs first, leaving out commands the program doesn't define. Watch and stop
+// commands share a name.
+func commandRefs(program *ast.Program) map[string][]commandRef {
+	defined := make(map[string]bool, len(program.Commands))
+	for _, command := range program.Commands {
+		defined[command.Name] = true
+	}
+
+	refs := make(map[string][]commandRef)
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		for _, need := range command.Needs {
+			if defined[need.Name] {
+				refs[command.Name] = append(refs[command.Name], commandRef{target: need.Name, via: "needs", token: need.Token})
+			}
+		}
+		ast.Walk(command, func(node ast.Node) bool {
+			var name string
+			var args []ast.NamedParameter
+			var at types.Token
+			switch n := node.(type) {
+			case *ast.ActionDecorator:
+				name, args, at = n.Name, n.Args, n.AtToken
+			case *ast.BlockDecorator:
+				name, args, at = n.Name, n.Args, n.AtToken
+			case *ast.PatternDecorator:
+				name, args, at = n.Name, n.Args, n.AtToken
+			default:
+				return true
+			}
+			decorator, _, err := decorators.GetAny(name)
+			if err != nil {
+				return true
+			}
+			if provider, ok := decorator.(decorators.CommandDependencyProvider); ok {
+				for _, target := range provider.GetCommandDependencies(args) {
+					if defined[target] {
+						refs[command.Name] = append(refs[command.Name], commandRef{target: target, via: "@" + name, token: at})
+					}
+				}
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// findCommandCycle returns the first cycle of commands that run each other, in file order,
+// starting and ending with the same command, and the decorator that closes it
+func findCommandCycle(program *ast.Program) ([]string, *commandRef) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	refs := commandRefs(program)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(name string) ([]string, *commandRef)
+	visit = func(name string) ([]string, *commandRef) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for i, ref := range refs[name] {
+			switch state[ref.target] {
+			case unvisited:
+				if cycle, closing := visit(ref.target); cycle != nil {
+					return cycle, closing
+				}
+			case visiting:
+				start := len(stack) - 1
+				for stack[start] != ref.target {
+					start--
+				}
+				cycle := append(append([]string{}, stack[start:]...), ref.target)
+				return cycle, &refs[name][i]
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil, nil
+	}
+	for _, command := range program.Commands {
+		if state[command.Name] == unvisited {
+			if cycle, closing := visit(command.Name); cycle != nil {
+				return cycle, closing
+			}
+		}
+	}
+	return nil, nil
+}
+
+// cycleMessage describes a cycle of commands and the reference that closes it
+func cycleMessage(cycle []string, closing *commandRef) string {
+	return fmt.Sprintf("dependency cycle: %s; a command can't run itself through %s", strings.Join(cycle, " → "), closing.via)
+}
+
+// checkCommandCycles reports commands that run themselves through @cmd or needs, directly or
+// through other commands, which would never finish
+func (p *Parser) checkCommandCycles(program *ast.Program) error {
+	cycle, closing := findCommandCycle(program)
+	if cycle == nil {
+		return nil
+	}
+	return p.formatError(cycleMessage(cycle, closing), closing.token)
+}
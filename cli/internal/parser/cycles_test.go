@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandCycles(t *testing.T) {
+	for input, want := range map[string]string{
+		"build: @cmd(build)":               "dependency cycle: build → build",
+		"a: @cmd(b)\nb: echo b && @cmd(a)": "dependency cycle: a → b → a",
+		"ci: @cmd(test)\ntest: @parallel {\n  @cmd(lint)\n  echo unit\n}\nlint: @cmd(ci)":                                "dependency cycle: ci → test → lint → ci",
+		"var ENV = \"dev\"\ndeploy: @when(ENV) {\n  prod: @cmd(release)\n  default: echo skip\n}\nrelease: @cmd(deploy)": "dependency cycle: deploy → release → deploy",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want one containing %q", input, err, want)
+		}
+	}
+
+	// Commands may share dependencies, and a watch and stop pair is one command
+	for _, input := range []string{
+		"ci: {\n  @cmd(build)\n  @cmd(test)\n}\ntest: @cmd(build)\nbuild: go build",
+		"watch api: go run ./cmd/api\nstop api: pkill api\nrestart: {\n  @cmd(api)\n}",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err != nil {
+			t.Errorf("Parse(%q) failed: %v", input, err)
+		}
+	}
+}
+
+func TestCommandCycles_Diagnostic(t *testing.T) {
+	_, diagnostics, err := ParseDiagnostics(strings.NewReader("a: @cmd(b)\nb: echo b && @cmd(a)"))
+	if err != nil {
+		t.Fatalf("ParseDiagnostics failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || diagnostics[0].Column != 14 {
+		t.Errorf("diagnostics = %+v, want one at the @cmd closing the cycle, 2:14", diagnostics)
+	}
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
 )
 
 func TestVarDecorators(t *testing.T) {
@@ -393,6 +396,43 @@ func TestPatternDecorators(t *testing.T) {
 	}
 }
 
+func TestPatternDecorators_StringPatterns(t *testing.T) {
+	program, err := Parse(strings.NewReader(`deploy: @when("BRANCH") {
+  main: echo main
+  "release/*": echo release
+  '/^v[0-9]+$/': echo version
+  default: echo other
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	when := program.Commands[0].Body.Content[0].(*ast.PatternDecorator)
+	var got []string
+	for _, branch := range when.Patterns {
+		pattern := branch.Pattern.GetPatternType().String() + " " + branch.Pattern.String()
+		if stringPattern, ok := branch.Pattern.(*ast.StringPattern); ok && stringPattern.IsRegex() {
+			pattern += " regex " + stringPattern.Expression()
+		}
+		got = append(got, pattern)
+	}
+	want := []string{`identifier main`, `string "release/*"`, `string "/^v[0-9]+$/" regex ^v[0-9]+$`, `wildcard *`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("patterns = %q, want %q", got, want)
+	}
+}
+
+func TestPatternDecorators_StringPatternErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"deploy: @when(\"BRANCH\") {\n  \"release/[\": echo release\n}": "invalid glob pattern \"release/[\"",
+		"deploy: @when(\"BRANCH\") {\n  \"/v(/\": echo version\n}":      "invalid regex pattern \"/v(/\"",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", input, err, want)
+		}
+	}
+}
+
 func TestNamedParameterSupport(t *testing.T) {
 	testCases := []TestCase{
 		{
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics_ValidInput(t *testing.T) {
+	program, diagnostics, err := ParseDiagnostics(strings.NewReader("build: go build ./..."))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+	if program == nil || len(program.Commands) != 1 {
+		t.Fatalf("expected program with one command, got %+v", program)
+	}
+}
+
+func TestParseDiagnostics_ReportsLocations(t *testing.T) {
+	input := "build: echo ok\nbad: @timeout("
+	program, diagnostics, err := ParseDiagnostics(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if program != nil {
+		t.Error("expected nil program when diagnostics are reported")
+	}
+	if len(diagnostics) == 0 {
+		t.Fatal("expected at least one diagnostic")
+	}
+
+	d := diagnostics[0]
+	if d.Line != 2 || d.Column == 0 {
+		t.Errorf("expected diagnostic on line 2 with a column, got %d:%d", d.Line, d.Column)
+	}
+	if strings.Contains(d.Message, "\n") {
+		t.Errorf("diagnostic message should be a single line, got %q", d.Message)
+	}
+}
+
+func TestParseDiagnostics_MatchesParseErrors(t *testing.T) {
+	input := "build echo missing colon"
+	_, parseErr := Parse(strings.NewReader(input))
+	_, diagnostics, _ := ParseDiagnostics(strings.NewReader(input))
+
+	if parseErr == nil || len(diagnostics) == 0 {
+		t.Fatalf("expected both Parse and ParseDiagnostics to fail, got %v and %v", parseErr, diagnostics)
+	}
+	for _, d := range diagnostics {
+		if !strings.Contains(parseErr.Error(), d.Message) {
+			t.Errorf("diagnostic %q not present in Parse error:\n%s", d.Message, parseErr)
+		}
+	}
+}
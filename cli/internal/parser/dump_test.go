@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestDump_WriteText(t *testing.T) {
+	input := `var PORT = 8080
+build: go build ./...
+deploy(env: string = "dev"): needs(build) {
+    @timeout(30s) {
+        echo "port @var(PORT)"
+    }
+}
+on failure of deploy: echo rollback`
+
+	program, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ast.Dump(program).WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	// The format is versioned: a change to this output must bump ast.DumpVersion
+	want := `# devcmd ast v4
+Program 1:1
+  VariableDecl 1:1 name="PORT"
+    NumberLiteral 1:12 value="8080"
+  CommandDecl 2:1 name="build"
+    CommandBody 2:8
+      ShellContent 2:8
+        TextPart 2:8 text="go build ./..."
+  CommandDecl 3:1 name="deploy" needs="build"
+    CommandParam 3:8 name="env" type="string"
+      StringLiteral 3:22 value="dev"
+    CommandBody 3:43 braces="true"
+      BlockDecorator 4:5 name="timeout"
+        NamedParameter 4:14 name="duration" positional="true"
+          DurationLiteral 4:14 value="30s"
+        ShellContent 5:9
+          TextPart 5:9 text="echo \"port "
+          ValueDecorator 5:20 name="var"
+            NamedParameter 5:25 name="name" positional="true"
+              Identifier 5:25 name="PORT"
+          TextPart 5:30 text="\""
+  TriggerDecl 8:1 command="deploy" event="failure"
+    CommandBody 8:23
+      ShellContent 8:23
+        TextPart 8:23 text="echo rollback"
+`
+	if got := out.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDump_WriteJSON(t *testing.T) {
+	program, err := Parse(strings.NewReader("test: @parallel(limit = 2) { go test ./... }"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ast.Dump(program).WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded struct {
+		Version int          `json:"version"`
+		AST     ast.DumpNode `json:"ast"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if decoded.Version != ast.DumpVersion {
+		t.Errorf("version = %d, want %d", decoded.Version, ast.DumpVersion)
+	}
+
+	parallel := decoded.AST.Children[0].Children[0].Children[0]
+	if parallel.Kind != "BlockDecorator" || parallel.Attrs["name"] != "parallel" {
+		t.Fatalf("expected the @parallel block, got %+v", parallel)
+	}
+	if parallel.Pos != (ast.DumpPosition{Line: 1, Column: 7}) {
+		t.Errorf("@parallel pos = %+v, want 1:7", parallel.Pos)
+	}
+	limit := parallel.Children[0]
+	if limit.Kind != "NamedParameter" || limit.Attrs["name"] != "limit" || limit.Children[0].Attrs["value"] != "2" {
+		t.Errorf("expected the limit argument, got %+v", limit)
+	}
+	if _, positional := limit.Attrs["positional"]; positional {
+		t.Errorf("limit is named, got %+v", limit.Attrs)
+	}
+}
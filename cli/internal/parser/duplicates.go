@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// checkDuplicates reports each variable and command defined twice in the program, at the
+// second definition and with the position of the first. A command, a watch command and a stop
+// command can share a name, but not two of the same kind. override doesn't allow a
+// redefinition within a file: it replaces a definition from a file merged before.
+func (p *Parser) checkDuplicates(program *ast.Program) []error {
+	var errs []error
+
+	variables := make(map[string]types.Token)
+	for _, variable := range programVariables(program) {
+		if first, ok := variables[variable.Name]; ok {
+			errs = append(errs, p.formatError(duplicateMessage("variable", variable.Name, first), variable.NameToken))
+			continue
+		}
+		variables[variable.Name] = variable.NameToken
+	}
+
+	type commandKey struct {
+		name        string
+		commandType ast.CommandType
+	}
+	commands := make(map[commandKey]types.Token)
+	for _, command := range program.Commands {
+		key := commandKey{command.Name, command.Type}
+		if first, ok := commands[key]; ok {
+			errs = append(errs, p.formatError(duplicateMessage(commandKind(command.Type), command.Name, first), command.NameToken))
+			continue
+		}
+		commands[key] = command.NameToken
+	}
+	return errs
+}
+
+// programVariables returns the variables of a program in the order they are declared,
+// including those in var groups
+func programVariables(program *ast.Program) []ast.VariableDecl {
+	variables := append([]ast.VariableDecl{}, program.Variables...)
+	for _, group := range program.VarGroups {
+		variables = append(variables, group.Variables...)
+	}
+	sort.SliceStable(variables, func(i, j int) bool {
+		return variables[i].NameToken.Line < variables[j].NameToken.Line
+	})
+	return variables
+}
+
+// commandKind names a kind of command in diagnostics
+func commandKind(commandType ast.CommandType) string {
+	switch commandType {
+	case ast.WatchCommand:
+		return "watch command"
+	case ast.StopCommand:
+		return "stop command"
+	default:
+		return "command"
+	}
+}
+
+func duplicateMessage(kind, name string, first types.Token) string {
+	return fmt.Sprintf("duplicate %s '%s': first defined at line %d, column %d", kind, name, first.Line, first.Column)
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	for input, want := range map[string]string{
+		"build: go build\ntest: go test\nbuild: go build -race":       "duplicate command 'build': first defined at line 1, column 1",
+		"var PORT = 1\nvar (\n  HOST = \"a\"\n  PORT = 2\n)\nx: echo": "duplicate variable 'PORT': first defined at line 1, column 5",
+		"watch api: air\nwatch api: go run .":                         "duplicate watch command 'api': first defined at line 1, column 7",
+		"build: go build\noverride build: go build -race":             "duplicate command 'build': first defined at line 1, column 1",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want one containing %q", input, err, want)
+		}
+	}
+
+	// A command, a watch command and a stop command can share a name
+	if _, err := Parse(strings.NewReader("api: go build\nwatch api: air\nstop api: pkill air")); err != nil {
+		t.Errorf("Parse failed: %v", err)
+	}
+}
+
+func TestDuplicates_Diagnostic(t *testing.T) {
+	_, diagnostics, err := ParseDiagnostics(strings.NewReader("build: go build\n\nbuild: make"))
+	if err != nil {
+		t.Fatalf("ParseDiagnostics failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 3 || diagnostics[0].Column != 1 {
+		t.Errorf("diagnostics = %+v, want one at the second definition, 3:1", diagnostics)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	program := mustParse(t, "override var PORT = 9090\noverride var (\n  HOST = \"b\"\n)\noverride watch api: air\noverride: echo a command named override")
+
+	if !program.Variables[0].Override || !program.VarGroups[0].Variables[0].Override {
+		t.Error("variables declared with override aren't marked")
+	}
+	if len(program.Commands) != 2 || !program.Commands[0].Override || program.Commands[0].Name != "api" {
+		t.Fatalf("commands = %+v, want the override of watch api", program.Commands)
+	}
+	if program.Commands[1].Override || program.Commands[1].Name != "override" {
+		t.Errorf("command = %s, want one named override", program.Commands[1].String())
+	}
+	if err := RoundTrip(program); err != nil {
+		t.Errorf("RoundTrip: %v", err)
+	}
+
+	_, err := Parse(strings.NewReader("build: go build\noverride on success of build: echo done"))
+	if err == nil || !strings.Contains(err.Error(), "override only applies to variables and commands") {
+		t.Errorf("override of a trigger error = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -83,6 +84,53 @@ func (e ParseError) createCodeSnippet() string {
 	return snippet.String()
 }
 
+// FormattedError is a parse error whose text already includes a multi-line source
+// snippet. It keeps the short message and offending token for tools that need
+// structured locations.
+type FormattedError struct {
+	Message string
+	Token   types.Token
+	text    string
+}
+
+// Error returns the formatted error text with source context
+func (e FormattedError) Error() string {
+	return e.text
+}
+
+// Diagnostic is a single parse problem with its source location.
+// Line and Column are 1-based and zero when the location is unknown.
+type Diagnostic struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+// NewDiagnostic converts a parse error into a diagnostic, extracting its location when available
+func NewDiagnostic(err error) Diagnostic {
+	var parseErr ParseError
+	if errors.As(err, &parseErr) {
+		return Diagnostic{
+			Message: parseErr.Type.String() + ": " + parseErr.Message,
+			Line:    parseErr.Token.Line,
+			Column:  parseErr.Token.Column,
+		}
+	}
+
+	var formattedErr FormattedError
+	if errors.As(err, &formattedErr) {
+		return Diagnostic{
+			Message: strings.TrimPrefix(formattedErr.Message, "parsing failed:\n- "),
+			Line:    formattedErr.Token.Line,
+			Column:  formattedErr.Token.Column,
+		}
+	}
+
+	// Unstructured errors keep their first line as the message
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	return Diagnostic{Message: message}
+}
+
 // Helper functions for creating standard error types
 
 // NewSyntaxError creates a syntax error with location information
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// LocalFileName returns the personal override file for a commands file, which is merged after
+// it when present: commands.cli → commands.local.cli
+func LocalFileName(commandsFile string) string {
+	ext := filepath.Ext(commandsFile)
+	if ext == "" {
+		return commandsFile + ".local.cli"
+	}
+	return strings.TrimSuffix(commandsFile, ext) + ".local" + ext
+}
+
+// LocalOverrides records what a local override file contributed to a program
+type LocalOverrides struct {
+	File      string
+	Variables []string // Variables the local file defines, whether new or overriding
+	Commands  []string // Commands the local file defines, whether new or overriding
+}
+
+// IsLocalVariable reports whether a variable's value comes from the local file
+func (o *LocalOverrides) IsLocalVariable(name string) bool {
+	return o != nil && containsName(o.Variables, name)
+}
+
+// IsLocalCommand reports whether a command is defined in the local file
+func (o *LocalOverrides) IsLocalCommand(name string) bool {
+	return o != nil && containsName(o.Commands, name)
+}
+
+// MergeLocal returns program with the local override program merged after it. Local variables
+// and commands are added; replacing one from the main file takes the override keyword, and the
+// replacement keeps the original's place. Local triggers and env profiles are added, a profile
+// with the name of one from the main file being an error, as is a config block in both files.
+// Neither program is modified.
+func MergeLocal(program, local *ast.Program, mainFile, localFile string) (*ast.Program, *LocalOverrides, error) {
+	if err := CheckOverrides(program, mainFile); err != nil {
+		return nil, nil, err
+	}
+
+	merged := *program
+	merged.Variables = append([]ast.VariableDecl{}, program.Variables...)
+	merged.VarGroups = make([]ast.VarGroup, len(program.VarGroups))
+	for i, group := range program.VarGroups {
+		merged.VarGroups[i] = group
+		merged.VarGroups[i].Variables = append([]ast.VariableDecl{}, group.Variables...)
+	}
+	merged.Commands = append([]ast.CommandDecl{}, program.Commands...)
+	overrides := &LocalOverrides{File: localFile}
+
+	for _, variable := range programVariables(local) {
+		original := findVariable(&merged, variable.Name)
+		switch {
+		case original != nil && !variable.Override:
+			return nil, nil, fmt.Errorf("%s:%d:%d: variable %q is already defined at %s:%d:%d; declare it with override to replace it",
+				localFile, variable.Pos.Line, variable.Pos.Column, variable.Name, mainFile, original.Pos.Line, original.Pos.Column)
+		case original == nil && variable.Override:
+			return nil, nil, fmt.Errorf("%s:%d:%d: override of variable %q, which %s doesn't define",
+				localFile, variable.Pos.Line, variable.Pos.Column, variable.Name, mainFile)
+		case original != nil:
+			*original = variable
+		default:
+			merged.Variables = append(merged.Variables, variable)
+		}
+		overrides.Variables = append(overrides.Variables, variable.Name)
+	}
+
+	for _, command := range local.Commands {
+		original := findCommand(&merged, command.Name, command.Type)
+		switch {
+		case original != nil && !command.Override:
+			return nil, nil, fmt.Errorf("%s:%d:%d: command %q is already defined at %s:%d:%d; declare it with override to replace it",
+				localFile, command.Pos.Line, command.Pos.Column, command.Name, mainFile, original.Pos.Line, original.Pos.Column)
+		case original == nil && command.Override:
+			return nil, nil, fmt.Errorf("%s:%d:%d: override of command %q, which %s doesn't define",
+				localFile, command.Pos.Line, command.Pos.Column, command.Name, mainFile)
+		case original != nil:
+			*original = command
+		default:
+			merged.Commands = append(merged.Commands, command)
+		}
+		if !containsName(overrides.Commands, command.Name) {
+			overrides.Commands = append(overrides.Commands, command.Name)
+		}
+	}
+
+	merged.Triggers = append(append([]ast.TriggerDecl{}, program.Triggers...), local.Triggers...)
+
+	merged.EnvProfiles = append([]ast.EnvProfileDecl{}, program.EnvProfiles...)
+	for _, profile := range local.EnvProfiles {
+		if original := merged.EnvProfile(profile.Name); original != nil {
+			return nil, nil, fmt.Errorf("%s:%d:%d: env profile %q is already defined at %s:%d:%d",
+				localFile, profile.Pos.Line, profile.Pos.Column, profile.Name, mainFile, original.Pos.Line, original.Pos.Column)
+		}
+		merged.EnvProfiles = append(merged.EnvProfiles, profile)
+	}
+
+	if local.Config != nil {
+		if program.Config != nil {
+			return nil, nil, fmt.Errorf("%s:%d:%d: the config block is already defined at %s:%d:%d",
+				localFile, local.Config.Pos.Line, local.Config.Pos.Column, mainFile, program.Config.Pos.Line, program.Config.Pos.Column)
+		}
+		merged.Config = local.Config
+	}
+
+	// Each file is free of cycles, but the commands a local file adds can close one
+	if cycle, closing := findCommandCycle(&merged); cycle != nil {
+		return nil, nil, fmt.Errorf("%s: %s", localFile, cycleMessage(cycle, closing))
+	}
+
+	return &merged, overrides, nil
+}
+
+// CheckOverrides returns an error for the first override in a file that no other file is
+// merged over, such as the main commands file, as there is nothing for it to replace
+func CheckOverrides(program *ast.Program, file string) error {
+	for _, variable := range programVariables(program) {
+		if variable.Override {
+			return fmt.Errorf("%s:%d:%d: override of variable %q: only a local override file can replace definitions",
+				file, variable.OverrideToken.Line, variable.OverrideToken.Column, variable.Name)
+		}
+	}
+	for _, command := range program.Commands {
+		if command.Override {
+			return fmt.Errorf("%s:%d:%d: override of command %q: only a local override file can replace definitions",
+				file, command.OverrideToken.Line, command.OverrideToken.Column, command.Name)
+		}
+	}
+	return nil
+}
+
+// findVariable returns the declaration of the variable with the given name, wherever it is declared
+func findVariable(program *ast.Program, name string) *ast.VariableDecl {
+	for i := range program.Variables {
+		if program.Variables[i].Name == name {
+			return &program.Variables[i]
+		}
+	}
+	for g := range program.VarGroups {
+		for i := range program.VarGroups[g].Variables {
+			if program.VarGroups[g].Variables[i].Name == name {
+				return &program.VarGroups[g].Variables[i]
+			}
+		}
+	}
+	return nil
+}
+
+// findCommand returns the command of the given name and kind
+func findCommand(program *ast.Program, name string, commandType ast.CommandType) *ast.CommandDecl {
+	for i := range program.Commands {
+		if program.Commands[i].Name == name && program.Commands[i].Type == commandType {
+			return &program.Commands[i]
+		}
+	}
+	return nil
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func mustParse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	program, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return program
+}
+
+func TestLocalFileName(t *testing.T) {
+	for file, want := range map[string]string{
+		"commands.cli":      "commands.local.cli",
+		"ci/commands.cli":   "ci/commands.local.cli",
+		"tasks.devcmd":      "tasks.local.devcmd",
+		"Commandfile":       "Commandfile.local.cli",
+		"./dev/release.cli": "./dev/release.local.cli",
+	} {
+		if got := LocalFileName(file); got != want {
+			t.Errorf("LocalFileName(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestMergeLocal(t *testing.T) {
+	main := mustParse(t, "var PORT = 8080\nvar (\n  ENV = \"dev\"\n  REGION = \"eu\"\n)\nbuild: go build\nserve: go run . --port @var(PORT)")
+	local := mustParse(t, "override var PORT = 9090\noverride var (\n  REGION = \"us\"\n)\nvar TOKEN = \"secret\"\nmine: echo @var(TOKEN)")
+	original := main.String()
+
+	merged, overrides, err := MergeLocal(main, local, "commands.cli", "commands.local.cli")
+	if err != nil {
+		t.Fatalf("MergeLocal failed: %v", err)
+	}
+
+	values := make(map[string]string)
+	var order []string
+	for _, variable := range merged.Variables {
+		values[variable.Name] = variable.Value.String()
+		order = append(order, variable.Name)
+	}
+	for _, group := range merged.VarGroups {
+		for _, variable := range group.Variables {
+			values[variable.Name] = variable.Value.String()
+		}
+	}
+	if values["PORT"] != "9090" || values["REGION"] != "us" || values["ENV"] != "dev" || values["TOKEN"] != "secret" {
+		t.Errorf("merged variables = %v", values)
+	}
+	if strings.Join(order, ",") != "PORT,TOKEN" {
+		t.Errorf("variables = %v, want the override in place and new variables after", order)
+	}
+
+	var commands []string
+	for _, command := range merged.Commands {
+		commands = append(commands, command.Name)
+	}
+	if strings.Join(commands, ",") != "build,serve,mine" {
+		t.Errorf("commands = %v, want build,serve,mine", commands)
+	}
+
+	if !overrides.IsLocalVariable("PORT") || !overrides.IsLocalVariable("TOKEN") || overrides.IsLocalVariable("ENV") {
+		t.Errorf("local variables = %v", overrides.Variables)
+	}
+	if !overrides.IsLocalCommand("mine") || overrides.IsLocalCommand("build") {
+		t.Errorf("local commands = %v", overrides.Commands)
+	}
+	if main.String() != original {
+		t.Error("MergeLocal modified the main program")
+	}
+}
+
+func TestMergeLocal_DuplicateCommand(t *testing.T) {
+	main := mustParse(t, "build: go build")
+	local := mustParse(t, "var X = 1\nbuild: go build -race")
+
+	_, _, err := MergeLocal(main, local, "commands.cli", "commands.local.cli")
+	want := `commands.local.cli:2:1: command "build" is already defined at commands.cli:1:1; declare it with override to replace it`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+
+	local = mustParse(t, "var PORT = 1")
+	_, _, err = MergeLocal(mustParse(t, "\nvar PORT = 8080"), local, "commands.cli", "commands.local.cli")
+	want = `commands.local.cli:1:1: variable "PORT" is already defined at commands.cli:2:1`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+}
+
+func TestMergeLocal_OverrideCommand(t *testing.T) {
+	main := mustParse(t, "build: go build\ntest: go test\nwatch build: air\nstop build: pkill air")
+	local := mustParse(t, "override build: go build -race")
+
+	merged, overrides, err := MergeLocal(main, local, "commands.cli", "commands.local.cli")
+	if err != nil {
+		t.Fatalf("MergeLocal failed: %v", err)
+	}
+	var commands []string
+	for _, command := range merged.Commands {
+		commands = append(commands, command.String())
+	}
+	if got := strings.Join(commands, "; "); !strings.HasPrefix(got, "override build: go build -race; test: go test; watch build") {
+		t.Errorf("commands = %s, want the override in place of build", got)
+	}
+	if !overrides.IsLocalCommand("build") {
+		t.Errorf("local commands = %v, want build", overrides.Commands)
+	}
+
+	_, _, err = MergeLocal(main, mustParse(t, "override deploy: echo"), "commands.cli", "commands.local.cli")
+	want := `commands.local.cli:1:1: override of command "deploy", which commands.cli doesn't define`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+
+	_, _, err = MergeLocal(mustParse(t, "override var X = 1"), local, "commands.cli", "commands.local.cli")
+	want = `commands.cli:1:1: override of variable "X": only a local override file can replace definitions`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+}
+
+func TestMergeLocal_Cycle(t *testing.T) {
+	main := mustParse(t, "ci: @cmd(mine)")
+	local := mustParse(t, "mine: @cmd(ci)")
+
+	_, _, err := MergeLocal(main, local, "commands.cli", "commands.local.cli")
+	want := "commands.local.cli: dependency cycle: ci → mine → ci"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+}
+
+func TestLocalOverrides_Nil(t *testing.T) {
+	var overrides *LocalOverrides
+	if overrides.IsLocalCommand("build") || overrides.IsLocalVariable("PORT") {
+		t.Error("nil overrides reported local items")
+	}
+}
+
+func TestMergeLocal_EnvProfiles(t *testing.T) {
+	main := mustParse(t, "var PORT = 80\nenv production {\n    PORT = 443\n}")
+
+	merged, _, err := MergeLocal(main, mustParse(t, "var DEBUG = false\nenv dev {\n    DEBUG = true\n}"), "commands.cli", "commands.local.cli")
+	if err != nil {
+		t.Fatalf("MergeLocal failed: %v", err)
+	}
+	if len(merged.EnvProfiles) != 2 || merged.EnvProfile("dev") == nil || len(main.EnvProfiles) != 1 {
+		t.Errorf("env profiles = %v, want production and dev, leaving the main program alone", merged.EnvProfiles)
+	}
+
+	_, _, err = MergeLocal(main, mustParse(t, "var DEBUG = false\nenv production {\n    DEBUG = true\n}"), "commands.cli", "commands.local.cli")
+	want := `commands.local.cli:2:1: env profile "production" is already defined at commands.cli:2:1`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+}
+
+func TestMergeLocal_Config(t *testing.T) {
+	main := mustParse(t, "build: go build")
+
+	merged, _, err := MergeLocal(main, mustParse(t, "config {\n    defaultTimeout = 5m\n}"), "commands.cli", "commands.local.cli")
+	if err != nil {
+		t.Fatalf("MergeLocal failed: %v", err)
+	}
+	if merged.Config == nil || main.Config != nil {
+		t.Errorf("config = %v, want the local config block, leaving the main program alone", merged.Config)
+	}
+
+	_, _, err = MergeLocal(merged, mustParse(t, "config {\n    defaultTimeout = 1m\n}"), "commands.cli", "commands.local.cli")
+	want := "commands.local.cli:1:1: the config block is already defined at commands.cli:1:1"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("MergeLocal error = %v, want %q", err, want)
+	}
+}
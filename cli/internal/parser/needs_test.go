@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestNeeds(t *testing.T) {
+	for input, want := range map[string][]string{
+		"deploy: needs(build, test) kubectl apply -f k8s":              {"build", "test"},
+		"deploy: needs(build) {\n  kubectl apply -f k8s\n}":            {"build"},
+		"deploy:needs( build ,test-unit )@parallel { echo a }":         {"build", "test-unit"},
+		"deploy(env: string = \"dev\"): needs(build) echo @param(env)": {"build"},
+		"deploy: needs to run first":                                   nil,
+		"deploy: echo needs(build)":                                    nil,
+	} {
+		program, err := Parse(strings.NewReader(input + "\nbuild: go build\ntest: go test\ntest-unit: go test"))
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", input, err)
+			continue
+		}
+		deploy := program.Commands[0]
+		if got := deploy.NeedNames(); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Parse(%q) needs = %v, want %v", input, got, want)
+		}
+		if len(want) == 0 && len(deploy.Body.Content) == 0 {
+			t.Errorf("Parse(%q) lost the shell content", input)
+		}
+		if len(want) > 0 {
+			if deploy.NeedsToken == nil {
+				t.Errorf("Parse(%q) has no needs token", input)
+			}
+			if shell, ok := deploy.Body.Content[0].(*ast.ShellContent); ok && strings.HasPrefix(shell.String(), " ") {
+				t.Errorf("Parse(%q) shell content %q starts with the space after needs", input, shell.String())
+			}
+		}
+	}
+}
+
+func TestNeeds_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"deploy: needs(build, build) echo":                           "duplicate need 'build'",
+		"deploy: needs(build test) echo":                             "expected ',' or ')' in needs(...)",
+		"watch api: needs(build) go run .":                           "only regular commands can declare needs",
+		"deploy: needs(deploy) echo":                                 "dependency cycle: deploy → deploy; a command can't run itself through needs",
+		"a: needs(b) echo a\nb: @cmd(a)":                             "dependency cycle: a → b → a; a command can't run itself through @cmd",
+		"a: needs(b) echo a\nb: needs(c) echo b\nc: needs(a) echo c": "dependency cycle: a → b → c → a",
+	} {
+		_, err := Parse(strings.NewReader(input + "\nbuild: go build"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want one containing %q", input, err, want)
+		}
+	}
+}
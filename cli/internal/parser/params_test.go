@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestCommandParams(t *testing.T) {
+	program := mustParse(t, `deploy(env: string, replicas: number = 3, force: boolean = false): kubectl scale --replicas=@param(replicas) deploy/@param(env)
+greet(name: string = "world"): echo "hello @param(name)"
+build: go build ./...`)
+
+	if len(program.Commands) != 3 {
+		t.Fatalf("commands = %v, want deploy, greet and build", program.Commands)
+	}
+	deploy := program.Commands[0]
+	if deploy.Name != "deploy" || len(deploy.Params) != 3 {
+		t.Fatalf("deploy = %+v, want three parameters", deploy)
+	}
+
+	env, replicas, force := deploy.Params[0], deploy.Params[1], deploy.Params[2]
+	if env.Name != "env" || env.Type != ast.StringType || !env.Required() {
+		t.Errorf("first parameter = %s, want a required string env", env)
+	}
+	if replicas.Type != ast.NumberType || replicas.Default.String() != "3" {
+		t.Errorf("second parameter = %s, want a number replicas defaulting to 3", replicas)
+	}
+	if force.Type != ast.BooleanType || force.Default.String() != "false" {
+		t.Errorf("third parameter = %s, want a boolean force defaulting to false", force)
+	}
+	if got := deploy.String(); !strings.HasPrefix(got, "deploy(env: string, replicas: number = 3, force: boolean = false): ") || !strings.Contains(got, "@param(replicas)") {
+		t.Errorf("String() = %q", got)
+	}
+
+	if got := program.Commands[1].Params[0].Default.String(); got != "world" {
+		t.Errorf("greet default = %q, want world", got)
+	}
+	if len(program.Commands[2].Params) != 0 {
+		t.Errorf("build parameters = %v, want none", program.Commands[2].Params)
+	}
+}
+
+func TestCommandParams_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"deploy(env: text): echo deploy":                    "unknown type 'text'",
+		"deploy(env): echo deploy":                          "expected ':' and a type after parameter 'env'",
+		"deploy(env: string, env: string): echo deploy":     "duplicate parameter 'env'",
+		"deploy(replicas: number = \"3\"): echo deploy":     "parameter 'replicas' expects number",
+		"deploy(env: string = DEFAULT_ENV): echo deploy":    "must be a string literal",
+		"deploy(env: string): echo @param(region)":          "@param references 'region'",
+		"watch api(port: number = 8080): go run ./cmd/api":  "only regular commands can declare parameters",
+		"build: echo @param(target)":                        "@param references 'target'",
+		"deploy(env: string) echo missing colon":            "expected ':' after command name",
+		"deploy(env: string replicas: number): echo deploy": "expected ',' or ')'",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want one containing %q", input, err, want)
+		}
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aledsdavies/devcmd/cli/internal/lexer"
 	"github.com/aledsdavies/devcmd/core/ast"
 	"github.com/aledsdavies/devcmd/core/types"
 	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/execution"
 )
 
 // Parser implements a fast, spec-compliant recursive descent parser for the Devcmd language.
@@ -22,10 +24,16 @@ type Parser struct {
 
 	// errors is a slice of errors encountered during parsing.
 	// This allows for better error reporting by collecting multiple errors.
-	errors []string
+	errors []error
 
 	// program is the AST being built during parsing (for variable type lookups)
 	program *ast.Program
+
+	// commandParams are the parameters of the command being parsed, which @param references
+	commandParams []ast.CommandParam
+
+	// comments are the comments collected since the last declaration, for the next one
+	comments []ast.Comment
 }
 
 // Parse tokenizes and parses the input from an io.Reader into a complete AST.
@@ -36,26 +44,59 @@ func Parse(reader io.Reader) (*ast.Program, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read input: %w", err)
 	}
-	input := string(data)
+	p, program := parse(string(data))
 
-	lex := lexer.New(strings.NewReader(input))
-	p := &Parser{
-		input:  input, // Store the raw input
-		tokens: lex.TokenizeToSlice(),
+	if len(p.errors) > 0 {
+		messages := make([]string, len(p.errors))
+		for i, err := range p.errors {
+			messages[i] = err.Error()
+		}
+		return nil, fmt.Errorf("parsing failed:\n- %s", strings.Join(messages, "\n- "))
 	}
-	program := p.parseProgram()
+	return program, nil
+}
+
+// ParseDiagnostics parses the input like Parse, but reports each parse error as a
+// separate diagnostic with its source location instead of one combined error.
+// The program is nil when any diagnostics are returned; the error is only set
+// when the input cannot be read.
+func ParseDiagnostics(reader io.Reader) (*ast.Program, []Diagnostic, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	p, program := parse(string(data))
 
 	if len(p.errors) > 0 {
-		return nil, fmt.Errorf("parsing failed:\n- %s", strings.Join(p.errors, "\n- "))
+		diagnostics := make([]Diagnostic, len(p.errors))
+		for i, err := range p.errors {
+			diagnostics[i] = NewDiagnostic(err)
+		}
+		return nil, diagnostics, nil
 	}
-	return program, nil
+	return program, nil, nil
+}
+
+// parse tokenizes and parses input. A panic in the lexer or parser is a bug, but it is
+// reported as an error at the token being parsed rather than crashing the caller, as editors
+// and the language server parse incomplete files on every keystroke.
+func parse(input string) (p *Parser, program *ast.Program) {
+	p = &Parser{input: input} // Store the raw input
+	defer func() {
+		if r := recover(); r != nil {
+			p.addError(p.formatError(fmt.Sprintf("internal parser error: %v; please report this with the input", r), p.current()))
+			program = nil
+		}
+	}()
+	p.tokens = lexer.New(strings.NewReader(input)).TokenizeToSlice()
+	return p, p.parseProgram()
 }
 
 // --- Main Parsing Logic ---
 
 // parseProgram is the top-level entry point for parsing.
 // It iterates through the tokens and parses all top-level statements.
-// Program = { VariableDecl | VarGroup | CommandDecl }*
+// Program = { [ "override" ] ( VariableDecl | VarGroup | CommandDecl ) | TriggerDecl | EnvProfileDecl | ConfigDecl }*
 func (p *Parser) parseProgram() *ast.Program {
 	program := &ast.Program{}
 	p.program = program // Store reference for variable type lookups
@@ -65,6 +106,15 @@ func (p *Parser) parseProgram() *ast.Program {
 		if p.isAtEnd() {
 			break
 		}
+		leading := p.leadingComments(p.current())
+
+		// override is only a keyword before a declaration, so a command can still be named override
+		var overrideToken *types.Token
+		if p.isOverride() {
+			token := p.current()
+			overrideToken = &token
+			p.advance()
+		}
 
 		switch p.current().Type {
 		case types.VAR:
@@ -74,6 +124,10 @@ func (p *Parser) parseProgram() *ast.Program {
 					p.addError(err)
 					p.synchronize()
 				} else {
+					for i := range varGroup.Variables {
+						setVariableOverride(&varGroup.Variables[i], overrideToken)
+					}
+					varGroup.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 					program.VarGroups = append(program.VarGroups, *varGroup)
 				}
 			} else {
@@ -82,10 +136,67 @@ func (p *Parser) parseProgram() *ast.Program {
 					p.addError(err)
 					p.synchronize()
 				} else {
+					if overrideToken != nil {
+						setVariableOverride(varDecl, overrideToken)
+						varDecl.Pos = ast.Position{Line: overrideToken.Line, Column: overrideToken.Column}
+					}
+					varDecl.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 					program.Variables = append(program.Variables, *varDecl)
 				}
 			}
 		case types.IDENTIFIER, types.WATCH, types.STOP:
+			if p.isTriggerDecl() {
+				if overrideToken != nil {
+					p.addError(p.formatError("override only applies to variables and commands; the triggers of every file run", *overrideToken))
+					p.synchronize()
+					continue
+				}
+				trigger, err := p.parseTriggerDecl()
+				if err != nil {
+					p.addError(err)
+					p.synchronize()
+				} else {
+					trigger.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
+					program.Triggers = append(program.Triggers, *trigger)
+				}
+				continue
+			}
+			if p.isEnvProfileDecl() {
+				if overrideToken != nil {
+					p.addError(p.formatError("override only applies to variables and commands; a local file can't replace an env profile", *overrideToken))
+					p.synchronize()
+					continue
+				}
+				profile, err := p.parseEnvProfileDecl()
+				if err != nil {
+					p.addError(err)
+					p.synchronize()
+				} else {
+					profile.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
+					program.EnvProfiles = append(program.EnvProfiles, *profile)
+				}
+				continue
+			}
+			if p.isConfigDecl() {
+				if overrideToken != nil {
+					p.addError(p.formatError("override only applies to variables and commands; a local file can't replace the config block", *overrideToken))
+					p.synchronize()
+					continue
+				}
+				config, err := p.parseConfigDecl()
+				switch {
+				case err != nil:
+					p.addError(err)
+					p.synchronize()
+				case program.Config != nil:
+					p.addError(p.formatError(fmt.Sprintf("duplicate config block: first defined at line %d, column %d",
+						program.Config.ConfigToken.Line, program.Config.ConfigToken.Column), config.ConfigToken))
+				default:
+					config.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
+					program.Config = config
+				}
+				continue
+			}
 			// A command can start with a name (IDENTIFIER), a keyword (WATCH/STOP),
 			// or a decorator (@).
 			cmd, err := p.parseCommandDecl()
@@ -93,6 +204,12 @@ func (p *Parser) parseProgram() *ast.Program {
 				p.addError(err)
 				p.synchronize()
 			} else {
+				if overrideToken != nil {
+					cmd.Override = true
+					cmd.OverrideToken = overrideToken
+					cmd.Pos = ast.Position{Line: overrideToken.Line, Column: overrideToken.Column}
+				}
+				cmd.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 				program.Commands = append(program.Commands, *cmd)
 			}
 		default:
@@ -101,11 +218,23 @@ func (p *Parser) parseProgram() *ast.Program {
 		}
 	}
 
+	p.flushComments()
+
+	for _, err := range p.checkDuplicates(program) {
+		p.addError(err)
+	}
+	for _, err := range p.checkEnvProfiles(program) {
+		p.addError(err)
+	}
+	if err := p.checkCommandCycles(program); err != nil {
+		p.addError(err)
+	}
+
 	return program
 }
 
 // parseCommandDecl parses a full command declaration.
-// CommandDecl = { Decorator }* [ "watch" | "stop" ] IDENTIFIER ":" CommandBody
+// CommandDecl = { Decorator }* [ "watch" | "stop" ] IDENTIFIER [ CommandParams ] ":" [ Needs ] CommandBody
 func (p *Parser) parseCommandDecl() (*ast.CommandDecl, error) {
 	startPos := p.current()
 
@@ -131,14 +260,43 @@ func (p *Parser) parseCommandDecl() (*ast.CommandDecl, error) {
 	}
 	name := nameToken.Value
 
-	// 3. Parse colon
+	// 3. Parse parameters, which only regular commands take
+	var params []ast.CommandParam
+	if p.match(types.LPAREN) {
+		if cmdType != ast.Command {
+			return nil, p.formatError("only regular commands can declare parameters", p.current())
+		}
+		params, err = p.parseCommandParams()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Parse colon
 	colonToken, err := p.consume(types.COLON, "expected ':' after command name")
 	if err != nil {
 		return nil, err
 	}
 
-	// 4. Parse command body (this will handle post-colon decorators and syntax sugar)
+	// 5. Parse the commands it needs, which only regular commands declare
+	var needs []ast.Identifier
+	var needsToken *types.Token
+	if p.current().Type == types.IDENTIFIER && p.current().Value == "needs" && p.peek().Type == types.LPAREN {
+		token := p.current()
+		if cmdType != ast.Command {
+			return nil, p.formatError("only regular commands can declare needs", token)
+		}
+		needsToken = &token
+		needs, err = p.parseNeeds()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 6. Parse command body (this will handle post-colon decorators and syntax sugar)
+	p.commandParams = params
 	body, err := p.parseCommandBody()
+	p.commandParams = nil
 	if err != nil {
 		return nil, err
 	}
@@ -146,14 +304,367 @@ func (p *Parser) parseCommandDecl() (*ast.CommandDecl, error) {
 	return &ast.CommandDecl{
 		Name:       name,
 		Type:       cmdType,
+		Params:     params,
+		Needs:      needs,
 		Body:       *body,
 		Pos:        ast.Position{Line: startPos.Line, Column: startPos.Column},
 		TypeToken:  typeToken,
 		NameToken:  nameToken,
 		ColonToken: colonToken,
+		NeedsToken: needsToken,
 	}, nil
 }
 
+// parseNeeds parses the commands a command needs to have run first.
+// Needs = "needs" "(" IDENTIFIER { "," IDENTIFIER } ")"
+func (p *Parser) parseNeeds() ([]ast.Identifier, error) {
+	p.advance() // consume 'needs'
+	p.advance() // consume '('
+
+	var needs []ast.Identifier
+	for {
+		nameToken, err := p.consume(types.IDENTIFIER, "expected a command name in needs(...)")
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range needs {
+			if existing.Name == nameToken.Value {
+				return nil, p.formatError(fmt.Sprintf("duplicate need '%s'", nameToken.Value), nameToken)
+			}
+		}
+		needs = append(needs, ast.Identifier{
+			Name:  nameToken.Value,
+			Pos:   ast.Position{Line: nameToken.Line, Column: nameToken.Column},
+			Token: nameToken,
+		})
+
+		if p.match(types.RPAREN) {
+			break
+		}
+		if _, err := p.consume(types.COMMA, "expected ',' or ')' in needs(...)"); err != nil {
+			return nil, err
+		}
+	}
+	p.advance() // consume ')'
+
+	return needs, nil
+}
+
+// parseCommandParams parses the typed parameters a command declares.
+// CommandParams = "(" [ CommandParam { "," CommandParam } ] ")"
+// CommandParam = IDENTIFIER ":" ( "string" | "number" | "boolean" ) [ "=" Value ]
+func (p *Parser) parseCommandParams() ([]ast.CommandParam, error) {
+	if _, err := p.consume(types.LPAREN, "expected '(' for command parameters"); err != nil {
+		return nil, err
+	}
+
+	var params []ast.CommandParam
+	for !p.match(types.RPAREN) {
+		if len(params) > 0 {
+			if _, err := p.consume(types.COMMA, "expected ',' or ')' after command parameter"); err != nil {
+				return nil, err
+			}
+		}
+
+		nameToken, err := p.consume(types.IDENTIFIER, "expected parameter name")
+		if err != nil {
+			return nil, err
+		}
+		for _, param := range params {
+			if param.Name == nameToken.Value {
+				return nil, p.formatError(fmt.Sprintf("duplicate parameter '%s'", nameToken.Value), nameToken)
+			}
+		}
+		if _, err := p.consume(types.COLON, fmt.Sprintf("expected ':' and a type after parameter '%s'", nameToken.Value)); err != nil {
+			return nil, err
+		}
+
+		typeToken, err := p.consume(types.IDENTIFIER, "expected parameter type: string, number or boolean")
+		if err != nil {
+			return nil, err
+		}
+		param := ast.CommandParam{
+			Name:      nameToken.Value,
+			Pos:       ast.Position{Line: nameToken.Line, Column: nameToken.Column},
+			NameToken: nameToken,
+			TypeToken: typeToken,
+		}
+		switch typeToken.Value {
+		case "string":
+			param.Type = ast.StringType
+		case "number":
+			param.Type = ast.NumberType
+		case "boolean":
+			param.Type = ast.BooleanType
+		default:
+			return nil, p.formatError(fmt.Sprintf("unknown type '%s' for parameter '%s', expected string, number or boolean", typeToken.Value, nameToken.Value), typeToken)
+		}
+
+		if p.match(types.EQUALS) {
+			p.advance()
+			param.Default, err = p.parseValueWithTypeCheck(param.Type, param.Name)
+			if err != nil {
+				return nil, err
+			}
+			// Defaults are literals, not references to variables
+			if param.Default.GetType() == ast.IdentifierType {
+				return nil, p.formatError(fmt.Sprintf("default of parameter '%s' must be a %s literal", param.Name, param.Type), p.previous())
+			}
+		}
+		params = append(params, param)
+	}
+	p.advance() // consume ')'
+
+	return params, nil
+}
+
+// isTriggerDecl checks if the current position starts a trigger such as "on failure of deploy:"
+// or `on change "src/**":`. "on" is not a keyword, so a command can still be named "on".
+func (p *Parser) isTriggerDecl() bool {
+	if p.current().Type != types.IDENTIFIER || p.current().Value != "on" {
+		return false
+	}
+	event, next := p.peek(), p.tokenAt(p.pos+2)
+	if event.Type != types.IDENTIFIER {
+		return false
+	}
+	switch ast.TriggerEvent(event.Value) {
+	case ast.TriggerOnSuccess, ast.TriggerOnFailure:
+		return next.Type == types.IDENTIFIER && next.Value == "of"
+	case ast.TriggerOnChange:
+		return next.Type == types.STRING
+	}
+	return false
+}
+
+// isEnvProfileDecl checks if the current position starts an env profile such as
+// "env production {". "env" is not a keyword, so a command can still be named "env".
+func (p *Parser) isEnvProfileDecl() bool {
+	return p.current().Type == types.IDENTIFIER && p.current().Value == "env" &&
+		p.peek().Type == types.IDENTIFIER && p.tokenAt(p.pos+2).Type == types.LBRACE
+}
+
+// isConfigDecl checks if the current position starts the config block, "config {". "config"
+// is not a keyword, so a command can still be named "config".
+func (p *Parser) isConfigDecl() bool {
+	return p.current().Type == types.IDENTIFIER && p.current().Value == "config" && p.peek().Type == types.LBRACE
+}
+
+// isOverride reports whether the current token is the override keyword before a declaration,
+// rather than the name of a command called override
+func (p *Parser) isOverride() bool {
+	if p.current().Type != types.IDENTIFIER || p.current().Value != "override" {
+		return false
+	}
+	switch p.peek().Type {
+	case types.VAR, types.WATCH, types.STOP, types.IDENTIFIER:
+		return true
+	}
+	return false
+}
+
+// setVariableOverride marks a variable as declared with the override keyword, when there was one
+func setVariableOverride(variable *ast.VariableDecl, overrideToken *types.Token) {
+	if overrideToken == nil {
+		return
+	}
+	variable.Override = true
+	variable.OverrideToken = overrideToken
+}
+
+// parseTriggerDecl parses a command run after another command succeeds or fails, or when
+// files change.
+// TriggerDecl = "on" ( "success" | "failure" ) "of" IDENTIFIER ":" CommandBody
+//
+//	| "on" "change" STRING { "," STRING } [ "debounce" DURATION ] ":" CommandBody
+func (p *Parser) parseTriggerDecl() (*ast.TriggerDecl, error) {
+	startPos := p.current()
+
+	onToken, _ := p.consume(types.IDENTIFIER, "")    // already checked by isTriggerDecl
+	eventToken, _ := p.consume(types.IDENTIFIER, "") // already checked
+	trigger := &ast.TriggerDecl{
+		Event:      ast.TriggerEvent(eventToken.Value),
+		Pos:        ast.Position{Line: startPos.Line, Column: startPos.Column},
+		OnToken:    onToken,
+		EventToken: eventToken,
+	}
+
+	if trigger.Event == ast.TriggerOnChange {
+		for {
+			pathToken, err := p.consume(types.STRING, "expected a quoted path pattern")
+			if err != nil {
+				return nil, err
+			}
+			if pathToken.Value == "" {
+				return nil, p.formatError("path patterns must not be empty", pathToken)
+			}
+			trigger.Paths = append(trigger.Paths, pathToken.Value)
+			trigger.PathTokens = append(trigger.PathTokens, pathToken)
+			if !p.match(types.COMMA) {
+				break
+			}
+			p.advance()
+		}
+
+		if p.match(types.IDENTIFIER) && p.current().Value == "debounce" {
+			p.advance()
+			durationToken, err := p.consume(types.DURATION, "expected a duration after 'debounce', e.g. 500ms")
+			if err != nil {
+				return nil, err
+			}
+			debounce, err := time.ParseDuration(durationToken.Value)
+			if err != nil {
+				return nil, p.formatError(fmt.Sprintf("invalid debounce duration: %v", err), durationToken)
+			}
+			trigger.Debounce = debounce
+			trigger.DebounceToken = &durationToken
+		}
+	} else {
+		trigger.OfToken, _ = p.consume(types.IDENTIFIER, "") // already checked
+
+		commandToken, err := p.consume(types.IDENTIFIER, "expected command name after 'of'")
+		if err != nil {
+			return nil, err
+		}
+		trigger.Command = commandToken.Value
+		trigger.CommandToken = commandToken
+	}
+
+	colonToken, err := p.consume(types.COLON, "expected ':' after trigger")
+	if err != nil {
+		return nil, err
+	}
+	trigger.ColonToken = colonToken
+
+	body, err := p.parseCommandBody()
+	if err != nil {
+		return nil, err
+	}
+	trigger.Body = *body
+
+	return trigger, nil
+}
+
+// parseEnvProfileDecl parses the variable values of a profile, one per line as in a var group.
+// EnvProfileDecl = "env" IDENTIFIER "{" { IDENTIFIER "=" VariableValue } "}"
+func (p *Parser) parseEnvProfileDecl() (*ast.EnvProfileDecl, error) {
+	startPos := p.current()
+
+	envToken, _ := p.consume(types.IDENTIFIER, "")  // already checked by isEnvProfileDecl
+	nameToken, _ := p.consume(types.IDENTIFIER, "") // already checked
+	openBrace, _ := p.consume(types.LBRACE, "")     // already checked
+
+	var variables []ast.VariableDecl
+	for !p.match(types.RBRACE) && !p.isAtEnd() {
+		p.skipWhitespaceAndComments()
+		if p.match(types.RBRACE) {
+			break
+		}
+		if p.current().Type != types.IDENTIFIER {
+			return nil, p.formatError(fmt.Sprintf("expected variable name inside env profile '%s', got %s", nameToken.Value, p.current().Type), p.current())
+		}
+
+		leading := p.leadingComments(p.current())
+		varDecl, err := p.parseGroupedVariableDecl()
+		if err != nil {
+			return nil, err
+		}
+		varDecl.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
+		variables = append(variables, *varDecl)
+		p.skipWhitespaceAndComments()
+	}
+
+	p.flushComments()
+	closeBrace, err := p.consume(types.RBRACE, fmt.Sprintf("expected '}' to close env profile '%s'", nameToken.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.EnvProfileDecl{
+		Name:       nameToken.Value,
+		Variables:  variables,
+		Pos:        ast.Position{Line: startPos.Line, Column: startPos.Column},
+		EnvToken:   envToken,
+		NameToken:  nameToken,
+		OpenBrace:  openBrace,
+		CloseBrace: closeBrace,
+	}, nil
+}
+
+// parseConfigDecl parses the settings of the config block, one per line as in a var group, and
+// checks each is a known setting with a value of its type.
+// ConfigDecl = "config" "{" { IDENTIFIER "=" VariableValue } "}"
+func (p *Parser) parseConfigDecl() (*ast.ConfigDecl, error) {
+	configToken, _ := p.consume(types.IDENTIFIER, "") // already checked by isConfigDecl
+	openBrace, _ := p.consume(types.LBRACE, "")       // already checked
+
+	config := &ast.ConfigDecl{
+		Pos:         ast.Position{Line: configToken.Line, Column: configToken.Column},
+		ConfigToken: configToken,
+		OpenBrace:   openBrace,
+	}
+	for !p.match(types.RBRACE) && !p.isAtEnd() {
+		p.skipWhitespaceAndComments()
+		if p.match(types.RBRACE) {
+			break
+		}
+		if p.current().Type != types.IDENTIFIER {
+			return nil, p.formatError(fmt.Sprintf("expected setting name inside config block, got %s", p.current().Type), p.current())
+		}
+
+		leading := p.leadingComments(p.current())
+		setting, err := p.parseGroupedVariableDecl()
+		if err != nil {
+			return nil, err
+		}
+		setting.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
+		if err := p.applyConfigSetting(config, setting); err != nil {
+			return nil, err
+		}
+		config.Settings = append(config.Settings, *setting)
+		p.skipWhitespaceAndComments()
+	}
+
+	p.flushComments()
+	closeBrace, err := p.consume(types.RBRACE, "expected '}' to close config block")
+	if err != nil {
+		return nil, err
+	}
+	config.CloseBrace = closeBrace
+	return config, nil
+}
+
+// applyConfigSetting checks a setting of the config block and records its value on config
+func (p *Parser) applyConfigSetting(config *ast.ConfigDecl, setting *ast.VariableDecl) error {
+	if first := config.Setting(setting.Name); first != nil {
+		return p.formatError(duplicateMessage("setting", setting.Name, first.NameToken), setting.NameToken)
+	}
+	switch setting.Name {
+	case ast.ConfigDefaultTimeout:
+		literal, ok := setting.Value.(*ast.DurationLiteral)
+		if !ok {
+			return p.formatError(fmt.Sprintf("config setting '%s' must be a duration, e.g. 10m, got %s", setting.Name, setting.Value.GetType()), setting.NameToken)
+		}
+		timeout, err := time.ParseDuration(literal.Value)
+		if err != nil || timeout <= 0 {
+			return p.formatError(fmt.Sprintf("config setting '%s' must be a positive duration, got %s", setting.Name, literal.Value), setting.NameToken)
+		}
+		config.DefaultTimeout = timeout
+	case ast.ConfigShell:
+		literal, ok := setting.Value.(*ast.StringLiteral)
+		if !ok {
+			return p.formatError(fmt.Sprintf("config setting '%s' must be a string, e.g. \"bash\", got %s", setting.Name, setting.Value.GetType()), setting.NameToken)
+		}
+		if !execution.IsShell(literal.Value) {
+			return p.formatError(fmt.Sprintf("config setting '%s' must be one of %s, got %q", setting.Name, strings.Join(execution.Shells, ", "), literal.Value), setting.NameToken)
+		}
+		config.Shell = literal.Value
+	default:
+		return p.formatError(fmt.Sprintf("unknown config setting '%s'; the config block can set %s and %s", setting.Name, ast.ConfigDefaultTimeout, ast.ConfigShell), setting.NameToken)
+	}
+	return nil
+}
+
 // parseCommandBody parses the content after the command's colon.
 // It handles the syntax sugar for simple vs. block commands.
 // **FIXED**: Now properly implements syntax sugar equivalence as per spec.
@@ -422,15 +933,18 @@ func (p *Parser) parsePatternContent() (*ast.PatternDecorator, error) {
 			break
 		}
 
+		leading := p.leadingComments(p.current())
 		branch, err := p.parsePatternBranch()
 		if err != nil {
 			return nil, err
 		}
+		branch.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 		patterns = append(patterns, *branch)
 		p.skipWhitespaceAndComments()
 	}
 
 	// Expect closing brace
+	p.flushComments()
 	_, err = p.consume(types.RBRACE, "expected '}' to close pattern block")
 	if err != nil {
 		return nil, err
@@ -458,9 +972,22 @@ func (p *Parser) parsePatternContent() (*ast.PatternDecorator, error) {
 func (p *Parser) parsePatternBranch() (*ast.PatternBranch, error) {
 	startPos := p.current()
 
-	// Parse pattern (identifier or wildcard)
+	// Parse pattern (identifier, wildcard, or quoted glob or regex)
 	var pattern ast.Pattern
-	if p.match(types.IDENTIFIER) {
+	if p.match(types.STRING) {
+		token := p.current()
+		p.advance()
+
+		stringPattern := &ast.StringPattern{
+			Value: token.Value,
+			Pos:   ast.Position{Line: token.Line, Column: token.Column},
+			Token: token,
+		}
+		if err := stringPattern.Validate(); err != nil {
+			return nil, p.NewSyntaxError(err.Error())
+		}
+		pattern = stringPattern
+	} else if p.match(types.IDENTIFIER) {
 		token := p.current()
 		p.advance()
 
@@ -478,7 +1005,7 @@ func (p *Parser) parsePatternBranch() (*ast.PatternBranch, error) {
 			}
 		}
 	} else {
-		return nil, p.NewSyntaxError(fmt.Sprintf("expected pattern identifier, got %s", p.current().Type.String()))
+		return nil, p.NewSyntaxError(fmt.Sprintf("expected pattern identifier or quoted pattern, got %s", p.current().Type.String()))
 	}
 
 	// Parse colon
@@ -612,7 +1139,8 @@ func (p *Parser) parseShellContent(inBlock bool) (*ast.ShellContent, error) {
 	for !p.match(types.SHELL_END) && !p.isAtEnd() && !p.match(types.RBRACE) {
 		if p.match(types.SHELL_TEXT) {
 			// Add shell text part
-			parts = append(parts, &ast.TextPart{Text: p.current().Value})
+			token := p.current()
+			parts = append(parts, &ast.TextPart{Text: token.Value, Pos: ast.Position{Line: token.Line, Column: token.Column}})
 			p.advance()
 		} else if p.match(types.AT) {
 			// Parse decorator in shell context - this can return ValueDecorator or ActionDecorator
@@ -723,14 +1251,17 @@ func (p *Parser) parseVarGroup() (*ast.VarGroup, error) {
 			continue
 		}
 
+		leading := p.leadingComments(p.current())
 		varDecl, err := p.parseGroupedVariableDecl()
 		if err != nil {
 			return nil, err // Be strict inside var groups
 		}
+		varDecl.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 		variables = append(variables, *varDecl)
 		p.skipWhitespaceAndComments()
 	}
 
+	p.flushComments()
 	closeParen, err := p.consume(types.RPAREN, "expected ')' to close var group")
 	if err != nil {
 		return nil, err
@@ -819,6 +1350,13 @@ func (p *Parser) parseShellDecorator() (ast.ShellPart, error) {
 		}
 	}
 
+	// @param references a parameter of the command it is in
+	if decoratorName == "param" && len(params) > 0 {
+		if ident, ok := params[0].Value.(*ast.Identifier); ok && p.findCommandParam(ident.Name) == nil {
+			return nil, p.formatError(fmt.Sprintf("@param references '%s', which the command doesn't declare as a parameter", ident.Name), startPos)
+		}
+	}
+
 	// In shell context, both ValueDecorator and ActionDecorator are allowed
 	switch decoratorType {
 	case decorators.ValueType:
@@ -842,6 +1380,16 @@ func (p *Parser) parseShellDecorator() (ast.ShellPart, error) {
 	}
 }
 
+// findCommandParam returns the parameter of the command being parsed with name, or nil
+func (p *Parser) findCommandParam(name string) *ast.CommandParam {
+	for i := range p.commandParams {
+		if p.commandParams[i].Name == name {
+			return &p.commandParams[i]
+		}
+	}
+	return nil
+}
+
 // parseDecorator parses a single decorator and returns the appropriate AST node type
 func (p *Parser) parseDecorator() (ast.CommandContent, error) {
 	startPos := p.current()
@@ -1136,14 +1684,17 @@ func (p *Parser) parsePatternBranchesInBlock() ([]ast.PatternBranch, error) {
 			break
 		}
 
+		leading := p.leadingComments(p.current())
 		branch, err := p.parsePatternBranch()
 		if err != nil {
 			return nil, err
 		}
+		branch.Comments = ast.Trivia{Leading: leading, Trailing: p.trailingComment()}
 		patterns = append(patterns, *branch)
 
 		p.skipWhitespaceAndComments()
 	}
+	p.flushComments()
 
 	return patterns, nil
 }
@@ -1157,9 +1708,25 @@ func (p *Parser) advance() types.Token {
 	return p.previous()
 }
 
-func (p *Parser) current() types.Token  { return p.tokens[p.pos] }
-func (p *Parser) previous() types.Token { return p.tokens[p.pos-1] }
-func (p *Parser) peek() types.Token     { return p.tokens[p.pos+1] }
+func (p *Parser) current() types.Token  { return p.tokenAt(p.pos) }
+func (p *Parser) previous() types.Token { return p.tokenAt(p.pos - 1) }
+func (p *Parser) peek() types.Token     { return p.tokenAt(p.pos + 1) }
+
+// tokenAt returns the token at index i, or an EOF token at the end of the input when i is
+// out of range, so lookahead past truncated input reads as the end instead of panicking
+func (p *Parser) tokenAt(i int) types.Token {
+	if i >= 0 && i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	if n := len(p.tokens); n > 0 {
+		last := p.tokens[n-1]
+		if last.Type == types.EOF {
+			return last
+		}
+		return types.Token{Type: types.EOF, Line: last.EndLine, Column: last.EndColumn}
+	}
+	return types.Token{Type: types.EOF, Line: 1, Column: 1}
+}
 
 func (p *Parser) isAtEnd() bool { return p.current().Type == types.EOF }
 
@@ -1209,7 +1776,11 @@ func (p *Parser) formatError(message string, token types.Token) error {
 		}
 	}
 
-	return fmt.Errorf("%s", errorMsg.String())
+	return FormattedError{
+		Message: message,
+		Token:   token,
+		text:    errorMsg.String(),
+	}
 }
 
 // max returns the larger of two integers
@@ -1237,10 +1808,11 @@ func (p *Parser) consume(t types.TokenType, message string) (types.Token, error)
 	return types.Token{}, p.formatError(message, p.current())
 }
 
+// skipWhitespaceAndComments collects comments for the declaration that follows them
 func (p *Parser) skipWhitespaceAndComments() {
 	// NEWLINE tokens no longer exist - they're handled as whitespace by lexer
 	for p.match(types.COMMENT, types.MULTILINE_COMMENT) {
-		p.advance()
+		p.comments = append(p.comments, newComment(p.advance()))
 	}
 }
 
@@ -1249,13 +1821,9 @@ func (p *Parser) isPatternDecorator() bool {
 	if p.current().Type != types.AT {
 		return false
 	}
-	if p.pos+1 < len(p.tokens) {
-		nextToken := p.tokens[p.pos+1]
-
-		if nextToken.Type == types.IDENTIFIER {
-			// Use the decorator registry to check for pattern decorators
-			return decorators.IsPatternDecorator(nextToken.Value)
-		}
+	if nextToken := p.peek(); nextToken.Type == types.IDENTIFIER {
+		// Use the decorator registry to check for pattern decorators
+		return decorators.IsPatternDecorator(nextToken.Value)
 	}
 	return false
 }
@@ -1265,25 +1833,16 @@ func (p *Parser) isBlockDecorator() bool {
 	if p.current().Type != types.AT {
 		return false
 	}
-	if p.pos+1 < len(p.tokens) {
-		nextToken := p.tokens[p.pos+1]
-		var name string
-
-		if nextToken.Type == types.IDENTIFIER {
-			name = nextToken.Value
-		} else {
-			return false
-		}
-
+	if nextToken := p.peek(); nextToken.Type == types.IDENTIFIER {
 		// Use the decorator registry to check for block decorators
-		return decorators.IsBlockDecorator(name)
+		return decorators.IsBlockDecorator(nextToken.Value)
 	}
 	return false
 }
 
 // addError records an error and allows parsing to continue.
 func (p *Parser) addError(err error) {
-	p.errors = append(p.errors, err.Error())
+	p.errors = append(p.errors, err)
 }
 
 // synchronize advances the parser until it finds a probable statement boundary,
@@ -1303,6 +1862,12 @@ func (p *Parser) synchronize() {
 
 // validateDecoratorParameters validates parameters against the decorator's schema
 func (p *Parser) validateDecoratorParameters(decorator decorators.Decorator, params []ast.NamedParameter, decoratorName string) error {
+	// Decorators like @set name the variables they assign with their parameters,
+	// so the parameters can't follow a schema
+	if _, ok := decorator.(decorators.VariableAssigner); ok {
+		return nil
+	}
+
 	schema := decorator.ParameterSchema()
 
 	// Check required parameters
@@ -1430,6 +1995,11 @@ func (p *Parser) validatePatternBranches(decorator decorators.PatternDecorator,
 			patternName = p.Name
 		case *ast.WildcardPattern:
 			patternName = "default"
+		case *ast.StringPattern:
+			if !schema.AllowsStringPatterns {
+				return fmt.Errorf("@%s decorator does not allow quoted pattern %s", decoratorName, p)
+			}
+			continue
 		default:
 			return fmt.Errorf("unknown pattern type for @%s decorator", decoratorName)
 		}
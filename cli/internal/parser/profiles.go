@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// checkEnvProfiles reports each env profile defined twice, each variable a profile sets twice,
+// and each variable a profile sets that has no global declaration to fall back to or whose
+// value is of another type than its default
+func (p *Parser) checkEnvProfiles(program *ast.Program) []error {
+	var errs []error
+
+	globals := make(map[string]ast.VariableDecl)
+	for _, variable := range programVariables(program) {
+		if _, ok := globals[variable.Name]; !ok {
+			globals[variable.Name] = variable
+		}
+	}
+
+	profiles := make(map[string]types.Token)
+	for _, profile := range program.EnvProfiles {
+		if first, ok := profiles[profile.Name]; ok {
+			errs = append(errs, p.formatError(duplicateMessage("env profile", profile.Name, first), profile.NameToken))
+			continue
+		}
+		profiles[profile.Name] = profile.NameToken
+
+		variables := make(map[string]types.Token)
+		for _, variable := range profile.Variables {
+			if first, ok := variables[variable.Name]; ok {
+				errs = append(errs, p.formatError(duplicateMessage("variable", variable.Name, first), variable.NameToken))
+				continue
+			}
+			variables[variable.Name] = variable.NameToken
+
+			global, ok := globals[variable.Name]
+			if !ok {
+				errs = append(errs, p.formatError(fmt.Sprintf("env profile '%s' sets undeclared variable '%s'; declare a default with var %s = ...",
+					profile.Name, variable.Name, variable.Name), variable.NameToken))
+				continue
+			}
+			if want, got := global.Value.GetType(), variable.Value.GetType(); want != got {
+				errs = append(errs, p.formatError(fmt.Sprintf("env profile '%s' sets variable '%s' to a %s, but its default is a %s",
+					profile.Name, variable.Name, got, want), variable.NameToken))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestEnvProfiles(t *testing.T) {
+	program := mustParse(t, `var API_URL = "http://localhost:8080"
+var (
+    REPLICAS = 1
+)
+
+# Live settings
+env production {
+    API_URL = "https://api.example.com" # public endpoint
+    REPLICAS = 3
+}
+
+env staging {
+    API_URL = "https://staging.example.com"
+}
+
+deploy: echo "@var(API_URL) x @var(REPLICAS)"
+env: echo a command named env`)
+
+	if len(program.Commands) != 2 || program.Commands[1].Name != "env" {
+		t.Fatalf("commands = %v, want deploy and env", program.Commands)
+	}
+	if len(program.EnvProfiles) != 2 {
+		t.Fatalf("env profiles = %v, want 2", program.EnvProfiles)
+	}
+
+	production := program.EnvProfile("production")
+	if production == nil || production.Pos.Line != 7 || len(production.Variables) != 2 {
+		t.Fatalf("production = %+v, want two variables at line 7", production)
+	}
+	if replicas := production.Variable("REPLICAS"); replicas == nil || replicas.Value.(*ast.NumberLiteral).Value != "3" {
+		t.Errorf("production REPLICAS = %+v, want 3", replicas)
+	}
+	if len(production.Comments.Leading) != 1 || production.Variables[0].Comments.Trailing == nil {
+		t.Errorf("comments = %+v, want the leading and trailing comments kept", production.Comments)
+	}
+	if staging := program.EnvProfile("staging"); staging == nil || staging.Variable("REPLICAS") != nil {
+		t.Errorf("staging = %+v, want only API_URL set", staging)
+	}
+	if got := program.EnvProfiles[1].String(); got != "env staging {\n  API_URL = https://staging.example.com\n}" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestEnvProfiles_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{"env production {\n    API_URL = \"x\"\n}", "env profile 'production' sets undeclared variable 'API_URL'; declare a default with var API_URL = ..."},
+		{"var PORT = 80\nenv production {\n    PORT = \"eighty\"\n}", "env profile 'production' sets variable 'PORT' to a string, but its default is a number"},
+		{"var PORT = 80\nenv production {\n    PORT = 1\n    PORT = 2\n}", "duplicate variable 'PORT'"},
+		{"var PORT = 80\nenv production {\n    PORT = 1\n}\nenv production {\n    PORT = 2\n}", "duplicate env profile 'production'"},
+		{"var PORT = 80\noverride env production {\n    PORT = 1\n}", "override only applies to variables and commands"},
+		{"var PORT = 80\nenv production {\n    PORT 1\n}", "expected '=' after variable name"},
+	} {
+		_, err := Parse(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
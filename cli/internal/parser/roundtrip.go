@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// RoundTrip checks that a program prints with ast.Format as source that parses back to the
+// same program, apart from positions. Tools that rewrite commands files, such as formatters,
+// rely on this for every valid program.
+func RoundTrip(program *ast.Program) error {
+	source := ast.Format(program)
+	reparsed, err := Parse(strings.NewReader(source))
+	if err != nil {
+		return fmt.Errorf("printed program does not parse: %w\n%s", err, source)
+	}
+	if diff := ast.Dump(program).Diff(ast.Dump(reparsed)); diff != "" {
+		return fmt.Errorf("printed program parses differently: %s\n%s", diff, source)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestRoundTrip_Examples(t *testing.T) {
+	for _, input := range []string{
+		"build: go build ./...",
+		"var PORT = 8080\nvar NAME = \"it's\"\nvar WAIT = 30s\nvar DEBUG = false\nserve: echo @var(NAME) @var(PORT)",
+		"var (\n  A = 1\n  B = 'say \"hi\"'\n)\nshow: echo @var(A) @var(B)",
+		"watch dev: npm run dev\nstop dev: pkill -f dev",
+		"build: go build\ndeploy(env: string = \"dev\", replicas: number = 3): needs(build) {\n  echo @param(env)\n  @cmd(build)\n}",
+		"test: @parallel(limit = 2, failFast = true) {\n  go test ./a\n  go test ./b\n}",
+		"var ENV = \"dev\"\nrelease: @when(ENV) {\n  prod: {\n    echo one\n    echo two\n  }\n  default: echo other\n}",
+		"deploy: @timeout(5m) {\n  @retry(attempts = 3) {\n    kubectl apply -f k8s\n  }\n}",
+		"deploy: kubectl apply\non failure of deploy: echo rollback\non change \"proto/**/*.proto\", \"api/*.yaml\" debounce 2s: echo regenerate",
+		"var URL = \"http://localhost\"\nvar (\n  REPLICAS = 1\n)\nenv production {\n  URL = \"https://example.com\"\n  REPLICAS = 3\n}\ndeploy: echo @var(URL) @var(REPLICAS)",
+		"config {\n  defaultTimeout = 10m\n}\nbuild: go build ./...",
+		"var ENV = \"dev\"\nnested: {\n  @when(ENV) {\n    prod: {\n      @when(ENV) {\n        prod: echo inner\n      }\n      echo after\n    }\n  }\n  @timeout(1s) {\n    echo next\n  }\n}",
+		"quoting: echo it\\'s \"a \\\"b\\\"\" 'c' `date` ${HOME:-/} $(pwd) | tr a b && echo \\}",
+	} {
+		program, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", input, err)
+			continue
+		}
+		if err := RoundTrip(program); err != nil {
+			t.Errorf("RoundTrip(%q): %v", input, err)
+		}
+	}
+}
+
+// randomSource is the source of a random valid program, generated by testing/quick
+type randomSource string
+
+// Generate writes variables, commands that use them and each other through decorators and
+// needs, and triggers, with shell text that mixes quoting, escapes and expansions
+func (randomSource) Generate(r *rand.Rand, size int) reflect.Value {
+	g := &programGenerator{r: r}
+	return reflect.ValueOf(randomSource(g.program(1 + size%6)))
+}
+
+type programGenerator struct {
+	r        *rand.Rand
+	vars     []string
+	strings  []string // The string variables, which @when can match
+	commands []string
+	params   []string // Parameters of the command being generated
+	out      strings.Builder
+}
+
+func (g *programGenerator) pick(options ...string) string {
+	return options[g.r.Intn(len(options))]
+}
+
+// variable declares a variable with a random literal value, returning its declaration
+func (g *programGenerator) variable() string {
+	name := fmt.Sprintf("VAR_%d", len(g.vars))
+	g.vars = append(g.vars, name)
+	value := g.pick("8080", "1.5", `"dev server"`, `'single "quoted"'`, "30s", "true", "false", `"it\"s"`)
+	if strings.ContainsAny(value[:1], `"'`) {
+		g.strings = append(g.strings, name)
+	}
+	return name + " = " + value
+}
+
+func (g *programGenerator) program(commands int) string {
+	for i := 0; i < g.r.Intn(3); i++ {
+		fmt.Fprintf(&g.out, "var %s\n", g.variable())
+	}
+	if g.r.Intn(3) == 0 {
+		g.out.WriteString("var (\n")
+		for i := 0; i < 1+g.r.Intn(2); i++ {
+			fmt.Fprintf(&g.out, "    %s\n", g.variable())
+		}
+		g.out.WriteString(")\n")
+	}
+	for i := 0; i < commands; i++ {
+		g.command(fmt.Sprintf("cmd-%d", i))
+	}
+	if len(g.commands) > 0 && g.r.Intn(2) == 0 {
+		g.params = nil // Triggers take no parameters
+		fmt.Fprintf(&g.out, "on %s of %s: %s\n", g.pick("success", "failure"), g.pick(g.commands...), g.shell())
+	}
+	return g.out.String()
+}
+
+func (g *programGenerator) command(name string) {
+	header := name
+	g.params = nil
+	if g.r.Intn(3) == 0 {
+		g.params = []string{"target"}
+		header += `(target: string = "all")`
+	}
+	header += ":"
+	if len(g.commands) > 0 && g.r.Intn(3) == 0 {
+		header += " needs(" + g.pick(g.commands...) + ")"
+	}
+	switch g.r.Intn(3) {
+	case 0:
+		fmt.Fprintf(&g.out, "%s %s\n", header, g.shell())
+	case 1:
+		g.out.WriteString(header + " ")
+		g.block(0)
+	default:
+		g.out.WriteString(header + " {\n")
+		g.contents(1)
+		g.out.WriteString("}\n")
+	}
+	g.commands = append(g.commands, name)
+}
+
+func (g *programGenerator) contents(depth int) {
+	for i := 0; i < 1+g.r.Intn(3); i++ {
+		g.out.WriteString(strings.Repeat("    ", depth))
+		switch n := g.r.Intn(6); {
+		case n == 0 && depth < 3:
+			g.block(depth)
+		case n == 1 && depth < 4 && len(g.strings) > 0:
+			g.when(depth)
+		case n == 2 && len(g.commands) > 0:
+			g.out.WriteString("@cmd(" + g.pick(g.commands...) + ")\n")
+		default:
+			g.out.WriteString(g.shell() + "\n")
+		}
+	}
+}
+
+func (g *programGenerator) block(depth int) {
+	g.out.WriteString(g.pick("@timeout(30s)", "@parallel", "@parallel(limit = 2, failFast = true)", "@retry(attempts = 3)", `@workdir("sub dir")`) + " {\n")
+	g.contents(depth + 1)
+	g.out.WriteString(strings.Repeat("    ", depth) + "}\n")
+}
+
+func (g *programGenerator) when(depth int) {
+	indent := strings.Repeat("    ", depth)
+	g.out.WriteString("@when(" + g.pick(g.strings...) + ") {\n")
+	for _, pattern := range []string{"prod", "staging", "default"}[:1+g.r.Intn(3)] {
+		if g.r.Intn(2) == 0 {
+			fmt.Fprintf(&g.out, "%s    %s: %s\n", indent, pattern, g.shell())
+			continue
+		}
+		fmt.Fprintf(&g.out, "%s    %s: {\n", indent, pattern)
+		g.contents(depth + 2)
+		g.out.WriteString(indent + "    }\n")
+	}
+	g.out.WriteString(indent + "}\n")
+}
+
+func (g *programGenerator) shell() string {
+	words := []string{g.pick("echo", "go", "npm", "kubectl")}
+	for i := 0; i < 1+g.r.Intn(5); i++ {
+		word := g.pick("build", "./...", "-v", "--flag=value", "it\\'s", `"double quoted"`, `'single quoted'`,
+			`"escaped \"quote\""`, "$HOME", "${HOME:-/tmp}", "$(date +%s)", "`pwd`", "|", "&&", ";", "2>&1", "\\}")
+		if len(g.vars) > 0 && g.r.Intn(5) == 0 {
+			word = "@var(" + g.pick(g.vars...) + ")"
+		}
+		if len(g.params) > 0 && g.r.Intn(5) == 0 {
+			word = `"@param(target)"`
+		}
+		words = append(words, word)
+	}
+	// A trailing operator would continue the command on the next line
+	if last := words[len(words)-1]; last == "|" || last == "&&" {
+		words = append(words, "true")
+	}
+	return strings.Join(words, " ")
+}
+
+func TestRoundTrip_RandomPrograms(t *testing.T) {
+	roundTrips := func(source randomSource) bool {
+		program, err := Parse(strings.NewReader(string(source)))
+		if err != nil {
+			t.Errorf("generated program does not parse: %v\n%s", err, source)
+			return false
+		}
+		if err := RoundTrip(program); err != nil {
+			t.Errorf("%v\nfrom:\n%s", err, source)
+			return false
+		}
+		return true
+	}
+
+	// A fixed seed keeps failures reproducible; the programs still cover every generator path
+	config := &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(roundTrips, config); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func TestTriggers(t *testing.T) {
+	program := mustParse(t, `deploy: ./deploy.sh
+rollback: ./rollback.sh
+on failure of deploy: @cmd(rollback)
+on success of deploy: {
+    echo deployed
+    @cmd(rollback)
+}
+on: echo a command named on`)
+
+	if len(program.Commands) != 3 || program.Commands[2].Name != "on" {
+		t.Fatalf("commands = %v, want deploy, rollback and on", program.Commands)
+	}
+	if len(program.Triggers) != 2 {
+		t.Fatalf("triggers = %v, want 2", program.Triggers)
+	}
+
+	failure, success := program.Triggers[0], program.Triggers[1]
+	if failure.Event != ast.TriggerOnFailure || failure.Command != "deploy" || failure.Pos.Line != 3 {
+		t.Errorf("first trigger = %+v, want on failure of deploy at line 3", failure)
+	}
+	if got := failure.String(); !strings.HasPrefix(got, "on failure of deploy: ") || !strings.Contains(got, "@cmd(rollback)") {
+		t.Errorf("String() = %q", got)
+	}
+	if success.Event != ast.TriggerOnSuccess || len(success.Body.Content) != 2 {
+		t.Errorf("second trigger = %+v, want on success of deploy with two steps", success)
+	}
+}
+
+func TestTriggers_Change(t *testing.T) {
+	program := mustParse(t, `codegen: buf generate
+on change "proto/**/*.proto": @cmd(codegen)
+on change "api/*.yaml", "schema.json" debounce 2s: {
+    echo regenerating
+}`)
+
+	if len(program.Triggers) != 2 {
+		t.Fatalf("triggers = %v, want 2", program.Triggers)
+	}
+	single, several := program.Triggers[0], program.Triggers[1]
+	if single.Event != ast.TriggerOnChange || single.Command != "" || strings.Join(single.Paths, ",") != "proto/**/*.proto" || single.Debounce != 0 {
+		t.Errorf("first trigger = %+v, want a change of proto/**/*.proto", single)
+	}
+	if got := several.Name(); got != `on change "api/*.yaml", "schema.json"` {
+		t.Errorf("Name() = %q", got)
+	}
+	if several.Debounce != 2*time.Second {
+		t.Errorf("debounce = %s, want 2s", several.Debounce)
+	}
+	if got := several.String(); !strings.HasPrefix(got, `on change "api/*.yaml", "schema.json" debounce 2s: `) {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestTriggers_Errors(t *testing.T) {
+	for _, input := range []string{
+		"on failure of: echo missing command",
+		"on success of deploy echo missing colon",
+		`on change "src/**" debounce: echo missing duration`,
+		`on change "": echo empty pattern`,
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/lexer"
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// truncationCorpus uses every kind of declaration, so cutting it anywhere stops the parser
+// in the middle of each construct
+const truncationCorpus = `# Project commands
+var PORT = 8080
+var (
+  ENV = "dev"
+  VERBOSE = true
+)
+deploy(env: string, replicas: number = 3): @timeout(30s) {
+  @when(ENV) {
+    prod: kubectl scale --replicas=@param(replicas) deploy/@param(env)
+    default: echo "skipping @param(env)"
+  }
+}
+build: go build -o bin/app ./... && echo "built on @var(PORT)"
+ci: @parallel {
+  @cmd(build)
+  echo 'lint'
+}
+release: @retry(attempts = 3) { @cmd(ci); echo done }
+on failure of build: echo "build failed"
+on change "src/**/*.go": @cmd(build)
+watch api: go run ./cmd/api --port @var(PORT)
+stop api: pkill -f cmd/api
+`
+
+// TestParse_TruncatedInput checks that every prefix of a valid program, as an editor sees it
+// while it is typed, parses to a program or to diagnostics without panicking
+func TestParse_TruncatedInput(t *testing.T) {
+	if _, err := Parse(strings.NewReader(truncationCorpus)); err != nil {
+		t.Fatalf("corpus doesn't parse: %v", err)
+	}
+
+	for i := 0; i <= len(truncationCorpus); i++ {
+		input := truncationCorpus[:i]
+		program, err := Parse(strings.NewReader(input))
+		if (program == nil) == (err == nil) {
+			t.Fatalf("Parse(%q) = %v, %v; want a program or an error", input, program, err)
+		}
+		program, diagnostics, err := ParseDiagnostics(strings.NewReader(input))
+		if err != nil || (program == nil) == (len(diagnostics) == 0) {
+			t.Fatalf("ParseDiagnostics(%q) = %v, %v, %v; want a program or diagnostics", input, program, diagnostics, err)
+		}
+		for _, diagnostic := range diagnostics {
+			if strings.Contains(diagnostic.Message, "internal parser error") {
+				t.Errorf("ParseDiagnostics(%q) recovered from a panic: %s", input, diagnostic.Message)
+			}
+		}
+	}
+}
+
+// TestParse_TruncatedTokens checks that the parser reads past the end of a token stream
+// missing its EOF token as the end of the input
+func TestParse_TruncatedTokens(t *testing.T) {
+	tokens := lexer.New(strings.NewReader(truncationCorpus)).TokenizeToSlice()
+	for i := 0; i < len(tokens); i++ {
+		p := &Parser{input: truncationCorpus, tokens: tokens[:i]}
+		p.parseProgram()
+	}
+
+	p := &Parser{}
+	for _, token := range []types.Token{p.current(), p.previous(), p.peek()} {
+		if token.Type != types.EOF {
+			t.Errorf("token of an empty stream = %s, want EOF", token.Type)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package processes
+
+import "syscall"
+
+// detachAttr starts a process in a new session, so it survives the terminal closing
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package processes
+
+import "syscall"
+
+// detachAttr starts a process without the console, so it survives the terminal closing
+func detachAttr() *syscall.SysProcAttr {
+	const detachedProcess = 0x00000008
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -0,0 +1,116 @@
+package processes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetachedEnvVar is set to "<namespace>/<name>" for a command started with StartDetached, so
+// it records its exit status with RecordExit when it finishes
+const DetachedEnvVar = "DEVCMD_DETACHED"
+
+// waitInterval is how often Wait checks whether a process has exited
+const waitInterval = 200 * time.Millisecond
+
+// StartDetached starts command in the background as the named process of a namespace, in a
+// new session so it outlives the terminal, writing its output to the process's log file. The
+// files of an earlier run of the process are replaced.
+func (r Registry) StartDetached(namespace, name string, command []string) (Process, error) {
+	dir, store := r.Dir(namespace), r.Store(namespace)
+	if err := store.Remove(name+".exit", name+".ports", name+".service"); err != nil {
+		return Process{}, err
+	}
+	logFile := filepath.Join(dir, name+".log")
+	log, err := os.Create(logFile)
+	if err != nil {
+		return Process{}, fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.Env = append(os.Environ(), DetachedEnvVar+"="+namespace+"/"+name)
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return Process{}, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	if err := store.Write(name+".pid", []byte(strconv.Itoa(cmd.Process.Pid))); err != nil {
+		_ = cmd.Process.Kill()
+		return Process{}, err
+	}
+	_ = store.Record(Event{Name: name, Event: EventStarted, PID: cmd.Process.Pid})
+	// Reap the process when it exits while this one still runs, so it isn't left a zombie
+	go func() { _ = cmd.Wait() }()
+
+	return Process{
+		Namespace: namespace,
+		Project:   r.Project(namespace),
+		Name:      name,
+		PID:       cmd.Process.Pid,
+		Running:   true,
+		LogFile:   logFile,
+	}, nil
+}
+
+// RecordExit records the exit status of a detached process, with the error it failed with,
+// and its exit in the history
+func (r Registry) RecordExit(namespace, name string, code int, message string) error {
+	content := strconv.Itoa(code) + "\n"
+	if message != "" {
+		content += message + "\n"
+	}
+	store := r.Store(namespace)
+	if err := store.Write(name+".exit", []byte(content)); err != nil {
+		return err
+	}
+	return store.Record(Event{Name: name, Event: EventExited, PID: os.Getpid(), ExitCode: code, Error: message})
+}
+
+// ExitCode returns the exit code to record for a command that failed with err: that of the
+// shell step it failed in, or 1
+func ExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// readExit reads the exit status a detached process recorded, if it has
+func readExit(store Store, name string) (code int, message string, ok bool) {
+	content, err := store.Read(name + ".exit")
+	if err != nil {
+		return 0, "", false
+	}
+	first, rest, _ := strings.Cut(string(content), "\n")
+	code, err = strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, "", false
+	}
+	return code, strings.TrimSpace(rest), true
+}
+
+// Wait waits until the process has exited, or ctx is done, and returns it as the registry
+// then records it, with the exit status it recorded if it was detached
+func (r Registry) Wait(ctx context.Context, process Process) (Process, error) {
+	for Alive(process.PID) {
+		select {
+		case <-ctx.Done():
+			return process, ctx.Err()
+		case <-time.After(waitInterval):
+		}
+	}
+	if exited, ok := r.Lookup(process.Namespace, process.Name); ok {
+		return exited, nil
+	}
+	process.Running = false
+	return process, nil
+}
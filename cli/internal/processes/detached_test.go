@@ -0,0 +1,82 @@
+package processes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_StartDetachedAndWait(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	registry := Registry{Root: t.TempDir()}
+	namespace := Namespace(t.TempDir())
+
+	process, err := registry.StartDetached(namespace, "build", []string{"sh", "-c", `sleep 0.2; echo "$` + DetachedEnvVar + `"`})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	if listed, ok := registry.Lookup(namespace, "build"); !ok || !listed.Running || listed.PID != process.PID {
+		t.Fatalf("Lookup() = %+v, %v; want the running build", listed, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	finished, err := registry.Wait(ctx, process)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if finished.Running || finished.Exited {
+		t.Errorf("Wait() = %+v; want stopped without an exit status, as none was recorded", finished)
+	}
+	log, err := os.ReadFile(process.LogFile)
+	if err != nil || strings.TrimSpace(string(log)) != namespace+"/build" {
+		t.Errorf("log = %q, %v; want the %s value", log, err, DetachedEnvVar)
+	}
+
+	if err := registry.RecordExit(namespace, "build", 2, "exit status 2"); err != nil {
+		t.Fatalf("RecordExit failed: %v", err)
+	}
+	finished, _ = registry.Lookup(namespace, "build")
+	if !finished.Exited || finished.ExitCode != 2 || finished.Error != "exit status 2" {
+		t.Errorf("Lookup() = %+v; want exit 2 with its error", finished)
+	}
+	history, err := registry.History(namespace)
+	if err != nil || len(history) != 2 || history[0].Event != EventStarted || history[1].Event != EventExited || history[1].ExitCode != 2 {
+		t.Errorf("History() = %+v, %v; want the start and exit", history, err)
+	}
+
+	// Starting again replaces the earlier run's exit status
+	process, err = registry.StartDetached(namespace, "build", []string{"sleep", "5"})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	defer func() { _ = registry.Stop(process) }()
+	if listed, _ := registry.Lookup(namespace, "build"); listed.Exited {
+		t.Errorf("Lookup() = %+v; want the new run without the old exit status", listed)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := registry.Wait(ctx, process); err == nil {
+		t.Errorf("Wait should give up when its context is done")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	if code := ExitCode(fmt.Errorf("step failed: %w", err)); code != 3 {
+		t.Errorf("ExitCode() = %d, want the step's 3", code)
+	}
+	if code := ExitCode(fmt.Errorf("no shell step")); code != 1 {
+		t.Errorf("ExitCode() = %d, want 1 without an exit status", code)
+	}
+}
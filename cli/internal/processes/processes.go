@@ -0,0 +1,456 @@
+package processes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aledsdavies/devcmd/runtime/config"
+)
+
+// ProjectFile is the file in a namespace that records the project directory it belongs to
+const ProjectFile = "project"
+
+// Registry is the user-level directory where generated CLIs record the background processes
+// their watch commands start, and devcmd the commands it runs detached: a PID, log and ports
+// file per process, in a namespace per project whose Store keeps them and a history of the
+// processes. Generated CLIs contain the same layout, so devcmd ps can list and stop processes
+// started by any of them.
+type Registry struct {
+	Root string
+}
+
+// Process is a background process recorded in the registry
+type Process struct {
+	Namespace string
+	Project   string // Directory of the project that started the process
+	Name      string // Watch command the process runs
+	PID       int
+	Running   bool
+	LogFile   string
+	Ports     []string // NAME=port allocations made with @freeport
+	Service   Service
+
+	// Detached commands record how they exited
+	Exited   bool
+	ExitCode int
+	Error    string // Error the command failed with
+}
+
+// DefaultStopTimeout is how long a stopping process has to exit after SIGTERM before it is
+// killed, unless its service says otherwise
+const DefaultStopTimeout = 5 * time.Second
+
+// Service describes how a background process stops alongside the others of its project. The
+// process that starts it records it in a <name>.service file beside its PID file.
+type Service struct {
+	DependsOn   []string      // Processes this one uses, which stop after it
+	StopTimeout time.Duration // Grace period between SIGTERM and SIGKILL; DefaultStopTimeout if zero
+}
+
+// String formats a service as its file records it
+func (s Service) String() string {
+	var b strings.Builder
+	if len(s.DependsOn) > 0 {
+		fmt.Fprintf(&b, "dependsOn=%s\n", strings.Join(s.DependsOn, ","))
+	}
+	if s.StopTimeout > 0 {
+		fmt.Fprintf(&b, "stopTimeout=%s\n", s.StopTimeout)
+	}
+	return b.String()
+}
+
+// ParseService reads a service file's contents. Unknown keys are ignored, so files written
+// by newer CLIs still parse.
+func ParseService(content string) (Service, error) {
+	var service Service
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "dependsOn":
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					service.DependsOn = append(service.DependsOn, name)
+				}
+			}
+		case "stopTimeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return Service{}, fmt.Errorf("invalid stopTimeout %q", value)
+			}
+			service.StopTimeout = timeout
+		}
+	}
+	return service, nil
+}
+
+// StopTimeout returns how long the process has to exit after SIGTERM
+func (p Process) StopTimeout() time.Duration {
+	if p.Service.StopTimeout > 0 {
+		return p.Service.StopTimeout
+	}
+	return DefaultStopTimeout
+}
+
+// StopOrder orders names so that every process stops before the processes it depends on,
+// and otherwise by name. Dependencies outside names are ignored. A dependency cycle is an
+// error; the order returned with it still contains every name.
+func StopOrder(names []string, dependsOn func(name string) []string) ([]string, error) {
+	included := make(map[string]bool, len(names))
+	for _, name := range names {
+		included[name] = true
+	}
+
+	// A process can stop once every process that depends on it has
+	dependents := make(map[string]int, len(names))
+	for name := range included {
+		for _, dependency := range dependsOn(name) {
+			if included[dependency] && dependency != name {
+				dependents[dependency]++
+			}
+		}
+	}
+	var ready []string
+	for name := range included {
+		if dependents[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	var order []string
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependency := range dependsOn(name) {
+			if included[dependency] && dependency != name {
+				if dependents[dependency]--; dependents[dependency] == 0 {
+					ready = append(ready, dependency)
+				}
+			}
+		}
+	}
+	if len(order) == len(included) {
+		return order, nil
+	}
+
+	var cycle []string
+	for name := range included {
+		if dependents[name] > 0 {
+			cycle = append(cycle, name)
+		}
+	}
+	sort.Strings(cycle)
+	return append(order, cycle...), fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+}
+
+// OrderForStop orders processes for stopping: per namespace, every process before the
+// processes its service depends on. Namespaces keep their order. A dependency cycle, which
+// devcmd build rejects, leaves the cycle in name order rather than failing to stop.
+func OrderForStop(list []Process) []Process {
+	var ordered []Process
+	for start := 0; start < len(list); {
+		end := start
+		byName := make(map[string]Process)
+		var names []string
+		for ; end < len(list) && list[end].Namespace == list[start].Namespace; end++ {
+			byName[list[end].Name] = list[end]
+			names = append(names, list[end].Name)
+		}
+		order, _ := StopOrder(names, func(name string) []string { return byName[name].Service.DependsOn })
+		for _, name := range order {
+			ordered = append(ordered, byName[name])
+		}
+		start = end
+	}
+	return ordered
+}
+
+// Health is how a recorded process is doing, as far as a liveness probe can tell
+type Health int
+
+const (
+	// Dead processes have exited, or are zombies waiting for their parent to reap them
+	Dead Health = iota
+	// Unresponsive processes are running, but none of the ports they allocated accept connections
+	Unresponsive
+	// Healthy processes are running and, if they allocated ports, accept connections on one
+	Healthy
+)
+
+// String returns the health as ps shows it
+func (h Health) String() string {
+	switch h {
+	case Healthy:
+		return "healthy"
+	case Unresponsive:
+		return "unresponsive"
+	default:
+		return "dead"
+	}
+}
+
+// Health probes whether the process is alive and, when it allocated ports with @freeport,
+// whether it accepts connections on any of them
+func (p Process) Health() Health {
+	if !p.Running {
+		return Dead
+	}
+	if len(p.Ports) == 0 {
+		return Healthy
+	}
+	for _, allocation := range p.Ports {
+		_, port, _ := strings.Cut(allocation, "=")
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", port), 200*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return Healthy
+		}
+	}
+	return Unresponsive
+}
+
+// RootEnvVar overrides the registry directory, e.g. to keep test or CI processes apart
+const RootEnvVar = "DEVCMD_REGISTRY"
+
+// DefaultRoot returns the registry directory: $DEVCMD_REGISTRY when set, otherwise
+// processes in the state directory of the config, devcmd/processes in the user cache
+// directory, or in the temporary directory when there is none
+func DefaultRoot() string {
+	if root := os.Getenv(RootEnvVar); root != "" {
+		return root
+	}
+	if state := config.StateDirFromEnv(); state != "" {
+		return filepath.Join(state, "processes")
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cache, "devcmd", "processes")
+	}
+	return filepath.Join(os.TempDir(), "devcmd", "processes")
+}
+
+// Default returns the registry at DefaultRoot
+func Default() Registry {
+	return Registry{Root: DefaultRoot()}
+}
+
+// Namespace returns the namespace of the project in dir: its base name, which keeps
+// listings readable, and a hash of its absolute path, which keeps projects with the same
+// name apart
+func Namespace(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	sum := sha256.Sum256([]byte(abs))
+
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, filepath.Base(abs))
+	if strings.Trim(name, ".-") == "" {
+		name = "project"
+	}
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Dir returns the directory holding a namespace's process files
+func (r Registry) Dir(namespace string) string {
+	return filepath.Join(r.Root, namespace)
+}
+
+// Register creates a namespace for the project in dir and records the project directory
+func (r Registry) Register(namespace, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return r.Store(namespace).Write(ProjectFile, []byte(abs+"\n"))
+}
+
+// Namespaces returns the namespaces in the registry, sorted
+func (r Registry) Namespaces() ([]string, error) {
+	entries, err := os.ReadDir(r.Root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			namespaces = append(namespaces, entry.Name())
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Project returns the project directory recorded for a namespace, or "" if there is none
+func (r Registry) Project(namespace string) string {
+	project, err := r.Store(namespace).Read(ProjectFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(project))
+}
+
+// Matches reports whether a namespace belongs to project, given as the namespace itself,
+// the project directory, or the directory's base name
+func (r Registry) Matches(namespace, project string) bool {
+	if namespace == project {
+		return true
+	}
+	dir := r.Project(namespace)
+	if dir == "" {
+		return false
+	}
+	if abs, err := filepath.Abs(project); err == nil && abs == dir {
+		return true
+	}
+	return filepath.Base(dir) == project
+}
+
+// Lookup returns the process recorded under name in a namespace, if there is one
+func (r Registry) Lookup(namespace, name string) (Process, bool) {
+	list, err := r.List(namespace)
+	if err != nil {
+		return Process{}, false
+	}
+	for _, process := range list {
+		if process.Name == name {
+			return process, true
+		}
+	}
+	return Process{}, false
+}
+
+// List returns the processes recorded in the given namespaces, sorted by namespace and name.
+// Namespaces that don't exist have no processes.
+func (r Registry) List(namespaces ...string) ([]Process, error) {
+	var processes []Process
+	for _, namespace := range namespaces {
+		dir, store := r.Dir(namespace), r.Store(namespace)
+		pidFiles, err := store.Files("*.pid")
+		if err != nil {
+			return nil, err
+		}
+		for _, pidFile := range pidFiles {
+			pidBytes, err := store.Read(pidFile)
+			if err != nil {
+				continue
+			}
+			name := strings.TrimSuffix(pidFile, ".pid")
+			process := Process{
+				Namespace: namespace,
+				Project:   r.Project(namespace),
+				Name:      name,
+				LogFile:   filepath.Join(dir, name+".log"),
+			}
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes))); err == nil {
+				process.PID = pid
+				process.Running = Alive(pid)
+			}
+			if ports, err := store.Read(name + ".ports"); err == nil {
+				process.Ports = strings.Fields(string(ports))
+			}
+			if service, err := store.Read(name + ".service"); err == nil {
+				process.Service, _ = ParseService(string(service))
+			}
+			if code, message, ok := readExit(store, name); ok && !process.Running {
+				process.Exited, process.ExitCode, process.Error = true, code, message
+			}
+			processes = append(processes, process)
+		}
+	}
+	return processes, nil
+}
+
+// Stop terminates a process, as the stop subcommand of its generated CLI does without a
+// custom stop command, and removes its PID and ports files. The process gets its stop
+// timeout to exit after SIGTERM before it is killed. The PID file goes first, so the devcmd
+// daemon doesn't restart a process it supervises. The stop is recorded in the history.
+func (r Registry) Stop(process Process) error {
+	store := r.Store(process.Namespace)
+	pidFile := process.Name + ".pid"
+	if err := store.Remove(pidFile); err != nil {
+		return err
+	}
+	if process.Running {
+		if err := terminate(process.PID, process.StopTimeout()); err != nil {
+			_ = store.Write(pidFile, []byte(strconv.Itoa(process.PID)))
+			return err
+		}
+		_ = store.Record(Event{Name: process.Name, Event: EventStopped, PID: process.PID})
+	}
+	return store.Remove(process.Name+".ports", process.Name+".exit")
+}
+
+// History returns the history of a namespace's processes, oldest first
+func (r Registry) History(namespace string) ([]Event, error) {
+	return r.Store(namespace).History()
+}
+
+// terminate sends a process SIGTERM and waits up to timeout for it to exit, then kills it
+func terminate(pid int, timeout time.Duration) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", pid, err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err == nil {
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if !Alive(pid) {
+				return nil
+			}
+		}
+	}
+	if err := p.Signal(syscall.SIGKILL); err != nil && Alive(pid) {
+		return fmt.Errorf("failed to kill process %d: %w", pid, err)
+	}
+	return nil
+}
+
+// Alive reports whether a process with the PID exists and isn't a zombie
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if process.Signal(syscall.Signal(0)) != nil {
+		return false
+	}
+	return !isZombie(pid)
+}
+
+// isZombie reports whether a process has exited without being reaped, which signals still
+// reach. Only Linux exposes the process state, in /proc.
+func isZombie(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses and may contain spaces
+	if end := strings.LastIndexByte(string(stat), ')'); end >= 0 {
+		fields := strings.Fields(string(stat[end+1:]))
+		return len(fields) > 0 && fields[0] == "Z"
+	}
+	return false
+}
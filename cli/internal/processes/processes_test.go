@@ -0,0 +1,220 @@
+package processes
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a", "my app")
+	b := filepath.Join(root, "b", "my app")
+
+	if got := Namespace(a); !strings.HasPrefix(got, "my-app-") {
+		t.Errorf("Namespace(%q) = %q, want the directory name first", a, got)
+	}
+	if Namespace(a) != Namespace(a) {
+		t.Errorf("Namespace should be stable")
+	}
+	if Namespace(a) == Namespace(b) {
+		t.Errorf("projects with the same directory name should get different namespaces")
+	}
+	if got := Namespace(string(filepath.Separator)); !strings.HasPrefix(got, "project-") {
+		t.Errorf("Namespace of the root directory = %q, want a placeholder name", got)
+	}
+}
+
+func TestRegistry_ListAndMatch(t *testing.T) {
+	registry := Registry{Root: t.TempDir()}
+	project := t.TempDir()
+	namespace := Namespace(project)
+	if err := registry.Register(namespace, project); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	other := Namespace(t.TempDir())
+	if err := registry.Register(other, t.TempDir()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	dir := registry.Dir(namespace)
+	writeFile(t, filepath.Join(dir, "api.pid"), strconv.Itoa(os.Getpid()))
+	writeFile(t, filepath.Join(dir, "api.ports"), "API_PORT=4000\nDB_PORT=5432\n")
+	writeFile(t, filepath.Join(dir, "web.pid"), "999999999")
+
+	namespaces, err := registry.Namespaces()
+	if err != nil || len(namespaces) != 2 {
+		t.Fatalf("Namespaces() = %v, %v; want both projects", namespaces, err)
+	}
+
+	list, err := registry.List(namespace, other)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("List returned %d processes, want 2: %+v", len(list), list)
+	}
+	api, web := list[0], list[1]
+	if api.Name != "api" || !api.Running || api.PID != os.Getpid() || api.Project != project {
+		t.Errorf("unexpected api process: %+v", api)
+	}
+	if strings.Join(api.Ports, " ") != "API_PORT=4000 DB_PORT=5432" {
+		t.Errorf("api ports = %v", api.Ports)
+	}
+	if api.LogFile != filepath.Join(dir, "api.log") {
+		t.Errorf("api log = %q", api.LogFile)
+	}
+	if web.Name != "web" || web.Running {
+		t.Errorf("web should be listed as stopped: %+v", web)
+	}
+
+	for _, match := range []string{namespace, project, filepath.Base(project)} {
+		if !registry.Matches(namespace, match) {
+			t.Errorf("Matches(%q) = false, want true", match)
+		}
+	}
+	if registry.Matches(other, project) {
+		t.Errorf("another project's namespace should not match %q", project)
+	}
+}
+
+func TestRegistry_Stop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available")
+	}
+	registry := Registry{Root: t.TempDir()}
+	namespace := Namespace(t.TempDir())
+	dir := registry.Dir(namespace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sleep := exec.Command("sleep", "30")
+	if err := sleep.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- sleep.Wait() }()
+	writeFile(t, filepath.Join(dir, "worker.pid"), strconv.Itoa(sleep.Process.Pid))
+	writeFile(t, filepath.Join(dir, "worker.ports"), "PORT=4000\n")
+
+	list, err := registry.List(namespace)
+	if err != nil || len(list) != 1 || !list[0].Running {
+		t.Fatalf("List() = %+v, %v; want the running worker", list, err)
+	}
+	if err := registry.Stop(list[0]); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := <-exited; err == nil {
+		t.Errorf("process should have been terminated")
+	}
+	for _, file := range []string{"worker.pid", "worker.ports"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", file)
+		}
+	}
+	if history, err := registry.History(namespace); err != nil || len(history) != 1 || history[0].Event != EventStopped {
+		t.Errorf("History() = %+v, %v; want the stop", history, err)
+	}
+}
+
+func TestProcess_Health(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, open, _ := net.SplitHostPort(listener.Addr().String())
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		process Process
+		want    Health
+	}{
+		{"exited", Process{PID: 999999999}, Dead},
+		{"no ports", Process{PID: os.Getpid(), Running: true}, Healthy},
+		{"listening", Process{PID: os.Getpid(), Running: true, Ports: []string{"DB_PORT=" + closedPort, "API_PORT=" + open}}, Healthy},
+		{"not listening", Process{PID: os.Getpid(), Running: true, Ports: []string{"API_PORT=" + closedPort}}, Unresponsive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.process.Health(); got != tt.want {
+				t.Errorf("Health() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopOrder(t *testing.T) {
+	dependencies := map[string][]string{
+		"web":    {"api"},
+		"api":    {"db", "cache"},
+		"worker": {"db", "queue"}, // queue isn't running
+	}
+	dependsOn := func(name string) []string { return dependencies[name] }
+
+	order, err := StopOrder([]string{"db", "web", "worker", "cache", "api"}, dependsOn)
+	if err != nil {
+		t.Fatalf("StopOrder failed: %v", err)
+	}
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+	for name, deps := range dependencies {
+		for _, dep := range deps {
+			if _, ok := position[dep]; ok && position[name] > position[dep] {
+				t.Errorf("%s stops after %s, which it depends on: %v", name, dep, order)
+			}
+		}
+	}
+	if len(order) != 5 {
+		t.Errorf("order = %v, want every name once", order)
+	}
+
+	dependencies["db"] = []string{"web"}
+	order, err = StopOrder([]string{"db", "web", "api"}, dependsOn)
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("cycle returned %v, want an error", err)
+	}
+	if len(order) != 3 {
+		t.Errorf("order with a cycle = %v, want every name once", order)
+	}
+}
+
+func TestService_RoundTrip(t *testing.T) {
+	service := Service{DependsOn: []string{"db", "cache"}, StopTimeout: 10 * time.Second}
+	parsed, err := ParseService(service.String() + "future=setting\n")
+	if err != nil {
+		t.Fatalf("ParseService failed: %v", err)
+	}
+	if strings.Join(parsed.DependsOn, ",") != "db,cache" || parsed.StopTimeout != 10*time.Second {
+		t.Errorf("ParseService(%q) = %+v", service.String(), parsed)
+	}
+	if (Process{}).StopTimeout() != DefaultStopTimeout {
+		t.Errorf("processes without a service should get the default stop timeout")
+	}
+	if _, err := ParseService("stopTimeout=soon"); err == nil {
+		t.Errorf("an invalid stop timeout should be an error")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,232 @@
+package processes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Files of a namespace that aren't a process's
+const (
+	// LockFile is held while a namespace's files are changed. Generated CLIs, devcmd and the
+	// daemon all take it, so concurrent writers don't interleave.
+	LockFile = ".lock"
+	// HistoryFile is the namespace's history, a JSON event per line, appended to as
+	// processes start, exit and are stopped
+	HistoryFile = "history.jsonl"
+)
+
+// Timings of the namespace lock. A lock is only held for the few file operations of an
+// update, so one older than staleLock was left by a writer that died holding it.
+const (
+	staleLock   = 10 * time.Second
+	lockTimeout = 15 * time.Second
+	lockRetry   = 5 * time.Millisecond
+)
+
+// historyLimit is the size past which the history moves to history.jsonl.1, replacing the
+// previous one, so it doesn't grow without bound
+const historyLimit = 1 << 20
+
+// Events of the history
+const (
+	EventStarted = "started"
+	EventExited  = "exited"
+	EventStopped = "stopped"
+)
+
+// Event is an entry of a namespace's history
+type Event struct {
+	Time     time.Time `json:"time"`
+	Name     string    `json:"name"`
+	Event    string    `json:"event"`
+	PID      int       `json:"pid,omitempty"`
+	ExitCode int       `json:"exitCode,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Store holds the files of a namespace's processes, such as api.pid, and its history.
+// Writes replace files whole, so readers see the old or the new contents without locking,
+// and writers exclude each other. Generated CLIs mirror DirStore.
+type Store interface {
+	// Read returns the contents of a file
+	Read(file string) ([]byte, error)
+	// Write replaces the contents of a file
+	Write(file string, content []byte) error
+	// Append adds a line to a file
+	Append(file, line string) error
+	// Remove removes files, which need not exist
+	Remove(files ...string) error
+	// Files returns the names of the files matching a pattern, sorted
+	Files(pattern string) ([]string, error)
+	// Record appends an event to the history, stamped with the current time if it has none
+	Record(event Event) error
+	// History returns the events of the history, oldest first
+	History() ([]Event, error)
+}
+
+// Store returns the store of a namespace's files
+func (r Registry) Store(namespace string) Store {
+	return DirStore{Dir: r.Dir(namespace)}
+}
+
+// DirStore is a Store in a directory. Changes take the directory's LockFile, created
+// exclusively, and write a temporary file that is renamed over the file.
+type DirStore struct {
+	Dir string
+}
+
+func (s DirStore) Read(file string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.Dir, file))
+}
+
+func (s DirStore) Write(file string, content []byte) error {
+	return s.locked(func() error { return s.replace(file, content) })
+}
+
+func (s DirStore) Append(file, line string) error {
+	return s.locked(func() error {
+		content, err := s.Read(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.replace(file, append(content, line+"\n"...))
+	})
+}
+
+func (s DirStore) Remove(files ...string) error {
+	return s.locked(func() error {
+		for _, file := range files {
+			if err := os.Remove(filepath.Join(s.Dir, file)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s DirStore) Files(pattern string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, len(paths))
+	for i, path := range paths {
+		files[i] = filepath.Base(path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (s DirStore) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.locked(func() error {
+		path := filepath.Join(s.Dir, HistoryFile)
+		if info, err := os.Stat(path); err == nil && info.Size() > historyLimit {
+			if err := os.Rename(path, path+".1"); err != nil {
+				return err
+			}
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			_ = file.Close()
+			return err
+		}
+		return file.Close()
+	})
+}
+
+// History returns the events of the history, including those moved to history.jsonl.1.
+// Lines that aren't events, such as one cut short by a crash, are skipped.
+func (s DirStore) History() ([]Event, error) {
+	var events []Event
+	for _, file := range []string{HistoryFile + ".1", HistoryFile} {
+		f, err := os.Open(filepath.Join(s.Dir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var event Event
+			if json.Unmarshal(scanner.Bytes(), &event) == nil && event.Name != "" {
+				events = append(events, event)
+			}
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// replace writes a file through a temporary file renamed over it
+func (s DirStore) replace(file string, content []byte) error {
+	temp, err := os.CreateTemp(s.Dir, "."+file+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(content); err != nil {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(s.Dir, file)); err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
+
+// locked runs fn holding the directory's lock, creating the directory if needed
+func (s DirStore) locked(fn func() error) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.Dir, LockFile)
+	for deadline := time.Now().Add(lockTimeout); ; time.Sleep(lockRetry) {
+		lock, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_ = lock.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the lock %s", path)
+		}
+	}
+	defer os.Remove(path)
+	return fn()
+}
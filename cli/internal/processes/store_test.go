@@ -0,0 +1,124 @@
+package processes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDirStore_ConcurrentAppends(t *testing.T) {
+	store := DirStore{Dir: filepath.Join(t.TempDir(), "ns")}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := store.Append("api.ports", fmt.Sprintf("PORT_%d=%d", i, 4000+i)); err != nil {
+				t.Errorf("Append failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	content, err := store.Read("api.ports")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if lines := strings.Fields(string(content)); len(lines) != 20 {
+		t.Errorf("got %d ports, want all 20:\n%s", len(lines), content)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, LockFile)); !os.IsNotExist(err) {
+		t.Errorf("the lock should be released, got %v", err)
+	}
+}
+
+func TestDirStore_WriteReplaces(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	if err := store.Write("api.pid", []byte("123")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := store.Write("api.pid", []byte("4")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if content, _ := store.Read("api.pid"); string(content) != "4" {
+		t.Errorf("Read = %q, want the second write whole", content)
+	}
+	files, err := store.Files("*")
+	if err != nil || strings.Join(files, ",") != "api.pid" {
+		t.Errorf("Files = %v, %v; want only api.pid, without temporary files", files, err)
+	}
+
+	if err := store.Remove("api.pid", "api.ports"); err != nil {
+		t.Errorf("Remove of a missing file failed: %v", err)
+	}
+	if _, err := store.Read("api.pid"); !os.IsNotExist(err) {
+		t.Errorf("api.pid should be removed, got %v", err)
+	}
+}
+
+func TestDirStore_BreaksStaleLock(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	lock := filepath.Join(store.Dir, LockFile)
+	writeFile(t, lock, "")
+	old := time.Now().Add(-2 * staleLock)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Write("api.pid", []byte("123")); err != nil {
+		t.Fatalf("Write should break a stale lock: %v", err)
+	}
+}
+
+func TestDirStore_History(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	if events, err := store.History(); err != nil || len(events) != 0 {
+		t.Fatalf("History of a new namespace = %v, %v; want none", events, err)
+	}
+
+	if err := store.Record(Event{Name: "api", Event: EventStarted, PID: 42}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	f, err := os.OpenFile(filepath.Join(store.Dir, HistoryFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2025-01-01T00:00:00Z","na` + "\n") // Cut short by a crash
+	f.Close()
+	if err := store.Record(Event{Name: "api", Event: EventExited, PID: 42, ExitCode: 2, Error: "boom"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	events, err := store.History()
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Event != EventStarted || events[1].ExitCode != 2 || events[1].Error != "boom" {
+		t.Fatalf("History = %+v, want the started and exited events", events)
+	}
+	if events[0].Time.IsZero() {
+		t.Error("Record should stamp events with the time")
+	}
+}
+
+func TestDirStore_HistoryRotates(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	writeFile(t, filepath.Join(store.Dir, HistoryFile),
+		`{"time":"2025-01-01T00:00:00Z","name":"old","event":"started"}`+"\n"+strings.Repeat("{}\n", historyLimit/3+1))
+
+	if err := store.Record(Event{Name: "api", Event: EventStarted}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(store.Dir, HistoryFile)); err != nil || info.Size() > 1024 {
+		t.Fatalf("the history should start again past the limit, got %v, %v", info, err)
+	}
+	events, err := store.History()
+	if err != nil || len(events) != 2 || events[0].Name != "old" || events[1].Name != "api" {
+		t.Errorf("History = %+v, %v; want the moved event first", events, err)
+	}
+}
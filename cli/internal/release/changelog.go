@@ -0,0 +1,154 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// changelogGroups lists changelog headings in display order with the commit types they collect
+var changelogGroups = []struct {
+	Title string
+	Types []string
+}{
+	{Title: "Features", Types: []string{"feat"}},
+	{Title: "Bug Fixes", Types: []string{"fix"}},
+	{Title: "Performance", Types: []string{"perf"}},
+}
+
+// RenderSection renders a changelog section for a version from the commits it contains.
+// Breaking changes are listed first, followed by features, fixes, and everything else.
+func RenderSection(version Version, date time.Time, commits []Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s - %s\n", version, date.Format("2006-01-02"))
+
+	var breaking, other []ConventionalCommit
+	grouped := make(map[string][]ConventionalCommit)
+	for _, c := range commits {
+		cc := ParseConventional(c)
+		if cc.Breaking {
+			breaking = append(breaking, cc)
+			continue
+		}
+		placed := false
+		for _, group := range changelogGroups {
+			for _, t := range group.Types {
+				if cc.Type == t {
+					grouped[group.Title] = append(grouped[group.Title], cc)
+					placed = true
+				}
+			}
+		}
+		if !placed {
+			other = append(other, cc)
+		}
+	}
+
+	writeGroup := func(title string, entries []ConventionalCommit) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		for _, cc := range entries {
+			b.WriteString("- ")
+			if cc.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", cc.Scope)
+			}
+			b.WriteString(cc.Description)
+			if cc.Hash != "" {
+				fmt.Fprintf(&b, " (%s)", shortHash(cc.Hash))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	writeGroup("Breaking Changes", breaking)
+	for _, group := range changelogGroups {
+		writeGroup(group.Title, grouped[group.Title])
+	}
+	writeGroup("Other Changes", other)
+
+	if len(commits) == 0 {
+		b.WriteString("\nNo changes.\n")
+	}
+	return b.String()
+}
+
+// PrependSection inserts a section above the newest entry of an existing changelog,
+// keeping any title and introduction at the top. An empty changelog gets a title.
+func PrependSection(changelog, section string) string {
+	section = strings.TrimRight(section, "\n") + "\n"
+	if strings.TrimSpace(changelog) == "" {
+		return "# Changelog\n\n" + section
+	}
+
+	lines := strings.SplitAfter(changelog, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			head := strings.Join(lines[:i], "")
+			return head + section + "\n" + strings.Join(lines[i:], "")
+		}
+	}
+	return strings.TrimRight(changelog, "\n") + "\n\n" + section
+}
+
+// FindSection returns the body of the changelog section for version, accepting
+// headings like "## v1.2.3", "## 1.2.3 - 2024-01-01", or "## [1.2.3]"
+func FindSection(changelog string, version Version) (string, bool) {
+	want := version
+	want.Prefix = ""
+
+	lines := strings.Split(changelog, "\n")
+	for i, line := range lines {
+		if !matchesSectionHeading(line, want) {
+			continue
+		}
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if strings.HasPrefix(lines[j], "## ") {
+				end = j
+				break
+			}
+		}
+		return strings.TrimSpace(strings.Join(lines[i+1:end], "\n")), true
+	}
+	return "", false
+}
+
+// ValidateChangelog checks that the changelog has a non-empty section for version
+func ValidateChangelog(changelog string, version Version) error {
+	body, ok := FindSection(changelog, version)
+	if !ok {
+		return fmt.Errorf("changelog has no section for %s", version)
+	}
+	if body == "" {
+		return fmt.Errorf("changelog section for %s is empty", version)
+	}
+	return nil
+}
+
+// matchesSectionHeading reports whether a "## " heading names the given unprefixed version
+func matchesSectionHeading(line string, version Version) bool {
+	if !strings.HasPrefix(line, "## ") {
+		return false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "## "))
+	if len(fields) == 0 {
+		return false
+	}
+	name := strings.Trim(fields[0], "[]")
+	parsed, err := ParseVersion(name)
+	if err != nil {
+		return false
+	}
+	parsed.Prefix = ""
+	return parsed == version
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,72 @@
+package release
+
+import (
+	"strings"
+)
+
+// Commit is a single commit message
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+}
+
+// ConventionalCommit is a commit subject parsed as "type(scope)!: description"
+type ConventionalCommit struct {
+	Commit
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// ParseConventional parses a commit following the Conventional Commits format.
+// Commits that don't follow it are returned with an empty Type and the subject as description.
+func ParseConventional(c Commit) ConventionalCommit {
+	bodyBreaking := strings.Contains(c.Body, "BREAKING CHANGE:") || strings.Contains(c.Body, "BREAKING-CHANGE:")
+	cc := ConventionalCommit{Commit: c, Description: c.Subject, Breaking: bodyBreaking}
+
+	colon := strings.Index(c.Subject, ": ")
+	if colon <= 0 {
+		return cc
+	}
+
+	header := c.Subject[:colon]
+	if strings.HasSuffix(header, "!") {
+		cc.Breaking = true
+		header = strings.TrimSuffix(header, "!")
+	}
+	if open := strings.Index(header, "("); open > 0 && strings.HasSuffix(header, ")") {
+		cc.Scope = header[open+1 : len(header)-1]
+		header = header[:open]
+	}
+	if header == "" || strings.ContainsAny(header, " \t") {
+		// Not conventional, so only a BREAKING CHANGE footer in the body makes it breaking
+		return ConventionalCommit{Commit: c, Description: c.Subject, Breaking: bodyBreaking}
+	}
+
+	cc.Type = strings.ToLower(header)
+	cc.Description = strings.TrimSpace(c.Subject[colon+2:])
+	return cc
+}
+
+// DetectBump derives the bump from commits since the last release:
+// breaking changes are major, "feat" is minor, and any other commit is a patch.
+// No commits means there is nothing to release.
+func DetectBump(commits []Commit) BumpKind {
+	bump := BumpNone
+	for _, c := range commits {
+		cc := ParseConventional(c)
+		kind := BumpPatch
+		switch {
+		case cc.Breaking:
+			kind = BumpMajor
+		case cc.Type == "feat":
+			kind = BumpMinor
+		}
+		if kind.rank() > bump.rank() {
+			bump = kind
+		}
+	}
+	return bump
+}
@@ -0,0 +1,148 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Plan describes the next release computed from a repository
+type Plan struct {
+	// Current is the latest released version, or v0.0.0 when there are no version tags
+	Current Version
+	// Tag is the tag Current was read from, empty when there are no version tags
+	Tag     string
+	Next    Version
+	Bump    BumpKind
+	Commits []Commit
+}
+
+// NewPlan computes the next version for the repository in dir.
+// With BumpAuto the bump is derived from conventional commits since the latest tag.
+func NewPlan(dir string, bump BumpKind) (*Plan, error) {
+	tag, current, err := LatestVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := CommitsSince(dir, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if bump == BumpAuto {
+		bump = DetectBump(commits)
+	}
+
+	return &Plan{
+		Current: current,
+		Tag:     tag,
+		Next:    current.Bump(bump),
+		Bump:    bump,
+		Commits: commits,
+	}, nil
+}
+
+// LatestVersion returns the highest semantic version tag reachable from HEAD and
+// its version. Tags that aren't semantic versions are ignored; without any, the
+// tag is empty and the version is v0.0.0.
+func LatestVersion(dir string) (string, Version, error) {
+	out, err := git(dir, "tag", "--merged", "HEAD")
+	if err != nil {
+		return "", Version{}, err
+	}
+
+	latestTag, latest := "", Version{Prefix: "v"}
+	for _, tag := range strings.Fields(out) {
+		v, err := ParseVersion(tag)
+		if err != nil {
+			continue
+		}
+		if latestTag == "" || Compare(v, latest) > 0 {
+			latestTag, latest = tag, v
+		}
+	}
+	return latestTag, latest, nil
+}
+
+// CommitsSince returns commits reachable from HEAD but not from tag, newest first.
+// An empty tag returns the full history.
+func CommitsSince(dir, tag string) ([]Commit, error) {
+	revision := "HEAD"
+	if tag != "" {
+		revision = tag + "..HEAD"
+	}
+
+	out, err := git(dir, "log", "--format=%H%x1f%s%x1f%b%x1e", revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+	return commits, nil
+}
+
+// CreateTag creates an annotated tag at HEAD
+func CreateTag(dir string, version Version, message string) error {
+	_, err := git(dir, "tag", "-a", version.String(), "-m", message)
+	return err
+}
+
+// Compare orders versions by precedence, returning -1, 0, or 1.
+// A prerelease sorts before its release.
+func Compare(a, b Version) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	case a.Prerelease < b.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// git runs git in dir and returns its stdout
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,255 @@
+package release
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "v1.2.3", want: Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3}},
+		{input: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{input: "v2.0.0-rc.1", want: Version{Prefix: "v", Major: 2, Prerelease: "rc.1"}},
+		{input: "v1.0.0+build.5", want: Version{Prefix: "v", Major: 1}},
+		{input: "v1.2", wantErr: true},
+		{input: "release-1", wantErr: true},
+		{input: "v1.x.0", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseVersion(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ParseVersion(%q) = %+v, want %+v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVersionBump(t *testing.T) {
+	testCases := []struct {
+		version string
+		bump    BumpKind
+		want    string
+	}{
+		{"v1.2.3", BumpPatch, "v1.2.4"},
+		{"v1.2.3", BumpMinor, "v1.3.0"},
+		{"v1.2.3", BumpMajor, "v2.0.0"},
+		{"v1.2.3", BumpNone, "v1.2.3"},
+		{"1.3.0-rc.1", BumpMinor, "1.3.0"},
+		{"1.3.0-rc.1", BumpMajor, "2.0.0"},
+		{"2.0.0-beta", BumpMajor, "2.0.0"},
+	}
+
+	for _, tc := range testCases {
+		v, err := ParseVersion(tc.version)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q): %v", tc.version, err)
+		}
+		if got := v.Bump(tc.bump).String(); got != tc.want {
+			t.Errorf("%s bumped %s = %s, want %s", tc.version, tc.bump, got, tc.want)
+		}
+	}
+}
+
+func TestParseBump(t *testing.T) {
+	if kind, err := ParseBump(""); err != nil || kind != BumpAuto {
+		t.Errorf("empty bump = %q, %v; want auto", kind, err)
+	}
+	if kind, err := ParseBump("Minor"); err != nil || kind != BumpMinor {
+		t.Errorf("Minor = %q, %v; want minor", kind, err)
+	}
+	if _, err := ParseBump("huge"); err == nil {
+		t.Error("expected error for unknown bump")
+	}
+}
+
+func TestParseConventional(t *testing.T) {
+	testCases := []struct {
+		subject  string
+		body     string
+		wantType string
+		scope    string
+		breaking bool
+		desc     string
+	}{
+		{subject: "feat: add release command", wantType: "feat", desc: "add release command"},
+		{subject: "fix(parser): handle tabs", wantType: "fix", scope: "parser", desc: "handle tabs"},
+		{subject: "refactor!: drop v1 config", wantType: "refactor", breaking: true, desc: "drop v1 config"},
+		{subject: "feat(api)!: rename fields", wantType: "feat", scope: "api", breaking: true, desc: "rename fields"},
+		{subject: "chore: bump deps", body: "BREAKING CHANGE: requires go 1.24", wantType: "chore", breaking: true, desc: "bump deps"},
+		{subject: "Update README", desc: "Update README"},
+		{subject: "Merge branch main: conflicts", desc: "Merge branch main: conflicts"},
+		{subject: "feat(api client)!: drop v1", wantType: "feat", scope: "api client", breaking: true, desc: "drop v1"},
+		{subject: "new feat: not conventional", body: "BREAKING CHANGE: still breaking", breaking: true, desc: "new feat: not conventional"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.subject, func(t *testing.T) {
+			cc := ParseConventional(Commit{Subject: tc.subject, Body: tc.body})
+			if cc.Type != tc.wantType || cc.Scope != tc.scope || cc.Breaking != tc.breaking || cc.Description != tc.desc {
+				t.Errorf("got type=%q scope=%q breaking=%v desc=%q", cc.Type, cc.Scope, cc.Breaking, cc.Description)
+			}
+		})
+	}
+}
+
+func TestDetectBump(t *testing.T) {
+	commit := func(subject string) Commit { return Commit{Subject: subject} }
+
+	testCases := []struct {
+		name    string
+		commits []Commit
+		want    BumpKind
+	}{
+		{"no commits", nil, BumpNone},
+		{"fixes only", []Commit{commit("fix: a"), commit("docs: b")}, BumpPatch},
+		{"feature", []Commit{commit("fix: a"), commit("feat: b")}, BumpMinor},
+		{"breaking", []Commit{commit("feat: a"), commit("fix!: b")}, BumpMajor},
+		{"non-conventional", []Commit{commit("Tweak things")}, BumpPatch},
+	}
+
+	for _, tc := range testCases {
+		if got := DetectBump(tc.commits); got != tc.want {
+			t.Errorf("%s: DetectBump = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRenderSection(t *testing.T) {
+	commits := []Commit{
+		{Hash: "aaaaaaaaaa", Subject: "feat(cli): add release command"},
+		{Hash: "bbbbbbbbbb", Subject: "fix: handle empty changelog"},
+		{Hash: "cccccccccc", Subject: "refactor!: remove legacy flags"},
+		{Hash: "dddddddddd", Subject: "Update docs"},
+	}
+
+	section := RenderSection(Version{Prefix: "v", Major: 1, Minor: 3}, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), commits)
+
+	expected := `## v1.3.0 - 2024-05-01
+
+### Breaking Changes
+
+- remove legacy flags (ccccccc)
+
+### Features
+
+- **cli:** add release command (aaaaaaa)
+
+### Bug Fixes
+
+- handle empty changelog (bbbbbbb)
+
+### Other Changes
+
+- Update docs (ddddddd)
+`
+	if section != expected {
+		t.Errorf("unexpected section:\n%s\nwant:\n%s", section, expected)
+	}
+}
+
+func TestPrependSection(t *testing.T) {
+	section := "## v1.1.0 - 2024-05-01\n\n- new\n"
+
+	if got := PrependSection("", section); got != "# Changelog\n\n"+section {
+		t.Errorf("empty changelog:\n%s", got)
+	}
+
+	existing := "# Changelog\n\nAll notable changes.\n\n## v1.0.0 - 2024-01-01\n\n- old\n"
+	want := "# Changelog\n\nAll notable changes.\n\n## v1.1.0 - 2024-05-01\n\n- new\n\n## v1.0.0 - 2024-01-01\n\n- old\n"
+	if got := PrependSection(existing, section); got != want {
+		t.Errorf("existing changelog:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestValidateChangelog(t *testing.T) {
+	changelog := "# Changelog\n\n## [1.2.0] - 2024-05-01\n\n- added things\n\n## v1.1.0\n\n## 1.0.0\n\n- first\n"
+	version := func(s string) Version {
+		v, err := ParseVersion(s)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q): %v", s, err)
+		}
+		return v
+	}
+
+	if err := ValidateChangelog(changelog, version("v1.2.0")); err != nil {
+		t.Errorf("v1.2.0 should be valid: %v", err)
+	}
+	if err := ValidateChangelog(changelog, version("v1.0.0")); err != nil {
+		t.Errorf("v1.0.0 should be valid: %v", err)
+	}
+	if err := ValidateChangelog(changelog, version("v1.1.0")); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("v1.1.0 should be reported empty, got %v", err)
+	}
+	if err := ValidateChangelog(changelog, version("v2.0.0")); err == nil || !strings.Contains(err.Error(), "no section") {
+		t.Errorf("v2.0.0 should be missing, got %v", err)
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=devcmd", "-c", "user.email=devcmd@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	run("init", "-q")
+	run("commit", "-q", "--allow-empty", "-m", "initial")
+
+	// Without tags the first release is computed from v0.0.0
+	plan, err := NewPlan(dir, BumpAuto)
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if plan.Tag != "" || plan.Next.String() != "v0.0.1" || len(plan.Commits) != 1 {
+		t.Errorf("untagged plan = tag %q next %s commits %d", plan.Tag, plan.Next, len(plan.Commits))
+	}
+
+	run("tag", "v1.2.0")
+	run("tag", "not-a-version")
+	run("commit", "-q", "--allow-empty", "-m", "fix: patch it")
+	run("commit", "-q", "--allow-empty", "-m", "feat(cli): add it", "-m", "details")
+
+	plan, err = NewPlan(dir, BumpAuto)
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if plan.Tag != "v1.2.0" || plan.Bump != BumpMinor || plan.Next.String() != "v1.3.0" {
+		t.Errorf("auto plan = tag %q bump %s next %s", plan.Tag, plan.Bump, plan.Next)
+	}
+	if len(plan.Commits) != 2 || plan.Commits[0].Subject != "feat(cli): add it" || plan.Commits[0].Body != "details" {
+		t.Errorf("unexpected commits: %+v", plan.Commits)
+	}
+
+	plan, err = NewPlan(dir, BumpMajor)
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if plan.Next.String() != "v2.0.0" {
+		t.Errorf("major plan next = %s, want v2.0.0", plan.Next)
+	}
+}
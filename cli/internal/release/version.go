@@ -0,0 +1,120 @@
+package release
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BumpKind identifies which part of a semantic version to increment
+type BumpKind string
+
+const (
+	BumpNone  BumpKind = "none"
+	BumpPatch BumpKind = "patch"
+	BumpMinor BumpKind = "minor"
+	BumpMajor BumpKind = "major"
+	BumpAuto  BumpKind = "auto" // Derived from conventional commits
+)
+
+// ParseBump parses a bump kind as accepted by @semver and devcmd release
+func ParseBump(s string) (BumpKind, error) {
+	switch kind := BumpKind(strings.ToLower(strings.TrimSpace(s))); kind {
+	case BumpPatch, BumpMinor, BumpMajor, BumpAuto:
+		return kind, nil
+	case "":
+		return BumpAuto, nil
+	default:
+		return "", fmt.Errorf("invalid bump %q: expected auto, major, minor, or patch", s)
+	}
+}
+
+// rank orders bump kinds so the largest requested bump wins
+func (k BumpKind) rank() int {
+	switch k {
+	case BumpPatch:
+		return 1
+	case BumpMinor:
+		return 2
+	case BumpMajor:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Version is a semantic version with an optional tag prefix such as "v"
+type Version struct {
+	Prefix     string
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseVersion parses tags like "v1.2.3", "1.2.3", or "v1.2.3-rc.1".
+// Build metadata after "+" is ignored.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "v") || strings.HasPrefix(rest, "V") {
+		v.Prefix, rest = rest[:1], rest[1:]
+	}
+	if i := strings.Index(rest, "+"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		v.Prerelease, rest = rest[i+1:], rest[:i]
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid semantic version %q", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid semantic version %q", s)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, nil
+}
+
+// String formats the version including its prefix
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Bump returns the next version for the given bump kind.
+// Bumping a prerelease releases it, so "1.3.0-rc.1" with a minor bump becomes "1.3.0".
+func (v Version) Bump(kind BumpKind) Version {
+	next := v
+	next.Prerelease = ""
+	if v.Prerelease != "" && kind != BumpNone {
+		switch {
+		case kind == BumpMajor && v.Minor == 0 && v.Patch == 0,
+			kind == BumpMinor && v.Patch == 0,
+			kind == BumpPatch:
+			return next
+		}
+	}
+
+	switch kind {
+	case BumpMajor:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case BumpMinor:
+		next.Minor, next.Patch = v.Minor+1, 0
+	case BumpPatch:
+		next.Patch = v.Patch + 1
+	default:
+		return v
+	}
+	return next
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// ProgramDiff describes how a reloaded program differs from the one it replaces. Commands are
+// named as declared, e.g. "watch api".
+type ProgramDiff struct {
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Variables []string // Variables added, removed or given a new value
+}
+
+// Empty reports whether the programs define the same commands and variables
+func (d ProgramDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.Variables) == 0
+}
+
+// String summarizes the diff, e.g. "added test; changed build"
+func (d ProgramDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"added", d.Added}, {"removed", d.Removed}, {"changed", d.Changed}, {"variables changed", d.Variables}} {
+		if len(group.names) > 0 {
+			parts = append(parts, group.label+" "+strings.Join(group.names, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DiffPrograms compares two programs command by command, in declaration order
+func DiffPrograms(old, updated *ast.Program) ProgramDiff {
+	var diff ProgramDiff
+	diff.Added, diff.Removed, diff.Changed = diffDeclarations(commandDeclarations(old), commandDeclarations(updated))
+	added, removed, changed := diffDeclarations(variableDeclarations(old), variableDeclarations(updated))
+	diff.Variables = append(append(added, removed...), changed...)
+	return diff
+}
+
+// declaration is a named top-level declaration and its source text
+type declaration struct {
+	name   string
+	source string
+}
+
+// commandDeclarations lists a program's commands, keeping watch and stop commands apart
+func commandDeclarations(program *ast.Program) []declaration {
+	declarations := make([]declaration, len(program.Commands))
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		name := command.Name
+		if command.Type != ast.Command {
+			name = command.Type.String() + " " + name
+		}
+		declarations[i] = declaration{name: name, source: command.String()}
+	}
+	return declarations
+}
+
+// variableDeclarations lists a program's variables
+func variableDeclarations(program *ast.Program) []declaration {
+	declarations := make([]declaration, len(program.Variables))
+	for i := range program.Variables {
+		declarations[i] = declaration{name: program.Variables[i].Name, source: program.Variables[i].String()}
+	}
+	return declarations
+}
+
+// diffDeclarations returns the names only in updated, only in old, and in both with new source
+func diffDeclarations(old, updated []declaration) (added, removed, changed []string) {
+	oldSource := make(map[string]string, len(old))
+	for _, d := range old {
+		oldSource[d.name] = d.source
+	}
+	kept := make(map[string]bool, len(updated))
+	for _, d := range updated {
+		kept[d.name] = true
+		source, existed := oldSource[d.name]
+		switch {
+		case !existed:
+			added = append(added, d.name)
+		case source != d.source:
+			changed = append(changed, d.name)
+		}
+	}
+	for _, d := range old {
+		if !kept[d.name] {
+			removed = append(removed, d.name)
+		}
+	}
+	return added, removed, changed
+}
+
+// Reload replaces the program being served and returns how it changed. Runs already in
+// progress finish with the definitions they started with; background processes started by
+// watch commands are left running.
+func (s *Server) Reload(program *ast.Program) ProgramDiff {
+	s.programMu.Lock()
+	defer s.programMu.Unlock()
+	diff := DiffPrograms(s.program, program)
+	s.program = program
+	return diff
+}
+
+// WatchFiles polls paths every interval until ctx is done, and reloads the program from load
+// whenever the contents of any of them change, including a file appearing or going away.
+// Reloads and load errors are reported to log; on an error the current program keeps being
+// served.
+func (s *Server) WatchFiles(ctx context.Context, paths []string, interval time.Duration, load func() (*ast.Program, error), log io.Writer) {
+	// The first check always loads, so an edit made while the server was starting is not
+	// missed; a program identical to the one being served reloads silently
+	hashes := make(map[string]string, len(paths))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var changed []string
+		for _, path := range paths {
+			hash := ""
+			if source, err := os.ReadFile(path); err == nil {
+				hash = engine.SourceHash(source)
+			}
+			if previous, seen := hashes[path]; !seen || previous != hash {
+				hashes[path] = hash
+				changed = append(changed, path)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		program, err := load()
+		if err != nil {
+			fmt.Fprintf(log, "devcmd: %s changed but failed to load, still serving the previous commands:\n%v\n", strings.Join(changed, ", "), err)
+			continue
+		}
+		if diff := s.Reload(program); !diff.Empty() {
+			fmt.Fprintf(log, "devcmd: reloaded %s: %s\n", strings.Join(changed, ", "), diff)
+		}
+	}
+}
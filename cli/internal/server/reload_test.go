@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+func mustParse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return program
+}
+
+func TestDiffPrograms(t *testing.T) {
+	old := mustParse(t, "var PORT = 8080\nbuild: go build\ntest: go test\nwatch api: go run .\nstop api: pkill api")
+	updated := mustParse(t, "var PORT = 9090\nbuild: go build ./...\ntest: go test\nwatch api: go run .\nlint: golangci-lint run")
+
+	diff := DiffPrograms(old, updated)
+	want := "added lint; removed stop api; changed build; variables changed PORT"
+	if diff.String() != want {
+		t.Errorf("diff = %q, want %q", diff, want)
+	}
+
+	if diff := DiffPrograms(old, mustParse(t, old.String())); !diff.Empty() {
+		t.Errorf("re-parsing the same program produced a diff: %s", diff)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to read while WatchFile writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor polls until condition holds or the test times out
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_WatchFilesReloadsCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.cli")
+	const initial = "hello: echo hello"
+	if err := os.WriteFile(path, []byte(initial), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(mustParse(t, initial))
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var log syncBuffer
+	done := make(chan struct{})
+	go func() {
+		load := func() (*ast.Program, error) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return parser.Parse(bytes.NewReader(source))
+		}
+		srv.WatchFiles(ctx, []string{path}, 10*time.Millisecond, load, &log)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	if err := os.WriteFile(path, []byte("hello: echo hello\nbye: echo bye"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the reload", func() bool { return strings.Contains(log.String(), "added bye") })
+
+	resp, err := http.Get(ts.URL + "/commands")
+	if err != nil {
+		t.Fatalf("GET /commands failed: %v", err)
+	}
+	var names []string
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		t.Fatalf("failed to decode commands: %v", err)
+	}
+	_ = resp.Body.Close()
+	if strings.Join(names, ",") != "hello,bye" {
+		t.Errorf("commands after reload = %q, want hello,bye", names)
+	}
+
+	// A file that doesn't parse leaves the current commands in place
+	if err := os.WriteFile(path, []byte("broken: {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the parse error", func() bool { return strings.Contains(log.String(), "failed to load") })
+	if got := len(srv.Program().Commands); got != 2 {
+		t.Errorf("commands after a failed reload = %d, want 2", got)
+	}
+}
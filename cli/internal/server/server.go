@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/metrics"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// Server exposes a command program over HTTP for long-running (serve) mode.
+//
+// Endpoints:
+//
+//	GET  /healthz         liveness check
+//	GET  /metrics         Prometheus metrics, see MetricsHandler with webhooks
+//	GET  /commands        list of runnable commands, see MetricsHandler with webhooks
+//	POST /hooks/{name}    run a webhook's command, see WithWebhooks
+type Server struct {
+	program   *ast.Program
+	programMu sync.RWMutex // Guards program, which Reload replaces
+	metrics   *metrics.Registry
+	setup     func(*engine.Engine) error
+
+	// Background processes are looked up in this namespace of the registry
+	registry  processes.Registry
+	namespace string
+
+	webhooks    map[string]Webhook
+	webhookRuns sync.WaitGroup // Runs started by webhooks, which outlive their requests
+	log         io.Writer      // Where the results of webhook runs are written
+
+	// Commands share the process stdout/stderr and working directory,
+	// so runs are serialized
+	runMu sync.Mutex
+}
+
+// New creates a server for the given program
+func New(program *ast.Program) *Server {
+	s := &Server{
+		program:   program,
+		metrics:   metrics.NewRegistry(),
+		registry:  processes.Default(),
+		namespace: processes.Namespace("."),
+		log:       os.Stderr,
+	}
+	s.metrics.SetProcessHealth(s.processHealth)
+	return s
+}
+
+// WithEngineSetup registers a function that configures each engine before a run,
+// e.g. to attach hooks from project settings
+func (s *Server) WithEngineSetup(setup func(*engine.Engine) error) *Server {
+	s.setup = setup
+	return s
+}
+
+// WithProcessNamespace sets the namespace of the process registry that background process
+// health is read from, by default that of the working directory
+func (s *Server) WithProcessNamespace(namespace string) *Server {
+	s.namespace = namespace
+	return s
+}
+
+// WithLog sets where the server reports the results of runs nobody waits on, by default stderr
+func (s *Server) WithLog(w io.Writer) *Server {
+	s.log = w
+	return s
+}
+
+// Metrics returns the server's metrics registry
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// Handler returns the HTTP handler serving all endpoints. A server with webhooks is bound to
+// an address providers can reach, so it serves only the webhooks and its liveness check;
+// MetricsHandler serves the rest on another address.
+func (s *Server) Handler() http.Handler {
+	if len(s.webhooks) == 0 {
+		return s.MetricsHandler()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", handleHealth)
+	mux.HandleFunc("POST /hooks/{name}", s.handleWebhook)
+	return mux
+}
+
+// MetricsHandler returns the HTTP handler serving the metrics, the commands and the liveness
+// check, without the webhooks. A server with webhooks serves it on a private address, so the
+// runs webhooks start can be monitored.
+func (s *Server) MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", handleHealth)
+	mux.Handle("GET /metrics", s.metrics)
+	mux.HandleFunc("GET /commands", s.handleCommands)
+	return mux
+}
+
+// handleHealth answers liveness checks
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// runResponse is the JSON body returned by the webhook endpoints
+type runResponse struct {
+	Command string `json:"command"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Program returns the program currently being served
+func (s *Server) Program() *ast.Program {
+	s.programMu.RLock()
+	defer s.programMu.RUnlock()
+	return s.program
+}
+
+// handleCommands lists runnable command names
+func (s *Server) handleCommands(w http.ResponseWriter, r *http.Request) {
+	program := s.Program()
+	names := make([]string, 0, len(program.Commands))
+	for _, cmd := range program.Commands {
+		names = append(names, cmd.Name)
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// findCommand returns the command of a program with the given name, or nil
+func findCommand(program *ast.Program, name string) *ast.CommandDecl {
+	for i := range program.Commands {
+		if program.Commands[i].Name == name {
+			return &program.Commands[i]
+		}
+	}
+	return nil
+}
+
+// run executes a command of a program once no other run is in progress, recording metrics
+// for it. The result is nil when the engine couldn't be set up.
+func (s *Server) run(program *ast.Program, target *ast.CommandDecl) (*engine.CommandResult, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	eng := engine.New(program)
+	eng.AddHook(execution.EventPostCommand, func(event execution.Event) error {
+		s.metrics.ObserveCommand(event.Command, event.Status, event.Duration)
+		return nil
+	})
+	if s.setup != nil {
+		if err := s.setup(eng); err != nil {
+			return nil, err
+		}
+	}
+	return eng.ExecuteCommand(target)
+}
+
+// processHealth reports whether each watch process in the program is running,
+// using the PID files written by generated CLIs in the project's namespace
+func (s *Server) processHealth() map[string]bool {
+	running := make(map[string]bool)
+	if list, err := s.registry.List(s.namespace); err == nil {
+		for _, process := range list {
+			running[process.Name] = process.Running
+		}
+	}
+
+	health := make(map[string]bool)
+	for _, cmd := range s.Program().Commands {
+		if cmd.Type != ast.WatchCommand {
+			continue
+		}
+		health[cmd.Name] = running[cmd.Name]
+	}
+	return health
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
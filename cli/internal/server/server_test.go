@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+
+	// Import builtins to register decorators
+	_ "github.com/aledsdavies/devcmd/cli/internal/builtins"
+)
+
+func newTestServer(t *testing.T, input string) (*Server, *httptest.Server) {
+	t.Helper()
+	program, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	srv := New(program)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func TestServer_RunRecordsMetrics(t *testing.T) {
+	srv, ts := newTestServer(t, "ok: true\nbad: false\nwatch api: sleep 1")
+
+	program := srv.Program()
+	for _, name := range []string{"ok", "ok", "bad"} {
+		_, err := srv.run(program, findCommand(program, name))
+		if (err != nil) != (name == "bad") {
+			t.Errorf("run %s: err = %v", name, err)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	text := string(data)
+
+	for _, line := range []string{
+		`devcmd_command_runs_total{command="ok",status="success"} 2`,
+		`devcmd_command_runs_total{command="bad",status="failed"} 1`,
+		`devcmd_background_process_up{process="api"} 0`,
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("missing metric line %q in output:\n%s", line, text)
+		}
+	}
+}
+
+// Commands only run from authenticated webhooks, never from a plain request
+func TestServer_NoRunEndpoint(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "ran")
+	_, ts := newTestServer(t, "pwn: touch "+out)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/run/pwn", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://evil.example")
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("POST /run ran the command")
+	}
+}
+
+func TestServer_ListCommands(t *testing.T) {
+	_, ts := newTestServer(t, "build: go build\ntest: go test")
+
+	resp, err := http.Get(ts.URL + "/commands")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var names []string
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if strings.Join(names, ",") != "build,test" {
+		t.Errorf("commands = %v, want [build test]", names)
+	}
+}
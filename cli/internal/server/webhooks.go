@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// Webhook providers, which decide how deliveries are authenticated and which header names the event
+const (
+	ProviderGitHub = "github" // HMAC-SHA256 of the body in X-Hub-Signature-256, event in X-GitHub-Event
+	ProviderGitLab = "gitlab" // Secret token in X-Gitlab-Token, event in X-Gitlab-Event
+)
+
+// maxWebhookPayload is the largest delivery accepted, GitHub's own limit
+const maxWebhookPayload = 25 << 20
+
+// Webhook runs a command when its provider delivers an event to /hooks/{name}
+type Webhook struct {
+	Name     string
+	Command  string
+	Provider string
+	Secret   string            // Deliveries not signed with it are rejected
+	Events   []string          // Events that run the command, e.g. "push"; all when empty
+	Vars     map[string]string // Variables set from payload fields, e.g. BRANCH = "ref"
+}
+
+// Validate checks that a webhook can authenticate deliveries
+func (h Webhook) Validate() error {
+	if h.Command == "" {
+		return fmt.Errorf("webhook %s: no command", h.Name)
+	}
+	if h.Provider != ProviderGitHub && h.Provider != ProviderGitLab {
+		return fmt.Errorf("webhook %s: unknown provider %q (expected %s or %s)", h.Name, h.Provider, ProviderGitHub, ProviderGitLab)
+	}
+	if h.Secret == "" {
+		return fmt.Errorf("webhook %s: no secret", h.Name)
+	}
+	return nil
+}
+
+// verify checks that a delivery comes from the provider: GitHub signs the body with the
+// secret, GitLab sends the secret itself
+func (h Webhook) verify(r *http.Request, body []byte) bool {
+	switch h.Provider {
+	case ProviderGitHub:
+		signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return false
+		}
+		got, err := hex.DecodeString(signature)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	case ProviderGitLab:
+		token := r.Header.Get("X-Gitlab-Token")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(h.Secret)) == 1
+	}
+	return false
+}
+
+// event returns the name of the delivered event, normalized so GitLab's "Tag Push Hook"
+// reads "tag_push" like its payload's object_kind
+func (h Webhook) event(r *http.Request) string {
+	if h.Provider == ProviderGitLab {
+		event := strings.TrimSuffix(strings.ToLower(r.Header.Get("X-Gitlab-Event")), " hook")
+		return strings.ReplaceAll(event, " ", "_")
+	}
+	return r.Header.Get("X-GitHub-Event")
+}
+
+// wants reports whether an event runs the command
+func (h Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		// GitHub pings a webhook when it's created, which isn't something to act on
+		return !(h.Provider == ProviderGitHub && event == "ping")
+	}
+	for _, want := range h.Events {
+		if want == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WithWebhooks serves each webhook at POST /hooks/{name}, in place of the metrics and the
+// command list
+func (s *Server) WithWebhooks(webhooks []Webhook) *Server {
+	s.webhooks = make(map[string]Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		s.webhooks[webhook.Name] = webhook
+	}
+	return s
+}
+
+// handleWebhook authenticates a delivery and starts the webhook's command with variables
+// from its payload. Providers time out long before a deploy finishes, so it responds as soon
+// as the run is accepted and logs the result.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	webhook, ok := s.webhooks[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, runResponse{Command: name, Status: "failed", Error: "webhook not found"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayload))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, runResponse{Command: webhook.Command, Status: "failed", Error: err.Error()})
+		return
+	}
+	if !webhook.verify(r, body) {
+		writeJSON(w, http.StatusUnauthorized, runResponse{Command: webhook.Command, Status: "failed", Error: "invalid signature"})
+		return
+	}
+	if event := webhook.event(r); !webhook.wants(event) {
+		writeJSON(w, http.StatusOK, runResponse{Command: webhook.Command, Status: "ignored"})
+		return
+	}
+
+	// A run keeps the definitions it started with, even if the program is reloaded meanwhile
+	program := s.Program()
+	if err := checkVariables(program, webhook.Vars); err != nil {
+		writeJSON(w, http.StatusInternalServerError, runResponse{Command: webhook.Command, Status: "failed", Error: fmt.Sprintf("webhook %s: %v", name, err)})
+		return
+	}
+	values, err := payloadVariables(body, r.Header.Get("Content-Type"), webhook.Vars)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, runResponse{Command: webhook.Command, Status: "failed", Error: err.Error()})
+		return
+	}
+	program = withVariables(program, values)
+	target := findCommand(program, webhook.Command)
+	if target == nil {
+		writeJSON(w, http.StatusNotFound, runResponse{Command: webhook.Command, Status: "failed", Error: "command not found"})
+		return
+	}
+
+	s.webhookRuns.Add(1)
+	go func() {
+		defer s.webhookRuns.Done()
+		if result, err := s.run(program, target); err != nil {
+			status := "failed"
+			if result != nil {
+				status = result.Status
+			}
+			_, _ = fmt.Fprintf(s.log, "devcmd: webhook %s: %s %s: %v\n", name, target.Name, status, err)
+		} else {
+			_, _ = fmt.Fprintf(s.log, "devcmd: webhook %s: %s %s\n", name, target.Name, result.Status)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, runResponse{Command: webhook.Command, Status: "accepted"})
+}
+
+// payloadVariables reads the variables mapped to payload fields. GitHub can deliver the JSON
+// payload as a form field instead of the body. Fields missing from the payload are left out,
+// so those variables keep the values the commands file gives them.
+func payloadVariables(body []byte, contentType string, vars map[string]string) (map[string]string, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form payload: %w", err)
+		}
+		body = []byte(form.Get("payload"))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	values := make(map[string]string, len(vars))
+	for name, path := range vars {
+		if value, ok := payloadField(payload, path); ok {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// payloadField looks up a dotted path such as "head_commit.id" or "commits.0.id" in a
+// payload. Strings, numbers and booleans are returned as written, anything else as JSON.
+func payloadField(payload interface{}, path string) (string, bool) {
+	value := payload
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return "", false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", false
+			}
+			value = node[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// checkVariables fails unless the program defines every variable a webhook sets, which would
+// otherwise go unnoticed until a payload had the field
+func checkVariables(program *ast.Program, vars map[string]string) error {
+	defined := make(map[string]bool)
+	for _, v := range program.Variables {
+		defined[v.Name] = true
+	}
+	for _, group := range program.VarGroups {
+		for _, v := range group.Variables {
+			defined[v.Name] = true
+		}
+	}
+	for name := range vars {
+		if !defined[name] {
+			return fmt.Errorf("no variable %s is defined", name)
+		}
+	}
+	return nil
+}
+
+// withVariables returns a copy of a program with new values for some of its variables,
+// leaving the served program as it is for other runs
+func withVariables(program *ast.Program, values map[string]string) *ast.Program {
+	if len(values) == 0 {
+		return program
+	}
+
+	replace := func(variables []ast.VariableDecl) []ast.VariableDecl {
+		variables = append([]ast.VariableDecl(nil), variables...)
+		for i := range variables {
+			if value, ok := values[variables[i].Name]; ok {
+				variables[i].Value = &ast.StringLiteral{Value: value, Pos: variables[i].Value.Position()}
+			}
+		}
+		return variables
+	}
+	updated := *program
+	updated.Variables = replace(program.Variables)
+	updated.VarGroups = append([]ast.VarGroup(nil), program.VarGroups...)
+	for i := range updated.VarGroups {
+		updated.VarGroups[i].Variables = replace(updated.VarGroups[i].Variables)
+	}
+	return &updated
+}
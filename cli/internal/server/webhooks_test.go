@@ -0,0 +1,309 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPushPayload = `{"ref":"refs/heads/main","after":"abc123","commits":[{"id":"abc123"}],"forced":false,"size":1}`
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(t *testing.T, url string, body []byte, headers map[string]string) (int, runResponse) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result runResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+func TestWebhook_GitHubRunsCommandWithPayloadVariables(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "deployed")
+	program := mustParse(t, "var BRANCH = \"none\"\nvar SHA = \"none\"\ndeploy: echo @var(BRANCH) @var(SHA) > "+out)
+
+	var log bytes.Buffer
+	srv := New(program).WithLog(&log).WithWebhooks([]Webhook{{
+		Name:     "push",
+		Command:  "deploy",
+		Provider: ProviderGitHub,
+		Secret:   "s3cret",
+		Events:   []string{"push"},
+		Vars:     map[string]string{"BRANCH": "ref", "SHA": "commits.0.id"},
+	}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body := []byte(testPushPayload)
+	status, result := postWebhook(t, ts.URL+"/hooks/push", body, map[string]string{
+		"X-Hub-Signature-256": githubSignature("s3cret", body),
+		"X-GitHub-Event":      "push",
+		"Content-Type":        "application/json",
+	})
+	if status != http.StatusAccepted || result.Status != "accepted" {
+		t.Fatalf("status = %d %q, want %d accepted (%s)", status, result.Status, http.StatusAccepted, result.Error)
+	}
+	srv.webhookRuns.Wait()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("command didn't run: %v (log: %s)", err, log.String())
+	}
+	if got := strings.TrimSpace(string(data)); got != "refs/heads/main abc123" {
+		t.Errorf("output = %q, want payload values", got)
+	}
+	if !strings.Contains(log.String(), "webhook push: deploy success") {
+		t.Errorf("log = %q, want the run's result", log.String())
+	}
+
+	// The served program keeps its own values
+	if value := srv.Program().Variables[0].Value.String(); !strings.Contains(value, "none") {
+		t.Errorf("served BRANCH = %s, want it unchanged", value)
+	}
+}
+
+func TestWebhook_RejectsBadSignatures(t *testing.T) {
+	srv := New(mustParse(t, "deploy: true")).WithWebhooks([]Webhook{
+		{Name: "gh", Command: "deploy", Provider: ProviderGitHub, Secret: "s3cret"},
+		{Name: "gl", Command: "deploy", Provider: ProviderGitLab, Secret: "s3cret"},
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body := []byte(testPushPayload)
+	tests := []struct {
+		name    string
+		hook    string
+		headers map[string]string
+	}{
+		{"missing signature", "gh", nil},
+		{"wrong secret", "gh", map[string]string{"X-Hub-Signature-256": githubSignature("other", body)}},
+		{"not hex", "gh", map[string]string{"X-Hub-Signature-256": "sha256=zz"}},
+		{"sha1 signature", "gh", map[string]string{"X-Hub-Signature": "sha1=abc"}},
+		{"missing token", "gl", nil},
+		{"wrong token", "gl", map[string]string{"X-Gitlab-Token": "other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := postWebhook(t, ts.URL+"/hooks/"+tt.hook, body, tt.headers)
+			if status != http.StatusUnauthorized {
+				t.Errorf("status = %d %q, want %d", status, result.Status, http.StatusUnauthorized)
+			}
+		})
+	}
+
+	if status, _ := postWebhook(t, ts.URL+"/hooks/missing", body, nil); status != http.StatusNotFound {
+		t.Errorf("unknown webhook status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestWebhook_FiltersEvents(t *testing.T) {
+	srv := New(mustParse(t, "deploy: true")).WithLog(&bytes.Buffer{}).WithWebhooks([]Webhook{
+		{Name: "gh", Command: "deploy", Provider: ProviderGitHub, Secret: "s3cret"},
+		{Name: "gl", Command: "deploy", Provider: ProviderGitLab, Secret: "s3cret", Events: []string{"tag_push"}},
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	defer srv.webhookRuns.Wait()
+
+	body := []byte(`{"zen":"Keep it logically awesome."}`)
+	signed := func(event string) map[string]string {
+		return map[string]string{"X-Hub-Signature-256": githubSignature("s3cret", body), "X-GitHub-Event": event}
+	}
+	gitlab := func(event string) map[string]string {
+		return map[string]string{"X-Gitlab-Token": "s3cret", "X-Gitlab-Event": event}
+	}
+
+	tests := []struct {
+		name    string
+		hook    string
+		headers map[string]string
+		want    string
+	}{
+		{"github ping is ignored", "gh", signed("ping"), "ignored"},
+		{"github push runs", "gh", signed("push"), "accepted"},
+		{"gitlab push is filtered out", "gl", gitlab("Push Hook"), "ignored"},
+		{"gitlab tag push runs", "gl", gitlab("Tag Push Hook"), "accepted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, result := postWebhook(t, ts.URL+"/hooks/"+tt.hook, body, tt.headers); result.Status != tt.want {
+				t.Errorf("status = %q, want %q (%s)", result.Status, tt.want, result.Error)
+			}
+		})
+	}
+}
+
+func TestWebhook_UndefinedVariable(t *testing.T) {
+	srv := New(mustParse(t, "deploy: true")).WithWebhooks([]Webhook{
+		{Name: "gl", Command: "deploy", Provider: ProviderGitLab, Secret: "s3cret", Vars: map[string]string{"BRANCH": "ref"}},
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	status, result := postWebhook(t, ts.URL+"/hooks/gl", []byte(testPushPayload), map[string]string{"X-Gitlab-Token": "s3cret"})
+	if status != http.StatusInternalServerError || !strings.Contains(result.Error, "no variable BRANCH") {
+		t.Errorf("status = %d %q, want an undefined variable error", status, result.Error)
+	}
+}
+
+// A server with webhooks is reachable by providers, so it mustn't run or list commands otherwise
+func TestWebhook_ServesOnlyHooks(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "ran")
+	srv := New(mustParse(t, "deploy: touch "+out)).WithWebhooks([]Webhook{
+		{Name: "gl", Command: "deploy", Provider: ProviderGitLab, Secret: "s3cret"},
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for _, request := range []struct{ method, path string }{
+		{http.MethodPost, "/run/deploy"},
+		{http.MethodGet, "/commands"},
+		{http.MethodGet, "/metrics"},
+	} {
+		req, err := http.NewRequest(request.method, ts.URL+request.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", request.method, request.path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s status = %d, want %d", request.method, request.path, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("a request outside /hooks ran the command")
+	}
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /healthz status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestWebhook_RunsRecordMetrics(t *testing.T) {
+	srv := New(mustParse(t, "deploy: true")).WithLog(&bytes.Buffer{}).WithWebhooks([]Webhook{
+		{Name: "gl", Command: "deploy", Provider: ProviderGitLab, Secret: "s3cret"},
+	})
+	hooks := httptest.NewServer(srv.Handler())
+	defer hooks.Close()
+	private := httptest.NewServer(srv.MetricsHandler())
+	defer private.Close()
+
+	status, result := postWebhook(t, hooks.URL+"/hooks/gl", []byte(testPushPayload), map[string]string{"X-Gitlab-Token": "s3cret"})
+	if status != http.StatusAccepted {
+		t.Fatalf("status = %d %q, want %d", status, result.Error, http.StatusAccepted)
+	}
+	srv.webhookRuns.Wait()
+
+	resp, err := http.Get(private.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var text bytes.Buffer
+	if _, err := text.ReadFrom(resp.Body); err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	if line := `devcmd_command_runs_total{command="deploy",status="success"} 1`; !strings.Contains(text.String(), line) {
+		t.Errorf("missing metric line %q in output:\n%s", line, text.String())
+	}
+}
+
+func TestPayloadVariables(t *testing.T) {
+	vars := map[string]string{
+		"BRANCH":  "ref",
+		"SHA":     "commits.0.id",
+		"FORCED":  "forced",
+		"SIZE":    "size",
+		"COMMITS": "commits",
+		"MISSING": "head_commit.id",
+		"OUT":     "commits.5.id",
+	}
+	want := map[string]string{
+		"BRANCH":  "refs/heads/main",
+		"SHA":     "abc123",
+		"FORCED":  "false",
+		"SIZE":    "1",
+		"COMMITS": `[{"id":"abc123"}]`,
+	}
+
+	form := "payload=" + url.QueryEscape(testPushPayload)
+	for contentType, body := range map[string]string{
+		"application/json":                  testPushPayload,
+		"application/x-www-form-urlencoded": form,
+	} {
+		values, err := payloadVariables([]byte(body), contentType, vars)
+		if err != nil {
+			t.Fatalf("%s: payloadVariables failed: %v", contentType, err)
+		}
+		if len(values) != len(want) {
+			t.Errorf("%s: values = %v, want %v", contentType, values, want)
+		}
+		for name, value := range want {
+			if values[name] != value {
+				t.Errorf("%s: %s = %q, want %q", contentType, name, values[name], value)
+			}
+		}
+	}
+
+	if _, err := payloadVariables([]byte("not json"), "application/json", vars); err == nil {
+		t.Error("expected an error for a payload that isn't JSON")
+	}
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	valid := Webhook{Name: "push", Command: "deploy", Provider: ProviderGitHub, Secret: "s3cret"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		modify func(*Webhook)
+		want   string
+	}{
+		{"no command", func(h *Webhook) { h.Command = "" }, "no command"},
+		{"unknown provider", func(h *Webhook) { h.Provider = "bitbucket" }, "unknown provider"},
+		{"no secret", func(h *Webhook) { h.Secret = "" }, "no secret"},
+	} {
+		webhook := valid
+		tt.modify(&webhook)
+		if err := webhook.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate() = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package settings
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultFileName is the project settings file looked up next to the commands file
+const DefaultFileName = "devcmd.settings"
+
+// Settings holds project-level devcmd settings.
+//
+// The settings file uses the same block syntax as the command DSL:
+//
+//	# devcmd.settings
+//	hooks {
+//	    preRun  = "./scripts/announce.sh"
+//	    postRun = "./notify.sh"
+//	}
+//
+// Nested sections are flattened into dotted keys, so the example above
+// produces "hooks.preRun" and "hooks.postRun".
+type Settings struct {
+	values map[string]string
+	path   string
+}
+
+// New creates an empty settings value
+func New() *Settings {
+	return &Settings{
+		values: make(map[string]string),
+	}
+}
+
+// Load reads settings from the given path. A missing file is not an error and
+// yields empty settings, so projects without a settings file keep working.
+func Load(path string) (*Settings, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(), nil
+		}
+		return nil, fmt.Errorf("failed to open settings file %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	s, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.path = path
+	return s, nil
+}
+
+// LoadForCommandsFile loads the settings file that sits next to the given commands file
+func LoadForCommandsFile(commandsFile string) (*Settings, error) {
+	return Load(filepath.Join(filepath.Dir(commandsFile), DefaultFileName))
+}
+
+// Parse reads settings from a reader
+func Parse(r io.Reader) (*Settings, error) {
+	s := New()
+	var sections []string
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		tokens, err := tokenizeLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		for len(tokens) > 0 {
+			switch {
+			case tokens[0] == "}":
+				if len(sections) == 0 {
+					return nil, fmt.Errorf("line %d: unexpected '}'", lineNum)
+				}
+				sections = sections[:len(sections)-1]
+				tokens = tokens[1:]
+			case tokens[0] == ";":
+				tokens = tokens[1:]
+			case len(tokens) >= 2 && tokens[1] == "{":
+				if !isIdentifier(tokens[0]) {
+					return nil, fmt.Errorf("line %d: invalid section name %q", lineNum, tokens[0])
+				}
+				sections = append(sections, tokens[0])
+				tokens = tokens[2:]
+			case len(tokens) >= 3 && tokens[1] == "=":
+				if !isIdentifier(tokens[0]) {
+					return nil, fmt.Errorf("line %d: invalid setting name %q", lineNum, tokens[0])
+				}
+				key := strings.Join(append(append([]string{}, sections...), tokens[0]), ".")
+				s.values[key] = unquote(tokens[2])
+				tokens = tokens[3:]
+			default:
+				return nil, fmt.Errorf("line %d: expected 'name = value' or 'section {', got %q", lineNum, strings.Join(tokens, " "))
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if len(sections) > 0 {
+		return nil, fmt.Errorf("unclosed section %q", strings.Join(sections, "."))
+	}
+
+	return s, nil
+}
+
+// Path returns the file the settings were loaded from, or "" if none was found
+func (s *Settings) Path() string {
+	return s.path
+}
+
+// Has reports whether a setting is defined
+func (s *Settings) Has(key string) bool {
+	_, ok := s.values[key]
+	return ok
+}
+
+// Set sets a setting value
+func (s *Settings) Set(key, value string) {
+	s.values[key] = value
+}
+
+// String returns a string setting or the default value
+func (s *Settings) String(key, defaultValue string) string {
+	if value, ok := s.values[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// Bool returns a boolean setting or the default value
+func (s *Settings) Bool(key string, defaultValue bool) (bool, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("setting %s: expected boolean, got %q", key, value)
+	}
+	return b, nil
+}
+
+// Int returns an integer setting or the default value
+func (s *Settings) Int(key string, defaultValue int) (int, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("setting %s: expected number, got %q", key, value)
+	}
+	return i, nil
+}
+
+// Duration returns a duration setting or the default value
+func (s *Settings) Duration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("setting %s: expected duration, got %q", key, value)
+	}
+	return d, nil
+}
+
+// Section returns the direct settings of a section keyed by their short name
+func (s *Settings) Section(name string) map[string]string {
+	prefix := name + "."
+	result := make(map[string]string)
+	for key, value := range s.values {
+		if rest, ok := strings.CutPrefix(key, prefix); ok && !strings.Contains(rest, ".") {
+			result[rest] = value
+		}
+	}
+	return result
+}
+
+// Keys returns all defined setting keys in sorted order
+func (s *Settings) Keys() []string {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tokenizeLine splits a settings line into identifiers, values, and punctuation
+func tokenizeLine(line string) ([]string, error) {
+	var tokens []string
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			return tokens, nil
+		case r == '{' || r == '}' || r == '=' || r == ';':
+			tokens = append(tokens, string(r))
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && r == '"' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("{}=;#", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+
+	return tokens, nil
+}
+
+// unquote strips quotes from a string value, leaving bare values untouched
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// isIdentifier reports whether a token is a valid setting or section name
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '-')) {
+			continue
+		}
+		return false
+	}
+	return true
+}
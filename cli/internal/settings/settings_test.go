@@ -0,0 +1,104 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_SectionsAndValues(t *testing.T) {
+	input := `# project settings
+abbreviations = true
+timeout = 5m
+
+hooks {
+    preRun  = "./announce.sh"
+    postRun = './notify.sh'   # trailing comment
+    nested {
+        level = 2
+    }
+}
+`
+	s, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := s.String("hooks.preRun", ""); got != "./announce.sh" {
+		t.Errorf("hooks.preRun = %q, want %q", got, "./announce.sh")
+	}
+	if got := s.String("hooks.postRun", ""); got != "./notify.sh" {
+		t.Errorf("hooks.postRun = %q, want %q", got, "./notify.sh")
+	}
+	if got, err := s.Int("hooks.nested.level", 0); err != nil || got != 2 {
+		t.Errorf("hooks.nested.level = %d (%v), want 2", got, err)
+	}
+	if got, err := s.Bool("abbreviations", false); err != nil || !got {
+		t.Errorf("abbreviations = %v (%v), want true", got, err)
+	}
+	if got, err := s.Duration("timeout", 0); err != nil || got != 5*time.Minute {
+		t.Errorf("timeout = %v (%v), want 5m", got, err)
+	}
+
+	hooks := s.Section("hooks")
+	if len(hooks) != 2 {
+		t.Errorf("expected 2 direct hook settings, got %v", hooks)
+	}
+}
+
+func TestParse_SingleLineSection(t *testing.T) {
+	s, err := Parse(strings.NewReader(`hooks { postRun = "./notify.sh" }`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := s.String("hooks.postRun", ""); got != "./notify.sh" {
+		t.Errorf("hooks.postRun = %q, want %q", got, "./notify.sh")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"unclosed section", "hooks {\n postRun = x\n"},
+		{"stray brace", "}"},
+		{"missing value", "postRun ="},
+		{"unterminated string", `postRun = "oops`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tc.input)); err == nil {
+				t.Errorf("expected error for %q", tc.input)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), DefaultFileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(s.Keys()) != 0 || s.Path() != "" {
+		t.Errorf("expected empty settings, got %v", s.Keys())
+	}
+}
+
+func TestLoadForCommandsFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DefaultFileName), []byte(`hooks { onFailure = "echo failed" }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadForCommandsFile(filepath.Join(dir, "commands.cli"))
+	if err != nil {
+		t.Fatalf("LoadForCommandsFile failed: %v", err)
+	}
+	if got := s.String("hooks.onFailure", ""); got != "echo failed" {
+		t.Errorf("hooks.onFailure = %q, want %q", got, "echo failed")
+	}
+}
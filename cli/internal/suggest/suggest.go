@@ -0,0 +1,160 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxSuggestions is the default number of suggestions returned
+const MaxSuggestions = 3
+
+// Suggest returns up to max candidate names closest to input, best match first.
+//
+// Candidates are ranked by edit distance (with transpositions, so "biuld"
+// matches "build"). Namespaced names such as "db:migrate" or "docker-build"
+// also match on individual segments, so "migrat" suggests "db:migrate".
+// Prefix matches are always suggested.
+func Suggest(input string, candidates []string, max int) []string {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" || max <= 0 {
+		return nil
+	}
+
+	type match struct {
+		name  string
+		score int
+	}
+
+	var matches []match
+	for _, candidate := range candidates {
+		if score, ok := Score(input, candidate); ok {
+			matches = append(matches, match{name: candidate, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	if len(matches) > max {
+		matches = matches[:max]
+	}
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.name
+	}
+	return result
+}
+
+// PrefixMatches returns the candidates that start with input, in their original order
+func PrefixMatches(input string, candidates []string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if input != "" && strings.HasPrefix(candidate, input) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// Score returns how far candidate is from input (lower is closer) and whether
+// it is close enough to be worth suggesting
+func Score(input, candidate string) (int, bool) {
+	input = strings.ToLower(input)
+	candidate = strings.ToLower(candidate)
+	if input == candidate {
+		// Only differs in case from what was typed
+		return 0, true
+	}
+
+	threshold := maxDistance(input)
+
+	// Prefix matches rank ahead of everything but exact-distance-one typos
+	if strings.HasPrefix(candidate, input) {
+		return 1, true
+	}
+
+	best := Distance(input, candidate)
+
+	// Namespaced candidates: compare the input with each segment, and with
+	// the candidate segment-by-segment when the input is namespaced too
+	segments := splitSegments(candidate)
+	if len(segments) > 1 {
+		inputSegments := splitSegments(input)
+		if len(inputSegments) == 1 {
+			for _, segment := range segments {
+				if d := Distance(input, segment) + 1; d < best {
+					best = d
+				}
+				if strings.HasPrefix(segment, input) && 2 < best {
+					best = 2
+				}
+			}
+		} else if len(inputSegments) == len(segments) {
+			total := 0
+			for i := range segments {
+				total += Distance(inputSegments[i], segments[i])
+			}
+			if total < best {
+				best = total
+			}
+		}
+	}
+
+	return best, best <= threshold
+}
+
+// Distance computes the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions, and adjacent transpositions each cost one
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows, cols := len(ra)+1, len(rb)+1
+
+	d := make([][]int, rows)
+	for i := range d {
+		d[i] = make([]int, cols)
+		d[i][0] = i
+	}
+	for j := 0; j < cols; j++ {
+		d[0][j] = j
+	}
+
+	for i := 1; i < rows; i++ {
+		for j := 1; j < cols; j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[rows-1][cols-1]
+}
+
+// maxDistance scales the accepted edit distance with input length so short
+// inputs don't match everything
+func maxDistance(input string) int {
+	n := len([]rune(input))
+	switch {
+	case n <= 2:
+		return 1
+	case n <= 5:
+		return 2
+	default:
+		return n/3 + 1
+	}
+}
+
+// splitSegments splits a command name on common namespace separators
+func splitSegments(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r == ':' || r == '-' || r == '_' || r == '/' || r == '.'
+	})
+}
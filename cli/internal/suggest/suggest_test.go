@@ -0,0 +1,75 @@
+package suggest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"build", "build", 0},
+		{"biuld", "build", 1}, // transposition
+		{"buld", "build", 1},  // deletion
+		{"builds", "build", 1},
+		{"tset", "test", 1},
+		{"deploy", "destroy", 3},
+		{"", "abc", 3},
+	}
+
+	for _, tc := range testCases {
+		if got := Distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	commands := []string{"build", "build-all", "test", "test-integration", "deploy", "db:migrate", "db:seed", "lint"}
+
+	testCases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"transposed letters", "biuld", []string{"build", "build-all"}},
+		{"missing letter", "tst", []string{"test", "test-integration"}},
+		{"prefix", "dep", []string{"deploy"}},
+		{"namespace segment", "migrat", []string{"db:migrate"}},
+		{"namespaced typo", "db:sed", []string{"db:seed"}},
+		{"case insensitive", "LINT", []string{"lint"}},
+		{"nothing close", "xyzzy", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Suggest(tc.input, commands, MaxSuggestions)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("Suggest(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSuggest_Limit(t *testing.T) {
+	got := Suggest("te", []string{"test", "term", "tea", "tex"}, 2)
+	if len(got) != 2 {
+		t.Errorf("expected 2 suggestions, got %v", got)
+	}
+}
+
+func TestPrefixMatches(t *testing.T) {
+	commands := []string{"deploy", "destroy", "build"}
+
+	if got := PrefixMatches("dep", commands); strings.Join(got, ",") != "deploy" {
+		t.Errorf("PrefixMatches(dep) = %v", got)
+	}
+	if got := PrefixMatches("de", commands); strings.Join(got, ",") != "deploy,destroy" {
+		t.Errorf("PrefixMatches(de) = %v", got)
+	}
+	if got := PrefixMatches("", commands); len(got) != 0 {
+		t.Errorf("PrefixMatches(\"\") = %v, want none", got)
+	}
+}
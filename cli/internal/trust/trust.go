@@ -0,0 +1,241 @@
+// Package trust keeps the commands files a user has approved to run, direnv-style: a file
+// that hasn't been approved, or has changed since, is summarized by its potentially
+// dangerous constructs until the user allows it.
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/config"
+)
+
+// Finding is a potentially dangerous construct in a commands file or its settings
+type Finding struct {
+	Line    int    `json:"line,omitempty"`
+	Command string `json:"command,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the finding as "command, line N: message"
+func (f Finding) String() string {
+	switch {
+	case f.Command != "" && f.Line > 0:
+		return fmt.Sprintf("%s, line %d: %s", f.Command, f.Line, f.Message)
+	case f.Command != "":
+		return f.Command + ": " + f.Message
+	default:
+		return f.Message
+	}
+}
+
+// riskyDecorators describes the decorators that reach the network, credentials or other
+// programs on the user's behalf
+var riskyDecorators = map[string]string{
+	"aws-profile": "uses AWS credentials, and may log in",
+	"container":   "runs commands in a container image, pulling it if needed",
+	"gcp-project": "uses Google Cloud credentials, and may log in",
+	"go":          "may download and install a Go toolchain",
+	"http":        "makes HTTP requests",
+	"node":        "may download and install a Node.js toolchain",
+	"open":        "opens URLs in a browser",
+	"python":      "may install a Python toolchain",
+	"requires":    "may run commands in a container image when tools are missing",
+	"secret":      "reads secrets",
+}
+
+// shellPatterns describe shell text that deserves a look before it runs
+var shellPatterns = []struct {
+	pattern *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`\bsudo\b`), "runs sudo"},
+	{regexp.MustCompile(`\brm\s+(-\w*(r\w*f|f\w*r)\w*|(-\w+\s+)*(-r|-R|--recursive)\s+(-\w+\s+)*(-f|--force)|(-\w+\s+)*(-f|--force)\s+(-\w+\s+)*(-r|-R|--recursive))\b`), "deletes recursively with rm -rf"},
+	{regexp.MustCompile(`\b(curl|wget)\b[^;&|]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`), "pipes a download into a shell"},
+	{regexp.MustCompile(`\b(curl|wget|ssh|scp)\b`), "uses the network"},
+	{regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\b`), "makes files writable by everyone"},
+	{regexp.MustCompile(`\bdd\s+.*\bof=/dev/|\bmkfs\b`), "writes to a disk device"},
+	{regexp.MustCompile(`\beval\b`), "evaluates generated shell code"},
+}
+
+// Scan returns the potentially dangerous constructs of a program: decorators that use the
+// network or credentials, and shell text that runs sudo, deletes recursively or pipes a
+// download into a shell. Each construct is reported once per command and line.
+func Scan(program *ast.Program) []Finding {
+	// Triggers run like commands, so they are scanned under the name they are written with
+	type declaration struct {
+		name string
+		node ast.Node
+	}
+	declarations := make([]declaration, 0, len(program.Commands)+len(program.Triggers))
+	for i := range program.Commands {
+		declarations = append(declarations, declaration{program.Commands[i].Name, &program.Commands[i]})
+	}
+	for i := range program.Triggers {
+		trigger := &program.Triggers[i]
+		declarations = append(declarations, declaration{trigger.Name(), trigger})
+	}
+
+	var findings []Finding
+	for _, command := range declarations {
+		seen := make(map[Finding]bool)
+		add := func(line int, message string) {
+			finding := Finding{Line: line, Command: command.name, Message: message}
+			if !seen[finding] {
+				seen[finding] = true
+				findings = append(findings, finding)
+			}
+		}
+
+		ast.Walk(command.node, func(n ast.Node) bool {
+			var decorator string
+			var line int
+			switch node := n.(type) {
+			case *ast.BlockDecorator:
+				decorator, line = node.Name, node.Pos.Line
+			case *ast.ActionDecorator:
+				decorator, line = node.Name, node.Pos.Line
+			case *ast.ValueDecorator:
+				decorator, line = node.Name, node.Pos.Line
+			case *ast.ShellContent:
+				// Interpolated decorators stand in as a plain word, as they do for lint
+				var text strings.Builder
+				for _, part := range node.Parts {
+					if textPart, ok := part.(*ast.TextPart); ok {
+						text.WriteString(textPart.Text)
+					} else {
+						text.WriteString("x")
+					}
+				}
+				for _, p := range shellPatterns {
+					if p.pattern.MatchString(text.String()) {
+						add(node.Pos.Line, p.message)
+					}
+				}
+			}
+			if message, ok := riskyDecorators[decorator]; ok {
+				add(line, "@"+decorator+" "+message)
+			}
+			return true
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// ScanHooks returns a finding for each settings hook, which runs its shell command around
+// every command
+func ScanHooks(hooks map[string]string) []Finding {
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	findings := make([]Finding, 0, len(names))
+	for _, name := range names {
+		findings = append(findings, Finding{Message: fmt.Sprintf("settings hook %s runs %q", name, hooks[name])})
+	}
+	return findings
+}
+
+// Key returns the approval key of a project: a hash of the commands file's absolute path and
+// the contents of it and the other files read with it, such as its local override and
+// settings files. Editing any of them, or moving the project, changes the key.
+func Key(commandsFile string, files ...string) (string, error) {
+	absolute, err := filepath.Abs(commandsFile)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", absolute)
+	for _, file := range append([]string{commandsFile}, files...) {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			// A file appearing later changes the key as well
+			fmt.Fprintf(hash, "%s: missing\n", filepath.Base(file))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s: %d\n", filepath.Base(file), len(data))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DefaultDir returns allow in the state directory of the config, or devcmd/allow in the
+// user state directory, $XDG_STATE_HOME or ~/.local/state, or "" when there is neither
+func DefaultDir() string {
+	if state := config.StateDirFromEnv(); state != "" {
+		return filepath.Join(state, "allow")
+	}
+	if state := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(state) {
+		return filepath.Join(state, "devcmd", "allow")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "devcmd", "allow")
+}
+
+// Allowed reports whether the key has been approved in dir
+func Allowed(dir, key string) bool {
+	_, err := os.Stat(filepath.Join(dir, key))
+	return err == nil
+}
+
+// Allow approves the key in dir. The approval records the commands file's path so that
+// Deny can find it.
+func Allow(dir, key, commandsFile string) error {
+	absolute, err := filepath.Abs(commandsFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, key), []byte(absolute+"\n"), 0o644)
+}
+
+// Deny removes every approval of the commands file from dir, whatever its contents were
+// when approved, and returns how many were removed
+func Deny(dir, commandsFile string) (int, error) {
+	absolute, err := filepath.Abs(commandsFile)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil || strings.TrimSpace(string(data)) != absolute {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
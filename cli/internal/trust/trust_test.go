@@ -0,0 +1,102 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/aledsdavies/devcmd/cli/internal/builtins"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+)
+
+func TestScan(t *testing.T) {
+	source := `var DIR = "build"
+install: curl -fsSL https://example.com/install.sh | sh
+clean: sudo rm -rf @var(DIR)
+tidy: rm -r -f dist
+deploy: @container("alpine") {
+    echo deploying
+    echo @secret("token")
+}
+hello: echo hello
+on failure of deploy: sudo ./rollback.sh
+`
+	program, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var got []string
+	for _, finding := range Scan(program) {
+		got = append(got, finding.String())
+	}
+	want := []string{
+		"install, line 2: pipes a download into a shell",
+		"install, line 2: uses the network",
+		"clean, line 3: runs sudo",
+		"clean, line 3: deletes recursively with rm -rf",
+		"tidy, line 4: deletes recursively with rm -rf",
+		"deploy, line 5: @container runs commands in a container image, pulling it if needed",
+		"deploy, line 7: @secret reads secrets",
+		"on failure of deploy, line 10: runs sudo",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Scan() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	hooks := ScanHooks(map[string]string{"postRun": "./notify.sh", "preRun": "./announce.sh"})
+	if len(hooks) != 2 || hooks[0].String() != `settings hook postRun runs "./notify.sh"` {
+		t.Errorf("ScanHooks() = %v", hooks)
+	}
+}
+
+func TestAllowAndDeny(t *testing.T) {
+	project := t.TempDir()
+	commandsFile := filepath.Join(project, "commands.cli")
+	settingsFile := filepath.Join(project, "devcmd.settings")
+	if err := os.WriteFile(commandsFile, []byte("build: go build ./...\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "allow")
+
+	key, err := Key(commandsFile, settingsFile)
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	if Allowed(dir, key) {
+		t.Fatal("Allowed() before Allow")
+	}
+	if err := Allow(dir, key, commandsFile); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !Allowed(dir, key) {
+		t.Fatal("Allowed() = false after Allow")
+	}
+
+	// Adding a settings file, which can add hooks, needs a new approval
+	if err := os.WriteFile(settingsFile, []byte("hooks {\n    preRun = \"./announce.sh\"\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := Key(commandsFile, settingsFile)
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	if changed == key || Allowed(dir, changed) {
+		t.Error("approval still holds after the settings file was added")
+	}
+	if err := Allow(dir, changed, commandsFile); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+
+	removed, err := Deny(dir, commandsFile)
+	if err != nil {
+		t.Fatalf("Deny failed: %v", err)
+	}
+	if removed != 2 || Allowed(dir, key) || Allowed(dir, changed) {
+		t.Errorf("Deny removed %d approvals, want both", removed)
+	}
+	if removed, err := Deny(filepath.Join(t.TempDir(), "missing"), commandsFile); removed != 0 || err != nil {
+		t.Errorf("Deny on a missing directory = %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package watch runs functions when files change, for @watch-files blocks and `on change`
+// triggers.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long changes settle before a Watcher runs its function
+const DefaultDebounce = 300 * time.Millisecond
+
+// Watcher runs a function when files matching its patterns change. It subscribes to file
+// system events in the directories files matching the patterns can be in, rather than
+// checking the files every so often, so large trees cost nothing while they don't change.
+// Changes in quick succession are coalesced: once events have settled for the debounce
+// period the matching files are compared by size and modification time with those of the
+// last run, and the function runs if they differ. Changes made while it runs lead to a single
+// further run after it returns.
+type Watcher struct {
+	Dir      string   // Directory relative patterns are matched in
+	Patterns []string // Glob patterns, where ** matches any number of directories
+	Debounce time.Duration
+	// Glob returns the paths matching a pattern in a directory; filepath.Glob in Dir if nil
+	Glob func(dir, pattern string) ([]string, error)
+}
+
+// fileState is what a Watcher compares to notice that a file changed
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watch watches the files until ctx is cancelled, calling run after each settled change.
+// It fails when a pattern is invalid or the directories can't be watched.
+func (w *Watcher) Watch(ctx context.Context, run func()) error {
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	files, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch files: %w", err)
+	}
+	defer func() { _ = events.Close() }()
+	watched := make(map[string]bool)
+	if err := w.watchDirs(events, watched); err != nil {
+		return err
+	}
+
+	var due <-chan time.Time // Fires once events have settled, nil when none are pending
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				// The watch of a removed directory goes with it
+				delete(watched, event.Name)
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			due = time.After(debounce)
+		case _, ok := <-events.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been lost, as when the queue overflows, so compare the files
+			due = time.After(debounce)
+		case <-due:
+			due = nil
+			// Directories created since are watched from now on; the snapshot finds files
+			// already created in them
+			if err := w.watchDirs(events, watched); err != nil {
+				return err
+			}
+			current, err := w.snapshot()
+			if err != nil {
+				return err
+			}
+			if !sameFiles(files, current) {
+				files = current
+				run()
+			}
+		}
+	}
+}
+
+// watchDirs adds the directories of the patterns that aren't watched yet to events
+func (w *Watcher) watchDirs(events *fsnotify.Watcher, watched map[string]bool) error {
+	for _, pattern := range w.Patterns {
+		for _, dir := range Dirs(w.Dir, pattern) {
+			if watched[dir] {
+				continue
+			}
+			if err := events.Add(dir); err != nil {
+				// Directories removed since they were listed are noticed by their parent
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			watched[dir] = true
+		}
+	}
+	return nil
+}
+
+// snapshot returns the size and modification time of every file matching the patterns
+func (w *Watcher) snapshot() (map[string]fileState, error) {
+	glob := w.Glob
+	if glob == nil {
+		glob = func(dir, pattern string) ([]string, error) {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+			return filepath.Glob(pattern)
+		}
+	}
+
+	files := make(map[string]fileState)
+	for _, pattern := range w.Patterns {
+		matches, err := glob(w.Dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			path := match
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(w.Dir, path)
+			}
+			// Files removed since the glob ran drop out, which the next snapshot notices
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+			}
+		}
+	}
+	return files, nil
+}
+
+// sameFiles reports whether two snapshots have the same files in the same states
+func sameFiles(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		if other, ok := b[path]; !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Dirs returns the directories files matching pattern can be in, relative to dir unless the
+// pattern is absolute: the directory its leading segments without wildcards name, and those
+// below it its wildcards reach. As with @glob, wildcards don't enter directories starting
+// with a dot unless the pattern does. When the directory doesn't exist yet, Dirs returns its
+// nearest existing parent, whose events tell when it is created.
+func Dirs(dir, pattern string) []string {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	literal, recursive, dotted := 0, false, false
+	for i, segment := range segments {
+		if literal == i && segment != "**" && !strings.ContainsAny(segment, `*?[\`) {
+			literal++
+			continue
+		}
+		recursive = recursive || segment == "**"
+		dotted = dotted || strings.HasPrefix(segment, ".")
+	}
+
+	// depth is how many levels of directories below root the wildcards reach
+	base, depth := strings.Join(segments[:literal], "/"), len(segments)-literal-1
+	if literal == len(segments) {
+		// A pattern without wildcards names a file, in the directory above it
+		base, depth = strings.Join(segments[:literal-1], "/"), 0
+	}
+	if base == "" && literal > 0 && segments[0] == "" {
+		base = "/"
+	}
+	root := filepath.FromSlash(base)
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(dir, root)
+	}
+	if root == "" {
+		root = "."
+	}
+	for {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return nil
+		}
+		root, depth, recursive = parent, 0, false
+	}
+
+	dirs := []string{root}
+	if depth <= 0 && !recursive {
+		return dirs
+	}
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		// Unreadable directories are left out
+		if err != nil || p == root || !d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && !dotted {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, p)
+		if rel, err := filepath.Rel(root, p); err == nil && !recursive && len(strings.Split(filepath.ToSlash(rel), "/")) >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs
+}
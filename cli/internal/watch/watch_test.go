@@ -0,0 +1,143 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatcher_DebouncesAndCoalescesChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "api.proto")
+	if err := os.WriteFile(file, []byte("v0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	watcher := &Watcher{Dir: dir, Patterns: []string{"*.proto", "*.missing"}, Debounce: 50 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Watch(ctx, func() {
+			if runs.Add(1) == 1 {
+				<-release
+			}
+		})
+	}()
+
+	waitFor := func(want int32) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); runs.Load() < want; {
+			if time.Now().After(deadline) {
+				t.Fatalf("runs = %d, want %d", runs.Load(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// A burst of writes settles into one run
+	for i := 1; i <= 3; i++ {
+		if err := os.WriteFile(file, []byte(strings.Repeat("v", i+2)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitFor(1)
+
+	// Changes while the first run is going lead to exactly one more run
+	for _, name := range []string{"a.proto", "b.proto"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("new"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	waitFor(2)
+	time.Sleep(150 * time.Millisecond)
+	if got := runs.Load(); got != 2 {
+		t.Errorf("runs = %d, want 2", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch failed: %v", err)
+	}
+}
+
+func TestWatcher_InvalidPattern(t *testing.T) {
+	watcher := &Watcher{Dir: t.TempDir(), Patterns: []string{"[broken"}}
+	if err := watcher.Watch(context.Background(), func() {}); err == nil {
+		t.Error("Watch succeeded with an invalid pattern")
+	}
+}
+
+func TestWatcher_NoticesNewDirectories(t *testing.T) {
+	dir := t.TempDir()
+	runs := make(chan struct{}, 10)
+	watcher := &Watcher{Dir: dir, Patterns: []string{"src/**/*.go"}, Debounce: 20 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = watcher.Watch(ctx, func() { runs <- struct{}{} }) }()
+	time.Sleep(50 * time.Millisecond) // Let the watches start
+
+	// src doesn't exist when watching starts
+	if err := os.MkdirAll(filepath.Join(dir, "src", "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "api", "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no run after a file was created in a new directory")
+	}
+
+	// Once created, the new directories are watched themselves
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "src", "api", "main.go"), []byte("package main // changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no run after a file in a new directory changed")
+	}
+}
+
+func TestDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"src/api/v1", "src/.cache", "docs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := func(names ...string) string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+		return strings.Join(paths, ",")
+	}
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"go.mod", join("")},
+		{"*.go", join("")},
+		{"src/*/*.go", join("src", "src/api")},
+		{"src/**/*.go", join("src", "src/api", "src/api/v1")},
+		{"src/.cache/*", join("src/.cache")},
+		{"build/out/*.js", join("")}, // build doesn't exist yet
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Dirs(dir, tt.pattern), ","); got != tt.want {
+			t.Errorf("Dirs(%q) = %s, want %s", tt.pattern, got, tt.want)
+		}
+	}
+}
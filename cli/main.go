@@ -1,18 +1,43 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
-	_ "github.com/aledsdavies/devcmd/cli/internal/builtins" // Import for decorator registration
+	builtins "github.com/aledsdavies/devcmd/cli/internal/builtins" // Also registers the builtin decorators
+	"github.com/aledsdavies/devcmd/cli/internal/check"
+	"github.com/aledsdavies/devcmd/cli/internal/completion"
+	"github.com/aledsdavies/devcmd/cli/internal/daemon"
 	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/lexer"
+	"github.com/aledsdavies/devcmd/cli/internal/palette"
 	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+	"github.com/aledsdavies/devcmd/cli/internal/release"
+	"github.com/aledsdavies/devcmd/cli/internal/settings"
+	"github.com/aledsdavies/devcmd/cli/internal/suggest"
+	"github.com/aledsdavies/devcmd/cli/internal/watch"
 	"github.com/aledsdavies/devcmd/core/ast"
 	"github.com/aledsdavies/devcmd/core/errors"
+	"github.com/aledsdavies/devcmd/runtime/config"
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+	"github.com/aledsdavies/devcmd/runtime/messages"
 	"github.com/spf13/cobra"
 )
 
@@ -25,69 +50,170 @@ var (
 
 // Global flags
 var (
-	commandsFile string
-	templateFile string
-	binaryName   string
-	output       string
-	debug        bool
-	outputDir    string
-	generateOnly bool
-	dryRun       bool
-	noColor      bool
+	commandsFile  string
+	templateFile  string
+	binaryName    string
+	output        string
+	debug         bool
+	outputDir     string
+	generateOnly  bool
+	buildDefines  []string
+	genBackend    string
+	buildVendor   bool
+	buildOffline  bool
+	reportSize    bool
+	dryRun        bool
+	simulate      bool
+	estimates     []string
+	noColor       bool
+	accessible    bool
+	noOpen        bool
+	keepGoing     bool
+	failOn        string
+	runOutput     string
+	runReports    []string
+	onlySteps     []string
+	skipSteps     []string
+	runForce      bool
+	runProfile    string
+	runVars       []string
+	runParams     []string
+	runSandbox    bool
+	sandboxWrite  []string
+	noNetwork     bool
+	runHeartbeat  time.Duration
+	runJobs       int
+	runDetach     bool
+	planApprove   bool
+	autoApprove   bool
+	planOut       string
+	appliedPlan   *engine.SavedPlan // The plan devcmd apply runs
+	waitTimeout   time.Duration
+	waitAny       bool
+	waitAll       bool
+	settingsFile  string
+	logLevel      string
+	logFormat     string
+	serveAddr     string
+	serveMetrics  string
+	serveReload   time.Duration
+	psAll         bool
+	psProject     string
+	psStop        bool
+	psHistory     bool
+	daemonDetach  bool
+	daemonMetrics string
+	envAll        bool
+	envFormat     string
+	envProfiles   []string
+	checkFormat   string
+	doctorList    bool
+	graphFormat   string
+	graphTimes    []string
+	lexDebug      bool
+	lexFormat     string
+	parseAST      bool
+	parseFormat   string
+	releaseBump   string
+	releaseLog    string
+	releaseWrite  bool
+	releaseCheck  bool
+	releaseTag    bool
+	benchRuns     int
+	benchWarmup   int
+	benchFile     string
+	benchLimit    int
+	benchSave     bool
 )
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		formatAndPrintError(err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// exitCode returns the exit code an error carries in its "exit_code" context, such as that of
+// a background command devcmd wait waited for, or 1
+func exitCode(err error) int {
+	if devErr, ok := err.(*errors.DevCmdError); ok {
+		if code, exists := devErr.GetContext("exit_code"); exists {
+			if code, ok := code.(int); ok && code > 0 && code < 256 {
+				return code
+			}
+		}
+	}
+	return 1
+}
+
+// marker returns the symbol that marks a line of output, or its status word in accessible
+// output, where screen readers would read the symbol's name or nothing
+func marker(symbol, word string) string {
+	if logging.Accessible() {
+		return word
+	}
+	return symbol
+}
+
 // formatAndPrintError formats and prints errors in a user-friendly way
 func formatAndPrintError(err error) {
+	// Errors may quote commands and their output, so the values @secret read are masked
+	stderr := logging.MaskWriter(os.Stderr)
 	if devErr, ok := err.(*errors.DevCmdError); ok {
 		// Handle structured DevCmd errors
 		switch devErr.GetType() {
 		case errors.ErrCommandNotFound:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
+			if candidates, exists := devErr.GetContext("candidates"); exists {
+				if candidateList, ok := candidates.([]string); ok && len(candidateList) > 0 {
+					fmt.Fprintf(stderr, "%s %s\n", marker("💡", "Hint:"), messages.Sprintf(messages.HintCouldBe, strings.Join(candidateList, ", ")))
+				}
+			}
+			if suggestions, exists := devErr.GetContext("suggestions"); exists {
+				if suggestionList, ok := suggestions.([]string); ok && len(suggestionList) > 0 {
+					fmt.Fprintf(stderr, "%s %s\n", marker("💡", "Hint:"), messages.Sprintf(messages.HintDidYouMean, strings.Join(suggestionList, ", ")))
+				}
+			}
 			if commands, exists := devErr.GetContext("available_commands"); exists {
 				if cmdList, ok := commands.([]string); ok && len(cmdList) > 0 {
-					fmt.Fprintf(os.Stderr, "💡 Available commands: %v\n", cmdList)
+					fmt.Fprintf(stderr, "%s %s\n", marker("💡", "Hint:"), messages.Sprintf(messages.HintAvailable, cmdList))
 				}
 			}
 		case errors.ErrNoCommandsDefined:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
-			fmt.Fprintf(os.Stderr, "💡 Create a commands.cli file or pipe command definitions to stdin\n")
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("💡", "Hint:"), messages.Get(messages.HintNoCommands))
 		case errors.ErrCommandExecution:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
 			if details, exists := devErr.GetContext("error_details"); exists {
-				fmt.Fprintf(os.Stderr, "   Details: %v\n", details)
+				fmt.Fprintf(stderr, "   %s\n", messages.Sprintf(messages.ErrorDetails, details))
+			} else if devErr.Cause != nil {
+				fmt.Fprintf(stderr, "   %s\n", messages.Sprintf(messages.ErrorCause, devErr.Cause))
 			}
 		case errors.ErrVariableNotFound:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
 			if varName, exists := devErr.GetContext("variable"); exists {
-				fmt.Fprintf(os.Stderr, "💡 Make sure the variable '%s' is defined before using it\n", varName)
+				fmt.Fprintf(stderr, "%s %s\n", marker("💡", "Hint:"), messages.Sprintf(messages.HintDefineVar, varName))
 			}
 		case errors.ErrInputRead:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
 			if devErr.Cause != nil {
-				fmt.Fprintf(os.Stderr, "   Cause: %v\n", devErr.Cause)
+				fmt.Fprintf(stderr, "   %s\n", messages.Sprintf(messages.ErrorCause, devErr.Cause))
 			}
 		case errors.ErrFileParse:
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
 			if devErr.Cause != nil {
-				fmt.Fprintf(os.Stderr, "   Parse error: %v\n", devErr.Cause)
+				fmt.Fprintf(stderr, "   %s\n", messages.Sprintf(messages.ErrorParse, devErr.Cause))
 			}
 		default:
 			// Generic structured error
-			fmt.Fprintf(os.Stderr, "❌ %s\n", devErr.Message)
+			fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), devErr.Message)
 			if devErr.Cause != nil {
-				fmt.Fprintf(os.Stderr, "   Cause: %v\n", devErr.Cause)
+				fmt.Fprintf(stderr, "   %s\n", messages.Sprintf(messages.ErrorCause, devErr.Cause))
 			}
 		}
 	} else {
 		// Handle regular errors
-		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		fmt.Fprintf(stderr, "%s %s\n", marker("❌", "Error:"), messages.Sprintf(messages.ErrorPrefix, err))
 	}
 }
 
@@ -116,310 +242,2724 @@ func getInputReader() (io.Reader, func() error, error) {
 	return file, closeFunc, nil
 }
 
-var rootCmd = &cobra.Command{
-	Use:   "devcmd [flags]",
-	Short: "Generate Go CLI applications from command definitions",
-	Long: `devcmd generates standalone Go CLI executables from simple command definition files.
-It reads .cli files containing command definitions and outputs Go source code or compiled binaries.
-By default, it looks for commands.cli in the current directory.`,
-	Args:          cobra.NoArgs,
-	RunE:          generateCommand,
-	SilenceUsage:  true, // Don't show usage on execution errors
-	SilenceErrors: true, // Don't let Cobra print errors, we'll handle them
-}
-
-var buildCmd = &cobra.Command{
-	Use:   "build [flags]",
-	Short: "Build CLI binary from command definitions",
-	Long: `Build a compiled Go CLI binary from command definitions.
-This generates the Go source code and compiles it into an executable binary.
-By default, it looks for commands.cli in the current directory.`,
-	Args:         cobra.NoArgs,
-	RunE:         buildCommand,
-	SilenceUsage: true, // Don't show usage on execution errors
-}
+// parseCommands parses the command definitions, merging in the local override file next to
+// the commands file (e.g. commands.local.cli) when there is one
+func parseCommands(reader io.Reader) (*ast.Program, *parser.LocalOverrides, error) {
+	program, err := parser.Parse(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Piped definitions have no file for a local override to sit next to
+	if reader == os.Stdin {
+		return program, nil, parser.CheckOverrides(program, "<stdin>")
+	}
 
-var runCmd = &cobra.Command{
-	Use:   "run <command> [args...]",
-	Short: "Run a command directly from command definitions",
-	Long: `Execute a command directly from the CLI file without compilation.
-This interprets and runs the command immediately, useful for development and testing.
-By default, it looks for commands.cli in the current directory.`,
-	Args:         cobra.MinimumNArgs(1),
-	RunE:         runCommand,
-	SilenceUsage: true, // Don't show usage on execution errors
-}
+	localFile := parser.LocalFileName(commandsFile)
+	file, err := os.Open(localFile)
+	if os.IsNotExist(err) {
+		return program, nil, parser.CheckOverrides(program, commandsFile)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening file %s: %w", localFile, err)
+	}
+	defer func() { _ = file.Close() }()
 
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Show version information",
-	Long:  "Display version, build time, and git commit information for devcmd.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("devcmd %s\n", Version)
-		fmt.Printf("Built: %s\n", BuildTime)
-		fmt.Printf("Commit: %s\n", GitCommit)
-	},
+	local, err := parser.Parse(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", localFile, err)
+	}
+	return parser.MergeLocal(program, local, commandsFile, localFile)
 }
 
-func init() {
-	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&commandsFile, "file", "f", "commands.cli", "Path to commands file")
-	rootCmd.PersistentFlags().StringVar(&templateFile, "template", "", "Custom template file for generation")
-	rootCmd.PersistentFlags().StringVar(&binaryName, "binary", "dev", "Binary name for the generated CLI")
-	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
-	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "Directory to write generated files (default: stdout for main.go only)")
+// configChain resolves the defaults devcmd runs with from its flags, the environment, the
+// project's defaults settings and the user config, set up before each command runs
+var configChain config.Chain
 
-	// Add version flag support
-	var showVersion bool
-	rootCmd.PersistentFlags().BoolVar(&showVersion, "version", false, "Show version information")
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
-		if showVersion {
-			fmt.Printf("devcmd %s\n", Version)
-			fmt.Printf("Built: %s\n", BuildTime)
-			fmt.Printf("Commit: %s\n", GitCommit)
-			os.Exit(0)
+// loadConfigChain sets up the config chain for cmd, with the flags it was given. A settings
+// file that can't be read adds no defaults, as the commands that read settings report it.
+func loadConfigChain(cmd *cobra.Command) (config.Chain, error) {
+	user, err := config.LoadUser()
+	if err != nil {
+		return config.Chain{}, err
+	}
+	// The chain sees the environment as it was before devcmd exports the state directory
+	env := make(map[string]string, len(config.Keys))
+	for _, key := range config.Keys {
+		env[key.EnvVar] = os.Getenv(key.EnvVar)
+	}
+	chain := config.Chain{
+		Flags:  make(map[string]string),
+		Getenv: func(name string) string { return env[name] },
+		User:   user,
+	}
+	path := settingsFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(commandsFile), settings.DefaultFileName)
+	}
+	if s, err := settings.Load(path); err == nil {
+		if chain.Project, err = config.ProjectDefaults(s.Section(config.SettingsSection)); err != nil {
+			return config.Chain{}, fmt.Errorf("%s: %w", path, err)
 		}
 	}
 
-	// Build command specific flags
-	buildCmd.Flags().StringVarP(&output, "output", "o", "", "Output binary path (default: ./<binary-name>)")
-	buildCmd.Flags().BoolVar(&generateOnly, "generate-only", false, "Generate code only without building binary")
-
-	// Run command specific flags
-	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show execution plan without running commands")
-	runCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output in dry-run mode")
-
-	// Add subcommands
-	rootCmd.AddCommand(buildCmd)
-	rootCmd.AddCommand(runCmd)
-	rootCmd.AddCommand(versionCmd)
+	flags := cmd.Flags()
+	if flags.Changed("log-level") {
+		chain.Flags[config.LogLevel.Name] = logLevel
+	} else if debug {
+		chain.Flags[config.LogLevel.Name] = "debug"
+	}
+	if flags.Changed("no-color") {
+		chain.Flags[config.Color.Name] = strconv.FormatBool(!noColor)
+	}
+	if (cmd == runCmd || cmd == planCmd) && flags.Changed("profile") {
+		chain.Flags[config.Profile.Name] = runProfile
+	}
+	return chain, nil
 }
 
-func generateCommand(cmd *cobra.Command, args []string) error {
-	// Get input reader (file or stdin)
-	reader, closeFunc, err := getInputReader()
-	if err != nil {
-		return err
+// defaultProfile returns the profile the config selects when none is given, if the program's
+// env blocks or the profiles section of the settings define it, as projects differ in theirs
+func defaultProfile(program *ast.Program, s *settings.Settings) string {
+	name, source, err := configChain.Lookup(config.Profile)
+	if err != nil || name == "" {
+		return ""
 	}
-	defer func() {
-		if closeErr := closeFunc(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
-		}
-	}()
-
-	// Parse the command definitions
-	program, err := parser.Parse(reader)
-	if err != nil {
-		return fmt.Errorf("error parsing commands: %w", err)
+	if program.EnvProfile(name) == nil && len(s.Section("profiles."+name)) == 0 {
+		logging.Debugf("config", "ignoring profile %s from the %s, which the project doesn't define", name, source)
+		return ""
 	}
+	return name
+}
 
-	// Generate Go output using the engine
-	eng := engine.New(program)
-	genResult, err := eng.GenerateCode(program)
+// loadSettings loads the project settings from --settings or next to the commands file
+func loadSettings() (*settings.Settings, error) {
+	var s *settings.Settings
+	var err error
+	if settingsFile != "" {
+		if _, err := os.Stat(settingsFile); err != nil {
+			return nil, fmt.Errorf("error opening settings file %s: %w", settingsFile, err)
+		}
+		s, err = settings.Load(settingsFile)
+	} else {
+		s, err = settings.LoadForCommandsFile(commandsFile)
+	}
 	if err != nil {
-		return fmt.Errorf("error generating Go output: %w", err)
+		return nil, err
 	}
+	if err := registerMessageCatalogs(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
 
-	// If output directory specified, write files there
-	if outputDir != "" {
-		moduleName := strings.ReplaceAll(binaryName, "-", "_")
-		if err := eng.WriteFiles(genResult, outputDir, moduleName); err != nil {
-			return fmt.Errorf("error writing files: %w", err)
+// registerMessageCatalogs registers the project's message catalogs, by locale in the
+// `messages` section, for devcmd's output and the CLIs it generates:
+//
+//	messages {
+//	    de = "locales/de.json"
+//	    pt-BR = "locales/pt-BR.json"
+//	}
+func registerMessageCatalogs(s *settings.Settings) error {
+	for locale, file := range s.Section("messages") {
+		// Relative to the commands file, like devcmd.settings itself
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(commandsFile), file)
 		}
-
-		if debug {
-			fmt.Fprintf(os.Stderr, "✅ Generated main.go and go.mod in %s\n", outputDir)
+		if err := messages.LoadFile(locale, file); err != nil {
+			return fmt.Errorf("messages.%s: %w", locale, err)
 		}
-	} else {
-		// Default behavior: output main.go to stdout
-		fmt.Print(genResult.String())
 	}
-
 	return nil
 }
 
-func buildCommand(cmd *cobra.Command, args []string) error {
-	// Get input reader (file or stdin)
-	reader, closeFunc, err := getInputReader()
+// cliOptionsFromSettings reads command dispatch options from the `cli` settings section,
+// and the container images @requires falls back to from the `containers` section:
+//
+//	cli {
+//	    abbreviations = true
+//	    regenerate = true
+//	    aliases { b = "build" }
+//	}
+//	containers { terraform = "hashicorp/terraform:1.9" }
+//
+// whether shell steps run in strict mode (set -eu and pipefail) from the top-level
+// `strictShell` setting,
+//
+//	strictShell = true
+//
+// how the devcmd daemon restarts the background processes of watch commands, in the
+// `daemon` section,
+//
+//	daemon { restart = "always" }
+//
+// how long shell steps may write no output before reporting that they are still running,
+// for every command and per command in the `heartbeat` section ("0" turns it off),
+//
+//	heartbeat {
+//	    interval = "1m"
+//	    commands { e2e = "5m"; seed = "0" }
+//	}
+//
+// the shell hooks of the `hooks` section, which generated CLIs run like devcmd run does,
+//
+//	hooks { postRun = "./notify.sh" }
+//
+// the order background processes stop in and how long each has to exit, per watch command
+// in the `services` section,
+//
+//	services {
+//	    api { dependsOn = "db, cache"; stopTimeout = "10s" }
+//	}
+//
+// and where @secret reads from in the `secrets` section: a sops-encrypted file, the OS
+// keyring under a service name (default "devcmd"), or Vault:
+//
+//	secrets { file = "secrets.yaml" }
+//	secrets { provider = "keyring"; service = "myproject" }
+//	secrets {
+//	    provider = "vault"
+//	    vault { address = "https://vault.example.com"; role = "ci" }
+//	}
+//
+// Profiles are read separately, by profileFromSettings, when one is selected.
+func cliOptionsFromSettings(s *settings.Settings) (engine.CLIOptions, error) {
+	abbreviations, err := s.Bool("cli.abbreviations", false)
 	if err != nil {
-		return err
+		return engine.CLIOptions{}, err
 	}
-	defer func() {
-		if closeErr := closeFunc(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
-		}
-	}()
-
-	program, err := parser.Parse(reader)
+	regenerate, err := s.Bool("cli.regenerate", false)
 	if err != nil {
-		return fmt.Errorf("error parsing commands: %w", err)
+		return engine.CLIOptions{}, err
 	}
-
-	// Generate Go source code using the engine
-	eng := engine.New(program)
-	genResult, err := eng.GenerateCode(program)
+	strictShell, err := s.Bool("strictShell", false)
 	if err != nil {
-		return fmt.Errorf("error generating Go source: %w", err)
+		return engine.CLIOptions{}, err
 	}
-
-	// Determine output path
-	outputPath := output
-	if outputPath == "" {
-		outputPath = "./" + binaryName
+	restart := s.String("daemon.restart", daemon.DefaultRestart)
+	if err := daemon.ValidateRestart(restart); err != nil {
+		return engine.CLIOptions{}, fmt.Errorf("daemon.restart: %w", err)
 	}
-
-	// Make output path absolute
-	if !filepath.IsAbs(outputPath) {
-		wd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("error getting working directory: %w", err)
-		}
-		outputPath = filepath.Join(wd, outputPath)
+	services, err := servicesFromSettings(s)
+	if err != nil {
+		return engine.CLIOptions{}, err
 	}
-
-	// Create temporary directory for build
-	tempDir, err := os.MkdirTemp("", "devcmd-build-*")
+	heartbeat, heartbeats, err := heartbeatsFromSettings(s)
 	if err != nil {
-		return fmt.Errorf("error creating temp directory: %w", err)
+		return engine.CLIOptions{}, err
 	}
-	defer func() {
-		if removeErr := os.RemoveAll(tempDir); removeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to remove temp directory: %v\n", removeErr)
+	var defaultEnv map[string]string
+	for tool, image := range s.Section("containers") {
+		if defaultEnv == nil {
+			defaultEnv = make(map[string]string)
 		}
-	}()
-
-	// Handle generate-only mode
-	if generateOnly {
-		if outputDir != "" {
-			// Create output directory if it doesn't exist
-			if err := os.MkdirAll(outputDir, 0o755); err != nil {
-				return fmt.Errorf("error creating output directory: %w", err)
-			}
-			// Write files to specified directory
-			moduleName := strings.ReplaceAll(binaryName, "-", "_")
-			if err := eng.WriteFiles(genResult, outputDir, moduleName); err != nil {
-				return fmt.Errorf("error writing source files: %w", err)
-			}
-			if debug {
-				fmt.Fprintf(os.Stderr, "✅ Generated files written to: %s\n", outputDir)
-			}
-		} else {
-			// Output main.go to stdout
-			fmt.Print(genResult.Code.String())
+		defaultEnv[builtins.ContainerImageEnvVar(tool)] = image
+	}
+	if file := s.String("secrets.file", ""); file != "" {
+		if defaultEnv == nil {
+			defaultEnv = make(map[string]string)
 		}
-		return nil
+		// Relative to the commands file, like devcmd.settings itself
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(commandsFile), file)
+		}
+		defaultEnv[builtins.SecretsFileEnvVar] = file
 	}
-
-	// Use engine to write both files to temp directory
-	moduleName := strings.ReplaceAll(binaryName, "-", "_")
-	if err := eng.WriteFiles(genResult, tempDir, moduleName); err != nil {
-		return fmt.Errorf("error writing source files: %w", err)
+	for key, envVar := range map[string]string{
+		"secrets.provider":        builtins.SecretsProviderEnvVar,
+		"secrets.service":         builtins.KeyringServiceEnvVar,
+		"secrets.vault.address":   builtins.VaultAddrEnvVar,
+		"secrets.vault.namespace": builtins.VaultNamespaceEnvVar,
+		"secrets.vault.mount":     builtins.VaultMountEnvVar,
+		"secrets.vault.role":      builtins.VaultRoleEnvVar,
+		"secrets.vault.auth_path": builtins.VaultAuthPathEnvVar,
+		"secrets.vault.audience":  builtins.VaultAudienceEnvVar,
+	} {
+		if value := s.String(key, ""); value != "" {
+			if defaultEnv == nil {
+				defaultEnv = make(map[string]string)
+			}
+			defaultEnv[envVar] = value
+		}
 	}
-
-	// Run go mod tidy to generate go.sum and download dependencies
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = tempDir
-	tidyCmd.Stderr = os.Stderr
-	if debug {
-		fmt.Fprintf(os.Stderr, "Running go mod tidy...\n")
-		tidyCmd.Stdout = os.Stderr
+	if provider := defaultEnv[builtins.SecretsProviderEnvVar]; provider != "" {
+		if err := builtins.ValidateSecretProvider(provider); err != nil {
+			return engine.CLIOptions{}, fmt.Errorf("secrets.provider: %w", err)
+		}
 	}
-	if err := tidyCmd.Run(); err != nil {
-		return fmt.Errorf("error running go mod tidy: %w", err)
+	defaults, err := config.ProjectDefaults(s.Section(config.SettingsSection))
+	if err != nil {
+		return engine.CLIOptions{}, err
 	}
+	return engine.CLIOptions{
+		Defaults:      defaults,
+		Abbreviations: abbreviations,
+		Aliases:       s.Section("cli.aliases"),
+		DefaultEnv:    defaultEnv,
+		Regenerate:    regenerate,
+		StrictShell:   strictShell,
+		Restart:       restart,
+		Services:      services,
+		Heartbeat:     heartbeat,
+		Heartbeats:    heartbeats,
+		Hooks:         s.Section("hooks"),
+	}, nil
+}
 
-	// Build the binary
-	buildCmd := exec.Command("go", "build", "-o", outputPath, ".")
-	buildCmd.Dir = tempDir
-	buildCmd.Stderr = os.Stderr
+// simulationOptions are what --simulate estimates step durations from
+type simulationOptions struct {
+	estimates map[string]time.Duration
+	history   map[string]engine.DurationStats
+}
 
-	if debug {
-		fmt.Fprintf(os.Stderr, "Building binary: %s\n", outputPath)
-		buildCmd.Stdout = os.Stderr
+// simulationFromSettings reads the duration history of the project's commands and the
+// durations annotated per command in the `estimates` section, which --estimate overrides:
+//
+//	estimates { e2e = "8m"; seed = "30s" }
+func simulationFromSettings(eng *engine.Engine, s *settings.Settings) (*simulationOptions, error) {
+	var values []string
+	for name, value := range s.Section("estimates") {
+		values = append(values, name+"="+value)
 	}
-
-	if err := buildCmd.Run(); err != nil {
-		return fmt.Errorf("error building binary: %w", err)
+	fromSettings, err := engine.ParseEstimates(values)
+	if err != nil {
+		return nil, fmt.Errorf("estimates: %w", err)
 	}
-
-	if debug {
-		fmt.Fprintf(os.Stderr, "✅ Successfully built: %s\n", outputPath)
+	fromFlags, err := engine.ParseEstimates(estimates)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
-}
-
-func runCommand(cmd *cobra.Command, args []string) error {
-	commandName := args[0]
-
-	// Get input reader (file or stdin)
-	reader, closeFunc, err := getInputReader()
+	for name, duration := range fromFlags {
+		fromSettings[name] = duration
+	}
+	history, err := engine.LoadDurations(engine.DurationsFile(eng.ProcessNamespace()))
 	if err != nil {
-		return errors.NewInputError("Failed to read command definitions", err)
+		return nil, err
 	}
-	defer func() {
-		if closeErr := closeFunc(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
-		}
-	}()
+	return &simulationOptions{estimates: fromSettings, history: history}, nil
+}
 
-	program, err := parser.Parse(reader)
+// heartbeatsFromSettings reads the heartbeat interval of every command, and those of
+// commands that override it, from the `heartbeat` section
+func heartbeatsFromSettings(s *settings.Settings) (time.Duration, map[string]time.Duration, error) {
+	interval, err := s.Duration("heartbeat.interval", 0)
 	if err != nil {
-		return errors.NewParseError("Failed to parse command definitions", err)
+		return 0, nil, err
+	}
+	if interval < 0 {
+		return 0, nil, fmt.Errorf("heartbeat.interval: must not be negative, got %s", interval)
 	}
 
-	// Find the command to execute
-	var targetCommand *ast.CommandDecl
-	for i := range program.Commands {
-		if program.Commands[i].Name == commandName {
-			targetCommand = &program.Commands[i]
-			break
+	var intervals map[string]time.Duration
+	for name := range s.Section("heartbeat.commands") {
+		commandInterval, err := s.Duration("heartbeat.commands."+name, 0)
+		if err != nil {
+			return 0, nil, err
+		}
+		if commandInterval < 0 {
+			return 0, nil, fmt.Errorf("heartbeat.commands.%s: must not be negative, got %s", name, commandInterval)
 		}
+		if intervals == nil {
+			intervals = make(map[string]time.Duration)
+		}
+		intervals[name] = commandInterval
 	}
+	return interval, intervals, nil
+}
 
-	if targetCommand == nil {
-		// List available commands
-		var availableCommands []string
-		for _, command := range program.Commands {
-			availableCommands = append(availableCommands, command.Name)
+// servicesFromSettings reads the stop order and grace period of each watch command from
+// the `services` section
+func servicesFromSettings(s *settings.Settings) (map[string]processes.Service, error) {
+	var services map[string]processes.Service
+	for _, key := range s.Keys() {
+		rest, ok := strings.CutPrefix(key, "services.")
+		if !ok {
+			continue
+		}
+		name, _, ok := strings.Cut(rest, ".")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a section per watch command, e.g. services { %s { dependsOn = \"db\" } }", key, name)
+		}
+		if _, seen := services[name]; seen {
+			continue
+		}
+		var service processes.Service
+		for field, value := range s.Section("services." + name) {
+			switch field {
+			case "dependsOn":
+				for _, dependency := range strings.Split(value, ",") {
+					if dependency = strings.TrimSpace(dependency); dependency != "" {
+						service.DependsOn = append(service.DependsOn, dependency)
+					}
+				}
+			case "stopTimeout":
+				timeout, err := s.Duration("services."+name+".stopTimeout", 0)
+				if err != nil {
+					return nil, err
+				}
+				service.StopTimeout = timeout
+			default:
+				return nil, fmt.Errorf("services.%s.%s: unknown setting (expected dependsOn or stopTimeout)", name, field)
+			}
 		}
-		if len(availableCommands) == 0 {
-			return errors.New(errors.ErrNoCommandsDefined, fmt.Sprintf("Command '%s' not found: no commands are defined in the file", commandName)).
-				WithContext("command", commandName)
+		if services == nil {
+			services = make(map[string]processes.Service)
 		}
-		return errors.NewCommandNotFoundError(commandName, availableCommands)
+		services[name] = service
 	}
+	return services, nil
+}
 
-	// Use the engine to execute the specific command
-	eng := engine.New(program)
-
-	if dryRun {
-		// Execute in plan mode to show execution plan
-		plan, err := eng.ExecuteCommandPlan(targetCommand)
-		if err != nil {
-			return errors.NewCommandExecutionError(commandName, err)
+// profileFromSettings reads the environment values of a profile from the `profiles` section:
+//
+//	profiles {
+//	    prod { API_URL = "https://api.example.com"; LOG_LEVEL = "warn" }
+//	}
+//
+// A profile the program defines with an env block needs no settings section.
+func profileFromSettings(program *ast.Program, s *settings.Settings, name string) (engine.EnvProfile, error) {
+	env := s.Section("profiles." + name)
+	if len(env) == 0 && program.EnvProfile(name) == nil {
+		var available []string
+		for _, profile := range program.EnvProfiles {
+			available = append(available, profile.Name)
 		}
-
-		// Print the plan using the plan DSL's beautiful ASCII tree visualization
-		if noColor {
-			fmt.Print(plan.StringNoColor())
-		} else {
-			fmt.Print(plan.String())
+		for _, key := range s.Keys() {
+			if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+				if profile, _, ok := strings.Cut(rest, "."); ok && !containsString(available, profile) {
+					available = append(available, profile)
+				}
+			}
 		}
-		return nil
+		if len(available) == 0 {
+			return engine.EnvProfile{}, fmt.Errorf("unknown profile %q: no env blocks or profiles settings section define any profiles", name)
+		}
+		return engine.EnvProfile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
 	}
+	return engine.EnvProfile{Name: name, Env: env}, nil
+}
 
-	// Execute the specific command normally
-	cmdResult, err := eng.ExecuteCommand(targetCommand)
-	if err != nil {
-		return errors.NewCommandExecutionError(commandName, err)
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
+}
 
-	if cmdResult.Status == "failed" {
-		return errors.New(errors.ErrCommandExecution, fmt.Sprintf("Command '%s' failed: %s", commandName, cmdResult.Error)).
-			WithContext("command", commandName).
-			WithContext("error_details", cmdResult.Error)
+// applyVariableOverrides replaces the values of the variables set with --var NAME=value
+func applyVariableOverrides(program *ast.Program, overrides []string) error {
+	for _, override := range overrides {
+		name, value, ok := strings.Cut(override, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("%q: expected NAME=value", override)
+		}
+		found := false
+		replace := func(variables []ast.VariableDecl) {
+			for i := range variables {
+				if variables[i].Name == name {
+					variables[i].Value = &ast.StringLiteral{Value: value, Pos: variables[i].Value.Position()}
+					found = true
+				}
+			}
+		}
+		replace(program.Variables)
+		for i := range program.VarGroups {
+			replace(program.VarGroups[i].Variables)
+		}
+		if !found {
+			return fmt.Errorf("no variable %s is defined", name)
+		}
+	}
+	return nil
+}
+
+// parseParamValues parses the parameter values set with --param name=value
+func parseParamValues(values []string) (map[string]string, error) {
+	params := make(map[string]string, len(values))
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q: expected name=value", value)
+		}
+		params[name] = v
+	}
+	return params, nil
+}
+
+// binarySizeReport attributes the size of a built CLI to the features of its program, using
+// the symbol sizes go tool nm reports and the dependencies go list finds in the build directory
+func binarySizeReport(eng *engine.Engine, program *ast.Program, buildDir, binary string) (*engine.SizeReport, error) {
+	info, err := os.Stat(binary)
+	if err != nil {
+		return nil, err
+	}
+	features, err := eng.Features(program)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []string
+	for _, feature := range features {
+		for _, pkg := range feature.Imports {
+			if !containsString(imports, pkg) {
+				imports = append(imports, pkg)
+			}
+		}
+	}
+	list := exec.Command("go", append([]string{"list", "-f", "{{.ImportPath}}{{range .Deps}} {{.}}{{end}}"}, imports...)...)
+	list.Dir = buildDir
+	list.Stderr = os.Stderr
+	listed, err := list.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+	deps := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(listed)), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			deps[fields[0]] = fields[1:]
+		}
+	}
+
+	nm := exec.Command("go", "tool", "nm", "-size", binary)
+	nm.Stderr = os.Stderr
+	symbols, err := nm.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go tool nm: %w", err)
+	}
+	sizes, err := engine.ParseSymbolSizes(bytes.NewReader(symbols))
+	if err != nil {
+		return nil, err
+	}
+	return engine.NewSizeReport(info.Size(), features, deps, sizes), nil
+}
+
+// backendNames lists the registered code generation backends for --backend
+func backendNames() string {
+	var names []string
+	for _, backend := range engine.Backends() {
+		names = append(names, backend.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseDefines reads the NAME=value pairs of devcmd build --define
+func parseDefines(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	defines := make(map[string]string, len(values))
+	for _, value := range values {
+		name, definition, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q: expected NAME=value", value)
+		}
+		defines[name] = definition
+	}
+	return defines, nil
+}
+
+// sourceFileName returns the commands file path for CI annotations, or "" when reading stdin
+func sourceFileName(reader io.Reader) string {
+	if reader == os.Stdin {
+		return ""
+	}
+	return filepath.ToSlash(filepath.Clean(commandsFile))
+}
+
+// newGeneratorEngine creates an engine configured with project settings for code generation,
+// for a program specialized with defines
+func newGeneratorEngine(program *ast.Program, sourceFile string, defines map[string]string) (*engine.Engine, error) {
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return nil, errors.NewInputError("Failed to load project settings", err)
+	}
+	opts, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return nil, errors.NewInputError("Invalid cli settings", err)
+	}
+	opts.Defines = defines
+
+	eng := engine.New(program)
+	eng.SetCLIOptions(opts)
+	eng.SetSourceFile(sourceFile)
+	if sourceFile != "" {
+		// Generated CLIs compare this with the file on disk to warn when they are stale
+		source, err := commandsSource()
+		if err != nil {
+			return nil, errors.NewInputError("Failed to read command definitions", err)
+		}
+		eng.SetSourceHash(source)
+	}
+	return eng, nil
+}
+
+// commandsSource returns the SourceHash of the commands file, followed by its local override
+// file when there is one
+func commandsSource() (string, error) {
+	source, err := os.ReadFile(commandsFile)
+	if err != nil {
+		return "", err
+	}
+	if local, err := os.ReadFile(parser.LocalFileName(commandsFile)); err == nil {
+		source = append(source, local...)
+	}
+	return engine.SourceHash(source), nil
+}
+
+// resolveCommandName maps aliases and, when enabled, unambiguous prefixes to a command name
+func resolveCommandName(name string, available []string, opts engine.CLIOptions) (string, error) {
+	for _, candidate := range available {
+		if candidate == name {
+			return name, nil
+		}
+	}
+
+	if target, ok := opts.Aliases[name]; ok {
+		return target, nil
+	}
+
+	if opts.Abbreviations {
+		matches := suggest.PrefixMatches(name, available)
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+		if len(matches) > 1 {
+			return "", errors.New(errors.ErrCommandNotFound, fmt.Sprintf("Command '%s' is ambiguous", name)).
+				WithContext("command", name).
+				WithContext("candidates", matches)
+		}
+	}
+
+	return name, nil
+}
+
+var rootCmd = &cobra.Command{
+	Use:   "devcmd [flags]",
+	Short: "Generate Go CLI applications from command definitions",
+	Long: `devcmd generates standalone Go CLI executables from simple command definition files.
+It reads .cli files containing command definitions and outputs Go source code or compiled binaries.
+By default, it looks for commands.cli in the current directory.
+Run without arguments in a terminal, it lets you search for a command to run instead.`,
+	Args:          cobra.NoArgs,
+	RunE:          generateCommand,
+	SilenceUsage:  true, // Don't show usage on execution errors
+	SilenceErrors: true, // Don't let Cobra print errors, we'll handle them
+}
+
+var buildCmd = &cobra.Command{
+	Use:   "build [flags]",
+	Short: "Build CLI binary from command definitions",
+	Long: `Build a compiled Go CLI binary from command definitions.
+This generates the Go source code and compiles it into an executable binary.
+With --define NAME=value, @when blocks on NAME are resolved while building, so the
+binary only contains the branches that value selects. --report-size shows what the
+binary's size comes from.
+By default, it looks for commands.cli in the current directory.`,
+	Args:         cobra.NoArgs,
+	RunE:         buildCommand,
+	SilenceUsage: true, // Don't show usage on execution errors
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run <command> [command...] [-- args...]",
+	Short: "Run commands directly from command definitions",
+	Long: `Execute commands directly from the CLI file without compilation.
+This interprets and runs the commands immediately, in order or up to --jobs at once, useful for development and testing.
+Arguments after -- are given to the command's parameters without a default, in order, as the
+generated CLI takes them: devcmd run deploy -- staging.
+Runs with several commands or steps end with a summary of each step's status and duration.
+With --dry-run --simulate, the plans are followed by how long each command is expected to take
+and its critical path, from the durations of earlier runs and --estimate annotations.
+By default, it looks for commands.cli in the current directory.`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runCommand,
+	SilenceUsage: true, // Don't show usage on execution errors
+}
+
+var psCmd = &cobra.Command{
+	Use:   "ps [flags]",
+	Short: "List background processes started by generated CLIs",
+	Long: `List the background processes that watch commands of generated CLIs and devcmd run
+--detach have started, with their PID, status, allocated ports and log file. Each project records its processes in its
+own namespace of a registry in the user cache directory, so --all lists the processes of
+every project on the machine. By default only the project of the commands file is listed.
+--stop terminates the listed processes; it doesn't run custom stop commands. Processes the
+devcmd daemon supervises are marked as such, with how often they were restarted. --history
+shows when processes started, exited and were stopped instead.`,
+	Args:         cobra.NoArgs,
+	RunE:         psCommand,
+	SilenceUsage: true,
+}
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <command> [command...]",
+	Short: "Wait for background commands to finish and exit with their exit code",
+	Long: `Wait for commands of the project running in the background, started with devcmd run
+--detach or as watch commands, then exit with their outcome: successfully if they succeeded,
+and with the exit code, error and log file of the first that failed otherwise. --any returns
+once one of them finishes, with its outcome, and --all, the default, once all have. A command
+that has already finished reports how it finished straight away; processes that didn't record
+how they finished, such as watch processes, count as failed.`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         waitCommand,
+	SilenceUsage: true,
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon [flags]",
+	Short: "Supervise the background processes of generated CLIs",
+	Long: `Run a supervisor that owns the background processes watch commands start. While it runs,
+generated CLIs hand their watch commands to it over a socket in the process registry instead
+of running them themselves. The daemon appends their output to their log files, restarts them
+as the daemon.restart setting says (on-failure, always or never; on-failure by default), and
+keeps them running after the terminal that started them closes. devcmd daemon watch hands it
+the on change triggers of the commands file as well. --detach starts the daemon in the
+background. Stopping the daemon stops its processes. --metrics-addr serves Prometheus metrics
+of the supervised processes: each run, its duration and whether it failed, and which are up.`,
+	Args:         cobra.NoArgs,
+	RunE:         daemonCommand,
+	SilenceUsage: true,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:          "stop",
+	Short:        "Stop the daemon and the processes it supervises",
+	Args:         cobra.NoArgs,
+	RunE:         daemonStopCommand,
+	SilenceUsage: true,
+}
+
+var daemonWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Run the project's on change triggers under the daemon",
+	Long: `Hand the on change triggers of the commands file to the devcmd daemon, which runs each
+trigger when files matching its patterns change, once they have settled for its debounce period
+(300ms by default). Changes made while a trigger runs lead to one more run after it finishes.
+Each trigger is a background process named change-1, change-2, ... in the order they are
+written, which devcmd ps lists with its log file. Running it again replaces the project's
+triggers with those of the commands file as it is now; devcmd daemon unwatch stops them.`,
+	Args:         cobra.NoArgs,
+	RunE:         daemonWatchCommand,
+	SilenceUsage: true,
+}
+
+var daemonUnwatchCmd = &cobra.Command{
+	Use:          "unwatch",
+	Short:        "Stop the project's on change triggers",
+	Args:         cobra.NoArgs,
+	RunE:         daemonUnwatchCommand,
+	SilenceUsage: true,
+}
+
+// daemonTriggerCmd is the process the daemon supervises for an on change trigger
+var daemonTriggerCmd = &cobra.Command{
+	Use:          "trigger <n>",
+	Short:        "Watch the files of the nth on change trigger and run it when they change",
+	Args:         cobra.ExactArgs(1),
+	RunE:         daemonTriggerCommand,
+	Hidden:       true,
+	SilenceUsage: true,
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the defaults devcmd and generated CLIs run with, and where each comes from",
+	Long: `Show each default of the config and the source it comes from. A flag wins over an
+environment variable, which wins over the defaults section of devcmd.settings, which wins over
+the user config file, ~/.config/devcmd/config.toml ($XDG_CONFIG_HOME or $DEVCMD_CONFIG):
+
+  color      --no-color     DEVCMD_COLOR      defaults { color = false }
+  log_level  --log-level    DEVCMD_LOG_LEVEL  defaults { logLevel = "warn" }
+  profile    --profile      DEVCMD_PROFILE    defaults { profile = "staging" }
+  state_dir                 DEVCMD_STATE_DIR  (user config only)
+  trust_ci                  DEVCMD_TRUST_CI   (user config only)
+
+A profile the config selects applies only in projects that define it.`,
+	Args:         cobra.NoArgs,
+	RunE:         configCommand,
+	SilenceUsage: true,
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env <command> [flags]",
+	Short: "Show the environment a command runs with",
+	Long: `Print the resolved environment a command would run with: the devcmd variables it reads,
+the environment variables it reads with @env, including in the commands it runs with @cmd,
+and the defaults devcmd.settings provides. Each value is shown with where it comes from:
+the environment, a profile, settings, or an @env default. --all also lists every variable
+inherited from the environment. --profile applies a profile, the values its env block gives
+variables and its environment from the profiles section of devcmd.settings, as devcmd run
+--profile does.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         envCommand,
+	SilenceUsage: true,
+}
+
+var envDiffCmd = &cobra.Command{
+	Use:   "diff <command> --profile <name> [--profile <name>]",
+	Short: "Compare the environment of a command between profiles",
+	Long: `Show the variables whose values differ between the environment a command runs with
+and the one it runs with under a profile, or between two profiles when --profile is given
+twice. Added variables are marked +, removed ones - and changed ones ~.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         envDiffCommand,
+	SilenceUsage: true,
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <command> [flags]",
+	Short: "Explain what a command does",
+	Long: `Print a breakdown of a command: its description from the # comment lines above it, the
+variables it reads, the decorators it uses with the value of each parameter (with defaults
+for those not given), the commands it runs with @cmd, the tools and environment variables it
+needs, and its execution plan.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         explainCommand,
+	SilenceUsage: true,
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <command> [command...] [flags]",
+	Short: "Time repeated runs of commands",
+	Long: `Run each command --warmup times untimed and --runs times timed, and report the
+minimum, mean and 95th percentile durations. Results are compared with the means in the
+baseline file (devcmd.bench.json next to the commands file by default), exiting non-zero
+when a command is more than --threshold percent slower. --save records the results as the
+new baseline.`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         benchCommand,
+	SilenceUsage: true,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available commands and variables",
+	Long: `List the commands and variables defined in the commands file, with the first line of the
+comment directly above each command. Items that come from the local override file next to it
+(e.g. commands.local.cli) are marked [local].`,
+	Args:         cobra.NoArgs,
+	RunE:         listCommand,
+	SilenceUsage: true,
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [flags]",
+	Short: "Validate command definitions without running them",
+	Long: `Parse and validate command definitions, run the linter, and resolve decorators,
+imports, and variables without executing or building anything. The linter also scans the
+shell text of each command for syntax errors and pipelines that hide failures.
+Exits non-zero when errors are found, making it suitable as a fast CI gate.`,
+	Args:         cobra.NoArgs,
+	RunE:         checkCommand,
+	SilenceUsage: true, // Don't show usage on execution errors
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [flags]",
+	Short: "Show the dependency graph of commands",
+	Long: `Print the graph of commands that run other commands with @cmd, as an ASCII tree,
+Graphviz DOT, or JSON. Commands nothing depends on are listed as roots, and commands with
+no dependencies or dependents as orphans. Given run summaries recorded with
+devcmd run --output=json, the critical path through the slowest commands is highlighted.
+Exits non-zero when the graph has a cycle.`,
+	Args:         cobra.NoArgs,
+	RunE:         graphCommand,
+	SilenceUsage: true, // Don't show usage on execution errors
+}
+
+var lexCmd = &cobra.Command{
+	Use:   "lex [file]",
+	Short: "Print the tokens of a commands file",
+	Long: `Print the token stream of a commands file (the --file one by default) with the span of
+each token. With --debug, each token also shows how reading it changed the lexer's state: its
+mode (language, command, shell or pattern), brace and parenthesis nesting, and the quoting of
+shell text, which decide where shell text ends. Attach the output to bug reports about how a
+file is tokenized.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         lexCommand,
+	SilenceUsage: true,
+}
+
+var parseCmd = &cobra.Command{
+	Use:   "parse [file]",
+	Short: "Parse a commands file and print its syntax tree",
+	Long: `Parse a commands file (the --file one by default), exiting non-zero on a syntax error.
+With --ast, print the parsed syntax tree as an indented tree or as JSON, with the line and
+column where each node starts. The output starts with its format version (# devcmd ast v4,
+or "version" in JSON), which changes whenever the layout or the nodes do, so tests and tools
+can rely on it. Attach the output to bug reports about how a file is parsed.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         parseCommand,
+	SilenceUsage: true,
+}
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [flags]",
+	Short: "Compute the next version, update the changelog, and tag",
+	Long: `Compute the next semantic version from the latest git tag and the conventional
+commits since it (feat: minor, breaking changes: major, anything else: patch).
+Prints the version and a changelog section by default; --write prepends the section
+to the changelog, --check validates that the changelog already has one, and --tag
+creates an annotated tag for the new version.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         releaseCommand,
+}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage @secret values in the OS keyring",
+	Long: `Store, show, and remove the secrets @secret reads with the keyring provider, in the
+macOS Keychain, libsecret (GNOME Keyring, KWallet), or the Windows Credential Manager.
+Secrets are stored under the service set by secrets.service in devcmd.settings, or "devcmd".`,
+	Args: cobra.NoArgs,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret in the keyring",
+	Long: `Store a secret in the keyring, replacing any existing value. The value is read from
+stdin, prompting without echo when stdin is a terminal, so it never appears in shell history.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         secretSetCommand,
+	SilenceUsage: true,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:          "get <name>",
+	Short:        "Print a secret from the keyring",
+	Args:         cobra.ExactArgs(1),
+	RunE:         secretGetCommand,
+	SilenceUsage: true,
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:          "rm <name>",
+	Short:        "Remove a secret from the keyring",
+	Args:         cobra.ExactArgs(1),
+	RunE:         secretRmCommand,
+	SilenceUsage: true,
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags]",
+	Short: "Check devcmd's own setup",
+	Long: `Check that devcmd itself is consistent: that every decorator in the registry the parser,
+engine and generator share is registered once, under its own name, with schemas it can satisfy.
+--decorators lists each decorator with its type, version, the modes and platforms it supports,
+and its capabilities.`,
+	Args:         cobra.NoArgs,
+	RunE:         doctorCommand,
+	SilenceUsage: true,
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version information",
+	Long:  "Display version, build time, and git commit information for devcmd.",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("devcmd %s\n", Version)
+		fmt.Printf("Built: %s\n", BuildTime)
+		fmt.Printf("Commit: %s\n", GitCommit)
+	},
+}
+
+func init() {
+	// Help headings come from the messages, in the locale
+	cobra.AddTemplateFunc("msg", engine.UsageMessage)
+	rootCmd.SetUsageTemplate(engine.UsageTemplate)
+
+	// Global flags
+	rootCmd.PersistentFlags().StringVarP(&commandsFile, "file", "f", "commands.cli", "Path to commands file")
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template", "", "Custom template file for generation")
+	rootCmd.PersistentFlags().StringVar(&binaryName, "binary", "dev", "Binary name for the generated CLI")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
+	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "Directory to write generated files (default: stdout for main.go only)")
+	rootCmd.PersistentFlags().StringVar(&settingsFile, "settings", "", "Path to project settings file (default: devcmd.settings next to the commands file)")
+
+	// Add version flag support
+	var showVersion bool
+	rootCmd.PersistentFlags().BoolVar(&showVersion, "version", false, "Show version information")
+	rootCmd.Flags().StringVar(&genBackend, "backend", engine.GoBackend, "Backend to generate with ("+backendNames()+")")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Lowest level of diagnostics to write: debug, info, warn or error (--debug implies debug)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of diagnostics on stderr: text or json")
+	rootCmd.PersistentFlags().BoolVar(&accessible, "accessible", false, "Screen reader friendly output: plain lines with status words, without colors, spinners or box drawing (or "+logging.AccessibleEnvVar+"=1)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if showVersion {
+			fmt.Printf("devcmd %s\n", Version)
+			fmt.Printf("Built: %s\n", BuildTime)
+			fmt.Printf("Commit: %s\n", GitCommit)
+			os.Exit(0)
+		}
+		if accessible {
+			logging.SetAccessible(true)
+		}
+		chain, err := loadConfigChain(cmd)
+		if err != nil {
+			return err
+		}
+		configChain = chain
+		level, _, err := configChain.Lookup(config.LogLevel)
+		if err != nil {
+			return err
+		}
+		if level == "" {
+			level = logLevel
+		}
+		if color, _, err := configChain.Lookup(config.Color); err != nil {
+			return err
+		} else if color == "false" {
+			noColor = true
+		}
+		// The commands devcmd runs, generated CLIs among them, keep their state where it does
+		if dir, _, err := configChain.Lookup(config.StateDir); err != nil {
+			return err
+		} else if dir != "" {
+			os.Setenv(config.StateDir.EnvVar, dir)
+		}
+		return logging.Configure(level, logFormat)
+	}
+
+	// Build command specific flags
+	buildCmd.Flags().StringVarP(&output, "output", "o", "", "Output binary path (default: ./<binary-name>)")
+	buildCmd.Flags().BoolVar(&generateOnly, "generate-only", false, "Generate code only without building binary")
+	buildCmd.Flags().BoolVar(&reportSize, "report-size", false, "Report what the binary's size comes from, by decorator and subsystem, with suggestions for making it smaller")
+	buildCmd.Flags().StringArrayVar(&buildDefines, "define", nil, "Fix a variable at build time as NAME=value, leaving other @when branches out of the binary (repeatable)")
+	buildCmd.Flags().BoolVar(&buildVendor, "vendor", false, "Vendor the CLI's dependencies into the generated module (with --generate-only --output-dir)")
+	buildCmd.Flags().BoolVar(&buildOffline, "offline", false, "Resolve dependencies from the local module cache only, without downloading")
+
+	// Run command specific flags
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show execution plan without running commands")
+	runCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output in dry-run mode")
+	runCmd.Flags().BoolVar(&simulate, "simulate", false, "With --dry-run, estimate each command's wall-clock time and critical path from its history")
+	runCmd.Flags().StringArrayVar(&estimates, "estimate", nil, "Annotate the duration of a step or command for --simulate as text=duration, e.g. lint=20s (repeatable)")
+	runCmd.Flags().BoolVar(&planApprove, "plan-approve", false, "Show the plan and ask for approval, then run the commands unless their plans have changed")
+	runCmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Approve the plan of --plan-approve without asking, as in CI")
+	runCmd.Flags().BoolVar(&noOpen, "no-open", false, "Don't open URLs in a browser (for headless environments)")
+	runCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Keep running the remaining commands after one fails")
+	runCmd.Flags().BoolVar(&runDetach, "detach", false, "Run the command in the background, recorded in the process registry with its log, for devcmd wait")
+	runCmd.Flags().IntVarP(&runJobs, "jobs", "j", 1, "Run up to this many commands at once, after the commands they run with @cmd (0 uses every CPU)")
+	runCmd.Flags().StringVar(&failOn, "fail-on", "any", "Exit non-zero when any, all, or none of the commands fail")
+	runCmd.Flags().StringVar(&runOutput, "output", "text", "Run summary format: text or json")
+	runCmd.Flags().StringArrayVar(&runReports, "report", nil, "Write a run report as format:path, e.g. junit:report.xml (repeatable)")
+	runCmd.Flags().StringSliceVar(&onlySteps, "only", nil, "Run only the steps that lead to these @cmd commands, and those commands in full")
+	runCmd.Flags().StringSliceVar(&skipSteps, "skip", nil, "Skip the steps that run these @cmd commands")
+	runCmd.Flags().BoolVar(&runForce, "force", false, "Restart watch commands that are already running")
+	runCmd.Flags().StringVar(&runProfile, "profile", "", "Apply a profile: the variable values of its env block and its profiles settings section environment")
+	runCmd.Flags().StringArrayVar(&runVars, "var", nil, "Override a variable as NAME=value (repeatable)")
+	runCmd.Flags().StringArrayVar(&runParams, "param", nil, "Set a parameter the commands declare as name=value (repeatable)")
+	runCmd.Flags().BoolVar(&runSandbox, "sandbox", false, "Run every shell step in a sandbox that can only write to --sandbox-write paths")
+	runCmd.Flags().StringSliceVar(&sandboxWrite, "sandbox-write", []string{"."}, "Paths sandboxed steps may write to, besides the temporary directory (implies --sandbox)")
+	runCmd.Flags().BoolVar(&noNetwork, "no-network", false, "Run every shell step in the sandbox without network access (implies --sandbox)")
+	runCmd.Flags().DurationVar(&runHeartbeat, "heartbeat", 0, "Report shell steps that write no output for this long as still running, overriding the heartbeat settings (0 disables)")
+	runCmd.MarkFlagsMutuallyExclusive("plan-approve", "dry-run")
+	runCmd.MarkFlagsMutuallyExclusive("plan-approve", "detach")
+
+	// Plan command specific flags, sharing what they select with run
+	planCmd.Flags().StringVarP(&planOut, "out", "o", "", "File to save the plan to, for devcmd apply")
+	planCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output in the plan")
+	planCmd.Flags().StringVar(&runProfile, "profile", "", "Apply a profile: the variable values of its env block and its profiles settings section environment")
+	planCmd.Flags().StringArrayVar(&runVars, "var", nil, "Override a variable as NAME=value (repeatable)")
+	planCmd.Flags().StringArrayVar(&runParams, "param", nil, "Set a parameter the commands declare as name=value (repeatable)")
+	planCmd.Flags().StringSliceVar(&onlySteps, "only", nil, "Plan only the steps that lead to these @cmd commands, and those commands in full")
+	planCmd.Flags().StringSliceVar(&skipSteps, "skip", nil, "Skip the steps that run these @cmd commands")
+	_ = planCmd.MarkFlagRequired("out")
+
+	// Apply command specific flags
+	applyCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Keep running the remaining commands after one fails")
+	applyCmd.Flags().StringVar(&runOutput, "output", "text", "Run summary format: text or json")
+
+	// Wait command specific flags
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 0, "Give up waiting after this long (0 waits until the commands finish)")
+	waitCmd.Flags().BoolVar(&waitAny, "any", false, "Return once any of the commands finishes, with its outcome")
+	waitCmd.Flags().BoolVar(&waitAll, "all", false, "Return once every command has finished, failing if any failed (the default)")
+	waitCmd.MarkFlagsMutuallyExclusive("any", "all")
+
+	// Serve command specific flags
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:9090", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveMetrics, "metrics-addr", "127.0.0.1:9091", "Address to serve /metrics and /commands on when webhooks are configured")
+	serveCmd.Flags().DurationVar(&serveReload, "reload-interval", 2*time.Second, "How often to check the commands file for changes to reload (0 disables reloading)")
+
+	// Ps command specific flags
+	psCmd.Flags().BoolVar(&psAll, "all", false, "List the processes of every project")
+	psCmd.Flags().StringVar(&psProject, "project", "", "List the processes of a project, by directory, directory name or namespace")
+	psCmd.Flags().BoolVar(&psStop, "stop", false, "Stop the listed processes")
+	psCmd.Flags().BoolVar(&psHistory, "history", false, "Show when processes started, exited and were stopped")
+	psCmd.MarkFlagsMutuallyExclusive("all", "project")
+	psCmd.MarkFlagsMutuallyExclusive("history", "stop")
+
+	// Daemon command specific flags
+	daemonCmd.Flags().BoolVar(&daemonDetach, "detach", false, "Start the daemon in the background")
+	daemonCmd.Flags().StringVar(&daemonMetrics, "metrics-addr", "", "Address to serve Prometheus metrics of the supervised processes on (off by default)")
+
+	// Env command specific flags
+	envCmd.Flags().BoolVar(&envAll, "all", false, "Also list the variables inherited from the environment")
+	envCmd.Flags().StringVar(&envFormat, "format", "text", "Output format: text or json")
+	envCmd.Flags().StringArrayVar(&envProfiles, "profile", nil, "Apply a profile: the variable values of its env block and its profiles settings section environment")
+	envDiffCmd.Flags().StringVar(&envFormat, "format", "text", "Output format: text or json")
+	envDiffCmd.Flags().StringArrayVar(&envProfiles, "profile", nil, "Profile to compare with, or twice to compare two profiles")
+	_ = envDiffCmd.MarkFlagRequired("profile")
+
+	// Explain command specific flags
+	explainCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output in the plan")
+
+	// Bench command specific flags
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 10, "Number of timed runs of each command")
+	benchCmd.Flags().IntVar(&benchWarmup, "warmup", 1, "Untimed runs of each command before the timed ones")
+	benchCmd.Flags().StringVar(&benchFile, "baseline", "", "Baseline file (default: devcmd.bench.json next to the commands file)")
+	benchCmd.Flags().IntVar(&benchLimit, "threshold", 10, "Percent a command's mean may be slower than its baseline before failing")
+	benchCmd.Flags().BoolVar(&benchSave, "save", false, "Record the results as the new baseline")
+
+	// Check command specific flags
+	checkCmd.Flags().StringVar(&checkFormat, "format", "text", "Diagnostics output format: text, json, or sarif")
+	doctorCmd.Flags().BoolVar(&doctorList, "decorators", false, "List the registered decorators")
+
+	// Graph command specific flags
+	graphCmd.Flags().StringVar(&graphFormat, "format", "tree", "Graph output format: tree, dot, or json")
+	graphCmd.Flags().StringArrayVar(&graphTimes, "durations", nil, "Run summary from devcmd run --output=json to take command durations from (repeatable)")
+
+	// Lex command specific flags
+	lexCmd.Flags().BoolVar(&lexDebug, "debug", false, "Show the lexer state transitions of each token")
+	lexCmd.Flags().StringVar(&lexFormat, "format", "text", "Token output format: text or json")
+
+	// Parse command specific flags
+	parseCmd.Flags().BoolVar(&parseAST, "ast", false, "Print the parsed syntax tree")
+	parseCmd.Flags().StringVar(&parseFormat, "format", "text", "Syntax tree output format: text or json")
+
+	// Release command flags
+	releaseCmd.Flags().StringVar(&releaseBump, "bump", "auto", "Version part to bump: auto, major, minor, or patch")
+	releaseCmd.Flags().StringVar(&releaseLog, "changelog", "CHANGELOG.md", "Path to the changelog file")
+	releaseCmd.Flags().BoolVar(&releaseWrite, "write", false, "Prepend the generated section to the changelog")
+	releaseCmd.Flags().BoolVar(&releaseCheck, "check", false, "Fail unless the changelog has a section for the next version")
+	releaseCmd.Flags().BoolVar(&releaseTag, "tag", false, "Create an annotated git tag for the next version")
+	releaseCmd.MarkFlagsMutuallyExclusive("write", "check")
+
+	// Complete command names, variables and profiles from the project's files
+	runCmd.ValidArgsFunction = completeCommandNames
+	planCmd.ValidArgsFunction = completeCommandNames
+	waitCmd.ValidArgsFunction = completeCommandNames
+	envCmd.ValidArgsFunction = completeCommandNames
+	envDiffCmd.ValidArgsFunction = completeCommandNames
+	explainCmd.ValidArgsFunction = completeCommandNames
+	benchCmd.ValidArgsFunction = completeCommandNames
+	for _, flag := range []struct {
+		cmd  *cobra.Command
+		name string
+		fn   func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)
+	}{
+		{runCmd, "profile", completeProfiles},
+		{planCmd, "profile", completeProfiles},
+		{envCmd, "profile", completeProfiles},
+		{envDiffCmd, "profile", completeProfiles},
+		{runCmd, "var", completeVariables},
+		{planCmd, "var", completeVariables},
+		{runCmd, "only", completeCommandNames},
+		{runCmd, "skip", completeCommandNames},
+	} {
+		_ = flag.cmd.RegisterFlagCompletionFunc(flag.name, flag.fn)
+	}
+
+	// Add subcommands
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(psCmd)
+	rootCmd.AddCommand(waitCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+	daemonCmd.AddCommand(daemonWatchCmd)
+	daemonCmd.AddCommand(daemonUnwatchCmd)
+	daemonCmd.AddCommand(daemonTriggerCmd)
+	rootCmd.AddCommand(daemonCmd)
+	envCmd.AddCommand(envDiffCmd)
+	rootCmd.AddCommand(envCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(allowCmd)
+	rootCmd.AddCommand(denyCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(lexCmd)
+	rootCmd.AddCommand(parseCmd)
+	rootCmd.AddCommand(releaseCmd)
+	secretCmd.AddCommand(secretSetCmd, secretGetCmd, secretRmCmd)
+	rootCmd.AddCommand(secretCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(versionCmd)
+}
+
+func generateCommand(cmd *cobra.Command, args []string) error {
+	// In a terminal, where printing Go source is rarely wanted, pick a command to run instead
+	if outputDir == "" && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		return paletteCommand(cmd)
+	}
+
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	// Parse the command definitions
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return fmt.Errorf("error parsing commands: %w", err)
+	}
+
+	// Generate Go output using the engine
+	eng, err := newGeneratorEngine(program, sourceFileName(reader), nil)
+	if err != nil {
+		return err
+	}
+	genResult, err := eng.Generate(genBackend, program, "")
+	if err != nil {
+		return fmt.Errorf("error generating %s output: %w", genBackend, err)
+	}
+
+	// If output directory specified, write files there
+	if outputDir != "" {
+		moduleName := strings.ReplaceAll(binaryName, "-", "_")
+		if err := eng.WriteFiles(genResult, outputDir, moduleName); err != nil {
+			return fmt.Errorf("error writing files: %w", err)
+		}
+
+		if debug {
+			fmt.Fprintf(os.Stderr, "%s Generated %s in %s\n", marker("✅", "Done:"), genResult.FileName, outputDir)
+		}
+	} else {
+		// Default behavior: output main.go to stdout
+		fmt.Print(genResult.String())
+	}
+
+	return nil
+}
+
+// paletteCommand lets the user search for a command, prompts for the environment variables
+// it reads with @env that aren't set, and runs it
+func paletteCommand(cmd *cobra.Command) error {
+	file, err := os.Open(commandsFile)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	program, overrides, err := parseCommands(file)
+	_ = file.Close()
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+
+	source, _ := os.ReadFile(commandsFile)
+	localSource, _ := os.ReadFile(parser.LocalFileName(commandsFile))
+	var items []palette.Item
+	seen := make(map[string]bool)
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		// Watch and stop commands share a name; running it runs the watch command
+		if seen[command.Name] || command.Type == ast.StopCommand {
+			continue
+		}
+		seen[command.Name] = true
+		commandSource := source
+		if overrides.IsLocalCommand(command.Name) {
+			commandSource = localSource
+		}
+		items = append(items, palette.Item{Name: command.Name, Description: engine.CommandDescription(commandSource, command.Pos.Line)})
+	}
+	if len(items) == 0 {
+		return errors.New(errors.ErrCommandNotFound, fmt.Sprintf("No commands are defined in %s", commandsFile))
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	picked, err := palette.Pick(in, os.Stderr, items)
+	if err != nil {
+		if err == palette.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	eng := engine.New(program)
+	for i := range program.Commands {
+		command := &program.Commands[i]
+		if command.Name != picked.Name || command.Type == ast.StopCommand {
+			continue
+		}
+		env, err := eng.ResolveEnvironment(command, os.Environ(), engine.EnvProfile{}, false)
+		if err != nil {
+			return errors.NewCommandExecutionError(command.Name, err)
+		}
+		for _, variable := range env.Env {
+			if variable.Source != engine.SourceUnset && variable.Source != engine.SourceDefault {
+				continue
+			}
+			value, err := palette.Prompt(in, os.Stderr, variable.Name, variable.Value)
+			if err != nil {
+				if err == palette.ErrCancelled {
+					return nil
+				}
+				return err
+			}
+			if value != "" {
+				os.Setenv(variable.Name, value)
+			}
+		}
+		break
+	}
+
+	fmt.Fprintf(os.Stderr, "Running devcmd run %s\n", picked.Name)
+	return runCommand(cmd, []string{picked.Name})
+}
+
+// completionCandidates returns the names to complete from the project's files, or nil when
+// they can't be read, such as outside a project
+func completionCandidates() *completion.Candidates {
+	path := settingsFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(commandsFile), settings.DefaultFileName)
+	}
+	candidates, err := completion.Load(completion.DefaultCacheDir(), commandsFile, path)
+	if err != nil {
+		return nil
+	}
+	return candidates
+}
+
+// completeCommandNames completes the names of commands not already given, with descriptions
+func completeCommandNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if cmd.Args != nil && cmd.Args(cmd, append(args, toComplete)) != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates := completionCandidates()
+	if candidates == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, command := range candidates.Commands {
+		if containsString(args, command.Name) || !strings.HasPrefix(command.Name, toComplete) {
+			continue
+		}
+		if command.Description != "" {
+			names = append(names, command.Name+"\t"+command.Description)
+		} else {
+			names = append(names, command.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeVariables completes --var with NAME= for each variable, leaving the value to type
+func completeVariables(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	candidates := completionCandidates()
+	if candidates == nil || strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, name := range candidates.Variables {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name+"=")
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeProfiles completes the profiles of env blocks and the profiles settings section
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	candidates := completionCandidates()
+	if candidates == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, name := range candidates.Profiles {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func buildCommand(cmd *cobra.Command, args []string) error {
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return fmt.Errorf("error parsing commands: %w", err)
+	}
+	defines, err := parseDefines(buildDefines)
+	if err != nil {
+		return errors.NewInputError("Invalid --define value", err)
+	}
+	if program, err = engine.Specialize(program, defines); err != nil {
+		return errors.NewInputError("Invalid --define value", err)
+	}
+
+	// Generate Go source code using the engine
+	eng, err := newGeneratorEngine(program, sourceFileName(reader), defines)
+	if err != nil {
+		return err
+	}
+	genResult, err := eng.GenerateCode(program)
+	if err != nil {
+		return fmt.Errorf("error generating Go source: %w", err)
+	}
+
+	// Determine output path
+	outputPath := output
+	if outputPath == "" {
+		outputPath = "./" + binaryName
+	}
+
+	// Make output path absolute
+	if !filepath.IsAbs(outputPath) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("error getting working directory: %w", err)
+		}
+		outputPath = filepath.Join(wd, outputPath)
+	}
+
+	// Create temporary directory for build
+	tempDir, err := os.MkdirTemp("", "devcmd-build-*")
+	if err != nil {
+		return fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(tempDir); removeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove temp directory: %v\n", removeErr)
+		}
+	}()
+
+	moduleOpts := engine.ModuleOptions{Vendor: buildVendor, Offline: buildOffline}
+
+	// Handle generate-only mode
+	if generateOnly {
+		if reportSize {
+			return errors.NewInputError("--report-size needs a binary to measure", fmt.Errorf("it can't be used with --generate-only"))
+		}
+		if outputDir != "" {
+			// Create output directory if it doesn't exist
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("error creating output directory: %w", err)
+			}
+			// Write files to specified directory
+			moduleName := strings.ReplaceAll(binaryName, "-", "_")
+			if err := eng.WriteModule(genResult, outputDir, moduleName, moduleOpts); err != nil {
+				return fmt.Errorf("error writing module: %w", err)
+			}
+			if debug {
+				fmt.Fprintf(os.Stderr, "%s Generated files written to: %s\n", marker("✅", "Done:"), outputDir)
+			}
+		} else {
+			// Output main.go to stdout
+			fmt.Print(genResult.Code.String())
+		}
+		return nil
+	}
+
+	// Write the complete module, with go.sum and any vendor directory, to the temp directory
+	moduleName := strings.ReplaceAll(binaryName, "-", "_")
+	if debug {
+		fmt.Fprintf(os.Stderr, "Writing module and resolving dependencies...\n")
+	}
+	if err := eng.WriteModule(genResult, tempDir, moduleName, moduleOpts); err != nil {
+		return fmt.Errorf("error writing module: %w", err)
+	}
+
+	// Build the binary
+	buildCmd := exec.Command("go", "build", "-o", outputPath, ".")
+	buildCmd.Dir = tempDir
+	buildCmd.Env = moduleOpts.Env()
+	buildCmd.Stderr = os.Stderr
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "Building binary: %s\n", outputPath)
+		buildCmd.Stdout = os.Stderr
+	}
+
+	if err := buildCmd.Run(); err != nil {
+		return fmt.Errorf("error building binary: %w", err)
+	}
+
+	if reportSize {
+		report, err := binarySizeReport(eng, program, tempDir, outputPath)
+		if err != nil {
+			return fmt.Errorf("error reporting binary size: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s", filepath.Base(outputPath), report)
+	}
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "%s Successfully built: %s\n", marker("✅", "Done:"), outputPath)
+	}
+
+	return nil
+}
+
+// findCommand resolves a command name, alias, or prefix to its declaration
+func findCommand(program *ast.Program, name string, cliOptions engine.CLIOptions) (*ast.CommandDecl, error) {
+	var commandNames []string
+	for _, command := range program.Commands {
+		commandNames = append(commandNames, command.Name)
+	}
+	commandName, err := resolveCommandName(name, commandNames, cliOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range program.Commands {
+		if program.Commands[i].Name == commandName {
+			return &program.Commands[i], nil
+		}
+	}
+
+	// List available commands
+	if len(commandNames) == 0 {
+		return nil, errors.New(errors.ErrNoCommandsDefined, fmt.Sprintf("Command '%s' not found: no commands are defined in the file", commandName)).
+			WithContext("command", commandName)
+	}
+	return nil, errors.NewCommandNotFoundError(commandName, commandNames).
+		WithContext("suggestions", suggest.Suggest(commandName, commandNames, suggest.MaxSuggestions))
+}
+
+func psCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+
+	var namespaces []string
+	switch {
+	case psAll || psProject != "":
+		all, err := registry.Namespaces()
+		if err != nil {
+			return errors.NewInputError("Failed to read the process registry", err)
+		}
+		for _, namespace := range all {
+			if psAll || registry.Matches(namespace, psProject) {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	default:
+		namespaces = []string{processes.Namespace(filepath.Dir(commandsFile))}
+	}
+	if psHistory {
+		return printProcessHistory(registry, namespaces)
+	}
+
+	list, err := registry.List(namespaces...)
+	if err != nil {
+		return errors.NewInputError("Failed to read the process registry", err)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(os.Stderr, "No background processes")
+		return nil
+	}
+
+	// Processes the daemon supervises are stopped through it, so they aren't restarted
+	supervised := make(map[string]daemon.Status)
+	if response, err := daemon.Call(registry, daemon.Request{Op: daemon.OpList}); err == nil {
+		for _, status := range response.Processes {
+			supervised[status.Namespace+"/"+status.Name] = status
+		}
+	}
+
+	if psStop {
+		// Dependents stop first, so nothing loses a service it uses while still running
+		var failed []string
+		for _, process := range processes.OrderForStop(list) {
+			_, viaDaemon := supervised[process.Namespace+"/"+process.Name]
+			var err error
+			if viaDaemon {
+				_, err = daemon.Call(registry, daemon.Request{Op: daemon.OpStop, Namespace: process.Namespace, Name: process.Name})
+			} else {
+				err = registry.Stop(process)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s (%s): %v\n", marker("❌", "Error:"), process.Name, process.Project, err)
+				failed = append(failed, process.Name)
+				continue
+			}
+			switch {
+			case viaDaemon:
+				fmt.Printf("Stopped %s process under the devcmd daemon in %s\n", process.Name, process.Project)
+			case process.Running:
+				fmt.Printf("Stopped %s process (PID: %d) in %s\n", process.Name, process.PID, process.Project)
+			default:
+				fmt.Printf("Removed stale PID file of %s in %s\n", process.Name, process.Project)
+			}
+		}
+		if len(failed) > 0 {
+			return errors.New(errors.ErrSystemCommand, fmt.Sprintf("Failed to stop %s", strings.Join(failed, ", ")))
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tNAME\tPID\tSTATUS\tPORTS\tLOG")
+	for _, process := range list {
+		project := process.Project
+		if project == "" {
+			project = process.Namespace
+		}
+		status := "stopped"
+		switch process.Health() {
+		case processes.Healthy:
+			status = "running"
+		case processes.Unresponsive:
+			status = "unresponsive"
+		}
+		if process.Exited {
+			status = "finished"
+			if process.ExitCode != 0 {
+				status = fmt.Sprintf("failed (exit %d)", process.ExitCode)
+			}
+		}
+		if supervisor, ok := supervised[process.Namespace+"/"+process.Name]; ok {
+			if process.Running {
+				status = fmt.Sprintf("supervised (%d restarts)", supervisor.Restarts)
+			} else {
+				status = fmt.Sprintf("restarting (%d restarts)", supervisor.Restarts)
+			}
+		}
+		ports := "-"
+		if len(process.Ports) > 0 {
+			ports = strings.Join(process.Ports, ",")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", project, process.Name, process.PID, status, ports, process.LogFile)
+	}
+	return tw.Flush()
+}
+
+// printProcessHistory prints the history of the namespaces' processes, oldest first
+func printProcessHistory(registry processes.Registry, namespaces []string) error {
+	var events []processes.Event
+	for _, namespace := range namespaces {
+		history, err := registry.History(namespace)
+		if err != nil {
+			return errors.NewInputError("Failed to read the process history", err)
+		}
+		events = append(events, history...)
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(os.Stderr, "No process history")
+		return nil
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tNAME\tPID\tEVENT")
+	for _, event := range events {
+		description := event.Event
+		if event.Event == processes.EventExited {
+			description = fmt.Sprintf("exited (exit %d)", event.ExitCode)
+			if event.Error != "" {
+				description += ": " + event.Error
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", event.Time.Local().Format(time.DateTime), event.Name, event.PID, description)
+	}
+	return tw.Flush()
+}
+
+// detachCommand starts devcmd run again in the background for the command, with the same
+// flags but --detach, recorded in the project's namespace of the process registry
+func detachCommand(command *ast.CommandDecl) error {
+	if command.Type == ast.WatchCommand {
+		return errors.NewInputError("Invalid --detach", fmt.Errorf("%s is a watch command, which records its own background process", command.Name))
+	}
+	registry := processes.Default()
+	dir := filepath.Dir(commandsFile)
+	namespace := processes.Namespace(dir)
+	if existing, ok := registry.Lookup(namespace, command.Name); ok && existing.Running {
+		return errors.New(errors.ErrCommandExecution, fmt.Sprintf("%s is already running in the background (PID: %d); wait for it with devcmd wait %s", command.Name, existing.PID, command.Name))
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to find the devcmd executable", err)
+	}
+	arguments := []string{executable}
+	for _, arg := range os.Args[1:] {
+		if arg != "--detach" && !strings.HasPrefix(arg, "--detach=") {
+			arguments = append(arguments, arg)
+		}
+	}
+
+	if err := registry.Register(namespace, dir); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to register the process", err)
+	}
+	process, err := registry.StartDetached(namespace, command.Name, arguments)
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to start "+command.Name+" in the background", err)
+	}
+	fmt.Printf("Started %s in the background (PID: %d)\n", command.Name, process.PID)
+	fmt.Printf("Logs: %s\n", process.LogFile)
+	fmt.Printf("Wait for it with: devcmd wait %s\n", command.Name)
+	return nil
+}
+
+// recordDetachedExit records the outcome of a command started with --detach, named by
+// "<namespace>/<name>", in the process registry
+func recordDetachedExit(detached string, err error) {
+	namespace, name, _ := strings.Cut(detached, "/")
+	code, message := 0, ""
+	if err != nil {
+		code, message = processes.ExitCode(err), strings.Join(strings.Fields(err.Error()), " ")
+	}
+	if recordErr := processes.Default().RecordExit(namespace, name, code, message); recordErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record how %s finished: %v\n", name, recordErr)
+	}
+}
+
+func waitCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+	namespace := processes.Namespace(filepath.Dir(commandsFile))
+	waiting := make([]processes.Process, len(args))
+	for i, name := range args {
+		process, ok := registry.Lookup(namespace, name)
+		if !ok {
+			return errors.New(errors.ErrCommandNotFound, fmt.Sprintf("No background process named %s; start one with devcmd run %s --detach", name, name))
+		}
+		waiting[i] = process
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		index   int
+		process processes.Process
+		err     error
+	}
+	outcomes := make(chan outcome, len(waiting))
+	for i, process := range waiting {
+		if process.Running {
+			fmt.Fprintf(os.Stderr, "Waiting for %s (PID: %d); logs: %s\n", process.Name, process.PID, process.LogFile)
+		}
+		go func() {
+			process, err := registry.Wait(ctx, process)
+			outcomes <- outcome{index: i, process: process, err: err}
+		}()
+	}
+
+	// Each process is reported as it finishes; with --any the first one decides the outcome,
+	// otherwise the first failure in the order given
+	results := make([]error, len(waiting))
+	var stillRunning []string
+	for range waiting {
+		result := <-outcomes
+		if result.err != nil {
+			stillRunning = append(stillRunning, result.process.Name)
+			continue
+		}
+		results[result.index] = reportWaited(result.process)
+		if waitAny {
+			cancel()
+			return results[result.index]
+		}
+	}
+	if len(stillRunning) > 0 {
+		return errors.New(errors.ErrTimeout, fmt.Sprintf("Stopped waiting for %s, still running", strings.Join(stillRunning, ", ")))
+	}
+	for _, err := range results {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportWaited reports how a process that was waited for finished, returning an error with
+// its exit code when it failed or didn't record how it finished
+func reportWaited(process processes.Process) error {
+	switch {
+	case !process.Exited:
+		return errors.New(errors.ErrCommandExecution, fmt.Sprintf("%s stopped without recording how it finished, e.g. a watch process or one that was killed; see %s", process.Name, process.LogFile))
+	case process.ExitCode != 0:
+		return errors.New(errors.ErrCommandExecution, fmt.Sprintf("%s failed (exit %d): %s; see %s", process.Name, process.ExitCode, process.Error, process.LogFile)).
+			WithContext("exit_code", process.ExitCode)
+	default:
+		fmt.Printf("%s finished successfully\n", process.Name)
+		return nil
+	}
+}
+
+func daemonCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+	if daemon.Running(registry) {
+		return errors.New(errors.ErrSystemCommand, fmt.Sprintf("A devcmd daemon is already running on %s", daemon.SocketPath(registry)))
+	}
+
+	if daemonDetach {
+		executable, err := os.Executable()
+		if err != nil {
+			return errors.Wrap(errors.ErrSystemCommand, "Failed to find the devcmd executable", err)
+		}
+		command := []string{executable, "daemon"}
+		if daemonMetrics != "" {
+			command = append(command, "--metrics-addr", daemonMetrics)
+		}
+		pid, err := daemon.StartDetached(registry, command)
+		if err != nil {
+			return errors.Wrap(errors.ErrSystemCommand, "Failed to start the devcmd daemon", err)
+		}
+		fmt.Printf("Started devcmd daemon (PID: %d)\n", pid)
+		fmt.Printf("Logs: %s\n", filepath.Join(registry.Root, daemon.LogFile))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "devcmd daemon supervising background processes on %s\n", daemon.SocketPath(registry))
+	d := daemon.New(registry)
+	if daemonMetrics != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", d.Metrics)
+		go func() {
+			if err := listenAndServe(map[string]http.Handler{daemonMetrics: mux}); err != nil {
+				fmt.Fprintf(os.Stderr, "devcmd daemon: metrics: %v\n", err)
+			}
+		}()
+		fmt.Fprintf(os.Stderr, "devcmd daemon metrics at http://%s/metrics\n", daemonMetrics)
+	}
+	if err := d.Serve(ctx); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Daemon error", err)
+	}
+	return nil
+}
+
+func daemonStopCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+	if !daemon.Running(registry) {
+		fmt.Fprintln(os.Stderr, "No devcmd daemon is running")
+		return nil
+	}
+	if _, err := daemon.Call(registry, daemon.Request{Op: daemon.OpShutdown}); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to stop the devcmd daemon", err)
+	}
+	fmt.Println("Stopped devcmd daemon")
+	return nil
+}
+
+// changeTriggerPrefix starts the process names of on change triggers under the daemon
+const changeTriggerPrefix = "change-"
+
+// changeTriggers returns the on change triggers of a program, in the order they are written
+func changeTriggers(program *ast.Program) []*ast.TriggerDecl {
+	var triggers []*ast.TriggerDecl
+	for i := range program.Triggers {
+		if program.Triggers[i].Event == ast.TriggerOnChange {
+			triggers = append(triggers, &program.Triggers[i])
+		}
+	}
+	return triggers
+}
+
+// loadChangeTriggers reads the commands file, which must be approved to run, and its settings
+func loadChangeTriggers() (*ast.Program, *settings.Settings, error) {
+	file, err := os.Open(commandsFile)
+	if err != nil {
+		return nil, nil, errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	program, _, err := parseCommands(file)
+	if err != nil {
+		return nil, nil, errors.NewParseError("Failed to parse command definitions", err)
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return nil, nil, errors.NewInputError("Failed to load project settings", err)
+	}
+	if err := requireTrust(file, program, projectSettings); err != nil {
+		return nil, nil, err
+	}
+	return program, projectSettings, nil
+}
+
+// stopChangeTriggers stops the on change triggers the daemon runs for a project, returning
+// how many there were
+func stopChangeTriggers(registry processes.Registry, namespace string) (int, error) {
+	response, err := daemon.Call(registry, daemon.Request{Op: daemon.OpList})
+	if err != nil {
+		return 0, err
+	}
+	stopped := 0
+	for _, status := range response.Processes {
+		if status.Namespace != namespace || !strings.HasPrefix(status.Name, changeTriggerPrefix) {
+			continue
+		}
+		if _, err := daemon.Call(registry, daemon.Request{Op: daemon.OpStop, Namespace: namespace, Name: status.Name}); err != nil {
+			return stopped, err
+		}
+		stopped++
+	}
+	return stopped, nil
+}
+
+func daemonWatchCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+	if !daemon.Running(registry) {
+		return errors.New(errors.ErrSystemCommand, "No devcmd daemon is running; start one with devcmd daemon --detach")
+	}
+	program, _, err := loadChangeTriggers()
+	if err != nil {
+		return err
+	}
+
+	// The trigger processes read the same files from the project directory
+	source, err := filepath.Abs(commandsFile)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	dir := filepath.Dir(source)
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to find the devcmd executable", err)
+	}
+	arguments := []string{executable, "--file", source}
+	if settingsFile != "" {
+		absolute, err := filepath.Abs(settingsFile)
+		if err != nil {
+			return errors.NewInputError("Failed to load project settings", err)
+		}
+		arguments = append(arguments, "--settings", absolute)
+	}
+
+	namespace := processes.Namespace(dir)
+	if _, err := stopChangeTriggers(registry, namespace); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to stop the previous on change triggers", err)
+	}
+	triggers := changeTriggers(program)
+	if len(triggers) == 0 {
+		fmt.Printf("%s has no on change triggers\n", commandsFile)
+		return nil
+	}
+	for i, trigger := range triggers {
+		name := changeTriggerPrefix + strconv.Itoa(i+1)
+		response, err := daemon.Call(registry, daemon.Request{
+			Op:        daemon.OpStart,
+			Namespace: namespace,
+			Project:   dir,
+			Name:      name,
+			Command:   append(append([]string{}, arguments...), "daemon", "trigger", strconv.Itoa(i+1)),
+			Dir:       dir,
+			Env:       os.Environ(),
+			Restart:   daemon.RestartOnFailure,
+		})
+		if err != nil {
+			return errors.Wrap(errors.ErrSystemCommand, fmt.Sprintf("Failed to start %s", trigger.Name()), err)
+		}
+		fmt.Printf("Watching %s as %s (PID: %d, logs: %s)\n", trigger.Name(), name, response.PID, response.LogFile)
+	}
+	return nil
+}
+
+func daemonUnwatchCommand(cmd *cobra.Command, args []string) error {
+	registry := processes.Default()
+	if !daemon.Running(registry) {
+		fmt.Fprintln(os.Stderr, "No devcmd daemon is running")
+		return nil
+	}
+	dir, err := filepath.Abs(filepath.Dir(commandsFile))
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	stopped, err := stopChangeTriggers(registry, processes.Namespace(dir))
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to stop the on change triggers", err)
+	}
+	fmt.Printf("Stopped %d on change trigger(s)\n", stopped)
+	return nil
+}
+
+func daemonTriggerCommand(cmd *cobra.Command, args []string) error {
+	// Commands the trigger runs aren't the daemon's process themselves
+	os.Unsetenv(daemon.SupervisedEnvVar)
+
+	program, projectSettings, err := loadChangeTriggers()
+	if err != nil {
+		return err
+	}
+	triggers := changeTriggers(program)
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 1 || index > len(triggers) {
+		return errors.NewInputError("Invalid trigger", fmt.Errorf("%s has %d on change triggers, not %q", commandsFile, len(triggers), args[0]))
+	}
+	trigger := triggers[index-1]
+
+	cliOptions, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Invalid cli settings", err)
+	}
+	for name, value := range cliOptions.DefaultEnv {
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, value)
+		}
+	}
+	eng := engine.New(program)
+	eng.SetCLIOptions(cliOptions)
+	eng.SetSourceFile(filepath.ToSlash(filepath.Clean(commandsFile)))
+	if err := eng.RegisterShellHooks(projectSettings.Section("hooks")); err != nil {
+		return errors.NewInputError("Invalid hooks in project settings", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watcher := &watch.Watcher{
+		Dir:      filepath.Dir(commandsFile),
+		Patterns: trigger.Paths,
+		Debounce: trigger.Debounce,
+		Glob:     builtins.GlobFiles,
+	}
+	fmt.Printf("Watching %s\n", trigger.Name())
+	err = watcher.Watch(ctx, func() {
+		fmt.Printf("[%s] Files changed, running %s\n", time.Now().Format(time.TimeOnly), trigger.Name())
+		start := time.Now()
+		if err := eng.RunTrigger(trigger); err != nil {
+			fmt.Printf("[%s] Failed after %s: %v\n", time.Now().Format(time.TimeOnly), time.Since(start).Round(time.Millisecond), err)
+			return
+		}
+		fmt.Printf("[%s] Finished in %s\n", time.Now().Format(time.TimeOnly), time.Since(start).Round(time.Millisecond))
+	})
+	if err != nil {
+		return errors.NewInputError("Invalid on change trigger", err)
+	}
+	return nil
+}
+
+// loadCommandEnvironment parses the commands file and settings and returns an engine for
+// resolving the environment of the named command, and the command
+func loadCommandEnvironment(name string) (*engine.Engine, *ast.CommandDecl, *settings.Settings, error) {
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return nil, nil, nil, errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return nil, nil, nil, errors.NewParseError("Failed to parse command definitions", err)
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return nil, nil, nil, errors.NewInputError("Failed to load project settings", err)
+	}
+	cliOptions, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return nil, nil, nil, errors.NewInputError("Invalid cli settings", err)
+	}
+	command, err := findCommand(program, name, cliOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	eng := engine.New(program)
+	eng.SetCLIOptions(cliOptions)
+	return eng, command, projectSettings, nil
+}
+
+func configCommand(cmd *cobra.Command, args []string) error {
+	path := config.UserFile()
+	if path == "" {
+		path = "(no home directory)"
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		path += " (not found)"
+	}
+	fmt.Printf("User config: %s\n\n", path)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, key := range config.Keys {
+		value, source, err := configChain.Lookup(key)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			value = "-"
+		}
+		from := string(source)
+		if source == config.FromEnv {
+			from += " (" + key.EnvVar + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key.Name, value, from)
+	}
+	return tw.Flush()
+}
+
+func envCommand(cmd *cobra.Command, args []string) error {
+	if envFormat != "text" && envFormat != "json" {
+		return fmt.Errorf("unsupported format %q: expected text or json", envFormat)
+	}
+	if len(envProfiles) > 1 {
+		return errors.NewInputError("Invalid --profile value", fmt.Errorf("only one profile applies at a time; use devcmd env diff to compare two"))
+	}
+	eng, command, projectSettings, err := loadCommandEnvironment(args[0])
+	if err != nil {
+		return err
+	}
+	var profile engine.EnvProfile
+	if len(envProfiles) == 1 {
+		if profile, err = profileFromSettings(eng.Program(), projectSettings, envProfiles[0]); err != nil {
+			return errors.NewInputError("Invalid --profile value", err)
+		}
+	}
+
+	env, err := eng.ResolveEnvironment(command, os.Environ(), profile, envAll)
+	if err != nil {
+		return errors.NewCommandExecutionError(command.Name, err)
+	}
+	if envFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(env)
+	}
+	return env.WriteText(os.Stdout)
+}
+
+func envDiffCommand(cmd *cobra.Command, args []string) error {
+	if envFormat != "text" && envFormat != "json" {
+		return fmt.Errorf("unsupported format %q: expected text or json", envFormat)
+	}
+	if len(envProfiles) > 2 {
+		return errors.NewInputError("Invalid --profile value", fmt.Errorf("expected one profile to compare with, or two to compare"))
+	}
+	eng, command, projectSettings, err := loadCommandEnvironment(args[0])
+	if err != nil {
+		return err
+	}
+	profiles := make([]engine.EnvProfile, len(envProfiles))
+	for i, name := range envProfiles {
+		if profiles[i], err = profileFromSettings(eng.Program(), projectSettings, name); err != nil {
+			return errors.NewInputError("Invalid --profile value", err)
+		}
+	}
+	// With one profile, compare it with running without any
+	if len(profiles) == 1 {
+		profiles = append([]engine.EnvProfile{{}}, profiles...)
+	}
+
+	// The inherited environment is compared too, so a profile value the caller already has isn't a change
+	var envs [2]*engine.Environment
+	for i, profile := range profiles {
+		if envs[i], err = eng.ResolveEnvironment(command, os.Environ(), profile, true); err != nil {
+			return errors.NewCommandExecutionError(command.Name, err)
+		}
+	}
+	changes := engine.DiffEnvironments(envs[0], envs[1])
+	if envFormat == "json" {
+		if changes == nil {
+			changes = []engine.EnvChange{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "No differences")
+		return nil
+	}
+	return engine.WriteEnvDiff(os.Stdout, changes)
+}
+
+func explainCommand(cmd *cobra.Command, args []string) error {
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, overrides, err := parseCommands(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return errors.NewInputError("Failed to load project settings", err)
+	}
+	cliOptions, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Invalid cli settings", err)
+	}
+	command, err := findCommand(program, args[0], cliOptions)
+	if err != nil {
+		return err
+	}
+
+	// Descriptions are read from the file the command is defined in; piped definitions have none
+	var source []byte
+	if reader != os.Stdin {
+		file := commandsFile
+		if overrides.IsLocalCommand(command.Name) {
+			file = parser.LocalFileName(commandsFile)
+		}
+		source, _ = os.ReadFile(file)
+	}
+
+	eng := engine.New(program)
+	eng.SetCLIOptions(cliOptions)
+	explanation, err := eng.Explain(command, source, os.Environ())
+	if err != nil {
+		return errors.NewCommandExecutionError(command.Name, err)
+	}
+	return explanation.WriteText(os.Stdout, !noColor)
+}
+
+func benchCommand(cmd *cobra.Command, args []string) error {
+	if benchRuns < 1 || benchWarmup < 0 || benchLimit < 0 {
+		return errors.NewInputError("Invalid bench flags", fmt.Errorf("--runs must be at least 1, --warmup and --threshold at least 0"))
+	}
+
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return errors.NewInputError("Failed to load project settings", err)
+	}
+	cliOptions, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Invalid cli settings", err)
+	}
+	if err := requireTrust(reader, program, projectSettings); err != nil {
+		return err
+	}
+	var targetCommands []*ast.CommandDecl
+	for _, name := range args {
+		targetCommand, err := findCommand(program, name, cliOptions)
+		if err != nil {
+			return err
+		}
+		targetCommands = append(targetCommands, targetCommand)
+	}
+
+	path := benchFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(commandsFile), builtins.BenchBaselineFileName)
+	}
+	baseline, err := builtins.ReadBenchBaseline(path)
+	if err != nil {
+		return errors.NewInputError("Failed to read benchmark baseline", err)
+	}
+
+	// Settings provide defaults, such as @requires container images; the environment wins
+	for name, value := range cliOptions.DefaultEnv {
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, value)
+		}
+	}
+
+	eng := engine.New(program)
+	eng.SetCLIOptions(cliOptions)
+	var regressed []string
+	for _, targetCommand := range targetCommands {
+		fmt.Fprintf(os.Stderr, "Benchmarking %s: %d runs after %d warmup\n", targetCommand.Name, benchRuns, benchWarmup)
+		stats, err := builtins.RunBench(benchRuns, benchWarmup, func() error {
+			_, err := eng.ExecuteCommand(targetCommand)
+			return err
+		})
+		if err != nil {
+			return errors.NewCommandExecutionError(targetCommand.Name, err)
+		}
+
+		line := fmt.Sprintf("%s: %s", targetCommand.Name, stats)
+		if previous, ok := baseline[targetCommand.Name]; ok {
+			change, slower := stats.Compare(previous, benchLimit)
+			line += fmt.Sprintf(" (mean %+.1f%% against the baseline)", change)
+			if slower {
+				line += " REGRESSION"
+				regressed = append(regressed, targetCommand.Name)
+			}
+		}
+		fmt.Println(line)
+		if benchSave {
+			baseline[targetCommand.Name] = stats
+		}
+	}
+
+	if benchSave {
+		if err := builtins.WriteBenchBaseline(path, baseline); err != nil {
+			return fmt.Errorf("error writing benchmark baseline: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Saved the baseline to %s\n", path)
+	}
+	if len(regressed) > 0 {
+		return errors.New(errors.ErrCommandExecution, fmt.Sprintf("%s more than %d%% slower than the baseline", strings.Join(regressed, ", "), benchLimit))
+	}
+	return nil
+}
+
+func listCommand(cmd *cobra.Command, args []string) error {
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, overrides, err := parseCommands(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+
+	// Watch and stop commands share a name and are listed once, with the first one's doc comment
+	var names []string
+	kinds := make(map[string][]string)
+	summaries := make(map[string]string)
+	for _, command := range program.Commands {
+		if _, seen := kinds[command.Name]; !seen {
+			names = append(names, command.Name)
+			kinds[command.Name] = nil
+		}
+		if command.Type != ast.Command {
+			kinds[command.Name] = append(kinds[command.Name], command.Type.String())
+		}
+		if summaries[command.Name] == "" {
+			summaries[command.Name] = command.Comments.Summary()
+		}
+	}
+
+	fmt.Println("Commands:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		marks := kinds[name]
+		if overrides.IsLocalCommand(name) {
+			marks = append(marks, "local")
+		}
+		line := "  " + name
+		if len(marks) > 0 {
+			line += " [" + strings.Join(marks, ", ") + "]"
+		}
+		if summary := summaries[name]; summary != "" {
+			line += "\t" + summary
+		}
+		fmt.Fprintln(tw, line)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	variables := append([]ast.VariableDecl{}, program.Variables...)
+	for _, group := range program.VarGroups {
+		variables = append(variables, group.Variables...)
+	}
+	if len(variables) > 0 {
+		fmt.Println("\nVariables:")
+		for _, variable := range variables {
+			line := fmt.Sprintf("  %s = %s", variable.Name, variable.Value.String())
+			if overrides.IsLocalVariable(variable.Name) {
+				line += " [local]"
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+func doctorCommand(cmd *cobra.Command, args []string) error {
+	infos := decorators.DescribeAll()
+	if doctorList {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DECORATOR\tTYPE\tVERSION\tMODES\tPLATFORMS\tCAPABILITIES")
+		for _, info := range infos {
+			capabilities := make([]string, len(info.Capabilities))
+			for i, capability := range info.Capabilities {
+				capabilities[i] = string(capability)
+			}
+			modes := make([]string, len(info.Modes))
+			var platforms []string
+			for i, mode := range info.Modes {
+				modes[i] = string(mode)
+				if list, ok := info.Platforms[mode]; ok {
+					platforms = append(platforms, fmt.Sprintf("%s: %s", mode, strings.Join(list, ", ")))
+				}
+			}
+			if len(platforms) == 0 {
+				platforms = []string{"all"}
+			}
+			fmt.Fprintf(tw, "@%s\t%s\t%d\t%s\t%s\t%s\n", info.Name, info.Type, info.Version, strings.Join(modes, ", "), strings.Join(platforms, "; "), strings.Join(capabilities, ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	problems := decorators.Verify()
+	if len(problems) == 0 {
+		fmt.Printf("decorator registry: ok (%d decorators)\n", len(infos))
+		return nil
+	}
+	fmt.Printf("decorator registry: %d problems\n", len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
+	}
+	return errors.New(errors.ErrDecoratorValidation, "devcmd's decorator registry is inconsistent")
+}
+
+func checkCommand(cmd *cobra.Command, args []string) error {
+	if checkFormat != "text" && checkFormat != "json" && checkFormat != "sarif" {
+		return fmt.Errorf("unsupported format %q: expected text, json, or sarif", checkFormat)
+	}
+
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	fileName := commandsFile
+	if reader == os.Stdin {
+		fileName = "<stdin>"
+	}
+
+	report, err := check.Run(fileName, reader)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+
+	switch checkFormat {
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	case "sarif":
+		err = report.WriteSARIF(os.Stdout, Version)
+	default:
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing diagnostics: %w", err)
+	}
+
+	errorCount, warningCount := report.Counts()
+	if report.HasErrors() {
+		return errors.New(errors.ErrCommandValidation, fmt.Sprintf("%s: %d error(s), %d warning(s)", fileName, errorCount, warningCount))
+	}
+	if checkFormat == "text" {
+		fmt.Fprintf(os.Stderr, "%s %s: no errors, %d warning(s)\n", marker("✅", "Done:"), fileName, warningCount)
+	}
+	return nil
+}
+
+func graphCommand(cmd *cobra.Command, args []string) error {
+	if graphFormat != "tree" && graphFormat != "dot" && graphFormat != "json" {
+		return fmt.Errorf("unsupported format %q: expected tree, dot, or json", graphFormat)
+	}
+	durations, err := engine.ReadRecordedDurations(graphTimes)
+	if err != nil {
+		return errors.NewInputError("Failed to read recorded durations", err)
+	}
+
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+
+	graph := engine.New(program).CommandGraph(durations)
+	switch graphFormat {
+	case "dot":
+		err = graph.WriteDOT(os.Stdout)
+	case "json":
+		err = graph.WriteJSON(os.Stdout)
+	default:
+		err = graph.WriteTree(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing graph: %w", err)
+	}
+
+	// The graph is still printed so the cycle can be seen in context
+	if err := graph.CycleError(); err != nil {
+		return errors.New(errors.ErrCommandValidation, err.Error())
+	}
+	return nil
+}
+
+// sourceReader opens the commands file given as the only argument, or the --file one (or
+// stdin) without arguments, for commands that inspect a single file as written
+func sourceReader(args []string) (io.Reader, func(), error) {
+	if len(args) == 1 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return nil, nil, errors.NewInputError("Failed to read command definitions", err)
+		}
+		return file, func() { _ = file.Close() }, nil
+	}
+	input, closeFunc, err := getInputReader()
+	if err != nil {
+		return nil, nil, errors.NewInputError("Failed to read command definitions", err)
+	}
+	return input, func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}, nil
+}
+
+func lexCommand(cmd *cobra.Command, args []string) error {
+	if lexFormat != "text" && lexFormat != "json" {
+		return fmt.Errorf("unsupported format %q: expected text or json", lexFormat)
+	}
+
+	reader, closeReader, err := sourceReader(args)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	trace := lexer.New(reader).Trace()
+	if lexFormat == "json" {
+		err = trace.WriteJSON(os.Stdout, lexDebug)
+	} else {
+		err = trace.WriteText(os.Stdout, lexDebug)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing tokens: %w", err)
+	}
+	return nil
+}
+
+func parseCommand(cmd *cobra.Command, args []string) error {
+	if parseFormat != "text" && parseFormat != "json" {
+		return fmt.Errorf("unsupported format %q: expected text or json", parseFormat)
+	}
+
+	reader, closeReader, err := sourceReader(args)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	program, err := parser.Parse(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+	if !parseAST {
+		return nil
+	}
+
+	tree := ast.Dump(program)
+	if parseFormat == "json" {
+		err = tree.WriteJSON(os.Stdout)
+	} else {
+		err = tree.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing AST: %w", err)
+	}
+	return nil
+}
+
+func releaseCommand(cmd *cobra.Command, args []string) error {
+	bump, err := release.ParseBump(releaseBump)
+	if err != nil {
+		return err
+	}
+
+	plan, err := release.NewPlan(".", bump)
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, "Failed to read release history", err)
+	}
+
+	since := "the beginning of history"
+	if plan.Tag != "" {
+		since = plan.Tag
+	}
+	if plan.Bump == release.BumpNone {
+		return errors.New(errors.ErrCommandValidation, fmt.Sprintf("Nothing to release: no commits since %s", since))
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s → %s (%s bump, %d commit(s) since %s)\n", marker("📦", "Release:"), plan.Current, plan.Next, plan.Bump, len(plan.Commits), since)
+
+	existing, err := os.ReadFile(releaseLog)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.NewInputError("Failed to read changelog", err)
+	}
+
+	switch {
+	case releaseCheck:
+		if err := release.ValidateChangelog(string(existing), plan.Next); err != nil {
+			return errors.Wrap(errors.ErrCommandValidation, fmt.Sprintf("%s is not ready for %s", releaseLog, plan.Next), err)
+		}
+		fmt.Fprintf(os.Stderr, "%s %s has a section for %s\n", marker("✅", "Done:"), releaseLog, plan.Next)
+	case releaseWrite:
+		if _, found := release.FindSection(string(existing), plan.Next); found {
+			fmt.Fprintf(os.Stderr, "%s %s already has a section for %s, leaving it unchanged\n", marker("ℹ️ ", "Note:"), releaseLog, plan.Next)
+			break
+		}
+		section := release.RenderSection(plan.Next, time.Now(), plan.Commits)
+		if err := os.WriteFile(releaseLog, []byte(release.PrependSection(string(existing), section)), 0o644); err != nil {
+			return fmt.Errorf("error writing changelog: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s Updated %s\n", marker("📝", "Done:"), releaseLog)
+	default:
+		fmt.Print(release.RenderSection(plan.Next, time.Now(), plan.Commits))
+	}
+
+	if releaseTag {
+		if err := release.CreateTag(".", plan.Next, "Release "+plan.Next.String()); err != nil {
+			return errors.Wrap(errors.ErrSystemCommand, fmt.Sprintf("Failed to tag %s", plan.Next), err)
+		}
+		fmt.Fprintf(os.Stderr, "%s Tagged %s\n", marker("🏷️ ", "Done:"), plan.Next)
+	}
+
+	return nil
+}
+
+// keyringService returns the keyring service from DEVCMD_KEYRING_SERVICE or project settings
+func keyringService() (string, error) {
+	if service := os.Getenv(builtins.KeyringServiceEnvVar); service != "" {
+		return service, nil
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return "", errors.NewInputError("Failed to load project settings", err)
+	}
+	return projectSettings.String("secrets.service", builtins.DefaultKeyringService), nil
+}
+
+func secretSetCommand(cmd *cobra.Command, args []string) error {
+	service, err := keyringService()
+	if err != nil {
+		return err
+	}
+	value, err := readSecretValue(args[0])
+	if err != nil {
+		return errors.NewInputError("Failed to read the secret value", err)
+	}
+	if err := builtins.KeyringSet(service, args[0], value); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, fmt.Sprintf("Failed to store %s", args[0]), err)
+	}
+	fmt.Fprintf(os.Stderr, "%s Stored %s in the keyring (service %q)\n", marker("🔑", "Done:"), args[0], service)
+	return nil
+}
+
+func secretGetCommand(cmd *cobra.Command, args []string) error {
+	service, err := keyringService()
+	if err != nil {
+		return err
+	}
+	value, err := builtins.KeyringGet(service, args[0])
+	if err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, fmt.Sprintf("Failed to read %s", args[0]), err)
 	}
+	fmt.Println(value)
+	return nil
+}
 
+func secretRmCommand(cmd *cobra.Command, args []string) error {
+	service, err := keyringService()
+	if err != nil {
+		return err
+	}
+	if err := builtins.KeyringDelete(service, args[0]); err != nil {
+		return errors.Wrap(errors.ErrSystemCommand, fmt.Sprintf("Failed to remove %s", args[0]), err)
+	}
+	fmt.Fprintf(os.Stderr, "%s Removed %s from the keyring (service %q)\n", marker("🗑️ ", "Done:"), args[0], service)
 	return nil
 }
+
+// readSecretValue reads a secret from stdin, prompting with echo turned off when stdin is a
+// terminal. A single trailing newline is dropped.
+func readSecretValue(name string) (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			_ = stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	value := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if value == "" {
+		return "", fmt.Errorf("no value entered for %s", name)
+	}
+	return value, nil
+}
+
+// stty changes terminal settings for stdin; it fails where stty isn't available, e.g. Windows
+func stty(setting string) error {
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/core/errors"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan <command> [command...] --out <file>",
+	Short: "Save the plan of commands to run later with devcmd apply",
+	Long: `Show the plan of commands, as devcmd run --dry-run does, and save it to a file for devcmd
+apply to run later, on this machine or another. The file holds the plan with the --profile,
+--var, --param, --only and --skip it was made with, and fingerprints of the commands file and
+of the environment variables the commands read, so apply refuses to run a stale plan.
+Credentials are only recorded as set or unset, and secrets are masked, but the file shows the
+other values the commands run with: it is written readable only by you, so keep it that way.`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         planCommand,
+	SilenceUsage: true,
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-file>",
+	Short: "Run a plan saved with devcmd plan",
+	Long: `Run the commands of a plan saved with devcmd plan --out, with the --profile, --var, --param,
+--only and --skip it was made with. Nothing runs if the commands file, the platform or an
+environment variable the commands read has changed since the plan was saved, and each command
+runs only if planning it again just before it starts gives the plan that was saved.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         applyCommand,
+	SilenceUsage: true,
+}
+
+// planCommand saves the plan of commands to the --out file, planning them as a dry run does
+func planCommand(cmd *cobra.Command, args []string) error {
+	dryRun = true
+	return runCommand(cmd, args)
+}
+
+// showPlans prints the plan of each command for --dry-run, with each value the plan
+// interpolates and where it comes from, to audit before running, and saves them to the
+// --out file of devcmd plan
+func showPlans(run *runSetup) error {
+	var simulation *simulationOptions
+	if simulate || len(estimates) > 0 {
+		var err error
+		if simulation, err = simulationFromSettings(run.eng, run.settings); err != nil {
+			return errors.NewInputError("Invalid duration estimates", err)
+		}
+	}
+	if run.sandbox != nil {
+		fmt.Printf("Sandboxed: %s\n", run.sandbox)
+	}
+	var saved []engine.SavedCommand
+	for _, targetCommand := range run.commands {
+		if len(targetCommand.Body.Content) == 0 {
+			// Commands without steps have no plan, but apply still runs them
+			saved = append(saved, engine.SavedCommand{Name: targetCommand.Name})
+			continue
+		}
+		plan, err := run.eng.PlanWithValues(targetCommand, os.Environ(), run.profile, run.overridden)
+		if err != nil {
+			return errors.NewCommandExecutionError(targetCommand.Name, err)
+		}
+
+		// Print the plan using the plan DSL's beautiful ASCII tree visualization
+		fmt.Print(engine.PlanText(plan, !noColor))
+		if simulation != nil {
+			sim, err := run.eng.Simulate(targetCommand, simulation.estimates, simulation.history)
+			if err != nil {
+				return errors.NewCommandExecutionError(targetCommand.Name, err)
+			}
+			sim.WriteText(os.Stdout)
+		}
+		if planOut != "" {
+			digest, err := engine.PlanDigest(plan)
+			if err != nil {
+				return errors.NewCommandExecutionError(targetCommand.Name, err)
+			}
+			saved = append(saved, engine.SavedCommand{Name: targetCommand.Name, Digest: digest, Plan: plan})
+		}
+	}
+	if planOut != "" {
+		return savePlan(run, saved)
+	}
+	return nil
+}
+
+// savePlan writes the plans of the commands to the --out file with what they were planned
+// with and the fingerprints devcmd apply checks
+func savePlan(run *runSetup, saved []engine.SavedCommand) error {
+	env, err := run.eng.EnvFingerprint(run.commands, os.Environ(), run.profile)
+	if err != nil {
+		return errors.NewInputError("Failed to fingerprint the environment", err)
+	}
+	if err := engine.WriteSavedPlan(planOut, &engine.SavedPlan{
+		Version:  engine.SavedPlanVersion,
+		Devcmd:   Version,
+		Created:  time.Now().UTC().Truncate(time.Second),
+		Source:   run.source,
+		Platform: engine.Platform(),
+		Env:      env,
+		Profile:  runProfile,
+		Vars:     runVars,
+		Params:   runParams,
+		Only:     onlySteps,
+		Skip:     skipSteps,
+		Commands: saved,
+	}); err != nil {
+		return errors.NewInputError("Failed to save the plan", err)
+	}
+	fmt.Fprintf(os.Stderr, "Plan saved to %s; run it with: devcmd apply %s\n", planOut, planOut)
+	return nil
+}
+
+// applyCommand runs a plan saved with devcmd plan, selecting what it was planned with
+func applyCommand(cmd *cobra.Command, args []string) error {
+	saved, err := engine.ReadSavedPlan(args[0])
+	if err != nil {
+		return errors.NewInputError("Failed to read the saved plan", err)
+	}
+	appliedPlan = saved
+	runProfile, runVars, runParams, onlySteps, skipSteps = saved.Profile, saved.Vars, saved.Params, saved.Only, saved.Skip
+	names := make([]string, len(saved.Commands))
+	for i, command := range saved.Commands {
+		names[i] = command.Name
+	}
+	fmt.Fprintf(os.Stderr, "Applying the plan of %s saved at %s\n", strings.Join(names, ", "), saved.Created.Format(time.RFC3339))
+	return runCommand(cmd, names)
+}
+
+// approvedPlans returns the plan digests the run checks before starting each command. With
+// --plan-approve the plans are shown and approved first, and
+// each command runs only if planning it again just before it starts gives the plan that was
+// approved. A saved plan was approved when it was saved, unless it has gone stale since.
+// Commands still run from their definitions, so this catches changes to plans, not to what
+// plans don't show.
+func approvedPlans(run *runSetup) (map[string]string, error) {
+	var approved map[string]string
+	if planApprove {
+		var err error
+		if approved, err = approvePlans(run); err != nil {
+			return nil, err
+		}
+	}
+	if appliedPlan != nil {
+		env, err := run.eng.EnvFingerprint(run.commands, os.Environ(), run.profile)
+		if err != nil {
+			return nil, errors.NewInputError("Failed to fingerprint the environment", err)
+		}
+		if err := appliedPlan.CheckStale(run.source, engine.Platform(), env); err != nil {
+			return nil, errors.NewInputError("The saved plan was not run", err)
+		}
+		approved = appliedPlan.Digests()
+	}
+	return approved, nil
+}
+
+// approvePlans shows the plans of the commands, with the values they interpolate, and asks
+// whether to run them unless --auto-approve approves them, as in CI. It returns the digest of
+// each approved plan by command, for the run to check before starting it.
+func approvePlans(run *runSetup) (map[string]string, error) {
+	approved := make(map[string]string)
+	for _, command := range run.commands {
+		if len(command.Body.Content) == 0 {
+			continue
+		}
+		plan, err := run.eng.PlanWithValues(command, os.Environ(), run.profile, run.overridden)
+		if err != nil {
+			return nil, errors.NewCommandExecutionError(command.Name, err)
+		}
+		if approved[command.Name], err = engine.PlanDigest(plan); err != nil {
+			return nil, errors.NewCommandExecutionError(command.Name, err)
+		}
+		fmt.Print(engine.PlanText(plan, !noColor && isTerminal(os.Stdout)))
+	}
+
+	if autoApprove {
+		fmt.Fprintln(os.Stderr, "Plan approved with --auto-approve")
+		return approved, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return nil, errors.NewInputError("Cannot approve the plan", fmt.Errorf("--plan-approve asks in a terminal; pass --auto-approve to approve without asking"))
+	}
+	fmt.Fprint(os.Stderr, "\nRun as planned? Only 'yes' approves: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		return nil, errors.New(errors.ErrPermission, "The plan was not approved, so nothing ran")
+	}
+	return approved, nil
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	builtins "github.com/aledsdavies/devcmd/cli/internal/builtins"
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+	"github.com/aledsdavies/devcmd/cli/internal/settings"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/errors"
+	"github.com/aledsdavies/devcmd/runtime/logging"
+	"github.com/spf13/cobra"
+)
+
+// runSetup is what devcmd run resolves from the commands file, the settings and its flags
+// before anything runs: the engine and the commands to run, and what their plans are made with
+type runSetup struct {
+	reader     io.Reader // os.Stdin for definitions piped on stdin
+	source     string    // The commands file a saved plan is checked against
+	settings   *settings.Settings
+	eng        *engine.Engine
+	commands   []*ast.CommandDecl
+	profile    engine.EnvProfile
+	overridden []string                 // Variables --var sets
+	sandbox    *builtins.SandboxOptions // nil without --sandbox
+}
+
+// runCommand runs the named commands or, with --dry-run, shows and saves their plans, or, with
+// --detach, starts the command in the background
+func runCommand(cmd *cobra.Command, args []string) (err error) {
+	// A command started with --detach records how it finished for devcmd wait; commands it
+	// runs don't inherit the variable
+	if detached := os.Getenv(processes.DetachedEnvVar); detached != "" {
+		os.Unsetenv(processes.DetachedEnvVar)
+		defer func() { recordDetachedExit(detached, err) }()
+	}
+
+	policy, err := engine.ParseFailurePolicy(failOn)
+	if err != nil {
+		return errors.NewInputError("Invalid --fail-on value", err)
+	}
+	// Arguments after -- belong to the command rather than naming more commands
+	var commandArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args, commandArgs = args[:dash], args[dash:]
+		if len(args) != 1 {
+			return errors.NewInputError("Invalid arguments", fmt.Errorf("arguments after -- are given to one command, got %d", len(args)))
+		}
+	}
+	if runDetach && len(args) != 1 {
+		return errors.NewInputError("Invalid --detach", fmt.Errorf("--detach runs one command, got %d", len(args)))
+	}
+	if autoApprove && !planApprove {
+		return errors.NewInputError("Invalid --auto-approve", fmt.Errorf("--auto-approve approves the plan of --plan-approve"))
+	}
+	if (simulate || len(estimates) > 0) && !dryRun {
+		return errors.NewInputError("Invalid --simulate", fmt.Errorf("--simulate and --estimate simulate the plans of --dry-run"))
+	}
+	if runOutput != "text" && runOutput != "json" {
+		return fmt.Errorf("unsupported output %q: expected text or json", runOutput)
+	}
+	for _, report := range runReports {
+		if _, _, err := parseReportFlag(report); err != nil {
+			return errors.NewInputError("Invalid --report value", err)
+		}
+	}
+
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	run, err := setupRun(cmd, reader, args, commandArgs)
+	if err != nil {
+		return err
+	}
+	switch {
+	case dryRun:
+		return showPlans(run)
+	case runDetach:
+		if reader == os.Stdin {
+			return errors.NewInputError("Invalid --detach", fmt.Errorf("a detached command reads its commands file again, so it can't come from stdin"))
+		}
+		return detachCommand(run.commands[0])
+	}
+	return executeRun(run, policy)
+}
+
+// setupRun parses the commands file and settings, checks the file is allowed to run and
+// resolves the commands to run with the profile, variables, parameters and steps the flags
+// select. The environment is set up as the commands will see it, so plans show its values.
+func setupRun(cmd *cobra.Command, reader io.Reader, args, commandArgs []string) (*runSetup, error) {
+	run := &runSetup{reader: reader}
+	var err error
+
+	// Saved plans are checked against the commands file they were made from
+	if planOut != "" || appliedPlan != nil {
+		if reader == os.Stdin {
+			return nil, errors.NewInputError("Cannot save or apply a plan", fmt.Errorf("a saved plan is checked against its commands file, so it can't come from stdin"))
+		}
+		if run.source, err = commandsSource(); err != nil {
+			return nil, errors.NewInputError("Failed to read command definitions", err)
+		}
+	}
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return nil, errors.NewParseError("Failed to parse command definitions", err)
+	}
+
+	// Resolve aliases and abbreviations from project settings
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return nil, errors.NewInputError("Failed to load project settings", err)
+	}
+	run.settings = projectSettings
+
+	// Dry runs evaluate values for their plans, so they need an allowed commands file too
+	if err := requireTrust(reader, program, projectSettings); err != nil {
+		return nil, err
+	}
+
+	// Without --profile, the config may select one; a saved plan keeps the profile it has
+	if runProfile == "" && appliedPlan == nil {
+		runProfile = defaultProfile(program, projectSettings)
+	}
+
+	// The values a profile gives variables apply first, so --var still wins over them
+	program, _ = engine.WithEnvProfile(program, runProfile)
+	if err := applyVariableOverrides(program, runVars); err != nil {
+		return nil, errors.NewInputError("Invalid --var value", err)
+	}
+	run.overridden = make([]string, len(runVars))
+	for i, override := range runVars {
+		run.overridden[i], _, _ = strings.Cut(override, "=")
+	}
+
+	cliOptions, err := cliOptionsFromSettings(projectSettings)
+	if err != nil {
+		return nil, errors.NewInputError("Invalid cli settings", err)
+	}
+	if runProfile != "" {
+		if run.profile, err = profileFromSettings(program, projectSettings, runProfile); err != nil {
+			return nil, errors.NewInputError("Invalid --profile value", err)
+		}
+	}
+
+	// Find the commands to execute
+	for _, name := range args {
+		targetCommand, err := findCommand(program, name, cliOptions)
+		if err != nil {
+			return nil, err
+		}
+		run.commands = append(run.commands, targetCommand)
+	}
+
+	// Narrow the run to the selected steps; the filtered program also drives --dry-run
+	filter := engine.StepFilter{Only: onlySteps, Skip: skipSteps}
+	if len(filter.Only) > 0 || len(filter.Skip) > 0 {
+		targetNames := make([]string, len(run.commands))
+		for i, targetCommand := range run.commands {
+			targetNames[i] = targetCommand.Name
+		}
+		filtered, warnings, err := engine.FilterSteps(program, targetNames, filter)
+		if err != nil {
+			return nil, errors.NewInputError("Invalid step filter", err)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+		program = filtered
+		for i, name := range targetNames {
+			for j := range program.Commands {
+				if program.Commands[j].Name == name {
+					run.commands[i] = &program.Commands[j]
+				}
+			}
+		}
+	}
+
+	// Each --param sets a parameter of the commands that declare it
+	params, err := parseParamValues(runParams)
+	if err != nil {
+		return nil, errors.NewInputError("Invalid --param value", err)
+	}
+	if commandArgs != nil {
+		if params, err = engine.ArgParams(run.commands[0], commandArgs, params); err != nil {
+			return nil, errors.NewInputError("Invalid arguments", err)
+		}
+	}
+	if unused := engine.UnusedParams(run.commands, params); len(unused) > 0 {
+		return nil, errors.NewInputError("Invalid --param value", fmt.Errorf("no command to run declares parameter %s", strings.Join(unused, ", ")))
+	}
+	if !dryRun {
+		for _, targetCommand := range run.commands {
+			if _, err := engine.ResolveParams(targetCommand, params); err != nil {
+				return nil, errors.NewInputError("Invalid --param value", err)
+			}
+		}
+	}
+
+	// Runs with JSON output are read by tools rather than watched, so they have no heartbeats
+	if cmd.Flags().Changed("heartbeat") {
+		if runHeartbeat < 0 {
+			return nil, errors.NewInputError("Invalid --heartbeat value", fmt.Errorf("must not be negative, got %s", runHeartbeat))
+		}
+		cliOptions.Heartbeat, cliOptions.Heartbeats = runHeartbeat, nil
+	}
+	if runOutput == "json" {
+		cliOptions.Heartbeat, cliOptions.Heartbeats = 0, nil
+	}
+
+	// Use the engine to execute the specific commands
+	run.eng = engine.New(program)
+	run.eng.SetCLIOptions(cliOptions)
+	run.eng.SetForceRestart(runForce)
+	run.eng.SetParams(params)
+
+	// A requested sandbox that isn't available fails the run rather than running unconfined
+	if runSandbox || noNetwork || cmd.Flags().Changed("sandbox-write") {
+		run.sandbox = &builtins.SandboxOptions{Write: sandboxWrite, Network: !noNetwork}
+	}
+
+	// A selected profile overrides the environment, which in turn wins over settings defaults,
+	// for dry runs too so their plans show the values commands will read
+	for name, value := range run.profile.Env {
+		os.Setenv(name, value)
+	}
+
+	// Settings provide defaults, such as @requires container images; the environment wins
+	for name, value := range cliOptions.DefaultEnv {
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, value)
+		}
+	}
+	return run, nil
+}
+
+// executeRun runs the commands in order, up to --jobs at once after the commands they run
+// with @cmd, and skips those not yet started after a failure unless --keep-going. Approved
+// plans are checked before each command starts.
+func executeRun(run *runSetup, policy engine.FailurePolicy) error {
+	eng := run.eng
+	approved, err := approvedPlans(run)
+	if err != nil {
+		return err
+	}
+
+	if run.sandbox != nil {
+		var base []string
+		if configShell := eng.Program().Shell(); configShell != "" {
+			base = []string{configShell}
+		}
+		shell, err := builtins.SandboxShell(*run.sandbox, "", base)
+		if err != nil {
+			return errors.NewInputError("Cannot run with --sandbox", err)
+		}
+		eng.SetShell(shell)
+	}
+
+	// @open reads DEVCMD_NO_OPEN from the environment, which nested invocations also inherit
+	if noOpen {
+		os.Setenv("DEVCMD_NO_OPEN", "1")
+	}
+
+	// Record the summary before registering settings hooks, which stop event delivery when they fail
+	summary := eng.Summarize()
+
+	// Group step output and annotate failures in GitHub Actions and GitLab CI logs
+	eng.SetSourceFile(sourceFileName(run.reader))
+	if provider := engine.DetectCI(); provider != "" {
+		eng.RegisterCILogging(provider, logging.MaskWriter(os.Stdout))
+	}
+
+	// Register lifecycle hooks from project settings
+	if err := eng.RegisterShellHooks(run.settings.Section("hooks")); err != nil {
+		return errors.NewInputError("Invalid hooks in project settings", err)
+	}
+
+	jobs := engine.Jobs(runJobs)
+	eng.SetOutputPrefix(jobs > 1 && len(run.commands) > 1)
+	var mu sync.Mutex
+	var runErr error
+	failed := 0
+	eng.Schedule(run.commands, jobs, func(targetCommand *ast.CommandDecl) {
+		if len(targetCommand.Body.Content) == 0 {
+			summary.Skip(targetCommand.Name)
+			return
+		}
+		var cmdResult *engine.CommandResult
+		var err error
+		if digest, ok := approved[targetCommand.Name]; ok {
+			err = eng.CheckApprovedPlan(targetCommand, digest, os.Environ(), run.profile, run.overridden)
+		}
+		if err == nil {
+			cmdResult, err = eng.ExecuteCommand(targetCommand)
+		} else {
+			cmdResult = &engine.CommandResult{Name: targetCommand.Name, Status: "failed", Output: []string{}, Error: err.Error()}
+		}
+		summary.Record(cmdResult)
+		if err == nil {
+			return
+		}
+		if run.sandbox != nil {
+			// Writes outside the sandbox fail with "Read-only file system", which alone doesn't say why
+			err = fmt.Errorf("%w (ran with --sandbox: %s)", err, run.sandbox)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		failed++
+		if runErr == nil {
+			runErr = errors.NewCommandExecutionError(targetCommand.Name, err)
+		}
+	}, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runErr != nil && !keepGoing
+	}, func(targetCommand *ast.CommandDecl) {
+		summary.Skip(targetCommand.Name)
+	})
+	runFailed := summary.Finish(policy)
+
+	if err := reportRun(run, summary); err != nil {
+		return err
+	}
+	if !runFailed {
+		return nil
+	}
+	if len(run.commands) == 1 {
+		return runErr
+	}
+	return errors.New(errors.ErrCommandExecution, fmt.Sprintf("%d of %d commands failed", failed, len(run.commands))).
+		WithContext("error_details", runErr.Error())
+}
+
+// reportRun adds a finished run to the project's histories and writes its --report files and
+// its summary
+func reportRun(run *runSetup, summary *engine.RunSummary) error {
+	// Add the attempts of @retry blocks to the project's history to report flaky commands
+	if err := summary.UpdateFlakiness(engine.FlakinessFile(run.eng.ProcessNamespace())); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update the flakiness history: %v\n", err)
+	}
+
+	// Add the durations of successful commands to the history --simulate estimates them from
+	if err := summary.UpdateDurations(engine.DurationsFile(run.eng.ProcessNamespace())); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update the duration history: %v\n", err)
+	}
+
+	for _, report := range runReports {
+		_, path, _ := parseReportFlag(report)
+		if err := writeJUnitReport(summary, path); err != nil {
+			return fmt.Errorf("error writing run report %s: %w", path, err)
+		}
+	}
+
+	// Single-step runs need no summary unless it was requested as JSON or had to retry
+	switch {
+	case runOutput == "json":
+		if err := summary.WriteJSON(os.Stdout); err != nil {
+			return fmt.Errorf("error writing run summary: %w", err)
+		}
+	case len(run.commands) > 1 || len(summary.Steps) > 1 || summary.HasRetries():
+		if err := summary.WriteText(os.Stderr); err != nil {
+			return fmt.Errorf("error writing run summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseReportFlag splits a --report value such as "junit:report.xml" into its format and path
+func parseReportFlag(value string) (string, string, error) {
+	format, path, found := strings.Cut(value, ":")
+	if !found || path == "" {
+		return "", "", fmt.Errorf("expected format:path, got %q", value)
+	}
+	if format != "junit" {
+		return "", "", fmt.Errorf("unsupported report format %q: expected junit", format)
+	}
+	return format, path, nil
+}
+
+// writeJUnitReport writes the run summary as a JUnit XML file
+func writeJUnitReport(summary *engine.RunSummary, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := summary.WriteJUnit(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/processes"
+	"github.com/aledsdavies/devcmd/cli/internal/server"
+	"github.com/aledsdavies/devcmd/cli/internal/settings"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/errors"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [flags]",
+	Short: "Serve Prometheus metrics and webhooks over HTTP",
+	Long: `Run devcmd as a long-lived server for shared development environments.
+Webhooks in the settings file's webhooks section run commands on GitHub or GitLab events
+posted to /hooks/<name>, with variables taken from the event payload. The run counts,
+durations and failure rates of those commands, and the health of background processes, are
+exposed at /metrics for Prometheus. A server with webhooks serves only them and /healthz on
+--addr, as providers must be able to reach it, and /metrics and /commands on --metrics-addr.
+Changes to the commands file are picked up without restarting the server.`,
+	Args:         cobra.NoArgs,
+	RunE:         serveCommand,
+	SilenceUsage: true, // Don't show usage on execution errors
+}
+
+// webhooksFromSettings reads the webhooks served by devcmd serve from the `webhooks` section.
+// Secrets are read from the environment variables named by secretEnv, so they stay out of the
+// settings file:
+//
+//	webhooks {
+//	    push {
+//	        command = "deploy"; provider = "github"; secretEnv = "DEPLOY_HOOK_SECRET"; events = "push"
+//	        vars { BRANCH = "ref"; SHA = "after" }
+//	    }
+//	}
+func webhooksFromSettings(s *settings.Settings) ([]server.Webhook, error) {
+	var webhooks []server.Webhook
+	seen := make(map[string]bool)
+	for _, key := range s.Keys() {
+		rest, ok := strings.CutPrefix(key, "webhooks.")
+		if !ok {
+			continue
+		}
+		name, _, ok := strings.Cut(rest, ".")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a section per webhook, e.g. webhooks { %s { command = \"deploy\" } }", key, name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		webhook := server.Webhook{Name: name}
+		for field, value := range s.Section("webhooks." + name) {
+			switch field {
+			case "command":
+				webhook.Command = value
+			case "provider":
+				webhook.Provider = value
+			case "secretEnv":
+				webhook.Secret = os.Getenv(value)
+				if webhook.Secret == "" {
+					return nil, fmt.Errorf("webhooks.%s.secretEnv: %s is not set", name, value)
+				}
+			case "events":
+				for _, event := range strings.Split(value, ",") {
+					if event = strings.TrimSpace(event); event != "" {
+						webhook.Events = append(webhook.Events, event)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("webhooks.%s.%s: unknown setting (expected command, provider, secretEnv, events or vars)", name, field)
+			}
+		}
+		if vars := s.Section("webhooks." + name + ".vars"); len(vars) > 0 {
+			webhook.Vars = vars
+		}
+		if err := webhook.Validate(); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func serveCommand(cmd *cobra.Command, args []string) error {
+	// Get input reader (file or stdin)
+	reader, closeFunc, err := getInputReader()
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() {
+		if closeErr := closeFunc(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close input: %v\n", closeErr)
+		}
+	}()
+
+	program, _, err := parseCommands(reader)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return errors.NewInputError("Failed to load project settings", err)
+	}
+	hooks := projectSettings.Section("hooks")
+	webhooks, err := webhooksFromSettings(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Invalid webhooks settings", err)
+	}
+	if err := requireTrust(reader, program, projectSettings); err != nil {
+		return err
+	}
+
+	sourceFile := sourceFileName(reader)
+	srv := server.New(program).WithEngineSetup(func(eng *engine.Engine) error {
+		eng.SetSourceFile(sourceFile)
+		return eng.RegisterShellHooks(hooks)
+	}).WithProcessNamespace(processes.Namespace(filepath.Dir(sourceFile))).WithWebhooks(webhooks)
+
+	// Commands read from stdin have no file to watch
+	if reader != os.Stdin && serveReload > 0 {
+		load := func() (*ast.Program, error) {
+			file, err := os.Open(commandsFile)
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = file.Close() }()
+			program, _, err := parseCommands(file)
+			return program, err
+		}
+		go srv.WatchFiles(context.Background(), []string{commandsFile, parser.LocalFileName(commandsFile)}, serveReload, load, os.Stderr)
+	}
+
+	handlers := map[string]http.Handler{serveAddr: srv.Handler()}
+	if len(webhooks) == 0 {
+		fmt.Fprintf(os.Stderr, "devcmd serving %d commands on http://%s (metrics at /metrics)\n", len(program.Commands), serveAddr)
+	} else {
+		if serveMetrics == serveAddr {
+			return errors.NewInputError("Invalid --metrics-addr", fmt.Errorf("webhooks are served on %s, so metrics need another address", serveAddr))
+		}
+		handlers[serveMetrics] = srv.MetricsHandler()
+		fmt.Fprintf(os.Stderr, "devcmd serving %d webhooks on http://%s (metrics at http://%s/metrics)\n", len(webhooks), serveAddr, serveMetrics)
+	}
+	for _, webhook := range webhooks {
+		fmt.Fprintf(os.Stderr, "  webhook %s: POST /hooks/%s runs %s\n", webhook.Name, webhook.Name, webhook.Command)
+	}
+	return listenAndServe(handlers)
+}
+
+// listenAndServe serves each handler on its address until one of the servers fails
+func listenAndServe(handlers map[string]http.Handler) error {
+	failed := make(chan error, len(handlers))
+	for addr, handler := range handlers {
+		httpServer := &http.Server{
+			Addr:    addr,
+			Handler: handler,
+			// Slow clients can't hold connections open; webhook payloads are read in full before a run starts
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+		}
+		go func() { failed <- httpServer.ListenAndServe() }()
+	}
+	return fmt.Errorf("server error: %w", <-failed)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/cli/internal/engine"
+	"github.com/aledsdavies/devcmd/cli/internal/parser"
+	"github.com/aledsdavies/devcmd/cli/internal/settings"
+	"github.com/aledsdavies/devcmd/cli/internal/trust"
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/errors"
+	"github.com/aledsdavies/devcmd/runtime/config"
+	"github.com/spf13/cobra"
+)
+
+var allowCmd = &cobra.Command{
+	Use:   "allow",
+	Short: "Approve the commands file to run",
+	Long: `Approve the commands file, its local override file and its settings file to run with
+devcmd run, bench and serve, after listing their potentially dangerous constructs. The
+approval is kept by a hash of the files in devcmd/allow in the user state directory
+($XDG_STATE_HOME or ~/.local/state), so it lapses when any of them changes or the project
+moves, and devcmd asks again.`,
+	Args:         cobra.NoArgs,
+	RunE:         allowCommand,
+	SilenceUsage: true,
+}
+
+var denyCmd = &cobra.Command{
+	Use:          "deny",
+	Short:        "Revoke the approvals of the commands file",
+	Args:         cobra.NoArgs,
+	RunE:         denyCommand,
+	SilenceUsage: true,
+}
+
+// trustKey returns the approval key of the commands file, read together with its local
+// override file and settings file
+func trustKey(projectSettings *settings.Settings) (string, error) {
+	settingsPath := projectSettings.Path()
+	if settingsPath == "" {
+		settingsPath = settingsFile
+	}
+	if settingsPath == "" {
+		// A settings file added later can add hooks, so it changes the key too
+		settingsPath = filepath.Join(filepath.Dir(commandsFile), settings.DefaultFileName)
+	}
+	return trust.Key(commandsFile, parser.LocalFileName(commandsFile), settingsPath)
+}
+
+// writeTrustFindings lists what in the commands file and its settings deserves a look before
+// it is allowed to run
+func writeTrustFindings(w io.Writer, program *ast.Program, projectSettings *settings.Settings) {
+	findings := append(trust.Scan(program), trust.ScanHooks(projectSettings.Section("hooks"))...)
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "No sudo, recursive deletes, network access, credentials or hooks were found.")
+		return
+	}
+	fmt.Fprintln(w, "It contains:")
+	for _, finding := range findings {
+		fmt.Fprintf(w, "  %s\n", finding)
+	}
+}
+
+// trustAllEnvVar, set to true, runs commands files without checking they were allowed, for
+// machines that run whatever they check out anyway
+const trustAllEnvVar = "DEVCMD_TRUST_ALL"
+
+// requireTrust stops commands files that haven't been approved with devcmd allow, or have
+// changed since, from running. It lists what in the file deserves a look, then asks in a
+// terminal and fails otherwise. Piped definitions aren't checked, nor are runs with
+// DEVCMD_TRUST_ALL set or, in CI, with trust_ci set in the user config or the environment.
+func requireTrust(reader io.Reader, program *ast.Program, projectSettings *settings.Settings) error {
+	if reader == os.Stdin {
+		return nil
+	}
+	if trustAll, _ := strconv.ParseBool(os.Getenv(trustAllEnvVar)); trustAll {
+		return nil
+	}
+	if engine.DetectCI() != "" || (os.Getenv("CI") != "" && os.Getenv("CI") != "false") {
+		trustCI, _, err := configChain.Lookup(config.TrustCI)
+		if err != nil {
+			return errors.NewInputError("Invalid config", err)
+		}
+		if trustCI == "true" {
+			return nil
+		}
+	}
+	dir := trust.DefaultDir()
+	if dir == "" {
+		return nil
+	}
+	key, err := trustKey(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	if trust.Allowed(dir, key) {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s has not been allowed to run, or has changed since it was.\n", commandsFile)
+	writeTrustFindings(os.Stderr, program, projectSettings)
+	if isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		fmt.Fprintf(os.Stderr, "Allow %s to run? [y/N]: ", commandsFile)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "y" || answer == "yes" {
+			if err := trust.Allow(dir, key, commandsFile); err != nil {
+				return fmt.Errorf("error recording approval: %w", err)
+			}
+			return nil
+		}
+	}
+	return errors.New(errors.ErrPermission, fmt.Sprintf("%s is not allowed to run: review it, then run devcmd allow", commandsFile))
+}
+
+// allowCommand approves the commands file, with its local override and settings files, to run
+func allowCommand(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(commandsFile)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	program, _, err := parseCommands(file)
+	if err != nil {
+		return errors.NewParseError("Failed to parse command definitions", err)
+	}
+	projectSettings, err := loadSettings()
+	if err != nil {
+		return errors.NewInputError("Failed to load project settings", err)
+	}
+	dir := trust.DefaultDir()
+	if dir == "" {
+		return fmt.Errorf("no user state directory to record approvals in (set HOME or XDG_STATE_HOME)")
+	}
+	key, err := trustKey(projectSettings)
+	if err != nil {
+		return errors.NewInputError("Failed to read command definitions", err)
+	}
+
+	writeTrustFindings(os.Stdout, program, projectSettings)
+	if err := trust.Allow(dir, key, commandsFile); err != nil {
+		return fmt.Errorf("error recording approval: %w", err)
+	}
+	fmt.Printf("Allowed %s to run until it changes\n", commandsFile)
+	return nil
+}
+
+// denyCommand revokes every approval of the commands file
+func denyCommand(cmd *cobra.Command, args []string) error {
+	removed, err := trust.Deny(trust.DefaultDir(), commandsFile)
+	if err != nil {
+		return fmt.Errorf("error revoking approvals: %w", err)
+	}
+	if removed == 0 {
+		fmt.Printf("%s was not allowed to run\n", commandsFile)
+		return nil
+	}
+	fmt.Printf("Revoked the approval of %s\n", commandsFile)
+	return nil
+}
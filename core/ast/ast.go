@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -34,15 +36,22 @@ type TokenRange struct {
 // Program represents the root of the CST (entire devcmd file)
 // Preserves concrete syntax for LSP, Tree-sitter, and formatting tools
 type Program struct {
-	Variables []VariableDecl
-	VarGroups []VarGroup // Grouped variable declarations: var ( ... )
-	Commands  []CommandDecl
-	Pos       Position
-	Tokens    TokenRange
+	Variables   []VariableDecl
+	VarGroups   []VarGroup // Grouped variable declarations: var ( ... )
+	Commands    []CommandDecl
+	Triggers    []TriggerDecl    // Commands run when another finishes: on failure of deploy: ...
+	EnvProfiles []EnvProfileDecl // Variable values selected with --profile: env production { ... }
+	Config      *ConfigDecl      // Settings of the whole program: config { defaultTimeout = 10m }
+	Comments    []Comment        // Comments that belong to no declaration, such as section headers
+	Pos         Position
+	Tokens      TokenRange
 }
 
 func (p *Program) String() string {
 	var parts []string
+	if p.Config != nil {
+		parts = append(parts, p.Config.String())
+	}
 	for _, v := range p.Variables {
 		parts = append(parts, v.String())
 	}
@@ -52,9 +61,25 @@ func (p *Program) String() string {
 	for _, c := range p.Commands {
 		parts = append(parts, c.String())
 	}
+	for _, t := range p.Triggers {
+		parts = append(parts, t.String())
+	}
+	for _, e := range p.EnvProfiles {
+		parts = append(parts, e.String())
+	}
 	return strings.Join(parts, "\n")
 }
 
+// EnvProfile returns the env profile with the given name, or nil if the program has none
+func (p *Program) EnvProfile(name string) *EnvProfileDecl {
+	for i := range p.EnvProfiles {
+		if p.EnvProfiles[i].Name == name {
+			return &p.EnvProfiles[i]
+		}
+	}
+	return nil
+}
+
 func (p *Program) Position() Position {
 	return p.Pos
 }
@@ -69,17 +94,23 @@ func (p *Program) SemanticTokens() []types.Token {
 
 // VariableDecl represents variable declarations (both individual and grouped)
 type VariableDecl struct {
-	Name   string
-	Value  Expression
-	Pos    Position
-	Tokens TokenRange
+	Name     string
+	Value    Expression
+	Override bool // Declared with override, replacing the variable of a file merged before
+	Comments Trivia
+	Pos      Position
+	Tokens   TokenRange
 
 	// LSP-specific information
-	NameToken  types.Token
-	ValueToken types.Token
+	OverrideToken *types.Token // The override keyword (nil without it)
+	NameToken     types.Token
+	ValueToken    types.Token
 }
 
 func (v *VariableDecl) String() string {
+	if v.Override {
+		return fmt.Sprintf("override var %s = %s", v.Name, v.Value.String())
+	}
 	return fmt.Sprintf("var %s = %s", v.Name, v.Value.String())
 }
 
@@ -105,6 +136,7 @@ func (v *VariableDecl) SemanticTokens() []types.Token {
 // Preserves the concrete syntax for formatting and LSP features
 type VarGroup struct {
 	Variables []VariableDecl
+	Comments  Trivia
 	Pos       Position
 	Tokens    TokenRange
 
@@ -152,6 +184,142 @@ func (g *VarGroup) SemanticTokens() []types.Token {
 	return tokens
 }
 
+// EnvProfileDecl gives variables the values they take when its profile is selected with
+// --profile, such as `env production { API_URL = "https://api.example.com" }`. Variables it
+// doesn't set keep their global values.
+type EnvProfileDecl struct {
+	Name      string
+	Variables []VariableDecl
+	Comments  Trivia
+	Pos       Position
+	Tokens    TokenRange
+
+	// Concrete syntax tokens for precise formatting and LSP
+	EnvToken   types.Token // The "env" word
+	NameToken  types.Token // The profile name token
+	OpenBrace  types.Token // The "{" token
+	CloseBrace types.Token // The "}" token
+}
+
+func (e *EnvProfileDecl) String() string {
+	parts := []string{fmt.Sprintf("env %s {", e.Name)}
+	for _, v := range e.Variables {
+		parts = append(parts, fmt.Sprintf("  %s = %s", v.Name, v.Value.String()))
+	}
+	parts = append(parts, "}")
+	return strings.Join(parts, "\n")
+}
+
+// Variable returns the profile's value for the variable with the given name, or nil if the
+// profile doesn't set it
+func (e *EnvProfileDecl) Variable(name string) *VariableDecl {
+	for i := range e.Variables {
+		if e.Variables[i].Name == name {
+			return &e.Variables[i]
+		}
+	}
+	return nil
+}
+
+func (e *EnvProfileDecl) Position() Position {
+	return e.Pos
+}
+
+func (e *EnvProfileDecl) TokenRange() TokenRange {
+	return e.Tokens
+}
+
+func (e *EnvProfileDecl) SemanticTokens() []types.Token {
+	envToken := e.EnvToken
+	envToken.Semantic = types.SemKeyword
+	nameToken := e.NameToken
+	nameToken.Semantic = types.SemVariable
+	tokens := []types.Token{envToken, nameToken, e.OpenBrace}
+	for _, v := range e.Variables {
+		tokens = append(tokens, v.SemanticTokens()...)
+	}
+	return append(tokens, e.CloseBrace)
+}
+
+// Settings a config block can have
+const (
+	// ConfigDefaultTimeout bounds every command without a @timeout of its own
+	ConfigDefaultTimeout = "defaultTimeout"
+	// ConfigShell is the shell that runs shell steps outside @shell blocks
+	ConfigShell = "shell"
+)
+
+// ConfigDecl holds the settings of the whole program, such as
+// `config { defaultTimeout = 10m }`. A program has at most one.
+type ConfigDecl struct {
+	Settings       []VariableDecl
+	DefaultTimeout time.Duration // Zero unless the block sets defaultTimeout
+	Shell          string        // Empty unless the block sets shell
+	Comments       Trivia
+	Pos            Position
+	Tokens         TokenRange
+
+	// Concrete syntax tokens for precise formatting and LSP
+	ConfigToken types.Token // The "config" word
+	OpenBrace   types.Token // The "{" token
+	CloseBrace  types.Token // The "}" token
+}
+
+func (c *ConfigDecl) String() string {
+	parts := []string{"config {"}
+	for _, s := range c.Settings {
+		parts = append(parts, fmt.Sprintf("  %s = %s", s.Name, s.Value.String()))
+	}
+	parts = append(parts, "}")
+	return strings.Join(parts, "\n")
+}
+
+// Setting returns the setting with the given name, or nil if the block doesn't set it
+func (c *ConfigDecl) Setting(name string) *VariableDecl {
+	for i := range c.Settings {
+		if c.Settings[i].Name == name {
+			return &c.Settings[i]
+		}
+	}
+	return nil
+}
+
+func (c *ConfigDecl) Position() Position {
+	return c.Pos
+}
+
+func (c *ConfigDecl) TokenRange() TokenRange {
+	return c.Tokens
+}
+
+func (c *ConfigDecl) SemanticTokens() []types.Token {
+	configToken := c.ConfigToken
+	configToken.Semantic = types.SemKeyword
+	tokens := []types.Token{configToken, c.OpenBrace}
+	for _, s := range c.Settings {
+		tokens = append(tokens, s.SemanticTokens()...)
+	}
+	return append(tokens, c.CloseBrace)
+}
+
+// DefaultTimeout returns the timeout of commands without a @timeout of their own, zero if the
+// program has no config block setting one
+func (p *Program) DefaultTimeout() time.Duration {
+	if p.Config == nil {
+		return 0
+	}
+	return p.Config.DefaultTimeout
+}
+
+// Shell returns the shell that runs shell steps outside @shell blocks, empty if the program
+// has no config block setting one
+func (p *Program) Shell() string {
+	if p.Config == nil {
+		return ""
+	}
+	return p.Config.Shell
+}
+
 // NamedParameter represents a named parameter in decorator arguments
 // Supports both named syntax (name = value) and positional (resolved by parser)
 type NamedParameter struct {
@@ -221,6 +389,17 @@ func GetStringParam(params []NamedParameter, name string, defaultValue string) s
 	return defaultValue
 }
 
+// GetNameParam retrieves a parameter naming a variable, given as a string ("ENV") or as the
+// variable itself (ENV), with default fallback
+func GetNameParam(params []NamedParameter, name string, defaultValue string) string {
+	if param := FindParameter(params, name); param != nil {
+		if identifier, ok := param.Value.(*Identifier); ok {
+			return identifier.Name
+		}
+	}
+	return GetStringParam(params, name, defaultValue)
+}
+
 // GetIntParam retrieves an integer parameter value with default fallback
 func GetIntParam(params []NamedParameter, name string, defaultValue int) int {
 	if param := FindParameter(params, name); param != nil {
@@ -439,16 +618,22 @@ func (i *Identifier) GetType() ExpressionType {
 
 // CommandDecl represents command definitions with concrete syntax preservation
 type CommandDecl struct {
-	Name   string
-	Type   CommandType
-	Body   CommandBody
-	Pos    Position
-	Tokens TokenRange
+	Name     string
+	Type     CommandType
+	Params   []CommandParam // Parameters declared as deploy(env: string, replicas: number = 3)
+	Needs    []Identifier   // Commands that run first, once per invocation: deploy: needs(build, test)
+	Body     CommandBody
+	Override bool // Declared with override, replacing the command of a file merged before
+	Comments Trivia
+	Pos      Position
+	Tokens   TokenRange
 
 	// Concrete syntax tokens for precise formatting and LSP
-	TypeToken  *types.Token // The watch/stop keyword (nil for regular commands)
-	NameToken  types.Token  // The command name token
-	ColonToken types.Token  // The ":" token
+	OverrideToken *types.Token // The override keyword (nil without it)
+	TypeToken     *types.Token // The watch/stop keyword (nil for regular commands)
+	NameToken     types.Token  // The command name token
+	ColonToken    types.Token  // The ":" token
+	NeedsToken    *types.Token // The needs keyword (nil without needs)
 }
 
 func (c *CommandDecl) String() string {
@@ -462,7 +647,25 @@ func (c *CommandDecl) String() string {
 		typeStr = ""
 	}
 
-	return fmt.Sprintf("%s%s: %s", typeStr, c.Name, c.Body.String())
+	params := ""
+	if len(c.Params) > 0 {
+		parts := make([]string, len(c.Params))
+		for i, param := range c.Params {
+			parts[i] = param.String()
+		}
+		params = "(" + strings.Join(parts, ", ") + ")"
+	}
+
+	needs := ""
+	if len(c.Needs) > 0 {
+		needs = "needs(" + strings.Join(c.NeedNames(), ", ") + ") "
+	}
+
+	if c.Override {
+		typeStr = "override " + typeStr
+	}
+
+	return fmt.Sprintf("%s%s%s: %s%s", typeStr, c.Name, params, needs, c.Body.String())
 }
 
 func (c *CommandDecl) Position() Position {
@@ -486,11 +689,176 @@ func (c *CommandDecl) SemanticTokens() []types.Token {
 	nameToken.Semantic = types.SemCommand
 	tokens = append(tokens, nameToken)
 
+	for _, param := range c.Params {
+		tokens = append(tokens, param.SemanticTokens()...)
+	}
+
+	if c.NeedsToken != nil {
+		needsToken := *c.NeedsToken
+		needsToken.Semantic = types.SemKeyword
+		tokens = append(tokens, needsToken)
+	}
+	for _, need := range c.Needs {
+		needToken := need.Token
+		needToken.Semantic = types.SemCommand
+		tokens = append(tokens, needToken)
+	}
+
 	tokens = append(tokens, c.Body.SemanticTokens()...)
 
 	return tokens
 }
 
+// NeedNames returns the names of the commands the command needs, in the order declared
+func (c *CommandDecl) NeedNames() []string {
+	names := make([]string, len(c.Needs))
+	for i, need := range c.Needs {
+		names[i] = need.Name
+	}
+	return names
+}
+
+// FindParam returns the parameter the command declares with name, or nil
+func (c *CommandDecl) FindParam(name string) *CommandParam {
+	for i := range c.Params {
+		if c.Params[i].Name == name {
+			return &c.Params[i]
+		}
+	}
+	return nil
+}
+
+// CommandParam is a typed parameter a command declares, e.g. replicas: number = 3.
+// Parameters without a default must be given whenever the command runs.
+type CommandParam struct {
+	Name    string
+	Type    ExpressionType // StringType, NumberType or BooleanType
+	Default Expression     // nil when the parameter is required
+	Pos     Position
+
+	// Concrete syntax tokens for precise formatting and LSP
+	NameToken types.Token
+	TypeToken types.Token
+}
+
+func (p CommandParam) String() string {
+	if p.Default != nil {
+		return fmt.Sprintf("%s: %s = %s", p.Name, p.Type, p.Default.String())
+	}
+	return fmt.Sprintf("%s: %s", p.Name, p.Type)
+}
+
+func (p CommandParam) Position() Position {
+	return p.Pos
+}
+
+// Required reports whether the parameter has no default
+func (p CommandParam) Required() bool {
+	return p.Default == nil
+}
+
+func (p CommandParam) SemanticTokens() []types.Token {
+	nameToken := p.NameToken
+	nameToken.Semantic = types.SemParameter
+	typeToken := p.TypeToken
+	typeToken.Semantic = types.SemKeyword
+	tokens := []types.Token{nameToken, typeToken}
+	if p.Default != nil {
+		tokens = append(tokens, p.Default.SemanticTokens()...)
+	}
+	return tokens
+}
+
+// TriggerEvent is the outcome of a command that runs a trigger
+type TriggerEvent string
+
+const (
+	TriggerOnSuccess TriggerEvent = "success"
+	TriggerOnFailure TriggerEvent = "failure"
+	TriggerOnChange  TriggerEvent = "change" // Files matching the trigger's paths changed
+)
+
+// TriggerDecl runs its body after another command finishes with the event's outcome,
+// such as `on failure of deploy: @cmd(rollback)`, or when files change, such as
+// `on change "proto/**/*.proto" debounce 1s: @cmd(codegen)`
+type TriggerDecl struct {
+	Event    TriggerEvent
+	Command  string        // The command whose outcome runs the trigger; empty for change triggers
+	Paths    []string      // The globs whose changes run a change trigger
+	Debounce time.Duration // How long changes must settle before a change trigger runs; 0 for the default
+	Body     CommandBody
+	Comments Trivia
+	Pos      Position
+	Tokens   TokenRange
+
+	// Concrete syntax tokens for precise formatting and LSP
+	OnToken       types.Token   // The "on" word
+	EventToken    types.Token   // The "success", "failure" or "change" word
+	OfToken       types.Token   // The "of" word (zero for change triggers)
+	CommandToken  types.Token   // The command name token (zero for change triggers)
+	PathTokens    []types.Token // The path string tokens of a change trigger
+	DebounceToken *types.Token  // The debounce duration token (nil without one)
+	ColonToken    types.Token   // The ":" token
+}
+
+// Name describes the trigger as it is written, without its body: `on failure of deploy` or
+// `on change "proto/**/*.proto"`
+func (t *TriggerDecl) Name() string {
+	if t.Event != TriggerOnChange {
+		return fmt.Sprintf("on %s of %s", t.Event, t.Command)
+	}
+	paths := make([]string, len(t.Paths))
+	for i, path := range t.Paths {
+		paths[i] = strconv.Quote(path)
+	}
+	return "on change " + strings.Join(paths, ", ")
+}
+
+func (t *TriggerDecl) String() string {
+	if t.Debounce > 0 {
+		return fmt.Sprintf("%s debounce %s: %s", t.Name(), t.Debounce, t.Body.String())
+	}
+	return fmt.Sprintf("%s: %s", t.Name(), t.Body.String())
+}
+
+func (t *TriggerDecl) Position() Position {
+	return t.Pos
+}
+
+func (t *TriggerDecl) TokenRange() TokenRange {
+	return t.Tokens
+}
+
+func (t *TriggerDecl) SemanticTokens() []types.Token {
+	var tokens []types.Token
+	for _, token := range []types.Token{t.OnToken, t.EventToken} {
+		token.Semantic = types.SemKeyword
+		tokens = append(tokens, token)
+	}
+
+	if t.Event == TriggerOnChange {
+		for _, token := range t.PathTokens {
+			token.Semantic = types.SemString
+			tokens = append(tokens, token)
+		}
+		if t.DebounceToken != nil {
+			debounceToken := *t.DebounceToken
+			debounceToken.Semantic = types.SemNumber
+			tokens = append(tokens, debounceToken)
+		}
+	} else {
+		ofToken := t.OfToken
+		ofToken.Semantic = types.SemKeyword
+		commandToken := t.CommandToken
+		commandToken.Semantic = types.SemCommand
+		tokens = append(tokens, ofToken, commandToken)
+	}
+
+	tokens = append(tokens, t.Body.SemanticTokens()...)
+
+	return tokens
+}
+
 // CommandType represents the type of command
 type CommandType int
 
@@ -836,6 +1204,7 @@ func (p *PatternContent) IsCommandContent() bool {
 type PatternBranch struct {
 	Pattern  Pattern          // The pattern identifier or wildcard
 	Commands []CommandContent // The commands to execute for this pattern (supports multiple)
+	Comments Trivia
 	Pos      Position
 	Tokens   TokenRange
 
@@ -888,6 +1257,7 @@ type PatternType int
 const (
 	IdentifierPatternType PatternType = iota // Named patterns like "production", "main"
 	WildcardPatternType                      // Wildcard pattern "*"
+	StringPatternType                        // Quoted glob or regex patterns like "release/*"
 )
 
 func (pt PatternType) String() string {
@@ -896,6 +1266,8 @@ func (pt PatternType) String() string {
 		return "identifier"
 	case WildcardPatternType:
 		return "wildcard"
+	case StringPatternType:
+		return "string"
 	default:
 		return "unknown"
 	}
@@ -968,6 +1340,89 @@ func (w *WildcardPattern) GetPatternType() PatternType {
 	return WildcardPatternType
 }
 
+// StringPattern represents quoted patterns like "release/*", matched as a glob, or "/^v[0-9]+$/",
+// matched as a regular expression when its value is delimited by slashes
+type StringPattern struct {
+	Value  string // Pattern as written between the quotes, escapes included
+	Pos    Position
+	Tokens TokenRange
+	Token  types.Token
+}
+
+func (s *StringPattern) String() string {
+	// Single quotes keep a value with double quotes in it reading the same
+	if strings.Contains(s.Value, `"`) {
+		return "'" + s.Value + "'"
+	}
+	return `"` + s.Value + `"`
+}
+
+func (s *StringPattern) Position() Position {
+	return s.Pos
+}
+
+func (s *StringPattern) TokenRange() TokenRange {
+	return s.Tokens
+}
+
+func (s *StringPattern) SemanticTokens() []types.Token {
+	token := s.Token
+	token.Semantic = types.SemPattern
+	return []types.Token{token}
+}
+
+func (s *StringPattern) IsPattern() bool {
+	return true
+}
+
+func (s *StringPattern) GetPatternType() PatternType {
+	return StringPatternType
+}
+
+// IsRegex reports whether the pattern is a regular expression, written between slashes
+func (s *StringPattern) IsRegex() bool {
+	return len(s.Value) >= 2 && strings.HasPrefix(s.Value, "/") && strings.HasSuffix(s.Value, "/")
+}
+
+// Expression returns the glob, or the regular expression without its slashes
+func (s *StringPattern) Expression() string {
+	if s.IsRegex() {
+		return s.Value[1 : len(s.Value)-1]
+	}
+	return s.Value
+}
+
+// Validate checks the glob or regular expression is well formed
+func (s *StringPattern) Validate() error {
+	if s.IsRegex() {
+		if _, err := regexp.Compile(s.Expression()); err != nil {
+			return fmt.Errorf("invalid regex pattern %s: %w", s, err)
+		}
+		return nil
+	}
+	if _, err := path.Match(s.Value, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %s: %w", s, err)
+	}
+	return nil
+}
+
+// Matches reports whether value matches the whole glob, as path.Match matches it, or
+// contains a match of the regular expression, which can anchor itself with ^ and $
+func (s *StringPattern) Matches(value string) (bool, error) {
+	if s.IsRegex() {
+		re, err := regexp.Compile(s.Expression())
+		if err != nil {
+			return false, fmt.Errorf("invalid regex pattern %s: %w", s, err)
+		}
+		return re.MatchString(value), nil
+	}
+	matched, err := path.Match(s.Value, value)
+	if err != nil {
+		return false, fmt.Errorf("invalid glob pattern %s: %w", s, err)
+	}
+	return matched, nil
+}
+
 // Decorator types: BlockDecorator, PatternDecorator, ValueDecorator, ActionDecorator
 
 // ValueDecorator represents inline decorators that provide values for shell interpolation
@@ -1142,6 +1597,9 @@ func Walk(node Node, fn func(Node) bool) {
 
 	switch n := node.(type) {
 	case *Program:
+		if n.Config != nil {
+			Walk(n.Config, fn)
+		}
 		for _, v := range n.Variables {
 			Walk(&v, fn)
 		}
@@ -1151,12 +1609,28 @@ func Walk(node Node, fn func(Node) bool) {
 		for _, c := range n.Commands {
 			Walk(&c, fn)
 		}
+		for _, t := range n.Triggers {
+			Walk(&t, fn)
+		}
+		for _, e := range n.EnvProfiles {
+			Walk(&e, fn)
+		}
 	case *VarGroup:
 		for _, v := range n.Variables {
 			Walk(&v, fn)
 		}
+	case *EnvProfileDecl:
+		for _, v := range n.Variables {
+			Walk(&v, fn)
+		}
+	case *ConfigDecl:
+		for _, s := range n.Settings {
+			Walk(&s, fn)
+		}
 	case *CommandDecl:
 		Walk(&n.Body, fn)
+	case *TriggerDecl:
+		Walk(&n.Body, fn)
 	case *CommandBody:
 		for _, content := range n.Content {
 			Walk(content, fn)
@@ -1195,6 +1669,8 @@ func Walk(node Node, fn func(Node) bool) {
 		// Leaf node - pattern identifier
 	case *WildcardPattern:
 		// Leaf node - wildcard pattern
+	case *StringPattern:
+		// Leaf node - glob or regex pattern
 	// Decorator types handle their own walking
 	case *ValueDecorator:
 		for _, arg := range n.Args {
@@ -0,0 +1,58 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// Comment is a # or /* */ comment from the source, with its markers
+type Comment struct {
+	Text  string
+	Pos   Position
+	Token types.Token
+}
+
+// Trivia are the comments that belong to a declaration or pattern branch: the comment lines
+// directly above it, with no blank line between, and a comment after it on its last line
+type Trivia struct {
+	Leading  []Comment
+	Trailing *Comment
+}
+
+// LeadingText returns the leading comments' text without their markers, one line per line
+// of comment, for documentation such as command help
+func (t Trivia) LeadingText() []string {
+	var lines []string
+	for _, comment := range t.Leading {
+		lines = append(lines, comment.Lines()...)
+	}
+	return lines
+}
+
+// Lines returns the comment's text without its markers and surrounding space, one entry per
+// line for /* */ comments
+func (c Comment) Lines() []string {
+	text := c.Text
+	if strings.HasPrefix(text, "/*") {
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*")))
+		}
+		return lines
+	}
+	return []string{strings.TrimSpace(strings.TrimPrefix(text, "#"))}
+}
+
+// Doc returns the documentation the leading comments give, such as the help of a command:
+// their text without markers, with blank lines at either end dropped
+func (t Trivia) Doc() string {
+	return strings.TrimSpace(strings.Join(t.LeadingText(), "\n"))
+}
+
+// Summary returns the first line of Doc, for one-line listings
+func (t Trivia) Summary() string {
+	summary, _, _ := strings.Cut(t.Doc(), "\n")
+	return summary
+}
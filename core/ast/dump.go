@@ -0,0 +1,324 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/types"
+)
+
+// DumpVersion is the version of the format Dump, WriteText and WriteJSON produce. Tools and
+// tests can rely on the format of a version; a change to the node kinds, their attributes or
+// the layout of either output bumps it.
+const DumpVersion = 4
+
+// DumpNode is a node of the AST as devcmd parse --ast prints it: its kind, its attributes
+// other than child nodes, where it starts in the source, and its children in source order
+type DumpNode struct {
+	Kind     string            `json:"kind"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Pos      DumpPosition      `json:"pos"`
+	Children []DumpNode        `json:"children,omitempty"`
+}
+
+// DumpPosition is the 1-based line and column where a node starts
+type DumpPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Dump returns the tree of a program
+func Dump(program *Program) DumpNode {
+	// The program starts with the file
+	pos := program.Pos
+	if pos.Line == 0 {
+		pos = Position{Line: 1, Column: 1}
+	}
+	node := dumpNode("Program", pos, nil)
+	if config := program.Config; config != nil {
+		child := dumpNode("ConfigDecl", config.Pos, nil)
+		for i := range config.Settings {
+			child.Children = append(child.Children, dumpVariable(&config.Settings[i]))
+		}
+		node.Children = append(node.Children, child)
+	}
+	for i := range program.Variables {
+		node.Children = append(node.Children, dumpVariable(&program.Variables[i]))
+	}
+	for i := range program.VarGroups {
+		group := &program.VarGroups[i]
+		child := dumpNode("VarGroup", group.Pos, nil)
+		for j := range group.Variables {
+			child.Children = append(child.Children, dumpVariable(&group.Variables[j]))
+		}
+		node.Children = append(node.Children, child)
+	}
+	for i := range program.Commands {
+		node.Children = append(node.Children, dumpCommand(&program.Commands[i]))
+	}
+	for i := range program.Triggers {
+		node.Children = append(node.Children, dumpTrigger(&program.Triggers[i]))
+	}
+	for i := range program.EnvProfiles {
+		profile := &program.EnvProfiles[i]
+		child := dumpNode("EnvProfileDecl", profile.Pos, map[string]string{"name": profile.Name})
+		for j := range profile.Variables {
+			child.Children = append(child.Children, dumpVariable(&profile.Variables[j]))
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node
+}
+
+func dumpNode(kind string, pos Position, attrs map[string]string) DumpNode {
+	return DumpNode{Kind: kind, Attrs: attrs, Pos: DumpPosition{Line: pos.Line, Column: pos.Column}}
+}
+
+// tokenPosition is where a node that records only its token, as literals do, starts
+func tokenPosition(pos Position, token types.Token) Position {
+	if pos.Line > 0 {
+		return pos
+	}
+	return Position{Line: token.Line, Column: token.Column}
+}
+
+func dumpVariable(variable *VariableDecl) DumpNode {
+	attrs := map[string]string{"name": variable.Name}
+	if variable.Override {
+		attrs["override"] = "true"
+	}
+	node := dumpNode("VariableDecl", variable.Pos, attrs)
+	if variable.Value != nil {
+		node.Children = append(node.Children, dumpExpression(variable.Value))
+	}
+	return node
+}
+
+func dumpCommand(command *CommandDecl) DumpNode {
+	attrs := map[string]string{"name": command.Name}
+	if command.Type != Command {
+		attrs["type"] = command.Type.String()
+	}
+	if len(command.Needs) > 0 {
+		attrs["needs"] = strings.Join(command.NeedNames(), ", ")
+	}
+	if command.Override {
+		attrs["override"] = "true"
+	}
+	node := dumpNode("CommandDecl", command.Pos, attrs)
+	for _, param := range command.Params {
+		child := dumpNode("CommandParam", param.Pos, map[string]string{"name": param.Name, "type": param.Type.String()})
+		if param.Default != nil {
+			child.Children = append(child.Children, dumpExpression(param.Default))
+		}
+		node.Children = append(node.Children, child)
+	}
+	node.Children = append(node.Children, dumpBody(&command.Body))
+	return node
+}
+
+func dumpTrigger(trigger *TriggerDecl) DumpNode {
+	attrs := map[string]string{"event": string(trigger.Event)}
+	if trigger.Command != "" {
+		attrs["command"] = trigger.Command
+	}
+	if len(trigger.Paths) > 0 {
+		attrs["paths"] = strings.Join(trigger.Paths, ", ")
+	}
+	if trigger.Debounce > 0 {
+		attrs["debounce"] = trigger.Debounce.String()
+	}
+	node := dumpNode("TriggerDecl", trigger.Pos, attrs)
+	node.Children = append(node.Children, dumpBody(&trigger.Body))
+	return node
+}
+
+func dumpBody(body *CommandBody) DumpNode {
+	var attrs map[string]string
+	if body.OpenBrace != nil {
+		attrs = map[string]string{"braces": "true"}
+	}
+	node := dumpNode("CommandBody", body.Pos, attrs)
+	node.Children = dumpContents(body.Content)
+	return node
+}
+
+func dumpContents(contents []CommandContent) []DumpNode {
+	var nodes []DumpNode
+	for _, content := range contents {
+		nodes = append(nodes, dumpContent(content))
+	}
+	return nodes
+}
+
+func dumpContent(content CommandContent) DumpNode {
+	switch c := content.(type) {
+	case *ShellContent:
+		node := dumpNode("ShellContent", c.Pos, nil)
+		for _, part := range c.Parts {
+			node.Children = append(node.Children, dumpShellPart(part))
+		}
+		return node
+	case *BlockDecorator:
+		node := dumpNode("BlockDecorator", c.Pos, map[string]string{"name": c.Name})
+		node.Children = append(dumpArgs(c.Args), dumpContents(c.Content)...)
+		return node
+	case *PatternDecorator:
+		node := dumpNode("PatternDecorator", c.Pos, map[string]string{"name": c.Name})
+		node.Children = dumpArgs(c.Args)
+		for i := range c.Patterns {
+			branch := &c.Patterns[i]
+			child := dumpNode("PatternBranch", branch.Pos, map[string]string{"pattern": branch.Pattern.String()})
+			child.Children = dumpContents(branch.Commands)
+			node.Children = append(node.Children, child)
+		}
+		return node
+	case *PatternContent:
+		node := dumpNode("PatternContent", c.Pos, map[string]string{"pattern": c.Pattern})
+		node.Children = dumpContents(c.Commands)
+		return node
+	case *ActionDecorator:
+		node := dumpNode("ActionDecorator", c.Pos, map[string]string{"name": c.Name})
+		node.Children = dumpArgs(c.Args)
+		return node
+	default:
+		return dumpNode(fmt.Sprintf("%T", content), content.Position(), nil)
+	}
+}
+
+func dumpShellPart(part ShellPart) DumpNode {
+	switch p := part.(type) {
+	case *TextPart:
+		return dumpNode("TextPart", p.Pos, map[string]string{"text": p.Text})
+	case *ValueDecorator:
+		node := dumpNode("ValueDecorator", p.Pos, map[string]string{"name": p.Name})
+		node.Children = dumpArgs(p.Args)
+		return node
+	case *ActionDecorator:
+		return dumpContent(p)
+	default:
+		return dumpNode(fmt.Sprintf("%T", part), part.Position(), nil)
+	}
+}
+
+func dumpArgs(args []NamedParameter) []DumpNode {
+	var nodes []DumpNode
+	for _, arg := range args {
+		attrs := map[string]string{"name": arg.Name}
+		if !arg.IsNamed() {
+			attrs["positional"] = "true"
+		}
+		node := dumpNode("NamedParameter", arg.Pos, attrs)
+		if arg.Value != nil {
+			node.Children = append(node.Children, dumpExpression(arg.Value))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func dumpExpression(expr Expression) DumpNode {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return dumpNode("StringLiteral", tokenPosition(e.Pos, e.StringToken), map[string]string{"value": e.Value})
+	case *NumberLiteral:
+		return dumpNode("NumberLiteral", tokenPosition(e.Pos, e.Token), map[string]string{"value": e.Value})
+	case *DurationLiteral:
+		return dumpNode("DurationLiteral", tokenPosition(e.Pos, e.Token), map[string]string{"value": e.Value})
+	case *BooleanLiteral:
+		return dumpNode("BooleanLiteral", tokenPosition(e.Pos, e.Token), map[string]string{"value": strconv.FormatBool(e.Value)})
+	case *Identifier:
+		return dumpNode("Identifier", tokenPosition(e.Pos, e.Token), map[string]string{"name": e.Name})
+	default:
+		return dumpNode(fmt.Sprintf("%T", expr), expr.Position(), map[string]string{"value": expr.String()})
+	}
+}
+
+// Diff describes the first difference between two trees other than positions, as the path
+// to the differing node and what differs, or returns "" when they are the same
+func (n DumpNode) Diff(other DumpNode) string {
+	return n.diff(other, n.Kind)
+}
+
+func (n DumpNode) diff(other DumpNode, path string) string {
+	if n.Kind != other.Kind {
+		return fmt.Sprintf("%s: kind %s, other %s", path, n.Kind, other.Kind)
+	}
+	names := make(map[string]bool)
+	for name := range n.Attrs {
+		names[name] = true
+	}
+	for name := range other.Attrs {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if n.Attrs[name] != other.Attrs[name] {
+			return fmt.Sprintf("%s: %s %q, other %q", path, name, n.Attrs[name], other.Attrs[name])
+		}
+	}
+	for i := 0; i < len(n.Children) && i < len(other.Children); i++ {
+		child := n.Children[i]
+		if diff := child.diff(other.Children[i], fmt.Sprintf("%s > %s[%d]", path, child.Kind, i)); diff != "" {
+			return diff
+		}
+	}
+	if len(n.Children) != len(other.Children) {
+		return fmt.Sprintf("%s: %d children, other %d", path, len(n.Children), len(other.Children))
+	}
+	return ""
+}
+
+// WriteText writes the tree indented by depth, one node per line after a version header:
+//
+//	# devcmd ast v1
+//	Program 1:1
+//	  CommandDecl 1:1 name="build"
+//
+// Each line has the node's kind, its line:column, and its attributes sorted by name with
+// Go-quoted values.
+func (n DumpNode) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# devcmd ast v%d\n", DumpVersion); err != nil {
+		return err
+	}
+	return n.writeText(w, 0)
+}
+
+func (n DumpNode) writeText(w io.Writer, depth int) error {
+	line := fmt.Sprintf("%s%s %d:%d", strings.Repeat("  ", depth), n.Kind, n.Pos.Line, n.Pos.Column)
+	names := make([]string, 0, len(n.Attrs))
+	for name := range n.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line += fmt.Sprintf(" %s=%s", name, strconv.Quote(n.Attrs[name]))
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if err := child.writeText(w, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the tree as an indented JSON object: {"version": 1, "ast": {...}}
+func (n DumpNode) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version int      `json:"version"`
+		AST     DumpNode `json:"ast"`
+	}{DumpVersion, n})
+}
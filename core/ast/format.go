@@ -0,0 +1,283 @@
+package ast
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// formatIndent is the indentation of each level of braces in formatted source
+const formatIndent = "    "
+
+// Format prints a program as devcmd source in a canonical layout: its config block, variables,
+// variable groups, commands, triggers and env profiles, one per line, and the content of each block on its own line
+// indented by four spaces. Comments stay above or after the declaration or pattern branch
+// they belong to, and those that belong to none go before the first declaration after them.
+// Parsing the result gives back the same program, apart from positions.
+func Format(program *Program) string {
+	f := formatter{floating: program.Comments}
+	if config := program.Config; config != nil {
+		f.leading(0, config.Pos, config.Comments)
+		f.line(0, "config {")
+		for _, s := range config.Settings {
+			f.leading(1, s.Pos, s.Comments)
+			f.line(1, s.Name+" = "+formatExpression(s.Value))
+			f.trailing(s.Comments)
+		}
+		f.line(0, "}")
+		f.trailing(config.Comments)
+	}
+	for i := range program.Variables {
+		v := &program.Variables[i]
+		f.leading(0, v.Pos, v.Comments)
+		f.line(0, overridePrefix(v.Override)+"var "+v.Name+" = "+formatExpression(v.Value))
+		f.trailing(v.Comments)
+	}
+	for i := range program.VarGroups {
+		group := &program.VarGroups[i]
+		f.leading(0, group.Pos, group.Comments)
+		// override applies to a whole group, so it is given by the group's first variable
+		variables := group.Variables
+		f.line(0, overridePrefix(len(variables) > 0 && variables[0].Override)+"var (")
+		for _, v := range group.Variables {
+			f.leading(1, v.Pos, v.Comments)
+			f.line(1, v.Name+" = "+formatExpression(v.Value))
+			f.trailing(v.Comments)
+		}
+		f.line(0, ")")
+		f.trailing(group.Comments)
+	}
+	for i := range program.Commands {
+		c := &program.Commands[i]
+		f.leading(0, c.Pos, c.Comments)
+		f.command(c)
+		f.trailing(c.Comments)
+	}
+	for i := range program.Triggers {
+		t := &program.Triggers[i]
+		header := t.Name()
+		if t.Debounce > 0 {
+			header += " debounce " + t.Debounce.String()
+		}
+		f.leading(0, t.Pos, t.Comments)
+		f.body(header+":", &t.Body)
+		f.trailing(t.Comments)
+	}
+	for i := range program.EnvProfiles {
+		profile := &program.EnvProfiles[i]
+		f.leading(0, profile.Pos, profile.Comments)
+		f.line(0, "env "+profile.Name+" {")
+		for _, v := range profile.Variables {
+			f.leading(1, v.Pos, v.Comments)
+			f.line(1, v.Name+" = "+formatExpression(v.Value))
+			f.trailing(v.Comments)
+		}
+		f.line(0, "}")
+		f.trailing(profile.Comments)
+	}
+	if len(f.floating) > 0 {
+		f.WriteString("\n")
+		f.comments(0, f.floating)
+	}
+	return f.String()
+}
+
+// overridePrefix returns the override keyword for a declaration that has it
+func overridePrefix(override bool) string {
+	if override {
+		return "override "
+	}
+	return ""
+}
+
+// formatter accumulates formatted source line by line
+type formatter struct {
+	bytes.Buffer
+	floating []Comment // The program's comments that belong to no declaration, yet to be written
+}
+
+func (f *formatter) line(depth int, text string) {
+	f.WriteString(strings.Repeat(formatIndent, depth))
+	f.WriteString(text)
+	f.WriteString("\n")
+}
+
+// leading writes the comments before a declaration at pos: those of the program that come
+// before it, separated from it by a blank line, then its own
+func (f *formatter) leading(depth int, pos Position, trivia Trivia) {
+	n := 0
+	for n < len(f.floating) && before(f.floating[n].Pos, pos) {
+		n++
+	}
+	if n > 0 {
+		f.comments(depth, f.floating[:n])
+		f.WriteString("\n")
+		f.floating = f.floating[n:]
+	}
+	f.comments(depth, trivia.Leading)
+}
+
+func (f *formatter) comments(depth int, comments []Comment) {
+	for _, comment := range comments {
+		f.line(depth, comment.Text)
+	}
+}
+
+// trailing appends a declaration's trailing comment to the last line written
+func (f *formatter) trailing(trivia Trivia) {
+	if trivia.Trailing == nil {
+		return
+	}
+	f.Truncate(f.Len() - 1)
+	f.WriteString(" " + trivia.Trailing.Text + "\n")
+}
+
+func before(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func (f *formatter) command(c *CommandDecl) {
+	header := c.Name
+	switch c.Type {
+	case WatchCommand:
+		header = "watch " + header
+	case StopCommand:
+		header = "stop " + header
+	}
+	header = overridePrefix(c.Override) + header
+	if len(c.Params) > 0 {
+		params := make([]string, len(c.Params))
+		for i, param := range c.Params {
+			params[i] = param.Name + ": " + param.Type.String()
+			if param.Default != nil {
+				params[i] += " = " + formatExpression(param.Default)
+			}
+		}
+		header += "(" + strings.Join(params, ", ") + ")"
+	}
+	header += ":"
+	if len(c.Needs) > 0 {
+		header += " needs(" + strings.Join(c.NeedNames(), ", ") + ")"
+	}
+	f.body(header, &c.Body)
+}
+
+// body writes a command or trigger body after its header: in braces when it had them, and
+// otherwise on the header's line
+func (f *formatter) body(header string, body *CommandBody) {
+	if body.OpenBrace != nil {
+		f.line(0, header+" {")
+		f.contents(1, body.Content)
+		f.line(0, "}")
+		return
+	}
+	if len(body.Content) == 0 {
+		f.line(0, header)
+		return
+	}
+	f.content(0, header+" ", body.Content[0])
+}
+
+func (f *formatter) contents(depth int, contents []CommandContent) {
+	for _, content := range contents {
+		f.content(depth, "", content)
+	}
+}
+
+// content writes content starting on a line that begins with prefix
+func (f *formatter) content(depth int, prefix string, content CommandContent) {
+	switch c := content.(type) {
+	case *BlockDecorator:
+		f.line(depth, prefix+"@"+c.Name+formatArgs(c.Args)+" {")
+		f.contents(depth+1, c.Content)
+		f.line(depth, "}")
+	case *PatternDecorator:
+		f.line(depth, prefix+"@"+c.Name+formatArgs(c.Args)+" {")
+		for _, branch := range c.Patterns {
+			pattern := branch.Pattern.String()
+			if _, ok := branch.Pattern.(*WildcardPattern); ok {
+				pattern = "default"
+			}
+			f.comments(depth+1, branch.Comments.Leading)
+			if len(branch.Commands) == 1 {
+				f.content(depth+1, pattern+": ", branch.Commands[0])
+			} else {
+				f.line(depth+1, pattern+": {")
+				f.contents(depth+2, branch.Commands)
+				f.line(depth+1, "}")
+			}
+			f.trailing(branch.Comments)
+		}
+		f.line(depth, "}")
+	case *ShellContent:
+		f.line(depth, prefix+formatShell(c))
+	case *ActionDecorator:
+		f.line(depth, prefix+"@"+c.Name+formatArgs(c.Args))
+	default:
+		f.line(depth, prefix+content.String())
+	}
+}
+
+// formatShell writes shell text as it was written, with its inline decorators
+func formatShell(shell *ShellContent) string {
+	var text strings.Builder
+	for _, part := range shell.Parts {
+		switch p := part.(type) {
+		case *ValueDecorator:
+			text.WriteString("@" + p.Name + formatArgs(p.Args))
+		case *ActionDecorator:
+			text.WriteString("@" + p.Name + formatArgs(p.Args))
+		default:
+			text.WriteString(part.String())
+		}
+	}
+	return text.String()
+}
+
+func formatArgs(args []NamedParameter) string {
+	if len(args) == 0 {
+		return ""
+	}
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = formatExpression(arg.Value)
+		if arg.IsNamed() {
+			formatted[i] = arg.Name + " = " + formatted[i]
+		}
+	}
+	return "(" + strings.Join(formatted, ", ") + ")"
+}
+
+func formatExpression(expr Expression) string {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return quoteString(e.Value)
+	case *BooleanLiteral:
+		return strconv.FormatBool(e.Value)
+	default:
+		return expr.String()
+	}
+}
+
+// quoteString quotes a string's source text, which keeps its escapes, in the first quotes
+// that don't occur unescaped in it
+func quoteString(value string) string {
+	for _, quote := range []string{`"`, `'`} {
+		if !containsUnescaped(value, quote[0]) {
+			return quote + value + quote
+		}
+	}
+	return "`" + value + "`"
+}
+
+func containsUnescaped(value string, quote byte) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case quote:
+			return true
+		}
+	}
+	return false
+}
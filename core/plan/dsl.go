@@ -54,6 +54,12 @@ type ParallelElement struct {
 	children         []PlanElement
 }
 
+// AnnotatedElement adds metadata to the step another element builds
+type AnnotatedElement struct {
+	element  PlanElement
+	metadata map[string]string
+}
+
 // SequenceElement represents sequential execution of multiple elements
 type SequenceElement struct {
 	description string
@@ -165,6 +171,17 @@ func (de *DecoratorElement) WithRetry(attempts int, delay time.Duration) *Decora
 	return de
 }
 
+// WithRetrySchedule adds the delay before each retry, for retries whose delays grow or are
+// jittered
+func (de *DecoratorElement) WithRetrySchedule(schedule []time.Duration, jitter bool) *DecoratorElement {
+	if de.timing == nil {
+		de.timing = &TimingInfo{}
+	}
+	de.timing.RetrySchedule = schedule
+	de.timing.RetryJitter = jitter
+	return de
+}
+
 // WithConcurrency adds concurrency timing information
 func (de *DecoratorElement) WithConcurrency(limit int) *DecoratorElement {
 	if de.timing == nil {
@@ -430,3 +447,32 @@ func (se *SequenceElement) Build() ExecutionStep {
 		Children:    children,
 	}
 }
+
+// Annotate creates an element adding metadata to the step element builds, such as the
+// name of the @parallel branch it runs in
+func Annotate(element PlanElement, key, value string) *AnnotatedElement {
+	return &AnnotatedElement{
+		element:  element,
+		metadata: map[string]string{key: value},
+	}
+}
+
+// WithMetadata adds more metadata to the step
+func (ae *AnnotatedElement) WithMetadata(key, value string) *AnnotatedElement {
+	ae.metadata[key] = value
+	return ae
+}
+
+// Build converts the annotated element to an execution step
+func (ae *AnnotatedElement) Build() ExecutionStep {
+	step := ae.element.Build()
+	metadata := make(map[string]string, len(step.Metadata)+len(ae.metadata))
+	for key, value := range step.Metadata {
+		metadata[key] = value
+	}
+	for key, value := range ae.metadata {
+		metadata[key] = value
+	}
+	step.Metadata = metadata
+	return step
+}
@@ -9,10 +9,19 @@ import (
 // ExecutionPlan represents a detailed plan of what would be executed in dry run mode
 type ExecutionPlan struct {
 	Steps   []ExecutionStep        `json:"steps"`
+	Values  []ValueInfo            `json:"values,omitempty"`
 	Context map[string]interface{} `json:"context"`
 	Summary PlanSummary            `json:"summary"`
 }
 
+// ValueInfo is a value the plan interpolates and where it comes from, so a dry run shows what
+// each command will be run with
+type ValueInfo struct {
+	Name   string `json:"name"`   // As the commands file reads it, e.g. @var(API_URL) or @env(HOME)
+	Value  string `json:"value"`  // The value, *** for secrets
+	Source string `json:"source"` // e.g. "var, line 3", "environment" or "--var"
+}
+
 // ExecutionStep represents a single step in the execution plan
 type ExecutionStep struct {
 	ID          string            `json:"id"`
@@ -71,11 +80,13 @@ type ConditionResult struct {
 
 // TimingInfo contains timing-related execution details
 type TimingInfo struct {
-	Timeout          *time.Duration `json:"timeout,omitempty"`
-	RetryAttempts    int            `json:"retry_attempts,omitempty"`
-	RetryDelay       *time.Duration `json:"retry_delay,omitempty"`
-	EstimatedTime    *time.Duration `json:"estimated_time,omitempty"`
-	ConcurrencyLimit int            `json:"concurrency_limit,omitempty"`
+	Timeout          *time.Duration  `json:"timeout,omitempty"`
+	RetryAttempts    int             `json:"retry_attempts,omitempty"`
+	RetryDelay       *time.Duration  `json:"retry_delay,omitempty"`
+	RetrySchedule    []time.Duration `json:"retry_schedule,omitempty"` // The delay before each retry, when they differ or are jittered
+	RetryJitter      bool            `json:"retry_jitter,omitempty"`   // Delays are random, from half of each to all of it
+	EstimatedTime    *time.Duration  `json:"estimated_time,omitempty"`
+	ConcurrencyLimit int             `json:"concurrency_limit,omitempty"`
 }
 
 // PlanSummary provides a high-level overview of the execution plan
@@ -122,6 +133,7 @@ func (ep *ExecutionPlan) String() string {
 		isLast := i == len(ep.Steps)-1
 		builder.WriteString(ep.formatStepAesthetic(step, "", isLast))
 	}
+	builder.WriteString(ep.formatValues(ColorGray, ColorReset))
 
 	return builder.String()
 }
@@ -146,7 +158,30 @@ func (ep *ExecutionPlan) StringNoColor() string {
 		isLast := i == len(ep.Steps)-1
 		builder.WriteString(ep.formatStepAestheticNoColor(step, "", isLast))
 	}
+	builder.WriteString(ep.formatValues("", ""))
+
+	return builder.String()
+}
+
+// formatValues formats the values the plan interpolates, aligned, with their sources between
+// the given color codes
+func (ep *ExecutionPlan) formatValues(color, reset string) string {
+	if len(ep.Values) == 0 {
+		return ""
+	}
+	width := 0
+	for _, value := range ep.Values {
+		if n := len(value.Name) + len(value.Value); n > width {
+			width = n
+		}
+	}
 
+	var builder strings.Builder
+	builder.WriteString("values:\n")
+	for _, value := range ep.Values {
+		padding := strings.Repeat(" ", width-len(value.Name)-len(value.Value))
+		builder.WriteString(fmt.Sprintf("   %s = %s%s  %s(%s)%s\n", value.Name, value.Value, padding, color, value.Source, reset))
+	}
 	return builder.String()
 }
 
@@ -188,8 +223,12 @@ func (ep *ExecutionPlan) formatStepAesthetic(step ExecutionStep, prefix string,
 		if step.Timing != nil && step.Timing.ConcurrencyLimit > 0 {
 			count = step.Timing.ConcurrencyLimit
 		}
+		failFast := ""
+		if parallelFailsFast(step) {
+			failFast = ", fail fast"
+		}
 		concurrency = fmt.Sprintf("%s{%s%d%s concurrent%s}%s",
-			ColorGray, ColorYellow, count, ColorGray, ColorGray, ColorReset)
+			ColorGray, ColorYellow, count, ColorGray, failFast, ColorReset)
 
 		builder.WriteString(fmt.Sprintf("%s%s%s@parallel%s %s\n",
 			prefix, connector, ColorYellow, ColorReset, concurrency))
@@ -198,8 +237,8 @@ func (ep *ExecutionPlan) formatStepAesthetic(step ExecutionStep, prefix string,
 		// Format timeout decorator with duration info
 		duration := ""
 		if step.Timing != nil && step.Timing.Timeout != nil {
-			duration = fmt.Sprintf("%s{%s%s timeout%s}%s",
-				ColorGray, ColorYellow, step.Timing.Timeout.String(), ColorGray, ColorReset)
+			duration = fmt.Sprintf("%s{%s%s timeout%s%s}%s",
+				ColorGray, ColorYellow, step.Timing.Timeout.String(), ColorGray, timeoutSource(step), ColorReset)
 		}
 
 		builder.WriteString(fmt.Sprintf("%s%s%s@timeout%s %s\n",
@@ -211,7 +250,9 @@ func (ep *ExecutionPlan) formatStepAesthetic(step ExecutionStep, prefix string,
 		if step.Timing != nil && step.Timing.RetryAttempts > 0 {
 			attempts = fmt.Sprintf("%s{%s%d%s attempts",
 				ColorGray, ColorYellow, step.Timing.RetryAttempts, ColorGray)
-			if step.Timing.RetryDelay != nil {
+			if len(step.Timing.RetrySchedule) > 0 {
+				attempts += fmt.Sprintf(", delays %s%s%s", ColorYellow, formatRetrySchedule(step.Timing), ColorGray)
+			} else if step.Timing.RetryDelay != nil {
 				attempts += fmt.Sprintf(", %s%s%s delay", ColorYellow, step.Timing.RetryDelay.String(), ColorGray)
 			}
 			attempts += fmt.Sprintf("}%s", ColorReset)
@@ -263,8 +304,53 @@ func (ep *ExecutionPlan) formatStepAestheticNoColor(step ExecutionStep, prefix s
 		connector = "├─ "
 		nextPrefix = prefix + "│  "
 	}
+	builder.WriteString(prefix + connector + stepLabel(step, false) + "\n")
+
+	// Format child steps recursively
+	for i, child := range step.Children {
+		isLastChild := i == len(step.Children)-1
+		builder.WriteString(ep.formatStepAestheticNoColor(child, nextPrefix, isLastChild))
+	}
+
+	return builder.String()
+}
+
+// StringPlain returns the execution plan for screen readers and other accessible output: one
+// line per step, numbered by its position in the tree as in "Step 2.1:", without colors,
+// box-drawing characters or arrows
+func (ep *ExecutionPlan) StringPlain() string {
+	var builder strings.Builder
+
+	commandName := "command"
+	if name, exists := ep.Context["command_name"]; exists {
+		if nameStr, ok := name.(string); ok {
+			commandName = nameStr
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("Plan for %s:\n", commandName))
+	var writeSteps func(steps []ExecutionStep, number string)
+	writeSteps = func(steps []ExecutionStep, number string) {
+		for i, step := range steps {
+			stepNumber := fmt.Sprintf("%s%d", number, i+1)
+			builder.WriteString(fmt.Sprintf("Step %s: %s\n", stepNumber, stepLabel(step, true)))
+			writeSteps(step.Children, stepNumber+".")
+		}
+	}
+	writeSteps(ep.Steps, "")
+	if len(ep.Values) > 0 {
+		builder.WriteString("Values:\n")
+		for _, value := range ep.Values {
+			builder.WriteString(fmt.Sprintf("Value %s = %s, from %s\n", value.Name, value.Value, value.Source))
+		}
+	}
 
-	// Format based on step type (no colors)
+	return builder.String()
+}
+
+// stepLabel describes a step without colors, as the tree shows it or, when plain, with its
+// details spelled out in words
+func stepLabel(step ExecutionStep, plain bool) string {
 	switch step.Type {
 	case StepShell:
 		// Clean shell command formatting
@@ -277,9 +363,15 @@ func (ep *ExecutionPlan) formatStepAestheticNoColor(step ExecutionStep, prefix s
 		if len(cmd) > 80 {
 			cmd = cmd[:77] + "..."
 		}
-
-		builder.WriteString(fmt.Sprintf("%s%s%s\n",
-			prefix, connector, cmd))
+		if plain {
+			// Values the plan resolved read "@env(PORT) is 8080" rather than with an arrow
+			cmd = strings.ReplaceAll(cmd, " → ", " is ")
+			if strings.HasPrefix(cmd, "@") {
+				return cmd
+			}
+			return "run " + cmd
+		}
+		return cmd
 
 	case StepParallel:
 		// Format parallel decorator with concurrency info (no colors)
@@ -287,61 +379,106 @@ func (ep *ExecutionPlan) formatStepAestheticNoColor(step ExecutionStep, prefix s
 		if step.Timing != nil && step.Timing.ConcurrencyLimit > 0 {
 			count = step.Timing.ConcurrencyLimit
 		}
-		concurrency := fmt.Sprintf("{%d concurrent}", count)
-
-		builder.WriteString(fmt.Sprintf("%s%s@parallel %s\n",
-			prefix, connector, concurrency))
+		failFast := ""
+		if parallelFailsFast(step) {
+			failFast = ", fail fast"
+		}
+		if plain {
+			return fmt.Sprintf("@parallel, %d concurrent%s", count, failFast)
+		}
+		return fmt.Sprintf("@parallel {%d concurrent%s}", count, failFast)
 
 	case StepTimeout:
 		// Format timeout decorator with duration info (no colors)
 		duration := ""
 		if step.Timing != nil && step.Timing.Timeout != nil {
-			duration = fmt.Sprintf("{%s timeout}", step.Timing.Timeout.String())
+			duration = fmt.Sprintf("{%s timeout%s}", step.Timing.Timeout.String(), timeoutSource(step))
+			if plain {
+				return fmt.Sprintf("@timeout, %s timeout%s", step.Timing.Timeout.String(), timeoutSource(step))
+			}
 		}
-
-		builder.WriteString(fmt.Sprintf("%s%s@timeout %s\n",
-			prefix, connector, duration))
+		return "@timeout " + duration
 
 	case StepRetry:
 		// Format retry decorator with attempt info (no colors)
 		attempts := ""
 		if step.Timing != nil && step.Timing.RetryAttempts > 0 {
-			attempts = fmt.Sprintf("{%d attempts", step.Timing.RetryAttempts)
-			if step.Timing.RetryDelay != nil {
+			attempts = fmt.Sprintf("%d attempts", step.Timing.RetryAttempts)
+			if len(step.Timing.RetrySchedule) > 0 {
+				schedule := formatRetrySchedule(step.Timing)
+				if plain {
+					schedule = strings.ReplaceAll(schedule, " ×", " times ")
+				}
+				attempts += fmt.Sprintf(", delays %s", schedule)
+			} else if step.Timing.RetryDelay != nil {
 				attempts += fmt.Sprintf(", %s delay", step.Timing.RetryDelay.String())
 			}
-			attempts += "}"
+			if plain {
+				return "@retry, " + attempts
+			}
+			attempts = "{" + attempts + "}"
 		}
-
-		builder.WriteString(fmt.Sprintf("%s%s@retry %s\n",
-			prefix, connector, attempts))
+		return "@retry " + attempts
 
 	case StepConditional:
 		// Format conditional decorator with evaluation info (no colors)
-		evalInfo := ""
-		if step.Condition != nil {
-			evalInfo = fmt.Sprintf("{%s = %s → %s}",
+		if step.Condition == nil {
+			return "@when "
+		}
+		if plain {
+			return fmt.Sprintf("@when, %s is %s, selects %s",
 				step.Condition.Variable,
 				step.Condition.Evaluation.CurrentValue,
 				step.Condition.Evaluation.SelectedBranch)
 		}
-
-		builder.WriteString(fmt.Sprintf("%s%s@when %s\n",
-			prefix, connector, evalInfo))
+		return fmt.Sprintf("@when {%s = %s → %s}",
+			step.Condition.Variable,
+			step.Condition.Evaluation.CurrentValue,
+			step.Condition.Evaluation.SelectedBranch)
 
 	default:
 		// Generic step formatting
-		builder.WriteString(fmt.Sprintf("%s%s%s\n",
-			prefix, connector, step.Description))
+		return step.Description
 	}
+}
 
-	// Format child steps recursively
-	for i, child := range step.Children {
-		isLastChild := i == len(step.Children)-1
-		builder.WriteString(ep.formatStepAestheticNoColor(child, nextPrefix, isLastChild))
+// formatRetrySchedule formats the delays before each retry, with runs of the same delay
+// counted, as in "1s, 2s, 4s, 5s ×3", and "jittered" when they are random
+func formatRetrySchedule(timing *TimingInfo) string {
+	var delays []string
+	for i := 0; i < len(timing.RetrySchedule); {
+		j := i
+		for j < len(timing.RetrySchedule) && timing.RetrySchedule[j] == timing.RetrySchedule[i] {
+			j++
+		}
+		if j-i > 1 {
+			delays = append(delays, fmt.Sprintf("%s ×%d", timing.RetrySchedule[i], j-i))
+		} else {
+			delays = append(delays, timing.RetrySchedule[i].String())
+		}
+		i = j
+	}
+	if timing.RetryJitter {
+		delays = append(delays, "jittered")
 	}
+	return strings.Join(delays, ", ")
+}
 
-	return builder.String()
+// parallelFailsFast reports whether a parallel step cancels its other commands when one fails
+func parallelFailsFast(step ExecutionStep) bool {
+	return step.Decorator != nil && step.Decorator.Parameters["failFast"] == "true"
+}
+
+// timeoutSource names where a timeout step's bound comes from when it isn't a @timeout of the
+// command itself, such as the defaultTimeout of the config block
+func timeoutSource(step ExecutionStep) string {
+	if step.Decorator == nil {
+		return ""
+	}
+	if source, ok := step.Decorator.Parameters["source"].(string); ok && source != "" {
+		return ", " + source
+	}
+	return ""
 }
 
 // AddStep adds a step to the execution plan
@@ -0,0 +1,310 @@
+// Package config resolves the defaults devcmd and generated CLIs run with, such as whether
+// to color output and the lowest log level to write, from a chain of sources. Each source
+// overrides the ones after it:
+//
+//  1. flags, such as --log-level
+//  2. environment variables, such as DEVCMD_LOG_LEVEL
+//  3. the defaults section of the project's devcmd.settings
+//  4. the user config file, ~/.config/devcmd/config.toml
+//
+// The user config file holds TOML key/value pairs:
+//
+//	# ~/.config/devcmd/config.toml
+//	color = false
+//	log_level = "warn"
+//	state_dir = "~/.devcmd"
+//	profile = "local"
+//	trust_ci = true
+//
+// Generated CLIs mirror this package, as they don't import devcmd.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key is a setting of the chain, named in each source
+type Key struct {
+	Name    string // In the user config file, e.g. "log_level"
+	Setting string // In the project settings' defaults section, or "" when projects can't set it
+	EnvVar  string // The environment variable that sets it
+}
+
+// The settings of the chain
+var (
+	Color    = Key{"color", "color", "DEVCMD_COLOR"}
+	LogLevel = Key{"log_level", "logLevel", "DEVCMD_LOG_LEVEL"}
+	Profile  = Key{"profile", "profile", "DEVCMD_PROFILE"}
+	// Projects can't choose the state directory, which holds the commands files allowed to
+	// run, so a cloned project can't bring its own approvals
+	StateDir = Key{"state_dir", "", "DEVCMD_STATE_DIR"}
+	// TrustCI skips the check that commands files were allowed when running in CI. Projects
+	// can't set it either, so a cloned project can't opt out of the check.
+	TrustCI = Key{"trust_ci", "", "DEVCMD_TRUST_CI"}
+)
+
+// Keys lists the settings of the chain, by name
+var Keys = []Key{Color, LogLevel, Profile, StateDir, TrustCI}
+
+// PathEnvVar overrides the user config file
+const PathEnvVar = "DEVCMD_CONFIG"
+
+// SettingsSection is the section of devcmd.settings holding the project's defaults
+const SettingsSection = "defaults"
+
+// Source is where a value of the chain comes from
+type Source string
+
+const (
+	FromFlag    Source = "flag"
+	FromEnv     Source = "environment"
+	FromProject Source = "project settings"
+	FromUser    Source = "user config"
+	FromDefault Source = "default"
+)
+
+// UserFile returns the user config file: $DEVCMD_CONFIG, or devcmd/config.toml in
+// $XDG_CONFIG_HOME or ~/.config, or "" when there is no home directory
+func UserFile() string {
+	if path := os.Getenv(PathEnvVar); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "devcmd", "config.toml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "devcmd", "config.toml")
+}
+
+// LoadUser reads the user config file, which may not exist
+func LoadUser() (map[string]string, error) {
+	path := UserFile()
+	if path == "" {
+		return map[string]string{}, nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	values, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Parse reads key/value pairs of TOML, checking each is a known setting with a valid value.
+// Strings are quoted, as in log_level = "warn", and booleans aren't; tables aren't supported.
+func Parse(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("line %d: tables aren't supported, settings go at the top level", line)
+		}
+		name, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		name = strings.TrimSpace(name)
+		key, ok := lookupKey(name)
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown setting %q (known: %s)", line, name, keyNames())
+		}
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("line %d: %s is set twice", line, name)
+		}
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line, name, err)
+		}
+		if values[name], err = Check(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// parseValue returns a TOML value: a basic or literal string, or a bare boolean or number,
+// any of them followed by a comment
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		quoted, err := strconv.QuotedPrefix(raw)
+		if err != nil {
+			return "", fmt.Errorf("unterminated string %s", raw)
+		}
+		if err := trailingComment(raw[len(quoted):]); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", raw)
+		}
+		if err := trailingComment(raw[end+2:]); err != nil {
+			return "", err
+		}
+		return raw[1 : end+1], nil
+	}
+	value, _, _ := strings.Cut(raw, "#")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return value, nil
+}
+
+// trailingComment checks that only a comment follows a value
+func trailingComment(rest string) error {
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after the value", rest)
+	}
+	return nil
+}
+
+// Check returns a value of a setting as the chain gives it, or an error when it isn't valid:
+// booleans are normalized to true or false and a state directory starting with ~ is expanded
+func Check(key Key, value string) (string, error) {
+	switch key {
+	case Color, TrustCI:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false, got %q", key.Name, value)
+		}
+		return strconv.FormatBool(b), nil
+	case LogLevel:
+		switch value {
+		case "debug", "info", "warn", "error":
+			return value, nil
+		}
+		return "", fmt.Errorf("%s must be debug, info, warn or error, got %q", key.Name, value)
+	case StateDir:
+		if rest, ok := strings.CutPrefix(value, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", key.Name, err)
+			}
+			value = filepath.Join(home, rest)
+		}
+		if !filepath.IsAbs(value) {
+			return "", fmt.Errorf("%s must be an absolute path or start with ~/, got %q", key.Name, value)
+		}
+		return value, nil
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s must not be empty", key.Name)
+	}
+	return value, nil
+}
+
+// ProjectDefaults checks the defaults section of a project's settings, by setting name
+func ProjectDefaults(section map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(section))
+	for setting, value := range section {
+		key, ok := lookupSetting(setting)
+		if !ok {
+			var known []string
+			for _, key := range Keys {
+				if key.Setting != "" {
+					known = append(known, key.Setting)
+				}
+			}
+			return nil, fmt.Errorf("%s.%s: unknown setting (known: %s)", SettingsSection, setting, strings.Join(known, ", "))
+		}
+		checked, err := Check(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", SettingsSection, setting, err)
+		}
+		values[key.Name] = checked
+	}
+	return values, nil
+}
+
+// Chain resolves settings from its sources, each keyed by setting name
+type Chain struct {
+	Flags   map[string]string
+	Getenv  func(string) string
+	Project map[string]string
+	User    map[string]string
+}
+
+// Lookup returns the value of a setting from the first source that sets it, with the
+// source, or "" from FromDefault when none does. An invalid environment variable is an error.
+func (c Chain) Lookup(key Key) (string, Source, error) {
+	if value, ok := c.Flags[key.Name]; ok {
+		return value, FromFlag, nil
+	}
+	if c.Getenv != nil {
+		if value := c.Getenv(key.EnvVar); value != "" {
+			checked, err := Check(key, value)
+			if err != nil {
+				return "", FromEnv, fmt.Errorf("%s: %w", key.EnvVar, err)
+			}
+			return checked, FromEnv, nil
+		}
+	}
+	if value, ok := c.Project[key.Name]; ok && key.Setting != "" {
+		return value, FromProject, nil
+	}
+	if value, ok := c.User[key.Name]; ok {
+		return value, FromUser, nil
+	}
+	return "", FromDefault, nil
+}
+
+// StateDirFromEnv returns the state directory $DEVCMD_STATE_DIR sets, or "" for the default
+// locations. devcmd sets it for the commands it runs when the chain selects one.
+func StateDirFromEnv() string {
+	dir, err := Check(StateDir, os.Getenv(StateDir.EnvVar))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+func lookupKey(name string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func lookupSetting(setting string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Setting != "" && key.Setting == setting {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func keyNames() string {
+	names := make([]string, len(Keys))
+	for i, key := range Keys {
+		names[i] = key.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	values, err := Parse(strings.NewReader(`# Defaults for every project
+color = false
+log_level = "warn"  # quieter
+state_dir = '/var/tmp/devcmd'
+profile = "local"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := map[string]string{"color": "false", "log_level": "warn", "state_dir": "/var/tmp/devcmd", "profile": "local"}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s = %q, want %q", name, values[name], value)
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if values, err := Parse(strings.NewReader(`state_dir = "~/.devcmd"`)); err != nil || values["state_dir"] != filepath.Join(home, ".devcmd") {
+		t.Errorf("state_dir = %q, %v, want it under the home directory", values["state_dir"], err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"colour = true":                      `line 1: unknown setting "colour"`,
+		"[devcmd]\ncolor = true":             "tables aren't supported",
+		"\ncolor":                            "line 2: expected key = value",
+		`log_level = "loud"`:                 "log_level must be debug, info, warn or error",
+		"color = maybe":                      "color must be true or false",
+		`state_dir = "relative/dir"`:         "must be an absolute path",
+		`profile = "a" "b"`:                  "unexpected",
+		`profile = "open`:                    "unterminated string",
+		"color = true\ncolor = false":        "color is set twice",
+		`log_level = "warn"` + "\nprofile =": "line 2: profile: missing value",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestChain_Precedence(t *testing.T) {
+	env := map[string]string{"DEVCMD_LOG_LEVEL": "debug", "DEVCMD_COLOR": "0"}
+	chain := Chain{
+		Flags:   map[string]string{"log_level": "error"},
+		Getenv:  func(name string) string { return env[name] },
+		Project: map[string]string{"log_level": "info", "color": "true", "profile": "staging", "state_dir": "/project", "trust_ci": "true"},
+		User:    map[string]string{"log_level": "warn", "profile": "local", "state_dir": "/home/me/.devcmd"},
+	}
+	tests := []struct {
+		key    Key
+		value  string
+		source Source
+	}{
+		{LogLevel, "error", FromFlag},
+		{Color, "false", FromEnv},
+		{Profile, "staging", FromProject},
+		{StateDir, "/home/me/.devcmd", FromUser}, // Projects can't set it
+		{TrustCI, "", FromDefault},               // Nor this
+	}
+	for _, tt := range tests {
+		value, source, err := chain.Lookup(tt.key)
+		if err != nil || value != tt.value || source != tt.source {
+			t.Errorf("Lookup(%s) = %q, %s, %v, want %q from %s", tt.key.Name, value, source, err, tt.value, tt.source)
+		}
+	}
+
+	// Without the flag, the environment wins
+	chain.Flags = nil
+	if value, source, _ := chain.Lookup(LogLevel); value != "debug" || source != FromEnv {
+		t.Errorf("Lookup(log_level) = %q from %s, want debug from the environment", value, source)
+	}
+	env["DEVCMD_LOG_LEVEL"] = "loud"
+	if _, _, err := chain.Lookup(LogLevel); err == nil || !strings.Contains(err.Error(), "DEVCMD_LOG_LEVEL") {
+		t.Errorf("invalid environment variable: err = %v", err)
+	}
+	if value, source, _ := (Chain{}).Lookup(Profile); value != "" || source != FromDefault {
+		t.Errorf("empty chain Lookup = %q from %s", value, source)
+	}
+}
+
+func TestProjectDefaults(t *testing.T) {
+	values, err := ProjectDefaults(map[string]string{"logLevel": "warn", "color": "no"})
+	if err == nil {
+		t.Fatalf("ProjectDefaults accepted color = no: %v", values)
+	}
+	values, err = ProjectDefaults(map[string]string{"logLevel": "warn", "color": "false"})
+	if err != nil || values["log_level"] != "warn" || values["color"] != "false" {
+		t.Errorf("ProjectDefaults = %v, %v", values, err)
+	}
+	if _, err := ProjectDefaults(map[string]string{"stateDir": "/tmp"}); err == nil || !strings.Contains(err.Error(), "unknown setting") {
+		t.Errorf("projects shouldn't set the state directory: err = %v", err)
+	}
+	if _, err := ProjectDefaults(map[string]string{"trustCI": "true"}); err == nil || !strings.Contains(err.Error(), "unknown setting") {
+		t.Errorf("projects shouldn't skip the trust check: err = %v", err)
+	}
+}
+
+func TestUserFile(t *testing.T) {
+	t.Setenv(PathEnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := UserFile(); got != filepath.Join("/xdg", "devcmd", "config.toml") {
+		t.Errorf("UserFile() = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv(PathEnvVar, path)
+	if values, err := LoadUser(); err != nil || len(values) != 0 {
+		t.Errorf("a missing config file should be empty: %v, %v", values, err)
+	}
+	if err := os.WriteFile(path, []byte("color = nope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUser(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadUser error = %v, want it to name the file", err)
+	}
+}
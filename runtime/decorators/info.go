@@ -0,0 +1,319 @@
+package decorators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// String returns the name of a decorator type, as used in diagnostics
+func (t DecoratorType) String() string {
+	switch t {
+	case ValueType:
+		return "value"
+	case ActionType:
+		return "action"
+	case BlockType:
+		return "block"
+	case PatternType:
+		return "pattern"
+	default:
+		return fmt.Sprintf("DecoratorType(%d)", int(t))
+	}
+}
+
+// Versioned is implemented by decorators whose parameters or behavior have changed in a way
+// commands files can depend on. Decorators that don't implement it are at version 1.
+type Versioned interface {
+	Version() int
+}
+
+// Capability is an optional interface a decorator implements, which the engine and
+// generator rely on beyond the methods of its type
+type Capability string
+
+const (
+	// Preflight decorators check preconditions before any step runs (PreflightChecker)
+	Preflight Capability = "preflight"
+	// CommandReferences decorators run other commands (CommandDependencyProvider)
+	CommandReferences Capability = "command-references"
+	// ReadsVariables decorators read variables through their parameters (VariableReferencer)
+	ReadsVariables Capability = "reads-variables"
+	// AssignsVariables decorators set variables (VariableAssigner)
+	AssignsVariables Capability = "assigns-variables"
+	// QuotesValues decorators give data that shell composition quotes (ShellValueQuoter)
+	QuotesValues Capability = "quotes-values"
+)
+
+// Mode is an execution mode a decorator runs in
+type Mode string
+
+const (
+	InterpreterMode Mode = "interpreter"
+	GeneratorMode   Mode = "generator"
+	PlanMode        Mode = "plan"
+)
+
+// Modes lists every execution mode
+var Modes = []Mode{InterpreterMode, GeneratorMode, PlanMode}
+
+// ModeRestricted is implemented by decorators that can't run in every execution mode.
+// Decorators that don't implement it support them all.
+type ModeRestricted interface {
+	SupportedModes() []Mode
+}
+
+// PlatformRestricted is implemented by decorators that only work on some operating systems.
+// SupportedPlatforms returns the GOOS values supported in a mode, or nil for every one; in
+// generator mode the platform is the target the CLI is built for.
+type PlatformRestricted interface {
+	SupportedPlatforms(mode Mode) []string
+}
+
+// DecoratorInfo describes a registered decorator: what the parser accepts it as, and what the
+// engine and generator can do with it
+type DecoratorInfo struct {
+	Name         string
+	Type         DecoratorType
+	Version      int
+	Description  string
+	Capabilities []Capability
+	Modes        []Mode            // Modes it runs in
+	Platforms    map[Mode][]string // Platforms it runs on in the modes where that is restricted
+}
+
+// newInfo describes a decorator registered as decoratorType
+func newInfo(decorator Decorator, decoratorType DecoratorType) DecoratorInfo {
+	info := DecoratorInfo{
+		Name:        decorator.Name(),
+		Type:        decoratorType,
+		Version:     1,
+		Description: decorator.Description(),
+		Modes:       Modes,
+	}
+	if restricted, ok := decorator.(ModeRestricted); ok {
+		info.Modes = restricted.SupportedModes()
+	}
+	if restricted, ok := decorator.(PlatformRestricted); ok {
+		for _, mode := range info.Modes {
+			if platforms := restricted.SupportedPlatforms(mode); platforms != nil {
+				if info.Platforms == nil {
+					info.Platforms = make(map[Mode][]string)
+				}
+				info.Platforms[mode] = platforms
+			}
+		}
+	}
+	if versioned, ok := decorator.(Versioned); ok {
+		info.Version = versioned.Version()
+	}
+	if _, ok := decorator.(PreflightChecker); ok {
+		info.Capabilities = append(info.Capabilities, Preflight)
+	}
+	if _, ok := decorator.(CommandDependencyProvider); ok {
+		info.Capabilities = append(info.Capabilities, CommandReferences)
+	}
+	if _, ok := decorator.(VariableReferencer); ok {
+		info.Capabilities = append(info.Capabilities, ReadsVariables)
+	}
+	if _, ok := decorator.(VariableAssigner); ok {
+		info.Capabilities = append(info.Capabilities, AssignsVariables)
+	}
+	if _, ok := decorator.(ShellValueQuoter); ok {
+		info.Capabilities = append(info.Capabilities, QuotesValues)
+	}
+	return info
+}
+
+// Has reports whether the decorator has a capability
+func (i DecoratorInfo) Has(capability Capability) bool {
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports reports why the decorator can't run in mode on the platform goos, or nil when it can
+func (i DecoratorInfo) Supports(mode Mode, goos string) error {
+	if !containsMode(i.Modes, mode) {
+		return fmt.Errorf("@%s is not supported in %s mode (it supports %s)", i.Name, mode, joinModes(i.Modes))
+	}
+	if platforms, ok := i.Platforms[mode]; ok && !containsString(platforms, goos) {
+		if mode == GeneratorMode {
+			return fmt.Errorf("@%s is not supported in CLIs built for %s (it supports %s)", i.Name, goos, strings.Join(platforms, ", "))
+		}
+		return fmt.Errorf("@%s is not supported on %s (it supports %s)", i.Name, goos, strings.Join(platforms, ", "))
+	}
+	return nil
+}
+
+// registered returns each registered decorator with its type, the same name once per type
+// it is registered as
+func (r *Registry) registered() []registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var registrations []registration
+	for name, decorator := range r.valueDecorators {
+		registrations = append(registrations, registration{name, decorator, ValueType})
+	}
+	for name, decorator := range r.actionDecorators {
+		registrations = append(registrations, registration{name, decorator, ActionType})
+	}
+	for name, decorator := range r.blockDecorators {
+		registrations = append(registrations, registration{name, decorator, BlockType})
+	}
+	for name, decorator := range r.patternDecorators {
+		registrations = append(registrations, registration{name, decorator, PatternType})
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		a, b := registrations[i], registrations[j]
+		return a.name < b.name || (a.name == b.name && a.decoratorType < b.decoratorType)
+	})
+	return registrations
+}
+
+type registration struct {
+	name          string
+	decorator     Decorator
+	decoratorType DecoratorType
+}
+
+// Describe describes the decorator the parser resolves name to, as GetAny does
+func (r *Registry) Describe(name string) (DecoratorInfo, bool) {
+	decorator, decoratorType, exists := r.GetAny(name)
+	if !exists {
+		return DecoratorInfo{}, false
+	}
+	return newInfo(decorator, decoratorType), true
+}
+
+// DescribeAll describes every registered decorator, sorted by name
+func (r *Registry) DescribeAll() []DecoratorInfo {
+	var infos []DecoratorInfo
+	for _, registration := range r.registered() {
+		infos = append(infos, newInfo(registration.decorator, registration.decoratorType))
+	}
+	return infos
+}
+
+// Verify reports registrations the parser, engine and generator would disagree on: a name
+// registered as two types, which the lexer, the parser and each mode's lookup resolve
+// differently, a decorator registered under another name than its own, schemas that can't be
+// satisfied, and modes or platforms that leave it nowhere to run
+func (r *Registry) Verify() []error {
+	var errs []error
+	types := make(map[string]DecoratorType)
+	for _, reg := range r.registered() {
+		if first, ok := types[reg.name]; ok {
+			errs = append(errs, fmt.Errorf("@%s is registered as two types of decorator, %s and %s", reg.name, first, reg.decoratorType))
+			continue
+		}
+		types[reg.name] = reg.decoratorType
+
+		if name := reg.decorator.Name(); name != reg.name {
+			errs = append(errs, fmt.Errorf("@%s is registered under the name of @%s", name, reg.name))
+		}
+		info := newInfo(reg.decorator, reg.decoratorType)
+		if info.Version < 1 {
+			errs = append(errs, fmt.Errorf("@%s has version %d; versions start at 1", reg.name, info.Version))
+		}
+		params := make(map[string]bool)
+		for _, param := range reg.decorator.ParameterSchema() {
+			if param.Name == "" {
+				errs = append(errs, fmt.Errorf("@%s has a parameter without a name", reg.name))
+			} else if params[param.Name] {
+				errs = append(errs, fmt.Errorf("@%s declares the parameter %s twice", reg.name, param.Name))
+			}
+			params[param.Name] = true
+		}
+		if len(info.Modes) == 0 {
+			errs = append(errs, fmt.Errorf("@%s supports no execution mode", reg.name))
+		}
+		for _, mode := range info.Modes {
+			if !containsMode(Modes, mode) {
+				errs = append(errs, fmt.Errorf("@%s supports the unknown execution mode %q", reg.name, mode))
+			}
+			if platforms, ok := info.Platforms[mode]; ok && len(platforms) == 0 {
+				errs = append(errs, fmt.Errorf("@%s supports no platform in %s mode", reg.name, mode))
+			}
+		}
+		if pattern, ok := reg.decorator.(PatternDecorator); ok {
+			schema := pattern.PatternSchema()
+			for _, required := range schema.RequiredPatterns {
+				if !schema.AllowsAnyIdentifier && !containsString(schema.AllowedPatterns, required) {
+					errs = append(errs, fmt.Errorf("@%s requires the pattern %s, which it doesn't allow", reg.name, required))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// Lookup returns a function finding decorators of one type by name, for the decorator
+// lookups of execution contexts, so every mode resolves names from this registry
+func (r *Registry) Lookup(decoratorType DecoratorType) func(name string) (interface{}, bool) {
+	return func(name string) (interface{}, bool) {
+		var decorator interface{}
+		var exists bool
+		switch decoratorType {
+		case ValueType:
+			decorator, exists = r.GetValue(name)
+		case ActionType:
+			decorator, exists = r.GetAction(name)
+		case BlockType:
+			decorator, exists = r.GetBlock(name)
+		case PatternType:
+			decorator, exists = r.GetPattern(name)
+		}
+		return decorator, exists
+	}
+}
+
+func containsMode(modes []Mode, mode Mode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func joinModes(modes []Mode) string {
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = string(mode)
+	}
+	return strings.Join(names, ", ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe describes a decorator in the global registry
+func Describe(name string) (DecoratorInfo, bool) {
+	return globalRegistry.Describe(name)
+}
+
+// DescribeAll describes every decorator in the global registry, sorted by name
+func DescribeAll() []DecoratorInfo {
+	return globalRegistry.DescribeAll()
+}
+
+// Verify checks the global registry, as devcmd doctor does
+func Verify() []error {
+	return globalRegistry.Verify()
+}
+
+// Lookup returns a function finding decorators of one type in the global registry
+func Lookup(decoratorType DecoratorType) func(name string) (interface{}, bool) {
+	return globalRegistry.Lookup(decoratorType)
+}
@@ -0,0 +1,148 @@
+package decorators
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/execution"
+)
+
+// fakeBlock is a block decorator with a configurable name, schema and version
+type fakeBlock struct {
+	name    string
+	params  []ParameterSchema
+	version int
+}
+
+func (d *fakeBlock) Name() string                          { return d.name }
+func (d *fakeBlock) Description() string                   { return "fake " + d.name }
+func (d *fakeBlock) ParameterSchema() []ParameterSchema    { return d.params }
+func (d *fakeBlock) ImportRequirements() ImportRequirement { return ImportRequirement{} }
+func (d *fakeBlock) Version() int                          { return d.version }
+
+func (d *fakeBlock) ExecuteInterpreter(execution.InterpreterContext, []ast.NamedParameter, []ast.CommandContent) *execution.ExecutionResult {
+	return nil
+}
+
+func (d *fakeBlock) GenerateTemplate(execution.GeneratorContext, []ast.NamedParameter, []ast.CommandContent) (*execution.TemplateResult, error) {
+	return nil, nil
+}
+
+func (d *fakeBlock) ExecutePlan(execution.PlanContext, []ast.NamedParameter, []ast.CommandContent) *execution.ExecutionResult {
+	return nil
+}
+
+// fakeAction is an action decorator that runs other commands
+type fakeAction struct{ fakeBlock }
+
+func (d *fakeAction) ExpandInterpreter(execution.InterpreterContext, []ast.NamedParameter) *execution.ExecutionResult {
+	return nil
+}
+
+func (d *fakeAction) GenerateTemplate(execution.GeneratorContext, []ast.NamedParameter) (*execution.TemplateResult, error) {
+	return nil, nil
+}
+
+func (d *fakeAction) ExpandPlan(execution.PlanContext, []ast.NamedParameter) *execution.ExecutionResult {
+	return nil
+}
+
+func (d *fakeAction) GetCommandDependencies([]ast.NamedParameter) []string { return nil }
+
+func TestRegistry_Describe(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterBlock(&fakeBlock{name: "retry", version: 2})
+	r.RegisterAction(&fakeAction{fakeBlock{name: "cmd", version: 1}})
+
+	info, ok := r.Describe("retry")
+	if !ok || info.Type != BlockType || info.Version != 2 || info.Description != "fake retry" {
+		t.Errorf("Describe(retry) = %+v, %v", info, ok)
+	}
+	if _, ok := r.Describe("missing"); ok {
+		t.Error("Describe(missing) found a decorator")
+	}
+
+	infos := r.DescribeAll()
+	if len(infos) != 2 || infos[0].Name != "cmd" || !infos[0].Has(CommandReferences) || infos[1].Has(CommandReferences) {
+		t.Errorf("DescribeAll() = %+v", infos)
+	}
+
+	if decorator, ok := r.Lookup(BlockType)("retry"); !ok || decorator.(Decorator).Name() != "retry" {
+		t.Errorf("Lookup(BlockType)(retry) = %v, %v", decorator, ok)
+	}
+	if decorator, ok := r.Lookup(ValueType)("retry"); ok || decorator != nil {
+		t.Errorf("Lookup(ValueType)(retry) = %v, %v; want nothing", decorator, ok)
+	}
+	if errs := r.Verify(); len(errs) != 0 {
+		t.Errorf("Verify() = %v", errs)
+	}
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterBlock(&fakeBlock{name: "timeout", version: 1})
+	r.RegisterAction(&fakeAction{fakeBlock{name: "timeout", version: 1}})
+	r.RegisterBlock(&fakeBlock{name: "retry", version: 0, params: []ParameterSchema{{Name: "attempts"}, {Name: "attempts"}, {}}})
+
+	var got []string
+	for _, err := range r.Verify() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"@retry has version 0; versions start at 1",
+		"@retry declares the parameter attempts twice",
+		"@retry has a parameter without a name",
+		"@timeout is registered as two types of decorator, action and block",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Verify() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// restrictedBlock is a block decorator that only runs in some modes and on some platforms
+type restrictedBlock struct {
+	fakeBlock
+	modes     []Mode
+	platforms map[Mode][]string
+}
+
+func (d *restrictedBlock) SupportedModes() []Mode                { return d.modes }
+func (d *restrictedBlock) SupportedPlatforms(mode Mode) []string { return d.platforms[mode] }
+
+func TestDecoratorInfo_Supports(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterBlock(&restrictedBlock{
+		fakeBlock: fakeBlock{name: "sandbox", version: 1},
+		modes:     []Mode{InterpreterMode, PlanMode},
+		platforms: map[Mode][]string{InterpreterMode: {"darwin", "linux"}},
+	})
+	r.RegisterBlock(&fakeBlock{name: "retry", version: 1})
+
+	info, _ := r.Describe("sandbox")
+	for _, tc := range []struct {
+		mode Mode
+		goos string
+		want string
+	}{
+		{InterpreterMode, "linux", ""},
+		{PlanMode, "windows", ""},
+		{InterpreterMode, "windows", "@sandbox is not supported on windows (it supports darwin, linux)"},
+		{GeneratorMode, "linux", "@sandbox is not supported in generator mode (it supports interpreter, plan)"},
+	} {
+		err := info.Supports(tc.mode, tc.goos)
+		if got := fmt.Sprint(err); (tc.want == "" && err != nil) || (tc.want != "" && got != tc.want) {
+			t.Errorf("Supports(%s, %s) = %v, want %q", tc.mode, tc.goos, err, tc.want)
+		}
+	}
+
+	if info, _ := r.Describe("retry"); len(info.Modes) != len(Modes) || info.Platforms != nil || info.Supports(GeneratorMode, "plan9") != nil {
+		t.Errorf("unrestricted decorator = %+v", info)
+	}
+
+	r.RegisterBlock(&restrictedBlock{fakeBlock: fakeBlock{name: "nowhere", version: 1}, platforms: map[Mode][]string{}})
+	if errs := r.Verify(); len(errs) != 1 || errs[0].Error() != "@nowhere supports no execution mode" {
+		t.Errorf("Verify() = %v", errs)
+	}
+}
@@ -15,11 +15,12 @@ type ParameterSchema struct {
 
 // PatternSchema describes what patterns a pattern decorator accepts
 type PatternSchema struct {
-	AllowedPatterns     []string // Specific patterns allowed (e.g., ["main", "error", "finally"] for @try)
-	RequiredPatterns    []string // Patterns that must be present (e.g., ["main"] for @try)
-	AllowsWildcard      bool     // Whether "default" wildcard is allowed (e.g., true for @when)
-	AllowsAnyIdentifier bool     // Whether any identifier is allowed (e.g., true for @when)
-	Description         string   // Human-readable description of pattern rules
+	AllowedPatterns      []string // Specific patterns allowed (e.g., ["main", "error", "finally"] for @try)
+	RequiredPatterns     []string // Patterns that must be present (e.g., ["main"] for @try)
+	AllowsWildcard       bool     // Whether "default" wildcard is allowed (e.g., true for @when)
+	AllowsAnyIdentifier  bool     // Whether any identifier is allowed (e.g., true for @when)
+	AllowsStringPatterns bool     // Whether quoted glob and regex patterns are allowed (e.g., true for @when)
+	Description          string   // Human-readable description of pattern rules
 }
 
 // ImportRequirement describes dependencies needed for code generation
@@ -38,6 +39,51 @@ type CommandDependencyProvider interface {
 	GetCommandDependencies(params []ast.NamedParameter) []string
 }
 
+// VariableReferencer interface for decorators that read variables through their parameters,
+// such as @upper(BRANCH) or @set(TAG = "v@var(VERSION)")
+// This allows the code generator to declare the variables they read
+type VariableReferencer interface {
+	// ReferencedVariables returns the names of the variables this decorator's parameters read
+	ReferencedVariables(params []ast.NamedParameter) []string
+}
+
+// VariableAssigner interface for decorators that define or update variables, such as @set
+// This allows the code generator to declare assignable Go variables instead of constants
+type VariableAssigner interface {
+	VariableReferencer
+
+	// AssignedVariables returns the names of the variables this decorator sets
+	AssignedVariables(params []ast.NamedParameter) []string
+}
+
+// ShellValueQuoter interface for value decorators whose values are data rather than shell syntax,
+// such as @var and @env
+// This allows shell composition to quote their values for the quotes around them
+type ShellValueQuoter interface {
+	// QuotesShellValue reports whether the value should be quoted, false for raw = true
+	QuotesShellValue(params []ast.NamedParameter) bool
+}
+
+// HelperProvider interface for decorators whose generated code calls functions shared by all
+// their uses, such as @secret's providers
+// This allows the code generator to emit each helper once instead of inlining it at every use
+type HelperProvider interface {
+	// GeneratedHelpers returns the Go source of top-level helpers, by the name generated code
+	// calls them with
+	GeneratedHelpers() (map[string]string, error)
+}
+
+// PreflightChecker interface for block decorators that assert preconditions, such as @requires
+// This allows the engine to check every assertion of a command before running any of its steps
+type PreflightChecker interface {
+	// PreflightInterpreter returns the unmet assertions without running the block's content
+	PreflightInterpreter(ctx execution.InterpreterContext, params []ast.NamedParameter) []error
+
+	// PreflightTemplate returns template for Go statements that append a message for each
+	// unmet assertion to the enclosing `failures []string`
+	PreflightTemplate(ctx execution.GeneratorContext, params []ast.NamedParameter) (*execution.TemplateResult, error)
+}
+
 // Decorator is a union interface for all decorator types
 // Used for registry and common operations
 type Decorator interface {
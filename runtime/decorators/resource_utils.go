@@ -3,6 +3,8 @@ package decorators
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -124,17 +126,61 @@ func (te *TimeoutExecutor) Cleanup() {
 	// Context cancellation in defer handles cleanup
 }
 
+// RetryPolicy is how long a RetryExecutor waits before each retry
+type RetryPolicy struct {
+	Delay       time.Duration // Before the first retry, and every retry without backoff
+	Exponential bool          // Double the delay before each further retry
+	MaxDelay    time.Duration // The longest delay, 0 for no limit
+	Jitter      bool          // Wait a random duration from half the delay to all of it
+}
+
+// Backoff returns the delay before the retry that follows the given failed attempt, counted
+// from 1, without jitter
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.Delay
+	for i := 1; p.Exponential && i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Wait returns how long to wait after the given failed attempt, with jitter applied
+func (p RetryPolicy) Wait(attempt int) time.Duration {
+	delay := p.Backoff(attempt)
+	if p.Jitter && delay > 1 {
+		delay = delay/2 + rand.N(delay-delay/2+1)
+	}
+	return delay
+}
+
+// Schedule returns the delays before each retry of maxAttempts attempts, without jitter
+func (p RetryPolicy) Schedule(maxAttempts int) []time.Duration {
+	var schedule []time.Duration
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		schedule = append(schedule, p.Backoff(attempt))
+	}
+	return schedule
+}
+
 // RetryExecutor provides utilities for retry-based execution
 type RetryExecutor struct {
 	maxAttempts int
-	delay       time.Duration
+	policy      RetryPolicy
 }
 
-// NewRetryExecutor creates a new retry executor
+// NewRetryExecutor creates a new retry executor waiting the same delay before each retry
 func NewRetryExecutor(maxAttempts int, delay time.Duration) *RetryExecutor {
+	return NewRetryExecutorWithPolicy(maxAttempts, RetryPolicy{Delay: delay})
+}
+
+// NewRetryExecutorWithPolicy creates a new retry executor waiting as the policy says
+func NewRetryExecutorWithPolicy(maxAttempts int, policy RetryPolicy) *RetryExecutor {
 	return &RetryExecutor{
 		maxAttempts: maxAttempts,
-		delay:       delay,
+		policy:      policy,
 	}
 }
 
@@ -147,7 +193,7 @@ func (re *RetryExecutor) Execute(fn ExecutionFunction) error {
 		} else {
 			lastErr = err
 			if attempt < re.maxAttempts {
-				time.Sleep(re.delay)
+				time.Sleep(re.policy.Wait(attempt))
 			}
 		}
 	}
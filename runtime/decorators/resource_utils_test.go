@@ -0,0 +1,41 @@
+package decorators
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Schedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration
+	}{
+		{"constant", RetryPolicy{Delay: time.Second}, []time.Duration{time.Second, time.Second, time.Second}},
+		{"exponential", RetryPolicy{Delay: time.Second, Exponential: true}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"capped", RetryPolicy{Delay: time.Second, Exponential: true, MaxDelay: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Schedule(4); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Doubling many times stays at the limit rather than overflowing
+	policy := RetryPolicy{Delay: time.Hour, Exponential: true}
+	if got := policy.Backoff(100); got < time.Hour {
+		t.Errorf("expected a long delay, got %v", got)
+	}
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	policy := RetryPolicy{Delay: 100 * time.Millisecond, Exponential: true, Jitter: true}
+	for i := 0; i < 100; i++ {
+		if wait := policy.Wait(2); wait < 100*time.Millisecond || wait > 200*time.Millisecond {
+			t.Fatalf("jittered wait should be between half the delay and all of it, got %v", wait)
+		}
+	}
+}
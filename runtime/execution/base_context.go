@@ -3,6 +3,9 @@ package execution
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
 
 	"github.com/aledsdavies/devcmd/core/ast"
 )
@@ -37,15 +40,31 @@ type BaseExecutionContext struct {
 	Program   *ast.Program
 	Variables map[string]string // Resolved variable values
 	env       map[string]string // Immutable environment variables captured at command start
+	exported  map[string]string // Environment variables exported by decorators for subsequent steps
+	params    map[string]string // Values of the running command's parameters, by name
 
 	// Execution state
 	WorkingDir string
+	shell      []string                  // Command prefix that runs shell steps (e.g. a container); defaults to DefaultShell
+	stdout     io.Writer                 // Destination of shell step output; defaults to os.Stdout
+	stderr     io.Writer                 // Destination of shell step errors; defaults to os.Stderr
+	runner     func(cmd *exec.Cmd) error // Runs shell step processes (e.g. under a PTY); defaults to cmd.Run
+	stdin      string                    // File shell steps read as stdin; empty inherits os.Stdin
+	strict     bool                      // Run shell steps with StrictShellPrefix
+	session    *ShellSession             // Runs shell steps in one long-lived shell; nil runs each in its own
+	heartbeat  *heartbeat                // Reports shell steps that stay silent; nil reports nothing
 	Debug      bool
 	DryRun     bool
 
+	// needs runs the commands a command needs before @cmd runs its body; nil runs none
+	needs func(command *ast.CommandDecl) error
+
 	// Current command name for generating meaningful variable names
 	currentCommand string
 
+	// Go identifiers of the program's commands in generated code, by command name
+	commandIdentifiers map[string]string
+
 	// Decorator lookup functions (set by engine during initialization)
 	valueDecoratorLookup  func(name string) (interface{}, bool)
 	actionDecoratorLookup func(name string) (interface{}, bool)
@@ -81,17 +100,63 @@ func (c *BaseExecutionContext) GetVariable(name string) (string, bool) {
 	return value, exists
 }
 
+// GetParam retrieves the value of a parameter of the running command
+func (c *BaseExecutionContext) GetParam(name string) (string, bool) {
+	value, exists := c.params[name]
+	return value, exists
+}
+
 // SetVariable sets a variable value
 func (c *BaseExecutionContext) SetVariable(name, value string) {
 	c.Variables[name] = value
 }
 
-// GetEnv retrieves an environment variable from the immutable captured environment
+// GetEnv retrieves an environment variable, preferring values exported by
+// decorators over the immutable captured environment
 func (c *BaseExecutionContext) GetEnv(name string) (string, bool) {
+	if value, exists := c.exported[name]; exists {
+		return value, true
+	}
 	value, exists := c.env[name]
 	return value, exists
 }
 
+// ExportEnv makes an environment variable visible to subsequent steps of the
+// command, both through GetEnv and in the environment of executed shells
+func (c *BaseExecutionContext) ExportEnv(name, value string) {
+	if c.exported == nil {
+		c.exported = make(map[string]string)
+	}
+	c.exported[name] = value
+}
+
+// ExportedEnv returns a copy of the environment variables exported by decorators
+func (c *BaseExecutionContext) ExportedEnv() map[string]string {
+	return c.copyExported()
+}
+
+// exportedEnviron returns the process environment with exported variables applied,
+// or nil when nothing has been exported so commands inherit the environment as-is
+func (c *BaseExecutionContext) exportedEnviron() []string {
+	if len(c.exported) == 0 {
+		return nil
+	}
+	environ := os.Environ()
+	for name, value := range c.exported {
+		environ = append(environ, name+"="+value)
+	}
+	return environ
+}
+
+// copyExported returns an independent copy of the exported variables for a child context
+func (c *BaseExecutionContext) copyExported() map[string]string {
+	exported := make(map[string]string, len(c.exported))
+	for name, value := range c.exported {
+		exported[name] = value
+	}
+	return exported
+}
+
 // GetProgram returns the AST program
 func (c *BaseExecutionContext) GetProgram() *ast.Program {
 	return c.Program
@@ -97,7 +97,7 @@ func (c *GeneratorExecutionContext) ExecuteTemplate(result *TemplateResult) (str
 func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 	return template.FuncMap{
 		// buildCommand processes individual commands - delegates to decorators or generates simple shell commands
-		"buildCommand": func(cmd interface{}) string {
+		"buildCommand": func(cmd interface{}) (string, error) {
 			// Handle CommandData wrapper using reflection
 			var actualContent ast.CommandContent
 
@@ -109,15 +109,15 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 					if astContent, ok := contentField.Interface().(ast.CommandContent); ok {
 						actualContent = astContent
 					} else {
-						panic(fmt.Sprintf("Content field is not ast.CommandContent: %T", contentField.Interface()))
+						return "", fmt.Errorf("Content field is not ast.CommandContent: %T", contentField.Interface())
 					}
 				} else {
-					panic("No Content field found in command data")
+					return "", fmt.Errorf("No Content field found in command data")
 				}
 			} else if astContent, ok := cmd.(ast.CommandContent); ok {
 				actualContent = astContent
 			} else {
-				panic(fmt.Sprintf("Invalid command type: %T", cmd))
+				return "", fmt.Errorf("Invalid command type: %T", cmd)
 			}
 
 			switch content := actualContent.(type) {
@@ -135,21 +135,21 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 										if code, err := c.ExecuteTemplate(result); err == nil {
 											return `if err := ` + code + `; err != nil {
 	return err
-}`
+}`, nil
 										} else {
-											panic("Error executing standalone action decorator template for @" + actionDec.Name + ": " + err.Error())
+											return "", fmt.Errorf("Error executing standalone action decorator template for @%s: %w", actionDec.Name, err)
 										}
 									} else {
-										panic("Error generating standalone action decorator template for @" + actionDec.Name + ": " + err.Error())
+										return "", fmt.Errorf("Error generating standalone action decorator template for @%s: %w", actionDec.Name, err)
 									}
 								} else {
-									panic("Standalone action decorator @" + actionDec.Name + " does not implement GenerateTemplate")
+									return "", fmt.Errorf("Standalone action decorator @%s does not implement GenerateTemplate", actionDec.Name)
 								}
 							} else {
-								panic("Unknown standalone action decorator: @" + actionDec.Name)
+								return "", fmt.Errorf("Unknown standalone action decorator: @%s", actionDec.Name)
 							}
 						} else {
-							panic("No action decorator lookup available for standalone @" + actionDec.Name)
+							return "", fmt.Errorf("No action decorator lookup available for standalone @%s", actionDec.Name)
 						}
 					}
 				}
@@ -158,11 +158,13 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 				var commandParts []string
 				var sprintfArgs []string
 				hasValueDecorators := false
+				var quote byte
 
 				for _, part := range content.Parts {
 					switch p := part.(type) {
 					case *ast.TextPart:
 						commandParts = append(commandParts, p.Text)
+						quote = shellQuotingAfter(quote, p.Text)
 					case *ast.ValueDecorator:
 						hasValueDecorators = true
 						commandParts = append(commandParts, "%s") // Placeholder for value decorator
@@ -175,21 +177,25 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 								}); ok {
 									if result, err := valueDec.GenerateTemplate(c, p.Args); err == nil {
 										if code, err := c.ExecuteTemplate(result); err == nil {
+											// Quote the value when the command runs, as the interpreter does
+											if quotesShellValue(c.valueDecoratorLookup, p) {
+												code = fmt.Sprintf("quoteShellValue(%s, %s)", code, goQuoteByte(quote))
+											}
 											sprintfArgs = append(sprintfArgs, code)
 										} else {
-											panic("Error executing value decorator template for @" + p.Name + ": " + err.Error())
+											return "", fmt.Errorf("Error executing value decorator template for @%s: %w", p.Name, err)
 										}
 									} else {
-										panic("Error generating value decorator template for @" + p.Name + ": " + err.Error())
+										return "", fmt.Errorf("Error generating value decorator template for @%s: %w", p.Name, err)
 									}
 								} else {
-									panic("Value decorator @" + p.Name + " does not implement GenerateTemplate")
+									return "", fmt.Errorf("Value decorator @%s does not implement GenerateTemplate", p.Name)
 								}
 							} else {
-								panic("Unknown value decorator: @" + p.Name)
+								return "", fmt.Errorf("Unknown value decorator: @%s", p.Name)
 							}
 						} else {
-							panic("No value decorator lookup available")
+							return "", fmt.Errorf("No value decorator lookup available")
 						}
 					case *ast.ActionDecorator:
 						// ActionDecorators in shell content should delegate to action decorators
@@ -207,27 +213,33 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 										if code, err := c.ExecuteTemplate(result); err == nil {
 											sprintfArgs = append(sprintfArgs, code)
 										} else {
-											panic("Error executing action decorator template for @" + p.Name + ": " + err.Error())
+											return "", fmt.Errorf("Error executing action decorator template for @%s: %w", p.Name, err)
 										}
 									} else {
-										panic("Error generating action decorator template for @" + p.Name + ": " + err.Error())
+										return "", fmt.Errorf("Error generating action decorator template for @%s: %w", p.Name, err)
 									}
 								} else {
-									panic("Action decorator @" + p.Name + " does not implement GenerateTemplate")
+									return "", fmt.Errorf("Action decorator @%s does not implement GenerateTemplate", p.Name)
 								}
 							} else {
-								panic("Unknown action decorator: @" + p.Name)
+								return "", fmt.Errorf("Unknown action decorator: @%s", p.Name)
 							}
 						} else {
-							panic("No action decorator lookup available for @" + p.Name)
+							return "", fmt.Errorf("No action decorator lookup available for @%s", p.Name)
 						}
 					default:
-						panic(fmt.Sprintf("Unsupported shell part type: %T", p))
+						return "", fmt.Errorf("Unsupported shell part type: %T", p)
 					}
 				}
 
 				if hasValueDecorators {
-					// Build fmt.Sprintf call with format string and arguments
+					// Build fmt.Sprintf call with format string and arguments, escaping the
+					// % signs of the text, such as in date +%Y
+					for i, part := range content.Parts {
+						if text, ok := part.(*ast.TextPart); ok {
+							commandParts[i] = strings.ReplaceAll(text.Text, "%", "%%")
+						}
+					}
 					formatString := strings.Join(commandParts, "")
 					allArgs := []string{fmt.Sprintf("%q", formatString)}
 					allArgs = append(allArgs, sprintfArgs...)
@@ -235,31 +247,31 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 
 					return `if err := exec(ctx, ` + commandExpr + `); err != nil {
 	return err
-}`
+}`, nil
 				} else {
 					// Simple case: no value decorators, just text
 					commandString := strings.Join(commandParts, "")
 					return `if err := exec(ctx, ` + fmt.Sprintf("%q", commandString) + `); err != nil {
 	return err
-}`
+}`, nil
 				}
 			case *ast.BlockDecorator:
-				// For block decorators, we delegate to their GenerateTemplate method
-				// The template execution happens at the parent level
+				// For block decorators, we delegate to their GenerateTemplate method, whose
+				// errors, such as invalid parameters, are the decorator's
 				if c.blockDecoratorLookup != nil {
 					if decoratorImpl, exists := c.blockDecoratorLookup(content.Name); exists {
 						if blockDec, ok := decoratorImpl.(interface {
 							GenerateTemplate(ctx GeneratorContext, params []ast.NamedParameter, content []ast.CommandContent) (*TemplateResult, error)
 						}); ok {
-							if result, err := blockDec.GenerateTemplate(c, content.Args, content.Content); err == nil {
-								if code, err := c.ExecuteTemplate(result); err == nil {
-									return code
-								}
+							result, err := blockDec.GenerateTemplate(c, content.Args, content.Content)
+							if err != nil {
+								return "", fmt.Errorf("@%s: %w", content.Name, err)
 							}
+							return c.ExecuteTemplate(result)
 						}
 					}
 				}
-				panic("Unknown block decorator: @" + content.Name)
+				return "", fmt.Errorf("Unknown block decorator: @%s", content.Name)
 			case *ast.PatternDecorator:
 				// For pattern decorators, we delegate to their GenerateTemplate method
 				if c.patternDecoratorLookup != nil {
@@ -267,32 +279,32 @@ func (c *GeneratorExecutionContext) GetTemplateFunctions() template.FuncMap {
 						if patternDec, ok := decoratorImpl.(interface {
 							GenerateTemplate(ctx GeneratorContext, params []ast.NamedParameter, patterns []ast.PatternBranch) (*TemplateResult, error)
 						}); ok {
-							if result, err := patternDec.GenerateTemplate(c, content.Args, content.Patterns); err == nil {
-								if code, err := c.ExecuteTemplate(result); err == nil {
-									return code
-								}
+							result, err := patternDec.GenerateTemplate(c, content.Args, content.Patterns)
+							if err != nil {
+								return "", fmt.Errorf("@%s: %w", content.Name, err)
 							}
+							return c.ExecuteTemplate(result)
 						}
 					}
 				}
-				panic("Unknown pattern decorator: @" + content.Name)
+				return "", fmt.Errorf("Unknown pattern decorator: @%s", content.Name)
 			default:
-				panic(fmt.Sprintf("Unsupported command type: %T", actualContent))
+				return "", fmt.Errorf("Unsupported command type: %T", actualContent)
 			}
 		},
 
 		// Helper functions for template data processing
-		"buildCommands": func(commands []ast.CommandContent) string {
+		"buildCommands": func(commands []ast.CommandContent) (string, error) {
 			// Recursively build multiple commands using the same template system
 			result, err := c.BuildCommandContent(commands)
 			if err != nil {
-				panic("Error building command content: " + err.Error())
+				return "", fmt.Errorf("Error building command content: %w", err)
 			}
 			code, err := c.ExecuteTemplate(result)
 			if err != nil {
-				panic("Template execution error: " + err.Error())
+				return "", fmt.Errorf("Template execution error: %w", err)
 			}
-			return code
+			return code, nil
 		},
 
 		// Duration formatting for clean Go code generation
@@ -413,12 +425,14 @@ func (c *GeneratorExecutionContext) Child() GeneratorContext {
 		Program:   c.Program,
 		Variables: make(map[string]string),
 		env:       c.env, // Share the same immutable environment reference
+		exported:  c.copyExported(),
 
 		// Copy execution state
-		WorkingDir:     c.WorkingDir,
-		Debug:          c.Debug,
-		DryRun:         c.DryRun,
-		currentCommand: c.currentCommand,
+		WorkingDir:         c.WorkingDir,
+		Debug:              c.Debug,
+		DryRun:             c.DryRun,
+		currentCommand:     c.currentCommand,
+		commandIdentifiers: c.commandIdentifiers,
 
 		// Initialize unique counter space for this child to avoid variable name conflicts
 		// Each child gets a unique counter space based on parent's counter and child ID
@@ -0,0 +1,114 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aledsdavies/devcmd/runtime/logging"
+)
+
+// heartbeatCommandWidth is how much of a step's command a heartbeat line shows
+const heartbeatCommandWidth = 60
+
+// heartbeat tracks when the shell steps of a context last wrote output, so a step that stays
+// silent for the interval can say it is still running rather than look hung
+type heartbeat struct {
+	interval time.Duration
+
+	mu     sync.Mutex // Serializes step output with heartbeat lines
+	output time.Time  // When a step last wrote output
+}
+
+// heartbeatWriter records the output written through it in its heartbeat. It is a comparable
+// value, so the steps of a shell session still share the session's shell.
+type heartbeatWriter struct {
+	w io.Writer
+	h *heartbeat
+}
+
+// Write records the time of the output and writes it through
+func (w heartbeatWriter) Write(p []byte) (int, error) {
+	w.h.mu.Lock()
+	defer w.h.mu.Unlock()
+	w.h.output = time.Now()
+	return w.w.Write(p)
+}
+
+// watch prints a heartbeat line for command to stderr each time the step has written no
+// output for the interval, until the returned function is called
+func (h *heartbeat) watch(ctx context.Context, command string, logger *logging.Logger) (stop func()) {
+	start := time.Now()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(h.interval)
+		defer timer.Stop()
+
+		quiet := start // Output or a heartbeat line restarts the silence
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+			}
+
+			h.mu.Lock()
+			if h.output.After(quiet) {
+				quiet = h.output
+			}
+			if wait := h.interval - time.Since(quiet); wait > 0 {
+				h.mu.Unlock()
+				timer.Reset(wait)
+				continue
+			}
+			logger.Infof("", "%s", heartbeatLine(ctx, command, time.Since(start)))
+			h.mu.Unlock()
+
+			quiet = time.Now()
+			timer.Reset(h.interval)
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// heartbeatLine formats a heartbeat as "still running: go test ./... — 2m30s elapsed, 7m30s
+// until timeout", showing the first line of the command and the time left before the
+// context's deadline when it has one
+func heartbeatLine(ctx context.Context, command string, elapsed time.Duration) string {
+	command, _, multiline := strings.Cut(strings.TrimSpace(command), "\n")
+	command = strings.TrimSpace(command)
+	if runes := []rune(command); len(runes) > heartbeatCommandWidth {
+		command, multiline = string(runes[:heartbeatCommandWidth]), true
+	}
+	if multiline {
+		command += "..."
+	}
+
+	line := fmt.Sprintf("still running: %s — %s elapsed", command, roundHeartbeat(elapsed))
+	if deadline, ok := ctx.Deadline(); ok {
+		line += fmt.Sprintf(", %s until timeout", roundHeartbeat(time.Until(deadline)))
+	}
+	return line
+}
+
+// roundHeartbeat rounds a heartbeat duration to the second, or to the millisecond under a second
+func roundHeartbeat(d time.Duration) time.Duration {
+	switch {
+	case d < 0:
+		return 0
+	case d < time.Second:
+		return d.Round(time.Millisecond)
+	default:
+		return d.Round(time.Second)
+	}
+}
@@ -0,0 +1,118 @@
+package execution
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType identifies a point in the command execution lifecycle
+type EventType string
+
+const (
+	EventPreCommand  EventType = "preRun"    // Before a command starts
+	EventPostCommand EventType = "postRun"   // After a command finishes, successfully or not
+	EventPreStep     EventType = "preStep"   // Before each top-level step of a command
+	EventPostStep    EventType = "postStep"  // After each top-level step of a command
+	EventFailure     EventType = "onFailure" // When a command fails
+)
+
+// EventTypes lists all lifecycle events in the order they can fire
+var EventTypes = []EventType{EventPreCommand, EventPreStep, EventPostStep, EventFailure, EventPostCommand}
+
+// Event describes a lifecycle event delivered to hooks
+type Event struct {
+	Type     EventType
+	Command  string
+	Step     int           // 1-based step index, 0 for command-level events
+	StepName string        // Human-readable step description, empty for command-level events
+	Line     int           // Line of the step, or of the command for command-level events, in the commands file
+	Column   int           // Column matching Line
+	Status   string        // "success" or "failed" for post and failure events
+	Duration time.Duration // Elapsed time for post and failure events
+	Err      error         // Failure cause for failed post and failure events
+
+	Attempts       int // Attempts made by @retry blocks in the step, for post step events
+	FailedAttempts int // Attempts of those that failed
+}
+
+// HookFunc is a callback invoked for lifecycle events.
+// Errors returned from pre hooks abort execution; errors from other hooks are
+// reported as command failures only if the command itself succeeded.
+type HookFunc func(event Event) error
+
+// hooks are the callbacks registered with RegisterHook, by event type
+var hooks = struct {
+	sync.RWMutex
+	byType map[EventType][]HookFunc
+}{byType: make(map[EventType][]HookFunc)}
+
+// RegisterHook registers a callback for a lifecycle event of every command devcmd runs,
+// after the hooks of the project settings
+func RegisterHook(eventType EventType, fn HookFunc) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.byType[eventType] = append(hooks.byType[eventType], fn)
+}
+
+// RegisteredHooks returns the callbacks registered for a lifecycle event, in registration order
+func RegisteredHooks(eventType EventType) []HookFunc {
+	hooks.RLock()
+	defer hooks.RUnlock()
+	return append([]HookFunc(nil), hooks.byType[eventType]...)
+}
+
+// ParseEventType resolves a hook name, as in the hooks section of the project settings, to
+// its event type
+func ParseEventType(name string) (EventType, error) {
+	names := make([]string, len(EventTypes))
+	for i, t := range EventTypes {
+		if string(t) == name {
+			return t, nil
+		}
+		names[i] = string(t)
+	}
+	return "", fmt.Errorf("unknown hook %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// ShellHook returns a hook that runs a shell script with the event exposed
+// through DEVCMD_* environment variables
+func ShellHook(script string) HookFunc {
+	return func(event Event) error {
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), event.Environ()...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", event.Type, script, err)
+		}
+		return nil
+	}
+}
+
+// Environ returns the event as the environment variable assignments shell hooks receive
+func (ev Event) Environ() []string {
+	env := []string{
+		"DEVCMD_EVENT=" + string(ev.Type),
+		"DEVCMD_COMMAND=" + ev.Command,
+		"DEVCMD_STATUS=" + ev.Status,
+		fmt.Sprintf("DEVCMD_DURATION_MS=%d", ev.Duration.Milliseconds()),
+	}
+	if ev.Step > 0 {
+		env = append(env,
+			fmt.Sprintf("DEVCMD_STEP=%d", ev.Step),
+			"DEVCMD_STEP_NAME="+ev.StepName)
+	}
+	if ev.Attempts > 0 {
+		env = append(env,
+			fmt.Sprintf("DEVCMD_ATTEMPTS=%d", ev.Attempts),
+			fmt.Sprintf("DEVCMD_FAILED_ATTEMPTS=%d", ev.FailedAttempts))
+	}
+	if ev.Err != nil {
+		env = append(env, "DEVCMD_ERROR="+ev.Err.Error())
+	}
+	return env
+}
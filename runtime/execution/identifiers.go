@@ -0,0 +1,37 @@
+package execution
+
+import "strings"
+
+// CommandIdentifier returns the Go identifier generated code derives from a command name,
+// in camelCase with the case of each part kept: "build" -> "build", "test-all" -> "testAll",
+// "dev_flow" -> "devFlow", "buildAPI" -> "buildAPI". Different names can give the same
+// identifier, so generators take the identifiers of a program's commands from the mapping
+// the engine makes unique, through GeneratorContext.GetCommandIdentifier.
+func CommandIdentifier(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == ' '
+	})
+	if len(parts) == 0 {
+		return name
+	}
+	result := parts[0]
+	for _, part := range parts[1:] {
+		result += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return result
+}
+
+// GetCommandIdentifier returns the Go identifier of a command in generated code, from the
+// mapping set with SetCommandIdentifiers, or derived from its name when it isn't mapped
+func (c *GeneratorExecutionContext) GetCommandIdentifier(name string) string {
+	if identifier, ok := c.commandIdentifiers[name]; ok {
+		return identifier
+	}
+	return CommandIdentifier(name)
+}
+
+// SetCommandIdentifiers sets the Go identifiers of the program's commands, by command name
+// (called by engine during setup)
+func (c *GeneratorExecutionContext) SetCommandIdentifiers(identifiers map[string]string) {
+	c.commandIdentifiers = identifiers
+}
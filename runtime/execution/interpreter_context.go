@@ -2,13 +2,16 @@ package execution
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/logging"
 )
 
 // InterpreterExecutionContext implements InterpreterContext for direct command execution
@@ -25,6 +28,11 @@ type InterpreterExecutionContext struct {
 // INTERPRETER-SPECIFIC FUNCTIONALITY
 // ================================================================================================
 
+// StrictShellPrefix is prepended to shell steps in strict mode, so they stop at the first
+// failing command or unset variable. pipefail makes a failure anywhere in a pipeline fail it,
+// and is enabled where sh supports it (bash, zsh, ksh and busybox; older dash does not).
+const StrictShellPrefix = "set -eu; (set -o pipefail) 2>/dev/null && set -o pipefail; "
+
 // ExecuteShell executes shell content directly
 func (c *InterpreterExecutionContext) ExecuteShell(content *ast.ShellContent) *ExecutionResult {
 	// Compose the command string from parts
@@ -36,17 +44,70 @@ func (c *InterpreterExecutionContext) ExecuteShell(content *ast.ShellContent) *E
 		}
 	}
 
-	// Execute the command
-	cmd := exec.CommandContext(c.Context, "sh", "-c", cmdStr)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Execute the command, through the configured shell prefix when one is set, in strict mode
+	// only where the shell runs POSIX syntax
+	shell := DefaultShell()
+	if len(c.shell) > 0 {
+		shell = c.shell
+	}
+	if c.strict && IsPOSIXShell(shell) {
+		cmdStr = StrictShellPrefix + cmdStr
+	}
+	args := append(append([]string{}, shell[1:]...), ShellFlag(shell), cmdStr)
+	cmd := exec.CommandContext(c.Context, shell[0], args...)
+	cmd.Stdout, cmd.Stderr = c.OutputWriters()
+	logger := c.Logger()
+	if c.heartbeat != nil {
+		stop := c.heartbeat.watch(c.Context, strings.TrimPrefix(cmdStr, StrictShellPrefix), logger)
+		defer stop()
+		cmd.Stdout = heartbeatWriter{w: cmd.Stdout, h: c.heartbeat}
+		cmd.Stderr = heartbeatWriter{w: cmd.Stderr, h: c.heartbeat}
+	}
 	cmd.Stdin = os.Stdin
+	cmd.Env = c.exportedEnviron()
+	if c.stdin != "" {
+		stdin, err := os.Open(c.stdin)
+		if err != nil {
+			return &ExecutionResult{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		defer stdin.Close()
+		cmd.Stdin = stdin
+	}
 
 	if c.WorkingDir != "" {
 		cmd.Dir = c.WorkingDir
 	}
 
-	err = cmd.Run()
+	// Processes the shell started in the background can hold its output open after it exits
+	// or is killed on cancellation; as in sessions, the wait for them is bounded
+	cmd.WaitDelay = sessionWaitDelay
+
+	if logger.Enabled(logging.Debug) {
+		logger.Log(logging.Debug, "shell", "running "+strings.TrimPrefix(cmdStr, StrictShellPrefix), logging.Fields{"dir": cmd.Dir})
+		start := time.Now()
+		defer func() {
+			fields := logging.Fields{"duration": time.Since(start).Round(time.Millisecond)}
+			if err != nil {
+				fields["error"] = err
+			}
+			logger.Log(logging.Debug, "shell", "finished", fields)
+		}()
+	}
+
+	switch {
+	case c.runner != nil:
+		err = c.runner(cmd)
+	case c.session != nil:
+		err = c.session.Run(c.Context, cmd)
+	default:
+		err = cmd.Run()
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil // The shell itself succeeded
+	}
 	return &ExecutionResult{
 		Data:  nil,
 		Error: err,
@@ -163,9 +224,20 @@ func (c *InterpreterExecutionContext) Child() InterpreterContext {
 		Program:   c.Program,
 		Variables: make(map[string]string),
 		env:       c.env, // Share the same immutable environment reference
+		exported:  c.copyExported(),
+		params:    c.params, // Parameters don't change while a command runs
 
 		// Copy execution state
 		WorkingDir:     c.WorkingDir,
+		shell:          c.shell,
+		stdout:         c.stdout,
+		stderr:         c.stderr,
+		runner:         c.runner,
+		stdin:          c.stdin,
+		strict:         c.strict,
+		session:        c.session,
+		heartbeat:      c.heartbeat,
+		needs:          c.needs,
 		Debug:          c.Debug,
 		DryRun:         c.DryRun,
 		currentCommand: c.currentCommand,
@@ -222,13 +294,132 @@ func (c *InterpreterExecutionContext) WithCurrentCommand(commandName string) Int
 	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
 }
 
+// WithShell creates a new interpreter context whose shell steps run through the given
+// command prefix (e.g. "docker run ... image sh") instead of the local sh
+func (c *InterpreterExecutionContext) WithShell(shell []string) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.shell = shell
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithOutput creates a new interpreter context whose shell steps write to the given writers,
+// so block decorators can capture or prefix the output of their content
+func (c *InterpreterExecutionContext) WithOutput(stdout, stderr io.Writer) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.stdout = stdout
+	newBase.stderr = stderr
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// GetShell returns the command prefix shell steps run through, or nil for DefaultShell
+func (c *InterpreterExecutionContext) GetShell() []string {
+	return c.shell
+}
+
+// WithCommandRunner creates a new interpreter context whose shell step processes are run by
+// the given function, e.g. to attach them to a pseudo-terminal, instead of cmd.Run
+func (c *InterpreterExecutionContext) WithCommandRunner(run func(cmd *exec.Cmd) error) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.runner = run
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithStdin creates a new interpreter context whose shell steps read stdin from the given
+// file, opened afresh for each step; an empty path inherits os.Stdin
+func (c *InterpreterExecutionContext) WithStdin(path string) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.stdin = path
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithStrictShell creates a new interpreter context whose shell steps stop at the first
+// failing command, unset variable, or failure inside a pipeline (see StrictShellPrefix)
+func (c *InterpreterExecutionContext) WithStrictShell(strict bool) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.strict = strict
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithShellSession creates a new interpreter context whose shell steps run in the given
+// session where they can, sharing one shell process and its state
+func (c *InterpreterExecutionContext) WithShellSession(session *ShellSession) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.session = session
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithHeartbeat creates a new interpreter context whose shell steps print a "still running"
+// line to stderr each time they write no output for the interval, with the time elapsed and
+// the time left before the context's deadline, such as that of @timeout. A zero interval
+// turns heartbeats off.
+func (c *InterpreterExecutionContext) WithHeartbeat(interval time.Duration) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.heartbeat = nil
+	if interval > 0 {
+		newBase.heartbeat = &heartbeat{interval: interval}
+	}
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithParams creates a new interpreter context for a command run with the given parameter
+// values, which @param substitutes
+func (c *InterpreterExecutionContext) WithParams(params map[string]string) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.params = params
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// WithNeedsRunner creates a new interpreter context whose RunNeeds runs the commands a command
+// needs with the given function, which skips those that already ran
+func (c *InterpreterExecutionContext) WithNeedsRunner(run func(command *ast.CommandDecl) error) InterpreterContext {
+	newBase := *c.BaseExecutionContext
+	newBase.needs = run
+	return &InterpreterExecutionContext{BaseExecutionContext: &newBase}
+}
+
+// RunNeeds runs the commands command declares with needs(...), for decorators such as @cmd
+// that run a command's body themselves
+func (c *InterpreterExecutionContext) RunNeeds(command *ast.CommandDecl) error {
+	if c.needs == nil || len(command.Needs) == 0 {
+		return nil
+	}
+	return c.needs(command)
+}
+
+// Logger returns the log of this context's decorators and shell steps: the default logger,
+// writing text records to the context's error output when a block decorator such as @parallel
+// redirected it. JSON records always go to the default logger's output, so a branch prefix
+// never splits them.
+func (c *InterpreterExecutionContext) Logger() *logging.Logger {
+	logger := logging.Default()
+	if c.stderr == nil || logger.Format() == logging.JSON {
+		return logger
+	}
+	return logger.WithOutput(c.stderr)
+}
+
+// OutputWriters returns the writers shell steps write to
+func (c *InterpreterExecutionContext) OutputWriters() (stdout, stderr io.Writer) {
+	stdout, stderr = c.stdout, c.stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return stdout, stderr
+}
+
 // ================================================================================================
 // SHELL COMMAND COMPOSITION
 // ================================================================================================
 
-// composeShellCommand composes the shell command string from AST parts
+// composeShellCommand composes the shell command string from AST parts.
+// Values of decorators such as @var are quoted for the quotes around them, so the shell
+// reads them literally.
 func (c *InterpreterExecutionContext) composeShellCommand(content *ast.ShellContent) (string, error) {
 	var parts []string
+	var quote byte
 
 	for _, part := range content.Parts {
 		result, err := c.processShellPart(part)
@@ -236,11 +427,19 @@ func (c *InterpreterExecutionContext) composeShellCommand(content *ast.ShellCont
 			return "", err
 		}
 
-		if value, ok := result.(string); ok {
-			parts = append(parts, value)
-		} else {
+		value, ok := result.(string)
+		if !ok {
 			return "", fmt.Errorf("shell part returned non-string result: %T", result)
 		}
+		switch p := part.(type) {
+		case *ast.TextPart:
+			quote = shellQuotingAfter(quote, p.Text)
+		case *ast.ValueDecorator:
+			if quotesShellValue(c.GetValueDecoratorLookup(), p) {
+				value = QuoteShellValue(value, quote)
+			}
+		}
+		parts = append(parts, value)
 	}
 
 	return strings.Join(parts, ""), nil
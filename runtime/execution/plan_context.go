@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/core/plan"
 )
 
 // PlanExecutionContext implements PlanContext for execution planning/dry-run
@@ -33,16 +34,8 @@ func (c *PlanExecutionContext) GenerateShellPlan(content *ast.ShellContent) *Exe
 		}
 	}
 
-	// For now, return a simple plan representation
-	// TODO: Replace with proper plan.PlanElement when we move plan package
-	planData := map[string]interface{}{
-		"type":        "shell",
-		"command":     cmdStr,
-		"description": "Execute shell command: " + cmdStr,
-	}
-
 	return &ExecutionResult{
-		Data:  planData,
+		Data:  plan.Command(cmdStr).WithDescription("Execute shell command: " + cmdStr),
 		Error: nil,
 	}
 }
@@ -92,6 +85,8 @@ func (c *PlanExecutionContext) Child() PlanContext {
 		Program:   c.Program,
 		Variables: make(map[string]string),
 		env:       c.env, // Share the same immutable environment reference
+		exported:  c.copyExported(),
+		params:    c.params, // Parameters don't change while a command runs
 
 		// Copy execution state
 		WorkingDir:     c.WorkingDir,
@@ -99,6 +94,11 @@ func (c *PlanExecutionContext) Child() PlanContext {
 		DryRun:         c.DryRun,
 		currentCommand: c.currentCommand,
 
+		// Copy decorator lookups from parent
+		valueDecoratorLookup:  c.BaseExecutionContext.valueDecoratorLookup,
+		actionDecoratorLookup: c.BaseExecutionContext.actionDecoratorLookup,
+		blockDecoratorLookup:  c.BaseExecutionContext.blockDecoratorLookup,
+
 		// Initialize unique counter space for this child to avoid variable name conflicts
 		// Each child gets a unique counter space based on parent's counter and child ID
 		shellCounter: c.shellCounter + (childID * 1000), // Give each child 1000 numbers of space
@@ -146,6 +146,13 @@ func (c *PlanExecutionContext) WithCurrentCommand(commandName string) PlanContex
 	return &PlanExecutionContext{BaseExecutionContext: &newBase}
 }
 
+// WithParams creates a new plan context for a command run with the given parameter values
+func (c *PlanExecutionContext) WithParams(params map[string]string) PlanContext {
+	newBase := *c.BaseExecutionContext
+	newBase.params = params
+	return &PlanExecutionContext{BaseExecutionContext: &newBase}
+}
+
 // ================================================================================================
 // PLAN-SAFE SHELL COMMAND COMPOSITION
 // ================================================================================================
@@ -153,11 +160,13 @@ func (c *PlanExecutionContext) WithCurrentCommand(commandName string) PlanContex
 // composeShellCommandForPlan composes shell command for plan display without executing ActionDecorators
 func (c *PlanExecutionContext) composeShellCommandForPlan(content *ast.ShellContent) (string, error) {
 	var parts []string
+	var quote byte
 
 	for _, part := range content.Parts {
 		switch p := part.(type) {
 		case *ast.TextPart:
 			parts = append(parts, p.Text)
+			quote = shellQuotingAfter(quote, p.Text)
 		case *ast.ValueDecorator:
 			// For plan mode, resolve value decorators to show actual values
 			// Special handling for @var decorator which just needs variable lookup
@@ -184,6 +193,10 @@ func (c *PlanExecutionContext) composeShellCommandForPlan(content *ast.ShellCont
 					// Look up the variable value
 					if varName != "" {
 						if value, exists := c.GetVariable(varName); exists {
+							// Quote the value as the interpreter does, so the plan shows what runs
+							if quotesShellValue(c.valueDecoratorLookup, p) {
+								value = QuoteShellValue(value, quote)
+							}
 							parts = append(parts, value)
 						} else {
 							parts = append(parts, fmt.Sprintf("@var(%s)", varName))
@@ -194,13 +207,30 @@ func (c *PlanExecutionContext) composeShellCommandForPlan(content *ast.ShellCont
 				} else {
 					parts = append(parts, fmt.Sprintf("@%s(...)", p.Name))
 				}
+			} else if p.Name == "param" && len(p.Args) > 0 {
+				// Parameters show the value the command runs with, quoted as for @var
+				if ident, ok := p.Args[0].Value.(*ast.Identifier); ok {
+					if value, exists := c.GetParam(ident.Name); exists {
+						if quotesShellValue(c.valueDecoratorLookup, p) {
+							value = QuoteShellValue(value, quote)
+						}
+						parts = append(parts, value)
+						continue
+					}
+				}
+				parts = append(parts, c.describeValueDecoratorForPlan(p))
+			} else if p.Name == "env" {
+				// @env describes its value and whether the variable is set
+				parts = append(parts, c.describeValueDecoratorForPlan(p))
 			} else {
-				// For other value decorators, show decorator syntax
+				// For other value decorators, show decorator syntax, since resolving them may
+				// reach out to services or read secrets
 				parts = append(parts, fmt.Sprintf("@%s(...)", p.Name))
 			}
 		case *ast.ActionDecorator:
-			// For plan mode, just show the decorator syntax without executing
-			parts = append(parts, fmt.Sprintf("@%s(...)", p.Name))
+			// For plan mode, show the decorator syntax without executing, unless the
+			// decorator describes itself inline, as @set does with its computed value
+			parts = append(parts, c.describeActionDecoratorForPlan(p))
 		default:
 			return "", fmt.Errorf("unsupported shell part type for plan: %T", part)
 		}
@@ -208,3 +238,41 @@ func (c *PlanExecutionContext) composeShellCommandForPlan(content *ast.ShellCont
 
 	return strings.Join(parts, ""), nil
 }
+
+// describeValueDecoratorForPlan returns a value decorator's inline plan description when its
+// ExpandPlan returns one as a string, falling back to the decorator syntax
+func (c *PlanExecutionContext) describeValueDecoratorForPlan(decorator *ast.ValueDecorator) string {
+	if c.valueDecoratorLookup != nil {
+		if decoratorInterface, exists := c.valueDecoratorLookup(decorator.Name); exists {
+			if valueDecorator, ok := decoratorInterface.(interface {
+				ExpandPlan(ctx PlanContext, params []ast.NamedParameter) *ExecutionResult
+			}); ok {
+				if result := valueDecorator.ExpandPlan(c, decorator.Args); result.Error == nil {
+					if description, ok := result.Data.(string); ok {
+						return description
+					}
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("@%s(...)", decorator.Name)
+}
+
+// describeActionDecoratorForPlan returns an action decorator's inline plan description when
+// its ExpandPlan returns one as a string, falling back to the decorator syntax
+func (c *PlanExecutionContext) describeActionDecoratorForPlan(decorator *ast.ActionDecorator) string {
+	if c.actionDecoratorLookup != nil {
+		if decoratorInterface, exists := c.actionDecoratorLookup(decorator.Name); exists {
+			if actionDecorator, ok := decoratorInterface.(interface {
+				ExpandPlan(ctx PlanContext, params []ast.NamedParameter) *ExecutionResult
+			}); ok {
+				if result := actionDecorator.ExpandPlan(c, decorator.Args); result.Error == nil {
+					if description, ok := result.Data.(string); ok {
+						return description
+					}
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("@%s(...)", decorator.Name)
+}
@@ -0,0 +1,94 @@
+package execution
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/ast"
+)
+
+// shellQuoteSafe are the characters besides ASCII letters and digits a value can contain
+// and still be a single, literal shell word without quotes
+const shellQuoteSafe = "@%+=:,./-_"
+
+// shellValueQuoter is implemented by value decorators whose values are data rather than
+// shell syntax, such as @var and @env (see decorators.ShellValueQuoter)
+type shellValueQuoter interface {
+	QuotesShellValue(params []ast.NamedParameter) bool
+}
+
+// quotesShellValue reports whether the value of decorator should be quoted for the shell
+func quotesShellValue(lookup func(name string) (interface{}, bool), decorator *ast.ValueDecorator) bool {
+	if lookup == nil {
+		return false
+	}
+	decoratorInterface, exists := lookup(decorator.Name)
+	if !exists {
+		return false
+	}
+	quoter, ok := decoratorInterface.(shellValueQuoter)
+	return ok && quoter.QuotesShellValue(decorator.Args)
+}
+
+// shellQuotingAfter returns the quote that is open after text, starting with quote open:
+// 0 outside quotes, or the single or double quote character inside quotes
+func shellQuotingAfter(quote byte, text string) byte {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote == '\'':
+			if text[i] == '\'' {
+				quote = 0
+			}
+		case text[i] == '\\':
+			i++ // The next character is escaped
+		case text[i] == '"':
+			if quote == '"' {
+				quote = 0
+			} else {
+				quote = '"'
+			}
+		case text[i] == '\'' && quote == 0:
+			quote = '\''
+		}
+	}
+	return quote
+}
+
+// QuoteShellValue returns value so the shell reads it literally where quote is open:
+// 0 outside quotes, or the single or double quote character inside quotes.
+// Generated CLIs contain the same logic as quoteShellValue.
+func QuoteShellValue(value string, quote byte) string {
+	switch quote {
+	case '\'':
+		// Close the quotes, add an escaped quote, and reopen them
+		return strings.ReplaceAll(value, "'", `'\''`)
+	case '"':
+		var b strings.Builder
+		for i := 0; i < len(value); i++ {
+			if strings.IndexByte("\\\"$`", value[i]) >= 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(value[i])
+		}
+		return b.String()
+	default:
+		if value == "" {
+			return "''"
+		}
+		for _, r := range value {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(shellQuoteSafe, r)) {
+				return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+			}
+		}
+		return value
+	}
+}
+
+// goQuoteByte returns the Go expression for a quote byte, as passed to quoteShellValue in
+// generated code
+func goQuoteByte(quote byte) string {
+	if quote == 0 {
+		return "0"
+	}
+	return strconv.QuoteRune(rune(quote))
+}
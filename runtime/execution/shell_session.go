@@ -0,0 +1,482 @@
+package execution
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionWaitDelay bounds how long a session waits for output after its shell exits, e.g.
+// when a background process still holds the output open
+const sessionWaitDelay = time.Second
+
+// ShellSession runs the shell steps of a command in one long-lived sh instead of a new
+// process per step, so steps are faster and share state such as the working directory and
+// shell variables.
+//
+// The shell reads the path of each step's script from fd 3, sources it, and writes its exit
+// status to fd 4. A step that exits the shell ends the session and the next step starts a new
+// one. Steps the session can't run as they would run on their own, such as steps through a
+// container shell, in another directory, or with other output, run in their own process.
+type ShellSession struct {
+	mu      sync.Mutex
+	scripts string // Directory holding step scripts, created with the first process
+	steps   int
+	process *sessionProcess
+}
+
+// sessionProcess is the running shell of a session and what its steps must match to use it
+type sessionProcess struct {
+	cmd      *exec.Cmd
+	commands *os.File // Write end of the shell's fd 3
+	status   *os.File // Read end of the shell's fd 4
+	statuses *bufio.Reader
+
+	dir            string
+	path           string
+	stdin          string // File steps read stdin from; empty for os.Stdin
+	stdout, stderr io.Writer
+	streams        []*sessionStream  // Output that is copied rather than inherited
+	marker         []byte            // Written to copied output after each step
+	env            map[string]string // Environment the shell has, as far as devcmd set it
+}
+
+// sessionStream copies a shell output pipe to its writer, step by step
+type sessionStream struct {
+	pipe    *os.File
+	dst     io.Writer
+	pending []byte // Read but not written, as it may be the start of the marker
+}
+
+// sessionExitError is the non-zero exit status of a step that ran in a session
+type sessionExitError struct {
+	code int
+}
+
+func (e *sessionExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode returns the step's exit status
+func (e *sessionExitError) ExitCode() int {
+	return e.code
+}
+
+// NewShellSession creates a session; its shell starts with the first step
+func NewShellSession() *ShellSession {
+	return &ShellSession{}
+}
+
+// Run runs the shell step cmd, as built by ExecuteShell, in the session's shell when it can,
+// and otherwise as its own process. Cancelling ctx kills the session's shell.
+func (s *ShellSession) Run(ctx context.Context, cmd *exec.Cmd) error {
+	command, ok := sessionCommand(cmd)
+	if !ok || runtime.GOOS == "windows" {
+		return cmd.Run()
+	}
+	stdin, ok := sessionStdin(cmd.Stdin)
+	if !ok {
+		return cmd.Run()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.process == nil {
+		process, err := s.start(cmd, stdin)
+		if err != nil {
+			return err
+		}
+		s.process = process
+	} else if !s.process.accepts(cmd, stdin) {
+		return cmd.Run()
+	}
+
+	s.steps++
+	script := filepath.Join(s.scripts, fmt.Sprintf("step-%d.sh", s.steps))
+	if err := os.WriteFile(script, []byte(s.process.script(cmd, command)), 0o600); err != nil {
+		return fmt.Errorf("failed to write session step: %w", err)
+	}
+	defer os.Remove(script)
+
+	exited, err := s.process.step(ctx, script)
+	if exited {
+		s.process = nil
+	}
+	return err
+}
+
+// Close ends the session's shell and removes its step scripts
+func (s *ShellSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.process != nil {
+		s.process.commands.Close()
+		if waitErr := s.process.wait(s.process.copyRest()); waitErr != nil {
+			err = fmt.Errorf("shell session: %w", waitErr)
+		}
+		s.process = nil
+	}
+	if s.scripts != "" {
+		os.RemoveAll(s.scripts)
+		s.scripts = ""
+	}
+	return err
+}
+
+// start starts a shell that runs steps like cmd
+func (s *ShellSession) start(cmd *exec.Cmd, stdin string) (*sessionProcess, error) {
+	if s.scripts == "" {
+		scripts, err := os.MkdirTemp("", "devcmd-session-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to start shell session: %w", err)
+		}
+		s.scripts = scripts
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to start shell session: %w", err)
+	}
+	p := &sessionProcess{
+		dir:    cmd.Dir,
+		path:   cmd.Path,
+		stdin:  stdin,
+		stdout: cmd.Stdout,
+		stderr: cmd.Stderr,
+		marker: []byte("devcmd-step-" + hex.EncodeToString(nonce)),
+		env:    environMap(cmd.Env),
+	}
+
+	// The shell's ends of the pipes are closed once it has started
+	var childFiles []*os.File
+	defer func() {
+		for _, f := range childFiles {
+			f.Close()
+		}
+	}()
+	pipe := func() (*os.File, *os.File, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start shell session: %w", err)
+		}
+		return r, w, nil
+	}
+
+	commandsR, commandsW, err := pipe()
+	if err != nil {
+		return nil, err
+	}
+	childFiles = append(childFiles, commandsR)
+	statusR, statusW, err := pipe()
+	if err != nil {
+		commandsW.Close()
+		return nil, err
+	}
+	childFiles = append(childFiles, statusW)
+	p.commands, p.status = commandsW, statusR
+	p.statuses = bufio.NewReader(statusR)
+
+	shell := exec.Command(cmd.Path)
+	shell.Dir = cmd.Dir
+	shell.Env = cmd.Env
+	shell.ExtraFiles = []*os.File{commandsR, statusW}
+	shell.WaitDelay = sessionWaitDelay
+	if stdin == "" {
+		shell.Stdin = cmd.Stdin
+	}
+
+	// Output to files is inherited; other writers get a pipe with a marker after each step,
+	// so a step returns once all its output is written
+	var markers []string
+	output := func(w io.Writer, fd string) (*os.File, error) {
+		if f, ok := w.(*os.File); ok || w == nil {
+			return f, nil
+		}
+		r, pw, err := pipe()
+		if err != nil {
+			return nil, err
+		}
+		childFiles = append(childFiles, pw)
+		p.streams = append(p.streams, &sessionStream{pipe: r, dst: w})
+		markers = append(markers, fmt.Sprintf("printf '%%s' %s%s", p.marker, fd))
+		return pw, nil
+	}
+	if shell.Stdout, err = output(cmd.Stdout, ""); err == nil {
+		shell.Stderr, err = output(cmd.Stderr, " >&2")
+	}
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+
+	driver := `while IFS= read -r __devcmd_step <&3; do { . "$__devcmd_step"; } 3<&- 4>&-; __devcmd_status=$?; `
+	for _, marker := range markers {
+		driver += marker + "; "
+	}
+	driver += `echo "$__devcmd_status" >&4; done`
+	shell.Args = append(append([]string{}, cmd.Args[:len(cmd.Args)-1]...), driver)
+
+	if err := shell.Start(); err != nil {
+		p.close()
+		return nil, err
+	}
+	p.cmd = shell
+	return p, nil
+}
+
+// accepts reports whether cmd would run the same in the session's shell as on its own
+func (p *sessionProcess) accepts(cmd *exec.Cmd, stdin string) bool {
+	return cmd.Path == p.path &&
+		cmd.Dir == p.dir &&
+		stdin == p.stdin &&
+		sameWriter(cmd.Stdout, p.stdout) &&
+		sameWriter(cmd.Stderr, p.stderr)
+}
+
+// script returns the script that runs command as cmd would run it: with its stdin and
+// environment, and with strict mode undone afterwards
+func (p *sessionProcess) script(cmd *exec.Cmd, command string) string {
+	var script strings.Builder
+	if p.stdin != "" {
+		fmt.Fprintf(&script, "exec <%s\n", QuoteShellValue(p.stdin, 0))
+	}
+
+	// Apply the changes devcmd made to the environment since the last step
+	env := environMap(cmd.Env)
+	var names []string
+	for name := range env {
+		names = append(names, name)
+	}
+	for name := range p.env {
+		if _, ok := env[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := env[name]
+		old, had := p.env[name]
+		if (ok && had && value == old) || !isShellName(name) {
+			continue
+		}
+		if ok {
+			fmt.Fprintf(&script, "export %s=%s\n", name, QuoteShellValue(value, 0))
+		} else {
+			fmt.Fprintf(&script, "unset %s\n", name)
+		}
+	}
+	p.env = env
+
+	if strings.HasPrefix(command, StrictShellPrefix) {
+		script.WriteString("__devcmd_options=$(set +o)\n")
+		script.WriteString(command)
+		script.WriteString("\n__devcmd_status=$?; eval \"$__devcmd_options\"; return $__devcmd_status\n")
+	} else {
+		script.WriteString(command)
+		script.WriteString("\n")
+	}
+	return script.String()
+}
+
+// step runs the script in the shell. exited reports that the shell is gone, because the
+// step exited it or ctx was cancelled.
+func (p *sessionProcess) step(ctx context.Context, script string) (exited bool, err error) {
+	if _, err := fmt.Fprintln(p.commands, script); err != nil {
+		return true, p.wait(p.copyRest())
+	}
+
+	copied := make(chan error, len(p.streams))
+	for _, stream := range p.streams {
+		go func(stream *sessionStream) {
+			copied <- stream.copyStep(p.marker)
+		}(stream)
+	}
+
+	statuses := make(chan string, 1)
+	go func() {
+		line, _ := p.statuses.ReadString('\n')
+		statuses <- line
+	}()
+
+	var line string
+	select {
+	case line = <-statuses:
+	case <-ctx.Done():
+		_ = p.cmd.Process.Kill()
+		<-statuses
+		return true, p.wait(copied)
+	}
+
+	if !strings.HasSuffix(line, "\n") {
+		// The step exited the shell
+		return true, p.wait(copied)
+	}
+	for range p.streams {
+		if err := <-copied; err != nil {
+			return false, err
+		}
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return false, fmt.Errorf("shell session: unexpected status %q", line)
+	}
+	if code != 0 {
+		return false, &sessionExitError{code: code}
+	}
+	return false, nil
+}
+
+// wait waits for the shell to exit, then for the rest of its copied output from copying
+func (p *sessionProcess) wait(copying <-chan error) error {
+	err := p.cmd.Wait()
+	timeout := time.After(sessionWaitDelay)
+	for range p.streams {
+		select {
+		case <-copying:
+		case <-timeout:
+		}
+	}
+	p.close()
+	return err
+}
+
+// copyRest copies the rest of the shell's output once it has no step running
+func (p *sessionProcess) copyRest() <-chan error {
+	copied := make(chan error, len(p.streams))
+	for _, stream := range p.streams {
+		go func(stream *sessionStream) {
+			copied <- stream.copyRest()
+		}(stream)
+	}
+	return copied
+}
+
+// close closes devcmd's ends of the shell's pipes
+func (p *sessionProcess) close() {
+	p.commands.Close()
+	p.status.Close()
+	for _, stream := range p.streams {
+		stream.pipe.Close()
+	}
+}
+
+// copyStep copies output up to the marker written after a step
+func (s *sessionStream) copyStep(marker []byte) error {
+	buf := make([]byte, 32*1024)
+	for {
+		if i := bytes.Index(s.pending, marker); i >= 0 {
+			_, err := s.dst.Write(s.pending[:i])
+			s.pending = append([]byte(nil), s.pending[i+len(marker):]...)
+			return err
+		}
+
+		// Hold back a tail that could be the start of the marker
+		keep := 0
+		for k := len(marker) - 1; k > 0; k-- {
+			if bytes.HasSuffix(s.pending, marker[:k]) {
+				keep = k
+				break
+			}
+		}
+		if n := len(s.pending) - keep; n > 0 {
+			if _, err := s.dst.Write(s.pending[:n]); err != nil {
+				return err
+			}
+			s.pending = append(s.pending[:0], s.pending[n:]...)
+		}
+
+		n, err := s.pipe.Read(buf)
+		s.pending = append(s.pending, buf[:n]...)
+		if err != nil {
+			return s.copyRest()
+		}
+	}
+}
+
+// copyRest copies output until the pipe is closed
+func (s *sessionStream) copyRest() error {
+	if len(s.pending) > 0 {
+		if _, err := s.dst.Write(s.pending); err != nil {
+			return err
+		}
+		s.pending = nil
+	}
+	_, err := io.Copy(s.dst, s.pipe)
+	return err
+}
+
+// sessionCommand returns the shell text of a step that runs with the local sh
+func sessionCommand(cmd *exec.Cmd) (string, bool) {
+	if len(cmd.Args) != 3 || cmd.Args[0] != "sh" || cmd.Args[1] != "-c" {
+		return "", false
+	}
+	if cmd.Process != nil || cmd.SysProcAttr != nil || len(cmd.ExtraFiles) > 0 {
+		return "", false
+	}
+	return cmd.Args[2], true
+}
+
+// sessionStdin returns the file a step reads as stdin, or "" for os.Stdin
+func sessionStdin(stdin io.Reader) (string, bool) {
+	f, ok := stdin.(*os.File)
+	switch {
+	case !ok:
+		return "", false
+	case f == os.Stdin:
+		return "", true
+	default:
+		return f.Name(), f.Name() != ""
+	}
+}
+
+// sameWriter reports whether a and b are the same writer
+func sameWriter(a, b io.Writer) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// environMap returns the variables of an exec.Cmd environment, where nil is os.Environ()
+func environMap(environ []string) map[string]string {
+	if environ == nil {
+		environ = os.Environ()
+	}
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && name != "" {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// isShellName reports whether name is a valid shell variable name
+func isShellName(name string) bool {
+	for i, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
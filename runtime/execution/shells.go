@@ -0,0 +1,84 @@
+package execution
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Shells are the shells @shell and the config block's shell setting select by name
+var Shells = []string{"bash", "sh", "pwsh", "cmd"}
+
+// IsShell reports whether name is one of Shells
+func IsShell(name string) bool {
+	for _, shell := range Shells {
+		if shell == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultShell returns the shell that runs shell steps when none is selected: sh, except on
+// Windows, where it is the first of sh (as Git for Windows provides), pwsh and powershell in
+// PATH, falling back to cmd, which every Windows has
+func DefaultShell() []string {
+	if runtime.GOOS != "windows" {
+		return []string{"sh"}
+	}
+	for _, name := range []string{"sh", "pwsh", "powershell"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return []string{name}
+		}
+	}
+	return []string{"cmd"}
+}
+
+// shellName returns the name of the program at the end of a shell prefix, without its
+// directory or .exe extension
+func shellName(shell []string) string {
+	if len(shell) == 0 {
+		return ""
+	}
+	name := shell[len(shell)-1]
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// ShellFlag returns the flag the shell at the end of a shell prefix takes the command to run
+// with: /C for cmd, -c for the others
+func ShellFlag(shell []string) string {
+	if shellName(shell) == "cmd" {
+		return "/C"
+	}
+	return "-c"
+}
+
+// IsPOSIXShell reports whether the shell at the end of a shell prefix runs POSIX shell
+// syntax, as strict mode's set -eu needs. Prefixes ending in something other than a known
+// shell, such as the image of @container, run sh.
+func IsPOSIXShell(shell []string) bool {
+	switch shellName(shell) {
+	case "cmd", "pwsh", "powershell":
+		return false
+	}
+	return true
+}
+
+// SelectShell returns the shell prefix that runs shell steps with the named shell instead of
+// the one at the end of prefix, keeping what runs it, such as @limits. A prefix ending in
+// something else, such as the image of @container, can't change its shell.
+func SelectShell(prefix []string, name string) ([]string, error) {
+	if !IsShell(name) {
+		return nil, fmt.Errorf("unknown shell %q; expected one of %s", name, strings.Join(Shells, ", "))
+	}
+	if len(prefix) == 0 {
+		return []string{name}, nil
+	}
+	switch shellName(prefix) {
+	case "sh", "bash", "zsh", "pwsh", "powershell", "cmd":
+		return append(append([]string{}, prefix[:len(prefix)-1]...), name), nil
+	}
+	return nil, fmt.Errorf("can't run %s in steps that %s runs", name, prefix[0])
+}
@@ -3,12 +3,15 @@ package execution
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/aledsdavies/devcmd/core/ast"
+	"github.com/aledsdavies/devcmd/runtime/logging"
 )
 
 // ExecutionResult represents the result of executing shell content
@@ -64,6 +67,9 @@ type BaseContext interface {
 	GetVariable(name string) (string, bool)
 	SetVariable(name, value string)
 	GetEnv(name string) (string, bool)
+	GetParam(name string) (string, bool)
+	ExportEnv(name, value string)
+	ExportedEnv() map[string]string
 	InitializeVariables() error
 
 	// Program access
@@ -96,6 +102,19 @@ type InterpreterContext interface {
 	WithCancel() (InterpreterContext, context.CancelFunc)
 	WithWorkingDir(workingDir string) InterpreterContext
 	WithCurrentCommand(commandName string) InterpreterContext
+	WithShell(shell []string) InterpreterContext
+	GetShell() []string
+	WithOutput(stdout, stderr io.Writer) InterpreterContext
+	OutputWriters() (stdout, stderr io.Writer)
+	Logger() *logging.Logger
+	WithCommandRunner(run func(cmd *exec.Cmd) error) InterpreterContext
+	WithStdin(path string) InterpreterContext
+	WithStrictShell(strict bool) InterpreterContext
+	WithShellSession(session *ShellSession) InterpreterContext
+	WithHeartbeat(interval time.Duration) InterpreterContext
+	WithParams(params map[string]string) InterpreterContext
+	WithNeedsRunner(run func(command *ast.CommandDecl) error) InterpreterContext
+	RunNeeds(command *ast.CommandDecl) error
 }
 
 // TemplateResult contains a parsed template and its data
@@ -120,6 +139,7 @@ type GeneratorContext interface {
 
 	// Context info for generation
 	GetCurrentCommand() string
+	GetCommandIdentifier(name string) string
 
 	// Environment variable tracking for generated code
 	TrackEnvironmentVariableReference(key, defaultValue string)
@@ -133,7 +153,8 @@ type GeneratorContext interface {
 type PlanContext interface {
 	BaseContext
 
-	// Plan generation - commands produce plan elements for visualization
+	// Plan generation - commands produce plan elements for visualization; GenerateShellPlan
+	// returns a plan.PlanElement as its data, as block and pattern decorators' ExecutePlan do
 	GenerateShellPlan(content *ast.ShellContent) *ExecutionResult
 	GenerateCommandPlan(commandName string) (*ExecutionResult, error)
 
@@ -147,6 +168,7 @@ type PlanContext interface {
 	WithCancel() (PlanContext, context.CancelFunc)
 	WithWorkingDir(workingDir string) PlanContext
 	WithCurrentCommand(commandName string) PlanContext
+	WithParams(params map[string]string) PlanContext
 }
 
 // ================================================================================================
@@ -197,6 +219,7 @@ func newBaseContext(ctx context.Context, program *ast.Program) *BaseExecutionCon
 		Program:    program,
 		Variables:  make(map[string]string),
 		env:        envMap,
+		exported:   make(map[string]string),
 		WorkingDir: workingDir,
 		Debug:      false,
 		DryRun:     false,
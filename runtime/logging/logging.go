@@ -0,0 +1,317 @@
+// Package logging is the leveled log of the interpreter, its decorators and the shell executor.
+// Records go to stderr, either as text lines in the form decorators have always written,
+//
+//	@retry: attempt 1 of 3 failed: exit status 1
+//
+// or, for tools that read them, as one JSON object per line:
+//
+//	{"time":"2025-01-02T15:04:05.000Z","level":"warn","source":"@retry","msg":"attempt 1 of 3 failed: exit status 1"}
+//
+// In accessible output, for screen readers, text records start with their level in words
+// rather than leaving it to color or symbols:
+//
+//	Warning: @retry: attempt 1 of 3 failed: exit status 1
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a record; a logger writes the records at or above its level
+type Level int
+
+const (
+	Debug Level = iota // What the interpreter is doing, such as each shell step it runs
+	Info               // Progress worth showing by default, such as a skipped @cache block
+	Warn               // Something that didn't go as asked but didn't fail, such as a failed @retry attempt
+	Error              // A failure
+)
+
+// String returns the level's name, as --log-level takes it
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel returns the level with the given name
+func ParseLevel(name string) (Level, error) {
+	for level := Debug; level <= Error; level++ {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return Info, fmt.Errorf("unsupported log level %q: expected debug, info, warn or error", name)
+}
+
+// Format is how records are written
+type Format string
+
+const (
+	Text Format = "text" // "source: message key=value", the way decorators write to stderr
+	JSON Format = "json" // One object per line with time, level, source, msg and the fields
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case Text:
+		return Text, nil
+	case JSON:
+		return JSON, nil
+	default:
+		return Text, fmt.Errorf("unsupported log format %q: expected text or json", name)
+	}
+}
+
+// Fields are the structured details of a record, written after its message
+type Fields map[string]interface{}
+
+// Logger writes records at or above its level in its format, each in a single write so
+// concurrent records don't interleave
+type Logger struct {
+	level  Level
+	format Format
+	out    io.Writer // nil writes to os.Stderr as it is when the record is written
+	mu     *sync.Mutex
+	now    func() time.Time
+}
+
+// New returns a logger writing to out, or to os.Stderr when out is nil
+func New(out io.Writer, level Level, format Format) *Logger {
+	return &Logger{level: level, format: format, out: out, mu: &sync.Mutex{}, now: time.Now}
+}
+
+// WithOutput returns a logger with the same level and format that writes to out, such as the
+// error output of a @parallel branch
+func (l *Logger) WithOutput(out io.Writer) *Logger {
+	logger := *l
+	logger.out = out
+	logger.mu = &sync.Mutex{}
+	return &logger
+}
+
+// Level returns the lowest level the logger writes
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Format returns the format the logger writes records in
+func (l *Logger) Format() Format {
+	return l.format
+}
+
+// Enabled reports whether the logger writes records at the given level
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log writes a record from source, such as "@retry" or "shell", with optional fields. Secrets
+// registered with AddSecret are masked in the message and the fields.
+func (l *Logger) Log(level Level, source, message string, fields Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+	message, fields = Mask(message), maskFields(fields)
+	var line []byte
+	if l.format == JSON {
+		line = l.jsonRecord(level, source, message, fields)
+	} else if Accessible() {
+		line = append([]byte(levelWords[level]+": "), textRecord(source, message, fields)...)
+	} else {
+		line = textRecord(source, message, fields)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := l.out
+	if out == nil {
+		out = os.Stderr
+	}
+	_, _ = out.Write(line)
+}
+
+// Debugf writes a formatted debug record
+func (l *Logger) Debugf(source, format string, args ...interface{}) {
+	if l.Enabled(Debug) {
+		l.Log(Debug, source, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Infof writes a formatted info record
+func (l *Logger) Infof(source, format string, args ...interface{}) {
+	l.Log(Info, source, fmt.Sprintf(format, args...), nil)
+}
+
+// Warnf writes a formatted warning record
+func (l *Logger) Warnf(source, format string, args ...interface{}) {
+	l.Log(Warn, source, fmt.Sprintf(format, args...), nil)
+}
+
+// Errorf writes a formatted error record
+func (l *Logger) Errorf(source, format string, args ...interface{}) {
+	l.Log(Error, source, fmt.Sprintf(format, args...), nil)
+}
+
+func textRecord(source, message string, fields Fields) []byte {
+	var b strings.Builder
+	if source != "" {
+		b.WriteString(source)
+		b.WriteString(": ")
+	}
+	b.WriteString(message)
+	for _, name := range fieldNames(fields) {
+		fmt.Fprintf(&b, " %s=%v", name, fields[name])
+	}
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+func (l *Logger) jsonRecord(level Level, source, message string, fields Fields) []byte {
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSON(&b, l.now().UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, level.String())
+	if source != "" {
+		b.WriteString(`,"source":`)
+		writeJSON(&b, source)
+	}
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, message)
+	for _, name := range fieldNames(fields) {
+		b.WriteString(",")
+		writeJSON(&b, name)
+		b.WriteString(":")
+		value := fields[name]
+		switch v := value.(type) {
+		case time.Duration:
+			// Durations are written as they read, "1.5s", rather than in nanoseconds
+			value = v.String()
+		case error:
+			value = v.Error()
+		}
+		writeJSON(&b, value)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// writeJSON writes a value as JSON, or its text as a JSON string when it has no JSON form
+func writeJSON(b *strings.Builder, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+func fieldNames(fields Fields) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(nil, Info, Text)
+)
+
+// Default returns the logger the interpreter and its decorators write to: info and above as
+// text on stderr, unless Configure or SetDefault changed it
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger
+func SetDefault(logger *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = logger
+}
+
+// Configure sets the default logger's level and format by name, as the --log-level and
+// --log-format flags give them
+func Configure(level, format string) error {
+	parsedLevel, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	parsedFormat, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	SetDefault(New(nil, parsedLevel, parsedFormat))
+	return nil
+}
+
+// Debugf writes a formatted debug record to the default logger
+func Debugf(source, format string, args ...interface{}) {
+	Default().Debugf(source, format, args...)
+}
+
+// Infof writes a formatted info record to the default logger
+func Infof(source, format string, args ...interface{}) {
+	Default().Infof(source, format, args...)
+}
+
+// Warnf writes a formatted warning record to the default logger
+func Warnf(source, format string, args ...interface{}) {
+	Default().Warnf(source, format, args...)
+}
+
+// Errorf writes a formatted error record to the default logger
+func Errorf(source, format string, args ...interface{}) {
+	Default().Errorf(source, format, args...)
+}
+
+// AccessibleEnvVar turns on accessible output when set to a true value such as 1, as the
+// --accessible flag does
+const AccessibleEnvVar = "DEVCMD_ACCESSIBLE"
+
+// accessible is whether output is accessible, from AccessibleEnvVar unless SetAccessible
+// changed it
+var accessible = func() *atomic.Bool {
+	var on atomic.Bool
+	enabled, _ := strconv.ParseBool(os.Getenv(AccessibleEnvVar))
+	on.Store(enabled)
+	return &on
+}()
+
+// levelWords are the words accessible text records start with
+var levelWords = map[Level]string{Debug: "Debug", Info: "Info", Warn: "Warning", Error: "Error"}
+
+// SetAccessible turns accessible output on or off
+func SetAccessible(on bool) {
+	accessible.Store(on)
+}
+
+// Accessible reports whether output is for screen readers and other assistive technology:
+// plain lines with explicit status words, without color-only signals, box-drawing
+// characters, symbols or animation. Plans, logs, summaries and prompts follow it.
+func Accessible() bool {
+	return accessible.Load()
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"debug": Debug, "INFO": Info, "warn": Warn, "error": Error} {
+		got, err := ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil || !strings.Contains(err.Error(), "expected debug, info, warn or error") {
+		t.Errorf("ParseLevel(verbose) error = %v", err)
+	}
+	if _, err := ParseFormat("yaml"); err == nil || !strings.Contains(err.Error(), "expected text or json") {
+		t.Errorf("ParseFormat(yaml) error = %v", err)
+	}
+}
+
+func TestLogger_Text(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, Info, Text)
+
+	logger.Debugf("shell", "running %s", "go build")
+	logger.Warnf("@retry", "attempt %d of %d failed: %v", 1, 3, errors.New("exit status 1"))
+	logger.Log(Info, "@parallel", "branch finished", Fields{"duration": 1500 * time.Millisecond, "branch": "build"})
+
+	want := "@retry: attempt 1 of 3 failed: exit status 1\n@parallel: branch finished branch=build duration=1.5s\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLogger_Accessible(t *testing.T) {
+	defer SetAccessible(Accessible())
+	SetAccessible(true)
+	var out bytes.Buffer
+	logger := New(&out, Info, Text)
+
+	logger.Warnf("@retry", "attempt %d of %d failed: %v", 1, 3, errors.New("exit status 1"))
+	logger.Infof("", "still running: go test ./...")
+
+	want := "Warning: @retry: attempt 1 of 3 failed: exit status 1\nInfo: still running: go test ./...\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, Debug, JSON)
+	logger.now = func() time.Time { return time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC) }
+
+	logger.Log(Debug, "shell", "finished", Fields{"duration": 2 * time.Second, "error": errors.New("exit status 2"), "exit": 2})
+
+	want := `{"time":"2025-01-02T15:04:05.000Z","level":"debug","source":"shell","msg":"finished","duration":"2s","error":"exit status 2","exit":2}` + "\n"
+	if out.String() != want {
+		t.Errorf("output = %s, want %s", out.String(), want)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Errorf("record is not JSON: %v", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	if err := Configure("debug", "json"); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	if Default().Level() != Debug || Default().Format() != JSON {
+		t.Errorf("default logger is %v %v, want debug json", Default().Level(), Default().Format())
+	}
+
+	var out bytes.Buffer
+	Default().WithOutput(&out).Infof("@cache", "inputs unchanged, skipping")
+	if !strings.Contains(out.String(), `"source":"@cache","msg":"inputs unchanged, skipping"`) {
+		t.Errorf("WithOutput record = %s", out.String())
+	}
+
+	if err := Configure("loud", "text"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestLogger_MasksSecrets(t *testing.T) {
+	t.Cleanup(func() {
+		secrets.Lock()
+		secrets.values = nil
+		secrets.Unlock()
+	})
+	AddSecret("hunter2")
+	AddSecret("hunter22")
+	AddSecret("line one\nline two")
+	AddSecret(" ")
+
+	var out bytes.Buffer
+	logger := New(&out, Debug, JSON)
+	logger.now = func() time.Time { return time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC) }
+	logger.Log(Debug, "shell", `running psql "hunter22"`, Fields{"error": errors.New("bad password hunter2"), "line": "line two"})
+
+	want := `{"time":"2025-01-02T15:04:05.000Z","level":"debug","source":"shell","msg":"running psql \"***\"","error":"bad password ***","line":"***"}` + "\n"
+	if out.String() != want {
+		t.Errorf("output = %s, want %s", out.String(), want)
+	}
+
+	var buf bytes.Buffer
+	if _, err := MaskWriter(&buf).Write([]byte("Cause: auth failed for hunter2 \n")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Cause: auth failed for *** \n" {
+		t.Errorf("MaskWriter wrote %q", buf.String())
+	}
+}
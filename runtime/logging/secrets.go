@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Masked is what records, and the output of MaskWriter, show in place of a secret
+const Masked = "***"
+
+// secrets are the values records never show, registered as @secret reads them
+var secrets struct {
+	sync.RWMutex
+	values []string
+}
+
+// AddSecret registers a value to mask from then on. A multi-line value is also masked line
+// by line, as tools may split it.
+func AddSecret(value string) {
+	candidates := []string{value}
+	if strings.Contains(value, "\n") {
+		candidates = append(candidates, strings.Split(value, "\n")...)
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+	for _, candidate := range candidates {
+		candidate = strings.TrimRight(candidate, "\r")
+		if strings.TrimSpace(candidate) == "" || containsValue(secrets.values, candidate) {
+			continue
+		}
+		secrets.values = append(secrets.values, candidate)
+	}
+	// Longer values first, so a secret containing another is masked whole
+	sort.SliceStable(secrets.values, func(i, j int) bool { return len(secrets.values[i]) > len(secrets.values[j]) })
+}
+
+// Mask returns s with every registered secret replaced by Masked
+func Mask(s string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	for _, value := range secrets.values {
+		s = strings.ReplaceAll(s, value, Masked)
+	}
+	return s
+}
+
+// MaskWriter returns a writer that masks the registered secrets in each write to w, for
+// output written whole, such as error messages
+func MaskWriter(w io.Writer) io.Writer {
+	return maskWriter{w: w}
+}
+
+type maskWriter struct {
+	w io.Writer
+}
+
+func (m maskWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, Mask(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// maskFields returns fields with the registered secrets masked in their text values
+func maskFields(fields Fields) Fields {
+	if len(fields) == 0 {
+		return fields
+	}
+	masked := make(Fields, len(fields))
+	for name, value := range fields {
+		switch v := value.(type) {
+		case string:
+			value = Mask(v)
+		case error:
+			value = Mask(v.Error())
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
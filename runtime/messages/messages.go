@@ -0,0 +1,294 @@
+// Package messages is the catalog of user-facing messages shown by devcmd, its decorators and
+// generated CLIs, such as @confirm prompts, help headings and error hints, by locale. English
+// is built in, as is the en-XA pseudo-locale, which accents and brackets every message so
+// text that doesn't come from the catalog stands out. Teams add their own locales from
+// catalog files, JSON objects of messages by ID:
+//
+//	{"confirm.prompt": "%s [j/N]: ", "confirm.answers": "j,ja"}
+//
+// Messages missing from a locale fall back to English.
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ID names a message in the catalog
+type ID string
+
+// The messages of the catalog
+const (
+	ConfirmPrompt           ID = "confirm.prompt"           // @confirm's prompt, with the message; no is the default
+	ConfirmPromptDefaultYes ID = "confirm.promptDefaultYes" // @confirm's prompt when yes is the default
+	ConfirmAnswers          ID = "confirm.answers"          // Comma-separated answers that confirm, lower case
+	ConfirmCancelled        ID = "confirm.cancelled"        // The error when a @confirm is declined
+	ConfirmAutoConfirmed    ID = "confirm.autoConfirmed"    // @confirm skipping its prompt in CI, with the message
+	InputReadFailed         ID = "input.readFailed"         // Reading an answer failed, wrapping the error
+
+	ErrorPrefix       ID = "error.prefix"       // Errors without more structure, with the error
+	ErrorCause        ID = "error.cause"        // The cause under an error
+	ErrorDetails      ID = "error.details"      // Details under an error
+	ErrorParse        ID = "error.parse"        // The parse error under an error
+	HintCouldBe       ID = "hint.couldBe"       // Commands an abbreviation could stand for
+	HintDidYouMean    ID = "hint.didYouMean"    // Commands close to an unknown one
+	HintAvailable     ID = "hint.available"     // The commands there are
+	HintNoCommands    ID = "hint.noCommands"    // What to do without a commands file
+	HintDefineVar     ID = "hint.defineVar"     // What to do about an undefined variable, with its name
+	CommandFailed     ID = "command.failed"     // A generated CLI's command failing, with its name and error
+	TriggerFailed     ID = "trigger.failed"     // A failure trigger failing, with its command and error
+	UnknownCommand    ID = "command.unknown"    // An unknown command, with it and the CLI
+	UnknownSuggestion ID = "command.suggestion" // Introduces the commands close to an unknown one
+	RunHelp           ID = "command.runHelp"    // Points to help after an error, with the CLI
+
+	HelpUsage       ID = "help.usage"       // Help heading of the usage line
+	HelpAliases     ID = "help.aliases"     // Help heading of a command's aliases
+	HelpExamples    ID = "help.examples"    // Help heading of examples
+	HelpCommands    ID = "help.commands"    // Help heading of the subcommands
+	HelpFlags       ID = "help.flags"       // Help heading of the flags
+	HelpGlobalFlags ID = "help.globalFlags" // Help heading of inherited flags
+	HelpTopics      ID = "help.topics"      // Help heading of additional help topics
+	HelpMoreCommand ID = "help.moreCommand" // Help's closing line, with the CLI
+	HelpSubcommand  ID = "help.subcommand"  // A usage line's placeholder for a subcommand
+)
+
+// English is the built-in catalog every locale falls back to
+var English = map[ID]string{
+	ConfirmPrompt:           "%s [y/N]: ",
+	ConfirmPromptDefaultYes: "%s [Y/n]: ",
+	ConfirmAnswers:          "y,yes",
+	ConfirmCancelled:        "user cancelled execution",
+	ConfirmAutoConfirmed:    "CI environment detected - auto-confirming: %s",
+	InputReadFailed:         "failed to read user input: %w",
+
+	ErrorPrefix:       "Error: %v",
+	ErrorCause:        "Cause: %v",
+	ErrorDetails:      "Details: %v",
+	ErrorParse:        "Parse error: %v",
+	HintCouldBe:       "Could be: %s",
+	HintDidYouMean:    "Did you mean: %s?",
+	HintAvailable:     "Available commands: %v",
+	HintNoCommands:    "Create a commands.cli file or pipe command definitions to stdin",
+	HintDefineVar:     "Make sure the variable '%s' is defined before using it",
+	CommandFailed:     "Command '%s' failed: %s",
+	TriggerFailed:     "Trigger 'on failure of %s' failed: %s",
+	UnknownCommand:    "unknown command %q for %q",
+	UnknownSuggestion: "Did you mean this?",
+	RunHelp:           "Run '%s --help' for usage",
+
+	HelpUsage:       "Usage:",
+	HelpAliases:     "Aliases:",
+	HelpExamples:    "Examples:",
+	HelpCommands:    "Available Commands:",
+	HelpFlags:       "Flags:",
+	HelpGlobalFlags: "Global Flags:",
+	HelpTopics:      "Additional help topics:",
+	HelpMoreCommand: `Use "%s [command] --help" for more information about a command.`,
+	HelpSubcommand:  "[command]",
+}
+
+// Pseudo is the pseudo-locale, for testing that user-facing text comes from the catalog
+const Pseudo = "en-XA"
+
+// LocaleEnvVar selects the locale, ahead of LC_ALL, LC_MESSAGES and LANG
+const LocaleEnvVar = "DEVCMD_LOCALE"
+
+// verbatim are the messages that are input rather than output, which the pseudo-locale
+// leaves as they are so they can still be typed
+var verbatim = map[ID]bool{ConfirmAnswers: true}
+
+var registry = struct {
+	sync.RWMutex
+	catalogs map[string]map[ID]string
+	locale   string
+}{
+	catalogs: map[string]map[ID]string{"en": English, Pseudo: pseudoCatalog(English)},
+	locale:   LocaleFromEnv(os.Getenv),
+}
+
+// Register adds a catalog for a locale, such as "de" or "pt-BR", replacing any before it.
+// Its messages must be in the English catalog and take the same arguments.
+func Register(locale string, catalog map[ID]string) error {
+	for id, message := range catalog {
+		english, ok := English[id]
+		if !ok {
+			return fmt.Errorf("unknown message %q", id)
+		}
+		if want, got := verbs(english), verbs(message); want != got {
+			return fmt.Errorf("message %q must take the arguments of %q (%s), got %q", id, english, want, message)
+		}
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.catalogs[normalize(locale)] = catalog
+	return nil
+}
+
+// LoadFile registers the catalog in a JSON file for a locale
+func LoadFile(locale, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var catalog map[ID]string
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return fmt.Errorf("%s is not a message catalog: %w", path, err)
+	}
+	if err := Register(locale, catalog); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Locales returns the locales with a catalog, in order
+func Locales() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	locales := make([]string, 0, len(registry.catalogs))
+	for locale := range registry.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Catalog returns every message of a locale, falling back to English for those it lacks
+func Catalog(locale string) map[ID]string {
+	catalog := make(map[ID]string, len(English))
+	for id := range English {
+		catalog[id] = lookup(locale, id)
+	}
+	return catalog
+}
+
+// SetLocale selects the locale of Get, which is otherwise the one the environment selects
+func SetLocale(locale string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.locale = normalize(locale)
+}
+
+// Locale returns the selected locale
+func Locale() string {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.locale
+}
+
+// Get returns a message in the selected locale
+func Get(id ID) string {
+	return lookup(Locale(), id)
+}
+
+// Sprintf formats a message in the selected locale
+func Sprintf(id ID, args ...interface{}) string {
+	return fmt.Sprintf(Get(id), args...)
+}
+
+// Errorf returns an error with a message in the selected locale, wrapping a %w argument
+func Errorf(id ID, args ...interface{}) error {
+	return fmt.Errorf(Get(id), args...)
+}
+
+// IsAnswer reports whether an answer to a prompt is one of the locale's confirming answers,
+// ignoring case unless caseSensitive, when they must be lower case or capitalized
+func IsAnswer(answer string, caseSensitive bool) bool {
+	for _, accepted := range strings.Split(Get(ConfirmAnswers), ",") {
+		accepted = strings.TrimSpace(accepted)
+		switch {
+		case accepted == "":
+		case !caseSensitive && strings.EqualFold(answer, accepted):
+			return true
+		case answer == accepted || answer == strings.ToUpper(accepted[:1])+accepted[1:]:
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns a message in a locale, in the locale's language for regional locales
+// without it, or in English
+func lookup(locale string, id ID) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, candidate := range []string{locale, language(locale)} {
+		if message, ok := registry.catalogs[candidate][id]; ok {
+			return message
+		}
+	}
+	return English[id]
+}
+
+// LocaleFromEnv returns the locale the environment selects: DEVCMD_LOCALE, or else the first
+// of LC_ALL, LC_MESSAGES and LANG that is set, in the form "de-DE", and "en" for none or the
+// C locale
+func LocaleFromEnv(getenv func(string) string) string {
+	for _, name := range []string{LocaleEnvVar, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := getenv(name); value != "" {
+			if locale := normalize(value); locale != "C" && locale != "POSIX" {
+				return locale
+			}
+			break
+		}
+	}
+	return "en"
+}
+
+// normalize turns a POSIX locale such as "de_DE.UTF-8" into the form "de-DE"
+func normalize(locale string) string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ReplaceAll(locale, "_", "-")
+}
+
+// language returns the language of a regional locale, such as "de" for "de-DE"
+func language(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
+
+// verbPattern matches the formatting verbs of a message
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// verbs returns the formatting verbs of a message, which its translations must keep
+func verbs(message string) string {
+	return strings.Join(verbPattern.FindAllString(message, -1), " ")
+}
+
+// pseudoAccents replaces each ASCII letter of pseudo-localized messages
+var pseudoAccents = strings.NewReplacer(
+	"a", "á", "b", "ƀ", "c", "ç", "d", "ð", "e", "é", "f", "ƒ", "g", "ĝ", "h", "ĥ", "i", "í",
+	"j", "ĵ", "k", "ķ", "l", "ļ", "m", "ɱ", "n", "ñ", "o", "ó", "p", "þ", "q", "ǫ", "r", "ŕ",
+	"s", "š", "t", "ţ", "u", "ú", "v", "ṽ", "w", "ŵ", "x", "ẋ", "y", "ý", "z", "ž",
+	"A", "Á", "B", "Ɓ", "C", "Ç", "D", "Ð", "E", "É", "F", "Ƒ", "G", "Ĝ", "H", "Ĥ", "I", "Í",
+	"J", "Ĵ", "K", "Ķ", "L", "Ļ", "M", "Ṁ", "N", "Ñ", "O", "Ó", "P", "Þ", "Q", "Ǫ", "R", "Ŕ",
+	"S", "Š", "T", "Ţ", "U", "Ú", "V", "Ṽ", "W", "Ŵ", "X", "Ẋ", "Y", "Ý", "Z", "Ž",
+)
+
+// pseudoCatalog returns a catalog's messages accented, keeping their formatting verbs, and
+// bracketed, so truncated and concatenated text shows too
+func pseudoCatalog(catalog map[ID]string) map[ID]string {
+	pseudo := make(map[ID]string, len(catalog))
+	for id, message := range catalog {
+		if verbatim[id] {
+			pseudo[id] = message
+			continue
+		}
+		var builder strings.Builder
+		last := 0
+		for _, span := range verbPattern.FindAllStringIndex(message, -1) {
+			builder.WriteString(pseudoAccents.Replace(message[last:span[0]]))
+			builder.WriteString(message[span[0]:span[1]])
+			last = span[1]
+		}
+		builder.WriteString(pseudoAccents.Replace(message[last:]))
+		pseudo[id] = "[" + builder.String() + "]"
+	}
+	return pseudo
+}
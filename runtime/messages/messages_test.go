@@ -0,0 +1,77 @@
+package messages
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocaleFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, "en"},
+		{map[string]string{"LANG": "de_DE.UTF-8"}, "de-DE"},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_ALL": "fr_FR"}, "fr-FR"},
+		{map[string]string{"LANG": "fr_FR", "DEVCMD_LOCALE": "en-XA"}, "en-XA"},
+		{map[string]string{"LC_ALL": "C.UTF-8", "LANG": "de_DE"}, "en"},
+	}
+	for _, tt := range tests {
+		if got := LocaleFromEnv(func(name string) string { return tt.env[name] }); got != tt.want {
+			t.Errorf("LocaleFromEnv(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterAndFallback(t *testing.T) {
+	defer SetLocale(Locale())
+	path := filepath.Join(t.TempDir(), "de.json")
+	if err := os.WriteFile(path, []byte(`{"confirm.prompt": "%s [j/N]: ", "confirm.answers": "j,ja"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile("de", path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	// A regional locale uses its language's catalog, and English for what that lacks
+	SetLocale("de_AT.UTF-8")
+	if got := Sprintf(ConfirmPrompt, "Weiter?"); got != "Weiter? [j/N]: " {
+		t.Errorf("prompt = %q", got)
+	}
+	if got := Get(ConfirmCancelled); got != English[ConfirmCancelled] {
+		t.Errorf("fallback = %q, want English", got)
+	}
+	if !IsAnswer("Ja", false) || !IsAnswer("Ja", true) || IsAnswer("JA", true) || IsAnswer("yes", false) {
+		t.Error("IsAnswer should accept the locale's answers only, by case when asked")
+	}
+}
+
+func TestRegister_Errors(t *testing.T) {
+	if err := Register("de", map[ID]string{"confirm.nope": "x"}); err == nil || !strings.Contains(err.Error(), "unknown message") {
+		t.Errorf("unknown ID: err = %v", err)
+	}
+	if err := Register("de", map[ID]string{ConfirmPrompt: "[j/N]: %d"}); err == nil || !strings.Contains(err.Error(), "must take the arguments") {
+		t.Errorf("changed verbs: err = %v", err)
+	}
+}
+
+func TestPseudo(t *testing.T) {
+	defer SetLocale(Locale())
+	SetLocale(Pseudo)
+	if got := Sprintf(ConfirmPrompt, "Deploy?"); got != "[Deploy? [ý/Ñ]: ]" {
+		t.Errorf("pseudo prompt = %q", got)
+	}
+	if got := Sprintf(HintDefineVar, "PORT"); got != "[Ṁáķé šúŕé ţĥé ṽáŕíáƀļé 'PORT' íš ðéƒíñéð ƀéƒóŕé úšíñĝ íţ]" {
+		t.Errorf("pseudo hint = %q", got)
+	}
+	if !IsAnswer("yes", false) {
+		t.Error("the pseudo-locale should keep the English answers")
+	}
+	for id := range English {
+		if !strings.HasPrefix(Get(id), "[") && !verbatim[id] {
+			t.Errorf("pseudo %s = %q, want it bracketed", id, Get(id))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package testing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/runtime/decorators"
+)
+
+// === CONFORMANCE CHECKS ===
+
+// RegistrationIsValid validates that the decorator passes the checks devcmd doctor runs on
+// registered decorators, registered on its own as the type of decorator it implements
+func (v *ValidationAssertions) RegistrationIsValid() *ValidationAssertions {
+	decorator := v.result.Decorator
+	if decorator == nil {
+		v.errors = append(v.errors, "No decorator to check the registration of")
+		return v
+	}
+	registry := decorators.NewRegistry()
+	switch d := decorator.(type) {
+	case decorators.PatternDecorator:
+		registry.RegisterPattern(d)
+	case decorators.BlockDecorator:
+		registry.RegisterBlock(d)
+	case decorators.ActionDecorator:
+		registry.RegisterAction(d)
+	case decorators.ValueDecorator:
+		registry.RegisterValue(d)
+	default:
+		v.errors = append(v.errors, fmt.Sprintf("@%s implements no type of decorator", decorator.Name()))
+		return v
+	}
+	for _, err := range registry.Verify() {
+		v.errors = append(v.errors, fmt.Sprintf("Registration: %v", err))
+	}
+	return v
+}
+
+// ModesAgree validates that the modes the decorator supports all accept the parameters and
+// content, or all reject them
+func (v *ValidationAssertions) ModesAgree() *ValidationAssertions {
+	modes := decorators.Modes
+	if restricted, ok := v.result.Decorator.(decorators.ModeRestricted); ok {
+		modes = restricted.SupportedModes()
+	}
+	results := map[decorators.Mode]TestResult{
+		decorators.InterpreterMode: v.result.InterpreterResult,
+		decorators.GeneratorMode:   v.result.GeneratorResult,
+		decorators.PlanMode:        v.result.PlanResult,
+	}
+
+	var succeeded, failed []string
+	for _, mode := range modes {
+		result, ok := results[mode]
+		if !ok {
+			continue
+		}
+		if result.Success {
+			succeeded = append(succeeded, string(mode))
+		} else {
+			failed = append(failed, fmt.Sprintf("%s (%v)", mode, result.Error))
+		}
+	}
+	if len(succeeded) > 0 && len(failed) > 0 {
+		v.errors = append(v.errors, fmt.Sprintf("Modes disagree: %s succeeded but %s failed",
+			strings.Join(succeeded, ", "), strings.Join(failed, ", ")))
+	}
+	return v
+}
+
+// Conforms runs the checks every decorator should pass, in-tree or from a plugin: its
+// registration is valid, its modes agree, and each mode returned a well-formed result. Block
+// and pattern decorators describe themselves in plans with plan elements; value and action
+// decorators may with text.
+func (v *ValidationAssertions) Conforms() *ValidationAssertions {
+	v.RegistrationIsValid().ModesAgree()
+	if v.result.GeneratorResult.Success {
+		v.GeneratorProducesValidGo()
+	}
+	_, isBlock := v.result.Decorator.(decorators.BlockDecorator)
+	_, isPattern := v.result.Decorator.(decorators.PatternDecorator)
+	if v.result.PlanResult.Success && (isBlock || isPattern) {
+		if _, ok := PlanStep(*v.result); !ok {
+			v.errors = append(v.errors, fmt.Sprintf("Plan mode should return a plan element, got %T", v.result.PlanResult.Data))
+		}
+	}
+	return v
+}
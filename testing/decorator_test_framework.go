@@ -20,6 +20,7 @@ type DecoratorTestSuite struct {
 	program   *ast.Program
 	variables map[string]string
 	env       map[string]string
+	params    map[string]string
 }
 
 // NewDecoratorTest creates a new independent decorator test suite
@@ -30,6 +31,7 @@ func NewDecoratorTest(t *testing.T, decorator decorators.Decorator) *DecoratorTe
 		program:   ast.NewProgram(),
 		variables: make(map[string]string),
 		env:       make(map[string]string),
+		params:    make(map[string]string),
 	}
 }
 
@@ -45,6 +47,12 @@ func (d *DecoratorTestSuite) WithEnv(name, value string) *DecoratorTestSuite {
 	return d
 }
 
+// WithParam sets a parameter of the command the decorator runs in
+func (d *DecoratorTestSuite) WithParam(name, value string) *DecoratorTestSuite {
+	d.params[name] = value
+	return d
+}
+
 // WithCommand adds a command definition to the test program
 func (d *DecoratorTestSuite) WithCommand(name string, content ...string) *DecoratorTestSuite {
 	// Create shell content for each line
@@ -81,6 +89,7 @@ type TestResult struct {
 
 // ValidationResult contains comprehensive validation results across all modes
 type ValidationResult struct {
+	Decorator         decorators.Decorator
 	InterpreterResult TestResult
 	GeneratorResult   TestResult
 	PlanResult        TestResult
@@ -98,6 +107,7 @@ func (d *DecoratorTestSuite) TestValueDecorator(params []ast.NamedParameter) Val
 	}
 
 	result := ValidationResult{
+		Decorator:        d.decorator,
 		ValidationErrors: []string{},
 	}
 
@@ -186,6 +196,7 @@ func (d *DecoratorTestSuite) TestActionDecorator(params []ast.NamedParameter) Va
 	}
 
 	result := ValidationResult{
+		Decorator:        d.decorator,
 		ValidationErrors: []string{},
 	}
 
@@ -274,6 +285,7 @@ func (d *DecoratorTestSuite) TestBlockDecorator(params []ast.NamedParameter, con
 	}
 
 	result := ValidationResult{
+		Decorator:        d.decorator,
 		ValidationErrors: []string{},
 	}
 
@@ -362,6 +374,7 @@ func (d *DecoratorTestSuite) TestPatternDecorator(params []ast.NamedParameter, p
 	}
 
 	result := ValidationResult{
+		Decorator:        d.decorator,
 		ValidationErrors: []string{},
 	}
 
@@ -442,6 +455,24 @@ func (d *DecoratorTestSuite) TestPatternDecorator(params []ast.NamedParameter, p
 
 // === CONTEXT CREATION ===
 
+// InterpreterContext returns a fake interpreter context with the suite's program, variables
+// and parameters, for calling a decorator directly where running it in every mode doesn't
+// suit, such as a block that runs until its context is cancelled
+func (d *DecoratorTestSuite) InterpreterContext() execution.InterpreterContext {
+	return d.createInterpreterContext()
+}
+
+// GeneratorContext returns a fake generator context with the suite's program and variables,
+// resolving nested decorators from the registry
+func (d *DecoratorTestSuite) GeneratorContext() execution.GeneratorContext {
+	return d.createGeneratorContext()
+}
+
+// PlanContext returns a fake plan context with the suite's program, variables and parameters
+func (d *DecoratorTestSuite) PlanContext() execution.PlanContext {
+	return d.createPlanContext()
+}
+
 func (d *DecoratorTestSuite) createInterpreterContext() execution.InterpreterContext {
 	ctx := execution.NewInterpreterContext(context.Background(), d.program)
 
@@ -458,7 +489,7 @@ func (d *DecoratorTestSuite) createInterpreterContext() execution.InterpreterCon
 		d.t.Fatalf("Failed to initialize interpreter context: %v", err)
 	}
 
-	return ctx
+	return ctx.WithParams(d.params)
 }
 
 func (d *DecoratorTestSuite) createGeneratorContext() execution.GeneratorContext {
@@ -501,7 +532,7 @@ func (d *DecoratorTestSuite) createPlanContext() execution.PlanContext {
 		d.t.Fatalf("Failed to initialize plan context: %v", err)
 	}
 
-	return ctx
+	return ctx.WithParams(d.params)
 }
 
 // === MODE-SPECIFIC VALIDATION ===
@@ -0,0 +1,24 @@
+// Package testing is the SDK for writing and testing decorators, in-tree or as plugins,
+// usually imported as decoratortesting.
+//
+// NewDecoratorTest runs a decorator in all three execution modes, interpreter, generator and
+// plan, against fake execution contexts holding the variables, environment, parameters and
+// commands the test gives it; InterpreterContext, GeneratorContext and PlanContext return
+// those contexts for calling a decorator directly. Assert then checks the results:
+//
+//	result := decoratortesting.NewDecoratorTest(t, &TimeoutDecorator{}).
+//		TestBlockDecorator(params, content)
+//	errors := decoratortesting.Assert(result).
+//		Conforms().
+//		GeneratorCodeMatchesGolden("testdata/timeout.golden").
+//		PlanDecoratorIs("timeout").
+//		PlanHasParameter("duration", "30s").
+//		Validate()
+//
+// Conforms checks what every decorator must do for the parser, engine and generator to rely
+// on it: pass the registry checks devcmd doctor runs, and have every mode it supports accept
+// or reject the same parameters. Golden files are rewritten with DEVCMD_UPDATE_GOLDEN=1.
+//
+// NewDecoratorHarness goes further for decorators whose behavior only shows when generated
+// code runs, compiling it and comparing what it does with the interpreter.
+package testing
@@ -470,49 +470,19 @@ func (h *DecoratorHarness) createPlanContext() execution.PlanContext {
 // setupInterpreterDecoratorLookups configures decorator registry access for interpreter context
 func (h *DecoratorHarness) setupInterpreterDecoratorLookups(ctx execution.InterpreterContext) {
 	if interpreterCtx, ok := ctx.(*execution.InterpreterExecutionContext); ok {
-		// Set up action decorator lookup function using the decorator registry
-		interpreterCtx.SetActionDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, exists := decorators.GetActionDecorator(name)
-			return decorator, exists
-		})
-
-		// Set up value decorator lookup function using the decorator registry
-		interpreterCtx.SetValueDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetValue(name)
-			return decorator, err == nil
-		})
-
-		// Set up block decorator lookup function using the decorator registry
-		interpreterCtx.SetBlockDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetBlock(name)
-			return decorator, err == nil
-		})
+		interpreterCtx.SetActionDecoratorLookup(decorators.Lookup(decorators.ActionType))
+		interpreterCtx.SetValueDecoratorLookup(decorators.Lookup(decorators.ValueType))
+		interpreterCtx.SetBlockDecoratorLookup(decorators.Lookup(decorators.BlockType))
 	}
 }
 
 // setupDecoratorLookups configures decorator registry access for template functions
 func (h *DecoratorHarness) setupDecoratorLookups(ctx execution.GeneratorContext) {
 	if generatorCtx, ok := ctx.(*execution.GeneratorExecutionContext); ok {
-		// Set up action decorator lookup function using the decorator registry
-		generatorCtx.SetActionDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, exists := decorators.GetActionDecorator(name)
-			return decorator, exists
-		})
-
-		generatorCtx.SetBlockDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetBlock(name)
-			return decorator, err == nil
-		})
-
-		generatorCtx.SetPatternDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetPattern(name)
-			return decorator, err == nil
-		})
-
-		generatorCtx.SetValueDecoratorLookup(func(name string) (interface{}, bool) {
-			decorator, err := decorators.GetValue(name)
-			return decorator, err == nil
-		})
+		generatorCtx.SetActionDecoratorLookup(decorators.Lookup(decorators.ActionType))
+		generatorCtx.SetBlockDecoratorLookup(decorators.Lookup(decorators.BlockType))
+		generatorCtx.SetPatternDecoratorLookup(decorators.Lookup(decorators.PatternType))
+		generatorCtx.SetValueDecoratorLookup(decorators.Lookup(decorators.ValueType))
 	}
 }
 
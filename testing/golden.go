@@ -0,0 +1,84 @@
+package testing
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UpdateGoldenEnv names the environment variable that rewrites golden files with the code
+// decorators generate, rather than comparing against them: DEVCMD_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "DEVCMD_UPDATE_GOLDEN"
+
+// GeneratorCodeMatchesGolden validates the generated code against a golden file, such as
+// testdata/timeout.golden. Code is gofmt-ed when it parses as Go, so golden files only change
+// when what is generated does.
+func (v *ValidationAssertions) GeneratorCodeMatchesGolden(path string) *ValidationAssertions {
+	if !v.result.GeneratorResult.Success {
+		v.errors = append(v.errors, fmt.Sprintf("Generator mode failed, nothing to compare with %s: %v", path, v.result.GeneratorResult.Error))
+		return v
+	}
+	code, ok := v.result.GeneratorResult.Data.(string)
+	if !ok {
+		v.errors = append(v.errors, fmt.Sprintf("Generator result should be code, got %T", v.result.GeneratorResult.Data))
+		return v
+	}
+	code = normalizeGenerated(code)
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			v.errors = append(v.errors, fmt.Sprintf("Failed to create the directory of %s: %v", path, err))
+		} else if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+			v.errors = append(v.errors, fmt.Sprintf("Failed to update %s: %v", path, err))
+		}
+		return v
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		v.errors = append(v.errors, fmt.Sprintf("Failed to read %s (run with %s=1 to create it): %v", path, UpdateGoldenEnv, err))
+		return v
+	}
+	if want := normalizeGenerated(string(golden)); code != want {
+		v.errors = append(v.errors, fmt.Sprintf("Generated code differs from %s (run with %s=1 to update it):\n%s",
+			path, UpdateGoldenEnv, lineDiff(want, code)))
+	}
+	return v
+}
+
+// normalizeGenerated gofmts code where it can and drops trailing whitespace
+func normalizeGenerated(code string) string {
+	if formatted, err := format.Source([]byte(code)); err == nil {
+		code = string(formatted)
+	}
+	lines := strings.Split(strings.TrimSpace(code), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// lineDiff shows the lines of want and got between those they start and end with alike
+func lineDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	first := 0
+	for first < len(wantLines) && first < len(gotLines) && wantLines[first] == gotLines[first] {
+		first++
+	}
+	wantEnd, gotEnd := len(wantLines), len(gotLines)
+	for wantEnd > first && gotEnd > first && wantLines[wantEnd-1] == gotLines[gotEnd-1] {
+		wantEnd--
+		gotEnd--
+	}
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "at line %d:\n", first+1)
+	for _, line := range wantLines[first:wantEnd] {
+		fmt.Fprintf(&diff, "- %s\n", line)
+	}
+	for _, line := range gotLines[first:gotEnd] {
+		fmt.Fprintf(&diff, "+ %s\n", line)
+	}
+	return diff.String()
+}
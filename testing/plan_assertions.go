@@ -0,0 +1,102 @@
+package testing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aledsdavies/devcmd/core/plan"
+)
+
+// === PLAN ELEMENT ASSERTIONS ===
+
+// PlanStep returns the step plan mode described, building plan elements as the plan does
+func PlanStep(result ValidationResult) (plan.ExecutionStep, bool) {
+	switch data := result.PlanResult.Data.(type) {
+	case plan.ExecutionStep:
+		return data, true
+	case *plan.ExecutionStep:
+		if data != nil {
+			return *data, true
+		}
+	case plan.PlanElement:
+		if data != nil {
+			return data.Build(), true
+		}
+	}
+	return plan.ExecutionStep{}, false
+}
+
+// planStep returns the step plan mode described, recording an error when there is none
+func (v *ValidationAssertions) planStep() (plan.ExecutionStep, bool) {
+	if !v.result.PlanResult.Success {
+		return plan.ExecutionStep{}, false
+	}
+	step, ok := PlanStep(*v.result)
+	if !ok {
+		v.errors = append(v.errors, fmt.Sprintf("Plan mode should return a plan element, got %T", v.result.PlanResult.Data))
+	}
+	return step, ok
+}
+
+// PlanStepType validates the type of the step plan mode describes
+func (v *ValidationAssertions) PlanStepType(stepType plan.StepType) *ValidationAssertions {
+	if step, ok := v.planStep(); ok && step.Type != stepType {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step type should be %q, got %q", stepType, step.Type))
+	}
+	return v
+}
+
+// PlanDecoratorIs validates that the plan step names the decorator
+func (v *ValidationAssertions) PlanDecoratorIs(name string) *ValidationAssertions {
+	step, ok := v.planStep()
+	if !ok {
+		return v
+	}
+	if step.Decorator == nil {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step should describe @%s, got no decorator", name))
+	} else if step.Decorator.Name != name {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step should describe @%s, got @%s", name, step.Decorator.Name))
+	}
+	return v
+}
+
+// PlanHasParameter validates that the plan step shows a decorator parameter with the value
+func (v *ValidationAssertions) PlanHasParameter(name, value string) *ValidationAssertions {
+	step, ok := v.planStep()
+	if !ok {
+		return v
+	}
+	if step.Decorator == nil {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step should show parameter %s, got no decorator", name))
+		return v
+	}
+	got, exists := step.Decorator.Parameters[name]
+	if !exists {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step should show parameter %s", name))
+	} else if fmt.Sprint(got) != value {
+		v.errors = append(v.errors, fmt.Sprintf("Plan parameter %s should be %q, got %q", name, value, fmt.Sprint(got)))
+	}
+	return v
+}
+
+// PlanDescriptionContains validates the description of the plan step
+func (v *ValidationAssertions) PlanDescriptionContains(expected ...string) *ValidationAssertions {
+	step, ok := v.planStep()
+	if !ok {
+		return v
+	}
+	for _, text := range expected {
+		if !strings.Contains(step.Description, text) {
+			v.errors = append(v.errors, fmt.Sprintf("Plan description should contain %q, got %q", text, step.Description))
+		}
+	}
+	return v
+}
+
+// PlanHasChildren validates how many steps the plan step holds
+func (v *ValidationAssertions) PlanHasChildren(count int) *ValidationAssertions {
+	if step, ok := v.planStep(); ok && len(step.Children) != count {
+		v.errors = append(v.errors, fmt.Sprintf("Plan step should have %d children, got %d", count, len(step.Children)))
+	}
+	return v
+}
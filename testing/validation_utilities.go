@@ -169,6 +169,11 @@ func exec(ctx ExecutionContext, command string) error {
 	return cmd.Run()
 }
 
+// logf writes a diagnostic record to stderr in the text form of the generated CLI's logf
+func logf(level, source, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", source, fmt.Sprintf(format, args...))
+}
+
 func main() {
 	// Initialize working directory from runtime
 	workingDir, err := os.Getwd()
@@ -502,11 +507,14 @@ func IntParam(name string, value int) coreast.NamedParameter {
 	}
 }
 
-// Helper function to create pattern branches for testing
+// Helper function to create pattern branches for testing. Quoted patterns, like `"release/*"`,
+// are glob or regex patterns.
 func PatternBranch(pattern string, commands ...string) coreast.PatternBranch {
 	var patternNode coreast.Pattern
 	if pattern == "*" || pattern == "default" {
 		patternNode = &coreast.WildcardPattern{}
+	} else if len(pattern) >= 2 && pattern[0] == '"' && pattern[len(pattern)-1] == '"' {
+		patternNode = &coreast.StringPattern{Value: pattern[1 : len(pattern)-1]}
 	} else {
 		patternNode = &coreast.IdentifierPattern{Name: pattern}
 	}